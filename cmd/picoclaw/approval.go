@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chzyer/readline"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const approvalPrompt = "  Allow? [y]es once / [a]lways / [N]o: "
+
+// newTerminalApprover asks on the terminal before a tool that needs
+// approval runs. readLine prints its prompt and returns one line of input.
+func newTerminalApprover(readLine func(prompt string) (string, error)) tools.Approver {
+	return tools.ApproverFunc(func(ctx context.Context, req tools.ApprovalRequest) (tools.ApprovalDecision, error) {
+		argsJSON, _ := json.Marshal(req.Arguments)
+		fmt.Printf("\n⚠️  %s wants to run:\n  %s\n", req.Tool, utils.Truncate(string(argsJSON), 500))
+
+		line, err := readLine(approvalPrompt)
+		if err != nil {
+			return tools.ApprovalDeny, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return tools.ApprovalAllowOnce, nil
+		case "a", "always":
+			return tools.ApprovalAllowAlways, nil
+		}
+		return tools.ApprovalDeny, nil
+	})
+}
+
+// readlineReader reads approval answers through an active readline instance
+// and restores its prompt afterwards.
+func readlineReader(rl *readline.Instance, restorePrompt string) func(string) (string, error) {
+	return func(prompt string) (string, error) {
+		defer rl.SetPrompt(restorePrompt)
+		rl.SetPrompt(prompt)
+		return rl.Readline()
+	}
+}
+
+// stdinReader reads approval answers from reader, which should be the
+// reader already used for standard input so no buffered input is lost.
+func stdinReader(reader *bufio.Reader) func(string) (string, error) {
+	return func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		return reader.ReadString('\n')
+	}
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/plugins"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/rag"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// chatSession is the state of one interactive `picoclaw chat` run. Unlike
+// `picoclaw agent`, it talks to the model directly with a user-controlled
+// system prompt and streams the reply as it arrives.
+type chatSession struct {
+	cfg          *config.Config
+	provider     providers.LLMProvider
+	sessions     *session.SessionManager
+	sessionKey   string
+	model        string
+	systemPrompt string
+	tools        *tools.ToolRegistry
+	toolsEnabled bool
+	tags         []string     // passed to the routing rules
+	mcp          *mcp.Manager // servers Anthropic connects to itself
+}
+
+func chatCmd() {
+	sessionName := "default"
+	resumeID := ""
+	model := ""
+	systemPrompt := ""
+	noTools := false
+	var tags []string
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-s", "--session":
+			if i+1 < len(args) {
+				sessionName = args[i+1]
+				i++
+			}
+		case "-r", "--resume":
+			if i+1 < len(args) {
+				resumeID = args[i+1]
+				i++
+			}
+		case "--model":
+			if i+1 < len(args) {
+				model = args[i+1]
+				i++
+			}
+		case "--system":
+			if i+1 < len(args) {
+				systemPrompt = args[i+1]
+				i++
+			}
+		case "--no-tools":
+			noTools = true
+		case "--tag":
+			if i+1 < len(args) {
+				tags = append(tags, args[i+1])
+				i++
+			}
+		case "--help", "-h":
+			chatHelp()
+			return
+		default:
+			fmt.Printf("Unknown option: %s\n", args[i])
+			chatHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	if model == "" {
+		model = cfg.Agents.Defaults.Model
+	}
+	if model == "" {
+		model = provider.GetDefaultModel()
+	}
+	workspace := cfg.WorkspacePath()
+
+	sessions, err := openSessions(cfg)
+	if err != nil {
+		fmt.Printf("Error opening sessions: %v\n", err)
+		os.Exit(1)
+	}
+	sessionKey := "chat:" + sessionName
+	if resumeID != "" {
+		if sessionKey, err = resolveResume(sessions, resumeID, ""); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		sessionName = strings.TrimPrefix(sessionKey, "chat:")
+	}
+
+	cs := &chatSession{
+		cfg:          cfg,
+		provider:     provider,
+		sessions:     sessions,
+		sessionKey:   sessionKey,
+		model:        model,
+		systemPrompt: systemPrompt,
+		tools:        newChatToolRegistry(cfg, workspace),
+		toolsEnabled: !noTools,
+		tags:         tags,
+	}
+	cs.tools.SetAuditLog(tools.NewAuditLogFromConfig(cfg.Audit, workspace))
+
+	mcpCtx, cancelMCP := context.WithTimeout(context.Background(), 30*time.Second)
+	mcpServers := mcp.Connect(mcpCtx, cfg.MCP)
+	cancelMCP()
+	defer mcpServers.Close()
+	cs.mcp = mcpServers
+	for _, tool := range mcpServers.Tools() {
+		cs.tools.Register(tool)
+	}
+	pluginCtx, cancelPlugins := context.WithTimeout(context.Background(), 30*time.Second)
+	pluginManager := plugins.Load(pluginCtx, cfg.Plugins, workspace)
+	cancelPlugins()
+	defer pluginManager.Close()
+	for _, tool := range pluginManager.Tools() {
+		if _, exists := cs.tools.Get(tool.Name()); exists {
+			fmt.Printf("Warning: plugin tool %s skipped: a tool with that name exists\n", tool.Name())
+			continue
+		}
+		cs.tools.Register(tool)
+	}
+	if cfg.Tools.RAG.Enabled {
+		if retrieveTool, err := rag.NewRetrieveToolFromConfig(cfg.Tools.RAG, workspace, provider); err == nil {
+			cs.tools.Register(retrieveTool)
+		} else {
+			fmt.Printf("Warning: retrieve tool disabled: %v\n", err)
+		}
+	}
+
+	history := cs.sessions.GetHistory(cs.sessionKey)
+	fmt.Printf("%s Chat with %s (session %q, %d messages). Type /help for commands, Ctrl+D to exit.\n\n",
+		logo, cs.model, sessionName, len(history))
+
+	cs.run()
+}
+
+func chatHelp() {
+	fmt.Println("\nChat options:")
+	fmt.Println("  -s, --session <name>   Resume or start a named chat session (default: default)")
+	fmt.Println("  -r, --resume <id>      Resume a saved session by ID (see picoclaw sessions list)")
+	fmt.Println("  --model <model>        Model or model alias to use (default: agents.defaults.model)")
+	fmt.Println("  --system <prompt>      System prompt for the conversation")
+	fmt.Println("  --no-tools             Start with tool use disabled")
+	fmt.Println("  --tag <tag>            Tag requests for the routing rules, such as cheap (repeatable)")
+	fmt.Println()
+	chatCommandsHelp()
+}
+
+func chatCommandsHelp() {
+	fmt.Println("Chat commands:")
+	fmt.Println("  /model [name]          Show or switch the model")
+	fmt.Println("  /system [prompt|clear] Show, set or clear the system prompt")
+	fmt.Println("  /save [path]           Save the transcript (.json, .html or markdown)")
+	fmt.Println("  /tools [on|off]        List tools, or enable/disable tool use")
+	fmt.Println("  /clear                 Clear the conversation history")
+	fmt.Println("  /help                  Show this help")
+	fmt.Println("  /exit                  Leave the chat")
+	fmt.Println()
+	fmt.Println("End a line with \\ to continue it, or wrap multi-line input in \"\"\".")
+}
+
+// newChatToolRegistry registers the workspace tools that make sense without a
+// channel to deliver messages to.
+func newChatToolRegistry(cfg *config.Config, workspace string) *tools.ToolRegistry {
+	restrict := cfg.Agents.Defaults.RestrictToWorkspace
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewReadFileTool(workspace, restrict))
+	registry.Register(tools.NewWriteFileTool(workspace, restrict))
+	registry.Register(tools.NewListDirTool(workspace, restrict))
+	registry.Register(tools.NewEditFileTool(workspace, restrict))
+	registry.Register(tools.NewAppendFileTool(workspace, restrict))
+	registry.Register(tools.NewGlobTool(workspace, restrict))
+	registry.Register(tools.NewGrepTool(workspace, restrict))
+	if execTool, err := tools.NewExecToolFromConfig(workspace, restrict, cfg.Tools.Exec); err == nil {
+		registry.Register(execTool)
+	} else {
+		fmt.Printf("Warning: exec tool disabled: %v\n", err)
+	}
+	for _, gitTool := range tools.NewGitTools(workspace, restrict, cfg.Tools.Git) {
+		registry.Register(gitTool)
+	}
+	for _, githubTool := range tools.NewGitHubTools(cfg.Tools.GitHub) {
+		registry.Register(githubTool)
+	}
+	if sshTool, err := tools.NewSSHToolFromConfig(cfg.Tools.SSH); err != nil {
+		fmt.Printf("Warning: ssh tool disabled: %v\n", err)
+	} else if sshTool != nil {
+		registry.Register(sshTool)
+	}
+	if sqlTools, err := tools.NewSQLTools(cfg.Tools.SQL); err != nil {
+		fmt.Printf("Warning: sql tools disabled: %v\n", err)
+	} else {
+		for _, sqlTool := range sqlTools {
+			registry.Register(sqlTool)
+		}
+	}
+	if httpTool, err := tools.NewHTTPRequestToolFromConfig(cfg.Tools.HTTP); err != nil {
+		fmt.Printf("Warning: http_request tool disabled: %v\n", err)
+	} else if httpTool != nil {
+		registry.Register(httpTool)
+	}
+	registry.Register(tools.NewWebFetchTool(50000))
+	registry.ApplyConfig(cfg.Tools, workspace)
+	registry.SetApprovalGate(tools.NewApprovalGateFromConfig(cfg.Tools.Approval,
+		filepath.Join(workspace, "state", "approvals.json")))
+	registry.SetPolicy(tools.NewPolicyFromConfig(cfg.Policy, workspace))
+	return registry
+}
+
+func (cs *chatSession) run() {
+	home, _ := os.UserHomeDir()
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "you> ",
+		HistoryFile:     filepath.Join(home, ".picoclaw", "chat_history"),
+		HistoryLimit:    1000,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		fmt.Printf("Error initializing readline: %v\n", err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+	if gate := cs.tools.ApprovalGate(); gate != nil {
+		gate.SetApprover(newTerminalApprover(readlineReader(rl, "you> ")))
+	}
+
+	for {
+		input, err := readChatInput(rl)
+		if err != nil {
+			if err == readline.ErrInterrupt {
+				continue
+			}
+			if err == io.EOF {
+				fmt.Println("Goodbye!")
+				return
+			}
+			fmt.Printf("Error reading input: %v\n", err)
+			continue
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		if strings.HasPrefix(input, "/") {
+			if !cs.handleCommand(input) {
+				fmt.Println("Goodbye!")
+				return
+			}
+			continue
+		}
+
+		if err := cs.send(input); err != nil {
+			fmt.Printf("\nError: %s\n\n", logger.Redact(err.Error()))
+		}
+	}
+}
+
+// readChatInput reads one message. Lines ending in a backslash continue on
+// the next line, and a line of """ opens a block that runs until the next """.
+func readChatInput(rl *readline.Instance) (string, error) {
+	defer rl.SetPrompt("you> ")
+
+	line, err := rl.Readline()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(line) == `"""` {
+		var lines []string
+		rl.SetPrompt("... ")
+		for {
+			line, err := rl.Readline()
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(line) == `"""` {
+				return strings.Join(lines, "\n"), nil
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	var lines []string
+	for strings.HasSuffix(line, `\`) {
+		lines = append(lines, strings.TrimSuffix(line, `\`))
+		rl.SetPrompt("... ")
+		line, err = rl.Readline()
+		if err != nil {
+			return "", err
+		}
+	}
+	lines = append(lines, line)
+	return strings.Join(lines, "\n"), nil
+}
+
+// handleCommand runs a slash command and reports whether the chat continues.
+func (cs *chatSession) handleCommand(input string) bool {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/exit", "/quit":
+		return false
+	case "/help":
+		chatCommandsHelp()
+	case "/model":
+		if arg == "" {
+			fmt.Printf("Model: %s\n", cs.model)
+			break
+		}
+		cs.model = arg
+		fmt.Printf("Switched to %s\n", cs.model)
+	case "/system":
+		switch arg {
+		case "":
+			if cs.systemPrompt == "" {
+				fmt.Println("No system prompt set")
+			} else {
+				fmt.Printf("System prompt: %s\n", cs.systemPrompt)
+			}
+		case "clear":
+			cs.systemPrompt = ""
+			fmt.Println("System prompt cleared")
+		default:
+			cs.systemPrompt = arg
+			fmt.Println("System prompt set")
+		}
+	case "/save":
+		path, err := cs.saveTranscript(arg)
+		if err != nil {
+			fmt.Printf("Error saving transcript: %v\n", err)
+			break
+		}
+		fmt.Printf("Transcript saved to %s\n", path)
+	case "/tools":
+		switch arg {
+		case "on":
+			cs.toolsEnabled = true
+			fmt.Println("Tool use enabled")
+		case "off":
+			cs.toolsEnabled = false
+			fmt.Println("Tool use disabled")
+		case "":
+			cs.listTools()
+		default:
+			fmt.Println("Usage: /tools [on|off]")
+		}
+	case "/clear":
+		cs.sessions.TruncateHistory(cs.sessionKey, 0)
+		cs.sessions.Save(cs.sessionKey)
+		fmt.Println("Conversation cleared")
+	default:
+		fmt.Printf("Unknown command: %s (try /help)\n", name)
+	}
+	return true
+}
+
+func (cs *chatSession) listTools() {
+	state := "enabled"
+	if !cs.toolsEnabled {
+		state = "disabled"
+	}
+	fmt.Printf("Tools (%s):\n", state)
+	summaries := cs.tools.GetSummaries()
+	sort.Strings(summaries)
+	for _, s := range summaries {
+		fmt.Printf("  %s\n", strings.ReplaceAll(strings.TrimPrefix(s, "- "), "`", ""))
+	}
+}
+
+// send adds input to the conversation and has an agent.Runner stream the
+// model's reply, running requested tools until the model answers without
+// tool calls.
+func (cs *chatSession) send(input string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = tools.WithAuditSession(ctx, cs.sessionKey, cs.model)
+
+	cs.sessions.AddMessage(cs.sessionKey, "user", input)
+	cs.sessions.SetModel(cs.sessionKey, cs.model)
+	defer cs.sessions.Save(cs.sessionKey)
+
+	options := map[string]interface{}{
+		"max_tokens":  cs.cfg.Agents.Defaults.MaxTokens,
+		"temperature": cs.cfg.Agents.Defaults.Temperature,
+	}
+	if len(cs.tags) > 0 {
+		options[providers.OptionTags] = cs.tags
+	}
+	if servers := cs.mcp.ConnectorServers(ctx); cs.toolsEnabled && len(servers) > 0 {
+		options[providers.OptionMCPServers] = servers
+	}
+
+	runner := agent.NewRunner(cs.provider, nil, cs.model)
+	if cs.toolsEnabled {
+		runner.Tools = cs.tools
+	}
+	runner.Options = options
+	runner.MaxIterations = cs.cfg.Agents.Defaults.MaxToolIterations
+	runner.MaxParallelTools = cs.cfg.Agents.Defaults.MaxParallelTools
+	runner.Hooks = agent.RunnerHooks{
+		BeforeLLMCall: func(ctx context.Context, iteration int, messages []providers.Message) {
+			fmt.Print("\nassistant> ")
+		},
+		OnStreamEvent: printStreamEvent,
+		AfterLLMCall: func(ctx context.Context, iteration int, resp *providers.LLMResponse) {
+			fmt.Println()
+			if resp.Usage != nil {
+				cs.sessions.AddUsage(cs.sessionKey, *resp.Usage)
+			}
+			if audit := cs.tools.AuditLog(); audit != nil {
+				audit.RecordResponse(ctx, resp)
+			}
+		},
+		OnToolOutput: func(call providers.ToolCall, chunk string) {
+			fmt.Print(chunk)
+		},
+		AfterToolCall: func(ctx context.Context, call providers.ToolCall, result *tools.ToolResult) {
+			if !result.IsError {
+				return
+			}
+			content := result.ForLLM
+			if content == "" && result.Err != nil {
+				content = result.Err.Error()
+			}
+			fmt.Printf("  [tool] %s failed: %s\n", call.Name, utils.Truncate(strings.ReplaceAll(content, "\n", " "), 120))
+		},
+		OnMessage: func(msg providers.Message) {
+			cs.sessions.AddFullMessage(cs.sessionKey, msg)
+		},
+	}
+
+	messages := cs.sessions.GetHistory(cs.sessionKey)
+	if cs.systemPrompt != "" {
+		messages = append([]providers.Message{{Role: "system", Content: cs.systemPrompt}}, messages...)
+	}
+	result, err := runner.RunMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	if result.MaxIterationsReached {
+		return fmt.Errorf("stopped after %d tool iterations", result.Iterations)
+	}
+	cs.sessions.AddMessage(cs.sessionKey, "assistant", result.Content)
+	fmt.Println()
+	return nil
+}
+
+// printStreamEvent prints streamed text as it arrives and keeps a status
+// line per tool call, redrawn as its arguments stream in.
+func printStreamEvent(ev providers.StreamEvent) {
+	switch ev.Type {
+	case providers.StreamEventText:
+		fmt.Print(ev.Text)
+	case providers.StreamEventToolCallStart:
+		fmt.Printf("\n  [tool] %s(…)", ev.ToolCall.Name)
+	case providers.StreamEventToolCallDelta:
+		preview := providers.FormatToolCallPreview(ev.ToolCall.Name, ev.ToolCall.Arguments, 40)
+		fmt.Printf("\r\033[K  [tool] %s", utils.Truncate(strings.TrimSuffix(preview, ")")+"…", 120))
+	case providers.StreamEventToolCallDone:
+		preview := providers.FormatToolCallPreview(ev.ToolCall.Name, ev.ToolCall.Arguments, 40)
+		fmt.Printf("\r\033[K  [tool] %s", utils.Truncate(preview, 120))
+	}
+}
+
+// saveTranscript writes the conversation to path: as JSON when the path ends
+// in .json, as an HTML page for .html and as markdown otherwise. An empty
+// path picks a timestamped file in the workspace.
+func (cs *chatSession) saveTranscript(path string) (string, error) {
+	if path == "" {
+		path = filepath.Join(cs.cfg.WorkspacePath(), "chats",
+			fmt.Sprintf("chat-%s.md", time.Now().Format("20060102-150405")))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	current, ok := cs.sessions.Get(cs.sessionKey)
+	if !ok {
+		current = &session.Session{Key: cs.sessionKey}
+	}
+	current.Model = cs.model
+
+	var buf bytes.Buffer
+	opts := session.ExportOptions{Title: "Chat transcript", SystemPrompt: cs.systemPrompt}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		transcript := struct {
+			Model    string              `json:"model"`
+			System   string              `json:"system,omitempty"`
+			Messages []providers.Message `json:"messages"`
+		}{cs.model, cs.systemPrompt, current.Messages}
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(transcript); err != nil {
+			return "", err
+		}
+	case ".html", ".htm":
+		if err := session.ExportHTML(&buf, current, opts); err != nil {
+			return "", err
+		}
+	default:
+		if err := session.ExportMarkdown(&buf, current, opts); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -51,6 +52,29 @@ var (
 
 const logo = "🦞"
 
+// profileName is the config profile selected with the global --profile flag.
+var profileName string
+
+// extractProfileFlag removes a global --profile flag from args so every
+// subcommand can keep indexing os.Args positionally.
+func extractProfileFlag(args []string) ([]string, string) {
+	out := make([]string, 0, len(args))
+	profile := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--profile" && i+1 < len(args):
+			profile = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out, profile
+}
+
 // formatVersion returns the version string with optional git commit
 func formatVersion() string {
 	v := version
@@ -118,6 +142,8 @@ func copyDirectory(src, dst string) error {
 }
 
 func main() {
+	os.Args, profileName = extractProfileFlag(os.Args)
+
 	if len(os.Args) < 2 {
 		printHelp()
 		os.Exit(1)
@@ -130,6 +156,8 @@ func main() {
 		onboard()
 	case "agent":
 		agentCmd()
+	case "chat":
+		chatCmd()
 	case "gateway":
 		gatewayCmd()
 	case "status":
@@ -140,6 +168,14 @@ func main() {
 		authCmd()
 	case "cron":
 		cronCmd()
+	case "models":
+		modelsCmd()
+	case "sessions":
+		sessionsCmd()
+	case "rag":
+		ragCmd()
+	case "serve":
+		serveCmd()
 	case "skills":
 		if len(os.Args) < 3 {
 			skillsHelp()
@@ -200,18 +236,26 @@ func main() {
 
 func printHelp() {
 	fmt.Printf("%s picoclaw - Personal AI Assistant v%s\n\n", logo, version)
-	fmt.Println("Usage: picoclaw <command>")
+	fmt.Println("Usage: picoclaw [--profile <name>] <command>")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  onboard     Initialize picoclaw configuration and workspace")
 	fmt.Println("  agent       Interact with the agent directly")
+	fmt.Println("  chat        Interactive chat with the model, with streaming output")
 	fmt.Println("  auth        Manage authentication (login, logout, status)")
 	fmt.Println("  gateway     Start picoclaw gateway")
+	fmt.Println("  serve       Serve the configured providers as an OpenAI/Anthropic-compatible API")
 	fmt.Println("  status      Show picoclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
+	fmt.Println("  models      List models offered by the configured providers")
+	fmt.Println("  sessions    List and delete saved conversations")
+	fmt.Println("  rag         Index documents for the retrieve tool")
 	fmt.Println("  migrate     Migrate from OpenClaw to PicoClaw")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
 	fmt.Println("  version     Show version information")
+	fmt.Println()
+	fmt.Println("Global options:")
+	fmt.Println("  --profile <name>  Use a named profile from the config file (or set PICOCLAW_PROFILE)")
 }
 
 func onboard() {
@@ -371,6 +415,9 @@ func migrateHelp() {
 func agentCmd() {
 	message := ""
 	sessionKey := "cli:default"
+	resume := false
+	resumeID := ""
+	dryRun := false
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
@@ -388,6 +435,14 @@ func agentCmd() {
 				sessionKey = args[i+1]
 				i++
 			}
+		case "-r", "--resume":
+			resume = true
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				resumeID = args[i+1]
+				i++
+			}
+		case "--dry-run":
+			dryRun = true
 		}
 	}
 
@@ -402,9 +457,30 @@ func agentCmd() {
 		fmt.Printf("Error creating provider: %v\n", err)
 		os.Exit(1)
 	}
+	if dryRun {
+		if message == "" {
+			fmt.Println("Error: --dry-run needs a message (-m)")
+			os.Exit(1)
+		}
+		provider = dryRunProvider{provider}
+	} else if message == "" {
+		// Interactive sessions run long enough for the config to change
+		watchCtx, stopWatching := context.WithCancel(context.Background())
+		defer stopWatching()
+		provider = reloadProvider(watchCtx, cfg, provider)
+	}
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	defer agentLoop.Stop() // disconnects MCP servers
+
+	if resume {
+		sessionKey, err = resolveResume(agentLoop.Sessions(), resumeID, "cli:")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Print agent startup info (only for interactive mode)
 	startupInfo := agentLoop.GetStartupInfo()
@@ -415,16 +491,28 @@ func agentCmd() {
 			"skills_available": startupInfo["skills"].(map[string]interface{})["available"],
 		})
 
+	// Show long builds and test runs as they go
+	agentLoop.SetToolOutput(func(tool, chunk string) { fmt.Print(chunk) })
+
 	if message != "" {
+		agentLoop.SetApprover(newTerminalApprover(stdinReader(bufio.NewReader(os.Stdin))))
 		ctx := context.Background()
 		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
+		var dry *providers.DryRunError
+		if errors.As(err, &dry) {
+			fmt.Print(dry.Request)
+			return
+		}
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", logger.Redact(err.Error()))
 			os.Exit(1)
 		}
 		fmt.Printf("\n%s %s\n", logo, response)
 	} else {
-		fmt.Printf("%s Interactive mode (Ctrl+C to exit)\n\n", logo)
+		current := agentLoop.Sessions().GetOrCreate(sessionKey)
+		fmt.Printf("%s Interactive mode (Ctrl+C to exit)\n", logo)
+		fmt.Printf("Session %s (%d messages) - resume later with: picoclaw agent --resume %s\n\n",
+			current.ID, len(agentLoop.Sessions().GetHistory(sessionKey)), current.ID)
 		interactiveMode(agentLoop, sessionKey)
 	}
 }
@@ -447,6 +535,7 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		return
 	}
 	defer rl.Close()
+	agentLoop.SetApprover(newTerminalApprover(readlineReader(rl, prompt)))
 
 	for {
 		line, err := rl.Readline()
@@ -469,10 +558,12 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 			return
 		}
 
-		ctx := context.Background()
+		// Ctrl+C cancels the running turn, including in-flight tools
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 		response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
+		stop()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", logger.Redact(err.Error()))
 			continue
 		}
 
@@ -482,6 +573,7 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 
 func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	reader := bufio.NewReader(os.Stdin)
+	agentLoop.SetApprover(newTerminalApprover(stdinReader(reader)))
 	for {
 		fmt.Print(fmt.Sprintf("%s You: ", logo))
 		line, err := reader.ReadString('\n')
@@ -504,10 +596,12 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 			return
 		}
 
-		ctx := context.Background()
+		// Ctrl+C cancels the running turn, including in-flight tools
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 		response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
+		stop()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", logger.Redact(err.Error()))
 			continue
 		}
 
@@ -537,6 +631,9 @@ func gatewayCmd() {
 		fmt.Printf("Error creating provider: %v\n", err)
 		os.Exit(1)
 	}
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	provider = reloadProvider(watchCtx, cfg, provider)
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
@@ -560,7 +657,7 @@ func gatewayCmd() {
 		})
 
 	// Setup cron tool and service
-	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath())
+	cronService := setupCronTool(agentLoop, msgBus, cfg)
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -705,6 +802,12 @@ func statusCmd() {
 	}
 
 	if _, err := os.Stat(configPath); err == nil {
+		if profile := cfg.ActiveProfile(); profile != "" {
+			fmt.Printf("Profile: %s\n", profile)
+		}
+		if names := cfg.ProfileNames(); len(names) > 0 {
+			fmt.Printf("Profiles: %s\n", strings.Join(names, ", "))
+		}
 		fmt.Printf("Model: %s\n", cfg.Agents.Defaults.Model)
 
 		hasOpenRouter := cfg.Providers.OpenRouter.APIKey != ""
@@ -734,16 +837,19 @@ func statusCmd() {
 		}
 
 		store, _ := auth.LoadStore()
-		if store != nil && len(store.Credentials) > 0 {
+		if store != nil && len(store.Providers()) > 0 {
 			fmt.Println("\nOAuth/Token Auth:")
-			for provider, cred := range store.Credentials {
-				status := "authenticated"
-				if cred.IsExpired() {
-					status = "expired"
-				} else if cred.NeedsRefresh() {
-					status = "needs refresh"
+			for _, provider := range store.Providers() {
+				for _, account := range store.AccountNames(provider) {
+					cred := store.Get(provider, account)
+					status := "authenticated"
+					if cred.IsExpired() {
+						status = "expired"
+					} else if cred.NeedsRefresh() {
+						status = "needs refresh"
+					}
+					fmt.Printf("  %s/%s (%s): %s\n", provider, account, cred.AuthMethod, status)
 				}
-				fmt.Printf("  %s (%s): %s\n", provider, cred.AuthMethod, status)
 			}
 		}
 	}
@@ -762,6 +868,8 @@ func authCmd() {
 		authLogoutCmd()
 	case "status":
 		authStatusCmd()
+	case "switch":
+		authSwitchCmd()
 	default:
 		fmt.Printf("Unknown auth command: %s\n", os.Args[2])
 		authHelp()
@@ -771,24 +879,35 @@ func authCmd() {
 func authHelp() {
 	fmt.Println("\nAuth commands:")
 	fmt.Println("  login       Login via OAuth or paste token")
-	fmt.Println("  logout      Remove stored credentials")
+	fmt.Println("  logout      Remove stored credentials, revoking OAuth tokens where supported")
 	fmt.Println("  status      Show current auth status")
+	fmt.Println("  switch      Set the default account for a provider")
 	fmt.Println()
 	fmt.Println("Login options:")
-	fmt.Println("  --provider <name>    Provider to login with (openai, anthropic)")
-	fmt.Println("  --device-code        Use device code flow (for headless environments)")
+	fmt.Println("  --provider <name>    Provider to login with (openai, anthropic, github)")
+	fmt.Println("  --account <name>     Named account to store the credential under (default: \"default\")")
+	fmt.Println("  --oauth              Use claude.ai OAuth (Pro/Max subscription) instead of an API key (anthropic)")
+	fmt.Println("  --device-code        Use device code flow (for headless environments; implies --oauth)")
+	fmt.Println("  --skip-validation    Store the credential without a test request to the provider")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  picoclaw auth login --provider openai")
-	fmt.Println("  picoclaw auth login --provider openai --device-code")
+	fmt.Println("  picoclaw auth login --provider openai --account work --device-code")
 	fmt.Println("  picoclaw auth login --provider anthropic")
-	fmt.Println("  picoclaw auth logout --provider openai")
+	fmt.Println("  picoclaw auth login --provider anthropic --oauth")
+	fmt.Println("  picoclaw auth login --provider anthropic --device-code")
+	fmt.Println("  picoclaw auth login --provider github")
+	fmt.Println("  picoclaw auth switch --provider openai --account work")
+	fmt.Println("  picoclaw auth logout --provider openai --account work")
 	fmt.Println("  picoclaw auth status")
 }
 
 func authLoginCmd() {
 	provider := ""
+	account := ""
 	useDeviceCode := false
+	useOAuth := false
+	skipValidation := false
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -798,31 +917,45 @@ func authLoginCmd() {
 				provider = args[i+1]
 				i++
 			}
+		case "--account", "-a":
+			if i+1 < len(args) {
+				account = args[i+1]
+				i++
+			}
 		case "--device-code":
 			useDeviceCode = true
+		case "--oauth":
+			useOAuth = true
+		case "--skip-validation":
+			skipValidation = true
 		}
 	}
 
 	if provider == "" {
 		fmt.Println("Error: --provider is required")
-		fmt.Println("Supported providers: openai, anthropic")
+		fmt.Println("Supported providers: openai, anthropic, github")
 		return
 	}
 
 	switch provider {
 	case "openai":
-		authLoginOpenAI(useDeviceCode)
+		authLoginOAuth(auth.OpenAIOAuthConfig(), account, useDeviceCode, skipValidation)
 	case "anthropic":
-		authLoginPasteToken(provider)
+		if useOAuth || useDeviceCode {
+			authLoginOAuth(auth.AnthropicOAuthConfig(), account, useDeviceCode, skipValidation)
+		} else {
+			authLoginPasteToken(provider, account, skipValidation)
+		}
+	case "github":
+		// Token for the github tools, not a model provider
+		authLoginPasteToken(provider, account, skipValidation)
 	default:
 		fmt.Printf("Unsupported provider: %s\n", provider)
-		fmt.Println("Supported providers: openai, anthropic")
+		fmt.Println("Supported providers: openai, anthropic, github")
 	}
 }
 
-func authLoginOpenAI(useDeviceCode bool) {
-	cfg := auth.OpenAIOAuthConfig()
-
+func authLoginOAuth(cfg auth.OAuthProviderConfig, account string, useDeviceCode, skipValidation bool) {
 	var cred *auth.AuthCredential
 	var err error
 
@@ -837,33 +970,46 @@ func authLoginOpenAI(useDeviceCode bool) {
 		os.Exit(1)
 	}
 
-	if err := auth.SetCredential("openai", cred); err != nil {
+	if !skipValidation {
+		validateLoginCredential(cred)
+	}
+
+	if err := auth.SetCredential(cred.Provider, account, cred); err != nil {
 		fmt.Printf("Failed to save credentials: %v\n", err)
 		os.Exit(1)
 	}
 
 	appCfg, err := loadConfig()
 	if err == nil {
-		appCfg.Providers.OpenAI.AuthMethod = "oauth"
+		if pc := appCfg.Providers.Get(cred.Provider); pc != nil {
+			pc.AuthMethod = "oauth"
+		}
 		if err := config.SaveConfig(getConfigPath(), appCfg); err != nil {
 			fmt.Printf("Warning: could not update config: %v\n", err)
 		}
 	}
 
 	fmt.Println("Login successful!")
+	if account != "" {
+		fmt.Printf("Stored as account: %s\n", account)
+	}
 	if cred.AccountID != "" {
 		fmt.Printf("Account: %s\n", cred.AccountID)
 	}
 }
 
-func authLoginPasteToken(provider string) {
+func authLoginPasteToken(provider, account string, skipValidation bool) {
 	cred, err := auth.LoginPasteToken(provider, os.Stdin)
 	if err != nil {
 		fmt.Printf("Login failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := auth.SetCredential(provider, cred); err != nil {
+	if !skipValidation {
+		validateLoginCredential(cred)
+	}
+
+	if err := auth.SetCredential(provider, account, cred); err != nil {
 		fmt.Printf("Failed to save credentials: %v\n", err)
 		os.Exit(1)
 	}
@@ -884,8 +1030,29 @@ func authLoginPasteToken(provider string) {
 	fmt.Printf("Token saved for %s!\n", provider)
 }
 
+// validateLoginCredential checks a freshly obtained credential with a
+// models-list request and exits before it is stored if the provider rejects it.
+func validateLoginCredential(cred *auth.AuthCredential) {
+	fmt.Println("Validating credential...")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	err := auth.ValidateCredential(ctx, cred)
+	switch {
+	case errors.Is(err, auth.ErrValidationUnsupported):
+		return
+	case err != nil:
+		fmt.Printf("Credential validation failed: %v\n", err)
+		fmt.Println("Nothing was saved. Re-run with --skip-validation to store it anyway.")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Credential valid (%d models available)\n", len(cred.Models))
+}
+
 func authLogoutCmd() {
 	provider := ""
+	account := ""
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -895,14 +1062,19 @@ func authLogoutCmd() {
 				provider = args[i+1]
 				i++
 			}
+		case "--account", "-a":
+			if i+1 < len(args) {
+				account = args[i+1]
+				i++
+			}
 		}
 	}
 
-	if provider != "" {
-		if err := auth.DeleteCredential(provider); err != nil {
-			fmt.Printf("Failed to remove credentials: %v\n", err)
-			os.Exit(1)
-		}
+	if provider != "" && account != "" {
+		logoutOrExit(auth.Logout(provider, account))
+		fmt.Printf("Logged out %s account %s\n", provider, account)
+	} else if provider != "" {
+		logoutOrExit(auth.Logout(provider, ""))
 
 		appCfg, err := loadConfig()
 		if err == nil {
@@ -917,10 +1089,7 @@ func authLogoutCmd() {
 
 		fmt.Printf("Logged out from %s\n", provider)
 	} else {
-		if err := auth.DeleteAllCredentials(); err != nil {
-			fmt.Printf("Failed to remove credentials: %v\n", err)
-			os.Exit(1)
-		}
+		logoutOrExit(auth.LogoutAll())
 
 		appCfg, err := loadConfig()
 		if err == nil {
@@ -933,6 +1102,19 @@ func authLogoutCmd() {
 	}
 }
 
+// logoutOrExit reports an error from auth.Logout, exiting unless the
+// credentials were removed and only revoking the token failed.
+func logoutOrExit(err error) {
+	if errors.Is(err, auth.ErrNotRevoked) {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to remove credentials: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func authStatusCmd() {
 	store, err := auth.LoadStore()
 	if err != nil {
@@ -940,7 +1122,8 @@ func authStatusCmd() {
 		return
 	}
 
-	if len(store.Credentials) == 0 {
+	providers := store.Providers()
+	if len(providers) == 0 {
 		fmt.Println("No authenticated providers.")
 		fmt.Println("Run: picoclaw auth login --provider <name>")
 		return
@@ -948,32 +1131,77 @@ func authStatusCmd() {
 
 	fmt.Println("\nAuthenticated Providers:")
 	fmt.Println("------------------------")
-	for provider, cred := range store.Credentials {
-		status := "active"
-		if cred.IsExpired() {
-			status = "expired"
-		} else if cred.NeedsRefresh() {
-			status = "needs refresh"
-		}
-
+	for _, provider := range providers {
+		defaultAccount := store.DefaultAccountName(provider)
 		fmt.Printf("  %s:\n", provider)
-		fmt.Printf("    Method: %s\n", cred.AuthMethod)
-		fmt.Printf("    Status: %s\n", status)
-		if cred.AccountID != "" {
-			fmt.Printf("    Account: %s\n", cred.AccountID)
+		for _, account := range store.AccountNames(provider) {
+			cred := store.Get(provider, account)
+			status := "active"
+			if cred.IsExpired() {
+				status = "expired"
+			} else if cred.NeedsRefresh() {
+				status = "needs refresh"
+			}
+
+			marker := ""
+			if account == defaultAccount {
+				marker = " (default)"
+			}
+			fmt.Printf("    %s%s:\n", account, marker)
+			fmt.Printf("      Method: %s\n", cred.AuthMethod)
+			fmt.Printf("      Status: %s\n", status)
+			if cred.AccountID != "" {
+				fmt.Printf("      Account ID: %s\n", cred.AccountID)
+			}
+			if !cred.ExpiresAt.IsZero() {
+				fmt.Printf("      Expires: %s\n", cred.ExpiresAt.Format("2006-01-02 15:04"))
+			}
+			if !cred.ValidatedAt.IsZero() {
+				fmt.Printf("      Validated: %s (%d models)\n", cred.ValidatedAt.Format("2006-01-02 15:04"), len(cred.Models))
+			}
 		}
-		if !cred.ExpiresAt.IsZero() {
-			fmt.Printf("    Expires: %s\n", cred.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+}
+
+func authSwitchCmd() {
+	provider := ""
+	account := ""
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--provider", "-p":
+			if i+1 < len(args) {
+				provider = args[i+1]
+				i++
+			}
+		case "--account", "-a":
+			if i+1 < len(args) {
+				account = args[i+1]
+				i++
+			}
 		}
 	}
+
+	if provider == "" || account == "" {
+		fmt.Println("Usage: picoclaw auth switch --provider <name> --account <name>")
+		return
+	}
+
+	if err := auth.SetDefaultAccount(provider, account); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Default %s account is now %s\n", provider, account)
 }
 
 func getConfigPath() string {
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".picoclaw", "config.json")
+	return config.ResolveConfigPath(filepath.Join(home, ".picoclaw"))
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string) *cron.CronService {
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, cfg *config.Config) *cron.CronService {
+	workspace := cfg.WorkspacePath()
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
 	// Create cron service
@@ -981,6 +1209,13 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 
 	// Create and register CronTool
 	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace)
+	if execTool, err := tools.NewExecToolFromConfig(workspace, false, cfg.Tools.Exec); err == nil {
+		cronTool.SetExecTool(execTool)
+	} else if cfg.Tools.Exec.Sandbox.Enabled {
+		logger.WarnCF("cron", "Exec sandbox unavailable, scheduled commands disabled",
+			map[string]interface{}{"error": err.Error()})
+		cronTool.SetExecTool(nil)
+	}
 	agentLoop.RegisterTool(cronTool)
 
 	// Set the onJob handler
@@ -992,8 +1227,97 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 	return cronService
 }
 
+// loadConfig loads the config and masks its credentials in log output.
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfigWithProfile(getConfigPath(), profileName)
+	if err != nil {
+		return nil, err
+	}
+	logger.AddSecret(cfg.Secrets()...)
+	return cfg, nil
+}
+
+// watchConfig calls onReload with the config each time it or the
+// credential store changes, or on SIGHUP, until ctx is done.
+func watchConfig(ctx context.Context, onReload func(*config.Config)) {
+	w := &config.Watcher{
+		Path:    getConfigPath(),
+		Profile: profileName,
+		Files:   []string{auth.StorePath()},
+		OnReload: func(cfg *config.Config) {
+			logger.AddSecret(cfg.Secrets()...)
+			logger.InfoC("config", "Config reloaded")
+			onReload(cfg)
+		},
+		OnError: func(err error) {
+			logger.WarnCF("config", "Config not reloaded, keeping the previous one",
+				map[string]interface{}{"error": err.Error()})
+		},
+	}
+	go w.Run(ctx)
+}
+
+// startWarmup gets the provider ready for the first request in the
+// background, so it does not wait for tokens or, with preconnect set, the
+// TLS handshake. Failures are only logged; the request retries them.
+func startWarmup(cfg *config.Config, warmup func(ctx context.Context, connect bool) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		start := time.Now()
+		if err := warmup(ctx, cfg.Agents.Defaults.Preconnect); err != nil {
+			logger.WarnCF("provider", "Provider warm-up failed",
+				map[string]interface{}{"error": err.Error()})
+			return
+		}
+		logger.DebugCF("provider", "Provider warmed up",
+			map[string]interface{}{"duration": time.Since(start).String()})
+	}()
+}
+
+// warmupFunc warms up provider for startWarmup.
+func warmupFunc(provider providers.LLMProvider) func(ctx context.Context, connect bool) error {
+	return func(ctx context.Context, connect bool) error {
+		return providers.Warmup(ctx, provider, connect)
+	}
+}
+
+// dryRunProvider sends nothing: each request fails with a
+// *providers.DryRunError holding what would have been sent, for
+// agent --dry-run.
+type dryRunProvider struct {
+	providers.LLMProvider
+}
+
+func (p dryRunProvider) Unwrap() providers.LLMProvider {
+	return p.LLMProvider
+}
+
+func (p dryRunProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	req, err := providers.DryRun(ctx, p.LLMProvider, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &providers.DryRunError{Request: req}
+}
+
+// reloadProvider returns a provider created again from each reloaded
+// config, so new API keys, aliases and budgets apply to the running agent.
+// Each provider, the first included, is warmed up in the background.
+func reloadProvider(ctx context.Context, cfg *config.Config, provider providers.LLMProvider) providers.LLMProvider {
+	reloadable := providers.NewReloadableProvider(provider)
+	watchConfig(ctx, func(cfg *config.Config) {
+		provider, err := providers.CreateProvider(cfg)
+		if err != nil {
+			logger.WarnCF("config", "Provider not reloaded, keeping the previous one",
+				map[string]interface{}{"error": err.Error()})
+			return
+		}
+		reloadable.Swap(provider)
+		startWarmup(cfg, warmupFunc(provider))
+	})
+	startWarmup(cfg, warmupFunc(provider))
+	return reloadable
 }
 
 func cronCmd() {
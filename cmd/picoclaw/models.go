@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func modelsCmd() {
+	if len(os.Args) < 3 {
+		modelsHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "list":
+		modelsListCmd()
+	case "health":
+		modelsHealthCmd()
+	default:
+		fmt.Printf("Unknown models command: %s\n", os.Args[2])
+		modelsHelp()
+	}
+}
+
+func modelsHelp() {
+	fmt.Println("\nModels commands:")
+	fmt.Println("  list                 List models offered by the configured providers")
+	fmt.Println("  health               Check that each configured provider is reachable and accepts its credentials")
+	fmt.Println()
+	fmt.Println("List options:")
+	fmt.Println("  --provider, -p <name>  Only query one provider (e.g. anthropic, openai, azure, ollama)")
+	fmt.Println("  --json                 Print the listing as JSON")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw models list")
+	fmt.Println("  picoclaw models list --provider ollama")
+	fmt.Println("  picoclaw models list --json")
+}
+
+func modelsListCmd() {
+	provider := ""
+	asJSON := false
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--provider", "-p":
+			if i+1 < len(args) {
+				provider = args[i+1]
+				i++
+			}
+		case "--json":
+			asJSON = true
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var listings []providers.ProviderModels
+	if provider != "" {
+		listing := providers.ProviderModels{Provider: provider}
+		models, err := providers.ListProviderModels(ctx, cfg, strings.ToLower(provider))
+		if err != nil {
+			listing.Error = err.Error()
+		}
+		listing.Models = models
+		listings = append(listings, listing)
+	} else {
+		listings, err = providers.ListModels(ctx, cfg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(listings, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(listings) == 0 {
+		fmt.Println("No providers configured. Add an API key to the config or run: picoclaw auth login")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tMODEL\tCONTEXT\tMAX OUTPUT\tCAPABILITIES")
+	for _, listing := range listings {
+		if listing.Error != "" {
+			fmt.Fprintf(w, "%s\t(error: %s)\t\t\t\n", listing.Provider, listing.Error)
+			continue
+		}
+		for _, m := range listing.Models {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				listing.Provider, m.ID, formatTokenCount(m.ContextWindow), formatTokenCount(m.MaxOutputTokens), strings.Join(m.Capabilities, ","))
+		}
+	}
+	w.Flush()
+}
+
+func modelsHealthCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := providers.NewHealthChecker(cfg, 0).Check(context.Background())
+	if len(results) == 0 {
+		fmt.Println("No providers configured. Add an API key to the config or run: picoclaw auth login")
+		return
+	}
+
+	healthy := true
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tSTATUS\tLATENCY\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%dms\t%s\n", r.Provider, r.Status, r.LatencyMS, r.Error)
+		healthy = healthy && r.Healthy()
+	}
+	w.Flush()
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// formatTokenCount renders token limits compactly, e.g. 200K or 1M.
+func formatTokenCount(n int) string {
+	switch {
+	case n <= 0:
+		return "-"
+	case n >= 1000000 && n%1000000 < 100000:
+		return fmt.Sprintf("%dM", n/1000000)
+	case n >= 1000:
+		return fmt.Sprintf("%dK", n/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/rag"
+)
+
+func ragCmd() {
+	if len(os.Args) < 3 {
+		ragHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	index, err := rag.OpenIndex(rag.IndexDir(cfg.WorkspacePath()))
+	if err != nil {
+		fmt.Printf("Error opening index: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "ingest", "add":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: picoclaw rag ingest <path>...")
+			return
+		}
+		ragIngestCmd(cfg, index, os.Args[3:])
+	case "search":
+		ragSearchCmd(cfg, index, os.Args[3:])
+	case "list":
+		ragListCmd(index)
+	case "remove", "rm":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: picoclaw rag remove <path>...")
+			return
+		}
+		ragRemoveCmd(index, os.Args[3:])
+	default:
+		fmt.Printf("Unknown rag command: %s\n", os.Args[2])
+		ragHelp()
+	}
+}
+
+func ragHelp() {
+	fmt.Println("\nRAG commands:")
+	fmt.Println("  ingest <path>...        Chunk, embed and index files or directories")
+	fmt.Println("  search <query> [-k n]   Show the passages the retrieve tool would return")
+	fmt.Println("  list                    List indexed files")
+	fmt.Println("  remove <path>...        Drop files from the index")
+	fmt.Println()
+	fmt.Println("Enable the retrieve tool with tools.rag.enabled in the config.")
+}
+
+func ragEmbedder(cfg *config.Config) providers.Embedder {
+	var provider providers.LLMProvider
+	if cfg.Tools.RAG.APIBase == "" {
+		var err error
+		if provider, err = providers.CreateProvider(cfg); err != nil {
+			fmt.Printf("Error creating provider: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	embedder, err := providers.NewEmbedder(cfg.Tools.RAG, provider)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return embedder
+}
+
+func ragIngestCmd(cfg *config.Config, index *rag.Index, paths []string) {
+	if model := index.Model(); model != "" && model != cfg.Tools.RAG.Model {
+		fmt.Printf("Embedding model changed from %s to %s; re-indexing everything.\n", model, cfg.Tools.RAG.Model)
+	}
+
+	ingester := &rag.Ingester{
+		Index:        index,
+		Embedder:     ragEmbedder(cfg),
+		Model:        cfg.Tools.RAG.Model,
+		ChunkSize:    cfg.Tools.RAG.ChunkSize,
+		ChunkOverlap: cfg.Tools.RAG.ChunkOverlap,
+	}
+	for _, path := range paths {
+		stats, err := ingester.Ingest(context.Background(), path)
+		if err != nil {
+			fmt.Printf("Error ingesting %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: %d files indexed (%d chunks embedded, %d reused), %d unchanged, %d skipped, %d removed\n",
+			path, stats.Files, stats.Chunks, stats.Reused, stats.Unchanged, stats.Skipped, stats.Removed)
+	}
+}
+
+func ragSearchCmd(cfg *config.Config, index *rag.Index, args []string) {
+	k := cfg.Tools.RAG.TopK
+	var query []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-k", "--top-k":
+			if i+1 < len(args) {
+				k, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		default:
+			query = append(query, args[i])
+		}
+	}
+	if len(query) == 0 {
+		fmt.Println("Usage: picoclaw rag search <query> [-k n]")
+		return
+	}
+
+	tool := rag.NewRetrieveTool(index, ragEmbedder(cfg), cfg.Tools.RAG.Model, cfg.Tools.RAG.TopK)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"query": strings.Join(query, " "),
+		"top_k": float64(k),
+	})
+	fmt.Println(result.ForLLM)
+	if result.IsError {
+		os.Exit(1)
+	}
+}
+
+func ragListCmd(index *rag.Index) {
+	docs := index.Documents()
+	if len(docs) == 0 {
+		fmt.Println("No indexed documents")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tCHUNKS\tINGESTED")
+	for _, doc := range docs {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", doc.Path, len(doc.Chunks), doc.Ingested.Local().Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	fmt.Printf("\nEmbedding model: %s\n", index.Model())
+}
+
+func ragRemoveCmd(index *rag.Index, paths []string) {
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if index.Remove(path) {
+			fmt.Printf("Removed %s\n", path)
+		} else {
+			fmt.Printf("%s is not indexed\n", path)
+		}
+	}
+	if err := index.Save(); err != nil {
+		fmt.Printf("Error saving index: %v\n", err)
+		os.Exit(1)
+	}
+}
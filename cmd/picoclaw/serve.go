@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/serve"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func serveCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(os.Args) > 2 && os.Args[2] == "keys" {
+		serveKeysCmd(cfg)
+		return
+	}
+	keys, err := serve.OpenKeyStore(serve.KeyDir(cfg.WorkspacePath()))
+	if err != nil {
+		fmt.Printf("Error opening keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	host, port, grpcPort := cfg.Serve.Host, cfg.Serve.Port, cfg.Serve.GRPCPort
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--host":
+			if i+1 < len(args) {
+				host = args[i+1]
+				i++
+			}
+		case "--port", "-p":
+			if i+1 < len(args) {
+				port, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		case "--grpc-port":
+			if i+1 < len(args) {
+				grpcPort, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		case "--debug", "-d":
+			logger.SetLevel(logger.DEBUG)
+		case "--help", "-h":
+			serveHelp()
+			return
+		}
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	api := serve.NewServer(cfg, providers.CreateProviderFor)
+	api.SetKeyStore(keys)
+	if cfg.Serve.HealthCheckInterval > 0 {
+		health := providers.NewHealthChecker(cfg, time.Duration(cfg.Serve.HealthCheckInterval)*time.Second)
+		go health.Run(context.Background())
+		api.SetHealthChecker(health)
+	}
+	api.SetAgentTools(func(cfg *config.Config) *tools.ToolRegistry {
+		return newChatToolRegistry(cfg, cfg.WorkspacePath())
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go keys.FlushEvery(watchCtx, 30*time.Second)
+	watchConfig(watchCtx, func(cfg *config.Config) {
+		api.SetConfig(cfg)
+		startWarmup(cfg, api.Warmup)
+	})
+	startWarmup(cfg, api.Warmup)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("%s OpenAI- and Anthropic-compatible API on http://%s/v1\n", logo, addr)
+	if len(cfg.Serve.APIKeys) == 0 && keys.Len() == 0 && host != "127.0.0.1" && host != "localhost" {
+		fmt.Println("Warning: no API keys configured (see picoclaw serve keys); anyone who can reach this address can use your providers")
+	}
+
+	var grpcServer *grpc.Server
+	if grpcPort > 0 {
+		grpcAddr := net.JoinHostPort(host, strconv.Itoa(grpcPort))
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		grpcServer = api.GRPCServer()
+		go grpcServer.Serve(lis)
+		fmt.Printf("%s gRPC API (picoclaw.v1.PicoClaw) on %s\n", logo, grpcAddr)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	go func() {
+		<-sigChan
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		server.Shutdown(ctx)
+	}()
+
+	err = server.ListenAndServe()
+	if flushErr := keys.Flush(); flushErr != nil {
+		fmt.Printf("Error: %v\n", flushErr)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serveHelp() {
+	fmt.Println("\nServe options:")
+	fmt.Println("  --host <host>      Address to listen on (default: serve.host, 127.0.0.1)")
+	fmt.Println("  -p, --port <port>  Port to listen on (default: serve.port, 18800)")
+	fmt.Println("  --grpc-port <port> Also serve the gRPC API on this port (default: serve.grpc_port)")
+	fmt.Println("  -d, --debug        Log each request")
+	fmt.Println()
+	fmt.Println()
+	fmt.Println("Endpoints: POST /v1/chat/completions, POST /v1/messages, GET /v1/models, GET /v1/ws (WebSocket), GET /healthz")
+	fmt.Println()
+	fmt.Println("Key commands:")
+	fmt.Println("  keys create <name> [--rpm n] [--monthly-tokens n]  Create a virtual API key")
+	fmt.Println("  keys list                                          List keys and this month's usage")
+	fmt.Println("  keys revoke <id|name>                              Revoke a key")
+}
+
+func serveKeysCmd(cfg *config.Config) {
+	keys, err := serve.OpenKeyStore(serve.KeyDir(cfg.WorkspacePath()))
+	if err != nil {
+		fmt.Printf("Error opening keys: %v\n", err)
+		os.Exit(1)
+	}
+	if len(os.Args) < 4 {
+		serveHelp()
+		return
+	}
+
+	switch os.Args[3] {
+	case "create", "add":
+		serveKeysCreateCmd(keys, os.Args[4:])
+	case "list":
+		serveKeysListCmd(keys)
+	case "revoke":
+		if len(os.Args) < 5 {
+			fmt.Println("Usage: picoclaw serve keys revoke <id|name>")
+			return
+		}
+		key, err := keys.Revoke(os.Args[4])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked %s (%s)\n", key.Name, key.ID)
+	default:
+		fmt.Printf("Unknown keys command: %s\n", os.Args[3])
+		serveHelp()
+	}
+}
+
+func serveKeysCreateCmd(keys *serve.KeyStore, args []string) {
+	var name string
+	var rpm, monthlyTokens int
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--rpm":
+			if i+1 < len(args) {
+				rpm, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		case "--monthly-tokens":
+			if i+1 < len(args) {
+				monthlyTokens, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		default:
+			name = args[i]
+		}
+	}
+	if name == "" {
+		fmt.Println("Usage: picoclaw serve keys create <name> [--rpm n] [--monthly-tokens n]")
+		return
+	}
+
+	key, secret, err := keys.Create(name, rpm, monthlyTokens)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created key %s (%s):\n\n  %s\n\n", key.Name, key.ID, secret)
+	fmt.Println("Store it now; it cannot be shown again.")
+}
+
+func serveKeysListCmd(keys *serve.KeyStore) {
+	list := keys.Keys()
+	if len(list) == 0 {
+		fmt.Println("No keys")
+		return
+	}
+
+	limit := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tKEY\tRPM\tMONTHLY TOKENS\tREQUESTS\tTOKENS\tSTATUS")
+	for _, k := range list {
+		status := "active"
+		if k.Revoked != nil {
+			status = "revoked " + k.Revoked.Local().Format("2006-01-02")
+		}
+		u := keys.Usage(k.ID, now)
+		fmt.Fprintf(w, "%s\t%s\t%s...\t%s\t%s\t%d\t%d\t%s\n",
+			k.ID, k.Name, k.Prefix, limit(k.RequestsPerMinute), limit(k.MonthlyTokens), u.Requests, u.Tokens(), status)
+	}
+	w.Flush()
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func sessionsCmd() {
+	if len(os.Args) < 3 {
+		sessionsHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	sessions, err := openSessions(cfg)
+	if err != nil {
+		fmt.Printf("Error opening sessions: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "list":
+		sessionsListCmd(sessions)
+	case "export":
+		sessionsExportCmd(sessions, os.Args[3:])
+	case "delete", "rm":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: picoclaw sessions delete <id>")
+			return
+		}
+		key, ok := sessions.Resolve(os.Args[3])
+		if !ok {
+			fmt.Printf("No session %q\n", os.Args[3])
+			os.Exit(1)
+		}
+		if err := sessions.Delete(key); err != nil {
+			fmt.Printf("Error deleting session: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted session %s\n", key)
+	default:
+		fmt.Printf("Unknown sessions command: %s\n", os.Args[2])
+		sessionsHelp()
+	}
+}
+
+func sessionsHelp() {
+	fmt.Println("\nSessions commands:")
+	fmt.Println("  list                 List saved conversations, most recent first")
+	fmt.Println("  export <id>          Render a conversation as Markdown or HTML")
+	fmt.Println("  delete <id>          Delete a conversation")
+	fmt.Println()
+	fmt.Println("Export options:")
+	fmt.Println("  -o, --output <path>    Write to a file; .html selects HTML (default: stdout)")
+	fmt.Println("  --format <md|html>     Output format (default: from the file extension, else md)")
+	fmt.Println("  --max-tool-output <n>  Cut each tool output to n characters")
+	fmt.Println()
+	fmt.Println("Resume a conversation with: picoclaw agent --resume <id>")
+}
+
+func sessionsExportCmd(sessions *session.SessionManager, args []string) {
+	id, output, format, maxToolOutput := "", "", "", 0
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "--format":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case "--max-tool-output":
+			if i+1 < len(args) {
+				maxToolOutput, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		default:
+			id = args[i]
+		}
+	}
+	if id == "" {
+		fmt.Println("Usage: picoclaw sessions export <id> [-o path] [--format md|html]")
+		os.Exit(1)
+	}
+
+	key, ok := sessions.Resolve(id)
+	if !ok {
+		fmt.Printf("No session %q\n", id)
+		os.Exit(1)
+	}
+	current, _ := sessions.Get(key)
+
+	if format == "" {
+		format = "md"
+		if ext := strings.ToLower(filepath.Ext(output)); ext == ".html" || ext == ".htm" {
+			format = "html"
+		}
+	}
+	export := session.ExportMarkdown
+	switch format {
+	case "md", "markdown":
+	case "html":
+		export = session.ExportHTML
+	default:
+		fmt.Printf("Unknown format %q (want md or html)\n", format)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Printf("Error creating %s: %v\n", output, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := export(w, current, session.ExportOptions{MaxToolOutput: maxToolOutput}); err != nil {
+		fmt.Printf("Error exporting session: %v\n", err)
+		os.Exit(1)
+	}
+	if output != "" {
+		fmt.Printf("Exported session %s to %s\n", current.ID, output)
+	}
+}
+
+// openSessions opens the workspace's session store in the configured format.
+func openSessions(cfg *config.Config) (*session.SessionManager, error) {
+	return session.OpenSessionManager(cfg.Agents.Defaults.SessionFormat,
+		filepath.Join(cfg.WorkspacePath(), "sessions"))
+}
+
+func sessionsListCmd(sessions *session.SessionManager) {
+	infos := sessions.List()
+	if len(infos) == 0 {
+		fmt.Println("No saved sessions")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSESSION\tMODEL\tMESSAGES\tTOKENS\tUPDATED")
+	for _, info := range infos {
+		model := info.Model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n",
+			info.ID, info.Key, model, info.Messages, info.Usage.TotalTokens,
+			info.Updated.Local().Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+}
+
+// resolveResume returns the session key to resume for --resume. An empty id
+// picks the most recently updated session whose key starts with prefix.
+func resolveResume(sessions *session.SessionManager, id, prefix string) (string, error) {
+	if id == "" {
+		for _, info := range sessions.List() {
+			if strings.HasPrefix(info.Key, prefix) {
+				return info.Key, nil
+			}
+		}
+		return "", fmt.Errorf("no previous session to resume")
+	}
+	key, ok := sessions.Resolve(id)
+	if !ok {
+		return "", fmt.Errorf("no session %q (see picoclaw sessions list)", id)
+	}
+	return key, nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/prompts"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -19,6 +20,8 @@ type ContextBuilder struct {
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
 	tools        *tools.ToolRegistry // Direct reference to tool registry
+	prompts      *prompts.Library
+	provider     string // selects per-provider prompt variants
 }
 
 func getGlobalConfigDir() string {
@@ -40,6 +43,7 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 		workspace:    workspace,
 		skillsLoader: skills.NewSkillsLoader(workspace, globalSkillsDir, builtinSkillsDir),
 		memory:       NewMemoryStore(workspace),
+		prompts:      prompts.New(filepath.Join(workspace, "prompts"), filepath.Join(getGlobalConfigDir(), "prompts")),
 	}
 }
 
@@ -48,62 +52,36 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
+// SetProvider sets the provider whose prompt variants are preferred.
+func (cb *ContextBuilder) SetProvider(name string) {
+	cb.provider = name
+}
+
 func (cb *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
-	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
-
-	// Build tools section dynamically
-	toolsSection := cb.buildToolsSection()
-
-	return fmt.Sprintf(`# picoclaw 🦞
-
-You are picoclaw, a helpful AI assistant.
-
-## Current Time
-%s
-
-## Runtime
-%s
-
-## Workspace
-Your workspace is at: %s
-- Memory: %s/memory/MEMORY.md
-- Daily Notes: %s/memory/YYYYMM/YYYYMMDD.md
-- Skills: %s/skills/{skill-name}/SKILL.md
-
-%s
-
-## Important Rules
-
-1. **ALWAYS use tools** - When you need to perform an action (schedule reminders, send messages, execute commands, etc.), you MUST call the appropriate tool. Do NOT just say you'll do it or pretend to do it.
-
-2. **Be helpful and accurate** - When using tools, briefly explain what you're doing.
-
-3. **Memory** - When remembering something, write to %s/memory/MEMORY.md`,
-		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
+	var toolSummaries []string
+	if cb.tools != nil {
+		toolSummaries = cb.tools.GetSummaries()
+	}
+	return cb.render("identity", map[string]interface{}{
+		"Time":      time.Now().Format("2006-01-02 15:04 (Monday)"),
+		"Runtime":   fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version()),
+		"Workspace": workspacePath,
+		"Tools":     toolSummaries,
+	})
 }
 
-func (cb *ContextBuilder) buildToolsSection() string {
-	if cb.tools == nil {
-		return ""
-	}
-
-	summaries := cb.tools.GetSummaries()
-	if len(summaries) == 0 {
-		return ""
-	}
-
-	var sb strings.Builder
-	sb.WriteString("## Available Tools\n\n")
-	sb.WriteString("**CRITICAL**: You MUST use tools to perform actions. Do NOT pretend to execute commands or schedule tasks.\n\n")
-	sb.WriteString("You have access to the following tools:\n\n")
-	for _, s := range summaries {
-		sb.WriteString(s)
-		sb.WriteString("\n")
-	}
-
-	return sb.String()
+// render renders a prompt for the agent's provider, falling back to the
+// built-in prompt when a workspace's version fails.
+func (cb *ContextBuilder) render(name string, data interface{}) string {
+	text, err := cb.prompts.RenderFor(name, cb.provider, data)
+	if err == nil {
+		return text
+	}
+	logger.WarnCF("agent", "Failed to render prompt, using the built-in one",
+		map[string]interface{}{"prompt": name, "error": err.Error()})
+	text, _ = prompts.Default().RenderFor(name, cb.provider, data)
+	return text
 }
 
 func (cb *ContextBuilder) BuildSystemPrompt() string {
@@ -121,11 +99,7 @@ func (cb *ContextBuilder) BuildSystemPrompt() string {
 	// Skills - show summary, AI can read full content with read_file tool
 	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
 	if skillsSummary != "" {
-		parts = append(parts, fmt.Sprintf(`# Skills
-
-The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
-
-%s`, skillsSummary))
+		parts = append(parts, cb.render("skills", skillsSummary))
 	}
 
 	// Memory context
@@ -8,7 +8,6 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,7 +21,10 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/plugins"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/rag"
 	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -36,12 +38,20 @@ type AgentLoop struct {
 	model          string
 	contextWindow  int // Maximum context window size in tokens
 	maxIterations  int
+	maxParallel    int // tool calls run at once
 	sessions       *session.SessionManager
 	state          *state.Manager
 	contextBuilder *ContextBuilder
 	tools          *tools.ToolRegistry
+	approvals      *tools.ApprovalGate // nil when approvals are disabled
+	mcp            *mcp.Manager
+	plugins        *plugins.Manager
 	running        atomic.Bool
 	summarizing    sync.Map // Tracks which sessions are currently being summarized
+	codeExecution  bool     // offer Anthropic's code execution tool
+	autoContinue   int      // follow-up requests for replies cut off by max_tokens
+	containers     sync.Map // session key -> code execution container to reuse
+	toolOutput     func(tool, chunk string)
 }
 
 // processOptions configures how a message is processed
@@ -50,6 +60,7 @@ type processOptions struct {
 	Channel         string // Target channel for tool execution
 	ChatID          string // Target chat ID for tool execution
 	UserMessage     string // User message content (may include prefix)
+	SenderName      string // Who sent the user message, in chats with several users
 	DefaultResponse string // Response when LLM returns empty
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
@@ -67,9 +78,50 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	registry.Register(tools.NewListDirTool(workspace, restrict))
 	registry.Register(tools.NewEditFileTool(workspace, restrict))
 	registry.Register(tools.NewAppendFileTool(workspace, restrict))
+	registry.Register(tools.NewGlobTool(workspace, restrict))
+	registry.Register(tools.NewGrepTool(workspace, restrict))
 
 	// Shell execution
-	registry.Register(tools.NewExecTool(workspace, restrict))
+	execTool, err := tools.NewExecToolFromConfig(workspace, restrict, cfg.Tools.Exec)
+	switch {
+	case err != nil && cfg.Tools.Exec.Sandbox.Enabled:
+		// Never fall back to running commands on the host
+		logger.WarnCF("agent", "Exec sandbox unavailable, exec tool disabled",
+			map[string]interface{}{"error": err.Error()})
+	case err != nil:
+		logger.WarnCF("agent", "Invalid exec tool config, using defaults",
+			map[string]interface{}{"error": err.Error()})
+		registry.Register(tools.NewExecTool(workspace, restrict))
+	default:
+		registry.Register(execTool)
+	}
+
+	for _, gitTool := range tools.NewGitTools(workspace, restrict, cfg.Tools.Git) {
+		registry.Register(gitTool)
+	}
+	for _, githubTool := range tools.NewGitHubTools(cfg.Tools.GitHub) {
+		registry.Register(githubTool)
+	}
+	if sshTool, err := tools.NewSSHToolFromConfig(cfg.Tools.SSH); err != nil {
+		logger.WarnCF("agent", "Invalid ssh tool config, ssh tool disabled",
+			map[string]interface{}{"error": err.Error()})
+	} else if sshTool != nil {
+		registry.Register(sshTool)
+	}
+	if sqlTools, err := tools.NewSQLTools(cfg.Tools.SQL); err != nil {
+		logger.WarnCF("agent", "Invalid sql tool config, sql tools disabled",
+			map[string]interface{}{"error": err.Error()})
+	} else {
+		for _, sqlTool := range sqlTools {
+			registry.Register(sqlTool)
+		}
+	}
+	if httpTool, err := tools.NewHTTPRequestToolFromConfig(cfg.Tools.HTTP); err != nil {
+		logger.WarnCF("agent", "Invalid http tool config, http_request tool disabled",
+			map[string]interface{}{"error": err.Error()})
+	} else if httpTool != nil {
+		registry.Register(httpTool)
+	}
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -99,6 +151,8 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	})
 	registry.Register(messageTool)
 
+	registry.ApplyConfig(cfg.Tools, workspace)
+
 	return registry
 }
 
@@ -108,11 +162,17 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
+	// Without a configured model, use the provider's default
+	model := cfg.Agents.Defaults.Model
+	if model == "" && provider != nil {
+		model = provider.GetDefaultModel()
+	}
+
 	// Create tool registry for main agent
 	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
 
 	// Create subagent manager with its own tool registry
-	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
+	subagentManager := tools.NewSubagentManager(provider, model, workspace, msgBus)
 	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
@@ -125,7 +185,66 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	subagentTool := tools.NewSubagentTool(subagentManager)
 	toolsRegistry.Register(subagentTool)
 
-	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	// Both registries share one gate so subagents cannot bypass approvals
+	approvals := tools.NewApprovalGateFromConfig(cfg.Tools.Approval, filepath.Join(workspace, "state", "approvals.json"))
+	if approvals != nil {
+		toolsRegistry.SetApprovalGate(approvals)
+		subagentTools.SetApprovalGate(approvals)
+	}
+	// and subagents' tool calls are audited with the agent's
+	audit := tools.NewAuditLogFromConfig(cfg.Audit, workspace)
+	toolsRegistry.SetAuditLog(audit)
+	subagentTools.SetAuditLog(audit)
+	policy := tools.NewPolicyFromConfig(cfg.Policy, workspace)
+	toolsRegistry.SetPolicy(policy)
+	subagentTools.SetPolicy(policy)
+
+	// Tools from MCP servers are available to subagents too
+	mcpCtx, cancelMCP := context.WithTimeout(context.Background(), 30*time.Second)
+	mcpManager := mcp.Connect(mcpCtx, cfg.MCP)
+	cancelMCP()
+	for _, tool := range mcpManager.Tools() {
+		toolsRegistry.Register(tool)
+		subagentTools.Register(tool)
+	}
+
+	// Plugins add tools but never replace built-in ones
+	pluginCtx, cancelPlugins := context.WithTimeout(context.Background(), 30*time.Second)
+	pluginManager := plugins.Load(pluginCtx, cfg.Plugins, workspace)
+	cancelPlugins()
+	for _, tool := range pluginManager.Tools() {
+		if _, exists := toolsRegistry.Get(tool.Name()); exists {
+			logger.WarnCF("agent", "Plugin tool has the name of an existing tool, skipped",
+				map[string]interface{}{
+					"tool": tool.Name(),
+				})
+			continue
+		}
+		toolsRegistry.Register(tool)
+		subagentTools.Register(tool)
+	}
+
+	if cfg.Tools.RAG.Enabled {
+		if retrieveTool, err := rag.NewRetrieveToolFromConfig(cfg.Tools.RAG, workspace, provider); err != nil {
+			logger.WarnCF("agent", "Retrieve tool disabled",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		} else {
+			toolsRegistry.Register(retrieveTool)
+			subagentTools.Register(retrieveTool)
+		}
+	}
+
+	sessionsDir := filepath.Join(workspace, "sessions")
+	sessionsManager, err := session.OpenSessionManager(cfg.Agents.Defaults.SessionFormat, sessionsDir)
+	if err != nil {
+		logger.WarnCF("agent", "Falling back to JSON session storage",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		sessionsManager = session.NewSessionManager(sessionsDir)
+	}
 
 	// Create state manager for atomic state persistence
 	stateManager := state.NewManager(workspace)
@@ -133,19 +252,26 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
+	contextBuilder.SetProvider(cfg.Agents.Defaults.Provider)
 
 	return &AgentLoop{
 		bus:            msgBus,
 		provider:       provider,
 		workspace:      workspace,
-		model:          cfg.Agents.Defaults.Model,
+		model:          model,
 		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		maxParallel:    cfg.Agents.Defaults.MaxParallelTools,
 		sessions:       sessionsManager,
 		state:          stateManager,
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
+		approvals:      approvals,
+		mcp:            mcpManager,
+		plugins:        pluginManager,
 		summarizing:    sync.Map{},
+		codeExecution:  cfg.Agents.Defaults.CodeExecution,
+		autoContinue:   cfg.Agents.Defaults.AutoContinue,
 	}
 }
 
@@ -193,12 +319,35 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	al.mcp.Close()
+	al.plugins.Close()
+}
+
+// Sessions returns the agent's conversation store, for embedding apps that
+// list, inspect or resume sessions.
+func (al *AgentLoop) Sessions() *session.SessionManager {
+	return al.sessions
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// SetApprover sets who confirms tool calls that need approval, replacing the
+// configured webhook. It does nothing when approvals are disabled.
+func (al *AgentLoop) SetApprover(approver tools.Approver) {
+	if al.approvals != nil {
+		al.approvals.SetApprover(approver)
+	}
+}
+
+// SetToolOutput makes the output of tools that stream it, such as exec, go
+// to fn while they run, for showing progress on long builds or test runs.
+// The model still only sees the final result.
+func (al *AgentLoop) SetToolOutput(fn func(tool, chunk string)) {
+	al.toolOutput = fn
+}
+
 // RecordLastChannel records the last active channel for this workspace.
 // This uses the atomic state save mechanism to prevent data loss on crash.
 func (al *AgentLoop) RecordLastChannel(channel string) error {
@@ -228,8 +377,10 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 }
 
 // ProcessHeartbeat processes a heartbeat request without session history.
-// Each heartbeat is independent and doesn't accumulate context.
+// Each heartbeat is independent and doesn't accumulate context. Its model
+// calls wait behind interactive ones on a busy provider.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
+	ctx = providers.WithPriority(ctx, providers.PriorityBatch)
 	return al.runAgentLoop(ctx, processOptions{
 		SessionKey:      "heartbeat",
 		Channel:         channel,
@@ -269,12 +420,26 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
 		UserMessage:     msg.Content,
+		SenderName:      senderName(msg),
 		DefaultResponse: "I've completed processing but have no response to give.",
 		EnableSummary:   true,
 		SendResponse:    false,
 	})
 }
 
+// senderNameKeys are the metadata keys channels put the sender's display
+// name in, in order of preference.
+var senderNameKeys = []string{"sender_name", "user_name", "username", "first_name"}
+
+func senderName(msg bus.InboundMessage) string {
+	for _, key := range senderNameKeys {
+		if name := msg.Metadata[key]; name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Verify this is a system message
 	if msg.Channel != "system" {
@@ -360,8 +525,11 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		opts.ChatID,
 	)
 
+	messages[len(messages)-1].Name = opts.SenderName
+
 	// 3. Save user message to session
-	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	al.sessions.AddFullMessage(opts.SessionKey, providers.Message{Role: "user", Content: opts.UserMessage, Name: opts.SenderName})
+	al.sessions.SetModel(opts.SessionKey, al.model)
 
 	// 4. Run LLM iteration loop
 	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
@@ -410,167 +578,111 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 // runLLMIteration executes the LLM call loop with tool handling.
 // Returns the final content, iteration count, and any error.
 func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
-	iteration := 0
-	var finalContent string
-
-	for iteration < al.maxIterations {
-		iteration++
-
-		logger.DebugCF("agent", "LLM iteration",
-			map[string]interface{}{
-				"iteration": iteration,
-				"max":       al.maxIterations,
-			})
-
-		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
-
-		// Log LLM request details
-		logger.DebugCF("agent", "LLM request",
-			map[string]interface{}{
-				"iteration":         iteration,
-				"model":             al.model,
-				"messages_count":    len(messages),
-				"tools_count":       len(providerToolDefs),
-				"max_tokens":        al.contextWindow,
-				"temperature":       0.7,
-				"system_prompt_len": len(messages[0].Content),
-			})
-
-		// Log full messages (detailed)
-		logger.DebugCF("agent", "Full LLM request",
-			map[string]interface{}{
-				"iteration":     iteration,
-				"messages_json": formatMessagesForLog(messages),
-				"tools_json":    formatToolsForLog(providerToolDefs),
-			})
-
-		// Call LLM
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-			"max_tokens":  al.contextWindow,
-			"temperature": 0.7,
-		})
-
-		if err != nil {
-			logger.ErrorCF("agent", "LLM call failed",
-				map[string]interface{}{
-					"iteration": iteration,
-					"error":     err.Error(),
-				})
-			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
-		}
-
-		// Check if no tool calls - we're done
-		if len(response.ToolCalls) == 0 {
-			finalContent = response.Content
-			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
-				map[string]interface{}{
-					"iteration":     iteration,
-					"content_chars": len(finalContent),
-				})
-			break
-		}
-
-		// Log tool calls
-		toolNames := make([]string, 0, len(response.ToolCalls))
-		for _, tc := range response.ToolCalls {
-			toolNames = append(toolNames, tc.Name)
-		}
-		logger.InfoCF("agent", "LLM requested tool calls",
-			map[string]interface{}{
-				"tools":     toolNames,
-				"count":     len(response.ToolCalls),
-				"iteration": iteration,
-			})
-
-		// Build assistant message with tool calls
-		assistantMsg := providers.Message{
-			Role:    "assistant",
-			Content: response.Content,
-		}
-		for _, tc := range response.ToolCalls {
-			argumentsJSON, _ := json.Marshal(tc.Arguments)
-			assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, providers.ToolCall{
-				ID:        tc.ID,
-				Name:      tc.Name, // Set top-level Name for Claude provider
-				Type:      "function",
-				Arguments: tc.Arguments, // Keep raw arguments for Claude
-				Function: &providers.FunctionCall{
-					Name:      tc.Name,
-					Arguments: string(argumentsJSON),
-				},
-			})
+	ctx = tools.WithAuditSession(ctx, opts.SessionKey, al.model)
+	options := map[string]interface{}{
+		"max_tokens":  al.contextWindow,
+		"temperature": 0.7,
+	}
+	if servers := al.mcp.ConnectorServers(ctx); len(servers) > 0 {
+		options[providers.OptionMCPServers] = servers
+	}
+	if al.autoContinue > 0 {
+		options[providers.OptionAutoContinue] = al.autoContinue
+	}
+	if al.codeExecution {
+		options[providers.OptionCodeExecution] = true
+		if container, ok := al.containers.Load(opts.SessionKey); ok {
+			options[providers.OptionContainer] = container
 		}
-		messages = append(messages, assistantMsg)
-
-		// Save assistant message with tool calls to session
-		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
-
-		// Execute tool calls
-		for _, tc := range response.ToolCalls {
-			// Log tool call with arguments preview
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
-				map[string]interface{}{
-					"tool":      tc.Name,
-					"iteration": iteration,
-				})
-
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
-				}
+	}
+	runner := &Runner{
+		Provider:         al.provider,
+		Tools:            al.tools,
+		Model:            al.model,
+		MaxIterations:    al.maxIterations,
+		MaxParallelTools: al.maxParallel,
+		Options:          options,
+		Channel:          opts.Channel,
+		ChatID:           opts.ChatID,
+		// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+		// Instead, they notify the agent via PublishInbound, and the agent decides
+		// whether to forward the result to the user (in processSystemMessage).
+		AsyncCallback: func(callbackCtx context.Context, result *tools.ToolResult) {
+			// Log the async completion but don't send directly to user
+			// The agent will handle user notification via processSystemMessage
+			if !result.Silent && result.ForUser != "" {
+				logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+					map[string]interface{}{
+						"content_len": len(result.ForUser),
+					})
 			}
-
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
-
+		},
+		Hooks: RunnerHooks{
+			BeforeLLMCall: func(ctx context.Context, iteration int, messages []providers.Message) {
+				providerToolDefs := al.tools.ToProviderDefs()
+				logger.DebugCF("agent", "LLM request",
+					map[string]interface{}{
+						"iteration":         iteration,
+						"max":               al.maxIterations,
+						"model":             al.model,
+						"messages_count":    len(messages),
+						"tools_count":       len(providerToolDefs),
+						"max_tokens":        al.contextWindow,
+						"temperature":       0.7,
+						"system_prompt_len": len(messages[0].Content),
+					})
+				logger.DebugCF("agent", "Full LLM request",
+					map[string]interface{}{
+						"iteration":     iteration,
+						"messages_json": formatMessagesForLog(messages),
+						"tools_json":    formatToolsForLog(providerToolDefs),
+					})
+			},
+			AfterLLMCall: func(ctx context.Context, iteration int, response *providers.LLMResponse) {
+				if audit := al.tools.AuditLog(); audit != nil {
+					audit.RecordResponse(ctx, response)
+				}
+			},
+			// Save assistant tool calls and tool results to session
+			OnMessage: func(msg providers.Message) {
+				al.sessions.AddFullMessage(opts.SessionKey, msg)
+			},
 			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+			AfterToolCall: func(ctx context.Context, call providers.ToolCall, result *tools.ToolResult) {
+				if result.Silent || result.ForUser == "" || !opts.SendResponse {
+					return
+				}
 				al.bus.PublishOutbound(bus.OutboundMessage{
 					Channel: opts.Channel,
 					ChatID:  opts.ChatID,
-					Content: toolResult.ForUser,
+					Content: result.ForUser,
 				})
 				logger.DebugCF("agent", "Sent tool result to user",
 					map[string]interface{}{
-						"tool":        tc.Name,
-						"content_len": len(toolResult.ForUser),
+						"tool":        call.Name,
+						"content_len": len(result.ForUser),
 					})
-			}
-
-			// Determine content for LLM based on tool result
-			contentForLLM := toolResult.ForLLM
-			if contentForLLM == "" && toolResult.Err != nil {
-				contentForLLM = toolResult.Err.Error()
-			}
-
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    contentForLLM,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
+			},
+		},
+	}
 
-			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+	if al.toolOutput != nil {
+		runner.Hooks.OnToolOutput = func(call providers.ToolCall, chunk string) {
+			al.toolOutput(call.Name, chunk)
 		}
 	}
 
-	return finalContent, iteration, nil
+	result, err := runner.RunMessages(ctx, messages)
+	al.sessions.AddUsage(opts.SessionKey, result.Usage)
+	if result.Container != "" {
+		al.containers.Store(opts.SessionKey, result.Container)
+	}
+	if err != nil {
+		return "", result.Iterations, err
+	}
+	return result.Content, result.Iterations, nil
 }
 
-// updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
 	if tool, ok := al.tools.Get("message"); ok {
@@ -594,7 +706,7 @@ func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 func (al *AgentLoop) maybeSummarize(sessionKey string) {
 	newHistory := al.sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
-	threshold := al.contextWindow * 75 / 100
+	threshold := al.summaryWindow() * 75 / 100
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
 		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
@@ -724,7 +836,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 		s2, _ := al.summarizeBatch(ctx, part2, "")
 
 		// Merge them
-		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
+		mergePrompt := al.contextBuilder.render("summary_merge", map[string]interface{}{"First": s1, "Second": s2})
 		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
 			"max_tokens":  1024,
 			"temperature": 0.3,
@@ -749,16 +861,19 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	}
 }
 
+// summaryWindow is the context size summarization keeps the history within:
+// the configured max_tokens, or the model's context window when the model
+// catalog says it is smaller.
+func (al *AgentLoop) summaryWindow() int {
+	if entry, ok := providers.LookupModel(al.model); ok && entry.ContextWindow > 0 && entry.ContextWindow < al.contextWindow {
+		return entry.ContextWindow
+	}
+	return al.contextWindow
+}
+
 // summarizeBatch summarizes a batch of messages.
 func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Message, existingSummary string) (string, error) {
-	prompt := "Provide a concise summary of this conversation segment, preserving core context and key points.\n"
-	if existingSummary != "" {
-		prompt += "Existing context: " + existingSummary + "\n"
-	}
-	prompt += "\nCONVERSATION:\n"
-	for _, m := range batch {
-		prompt += fmt.Sprintf("%s: %s\n", m.Role, m.Content)
-	}
+	prompt := al.contextBuilder.render("summarize", map[string]interface{}{"Existing": existingSummary, "Messages": batch})
 
 	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
 		"max_tokens":  1024,
@@ -0,0 +1,336 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// defaultMaxIterations bounds a Runner whose MaxIterations is unset.
+const defaultMaxIterations = 20
+
+// RunnerHooks observe and steer a Runner. Every hook is optional.
+type RunnerHooks struct {
+	// BeforeLLMCall runs before each provider call with the messages sent.
+	BeforeLLMCall func(ctx context.Context, iteration int, messages []providers.Message)
+	// AfterLLMCall runs after each successful provider call.
+	AfterLLMCall func(ctx context.Context, iteration int, response *providers.LLMResponse)
+	// OnStreamEvent, when set, makes the Runner stream provider calls and
+	// receives their text and tool call events as they arrive. Runs with a
+	// response schema are not streamed, as their replies are checked, and
+	// may be asked for again, before they are final.
+	OnStreamEvent func(ev providers.StreamEvent)
+	// BeforeToolCall runs before a tool executes. Returning an error skips
+	// the tool and reports the error to the model as the tool result.
+	// BeforeToolCall and AfterToolCall may run concurrently when several
+	// tool calls execute in parallel.
+	BeforeToolCall func(ctx context.Context, call providers.ToolCall) error
+	// AfterToolCall runs with the result of every tool call.
+	AfterToolCall func(ctx context.Context, call providers.ToolCall, result *tools.ToolResult)
+	// OnToolOutput receives the output of tools that stream it, such as
+	// exec, while they run. It is for display only: the model still gets
+	// the final result. Calls for one tool call arrive in order.
+	OnToolOutput func(call providers.ToolCall, chunk string)
+	// OnMessage runs for every message the Runner appends to the
+	// conversation: assistant messages with tool calls and tool results.
+	// The final assistant reply is returned in RunResult instead.
+	OnMessage func(msg providers.Message)
+}
+
+// Runner drives the agent loop: it calls the provider, executes the tool
+// calls it asks for, appends the results and repeats until the model answers
+// without tool calls, MaxIterations is reached or ctx is cancelled.
+type Runner struct {
+	Provider providers.LLMProvider
+	Tools    *tools.ToolRegistry
+	Model    string
+	// Options are passed to the provider; nil uses a temperature of 0.7
+	// and the model's default max_tokens. With
+	// providers.OptionResponseSchema the final answer must be JSON matching
+	// the schema; see providers.ChatStructured.
+	Options       map[string]interface{}
+	MaxIterations int
+	// MaxParallelTools bounds how many tool calls from one response run at
+	// once; 1 runs them in order. Zero uses tools.DefaultMaxParallelTools.
+	// Per-tool limits set on the registry still apply.
+	MaxParallelTools int
+	// MaxArgumentRepairs bounds how many responses in a row may contain
+	// tool calls whose arguments are not valid JSON. Those calls are not
+	// run; the model gets an error asking it to send them again. Zero uses
+	// tools.DefaultMaxArgumentRepairs.
+	MaxArgumentRepairs int
+
+	// SystemPrompt is prepended by Run; RunMessages uses messages as given.
+	SystemPrompt string
+
+	// Channel and ChatID are passed to contextual tools.
+	Channel string
+	ChatID  string
+	// AsyncCallback receives the results of async tools.
+	AsyncCallback tools.AsyncCallback
+
+	Hooks RunnerHooks
+}
+
+// RunResult is the outcome of a Runner run.
+type RunResult struct {
+	// Content is the final assistant reply; empty when the run stopped at
+	// MaxIterations.
+	Content    string
+	Iterations int
+	// Messages is the full conversation, including the messages passed in.
+	Messages []providers.Message
+	// Container is the code execution container the run last used, for
+	// passing to later runs in the same conversation as
+	// providers.OptionContainer.
+	Container string
+	// Usage sums the token usage reported for every provider call.
+	Usage providers.UsageInfo
+	// MaxIterationsReached is set when the loop stopped before the model
+	// gave a final answer.
+	MaxIterationsReached bool
+}
+
+// NewRunner creates a Runner for provider with the tools in registry.
+func NewRunner(provider providers.LLMProvider, registry *tools.ToolRegistry, model string) *Runner {
+	return &Runner{
+		Provider:      provider,
+		Tools:         registry,
+		Model:         model,
+		MaxIterations: defaultMaxIterations,
+	}
+}
+
+// Run works on goal as a single user message.
+func (r *Runner) Run(ctx context.Context, goal string) (*RunResult, error) {
+	var messages []providers.Message
+	if r.SystemPrompt != "" {
+		messages = append(messages, providers.Message{Role: "system", Content: r.SystemPrompt})
+	}
+	messages = append(messages, providers.Message{Role: "user", Content: goal})
+	return r.RunMessages(ctx, messages)
+}
+
+// RunMessages continues the conversation in messages.
+func (r *Runner) RunMessages(ctx context.Context, messages []providers.Message) (*RunResult, error) {
+	maxIterations := r.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxIterations
+	}
+	// The run sets its own options, such as the code execution container,
+	// so it works on a copy that other runs never see
+	options := map[string]interface{}{}
+	if r.Options == nil {
+		options["temperature"] = 0.7
+	}
+	for k, v := range r.Options {
+		options[k] = v
+	}
+
+	// Leave out tools and images the model cannot take
+	caps := providers.CapabilitiesOf(r.Provider, r.Model)
+
+	result := &RunResult{Messages: messages}
+	repairs := tools.ArgumentRepairs{Max: r.MaxArgumentRepairs}
+	for result.Iterations < maxIterations {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Iterations++
+		iteration := result.Iterations
+
+		var toolDefs []providers.ToolDefinition
+		if r.Tools != nil && caps.Tools {
+			toolDefs = r.Tools.ToProviderDefs()
+		}
+
+		if r.Hooks.BeforeLLMCall != nil {
+			r.Hooks.BeforeLLMCall(ctx, iteration, result.Messages)
+		}
+		response, err := r.chat(ctx, providers.AdaptMessages(result.Messages, caps), toolDefs, options)
+		var invalid *providers.SchemaValidationError
+		if errors.As(err, &invalid) {
+			logger.WarnCF("agent", "Response does not match the requested schema",
+				map[string]interface{}{
+					"iteration": iteration,
+					"attempts":  invalid.Attempts,
+					"errors":    invalid.Errors,
+				})
+			result.Usage.Add(response.Usage)
+			return result, err
+		}
+		if err != nil {
+			logger.ErrorCF("agent", "LLM call failed",
+				map[string]interface{}{
+					"iteration": iteration,
+					"error":     err.Error(),
+				})
+			return result, fmt.Errorf("LLM call failed: %w", err)
+		}
+		result.Usage.Add(response.Usage)
+		if r.Hooks.AfterLLMCall != nil {
+			r.Hooks.AfterLLMCall(ctx, iteration, response)
+		}
+		if container := response.Metadata[providers.MetadataContainer]; container != "" {
+			// Later calls run code in the same container, keeping its files
+			options[providers.OptionContainer] = container
+			result.Container = container
+		}
+
+		if len(response.ToolCalls) == 0 {
+			result.Content = response.Content
+			result.Messages = append(result.Messages, providers.Message{Role: "assistant", Content: response.Content})
+			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
+				map[string]interface{}{
+					"iteration":     iteration,
+					"content_chars": len(response.Content),
+				})
+			return result, nil
+		}
+
+		toolNames := make([]string, 0, len(response.ToolCalls))
+		for _, tc := range response.ToolCalls {
+			toolNames = append(toolNames, tc.Name)
+		}
+		logger.InfoCF("agent", "LLM requested tool calls",
+			map[string]interface{}{
+				"tools":     toolNames,
+				"count":     len(response.ToolCalls),
+				"iteration": iteration,
+			})
+
+		r.appendMessage(result, assistantToolCallMessage(response))
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		toolResults := r.executeTools(ctx, response.ToolCalls, iteration)
+
+		// Results are appended in call order so each follows its request
+		for i, tc := range response.ToolCalls {
+			r.appendMessage(result, toolResults[i].ToolMessage(tc.ID))
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := repairs.Check(response.ToolCalls); err != nil {
+			return result, err
+		}
+	}
+
+	result.MaxIterationsReached = true
+	logger.WarnCF("agent", "Reached max tool iterations without a final answer",
+		map[string]interface{}{
+			"max": maxIterations,
+		})
+	return result, nil
+}
+
+// chat makes one provider call, streaming it to the OnStreamEvent hook when
+// there is one.
+func (r *Runner) chat(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if r.Hooks.OnStreamEvent == nil || options[providers.OptionResponseSchema] != nil {
+		return providers.ChatStructured(ctx, r.Provider, messages, toolDefs, r.Model, options)
+	}
+	events, err := providers.ChatStream(ctx, r.Provider, messages, toolDefs, r.Model, options)
+	if err != nil {
+		return nil, err
+	}
+	return providers.ConsumeStream(events, r.Hooks.OnStreamEvent)
+}
+
+func (r *Runner) appendMessage(result *RunResult, msg providers.Message) {
+	result.Messages = append(result.Messages, msg)
+	if r.Hooks.OnMessage != nil {
+		r.Hooks.OnMessage(msg)
+	}
+}
+
+// executeTools runs the calls of one response, in parallel where the
+// registry's limits allow, and returns their results in call order.
+func (r *Runner) executeTools(ctx context.Context, calls []providers.ToolCall, iteration int) []*tools.ToolResult {
+	run := func(ctx context.Context, tc providers.ToolCall) *tools.ToolResult {
+		if err := ctx.Err(); err != nil {
+			return tools.ErrorResult(fmt.Sprintf("tool %s was not run: %v", tc.Name, err)).WithError(err)
+		}
+		return r.executeTool(ctx, tc, iteration)
+	}
+
+	if r.Tools == nil {
+		results := make([]*tools.ToolResult, len(calls))
+		for i, tc := range calls {
+			results[i] = run(ctx, tc)
+		}
+		return results
+	}
+	return r.Tools.ExecuteParallel(ctx, calls, r.MaxParallelTools, run)
+}
+
+func (r *Runner) executeTool(ctx context.Context, tc providers.ToolCall, iteration int) *tools.ToolResult {
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	argsPreview := utils.Truncate(string(argsJSON), 200)
+	logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+		map[string]interface{}{
+			"tool":      tc.Name,
+			"iteration": iteration,
+		})
+
+	var toolResult *tools.ToolResult
+	switch {
+	case r.Tools == nil:
+		toolResult = tools.ErrorResult("No tools available")
+	case tc.ArgumentsError != "":
+		logger.WarnCF("agent", "Tool call has malformed arguments",
+			map[string]interface{}{
+				"tool":  tc.Name,
+				"error": tc.ArgumentsError,
+			})
+		toolResult = tools.InvalidArgumentsResult(tc)
+	default:
+		if r.Hooks.BeforeToolCall != nil {
+			if err := r.Hooks.BeforeToolCall(ctx, tc); err != nil {
+				toolResult = tools.ErrorResult(fmt.Sprintf("tool %s was not run: %v", tc.Name, err)).WithError(err)
+				break
+			}
+		}
+		if r.Hooks.OnToolOutput != nil {
+			ctx = tools.WithOutputStream(ctx, func(chunk string) { r.Hooks.OnToolOutput(tc, chunk) })
+		}
+		toolResult = r.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, r.Channel, r.ChatID, r.AsyncCallback)
+	}
+
+	if r.Hooks.AfterToolCall != nil {
+		r.Hooks.AfterToolCall(ctx, tc, toolResult)
+	}
+	return toolResult
+}
+
+// assistantToolCallMessage records the tool calls of response in the shape
+// both OpenAI-compatible and Claude providers read back, along with any
+// reasoning the model needs to continue from.
+func assistantToolCallMessage(response *providers.LLMResponse) providers.Message {
+	msg := providers.Message{
+		Role:    "assistant",
+		Content: response.Content,
+	}
+	for _, tc := range response.ToolCalls {
+		argumentsJSON, _ := json.Marshal(tc.Arguments)
+		msg.ToolCalls = append(msg.ToolCalls, providers.ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Name, // Set top-level Name for Claude provider
+			Type:      "function",
+			Arguments: tc.Arguments, // Keep raw arguments for Claude
+			Function: &providers.FunctionCall{
+				Name:      tc.Name,
+				Arguments: string(argumentsJSON),
+			},
+		})
+	}
+	return providers.WithReasoning(msg, response)
+}
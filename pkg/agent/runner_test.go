@@ -0,0 +1,390 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// scriptedProvider returns its responses in order, repeating the last one.
+type scriptedProvider struct {
+	responses  []*providers.LLMResponse
+	calls      int
+	containers []interface{} // the container option of each call
+	options    map[string]interface{}
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.containers = append(p.containers, opts[providers.OptionContainer])
+	p.options = opts
+	i := p.calls
+	if i >= len(p.responses) {
+		i = len(p.responses) - 1
+	}
+	p.calls++
+	return p.responses[i], nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string {
+	return "scripted"
+}
+
+// countingTool records how often it ran.
+type countingTool struct {
+	runs int
+}
+
+func (t *countingTool) Name() string        { return "count" }
+func (t *countingTool) Description() string { return "Counts calls" }
+func (t *countingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *countingTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	t.runs++
+	return tools.NewToolResult("counted")
+}
+
+func toolCallResponse(id string) *providers.LLMResponse {
+	return &providers.LLMResponse{
+		ToolCalls: []providers.ToolCall{{ID: id, Name: "count", Arguments: map[string]interface{}{}}},
+		Usage:     &providers.UsageInfo{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}
+}
+
+func TestRunner_ExecutesToolsUntilAnswer(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		toolCallResponse("call_1"),
+		{Content: "done", Usage: &providers.UsageInfo{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}},
+	}}
+	tool := &countingTool{}
+	registry := tools.NewToolRegistry()
+	registry.Register(tool)
+
+	var appended []providers.Message
+	var toolResults []string
+	runner := NewRunner(provider, registry, "scripted")
+	runner.SystemPrompt = "be brief"
+	runner.Hooks = RunnerHooks{
+		OnMessage: func(msg providers.Message) { appended = append(appended, msg) },
+		AfterToolCall: func(ctx context.Context, call providers.ToolCall, result *tools.ToolResult) {
+			toolResults = append(toolResults, result.ForLLM)
+		},
+	}
+
+	result, err := runner.Run(context.Background(), "count once")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, ok := provider.options["max_tokens"]; ok {
+		t.Errorf("options = %v, want max_tokens left to the provider", provider.options)
+	}
+	if result.Content != "done" {
+		t.Errorf("Content = %q, want %q", result.Content, "done")
+	}
+	if result.Iterations != 2 {
+		t.Errorf("Iterations = %d, want 2", result.Iterations)
+	}
+	if tool.runs != 1 {
+		t.Errorf("tool ran %d times, want 1", tool.runs)
+	}
+	if result.Usage.TotalTokens != 35 {
+		t.Errorf("Usage.TotalTokens = %d, want 35", result.Usage.TotalTokens)
+	}
+
+	// system, user, assistant tool call, tool result, final answer
+	if len(result.Messages) != 5 {
+		t.Fatalf("len(Messages) = %d, want 5", len(result.Messages))
+	}
+	if result.Messages[3].Role != "tool" || result.Messages[3].ToolCallID != "call_1" {
+		t.Errorf("Messages[3] = %+v, want tool result for call_1", result.Messages[3])
+	}
+	if len(appended) != 2 {
+		t.Errorf("OnMessage called %d times, want 2", len(appended))
+	}
+	if len(toolResults) != 1 || toolResults[0] != "counted" {
+		t.Errorf("AfterToolCall results = %q, want [counted]", toolResults)
+	}
+}
+
+func TestRunner_MaxIterations(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{toolCallResponse("call")}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{})
+
+	runner := NewRunner(provider, registry, "scripted")
+	runner.MaxIterations = 3
+
+	result, err := runner.Run(context.Background(), "loop forever")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.MaxIterationsReached {
+		t.Error("MaxIterationsReached = false, want true")
+	}
+	if provider.calls != 3 {
+		t.Errorf("provider called %d times, want 3", provider.calls)
+	}
+	if result.Content != "" {
+		t.Errorf("Content = %q, want empty", result.Content)
+	}
+}
+
+func TestRunner_BeforeToolCallCanSkipTool(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		toolCallResponse("call_1"),
+		{Content: "ok"},
+	}}
+	tool := &countingTool{}
+	registry := tools.NewToolRegistry()
+	registry.Register(tool)
+
+	runner := NewRunner(provider, registry, "scripted")
+	runner.Hooks.BeforeToolCall = func(ctx context.Context, call providers.ToolCall) error {
+		return errors.New("denied")
+	}
+
+	result, err := runner.Run(context.Background(), "count")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if tool.runs != 0 {
+		t.Errorf("tool ran %d times, want 0", tool.runs)
+	}
+	toolMsg := result.Messages[2]
+	if toolMsg.Role != "tool" || toolMsg.Content == "" {
+		t.Errorf("tool message = %+v, want an error result", toolMsg)
+	}
+}
+
+func TestRunner_Cancellation(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{{Content: "never"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewRunner(provider, nil, "scripted").Run(ctx, "hello")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("provider called %d times after cancellation, want 0", provider.calls)
+	}
+}
+
+// blindProvider reports that it takes neither tools nor images, recording
+// what it was sent.
+type blindProvider struct {
+	scriptedProvider
+	messages []providers.Message
+	tools    []providers.ToolDefinition
+}
+
+func (p *blindProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.messages, p.tools = messages, tools
+	return p.scriptedProvider.Chat(ctx, messages, tools, model, opts)
+}
+
+func (p *blindProvider) Capabilities(model string) providers.Capabilities {
+	return providers.Capabilities{}
+}
+
+func TestRunner_LeavesOutUnsupportedContent(t *testing.T) {
+	provider := &blindProvider{scriptedProvider: scriptedProvider{responses: []*providers.LLMResponse{{Content: "a cat"}}}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{})
+
+	runner := NewRunner(provider, registry, "scripted")
+	message := providers.NewMessage("user", providers.TextPart("What is this?"), providers.ImagePart("image/png", []byte("png")))
+	if _, err := runner.RunMessages(context.Background(), []providers.Message{message}); err != nil {
+		t.Fatalf("RunMessages() error = %v", err)
+	}
+	if len(provider.tools) != 0 {
+		t.Errorf("sent %d tools to a provider without tool support", len(provider.tools))
+	}
+	if parts := provider.messages[0].Parts; parts[1].Type != providers.ContentText {
+		t.Errorf("parts = %+v, want the image replaced with text", parts)
+	}
+}
+
+func TestRunner_ReusesCodeExecutionContainer(t *testing.T) {
+	first := toolCallResponse("call_1")
+	first.Metadata = map[string]string{providers.MetadataContainer: "container_1"}
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{first, {Content: "done"}}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{})
+
+	runner := &Runner{Provider: provider, Tools: registry, Model: "scripted", Options: map[string]interface{}{"max_tokens": 100}}
+	result, err := runner.Run(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(provider.containers) != 2 || provider.containers[0] != nil || provider.containers[1] != "container_1" {
+		t.Errorf("containers = %v, want the second call in container_1", provider.containers)
+	}
+	if result.Container != "container_1" {
+		t.Errorf("Container = %q, want container_1", result.Container)
+	}
+
+	// A second run on the same Runner starts without the first one's container
+	provider.calls = 0
+	provider.containers = nil
+	if _, err := runner.Run(context.Background(), "hi again"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if provider.containers[0] != nil {
+		t.Errorf("second run started in %v, want no container", provider.containers[0])
+	}
+	if len(runner.Options) != 1 || runner.Options["max_tokens"] != 100 {
+		t.Errorf("Options = %v, want the caller's options unchanged", runner.Options)
+	}
+}
+
+// chattyTool streams its progress before returning a short result.
+type chattyTool struct{ countingTool }
+
+func (t *chattyTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	if w := tools.StreamWriter(ctx); w != nil {
+		w.Write([]byte("step 1\n"))
+		w.Write([]byte("step 2\n"))
+	}
+	return tools.NewToolResult("counted")
+}
+
+func TestRunner_StreamsToolOutput(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		toolCallResponse("call_1"),
+		{Content: "done"},
+	}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&chattyTool{})
+
+	var streamed string
+	runner := NewRunner(provider, registry, "scripted")
+	runner.Hooks.OnToolOutput = func(call providers.ToolCall, chunk string) {
+		if call.ID != "call_1" {
+			t.Errorf("output attributed to %s", call.ID)
+		}
+		streamed += chunk
+	}
+
+	result, err := runner.Run(context.Background(), "count")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if streamed != "step 1\nstep 2\n" {
+		t.Errorf("streamed %q", streamed)
+	}
+	if toolMsg := result.Messages[2]; toolMsg.Content != "counted" {
+		t.Errorf("tool message = %q, want only the final result", toolMsg.Content)
+	}
+}
+
+func TestRunner_StreamsResponses(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		toolCallResponse("call_1"),
+		{Content: "done"},
+	}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{})
+
+	var events []string
+	runner := NewRunner(provider, registry, "scripted")
+	runner.Hooks.OnStreamEvent = func(ev providers.StreamEvent) {
+		switch ev.Type {
+		case providers.StreamEventText:
+			events = append(events, "text:"+ev.Text)
+		case providers.StreamEventToolCallStart:
+			events = append(events, "tool:"+ev.ToolCall.Name)
+		}
+	}
+
+	result, err := runner.Run(context.Background(), "count")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := strings.Join(events, ","); got != "tool:count,text:done" {
+		t.Errorf("events = %s, want the tool call then the reply", got)
+	}
+	if result.Content != "done" || result.Iterations != 2 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func malformedCallResponse(id string) *providers.LLMResponse {
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+		ID: id, Name: "count",
+		Arguments:      map[string]interface{}{"raw": `{"n": 1`},
+		ArgumentsError: "unexpected end of JSON input",
+	}}}
+}
+
+func TestRunner_AsksForMalformedArgumentsAgain(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		malformedCallResponse("call_1"),
+		toolCallResponse("call_2"),
+		{Content: "done"},
+	}}
+	tool := &countingTool{}
+	registry := tools.NewToolRegistry()
+	registry.Register(tool)
+
+	result, err := NewRunner(provider, registry, "scripted").Run(context.Background(), "count")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if tool.runs != 1 {
+		t.Errorf("tool ran %d times, want only for the repaired call", tool.runs)
+	}
+	repair := result.Messages[2]
+	if repair.ToolCallID != "call_1" || !strings.Contains(repair.Content, `"error":"invalid_arguments"`) {
+		t.Errorf("repair message = %+v", repair)
+	}
+	if result.Content != "done" {
+		t.Errorf("Content = %q", result.Content)
+	}
+}
+
+func TestRunner_GivesUpOnMalformedArguments(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{malformedCallResponse("call_1")}}
+	registry := tools.NewToolRegistry()
+	registry.Register(&countingTool{})
+
+	runner := NewRunner(provider, registry, "scripted")
+	runner.MaxArgumentRepairs = 2
+	result, err := runner.Run(context.Background(), "count")
+	if err == nil || !strings.Contains(err.Error(), "malformed arguments") {
+		t.Fatalf("Run() error = %v, want it to give up", err)
+	}
+	if provider.calls != 3 {
+		t.Errorf("provider called %d times, want the first try and 2 repairs", provider.calls)
+	}
+	// Every call still has its result, so the conversation can go on
+	if last := result.Messages[len(result.Messages)-1]; last.Role != "tool" {
+		t.Errorf("last message = %+v, want a tool result", last)
+	}
+}
+
+func TestRunner_ResponseSchema(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{{Content: "forty-two"}}}
+	runner := NewRunner(provider, tools.NewToolRegistry(), "scripted")
+	runner.Options = map[string]interface{}{
+		providers.OptionResponseSchema: map[string]interface{}{"type": "integer"},
+		providers.OptionSchemaRetries:  1,
+	}
+
+	_, err := runner.Run(context.Background(), "What is 6 times 7?")
+	var invalid *providers.SchemaValidationError
+	if !errors.As(err, &invalid) || invalid.Attempts != 2 {
+		t.Fatalf("Run() error = %v, want a SchemaValidationError after 2 attempts", err)
+	}
+
+	provider.responses = []*providers.LLMResponse{{Content: "42"}}
+	result, err := runner.Run(context.Background(), "What is 6 times 7?")
+	if err != nil || result.Content != "42" {
+		t.Errorf("Run() = %q, %v", result.Content, err)
+	}
+}
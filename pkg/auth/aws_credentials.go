@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentialsProvider supplies AWS credentials for request signing.
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+// errNoAWSCredentials is returned by chain links that have nothing to offer.
+var errNoAWSCredentials = errors.New("no AWS credentials found")
+
+// AWSCredentialsFunc adapts a function to AWSCredentialsProvider.
+type AWSCredentialsFunc func(ctx context.Context) (AWSCredentials, error)
+
+func (f AWSCredentialsFunc) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return f(ctx)
+}
+
+// StaticAWSCredentials returns fixed access keys.
+func StaticAWSCredentials(accessKeyID, secretAccessKey, sessionToken string) AWSCredentialsProvider {
+	return AWSCredentialsFunc(func(context.Context) (AWSCredentials, error) {
+		return AWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}, nil
+	})
+}
+
+// DefaultAWSCredentials returns the standard AWS credentials chain, in the
+// order the AWS SDKs use: environment variables, web identity (EKS), the
+// shared config and credentials files including role_arn profiles, the ECS
+// container endpoint and finally the EC2 instance metadata service. The
+// result is cached until shortly before it expires.
+func DefaultAWSCredentials() AWSCredentialsProvider {
+	return NewCachedAWSCredentials(awsCredentialsChain{
+		AWSCredentialsFunc(envAWSCredentials),
+		AWSCredentialsFunc(webIdentityAWSCredentials),
+		AWSCredentialsFunc(func(ctx context.Context) (AWSCredentials, error) {
+			return profileAWSCredentials(ctx, awsProfileName())
+		}),
+		AWSCredentialsFunc(containerAWSCredentials),
+		AWSCredentialsFunc(imdsAWSCredentials),
+	})
+}
+
+type awsCredentialsChain []AWSCredentialsProvider
+
+func (c awsCredentialsChain) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	for _, p := range c {
+		creds, err := p.Retrieve(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, errNoAWSCredentials) {
+			return AWSCredentials{}, err
+		}
+	}
+	return AWSCredentials{}, errNoAWSCredentials
+}
+
+// CachedAWSCredentials wraps a provider and reuses its result until the
+// credentials are close to expiry.
+type CachedAWSCredentials struct {
+	provider AWSCredentialsProvider
+	mu       sync.Mutex
+	creds    AWSCredentials
+}
+
+func NewCachedAWSCredentials(provider AWSCredentialsProvider) *CachedAWSCredentials {
+	return &CachedAWSCredentials{provider: provider}
+}
+
+func (c *CachedAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.creds.AccessKeyID != "" && !c.creds.expired() {
+		return c.creds, nil
+	}
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	c.creds = creds
+	return creds, nil
+}
+
+func envAWSCredentials(context.Context) (AWSCredentials, error) {
+	id := os.Getenv("AWS_ACCESS_KEY_ID")
+	secret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+	return AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+}
+
+func webIdentityAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if tokenFile == "" || roleARN == "" {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("reading web identity token: %w", err)
+	}
+	return assumeRoleWithWebIdentity(ctx, ResolveAWSRegion(), roleARN, os.Getenv("AWS_ROLE_SESSION_NAME"), strings.TrimSpace(string(token)))
+}
+
+// profileAWSCredentials reads static keys for profile from the shared
+// credentials and config files, assuming role_arn via source_profile when set.
+func profileAWSCredentials(ctx context.Context, profile string) (AWSCredentials, error) {
+	settings := awsProfileSettings(profile)
+	if settings == nil {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+
+	if roleARN := settings["role_arn"]; roleARN != "" {
+		source := settings["source_profile"]
+		if source == "" || source == profile {
+			return AWSCredentials{}, fmt.Errorf("AWS profile %q: role_arn requires a different source_profile", profile)
+		}
+		sourceCreds, err := profileAWSCredentials(ctx, source)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("AWS profile %q: %w", source, err)
+		}
+		region := settings["region"]
+		if region == "" {
+			region = ResolveAWSRegion()
+		}
+		role := &AssumeRoleProvider{
+			Source:      StaticAWSCredentials(sourceCreds.AccessKeyID, sourceCreds.SecretAccessKey, sourceCreds.SessionToken),
+			RoleARN:     roleARN,
+			SessionName: settings["role_session_name"],
+			ExternalID:  settings["external_id"],
+			Region:      region,
+		}
+		return role.Retrieve(ctx)
+	}
+
+	id, secret := settings["aws_access_key_id"], settings["aws_secret_access_key"]
+	if id == "" || secret == "" {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+	return AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: settings["aws_session_token"]}, nil
+}
+
+func containerAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		endpoint = "http://169.254.170.2" + rel
+	}
+	if endpoint == "" {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	body, err := doMetadataRequest(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("container credentials: %w", err)
+	}
+	return parseMetadataCredentials(body)
+}
+
+// imdsEndpoint is the EC2 instance metadata service; replaced in tests.
+var imdsEndpoint = "http://169.254.169.254"
+
+func imdsAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// IMDSv2 session token
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imdsEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := doMetadataRequest(req)
+	if err != nil {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+		return doMetadataRequest(req)
+	}
+
+	roles, err := get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return AWSCredentials{}, errNoAWSCredentials
+	}
+	body, err := get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("instance credentials: %w", err)
+	}
+	return parseMetadataCredentials(body)
+}
+
+func doMetadataRequest(req *http.Request) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func parseMetadataCredentials(body []byte) (AWSCredentials, error) {
+	var m struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return AWSCredentials{}, fmt.Errorf("parsing credentials response: %w", err)
+	}
+	if m.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("credentials response has no AccessKeyId")
+	}
+	return AWSCredentials{AccessKeyID: m.AccessKeyID, SecretAccessKey: m.SecretAccessKey, SessionToken: m.Token, Expires: m.Expiration}, nil
+}
+
+// ResolveAWSRegion returns the region from AWS_REGION, AWS_DEFAULT_REGION or
+// the active profile in ~/.aws/config, defaulting to us-east-1.
+func ResolveAWSRegion() string {
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	if r := os.Getenv("AWS_DEFAULT_REGION"); r != "" {
+		return r
+	}
+	if r := awsProfileSettings(awsProfileName())["region"]; r != "" {
+		return r
+	}
+	return "us-east-1"
+}
+
+func awsProfileName() string {
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+// awsProfileSettings merges the profile's keys from the shared config file
+// and the shared credentials file (which wins). Nil means neither has it.
+func awsProfileSettings(profile string) map[string]string {
+	home, _ := os.UserHomeDir()
+	configPath := os.Getenv("AWS_CONFIG_FILE")
+	if configPath == "" {
+		configPath = filepath.Join(home, ".aws", "config")
+	}
+	credsPath := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credsPath == "" {
+		credsPath = filepath.Join(home, ".aws", "credentials")
+	}
+
+	configSection := profile
+	if profile != "default" {
+		configSection = "profile " + profile
+	}
+
+	var merged map[string]string
+	for _, src := range []struct{ path, section string }{
+		{configPath, configSection},
+		{credsPath, profile},
+	} {
+		for k, v := range readINISection(src.path, src.section) {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// readINISection returns the key/value pairs of one section of an AWS
+// style INI file. Missing files and sections yield nil.
+func readINISection(path, section string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var values map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+// AWSCredentials is a set of AWS access keys. SessionToken and Expires are
+// set for temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// expired reports whether the credentials are expired or about to be.
+func (c AWSCredentials) expired() bool {
+	return !c.Expires.IsZero() && time.Now().Add(5*time.Minute).After(c.Expires)
+}
+
+// SigV4Signer signs HTTP requests with AWS Signature Version 4 for one
+// service and region, e.g. "bedrock" in "us-east-1".
+type SigV4Signer struct {
+	Credentials AWSCredentialsProvider
+	Region      string
+	Service     string
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewSigV4Signer creates a signer. A nil provider uses the default
+// credentials chain and an empty region is resolved with ResolveAWSRegion.
+func NewSigV4Signer(creds AWSCredentialsProvider, region, service string) *SigV4Signer {
+	if creds == nil {
+		creds = DefaultAWSCredentials()
+	}
+	if region == "" {
+		region = ResolveAWSRegion()
+	}
+	return &SigV4Signer{Credentials: creds, Region: region, Service: service, now: time.Now}
+}
+
+// Sign adds the X-Amz-Date, X-Amz-Security-Token and Authorization headers
+// to req. The body is read to hash it and then restored.
+func (s *SigV4Signer) Sign(ctx context.Context, req *http.Request) error {
+	creds, err := s.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving AWS credentials: %w", err)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, body, creds, now().UTC())
+	return nil
+}
+
+func (s *SigV4Signer) sign(req *http.Request, body []byte, creds AWSCredentials, t time.Time) {
+	amzDate := t.Format(sigV4TimeFormat)
+	date := t.Format(sigV4DateFormat)
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// Send the path exactly as it is signed.
+	encodedPath := encodeSigV4Path(req.URL.Path)
+	req.URL.RawPath = encodedPath
+	canonicalPath := encodedPath
+	if s.Service != "s3" {
+		canonicalPath = encodeSigV4Path(encodedPath)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalSigV4Headers(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		canonicalSigV4Query(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// RoundTrip signs and sends req, so the signer can wrap an http.Client
+// transport. The request is cloned before its headers are modified.
+func (s *SigV4Signer) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed := req.Clone(req.Context())
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		signed.Body = body
+	}
+	if err := s.Sign(req.Context(), signed); err != nil {
+		return nil, err
+	}
+	return http.DefaultTransport.RoundTrip(signed)
+}
+
+// canonicalSigV4Headers returns the signed header list and the canonical
+// header block. Host, Content-Type and all X-Amz-* headers are signed.
+func canonicalSigV4Headers(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.Host}
+	if headers["host"] == "" {
+		headers["host"] = req.URL.Host
+	}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			headers[lower] = strings.Join(trimmed, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name)
+		canonical.WriteByte(':')
+		canonical.WriteString(headers[name])
+		canonical.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+func canonicalSigV4Query(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, sigV4Escape(k)+"="+sigV4Escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// encodeSigV4Path URI-encodes each path segment, keeping the slashes.
+func encodeSigV4Path(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = sigV4Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// sigV4Escape percent-encodes everything except the RFC 3986 unreserved
+// characters, as SigV4 requires.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Example request from the AWS Signature Version 4 documentation.
+func TestSigV4SignerDocumentationExample(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signer := &SigV4Signer{
+		Credentials: StaticAWSCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", ""),
+		Region:      "us-east-1",
+		Service:     "iam",
+		now:         func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	if err := signer.Sign(context.Background(), req); err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSigV4SignerSessionTokenAndPath(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-west-2.amazonaws.com/model/anthropic.claude-v2:1/invoke", strings.NewReader(`{}`))
+	signer := &SigV4Signer{
+		Credentials: StaticAWSCredentials("AKID", "SECRET", "session-token"),
+		Region:      "us-west-2",
+		Service:     "bedrock",
+	}
+	if err := signer.Sign(context.Background(), req); err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "session-token" {
+		t.Error("X-Amz-Security-Token not set")
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), "x-amz-security-token") {
+		t.Errorf("session token not signed: %s", req.Header.Get("Authorization"))
+	}
+	if got := req.URL.EscapedPath(); got != "/model/anthropic.claude-v2%3A1/invoke" {
+		t.Errorf("EscapedPath() = %q, want colon encoded", got)
+	}
+}
+
+func TestAWSCredentialsChain(t *testing.T) {
+	dir := t.TempDir()
+	credsFile := filepath.Join(dir, "credentials")
+	os.WriteFile(credsFile, []byte("[default]\naws_access_key_id = FILEKEY\naws_secret_access_key = FILESECRET\n\n[other]\naws_access_key_id = OTHER\naws_secret_access_key = OTHERSECRET\n"), 0600)
+
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credsFile)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ENVSECRET")
+	creds, err := DefaultAWSCredentials().Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "ENVKEY" {
+		t.Errorf("env credentials = %+v, %v, want ENVKEY", creds, err)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	creds, err = DefaultAWSCredentials().Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "FILEKEY" {
+		t.Errorf("file credentials = %+v, %v, want FILEKEY", creds, err)
+	}
+
+	t.Setenv("AWS_PROFILE", "other")
+	creds, err = DefaultAWSCredentials().Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "OTHER" {
+		t.Errorf("profile credentials = %+v, %v, want OTHER", creds, err)
+	}
+
+	t.Setenv("AWS_PROFILE", "missing")
+	if _, err := DefaultAWSCredentials().Retrieve(context.Background()); err == nil {
+		t.Error("expected error when no credentials are available")
+	}
+}
+
+func TestAssumeRoleProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=SOURCEKEY/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRole" || r.Form.Get("RoleArn") != "arn:aws:iam::123:role/llm" {
+			t.Errorf("unexpected form: %v", r.Form)
+		}
+		w.Write([]byte(`<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ASIAROLE</AccessKeyId><SecretAccessKey>rolesecret</SecretAccessKey>
+<SessionToken>roletoken</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+	orig := stsEndpoint
+	stsEndpoint = func(string) string { return server.URL + "/" }
+	defer func() { stsEndpoint = orig }()
+
+	p := &AssumeRoleProvider{
+		Source:  StaticAWSCredentials("SOURCEKEY", "sourcesecret", ""),
+		RoleARN: "arn:aws:iam::123:role/llm",
+		Region:  "us-east-1",
+	}
+	creds, err := p.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve() error: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAROLE" || creds.SessionToken != "roletoken" {
+		t.Errorf("Retrieve() = %+v", creds)
+	}
+}
+
+func TestAWSSecretsBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			t.Errorf("X-Amz-Target = %q", r.Header.Get("X-Amz-Target"))
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"Name":"llm","SecretString":"{\"openai\":\"sk-from-aws\"}"}`))
+	}))
+	defer server.Close()
+
+	b := &AWSSecretsBackend{
+		Region:   "us-east-1",
+		Endpoint: server.URL + "/",
+		signer:   &SigV4Signer{Credentials: StaticAWSCredentials("AKID", "SECRET", ""), Region: "us-east-1", Service: "secretsmanager"},
+		client:   server.Client(),
+	}
+	got, err := b.Secret(context.Background(), "llm#openai")
+	if err != nil {
+		t.Fatalf("Secret() error: %v", err)
+	}
+	if got != "sk-from-aws" {
+		t.Errorf("Secret() = %q, want %q", got, "sk-from-aws")
+	}
+}
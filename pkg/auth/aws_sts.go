@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stsEndpoint returns the regional STS endpoint; replaced in tests.
+var stsEndpoint = func(region string) string {
+	return "https://sts." + region + ".amazonaws.com/"
+}
+
+// AssumeRoleProvider exchanges Source credentials for temporary credentials
+// of RoleARN through STS AssumeRole. Results are cached until near expiry.
+type AssumeRoleProvider struct {
+	Source      AWSCredentialsProvider
+	RoleARN     string
+	SessionName string
+	ExternalID  string
+	Region      string
+	Duration    time.Duration
+
+	mu    sync.Mutex
+	creds AWSCredentials
+}
+
+// NewAssumeRoleProvider creates a role provider on top of the default chain.
+func NewAssumeRoleProvider(roleARN, region string) *AssumeRoleProvider {
+	if region == "" {
+		region = ResolveAWSRegion()
+	}
+	return &AssumeRoleProvider{Source: DefaultAWSCredentials(), RoleARN: roleARN, Region: region}
+}
+
+func (p *AssumeRoleProvider) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.creds.AccessKeyID != "" && !p.creds.expired() {
+		return p.creds, nil
+	}
+
+	duration := p.Duration
+	if duration == 0 {
+		duration = time.Hour
+	}
+	form := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {p.RoleARN},
+		"RoleSessionName": {roleSessionName(p.SessionName)},
+		"DurationSeconds": {fmt.Sprintf("%d", int(duration.Seconds()))},
+	}
+	if p.ExternalID != "" {
+		form.Set("ExternalId", p.ExternalID)
+	}
+
+	req, err := newSTSRequest(ctx, p.Region, form)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	signer := &SigV4Signer{Credentials: p.Source, Region: p.Region, Service: "sts"}
+	if err := signer.Sign(ctx, req); err != nil {
+		return AWSCredentials{}, err
+	}
+
+	creds, err := doSTSRequest(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("assuming role %s: %w", p.RoleARN, err)
+	}
+	p.creds = creds
+	return creds, nil
+}
+
+// assumeRoleWithWebIdentity exchanges an OIDC token (e.g. an EKS service
+// account token) for role credentials. The call is not signed.
+func assumeRoleWithWebIdentity(ctx context.Context, region, roleARN, sessionName, token string) (AWSCredentials, error) {
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {roleSessionName(sessionName)},
+		"WebIdentityToken": {token},
+	}
+	req, err := newSTSRequest(ctx, region, form)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	creds, err := doSTSRequest(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("assuming role %s with web identity: %w", roleARN, err)
+	}
+	return creds, nil
+}
+
+func roleSessionName(name string) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("picoclaw-%d", time.Now().Unix())
+}
+
+func newSTSRequest(ctx context.Context, region string, form url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stsEndpoint(region), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	return req, nil
+}
+
+func doSTSRequest(req *http.Request) (AWSCredentials, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, fmt.Errorf("STS returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// AssumeRole and AssumeRoleWithWebIdentity share the Credentials shape
+	// under differently named result elements.
+	var result struct {
+		AssumeRole  stsCredentials `xml:"AssumeRoleResult>Credentials"`
+		WebIdentity stsCredentials `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return AWSCredentials{}, fmt.Errorf("parsing STS response: %w", err)
+	}
+	c := result.AssumeRole
+	if c.AccessKeyID == "" {
+		c = result.WebIdentity
+	}
+	if c.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("STS response has no credentials")
+	}
+	return AWSCredentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken, Expires: c.Expiration}, nil
+}
+
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
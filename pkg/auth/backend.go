@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CredentialBackend resolves secrets held in an external secret manager, for
+// deployments where API keys may not live in local files. References are
+// backend specific names with an optional "#field" suffix selecting one key
+// of a JSON secret, e.g. "picoclaw/anthropic#api_key".
+type CredentialBackend interface {
+	// Name identifies the backend in logs and errors.
+	Name() string
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// splitSecretRef separates "name#field" into its parts.
+func splitSecretRef(ref string) (name, field string) {
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// selectSecretField returns value itself when field is empty, otherwise the
+// named string field of value parsed as a JSON object.
+func selectSecretField(value, field string) (string, error) {
+	if field == "" {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select field %q", field)
+	}
+	return stringField(fields, field)
+}
+
+func stringField(fields map[string]interface{}, field string) (string, error) {
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field %q is not a string", field)
+	}
+	return s, nil
+}
+
+// runSecretCommand runs a secret manager CLI and returns its trimmed output.
+func runSecretCommand(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
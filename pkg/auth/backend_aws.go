@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AWSSecretsBackend reads secrets from AWS Secrets Manager, signing requests
+// with the default credentials chain or an assumed role. A reference is a
+// secret name or ARN with an optional "#field".
+type AWSSecretsBackend struct {
+	Region   string
+	Endpoint string
+	signer   *SigV4Signer
+	client   *http.Client
+}
+
+// NewAWSSecretsBackend creates the backend. An empty region is resolved with
+// ResolveAWSRegion; a non-empty roleARN is assumed before reading secrets.
+func NewAWSSecretsBackend(region, roleARN string) *AWSSecretsBackend {
+	if region == "" {
+		region = ResolveAWSRegion()
+	}
+	var creds AWSCredentialsProvider = DefaultAWSCredentials()
+	if roleARN != "" {
+		creds = &AssumeRoleProvider{Source: creds, RoleARN: roleARN, Region: region}
+	}
+	return &AWSSecretsBackend{
+		Region:   region,
+		Endpoint: "https://secretsmanager." + region + ".amazonaws.com/",
+		signer:   NewSigV4Signer(creds, region, "secretsmanager"),
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (b *AWSSecretsBackend) Name() string {
+	return "aws"
+}
+
+func (b *AWSSecretsBackend) Secret(ctx context.Context, ref string) (string, error) {
+	name, field := splitSecretRef(ref)
+	payload, _ := json.Marshal(map[string]string{"SecretId": name})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("aws: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if err := b.signer.Sign(ctx, req); err != nil {
+		return "", fmt.Errorf("aws: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("aws: reading %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("aws: reading %s: %w", name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiErr)
+		if strings.HasSuffix(apiErr.Type, "ResourceNotFoundException") {
+			return "", fmt.Errorf("aws: secret %s: %w", name, ErrCredentialNotFound)
+		}
+		return "", fmt.Errorf("aws: reading %s failed (HTTP %d): %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("aws: parsing %s: %w", name, err)
+	}
+	value, err := selectSecretField(out.SecretString, field)
+	if err != nil {
+		return "", fmt.Errorf("aws: %s: %w", name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GCPSecretsBackend reads secrets from Google Cloud Secret Manager using
+// Application Default Credentials. A reference is a secret name, read at its
+// latest version, or a full "projects/.../secrets/.../versions/..." resource
+// name, with an optional "#field".
+type GCPSecretsBackend struct {
+	Project  string
+	Endpoint string
+
+	token  func() (string, error)
+	client *http.Client
+}
+
+func NewGCPSecretsBackend(project string) *GCPSecretsBackend {
+	return &GCPSecretsBackend{
+		Project:  project,
+		Endpoint: "https://secretmanager.googleapis.com",
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (b *GCPSecretsBackend) Name() string {
+	return "gcp"
+}
+
+func (b *GCPSecretsBackend) Secret(ctx context.Context, ref string) (string, error) {
+	name, field := splitSecretRef(ref)
+
+	token := b.token
+	project := b.Project
+	if token == nil {
+		ts, err := DefaultGCPTokenSource(ctx)
+		if err != nil {
+			return "", fmt.Errorf("gcp: %w", err)
+		}
+		token = ts.Token
+		if project == "" {
+			project = ts.ProjectID
+		}
+	}
+
+	project, secret, version := parseGCPSecretName(name, project)
+	if project == "" {
+		return "", fmt.Errorf("gcp: no project for secret %s (set credentials.gcp.project or GOOGLE_CLOUD_PROJECT)", name)
+	}
+	accessToken, err := token()
+	if err != nil {
+		return "", fmt.Errorf("gcp: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/%s:access", strings.TrimRight(b.Endpoint, "/"), project, secret, version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("gcp: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gcp: reading %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("gcp: reading %s: %w", name, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("gcp: secret %s: %w", name, ErrCredentialNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcp: reading %s failed (HTTP %d): %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("gcp: parsing %s: %w", name, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcp: decoding %s: %w", name, err)
+	}
+
+	value, err := selectSecretField(string(data), field)
+	if err != nil {
+		return "", fmt.Errorf("gcp: %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// parseGCPSecretName splits a secret reference into project, secret and
+// version, accepting either a bare name or a full resource name.
+func parseGCPSecretName(name, defaultProject string) (project, secret, version string) {
+	project, secret, version = defaultProject, name, "latest"
+	parts := strings.Split(name, "/")
+	if len(parts) >= 4 && parts[0] == "projects" && parts[2] == "secrets" {
+		project, secret = parts[1], parts[3]
+		if len(parts) >= 6 && parts[4] == "versions" {
+			version = parts[5]
+		}
+	}
+	return project, secret, version
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultBackendSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/picoclaw/anthropic":
+			w.Write([]byte(`{"data":{"data":{"api_key":"sk-ant-from-vault","org":"acme"}}}`))
+		case "/v1/kv/data/picoclaw/single":
+			w.Write([]byte(`{"data":{"data":{"value":"only-one"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	b := NewVaultBackend(server.URL, "vault-token", "", "kv")
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "picoclaw/anthropic#api_key", want: "sk-ant-from-vault"},
+		{ref: "picoclaw/single", want: "only-one"},
+		{ref: "picoclaw/anthropic", wantErr: true},
+		{ref: "picoclaw/anthropic#missing", wantErr: true},
+		{ref: "picoclaw/absent", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := b.Secret(context.Background(), tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Secret(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Secret(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+
+	if _, err := b.Secret(context.Background(), "picoclaw/absent"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("missing secret error = %v, want ErrCredentialNotFound", err)
+	}
+}
+
+func TestSelectSecretField(t *testing.T) {
+	if got, _ := selectSecretField("plain", ""); got != "plain" {
+		t.Errorf("selectSecretField(plain) = %q", got)
+	}
+	if got, _ := selectSecretField(`{"key":"v"}`, "key"); got != "v" {
+		t.Errorf("selectSecretField(json, key) = %q, want %q", got, "v")
+	}
+	if _, err := selectSecretField("plain", "key"); err == nil {
+		t.Error("expected error selecting a field of a non-JSON secret")
+	}
+}
+
+func TestParseGCPSecretName(t *testing.T) {
+	tests := []struct {
+		name                           string
+		wantProject, wantSecret, wantV string
+	}{
+		{"openai-key", "default-proj", "openai-key", "latest"},
+		{"projects/p1/secrets/s1", "p1", "s1", "latest"},
+		{"projects/p1/secrets/s1/versions/3", "p1", "s1", "3"},
+	}
+	for _, tt := range tests {
+		project, secret, version := parseGCPSecretName(tt.name, "default-proj")
+		if project != tt.wantProject || secret != tt.wantSecret || version != tt.wantV {
+			t.Errorf("parseGCPSecretName(%q) = %q, %q, %q, want %q, %q, %q",
+				tt.name, project, secret, version, tt.wantProject, tt.wantSecret, tt.wantV)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultBackend reads secrets from a HashiCorp Vault KV version 2 engine.
+// A reference is the secret path within the mount plus an optional field;
+// without a field the secret must hold exactly one key.
+type VaultBackend struct {
+	Address   string
+	Token     string
+	Namespace string
+	Mount     string
+
+	client *http.Client
+}
+
+// NewVaultBackend creates a Vault backend. Empty values fall back to the
+// standard VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE variables; the mount
+// defaults to "secret".
+func NewVaultBackend(address, token, namespace, mount string) *VaultBackend {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if mount == "" {
+		mount = "secret"
+	}
+	return &VaultBackend{
+		Address:   strings.TrimRight(address, "/"),
+		Token:     token,
+		Namespace: namespace,
+		Mount:     strings.Trim(mount, "/"),
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (b *VaultBackend) Name() string {
+	return "vault"
+}
+
+func (b *VaultBackend) Secret(ctx context.Context, ref string) (string, error) {
+	if b.Address == "" {
+		return "", fmt.Errorf("vault: address not configured (set credentials.vault.address or VAULT_ADDR)")
+	}
+	if b.Token == "" {
+		return "", fmt.Errorf("vault: token not configured (set credentials.vault.token or VAULT_TOKEN)")
+	}
+
+	path, field := splitSecretRef(ref)
+	url := fmt.Sprintf("%s/v1/%s/data/%s", b.Address, b.Mount, strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", b.Token)
+	if b.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.Namespace)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: reading %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("vault: reading %s: %w", path, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("vault: secret %s: %w", path, ErrCredentialNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: reading %s failed (HTTP %d): %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("vault: parsing %s: %w", path, err)
+	}
+
+	fields := secret.Data.Data
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("vault: secret %s has %d fields, name one with %s#<field>", path, len(fields), path)
+		}
+		for k := range fields {
+			field = k
+		}
+	}
+	value, err := stringField(fields, field)
+	if err != nil {
+		return "", fmt.Errorf("vault: %s: %w", path, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "errors"
+
+// CredentialStore is an OS-managed secret store such as the macOS Keychain,
+// the Windows Credential Manager or the Linux Secret Service. Secrets are
+// addressed by service name and an optional account.
+type CredentialStore interface {
+	// Name identifies the backend in logs and status output.
+	Name() string
+	Get(service, account string) (string, error)
+	Set(service, account, secret string) error
+	Delete(service, account string) error
+}
+
+var (
+	// ErrCredentialNotFound is returned by Get when no secret is stored.
+	ErrCredentialNotFound = errors.New("credential not found")
+
+	// ErrCredentialStoreReadOnly is returned by backends that only support lookups.
+	ErrCredentialStoreReadOnly = errors.New("credential store is read-only")
+)
+
+// SystemCredentialStore returns the secret store of the current platform.
+// Where no OS store is reachable it falls back to the encrypted file store.
+func SystemCredentialStore() CredentialStore {
+	return newSystemCredentialStore()
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore keeps generic passwords in the macOS login keychain via the
+// security command line tool.
+type keychainStore struct{}
+
+// securityItemNotFound is the exit status security uses for a missing item.
+const securityItemNotFound = 44
+
+func newSystemCredentialStore() CredentialStore {
+	return keychainStore{}
+}
+
+func (keychainStore) Name() string {
+	return "macOS Keychain"
+}
+
+func (keychainStore) Get(service, account string) (string, error) {
+	args := []string{"find-generic-password", "-s", service, "-w"}
+	if account != "" {
+		args = append(args, "-a", account)
+	}
+
+	output, err := exec.Command("security", args...).Output()
+	if err != nil {
+		return "", ErrCredentialNotFound
+	}
+
+	secret := strings.TrimSpace(string(output))
+	if secret == "" {
+		return "", ErrCredentialNotFound
+	}
+	return secret, nil
+}
+
+// Set adds or updates the item. The command is fed to "security -i" on stdin
+// with the secret hex-encoded, so it never appears in the process list.
+func (keychainStore) Set(service, account, secret string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		securityQuote(service), securityQuote(account), hex.EncodeToString([]byte(secret)))
+
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("writing keychain item %q: %w: %s", service, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (keychainStore) Delete(service, account string) error {
+	args := []string{"delete-generic-password", "-s", service}
+	if account != "" {
+		args = append(args, "-a", account)
+	}
+
+	output, err := exec.Command("security", args...).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+			return ErrCredentialNotFound
+		}
+		return fmt.Errorf("deleting keychain item %q: %w: %s", service, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for the interactive security shell.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
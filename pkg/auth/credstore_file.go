@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileCredentialStore keeps secrets AES-256-GCM encrypted in a JSON file,
+// with the key in a separate 0600 file. It is the fallback on machines
+// without an OS secret store (e.g. headless Linux servers): it keeps secrets
+// out of plain-text files and backups, but does not protect against another
+// process running as the same user.
+type fileCredentialStore struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+// NewFileCredentialStore returns an encrypted file store under dir.
+func NewFileCredentialStore(dir string) CredentialStore {
+	return &fileCredentialStore{
+		path:    filepath.Join(dir, "credentials.enc"),
+		keyPath: filepath.Join(dir, "credentials.key"),
+	}
+}
+
+func defaultFileCredentialStore() CredentialStore {
+	return NewFileCredentialStore(filepath.Dir(authFilePath()))
+}
+
+func (s *fileCredentialStore) Name() string {
+	return "encrypted file store"
+}
+
+func fileCredentialKey(service, account string) string {
+	if account == "" {
+		return service
+	}
+	return service + "/" + account
+}
+
+func (s *fileCredentialStore) Get(service, account string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := entries[fileCredentialKey(service, account)]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("corrupt entry for %s", fileCredentialKey(service, account))
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s: %w", fileCredentialKey(service, account), err)
+	}
+	return string(plain), nil
+}
+
+func (s *fileCredentialStore) Set(service, account, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	entries[fileCredentialKey(service, account)] = base64.StdEncoding.EncodeToString(sealed)
+	return s.save(entries)
+}
+
+func (s *fileCredentialStore) Delete(service, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	key := fileCredentialKey(service, account)
+	if _, ok := entries[key]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(entries, key)
+	return s.save(entries)
+}
+
+func (s *fileCredentialStore) load() (map[string]string, error) {
+	entries := make(map[string]string)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+func (s *fileCredentialStore) save(entries map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// cipher loads the store key, generating it first when create is set.
+func (s *fileCredentialStore) cipher(create bool) (cipher.AEAD, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if os.IsNotExist(err) && create {
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(s.keyPath), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(s.keyPath, key, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("reading credential key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credential key %s has invalid length %d", s.keyPath, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
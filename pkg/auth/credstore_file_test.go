@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileCredentialStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileCredentialStore(dir)
+
+	if _, err := store.Get("picoclaw", "openai"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("Get() on empty store error = %v, want ErrCredentialNotFound", err)
+	}
+
+	if err := store.Set("picoclaw", "openai", "sk-secret"); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+	got, err := store.Get("picoclaw", "openai")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if got != "sk-secret" {
+		t.Errorf("Get() = %q, want %q", got, "sk-secret")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.enc"))
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if strings.Contains(string(data), "sk-secret") {
+		t.Error("secret stored in plain text")
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "credentials.key"))
+	if err != nil {
+		t.Fatalf("Stat(key) error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("key permissions = %o, want 0600", perm)
+	}
+
+	if err := store.Delete("picoclaw", "openai"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := store.Get("picoclaw", "openai"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrCredentialNotFound", err)
+	}
+}
+
+func TestFileCredentialStoreWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileCredentialStore(dir)
+	if err := store.Set("picoclaw", "", "secret"); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "credentials.key"), make([]byte, 32), 0600)
+	if _, err := store.Get("picoclaw", ""); err == nil {
+		t.Error("expected decryption error with a different key")
+	}
+}
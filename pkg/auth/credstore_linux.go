@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretServiceTimeout bounds each secret-tool call so a wedged or locked
+// keyring prompt cannot hang the agent.
+const secretServiceTimeout = 10 * time.Second
+
+// secretServiceStore talks to the freedesktop Secret Service (gnome-keyring,
+// KWallet) through libsecret's secret-tool. Items use the same "service" and
+// "username" attributes as Python keyring and go-keyring, so secrets stored
+// by those tools are visible here too.
+type secretServiceStore struct {
+	tool string
+}
+
+// newSystemCredentialStore uses the Secret Service when a session bus and
+// secret-tool are available, and falls back to the encrypted file store on
+// headless machines.
+func newSystemCredentialStore() CredentialStore {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		if tool, err := exec.LookPath("secret-tool"); err == nil {
+			return &secretServiceStore{tool: tool}
+		}
+	}
+	return defaultFileCredentialStore()
+}
+
+func (s *secretServiceStore) Name() string {
+	return "Secret Service"
+}
+
+func secretServiceAttrs(service, account string) []string {
+	attrs := []string{"service", service}
+	if account != "" {
+		attrs = append(attrs, "username", account)
+	}
+	return attrs
+}
+
+func (s *secretServiceStore) run(stdin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretServiceTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.tool, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New("secret-tool: " + msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (s *secretServiceStore) Get(service, account string) (string, error) {
+	args := append([]string{"lookup"}, secretServiceAttrs(service, account)...)
+	out, err := s.run("", args...)
+	if err != nil {
+		// secret-tool exits non-zero with no output when nothing matches
+		if strings.Contains(err.Error(), "secret-tool:") {
+			return "", err
+		}
+		return "", ErrCredentialNotFound
+	}
+	secret := strings.TrimRight(out, "\n")
+	if secret == "" {
+		return "", ErrCredentialNotFound
+	}
+	return secret, nil
+}
+
+func (s *secretServiceStore) Set(service, account, secret string) error {
+	label := "picoclaw: " + fileCredentialKey(service, account)
+	args := append([]string{"store", "--label=" + label}, secretServiceAttrs(service, account)...)
+	_, err := s.run(secret, args...)
+	return err
+}
+
+func (s *secretServiceStore) Delete(service, account string) error {
+	args := append([]string{"clear"}, secretServiceAttrs(service, account)...)
+	_, err := s.run("", args...)
+	return err
+}
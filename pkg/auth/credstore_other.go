@@ -0,0 +1,9 @@
+//go:build !darwin && !windows && !linux
+
+package auth
+
+// newSystemCredentialStore falls back to the encrypted file store on
+// platforms without a supported OS secret store.
+func newSystemCredentialStore() CredentialStore {
+	return defaultFileCredentialStore()
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"fmt"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+var (
+	modadvapi32    = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = modadvapi32.NewProc("CredReadW")
+	procCredWriteW = modadvapi32.NewProc("CredWriteW")
+	procCredDelete = modadvapi32.NewProc("CredDeleteW")
+	procCredFree   = modadvapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// winCredential mirrors the CREDENTIALW structure from wincred.h.
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// wincredStore keeps secrets as generic credentials in the Windows
+// Credential Manager. The target name is the service, suffixed with the
+// account when one is given, so entries show up as e.g. "picoclaw/openai".
+type wincredStore struct{}
+
+func newSystemCredentialStore() CredentialStore {
+	return wincredStore{}
+}
+
+func (wincredStore) Name() string {
+	return "Windows Credential Manager"
+}
+
+func wincredTarget(service, account string) string {
+	if account == "" {
+		return service
+	}
+	return service + "/" + account
+}
+
+func (wincredStore) Get(service, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(wincredTarget(service, account))
+	if err != nil {
+		return "", err
+	}
+
+	var cred *winCredential
+	ret, _, callErr := procCredReadW.Call(
+		uintptr(unsafe.Pointer(target)),
+		credTypeGeneric,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	)
+	if ret == 0 {
+		if callErr == errorNotFound {
+			return "", ErrCredentialNotFound
+		}
+		return "", fmt.Errorf("CredReadW: %w", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", ErrCredentialNotFound
+	}
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return decodeCredentialBlob(blob), nil
+}
+
+func (wincredStore) Set(service, account, secret string) error {
+	target, err := syscall.UTF16PtrFromString(wincredTarget(service, account))
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	if len(blob) == 0 {
+		return fmt.Errorf("refusing to store empty secret")
+	}
+
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+	}
+	if account != "" {
+		if cred.UserName, err = syscall.UTF16PtrFromString(account); err != nil {
+			return err
+		}
+	}
+
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("CredWriteW: %w", callErr)
+	}
+	return nil
+}
+
+func (wincredStore) Delete(service, account string) error {
+	target, err := syscall.UTF16PtrFromString(wincredTarget(service, account))
+	if err != nil {
+		return err
+	}
+
+	ret, _, callErr := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 {
+		if callErr == errorNotFound {
+			return ErrCredentialNotFound
+		}
+		return fmt.Errorf("CredDeleteW: %w", callErr)
+	}
+	return nil
+}
+
+// decodeCredentialBlob handles both UTF-8 blobs (written by picoclaw and most
+// CLIs) and UTF-16LE blobs (written by cmdkey and the Control Panel).
+func decodeCredentialBlob(blob []byte) string {
+	if len(blob)%2 == 0 && len(blob) >= 2 && blob[1] == 0 {
+		u16 := make([]uint16, len(blob)/2)
+		for i := range u16 {
+			u16[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+		}
+		return string(utf16.Decode(u16))
+	}
+	return string(blob)
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestDecodeCredentialBlob(t *testing.T) {
+	tests := []struct {
+		name string
+		blob []byte
+		want string
+	}{
+		{"utf8", []byte("sk-ant-123"), "sk-ant-123"},
+		{"utf16le", []byte{'s', 0, 'k', 0, '-', 0, '1', 0}, "sk-1"},
+	}
+	for _, tt := range tests {
+		if got := decodeCredentialBlob(tt.blob); got != tt.want {
+			t.Errorf("%s: decodeCredentialBlob() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWincredTarget(t *testing.T) {
+	if got := wincredTarget("picoclaw", ""); got != "picoclaw" {
+		t.Errorf("wincredTarget() = %q, want %q", got, "picoclaw")
+	}
+	if got := wincredTarget("picoclaw", "openai"); got != "picoclaw/openai" {
+		t.Errorf("wincredTarget() = %q, want %q", got, "picoclaw/openai")
+	}
+}
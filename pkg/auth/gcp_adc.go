@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCPCloudPlatformScope grants access to all Google Cloud APIs the
+// credential's IAM roles allow, as Vertex AI and Secret Manager expect.
+const GCPCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// GCPTokenSource issues OAuth access tokens from Google Application Default
+// Credentials: the GOOGLE_APPLICATION_CREDENTIALS service account or
+// external account file, the gcloud application-default login file, or the
+// GCE/GKE/Cloud Run metadata server. When none of those is available the
+// active gcloud user login is used through the gcloud CLI.
+type GCPTokenSource struct {
+	// ProjectID is the project the credentials belong to, if known.
+	ProjectID string
+	// Source describes where the credentials came from.
+	Source string
+
+	ts oauth2.TokenSource
+}
+
+// NewGCPTokenSource finds Application Default Credentials for scopes,
+// defaulting to GCPCloudPlatformScope.
+func NewGCPTokenSource(ctx context.Context, scopes ...string) (*GCPTokenSource, error) {
+	if len(scopes) == 0 {
+		scopes = []string{GCPCloudPlatformScope}
+	}
+
+	creds, adcErr := google.FindDefaultCredentials(ctx, scopes...)
+	if adcErr == nil {
+		project := creds.ProjectID
+		if project == "" {
+			project = gcpProjectFromEnv()
+		}
+		return &GCPTokenSource{
+			ProjectID: project,
+			Source:    gcpCredentialsSource(creds),
+			ts:        oauth2.ReuseTokenSource(nil, creds.TokenSource),
+		}, nil
+	}
+
+	if _, err := exec.LookPath("gcloud"); err == nil {
+		project := gcpProjectFromEnv()
+		if project == "" {
+			project, _ = runGcloud(ctx, "config", "get-value", "project")
+		}
+		return &GCPTokenSource{
+			ProjectID: project,
+			Source:    "gcloud user login",
+			ts:        oauth2.ReuseTokenSource(nil, gcloudTokenSource{}),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no Google Cloud credentials found (run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS): %w", adcErr)
+}
+
+// Token returns a valid access token, refreshing it when needed.
+func (s *GCPTokenSource) Token() (string, error) {
+	tok, err := s.ts.Token()
+	if err != nil {
+		return "", fmt.Errorf("fetching Google Cloud access token: %w", err)
+	}
+	return tok.AccessToken, nil
+}
+
+// TokenFunc adapts the source to the func() (string, error) token sources
+// used by the providers.
+func (s *GCPTokenSource) TokenFunc() func() (string, error) {
+	return s.Token
+}
+
+var (
+	defaultGCPTokenSourceOnce sync.Once
+	defaultGCPTokenSource     *GCPTokenSource
+	defaultGCPTokenSourceErr  error
+)
+
+// DefaultGCPTokenSource returns a process-wide cloud-platform token source so
+// every caller shares one cached token.
+func DefaultGCPTokenSource(ctx context.Context) (*GCPTokenSource, error) {
+	defaultGCPTokenSourceOnce.Do(func() {
+		defaultGCPTokenSource, defaultGCPTokenSourceErr = NewGCPTokenSource(ctx)
+	})
+	return defaultGCPTokenSource, defaultGCPTokenSourceErr
+}
+
+func gcpCredentialsSource(creds *google.Credentials) string {
+	if len(creds.JSON) == 0 {
+		return "metadata server"
+	}
+	var file struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(creds.JSON, &file)
+	if file.Type == "" {
+		return "credentials file"
+	}
+	return file.Type
+}
+
+func gcpProjectFromEnv() string {
+	for _, name := range []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gcloudTokenSource prints an access token for the active gcloud account.
+// gcloud tokens last an hour; the expiry is set conservatively so the
+// ReuseTokenSource wrapper asks again in time.
+type gcloudTokenSource struct{}
+
+func (gcloudTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	token, err := runGcloud(ctx, "auth", "print-access-token")
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("gcloud returned an empty access token")
+	}
+	return &oauth2.Token{AccessToken: token, TokenType: "Bearer", Expiry: time.Now().Add(45 * time.Minute)}, nil
+}
+
+func runGcloud(ctx context.Context, args ...string) (string, error) {
+	out, err := runSecretCommand(ctx, "gcloud", args...)
+	if err != nil {
+		return "", fmt.Errorf("gcloud %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewGCPTokenSourceFromCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adc.json")
+	os.WriteFile(path, []byte(`{
+  "type": "authorized_user",
+  "client_id": "id.apps.googleusercontent.com",
+  "client_secret": "secret",
+  "refresh_token": "refresh"
+}`), 0600)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+
+	ts, err := NewGCPTokenSource(context.Background())
+	if err != nil {
+		t.Fatalf("NewGCPTokenSource() error: %v", err)
+	}
+	if ts.Source != "authorized_user" {
+		t.Errorf("Source = %q, want %q", ts.Source, "authorized_user")
+	}
+	if ts.ProjectID != "my-project" {
+		t.Errorf("ProjectID = %q, want %q", ts.ProjectID, "my-project")
+	}
+}
+
+func TestGCPSecretsBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/projects/proj/secrets/llm-keys/versions/latest:access" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data := base64.StdEncoding.EncodeToString([]byte(`{"gemini":"AIza-from-gcp"}`))
+		w.Write([]byte(`{"name":"x","payload":{"data":"` + data + `"}}`))
+	}))
+	defer server.Close()
+
+	b := NewGCPSecretsBackend("proj")
+	b.Endpoint = server.URL
+	b.token = func() (string, error) { return "ya29.test", nil }
+
+	got, err := b.Secret(context.Background(), "llm-keys#gemini")
+	if err != nil {
+		t.Fatalf("Secret() error: %v", err)
+	}
+	if got != "AIza-from-gcp" {
+		t.Errorf("Secret() = %q, want %q", got, "AIza-from-gcp")
+	}
+
+	if _, err := b.Secret(context.Background(), "projects/proj/secrets/other"); err == nil {
+		t.Error("expected error for missing secret")
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotRevoked is returned by Logout and LogoutAll when the credentials
+// were removed but a token could not be revoked with the provider. Check
+// for it with errors.Is.
+var ErrNotRevoked = errors.New("logged out, but the token was not revoked")
+
+// oauthConfigs are the OAuth clients of the providers picoclaw logs in to.
+var oauthConfigs = map[string]func() OAuthProviderConfig{
+	"openai":    OpenAIOAuthConfig,
+	"anthropic": AnthropicOAuthConfig,
+}
+
+// Logout removes the credential stored for provider/account, revoking its
+// token first when it came from an OAuth login to a provider that supports
+// revocation (OpenAI). An empty account selects the provider's default.
+func Logout(provider, account string) error {
+	cred, err := GetCredential(provider, account)
+	if err != nil {
+		return err
+	}
+	revokeErr := revoke(provider, cred)
+	if err := DeleteCredential(provider, account); err != nil {
+		return err
+	}
+	if revokeErr != nil {
+		return fmt.Errorf("%w: %v", ErrNotRevoked, revokeErr)
+	}
+	return nil
+}
+
+// LogoutAll revokes every stored OAuth token that can be revoked and
+// removes all credentials.
+func LogoutAll() error {
+	var revokeErrs []error
+	if store, err := LoadStore(); err == nil {
+		for _, provider := range store.Providers() {
+			for _, account := range store.AccountNames(provider) {
+				cred, err := openSecrets(store.Get(provider, account))
+				if err == nil {
+					err = revoke(provider, cred)
+				}
+				if err != nil {
+					revokeErrs = append(revokeErrs, fmt.Errorf("%s account %s: %w", provider, account, err))
+				}
+			}
+		}
+	}
+	if err := DeleteAllCredentials(); err != nil {
+		return err
+	}
+	if len(revokeErrs) > 0 {
+		return fmt.Errorf("%w: %v", ErrNotRevoked, errors.Join(revokeErrs...))
+	}
+	return nil
+}
+
+// revoke revokes the token of cred when it is an OAuth login and provider
+// supports revocation.
+func revoke(provider string, cred *AuthCredential) error {
+	if cred == nil || cred.AuthMethod != "oauth" {
+		return nil
+	}
+	newConfig, ok := oauthConfigs[provider]
+	if !ok {
+		return nil
+	}
+	cfg := newConfig()
+	if cfg.RevokeURL == "" {
+		return nil
+	}
+	return RevokeToken(cred, cfg)
+}
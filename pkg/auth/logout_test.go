@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogout_RevokesOAuthToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var revoked []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.FormValue("token_type_hint") != "refresh_token" || r.FormValue("client_id") != "test-client" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		revoked = append(revoked, r.FormValue("token"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	orig := oauthConfigs["openai"]
+	oauthConfigs["openai"] = func() OAuthProviderConfig {
+		return OAuthProviderConfig{ClientID: "test-client", RevokeURL: server.URL}
+	}
+	defer func() { oauthConfigs["openai"] = orig }()
+
+	SetCredential("openai", "", &AuthCredential{AccessToken: "access", RefreshToken: "refresh-1", Provider: "openai", AuthMethod: "oauth"})
+	SetCredential("openai", "work", &AuthCredential{AccessToken: "access", RefreshToken: "refresh-2", Provider: "openai", AuthMethod: "oauth"})
+	SetCredential("anthropic", "", &AuthCredential{AccessToken: "sk-ant", Provider: "anthropic", AuthMethod: "token"})
+
+	if err := Logout("openai", ""); err != nil {
+		t.Fatalf("Logout() error: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != "refresh-1" {
+		t.Errorf("revoked %v, want the default account's refresh token", revoked)
+	}
+	if cred, _ := GetCredential("openai", DefaultAccount); cred != nil {
+		t.Error("credential still stored after Logout()")
+	}
+
+	// A failed revocation still removes the credentials
+	status = http.StatusServiceUnavailable
+	if err := LogoutAll(); !errors.Is(err, ErrNotRevoked) {
+		t.Errorf("LogoutAll() error = %v, want ErrNotRevoked", err)
+	}
+	if len(revoked) != 2 || revoked[1] != "refresh-2" {
+		t.Errorf("revoked %v, want only OAuth tokens revoked", revoked)
+	}
+	store, _ := LoadStore()
+	if providers := store.Providers(); len(providers) != 0 {
+		t.Errorf("Providers() = %v after LogoutAll(), want none", providers)
+	}
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
@@ -11,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"runtime"
 	"strconv"
@@ -19,11 +22,60 @@ import (
 )
 
 type OAuthProviderConfig struct {
+	Provider   string // auth store provider name; empty means "openai"
 	Issuer     string
 	ClientID   string
 	Scopes     string
 	Originator string
 	Port       int
+
+	// AuthorizeURL and TokenURL override the Issuer-relative
+	// /oauth/authorize and /oauth/token endpoints.
+	AuthorizeURL string
+	TokenURL     string
+
+	// RevokeURL is the RFC 7009 token revocation endpoint; empty when the
+	// provider has none.
+	RevokeURL string
+
+	// JSONTokenRequests sends token requests as JSON instead of form data.
+	JSONTokenRequests bool
+
+	// ManualRedirectURI is a provider-hosted page that displays the
+	// authorization code so it can be pasted into a headless terminal.
+	ManualRedirectURI string
+
+	// CallbackPath is the local redirect path registered for the client;
+	// empty means "/auth/callback".
+	CallbackPath string
+}
+
+func (cfg OAuthProviderConfig) callbackPath() string {
+	if cfg.CallbackPath == "" {
+		return "/auth/callback"
+	}
+	return cfg.CallbackPath
+}
+
+func (cfg OAuthProviderConfig) providerName() string {
+	if cfg.Provider == "" {
+		return "openai"
+	}
+	return cfg.Provider
+}
+
+func (cfg OAuthProviderConfig) authorizeURL() string {
+	if cfg.AuthorizeURL != "" {
+		return cfg.AuthorizeURL
+	}
+	return cfg.Issuer + "/oauth/authorize"
+}
+
+func (cfg OAuthProviderConfig) tokenURL() string {
+	if cfg.TokenURL != "" {
+		return cfg.TokenURL
+	}
+	return cfg.Issuer + "/oauth/token"
 }
 
 func OpenAIOAuthConfig() OAuthProviderConfig {
@@ -33,6 +85,25 @@ func OpenAIOAuthConfig() OAuthProviderConfig {
 		Scopes:     "openid profile email offline_access",
 		Originator: "codex_cli_rs",
 		Port:       1455,
+		RevokeURL:  "https://auth.openai.com/oauth/revoke",
+	}
+}
+
+// AnthropicOAuthConfig is the claude.ai OAuth client used by Claude Pro/Max
+// subscriptions. It has no device authorization endpoint, so headless logins
+// use the code display page at ManualRedirectURI.
+func AnthropicOAuthConfig() OAuthProviderConfig {
+	return OAuthProviderConfig{
+		Provider:          "anthropic",
+		Issuer:            "https://claude.ai",
+		ClientID:          "9d1c250a-e61b-44d9-88ed-5944d1962f5e",
+		Scopes:            "org:create_api_key user:profile user:inference",
+		Port:              54545,
+		AuthorizeURL:      "https://claude.ai/oauth/authorize",
+		TokenURL:          "https://console.anthropic.com/v1/oauth/token",
+		JSONTokenRequests: true,
+		ManualRedirectURI: "https://console.anthropic.com/oauth/code/callback",
+		CallbackPath:      "/callback",
 	}
 }
 
@@ -55,14 +126,14 @@ func LoginBrowser(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		return nil, fmt.Errorf("generating state: %w", err)
 	}
 
-	redirectURI := fmt.Sprintf("http://localhost:%d/auth/callback", cfg.Port)
+	redirectURI := fmt.Sprintf("http://localhost:%d%s", cfg.Port, cfg.callbackPath())
 
 	authURL := buildAuthorizeURL(cfg, pkce, state, redirectURI)
 
 	resultCh := make(chan callbackResult, 1)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/auth/callback", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(cfg.callbackPath(), func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Query().Get("state") != state {
 			resultCh <- callbackResult{err: fmt.Errorf("state mismatch")}
 			http.Error(w, "State mismatch", http.StatusBadRequest)
@@ -101,7 +172,7 @@ func LoginBrowser(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		fmt.Printf("Could not open browser automatically.\nPlease open this URL manually:\n\n%s\n\n", authURL)
 	}
 
-	fmt.Println("If you're running in a headless environment, use: picoclaw auth login --provider openai --device-code")
+	fmt.Printf("If you're running in a headless environment, use: picoclaw auth login --provider %s --device-code\n", cfg.providerName())
 	fmt.Println("Waiting for authentication in browser...")
 
 	select {
@@ -109,7 +180,7 @@ func LoginBrowser(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		if result.err != nil {
 			return nil, result.err
 		}
-		return exchangeCodeForTokens(cfg, result.code, pkce.CodeVerifier, redirectURI)
+		return exchangeCode(cfg, result.code, pkce.CodeVerifier, redirectURI, state)
 	case <-time.After(5 * time.Minute):
 		return nil, fmt.Errorf("authentication timed out after 5 minutes")
 	}
@@ -171,7 +242,14 @@ func parseFlexibleInt(raw json.RawMessage) (int, error) {
 	return 0, fmt.Errorf("invalid integer value: %s", string(raw))
 }
 
+// LoginDeviceCode authenticates without a local browser. OpenAI uses its
+// device authorization endpoint; providers without one (Anthropic) print an
+// authorization URL to open on any device and read back the code it shows.
 func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
+	if cfg.ManualRedirectURI != "" {
+		return loginManualCode(cfg, os.Stdin)
+	}
+
 	reqBody, _ := json.Marshal(map[string]string{
 		"client_id": cfg.ClientID,
 	})
@@ -223,6 +301,41 @@ func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
 	}
 }
 
+// loginManualCode runs the authorization code flow with a redirect to a page
+// that displays the code, which the user pastes back as "code#state".
+func loginManualCode(cfg OAuthProviderConfig, r io.Reader) (*AuthCredential, error) {
+	pkce, err := GeneratePKCE()
+	if err != nil {
+		return nil, fmt.Errorf("generating PKCE: %w", err)
+	}
+
+	state, err := generateState()
+	if err != nil {
+		return nil, fmt.Errorf("generating state: %w", err)
+	}
+
+	authURL := buildAuthorizeURL(cfg, pkce, state, cfg.ManualRedirectURI)
+	fmt.Printf("\nTo authenticate, open this URL on any device:\n\n  %s\n\nAfter approving, paste the code shown on the page:\n> ", authURL)
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading code: %w", err)
+		}
+		return nil, fmt.Errorf("no code received")
+	}
+
+	code, codeState, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "#")
+	if code == "" {
+		return nil, fmt.Errorf("code cannot be empty")
+	}
+	if codeState != "" && codeState != state {
+		return nil, fmt.Errorf("state mismatch")
+	}
+
+	return exchangeCode(cfg, code, pkce.CodeVerifier, cfg.ManualRedirectURI, state)
+}
+
 func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*AuthCredential, error) {
 	reqBody, _ := json.Marshal(map[string]string{
 		"device_auth_id": deviceAuthID,
@@ -263,25 +376,90 @@ func RefreshAccessToken(cred *AuthCredential, cfg OAuthProviderConfig) (*AuthCre
 		return nil, fmt.Errorf("no refresh token available")
 	}
 
-	data := url.Values{
-		"client_id":     {cfg.ClientID},
-		"grant_type":    {"refresh_token"},
-		"refresh_token": {cred.RefreshToken},
-		"scope":         {"openid profile email"},
+	fields := map[string]string{
+		"client_id":     cfg.ClientID,
+		"grant_type":    "refresh_token",
+		"refresh_token": cred.RefreshToken,
+	}
+	if !cfg.JSONTokenRequests {
+		fields["scope"] = "openid profile email"
 	}
 
-	resp, err := http.PostForm(cfg.Issuer+"/oauth/token", data)
+	body, status, err := postTokenRequest(cfg, fields)
 	if err != nil {
 		return nil, fmt.Errorf("refreshing token: %w", err)
 	}
-	defer resp.Body.Close()
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token refresh failed: %s", string(body))
+	}
 
-	body, _ := io.ReadAll(resp.Body)
+	refreshed, err := parseTokenResponse(body, cred.Provider)
+	if err != nil {
+		return nil, err
+	}
+	// Providers may omit the refresh token when it is not rotated
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = cred.RefreshToken
+	}
+	if refreshed.AccountID == "" {
+		refreshed.AccountID = cred.AccountID
+	}
+	return refreshed, nil
+}
+
+// revokeClient bounds revocation requests, so logging out does not hang on
+// an unreachable server.
+var revokeClient = &http.Client{Timeout: 10 * time.Second}
+
+// RevokeToken asks the provider to revoke cred's refresh token, or its
+// access token when it has none, so the login cannot be used again even
+// from a copy of the credential.
+func RevokeToken(cred *AuthCredential, cfg OAuthProviderConfig) error {
+	if cfg.RevokeURL == "" {
+		return fmt.Errorf("%s does not support token revocation", cfg.providerName())
+	}
+	token, hint := cred.RefreshToken, "refresh_token"
+	if token == "" {
+		token, hint = cred.AccessToken, "access_token"
+	}
+	resp, err := revokeClient.PostForm(cfg.RevokeURL, url.Values{
+		"token":           {token},
+		"token_type_hint": {hint},
+		"client_id":       {cfg.ClientID},
+	})
+	if err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token refresh failed: %s", string(body))
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("token revocation failed: %s", string(body))
 	}
+	return nil
+}
+
+// postTokenRequest posts fields to the token endpoint as form data, or as
+// JSON for providers that require it, and returns the body and status code.
+func postTokenRequest(cfg OAuthProviderConfig, fields map[string]string) ([]byte, int, error) {
+	var resp *http.Response
+	var err error
+	if cfg.JSONTokenRequests {
+		payload, _ := json.Marshal(fields)
+		resp, err = http.Post(cfg.tokenURL(), "application/json", bytes.NewReader(payload))
+	} else {
+		data := url.Values{}
+		for k, v := range fields {
+			data.Set(k, v)
+		}
+		resp, err = http.PostForm(cfg.tokenURL(), data)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
 
-	return parseTokenResponse(body, cred.Provider)
+	body, _ := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, nil
 }
 
 func BuildAuthorizeURL(cfg OAuthProviderConfig, pkce PKCECodes, state, redirectURI string) string {
@@ -290,43 +468,55 @@ func BuildAuthorizeURL(cfg OAuthProviderConfig, pkce PKCECodes, state, redirectU
 
 func buildAuthorizeURL(cfg OAuthProviderConfig, pkce PKCECodes, state, redirectURI string) string {
 	params := url.Values{
-		"response_type":              {"code"},
-		"client_id":                  {cfg.ClientID},
-		"redirect_uri":               {redirectURI},
-		"scope":                      {cfg.Scopes},
-		"code_challenge":             {pkce.CodeChallenge},
-		"code_challenge_method":      {"S256"},
-		"id_token_add_organizations": {"true"},
-		"codex_cli_simplified_flow":  {"true"},
-		"state":                      {state},
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {cfg.Scopes},
+		"code_challenge":        {pkce.CodeChallenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+	}
+	switch cfg.providerName() {
+	case "anthropic":
+		// Ask claude.ai to display the code when redirecting to the manual page
+		params.Set("code", "true")
+	default:
+		params.Set("id_token_add_organizations", "true")
+		params.Set("codex_cli_simplified_flow", "true")
 	}
 	if cfg.Originator != "" {
 		params.Set("originator", cfg.Originator)
 	}
-	return cfg.Issuer + "/oauth/authorize?" + params.Encode()
+	return cfg.authorizeURL() + "?" + params.Encode()
 }
 
 func exchangeCodeForTokens(cfg OAuthProviderConfig, code, codeVerifier, redirectURI string) (*AuthCredential, error) {
-	data := url.Values{
-		"grant_type":    {"authorization_code"},
-		"code":          {code},
-		"redirect_uri":  {redirectURI},
-		"client_id":     {cfg.ClientID},
-		"code_verifier": {codeVerifier},
+	return exchangeCode(cfg, code, codeVerifier, redirectURI, "")
+}
+
+// exchangeCode redeems an authorization code. state is echoed back for
+// providers that require it in the token request.
+func exchangeCode(cfg OAuthProviderConfig, code, codeVerifier, redirectURI, state string) (*AuthCredential, error) {
+	fields := map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  redirectURI,
+		"client_id":     cfg.ClientID,
+		"code_verifier": codeVerifier,
+	}
+	if state != "" && cfg.JSONTokenRequests {
+		fields["state"] = state
 	}
 
-	resp, err := http.PostForm(cfg.Issuer+"/oauth/token", data)
+	body, status, err := postTokenRequest(cfg, fields)
 	if err != nil {
 		return nil, fmt.Errorf("exchanging code for tokens: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
+	if status != http.StatusOK {
 		return nil, fmt.Errorf("token exchange failed: %s", string(body))
 	}
 
-	return parseTokenResponse(body, "openai")
+	return parseTokenResponse(body, cfg.providerName())
 }
 
 func parseTokenResponse(body []byte, provider string) (*AuthCredential, error) {
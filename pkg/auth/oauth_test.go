@@ -274,3 +274,71 @@ func TestParseDeviceCodeResponseInvalidInterval(t *testing.T) {
 		t.Fatal("expected error for invalid interval")
 	}
 }
+
+func TestAnthropicOAuthConfig(t *testing.T) {
+	cfg := AnthropicOAuthConfig()
+	if cfg.providerName() != "anthropic" {
+		t.Errorf("providerName() = %q, want %q", cfg.providerName(), "anthropic")
+	}
+	if !cfg.JSONTokenRequests {
+		t.Error("Anthropic token requests should be JSON")
+	}
+
+	u := buildAuthorizeURL(cfg, PKCECodes{CodeChallenge: "challenge"}, "st", cfg.ManualRedirectURI)
+	if !strings.HasPrefix(u, "https://claude.ai/oauth/authorize?") {
+		t.Errorf("authorize URL = %q, want claude.ai authorize endpoint", u)
+	}
+	if !strings.Contains(u, "code=true") {
+		t.Error("URL missing code=true")
+	}
+	if strings.Contains(u, "codex_cli_simplified_flow") {
+		t.Error("Anthropic URL should not include OpenAI-specific params")
+	}
+}
+
+func TestLoginManualCode(t *testing.T) {
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "want JSON", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "sk-ant-oat01-test",
+			"refresh_token": "sk-ant-ort01-test",
+			"expires_in":    28800,
+		})
+	}))
+	defer server.Close()
+
+	cfg := AnthropicOAuthConfig()
+	cfg.TokenURL = server.URL + "/v1/oauth/token"
+
+	cred, err := loginManualCode(cfg, strings.NewReader("the-code\n"))
+	if err != nil {
+		t.Fatalf("loginManualCode() error: %v", err)
+	}
+	if cred.AccessToken != "sk-ant-oat01-test" {
+		t.Errorf("AccessToken = %q, want %q", cred.AccessToken, "sk-ant-oat01-test")
+	}
+	if cred.Provider != "anthropic" {
+		t.Errorf("Provider = %q, want %q", cred.Provider, "anthropic")
+	}
+	if gotBody["code"] != "the-code" {
+		t.Errorf("code = %q, want %q", gotBody["code"], "the-code")
+	}
+	if gotBody["state"] == "" || gotBody["code_verifier"] == "" {
+		t.Error("token request missing state or code_verifier")
+	}
+}
+
+func TestLoginManualCodeStateMismatch(t *testing.T) {
+	cfg := AnthropicOAuthConfig()
+	cfg.TokenURL = "http://127.0.0.1:0/unused"
+
+	if _, err := loginManualCode(cfg, strings.NewReader("code#wrong-state\n")); err == nil {
+		t.Error("expected state mismatch error")
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var refreshGroup singleflight.Group
+
+// RefreshStoredCredential refreshes the stored OAuth credential for a provider
+// account and persists the result. Concurrent callers for the same account
+// share a single refresh, so a rotated refresh token is never overwritten by a
+// racing request that was still holding the old one.
+func RefreshStoredCredential(provider, account string, cfg OAuthProviderConfig) (*AuthCredential, error) {
+	// Resolve the default account first, so callers naming it and callers
+	// naming none share the refresh
+	store, err := LoadStore()
+	if err != nil {
+		return nil, fmt.Errorf("loading auth credentials: %w", err)
+	}
+	account = store.resolveAccount(provider, account)
+	key := provider + "/" + account
+
+	v, err, _ := refreshGroup.Do(key, func() (interface{}, error) {
+		// Re-read the store: a refresh that finished just before this one
+		// started has already rotated the token.
+		cred, err := GetCredential(provider, account)
+		if err != nil {
+			return nil, fmt.Errorf("loading auth credentials: %w", err)
+		}
+		if cred == nil {
+			return nil, fmt.Errorf("no credentials for %s", key)
+		}
+		if !cred.NeedsRefresh() {
+			return cred, nil
+		}
+
+		refreshed, err := RefreshAccessToken(cred, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("refreshing token: %w", err)
+		}
+		if err := SetCredential(provider, account, refreshed); err != nil {
+			return nil, fmt.Errorf("saving refreshed token: %w", err)
+		}
+		return refreshed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*AuthCredential), nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshStoredCredentialSingleflight(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "fresh-access",
+			"refresh_token": "rotated-refresh",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	if err := SetCredential("anthropic", "work", &AuthCredential{
+		AccessToken:  "stale-access",
+		RefreshToken: "original-refresh",
+		ExpiresAt:    time.Now().Add(-time.Minute),
+		Provider:     "anthropic",
+		AuthMethod:   "oauth",
+	}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+	if err := SetDefaultAccount("anthropic", "work"); err != nil {
+		t.Fatalf("SetDefaultAccount() error: %v", err)
+	}
+
+	cfg := OAuthProviderConfig{Provider: "anthropic", ClientID: "test", TokenURL: server.URL, JSONTokenRequests: true}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		// Naming the default account or none is the same refresh
+		account := ""
+		if i%2 == 1 {
+			account = "work"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cred, err := RefreshStoredCredential("anthropic", account, cfg)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if cred.AccessToken != "fresh-access" {
+				t.Errorf("AccessToken = %q, want %q", cred.AccessToken, "fresh-access")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("RefreshStoredCredential() error: %v", err)
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("token endpoint called %d times, want 1", got)
+	}
+
+	stored, err := GetCredential("anthropic", "")
+	if err != nil {
+		t.Fatalf("GetCredential() error: %v", err)
+	}
+	if stored.RefreshToken != "rotated-refresh" {
+		t.Errorf("stored RefreshToken = %q, want %q", stored.RefreshToken, "rotated-refresh")
+	}
+}
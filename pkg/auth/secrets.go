@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// secretService is the service name picoclaw's own items are stored under in
+// the system credential store.
+const secretService = "picoclaw"
+
+// secretStore returns the store used for token secrets; replaced in tests.
+var secretStore = SystemCredentialStore
+
+// credentialSecrets is the part of a credential kept out of auth.json when
+// the system store is in use.
+type credentialSecrets struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// useSecretStore reports whether new credentials should keep their tokens in
+// the system credential store. PICOCLAW_AUTH_STORE=system|file overrides the
+// platform default, which is the keychain on macOS and auth.json elsewhere.
+func useSecretStore() bool {
+	switch strings.ToLower(os.Getenv("PICOCLAW_AUTH_STORE")) {
+	case "system", "keychain":
+		return true
+	case "file":
+		return false
+	}
+	return runtime.GOOS == "darwin"
+}
+
+// secretAccount is the store account name for provider/account.
+func secretAccount(provider, account string) string {
+	return provider + "/" + account
+}
+
+// sealSecrets moves the tokens of cred into the system store and returns the
+// copy to write to auth.json. If the store cannot be written the credential
+// is returned unchanged so the login still succeeds.
+func sealSecrets(provider, account string, cred *AuthCredential) *AuthCredential {
+	if !useSecretStore() || cred == nil {
+		return cred
+	}
+	store := secretStore()
+	if store == nil {
+		return cred
+	}
+
+	ref := secretAccount(provider, account)
+	data, err := json.Marshal(credentialSecrets{AccessToken: cred.AccessToken, RefreshToken: cred.RefreshToken})
+	if err != nil {
+		return cred
+	}
+	if err := store.Set(secretService, ref, string(data)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write to %s, keeping token in auth.json: %v\n", store.Name(), err)
+		return cred
+	}
+
+	sealed := *cred
+	sealed.AccessToken = ""
+	sealed.RefreshToken = ""
+	sealed.SecretRef = ref
+	return &sealed
+}
+
+// openSecrets returns cred with its tokens loaded from the system store when
+// they were sealed by sealSecrets.
+func openSecrets(cred *AuthCredential) (*AuthCredential, error) {
+	if cred == nil || cred.SecretRef == "" {
+		return cred, nil
+	}
+	store := secretStore()
+	if store == nil {
+		return nil, fmt.Errorf("credential %s is kept in the system credential store, which is unavailable", cred.SecretRef)
+	}
+
+	data, err := store.Get(secretService, cred.SecretRef)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s from %s: %w", cred.SecretRef, store.Name(), err)
+	}
+	var secrets credentialSecrets
+	if err := json.Unmarshal([]byte(data), &secrets); err != nil {
+		return nil, fmt.Errorf("parsing %s from %s: %w", cred.SecretRef, store.Name(), err)
+	}
+
+	opened := *cred
+	opened.AccessToken = secrets.AccessToken
+	opened.RefreshToken = secrets.RefreshToken
+	opened.SecretRef = ""
+	return &opened, nil
+}
+
+// deleteSecrets removes the system store item behind cred, if any.
+func deleteSecrets(cred *AuthCredential) error {
+	if cred == nil || cred.SecretRef == "" {
+		return nil
+	}
+	store := secretStore()
+	if store == nil {
+		return nil
+	}
+	if err := store.Delete(secretService, cred.SecretRef); err != nil && !errors.Is(err, ErrCredentialNotFound) {
+		return fmt.Errorf("removing %s from %s: %w", cred.SecretRef, store.Name(), err)
+	}
+	return nil
+}
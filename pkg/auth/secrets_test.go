@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoryCredentialStore is an in-process CredentialStore for tests.
+type memoryCredentialStore map[string]string
+
+func (memoryCredentialStore) Name() string { return "memory" }
+
+func (m memoryCredentialStore) Get(service, account string) (string, error) {
+	secret, ok := m[service+"/"+account]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return secret, nil
+}
+
+func (m memoryCredentialStore) Set(service, account, secret string) error {
+	m[service+"/"+account] = secret
+	return nil
+}
+
+func (m memoryCredentialStore) Delete(service, account string) error {
+	if _, ok := m[service+"/"+account]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(m, service+"/"+account)
+	return nil
+}
+
+// Keep tests away from the real keychain on macOS.
+func init() {
+	secretStore = func() CredentialStore { return memoryCredentialStore{} }
+}
+
+func useMemorySecretStore(t *testing.T) memoryCredentialStore {
+	t.Helper()
+	mem := memoryCredentialStore{}
+	orig := secretStore
+	secretStore = func() CredentialStore { return mem }
+	t.Cleanup(func() { secretStore = orig })
+	return mem
+}
+
+func TestSetCredentialSystemStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PICOCLAW_AUTH_STORE", "system")
+	mem := useMemorySecretStore(t)
+
+	cred := &AuthCredential{
+		AccessToken:  "sk-ant-oat01-secret",
+		RefreshToken: "refresh-secret",
+		Provider:     "anthropic",
+		AuthMethod:   "oauth",
+	}
+	if err := SetCredential("anthropic", "work", cred); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".picoclaw", "auth.json"))
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if strings.Contains(string(data), "oat01") || strings.Contains(string(data), "refresh-secret") {
+		t.Errorf("auth.json contains token material: %s", data)
+	}
+
+	var secrets credentialSecrets
+	if err := json.Unmarshal([]byte(mem["picoclaw/anthropic/work"]), &secrets); err != nil {
+		t.Fatalf("store item not written: %v", err)
+	}
+	if secrets.RefreshToken != "refresh-secret" {
+		t.Errorf("stored RefreshToken = %q, want %q", secrets.RefreshToken, "refresh-secret")
+	}
+
+	got, err := GetCredential("anthropic", "work")
+	if err != nil {
+		t.Fatalf("GetCredential() error: %v", err)
+	}
+	if got.AccessToken != "sk-ant-oat01-secret" || got.SecretRef != "" {
+		t.Errorf("GetCredential() = %+v, want tokens loaded from store", got)
+	}
+
+	if err := DeleteCredential("anthropic", "work"); err != nil {
+		t.Fatalf("DeleteCredential() error: %v", err)
+	}
+	if len(mem) != 0 {
+		t.Errorf("store still holds %d items after delete", len(mem))
+	}
+}
+
+func TestSetCredentialFileStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PICOCLAW_AUTH_STORE", "file")
+	mem := useMemorySecretStore(t)
+
+	if err := SetCredential("openai", "", &AuthCredential{AccessToken: "tok", Provider: "openai", AuthMethod: "oauth"}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+	if len(mem) != 0 {
+		t.Errorf("file mode wrote %d items to the system store", len(mem))
+	}
+	got, _ := GetCredential("openai", "")
+	if got == nil || got.AccessToken != "tok" {
+		t.Errorf("GetCredential() = %+v, want token from auth.json", got)
+	}
+}
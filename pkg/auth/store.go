@@ -2,9 +2,13 @@ package auth
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/filelock"
 )
 
 type AuthCredential struct {
@@ -14,10 +18,124 @@ type AuthCredential struct {
 	ExpiresAt    time.Time `json:"expires_at,omitempty"`
 	Provider     string    `json:"provider"`
 	AuthMethod   string    `json:"auth_method"`
+
+	// Models and ValidatedAt record the result of the last ValidateCredential
+	// call: the model IDs the credential could list, and when.
+	Models      []string  `json:"models,omitempty"`
+	ValidatedAt time.Time `json:"validated_at,omitempty"`
+
+	// SecretRef names the system credential store item holding the tokens
+	// when they are not kept in auth.json.
+	SecretRef string `json:"secret_ref,omitempty"`
 }
 
+// DefaultAccount is the account name used when none is given. Its credential
+// lives in Credentials so auth files written before named accounts existed
+// keep working.
+const DefaultAccount = "default"
+
 type AuthStore struct {
 	Credentials map[string]*AuthCredential `json:"credentials"`
+
+	// Accounts holds additional named accounts per provider, e.g.
+	// accounts["openai"]["work"].
+	Accounts map[string]map[string]*AuthCredential `json:"accounts,omitempty"`
+
+	// DefaultAccounts selects which account is used for a provider when the
+	// caller does not name one. Unset means DefaultAccount.
+	DefaultAccounts map[string]string `json:"default_accounts,omitempty"`
+}
+
+// resolveAccount maps an empty account name to the provider's default.
+func (s *AuthStore) resolveAccount(provider, account string) string {
+	if account != "" {
+		return account
+	}
+	if name := s.DefaultAccounts[provider]; name != "" {
+		return name
+	}
+	return DefaultAccount
+}
+
+// Get returns the credential for provider/account, or nil if none is stored.
+// An empty account selects the provider's default account.
+func (s *AuthStore) Get(provider, account string) *AuthCredential {
+	account = s.resolveAccount(provider, account)
+	if account == DefaultAccount {
+		return s.Credentials[provider]
+	}
+	return s.Accounts[provider][account]
+}
+
+// Set stores cred for provider/account. An empty account selects the
+// provider's default account.
+func (s *AuthStore) Set(provider, account string, cred *AuthCredential) {
+	account = s.resolveAccount(provider, account)
+	if account == DefaultAccount {
+		s.Credentials[provider] = cred
+		return
+	}
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]map[string]*AuthCredential)
+	}
+	if s.Accounts[provider] == nil {
+		s.Accounts[provider] = make(map[string]*AuthCredential)
+	}
+	s.Accounts[provider][account] = cred
+}
+
+// Delete removes provider/account. Removing the default account clears the
+// default-account setting so lookups fall back to DefaultAccount.
+func (s *AuthStore) Delete(provider, account string) {
+	account = s.resolveAccount(provider, account)
+	if account == DefaultAccount {
+		delete(s.Credentials, provider)
+	} else if accounts := s.Accounts[provider]; accounts != nil {
+		delete(accounts, account)
+		if len(accounts) == 0 {
+			delete(s.Accounts, provider)
+		}
+	}
+	if s.DefaultAccounts[provider] == account {
+		delete(s.DefaultAccounts, provider)
+	}
+}
+
+// AccountNames returns the stored account names for provider, sorted.
+func (s *AuthStore) AccountNames(provider string) []string {
+	var names []string
+	if _, ok := s.Credentials[provider]; ok {
+		names = append(names, DefaultAccount)
+	}
+	for name := range s.Accounts[provider] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Providers returns every provider with at least one stored account, sorted.
+func (s *AuthStore) Providers() []string {
+	seen := make(map[string]bool)
+	for provider := range s.Credentials {
+		seen[provider] = true
+	}
+	for provider, accounts := range s.Accounts {
+		if len(accounts) > 0 {
+			seen[provider] = true
+		}
+	}
+	providers := make([]string, 0, len(seen))
+	for provider := range seen {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// DefaultAccountName returns the account used for provider when none is named.
+func (s *AuthStore) DefaultAccountName(provider string) string {
+	return s.resolveAccount(provider, "")
 }
 
 func (c *AuthCredential) IsExpired() bool {
@@ -39,6 +157,11 @@ func authFilePath() string {
 	return filepath.Join(home, ".picoclaw", "auth.json")
 }
 
+// StorePath returns the path of the credential store, auth.json.
+func StorePath() string {
+	return authFilePath()
+}
+
 func LoadStore() (*AuthStore, error) {
 	path := authFilePath()
 	data, err := os.ReadFile(path)
@@ -59,51 +182,147 @@ func LoadStore() (*AuthStore, error) {
 	return &store, nil
 }
 
+// SaveStore replaces the stored credentials with store. To change one
+// credential, use SetCredential, which keeps what other processes saved
+// since store was loaded.
 func SaveStore(store *AuthStore) error {
-	path := authFilePath()
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	unlock, err := lockStore()
+	if err != nil {
 		return err
 	}
+	defer unlock()
+	return writeStore(store)
+}
 
-	data, err := json.MarshalIndent(store, "", "  ")
+// lockStore takes the advisory lock serializing changes to auth.json
+// across processes. It is held on a separate file, as auth.json itself is
+// replaced on every write.
+func lockStore() (unlock func(), err error) {
+	return filelock.Lock(authFilePath() + ".lock")
+}
+
+// updateStore applies update to the stored credentials under the lock,
+// reading them afresh so concurrent changes from other processes, such as
+// a token refresh, are not lost.
+func updateStore(update func(store *AuthStore) error) error {
+	unlock, err := lockStore()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0600)
-}
+	defer unlock()
 
-func GetCredential(provider string) (*AuthCredential, error) {
 	store, err := LoadStore()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	cred, ok := store.Credentials[provider]
-	if !ok {
-		return nil, nil
+	if err := update(store); err != nil {
+		return err
 	}
-	return cred, nil
+	return writeStore(store)
 }
 
-func SetCredential(provider string, cred *AuthCredential) error {
-	store, err := LoadStore()
+// writeStore writes store to a temporary file and renames it over
+// auth.json, so readers never see a partly written file.
+func writeStore(store *AuthStore) error {
+	path := authFilePath()
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "auth-*.json.tmp")
 	if err != nil {
 		return err
 	}
-	store.Credentials[provider] = cred
-	return SaveStore(store)
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
-func DeleteCredential(provider string) error {
+// GetCredential returns the credential stored for provider and account, or
+// nil if there is none. An empty account selects the provider's default.
+func GetCredential(provider, account string) (*AuthCredential, error) {
 	store, err := LoadStore()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	delete(store.Credentials, provider)
-	return SaveStore(store)
+	return openSecrets(store.Get(provider, account))
+}
+
+// SetCredential stores cred for provider/account. On macOS the tokens go to
+// the keychain and auth.json keeps only a reference to them.
+func SetCredential(provider, account string, cred *AuthCredential) error {
+	return updateStore(func(store *AuthStore) error {
+		account = store.resolveAccount(provider, account)
+		store.Set(provider, account, sealSecrets(provider, account, cred))
+		return nil
+	})
+}
+
+func DeleteCredential(provider, account string) error {
+	return updateStore(func(store *AuthStore) error {
+		if err := deleteSecrets(store.Get(provider, account)); err != nil {
+			return err
+		}
+		store.Delete(provider, account)
+		return nil
+	})
+}
+
+// SetDefaultAccount makes account the one used for provider when callers
+// do not name an account. The account must already be stored.
+func SetDefaultAccount(provider, account string) error {
+	return updateStore(func(store *AuthStore) error {
+		if store.Get(provider, account) == nil {
+			return fmt.Errorf("no %s credential stored for account %q", provider, account)
+		}
+		if account == DefaultAccount {
+			delete(store.DefaultAccounts, provider)
+		} else {
+			if store.DefaultAccounts == nil {
+				store.DefaultAccounts = make(map[string]string)
+			}
+			store.DefaultAccounts[provider] = account
+		}
+		return nil
+	})
 }
 
 func DeleteAllCredentials() error {
+	unlock, err := lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if store, err := LoadStore(); err == nil {
+		for _, provider := range store.Providers() {
+			for _, account := range store.AccountNames(provider) {
+				if err := deleteSecrets(store.Get(provider, account)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	path := authFilePath()
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return err
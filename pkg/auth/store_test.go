@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -65,11 +67,11 @@ func TestStoreRoundtrip(t *testing.T) {
 		AuthMethod:   "oauth",
 	}
 
-	if err := SetCredential("openai", cred); err != nil {
+	if err := SetCredential("openai", "", cred); err != nil {
 		t.Fatalf("SetCredential() error: %v", err)
 	}
 
-	loaded, err := GetCredential("openai")
+	loaded, err := GetCredential("openai", "")
 	if err != nil {
 		t.Fatalf("GetCredential() error: %v", err)
 	}
@@ -98,7 +100,7 @@ func TestStoreFilePermissions(t *testing.T) {
 		Provider:    "openai",
 		AuthMethod:  "oauth",
 	}
-	if err := SetCredential("openai", cred); err != nil {
+	if err := SetCredential("openai", "", cred); err != nil {
 		t.Fatalf("SetCredential() error: %v", err)
 	}
 
@@ -122,14 +124,14 @@ func TestStoreMultiProvider(t *testing.T) {
 	openaiCred := &AuthCredential{AccessToken: "openai-token", Provider: "openai", AuthMethod: "oauth"}
 	anthropicCred := &AuthCredential{AccessToken: "anthropic-token", Provider: "anthropic", AuthMethod: "token"}
 
-	if err := SetCredential("openai", openaiCred); err != nil {
+	if err := SetCredential("openai", "", openaiCred); err != nil {
 		t.Fatalf("SetCredential(openai) error: %v", err)
 	}
-	if err := SetCredential("anthropic", anthropicCred); err != nil {
+	if err := SetCredential("anthropic", "", anthropicCred); err != nil {
 		t.Fatalf("SetCredential(anthropic) error: %v", err)
 	}
 
-	loaded, err := GetCredential("openai")
+	loaded, err := GetCredential("openai", "")
 	if err != nil {
 		t.Fatalf("GetCredential(openai) error: %v", err)
 	}
@@ -137,7 +139,7 @@ func TestStoreMultiProvider(t *testing.T) {
 		t.Errorf("openai token = %q, want %q", loaded.AccessToken, "openai-token")
 	}
 
-	loaded, err = GetCredential("anthropic")
+	loaded, err = GetCredential("anthropic", "")
 	if err != nil {
 		t.Fatalf("GetCredential(anthropic) error: %v", err)
 	}
@@ -153,15 +155,15 @@ func TestDeleteCredential(t *testing.T) {
 	defer os.Setenv("HOME", origHome)
 
 	cred := &AuthCredential{AccessToken: "to-delete", Provider: "openai", AuthMethod: "oauth"}
-	if err := SetCredential("openai", cred); err != nil {
+	if err := SetCredential("openai", "", cred); err != nil {
 		t.Fatalf("SetCredential() error: %v", err)
 	}
 
-	if err := DeleteCredential("openai"); err != nil {
+	if err := DeleteCredential("openai", ""); err != nil {
 		t.Fatalf("DeleteCredential() error: %v", err)
 	}
 
-	loaded, err := GetCredential("openai")
+	loaded, err := GetCredential("openai", "")
 	if err != nil {
 		t.Fatalf("GetCredential() error: %v", err)
 	}
@@ -187,3 +189,90 @@ func TestLoadStoreEmpty(t *testing.T) {
 		t.Errorf("expected empty credentials, got %d", len(store.Credentials))
 	}
 }
+
+func TestStoreNamedAccounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	personal := &AuthCredential{AccessToken: "personal-token", Provider: "openai", AuthMethod: "oauth"}
+	work := &AuthCredential{AccessToken: "work-token", Provider: "openai", AuthMethod: "oauth"}
+
+	if err := SetCredential("openai", "", personal); err != nil {
+		t.Fatalf("SetCredential(default) error: %v", err)
+	}
+	if err := SetCredential("openai", "work", work); err != nil {
+		t.Fatalf("SetCredential(work) error: %v", err)
+	}
+
+	loaded, _ := GetCredential("openai", "")
+	if loaded == nil || loaded.AccessToken != "personal-token" {
+		t.Fatalf("default account token = %v, want personal-token", loaded)
+	}
+	loaded, _ = GetCredential("openai", "work")
+	if loaded == nil || loaded.AccessToken != "work-token" {
+		t.Fatalf("work account token = %v, want work-token", loaded)
+	}
+
+	if err := SetDefaultAccount("openai", "work"); err != nil {
+		t.Fatalf("SetDefaultAccount() error: %v", err)
+	}
+	loaded, _ = GetCredential("openai", "")
+	if loaded == nil || loaded.AccessToken != "work-token" {
+		t.Fatalf("default after switch = %v, want work-token", loaded)
+	}
+	loaded, _ = GetCredential("openai", DefaultAccount)
+	if loaded == nil || loaded.AccessToken != "personal-token" {
+		t.Fatalf("explicit default account = %v, want personal-token", loaded)
+	}
+
+	store, _ := LoadStore()
+	names := store.AccountNames("openai")
+	if len(names) != 2 || names[0] != "default" || names[1] != "work" {
+		t.Errorf("AccountNames() = %v, want [default work]", names)
+	}
+
+	if err := DeleteCredential("openai", "work"); err != nil {
+		t.Fatalf("DeleteCredential(work) error: %v", err)
+	}
+	loaded, _ = GetCredential("openai", "")
+	if loaded == nil || loaded.AccessToken != "personal-token" {
+		t.Errorf("default after deleting work = %v, want personal-token", loaded)
+	}
+}
+
+func TestSetDefaultAccountUnknown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := SetDefaultAccount("openai", "missing"); err == nil {
+		t.Error("expected error for unknown account")
+	}
+}
+
+func TestSetCredential_Concurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cred := &AuthCredential{AccessToken: fmt.Sprintf("token-%d", i), Provider: "openai", AuthMethod: "token"}
+			if err := SetCredential("openai", fmt.Sprintf("account-%d", i), cred); err != nil {
+				t.Errorf("SetCredential(%d) error: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	store, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	if names := store.AccountNames("openai"); len(names) != 20 {
+		t.Errorf("AccountNames() = %v, want all 20 accounts kept", names)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(authFilePath()), "*.tmp"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
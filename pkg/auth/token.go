@@ -37,6 +37,8 @@ func providerDisplayName(provider string) string {
 		return "console.anthropic.com"
 	case "openai":
 		return "platform.openai.com"
+	case "github":
+		return "github.com/settings/tokens"
 	default:
 		return provider
 	}
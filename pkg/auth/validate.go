@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrValidationUnsupported is returned when a credential cannot be checked
+// with a cheap request, e.g. ChatGPT OAuth tokens that only work against the
+// Codex backend.
+var ErrValidationUnsupported = errors.New("credential validation not supported")
+
+// Base URLs used for validation requests; overridden in tests.
+var (
+	anthropicValidationBase = "https://api.anthropic.com"
+	openaiValidationBase    = "https://api.openai.com"
+)
+
+// ValidateCredential makes a models-list request with cred and records the
+// models it can access. Bad keys fail here instead of mid-run. On success
+// cred.Models and cred.ValidatedAt are updated.
+func ValidateCredential(ctx context.Context, cred *AuthCredential) error {
+	var req *http.Request
+	var err error
+
+	switch cred.Provider {
+	case "anthropic":
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, anthropicValidationBase+"/v1/models?limit=100", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("anthropic-version", "2023-06-01")
+		if cred.AuthMethod == "oauth" || strings.HasPrefix(cred.AccessToken, "sk-ant-oat") {
+			req.Header.Set("Authorization", "Bearer "+cred.AccessToken)
+			req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+		} else {
+			req.Header.Set("x-api-key", cred.AccessToken)
+		}
+	case "openai":
+		if cred.AuthMethod == "oauth" {
+			return ErrValidationUnsupported
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, openaiValidationBase+"/v1/models", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+cred.AccessToken)
+	default:
+		return ErrValidationUnsupported
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("validating credential: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading validation response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("credential rejected by %s (HTTP %d): %s", cred.Provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("validation request failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return fmt.Errorf("parsing models response: %w", err)
+	}
+
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	cred.Models = models
+	cred.ValidatedAt = time.Now()
+	return nil
+}
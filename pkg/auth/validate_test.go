@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateCredentialAnthropicAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("Path = %q, want %q", r.URL.Path, "/v1/models")
+		}
+		if r.Header.Get("x-api-key") != "sk-ant-api03-good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-5"},{"id":"claude-haiku-4-5"}],"has_more":false}`))
+	}))
+	defer server.Close()
+	orig := anthropicValidationBase
+	anthropicValidationBase = server.URL
+	defer func() { anthropicValidationBase = orig }()
+
+	cred := &AuthCredential{AccessToken: "sk-ant-api03-good", Provider: "anthropic", AuthMethod: "token"}
+	if err := ValidateCredential(context.Background(), cred); err != nil {
+		t.Fatalf("ValidateCredential() error: %v", err)
+	}
+	if len(cred.Models) != 2 || cred.Models[0] != "claude-sonnet-4-5" {
+		t.Errorf("Models = %v, want [claude-sonnet-4-5 claude-haiku-4-5]", cred.Models)
+	}
+	if cred.ValidatedAt.IsZero() {
+		t.Error("ValidatedAt not set")
+	}
+
+	bad := &AuthCredential{AccessToken: "sk-ant-api03-bad", Provider: "anthropic", AuthMethod: "token"}
+	if err := ValidateCredential(context.Background(), bad); err == nil {
+		t.Error("expected error for rejected key")
+	}
+	if !bad.ValidatedAt.IsZero() {
+		t.Error("ValidatedAt set for rejected key")
+	}
+}
+
+func TestValidateCredentialAnthropicOAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-ant-oat01-abc" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("anthropic-beta") == "" {
+			t.Error("missing anthropic-beta header")
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+	orig := anthropicValidationBase
+	anthropicValidationBase = server.URL
+	defer func() { anthropicValidationBase = orig }()
+
+	cred := &AuthCredential{AccessToken: "sk-ant-oat01-abc", Provider: "anthropic", AuthMethod: "oauth"}
+	if err := ValidateCredential(context.Background(), cred); err != nil {
+		t.Fatalf("ValidateCredential() error: %v", err)
+	}
+}
+
+func TestValidateCredentialUnsupported(t *testing.T) {
+	cred := &AuthCredential{AccessToken: "chatgpt-token", Provider: "openai", AuthMethod: "oauth"}
+	if err := ValidateCredential(context.Background(), cred); !errors.Is(err, ErrValidationUnsupported) {
+		t.Errorf("ValidateCredential() error = %v, want ErrValidationUnsupported", err)
+	}
+}
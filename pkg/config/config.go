@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/caarlos0/env/v11"
@@ -48,10 +50,54 @@ type Config struct {
 	Channels  ChannelsConfig  `json:"channels"`
 	Providers ProvidersConfig `json:"providers"`
 	Gateway   GatewayConfig   `json:"gateway"`
+	Serve     ServeConfig     `json:"serve"`
 	Tools     ToolsConfig     `json:"tools"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
-	mu        sync.RWMutex
+
+	// MCP lists Model Context Protocol servers whose tools the agent uses.
+	MCP MCPConfig `json:"mcp,omitempty"`
+
+	// Plugins are executables that add tools over a JSON-RPC stdio
+	// protocol.
+	Plugins PluginsConfig `json:"plugins,omitempty"`
+
+	// Cache stores model responses so identical requests are answered
+	// without calling the provider again.
+	Cache CacheConfig `json:"cache,omitempty"`
+
+	// RateLimit is where providers' requests_per_minute budgets are
+	// counted, so several processes can share them.
+	RateLimit RateLimitConfig `json:"rate_limit,omitempty"`
+
+	// Routing picks the provider and model per request by rules.
+	Routing RoutingConfig `json:"routing,omitempty"`
+
+	// Audit keeps an append-only record of model responses and tool calls.
+	Audit AuditConfig `json:"audit,omitempty"`
+
+	// Policy limits which tools the agent may call and what they may do.
+	Policy PolicyConfig `json:"policy,omitempty"`
+
+	// ModelAliases maps short names such as "fast" to "provider/model", so
+	// commands and settings need not spell out vendor model names. Without
+	// a slash the provider is picked from the model name.
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+
+	// ModelCatalog adds models to the built-in catalog of context sizes,
+	// parameters and prices, or corrects what it says about them.
+	ModelCatalog []ModelCatalogEntry `json:"model_catalog,omitempty"`
+
+	// Credentials selects an external secret manager for provider API keys.
+	Credentials CredentialsConfig `json:"credentials,omitempty"`
+
+	// Profiles are named overrides selected with --profile, PICOCLAW_PROFILE
+	// or default_profile.
+	DefaultProfile string                   `json:"default_profile,omitempty"`
+	Profiles       map[string]ProfileConfig `json:"profiles,omitempty"`
+
+	activeProfile string
+	mu            sync.RWMutex
 }
 
 type AgentsConfig struct {
@@ -66,6 +112,11 @@ type AgentDefaults struct {
 	MaxTokens           int     `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int     `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	MaxParallelTools    int     `json:"max_parallel_tools,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_PARALLEL_TOOLS"` // tool calls run at once; 1 runs them in order
+	SessionFormat       string  `json:"session_format,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_SESSION_FORMAT"`         // json (default) or jsonl
+	CodeExecution       bool    `json:"code_execution,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_CODE_EXECUTION"`         // offer Anthropic's code execution tool to Claude
+	AutoContinue        int     `json:"auto_continue,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_AUTO_CONTINUE"`           // follow-up requests to finish a reply cut off by max_tokens
+	Preconnect          bool    `json:"preconnect,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_PRECONNECT"`                 // open a connection to the provider's API at startup
 }
 
 type ChannelsConfig struct {
@@ -179,6 +230,16 @@ type ProvidersConfig struct {
 	ShengSuanYun  ProviderConfig `json:"shengsuanyun"`
 	DeepSeek      ProviderConfig `json:"deepseek"`
 	GitHubCopilot ProviderConfig `json:"github_copilot"`
+	GitHubModels  ProviderConfig `json:"github_models"`
+	Cohere        ProviderConfig `json:"cohere"`
+	Together      ProviderConfig `json:"together"`
+	Fireworks     ProviderConfig `json:"fireworks"`
+	Perplexity    ProviderConfig `json:"perplexity"`
+	DashScope     ProviderConfig `json:"dashscope"`
+	AzureFoundry  ProviderConfig `json:"azure_foundry"`
+	Ollama        ProviderConfig `json:"ollama"`
+	LMStudio      ProviderConfig `json:"lmstudio"`
+	LlamaCpp      ProviderConfig `json:"llamacpp"`
 }
 
 type ProviderConfig struct {
@@ -186,7 +247,64 @@ type ProviderConfig struct {
 	APIBase     string `json:"api_base" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_BASE"`
 	Proxy       string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
 	AuthMethod  string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
-	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio`, `grpc` or `api`
+	Account     string `json:"account,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_ACCOUNT"`           // named auth store account, empty for the default
+	// Region picks the regional endpoint when APIBase is empty: "cn" or
+	// "intl" for Moonshot, and "cn", "intl" or "us" for DashScope.
+	Region string `json:"region,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_REGION"`
+	// APIKeySecret names the secret holding the API key in the configured
+	// credentials backend; used when APIKey is empty.
+	APIKeySecret string `json:"api_key_secret,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_KEY_SECRET"`
+	// MaxConcurrent bounds the requests in flight to this provider across
+	// the process; further requests wait their turn. 0 is unlimited.
+	MaxConcurrent int `json:"max_concurrent,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_MAX_CONCURRENT"`
+	// RequestsPerMinute bounds the requests sent to this provider each
+	// minute, counted where rate_limit says; further requests wait for the
+	// next minute. 0 is unlimited.
+	RequestsPerMinute int `json:"requests_per_minute,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_REQUESTS_PER_MINUTE"`
+	// StreamStallTimeout abandons a streamed response after that many
+	// seconds without an event, and StreamStallResumes is how many times
+	// it is then requested again from the text received so far. 0
+	// disables the check.
+	StreamStallTimeout int `json:"stream_stall_timeout,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_STREAM_STALL_TIMEOUT"`
+	StreamStallResumes int `json:"stream_stall_resumes,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_STREAM_STALL_RESUMES"`
+	// DefaultModel replaces the provider's built-in default model, used
+	// when agents.defaults.model is empty.
+	DefaultModel string `json:"default_model,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_DEFAULT_MODEL"`
+	// Betas lists anthropic-beta flags sent with every request; Anthropic
+	// only.
+	Betas []string `json:"betas,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_BETAS"`
+	// Organization and Project are the OpenAI organization and project
+	// requests are billed to, and API is "responses" (the default) or
+	// "chat_completions"; OpenAI API keys only.
+	Organization string `json:"organization,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_ORGANIZATION"`
+	Project      string `json:"project,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROJECT"`
+	API          string `json:"api,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_API"`
+}
+
+// CredentialsConfig selects where provider API keys referenced by
+// api_key_secret are read from: "vault", "aws" or "gcp".
+type CredentialsConfig struct {
+	Backend string             `json:"backend,omitempty" env:"PICOCLAW_CREDENTIALS_BACKEND"`
+	Vault   VaultBackendConfig `json:"vault,omitempty"`
+	AWS     AWSSecretsConfig   `json:"aws,omitempty"`
+	GCP     GCPSecretsConfig   `json:"gcp,omitempty"`
+}
+
+type VaultBackendConfig struct {
+	Address   string `json:"address,omitempty" env:"PICOCLAW_CREDENTIALS_VAULT_ADDRESS"`
+	Token     string `json:"token,omitempty" env:"PICOCLAW_CREDENTIALS_VAULT_TOKEN"`
+	Namespace string `json:"namespace,omitempty" env:"PICOCLAW_CREDENTIALS_VAULT_NAMESPACE"`
+	Mount     string `json:"mount,omitempty" env:"PICOCLAW_CREDENTIALS_VAULT_MOUNT"`
+}
+
+type AWSSecretsConfig struct {
+	Region  string `json:"region,omitempty" env:"PICOCLAW_CREDENTIALS_AWS_REGION"`
+	RoleARN string `json:"role_arn,omitempty" env:"PICOCLAW_CREDENTIALS_AWS_ROLE_ARN"` // assumed before reading secrets
+}
+
+type GCPSecretsConfig struct {
+	Project string `json:"project,omitempty" env:"PICOCLAW_CREDENTIALS_GCP_PROJECT"`
 }
 
 type GatewayConfig struct {
@@ -194,6 +312,38 @@ type GatewayConfig struct {
 	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
 }
 
+// ServeConfig configures "picoclaw serve", the OpenAI- and
+// Anthropic-compatible API.
+// Models maps the model names clients ask for to a provider and upstream
+// model; besides them, only model aliases and the default model are served. When APIKeys is
+// set, clients must send one of them as a bearer token or x-api-key; these
+// keys are not limited, unlike the virtual keys of "picoclaw serve keys".
+// GRPCPort, when set, also serves the gRPC API on that port.
+// AllowedOrigins lists the web origins, or "*", whose pages may open the
+// WebSocket endpoint besides the server's own. HealthCheckInterval is how
+// often, in seconds, the providers are probed for /healthz; zero disables
+// the probes.
+type ServeConfig struct {
+	Host                string                      `json:"host" env:"PICOCLAW_SERVE_HOST"`
+	Port                int                         `json:"port" env:"PICOCLAW_SERVE_PORT"`
+	GRPCPort            int                         `json:"grpc_port,omitempty" env:"PICOCLAW_SERVE_GRPC_PORT"`
+	APIKeys             []string                    `json:"api_keys,omitempty" env:"PICOCLAW_SERVE_API_KEYS"`
+	AllowedOrigins      []string                    `json:"allowed_origins,omitempty" env:"PICOCLAW_SERVE_ALLOWED_ORIGINS"`
+	HealthCheckInterval int                         `json:"health_check_interval" env:"PICOCLAW_SERVE_HEALTH_CHECK_INTERVAL"`
+	Models              map[string]ServeModelConfig `json:"models,omitempty"`
+	// RemoteAgent lets API clients run the agent, with the workspace tools,
+	// through the gRPC RunAgent call. Every tool call of a remote run needs
+	// the client's approval.
+	RemoteAgent bool `json:"remote_agent,omitempty" env:"PICOCLAW_SERVE_REMOTE_AGENT"`
+}
+
+// ServeModelConfig routes one served model name. An empty Model keeps the
+// name the client sent.
+type ServeModelConfig struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
 type BraveConfig struct {
 	Enabled    bool   `json:"enabled" env:"PICOCLAW_TOOLS_WEB_BRAVE_ENABLED"`
 	APIKey     string `json:"api_key" env:"PICOCLAW_TOOLS_WEB_BRAVE_API_KEY"`
@@ -210,8 +360,329 @@ type WebToolsConfig struct {
 	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo"`
 }
 
+// ExecToolsConfig controls the exec tool. Timeouts are in seconds; zero
+// values keep the built-in defaults.
+type ExecToolsConfig struct {
+	Timeout    int `json:"timeout,omitempty" env:"PICOCLAW_TOOLS_EXEC_TIMEOUT"`
+	MaxTimeout int `json:"max_timeout,omitempty" env:"PICOCLAW_TOOLS_EXEC_MAX_TIMEOUT"` // upper bound for per-call timeouts
+	MaxOutput  int `json:"max_output,omitempty" env:"PICOCLAW_TOOLS_EXEC_MAX_OUTPUT"`   // characters kept from command output
+	// EnvAllowlist limits which environment variables commands inherit, so
+	// API keys in the agent's environment are not exposed. Empty inherits
+	// everything.
+	EnvAllowlist  []string `json:"env_allowlist,omitempty" env:"PICOCLAW_TOOLS_EXEC_ENV_ALLOWLIST"`
+	AllowPatterns []string `json:"allow_patterns,omitempty" env:"PICOCLAW_TOOLS_EXEC_ALLOW_PATTERNS"`
+	// Sandbox runs commands in a container instead of on the host.
+	Sandbox ExecSandboxConfig `json:"sandbox,omitempty"`
+}
+
+// ExecSandboxConfig runs exec commands in a Docker or Podman container.
+// Runtime is "docker", "podman" or a path to either (default: whichever is
+// installed). The workspace is mounted at its own path; Mounts adds
+// "host:container[:ro]" volumes. Network is passed to --network (default:
+// "none"); Memory and CPUs to --memory and --cpus; User to --user.
+type ExecSandboxConfig struct {
+	Enabled bool     `json:"enabled" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_ENABLED"`
+	Runtime string   `json:"runtime,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_RUNTIME"`
+	Image   string   `json:"image,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_IMAGE"` // default: alpine:3
+	Mounts  []string `json:"mounts,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MOUNTS"`
+	Network string   `json:"network,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_NETWORK"`
+	Memory  string   `json:"memory,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MEMORY"`
+	CPUs    string   `json:"cpus,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_CPUS"`
+	User    string   `json:"user,omitempty" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_USER"`
+}
+
+// GitToolsConfig controls the git tools. git_status and git_diff are
+// registered when Enabled; git_commit and git_branch, which change the
+// repository, only with Write as well, and ask for approval when approvals
+// are on.
+type GitToolsConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TOOLS_GIT_ENABLED"`
+	Write   bool `json:"write" env:"PICOCLAW_TOOLS_GIT_WRITE"`
+}
+
+// GitHubConfig controls the github tool, which reads issues, pull requests,
+// reviews and checks with the token stored by `picoclaw auth login
+// --provider github`. Write adds github_write, which opens issues and pull
+// requests, comments and reviews. Repo is the default "owner/name";
+// APIBase points at a GitHub Enterprise API (default: api.github.com).
+type GitHubConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_TOOLS_GITHUB_ENABLED"`
+	Write   bool   `json:"write" env:"PICOCLAW_TOOLS_GITHUB_WRITE"`
+	Repo    string `json:"repo,omitempty" env:"PICOCLAW_TOOLS_GITHUB_REPO"`
+	Account string `json:"account,omitempty" env:"PICOCLAW_TOOLS_GITHUB_ACCOUNT"`
+	APIBase string `json:"api_base,omitempty" env:"PICOCLAW_TOOLS_GITHUB_API_BASE"`
+}
+
+// SSHToolsConfig enables the ssh tool, which runs commands on the hosts
+// named in Hosts and nowhere else. Host keys are checked against
+// KnownHosts (default: ~/.ssh/known_hosts); unknown hosts are refused.
+// Timeout and MaxTimeout are in seconds; MaxOutput in characters.
+type SSHToolsConfig struct {
+	Enabled    bool                     `json:"enabled" env:"PICOCLAW_TOOLS_SSH_ENABLED"`
+	Hosts      map[string]SSHHostConfig `json:"hosts,omitempty"`
+	KnownHosts string                   `json:"known_hosts,omitempty" env:"PICOCLAW_TOOLS_SSH_KNOWN_HOSTS"`
+	Timeout    int                      `json:"timeout,omitempty" env:"PICOCLAW_TOOLS_SSH_TIMEOUT"`         // default 60
+	MaxTimeout int                      `json:"max_timeout,omitempty" env:"PICOCLAW_TOOLS_SSH_MAX_TIMEOUT"` // default 600
+	MaxOutput  int                      `json:"max_output,omitempty" env:"PICOCLAW_TOOLS_SSH_MAX_OUTPUT"`   // default 10000
+}
+
+// SSHHostConfig is a host the ssh tool may connect to. Address is
+// host[:port] (default port 22). KeyFile is a private key; without one the
+// keys in the ssh-agent at SSH_AUTH_SOCK are used. AllowPatterns, when
+// set, are regular expressions one of which each command must match.
+type SSHHostConfig struct {
+	Address       string   `json:"address"`
+	User          string   `json:"user"`
+	KeyFile       string   `json:"key_file,omitempty"`
+	AllowPatterns []string `json:"allow_patterns,omitempty"`
+}
+
+// SQLToolsConfig enables the sql tool, which answers questions from the
+// databases named in Databases. Queries run read-only; databases with
+// Write set can also be changed through sql_write. MaxRows caps the rows
+// returned per query (default 100), MaxOutput the characters (default
+// 20000) and Timeout, in seconds, each query (default 30).
+type SQLToolsConfig struct {
+	Enabled   bool                         `json:"enabled" env:"PICOCLAW_TOOLS_SQL_ENABLED"`
+	Databases map[string]SQLDatabaseConfig `json:"databases,omitempty"`
+	MaxRows   int                          `json:"max_rows,omitempty" env:"PICOCLAW_TOOLS_SQL_MAX_ROWS"`
+	MaxOutput int                          `json:"max_output,omitempty" env:"PICOCLAW_TOOLS_SQL_MAX_OUTPUT"`
+	Timeout   int                          `json:"timeout,omitempty" env:"PICOCLAW_TOOLS_SQL_TIMEOUT"`
+}
+
+// SQLDatabaseConfig is a connection profile. Driver is "postgres", "mysql"
+// or "sqlite"; DSN is in that driver's format, with ${VAR} references
+// expanded from the environment so passwords can stay out of the file.
+type SQLDatabaseConfig struct {
+	Driver string `json:"driver"`
+	DSN    string `json:"dsn"`
+	Write  bool   `json:"write,omitempty"`
+}
+
+// HTTPToolsConfig enables the http_request tool, which calls APIs through
+// the profiles in Profiles only. Timeout is in seconds (default 30) and
+// MaxOutput in characters (default 20000).
+type HTTPToolsConfig struct {
+	Enabled   bool                         `json:"enabled" env:"PICOCLAW_TOOLS_HTTP_ENABLED"`
+	Profiles  map[string]HTTPProfileConfig `json:"profiles,omitempty"`
+	Timeout   int                          `json:"timeout,omitempty" env:"PICOCLAW_TOOLS_HTTP_TIMEOUT"`
+	MaxOutput int                          `json:"max_output,omitempty" env:"PICOCLAW_TOOLS_HTTP_MAX_OUTPUT"`
+}
+
+// HTTPProfileConfig is a set of hosts the http_request tool may call.
+// AllowedDomains are host names, "*.example.com" matching any subdomain
+// (default: the host of BaseURL), and relative URLs are resolved against
+// BaseURL. Headers and Query are added to every request, after the
+// model's own, so credentials can be injected without the model seeing
+// them; ${VAR} references in their values are read from the environment.
+// Methods lists the allowed methods (default: GET and HEAD).
+type HTTPProfileConfig struct {
+	BaseURL        string            `json:"base_url,omitempty"`
+	AllowedDomains []string          `json:"allowed_domains,omitempty"`
+	Methods        []string          `json:"methods,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Query          map[string]string `json:"query,omitempty"`
+}
+
+// ApprovalConfig makes the agent ask before running tools that change the
+// system (exec and the file writers). Tools lists extra tool names that need
+// approval; WebhookURL, when set, is asked instead of the terminal. Timeout
+// is in seconds.
+type ApprovalConfig struct {
+	Enabled    bool     `json:"enabled" env:"PICOCLAW_TOOLS_APPROVAL_ENABLED"`
+	Tools      []string `json:"tools,omitempty" env:"PICOCLAW_TOOLS_APPROVAL_TOOLS"`
+	WebhookURL string   `json:"webhook_url,omitempty" env:"PICOCLAW_TOOLS_APPROVAL_WEBHOOK_URL"`
+	Timeout    int      `json:"timeout,omitempty" env:"PICOCLAW_TOOLS_APPROVAL_TIMEOUT"`
+}
+
+// PolicyConfig restricts tool calls before they run. Tools lists the tools
+// the agent may call, by name or glob such as "mcp_github_*"; exec commands
+// must match one of AllowCommands, when set, and none of DenyCommands
+// (regular expressions); write_file, edit_file and append_file may only
+// change paths matching WritablePaths (globs where ** matches any number of
+// directories, relative to the workspace); web_fetch and http_request may
+// only reach hosts in AllowHosts, where "*.example.com" matches subdomains.
+// Empty lists allow everything.
+type PolicyConfig struct {
+	Tools         []string `json:"tools,omitempty" env:"PICOCLAW_POLICY_TOOLS"`
+	AllowCommands []string `json:"allow_commands,omitempty" env:"PICOCLAW_POLICY_ALLOW_COMMANDS"`
+	DenyCommands  []string `json:"deny_commands,omitempty" env:"PICOCLAW_POLICY_DENY_COMMANDS"`
+	WritablePaths []string `json:"writable_paths,omitempty" env:"PICOCLAW_POLICY_WRITABLE_PATHS"`
+	AllowHosts    []string `json:"allow_hosts,omitempty" env:"PICOCLAW_POLICY_ALLOW_HOSTS"`
+}
+
+// Validate reports command patterns that are not valid regular expressions.
+func (p PolicyConfig) Validate() error {
+	for _, pattern := range append(append([]string{}, p.AllowCommands...), p.DenyCommands...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("policy: invalid command pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// AuditConfig enables the audit log: one JSON line per model response and
+// tool call, with the session, model, a hash of the arguments, the result
+// size and the approval decision. A relative Path is resolved against the
+// workspace (default: <workspace>/state/audit.jsonl).
+type AuditConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_AUDIT_ENABLED"`
+	Path    string `json:"path,omitempty" env:"PICOCLAW_AUDIT_PATH"`
+}
+
+// RAGConfig enables the retrieve tool, which searches documents ingested
+// with "picoclaw rag ingest". Model names the embedding model; APIBase and
+// APIKey select an OpenAI-compatible embeddings endpoint when the chat
+// provider cannot embed. ChunkSize and ChunkOverlap are in characters.
+type RAGConfig struct {
+	Enabled      bool   `json:"enabled" env:"PICOCLAW_TOOLS_RAG_ENABLED"`
+	Model        string `json:"model,omitempty" env:"PICOCLAW_TOOLS_RAG_MODEL"`
+	APIBase      string `json:"api_base,omitempty" env:"PICOCLAW_TOOLS_RAG_API_BASE"`
+	APIKey       string `json:"api_key,omitempty" env:"PICOCLAW_TOOLS_RAG_API_KEY"`
+	ChunkSize    int    `json:"chunk_size,omitempty" env:"PICOCLAW_TOOLS_RAG_CHUNK_SIZE"`
+	ChunkOverlap int    `json:"chunk_overlap,omitempty" env:"PICOCLAW_TOOLS_RAG_CHUNK_OVERLAP"`
+	TopK         int    `json:"top_k,omitempty" env:"PICOCLAW_TOOLS_RAG_TOP_K"`
+}
+
+// CacheConfig controls the response cache. Backend is "memory" (the
+// default), "file" (JSON entries under Dir, default workspace/cache) or
+// "redis" (RedisURL, such as redis://:password@localhost:6379/0). TTL is in
+// seconds; 0 keeps entries until they are evicted. MaxEntries bounds the
+// memory and file backends; Redis relies on its own eviction policy.
+type CacheConfig struct {
+	Enabled    bool   `json:"enabled" env:"PICOCLAW_CACHE_ENABLED"`
+	Backend    string `json:"backend,omitempty" env:"PICOCLAW_CACHE_BACKEND"`
+	TTL        int    `json:"ttl,omitempty" env:"PICOCLAW_CACHE_TTL"`
+	MaxEntries int    `json:"max_entries,omitempty" env:"PICOCLAW_CACHE_MAX_ENTRIES"`
+	Dir        string `json:"dir,omitempty" env:"PICOCLAW_CACHE_DIR"`
+	RedisURL   string `json:"redis_url,omitempty" env:"PICOCLAW_CACHE_REDIS_URL"`
+}
+
+// RateLimitConfig selects where request budgets are counted. Backend is
+// "memory" (the default, for this process alone), "file" (under Dir,
+// default workspace/ratelimit, shared by processes on one machine) or
+// "redis" (RedisURL, shared by processes anywhere).
+type RateLimitConfig struct {
+	Backend  string `json:"backend,omitempty" env:"PICOCLAW_RATE_LIMIT_BACKEND"`
+	Dir      string `json:"dir,omitempty" env:"PICOCLAW_RATE_LIMIT_DIR"`
+	RedisURL string `json:"redis_url,omitempty" env:"PICOCLAW_RATE_LIMIT_REDIS_URL"`
+}
+
+// RoutingConfig sends requests to the provider and model of the first rule
+// they match; requests no rule matches use agents.defaults. Requests that
+// would not fit the context window of their model, or fail with a
+// context-length error, are retried on OverflowModel when it is set.
+type RoutingConfig struct {
+	Rules            []RoutingRule `json:"rules,omitempty"`
+	OverflowProvider string        `json:"overflow_provider,omitempty" env:"PICOCLAW_ROUTING_OVERFLOW_PROVIDER"`
+	OverflowModel    string        `json:"overflow_model,omitempty" env:"PICOCLAW_ROUTING_OVERFLOW_MODEL"`
+}
+
+// RoutingRule matches requests by every condition that is set. Prompt
+// length is in characters across all messages, Tags match the request's
+// "tags" option and Hours is a local-time range such as "9-18" ("22-6"
+// wraps past midnight). Once DailyRequests requests have matched in a day
+// the rule is skipped until midnight. Provider may be empty to pick one
+// from the model name.
+type RoutingRule struct {
+	Name           string   `json:"name,omitempty"`
+	Provider       string   `json:"provider,omitempty"`
+	Model          string   `json:"model"`
+	MinPromptChars int      `json:"min_prompt_chars,omitempty"`
+	MaxPromptChars int      `json:"max_prompt_chars,omitempty"`
+	HasTools       *bool    `json:"has_tools,omitempty"`
+	HasImages      *bool    `json:"has_images,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Hours          string   `json:"hours,omitempty"`
+	DailyRequests  int      `json:"daily_requests,omitempty"`
+}
+
+// ModelCatalogEntry describes the models whose lowercased names, without
+// any "vendor/" prefix, start with Prefix; the longest matching prefix
+// wins. Fields left unset keep the built-in values for the same prefix.
+// FixedSampling marks models that only accept the default temperature and
+// top_p, LegacyMaxTokens those that take max_tokens rather than
+// max_completion_tokens. Prices are in USD per million tokens and
+// Deprecated is the YYYY-MM-DD date the vendor retires the model.
+type ModelCatalogEntry struct {
+	Prefix          string   `json:"prefix"`
+	ContextWindow   int      `json:"context_window,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+	FixedSampling   *bool    `json:"fixed_sampling,omitempty"`
+	LegacyMaxTokens *bool    `json:"legacy_max_tokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	InputPrice      float64  `json:"input_price,omitempty"`
+	OutputPrice     float64  `json:"output_price,omitempty"`
+	Deprecated      string   `json:"deprecated,omitempty"`
+}
+
+// MCPConfig maps server names to the MCP servers to connect to.
+type MCPConfig struct {
+	Servers map[string]MCPServerConfig `json:"servers,omitempty"`
+}
+
+// PluginsConfig loads tool plugins: the executables in Dir (default
+// ~/.picoclaw/plugins), each started once and asked for its tools over
+// stdio. Disabled lists plugin names, the file names without extension, to
+// skip. Timeout bounds each call, in seconds (default 60).
+type PluginsConfig struct {
+	Enabled  bool     `json:"enabled" env:"PICOCLAW_PLUGINS_ENABLED"`
+	Dir      string   `json:"dir,omitempty" env:"PICOCLAW_PLUGINS_DIR"`
+	Disabled []string `json:"disabled,omitempty" env:"PICOCLAW_PLUGINS_DISABLED"`
+	Timeout  int      `json:"timeout,omitempty" env:"PICOCLAW_PLUGINS_TIMEOUT"`
+}
+
+// MCPServerConfig describes one MCP server. Local servers set Command and
+// are spoken to over stdio; remote servers set URL and use streamable HTTP,
+// or the older HTTP+SSE transport when Transport is "sse". Env and Headers
+// values may reference environment variables as ${VAR}.
+type MCPServerConfig struct {
+	Disabled  bool              `json:"disabled,omitempty"`
+	Transport string            `json:"transport,omitempty"` // stdio, http or sse; inferred when empty
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timeout   int               `json:"timeout,omitempty"` // seconds per request, default 60
+	// Tools limits which of the server's tools are exposed; empty exposes all.
+	Tools []string `json:"tools,omitempty"`
+	// RequireApproval makes the server's tools ask before running when
+	// tools.approval is enabled.
+	RequireApproval bool `json:"require_approval,omitempty"`
+	// Connector hands a remote server to Anthropic's MCP connector: Claude
+	// requests list it and Anthropic calls its tools, instead of picoclaw.
+	Connector bool `json:"connector,omitempty"`
+}
+
+// ToolOutputConfig limits how much of a tool result reaches the model.
+// Longer results keep their head and tail; the full text is written to
+// ArtifactDir (default: <workspace>/artifacts). MaxTokens 0 disables it.
+type ToolOutputConfig struct {
+	MaxTokens   int    `json:"max_tokens" env:"PICOCLAW_TOOLS_OUTPUT_MAX_TOKENS"`
+	ArtifactDir string `json:"artifact_dir,omitempty" env:"PICOCLAW_TOOLS_OUTPUT_ARTIFACT_DIR"`
+}
+
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
+	Web      WebToolsConfig  `json:"web"`
+	Exec     ExecToolsConfig `json:"exec"`
+	Git      GitToolsConfig  `json:"git"`
+	GitHub   GitHubConfig    `json:"github"`
+	SSH      SSHToolsConfig  `json:"ssh"`
+	SQL      SQLToolsConfig  `json:"sql"`
+	HTTP     HTTPToolsConfig `json:"http"`
+	Approval ApprovalConfig  `json:"approval"`
+	// Concurrency caps how many calls of a tool run at once, by tool name;
+	// 1 serializes the tool and 0 removes its built-in limit.
+	Concurrency map[string]int `json:"concurrency,omitempty" env:"PICOCLAW_TOOLS_CONCURRENCY"`
+	// DefaultTimeout bounds each tool call, in seconds, unless the tool has
+	// its own limit (exec, subagent) or Timeouts names it; 0 disables it.
+	DefaultTimeout int              `json:"default_timeout" env:"PICOCLAW_TOOLS_DEFAULT_TIMEOUT"`
+	Timeouts       map[string]int   `json:"timeouts,omitempty" env:"PICOCLAW_TOOLS_TIMEOUTS"`
+	Output         ToolOutputConfig `json:"output"`
+	RAG            RAGConfig        `json:"rag"`
 }
 
 func DefaultConfig() *Config {
@@ -309,7 +780,25 @@ func DefaultConfig() *Config {
 			Host: "0.0.0.0",
 			Port: 18790,
 		},
+		Serve: ServeConfig{
+			Host:                "127.0.0.1",
+			Port:                18800,
+			HealthCheckInterval: 60,
+		},
 		Tools: ToolsConfig{
+			DefaultTimeout: 300,
+			Git: GitToolsConfig{
+				Enabled: true,
+			},
+			Output: ToolOutputConfig{
+				MaxTokens: 8000,
+			},
+			RAG: RAGConfig{
+				Model:        "text-embedding-3-small",
+				ChunkSize:    1000,
+				ChunkOverlap: 200,
+				TopK:         5,
+			},
 			Web: WebToolsConfig{
 				Brave: BraveConfig{
 					Enabled:    false,
@@ -326,6 +815,11 @@ func DefaultConfig() *Config {
 			Enabled:  true,
 			Interval: 30, // default 30 minutes
 		},
+		Cache: CacheConfig{
+			Backend:    "memory",
+			TTL:        86400,
+			MaxEntries: 1000,
+		},
 		Devices: DevicesConfig{
 			Enabled:    false,
 			MonitorUSB: true,
@@ -334,13 +828,34 @@ func DefaultConfig() *Config {
 }
 
 func LoadConfig(path string) (*Config, error) {
+	return LoadConfigWithProfile(path, "")
+}
+
+// LoadConfigWithProfile loads a JSON, YAML or TOML config file and applies
+// the named profile (or PICOCLAW_PROFILE / default_profile when empty).
+// Precedence from lowest to highest: defaults, config file, profile, .env
+// files (see DotEnvFiles), environment variables. Command line flags are
+// applied over the result by the caller.
+func LoadConfigWithProfile(path, profile string) (*Config, error) {
+	if err := LoadDotEnv(DotEnvFiles()...); err != nil {
+		return nil, err
+	}
+
 	cfg := DefaultConfig()
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
+		if !os.IsNotExist(err) {
+			return nil, err
 		}
+		if profile != "" {
+			return nil, fmt.Errorf("profile %q not found: no config file at %s", profile, path)
+		}
+		return cfg, nil
+	}
+
+	data, err = decodeConfigData(path, data)
+	if err != nil {
 		return nil, err
 	}
 
@@ -348,10 +863,18 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.applyProfile(cfg.selectProfile(profile)); err != nil {
+		return nil, err
+	}
+
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -364,6 +887,11 @@ func SaveConfig(path string, cfg *Config) error {
 		return err
 	}
 
+	data, err = encodeConfigData(path, data)
+	if err != nil {
+		return err
+	}
+
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -378,6 +906,21 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// ResolveModel returns the provider and model the alias model stands for,
+// or providerName and model unchanged when it is not an alias.
+func (c *Config) ResolveModel(providerName, model string) (string, string) {
+	c.mu.RLock()
+	target, ok := c.ModelAliases[model]
+	c.mu.RUnlock()
+	if !ok {
+		return providerName, model
+	}
+	if provider, name, ok := strings.Cut(target, "/"); ok {
+		return provider, name
+	}
+	return "", target
+}
+
 func (c *Config) GetAPIKey() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -408,6 +951,62 @@ func (c *Config) GetAPIKey() string {
 	return ""
 }
 
+// Secrets returns the credentials set in c: provider API keys, serve API
+// keys, channel tokens and secrets, tool API keys, database DSNs, injected
+// HTTP credentials and the Vault token, so they can be masked in logs.
+func (c *Config) Secrets() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var secrets []string
+	for _, pc := range c.Providers.All() {
+		secrets = append(secrets, pc.APIKey)
+	}
+	secrets = append(secrets, c.Serve.APIKeys...)
+	ch := c.Channels
+	secrets = append(secrets,
+		ch.Telegram.Token,
+		ch.Feishu.AppSecret, ch.Feishu.EncryptKey, ch.Feishu.VerificationToken,
+		ch.Discord.Token,
+		ch.QQ.AppSecret,
+		ch.DingTalk.ClientSecret,
+		ch.Slack.BotToken, ch.Slack.AppToken,
+		ch.LINE.ChannelSecret, ch.LINE.ChannelAccessToken,
+		ch.OneBot.AccessToken,
+		c.Tools.Web.Brave.APIKey,
+		c.Tools.RAG.APIKey,
+		c.Credentials.Vault.Token,
+	)
+	for _, db := range c.Tools.SQL.Databases {
+		secrets = append(secrets, os.ExpandEnv(db.DSN))
+	}
+	for _, profile := range c.Tools.HTTP.Profiles {
+		for _, values := range []map[string]string{profile.Headers, profile.Query} {
+			for name, v := range values {
+				if httpCredential(name, v) {
+					secrets = append(secrets, os.ExpandEnv(v))
+				}
+			}
+		}
+	}
+	return secrets
+}
+
+// httpCredential reports whether an injected HTTP header or query value is
+// a credential: it is read from the environment, or its name says so.
+// Other values, such as Accept headers, are left unmasked.
+func httpCredential(name, value string) bool {
+	if strings.Contains(value, "$") {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, hint := range []string{"auth", "key", "token", "secret", "password", "cookie"} {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) GetAPIBase() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DotEnvFiles returns the .env files LoadConfig reads, most important
+// first: the one in the working directory, then ~/.picoclaw/.env.
+func DotEnvFiles() []string {
+	files := []string{".env"}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".picoclaw", ".env"))
+	}
+	return files
+}
+
+// LoadDotEnv sets the variables of each .env file in paths that are not
+// set already, so the real environment wins over the files and an earlier
+// file over a later one. Missing files are skipped.
+func LoadDotEnv(paths ...string) error {
+	for _, path := range paths {
+		vars, err := readDotEnv(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, kv := range vars {
+			if _, set := os.LookupEnv(kv[0]); !set {
+				os.Setenv(kv[0], kv[1])
+			}
+		}
+	}
+	return nil
+}
+
+// readDotEnv parses a .env file of KEY=value lines, in order. Lines may
+// start with "export"; blank lines and lines starting with # are skipped.
+// Values may be single quoted, taken as they are, or double quoted, with
+// Go escapes such as \n; an unquoted value ends at " #".
+func readDotEnv(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: want KEY=value", path, n)
+		}
+		value, err := dotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, scanner.Err()
+}
+
+func dotEnvValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, `"`):
+		quoted, err := strconv.QuotedPrefix(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value: %w", err)
+		}
+		return strconv.Unquote(quoted)
+	}
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local.env")
+	home := filepath.Join(dir, "home.env")
+	os.WriteFile(local, []byte(strings.Join([]string{
+		"# comment",
+		"export DOTENV_TEST_PLAIN=plain value # trailing",
+		`DOTENV_TEST_DOUBLE="line\nbreak"`,
+		"DOTENV_TEST_SINGLE='raw\\n # kept'",
+		"DOTENV_TEST_SET=from file",
+		"",
+	}, "\n")), 0o644)
+	os.WriteFile(home, []byte("DOTENV_TEST_PLAIN=home\nDOTENV_TEST_HOME=home\n"), 0o644)
+
+	for _, key := range []string{"DOTENV_TEST_PLAIN", "DOTENV_TEST_DOUBLE", "DOTENV_TEST_SINGLE", "DOTENV_TEST_HOME"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("DOTENV_TEST_SET", "from env")
+
+	if err := LoadDotEnv(local, filepath.Join(dir, "missing.env"), home); err != nil {
+		t.Fatalf("LoadDotEnv() error: %v", err)
+	}
+	want := map[string]string{
+		"DOTENV_TEST_PLAIN":  "plain value",
+		"DOTENV_TEST_DOUBLE": "line\nbreak",
+		"DOTENV_TEST_SINGLE": `raw\n # kept`,
+		"DOTENV_TEST_SET":    "from env",
+		"DOTENV_TEST_HOME":   "home",
+	}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestLoadDotEnv_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("GOOD=1\nnot a variable\n"), 0o644)
+
+	err := LoadDotEnv(path)
+	if err == nil || !strings.Contains(err.Error(), ".env:2") {
+		t.Errorf("LoadDotEnv() error = %v, want one naming line 2", err)
+	}
+}
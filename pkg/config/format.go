@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames lists the config files looked up in the picoclaw home
+// directory, in order of preference.
+var configFileNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// ResolveConfigPath returns the first existing config file in dir, or
+// dir/config.json when none exists yet.
+func ResolveConfigPath(dir string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, configFileNames[0])
+}
+
+// configFormat returns "json", "yaml" or "toml" based on the file extension.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	default:
+		return "json"
+	}
+}
+
+// decodeConfigData converts YAML or TOML input to JSON so every format is
+// decoded through the same json tags and custom unmarshalers.
+func decodeConfigData(path string, data []byte) ([]byte, error) {
+	var raw map[string]interface{}
+	switch configFormat(path) {
+	case "yaml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parsing YAML config: %w", err)
+		}
+	case "toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parsing TOML config: %w", err)
+		}
+	default:
+		return data, nil
+	}
+	if raw == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(raw)
+}
+
+// encodeConfigData renders JSON config data in the format matching path.
+func encodeConfigData(path string, data []byte) ([]byte, error) {
+	format := configFormat(path)
+	if format == "json" {
+		return data, nil
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(raw); err != nil {
+			return nil, err
+		}
+		enc.Close()
+	case "toml":
+		if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ProfileConfig is a named set of overrides applied on top of the base
+// config, e.g. "work" pointing at an Azure deployment and "home" at OpenRouter.
+// Zero values leave the base setting untouched.
+type ProfileConfig struct {
+	Provider          string  `json:"provider,omitempty"`
+	Model             string  `json:"model,omitempty"`
+	Endpoint          string  `json:"endpoint,omitempty"`
+	AuthMethod        string  `json:"auth_method,omitempty"`
+	Account           string  `json:"account,omitempty"`
+	Workspace         string  `json:"workspace,omitempty"`
+	MaxTokens         int     `json:"max_tokens,omitempty"`
+	Temperature       float64 `json:"temperature,omitempty"`
+	MaxToolIterations int     `json:"max_tool_iterations,omitempty"`
+	// DefaultModels sets the default_model of providers by name
+	DefaultModels map[string]string `json:"default_models,omitempty"`
+	// Policy replaces the top-level tool policy
+	Policy *PolicyConfig `json:"policy,omitempty"`
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (c *Config) ProfileNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActiveProfile returns the name of the profile applied at load time, if any.
+func (c *Config) ActiveProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.activeProfile
+}
+
+// selectProfile resolves which profile to apply: an explicit name wins over
+// PICOCLAW_PROFILE, which wins over default_profile from the config file.
+func (c *Config) selectProfile(name string) string {
+	if name != "" {
+		return name
+	}
+	if env := os.Getenv("PICOCLAW_PROFILE"); env != "" {
+		return env
+	}
+	return c.DefaultProfile
+}
+
+// applyProfile overlays the named profile onto the agent defaults and the
+// profile's provider settings.
+func (c *Config) applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found in config", name)
+	}
+
+	defaults := &c.Agents.Defaults
+	if profile.Provider != "" {
+		defaults.Provider = profile.Provider
+	}
+	if profile.Model != "" {
+		defaults.Model = profile.Model
+	}
+	if profile.Workspace != "" {
+		defaults.Workspace = profile.Workspace
+	}
+	if profile.MaxTokens != 0 {
+		defaults.MaxTokens = profile.MaxTokens
+	}
+	if profile.Temperature != 0 {
+		defaults.Temperature = profile.Temperature
+	}
+	if profile.MaxToolIterations != 0 {
+		defaults.MaxToolIterations = profile.MaxToolIterations
+	}
+	if profile.Policy != nil {
+		c.Policy = *profile.Policy
+	}
+
+	if profile.Endpoint != "" || profile.AuthMethod != "" || profile.Account != "" {
+		pc := c.Providers.Get(defaults.Provider)
+		if pc == nil {
+			return fmt.Errorf("profile %q: endpoint, auth_method and account require a known provider, got %q", name, defaults.Provider)
+		}
+		if profile.Endpoint != "" {
+			pc.APIBase = profile.Endpoint
+		}
+		if profile.AuthMethod != "" {
+			pc.AuthMethod = profile.AuthMethod
+		}
+		if profile.Account != "" {
+			pc.Account = profile.Account
+		}
+	}
+
+	for provider, model := range profile.DefaultModels {
+		pc := c.Providers.Get(provider)
+		if pc == nil {
+			return fmt.Errorf("profile %q: default_models: unknown provider %q", name, provider)
+		}
+		pc.DefaultModel = model
+	}
+
+	c.activeProfile = name
+	return nil
+}
+
+// Get returns the provider config for a provider name as used in
+// agents.defaults.provider, or nil if the name is unknown.
+func (p *ProvidersConfig) Get(name string) *ProviderConfig {
+	switch strings.ToLower(name) {
+	case "anthropic", "claude":
+		return &p.Anthropic
+	case "openai", "gpt":
+		return &p.OpenAI
+	case "openrouter":
+		return &p.OpenRouter
+	case "groq":
+		return &p.Groq
+	case "zhipu", "glm":
+		return &p.Zhipu
+	case "vllm":
+		return &p.VLLM
+	case "gemini", "google":
+		return &p.Gemini
+	case "nvidia":
+		return &p.Nvidia
+	case "moonshot", "kimi":
+		return &p.Moonshot
+	case "shengsuanyun":
+		return &p.ShengSuanYun
+	case "deepseek":
+		return &p.DeepSeek
+	case "github_copilot", "copilot":
+		return &p.GitHubCopilot
+	case "github_models", "github":
+		return &p.GitHubModels
+	case "cohere":
+		return &p.Cohere
+	case "together":
+		return &p.Together
+	case "fireworks":
+		return &p.Fireworks
+	case "perplexity":
+		return &p.Perplexity
+	case "dashscope", "qwen":
+		return &p.DashScope
+	case "azure_foundry", "foundry":
+		return &p.AzureFoundry
+	case "ollama":
+		return &p.Ollama
+	case "lmstudio", "lm_studio", "lm-studio":
+		return &p.LMStudio
+	case "llamacpp", "llama_cpp", "llama.cpp", "llama-server":
+		return &p.LlamaCpp
+	}
+	return nil
+}
+
+// All returns every provider config keyed by its canonical name.
+func (p *ProvidersConfig) All() map[string]*ProviderConfig {
+	return map[string]*ProviderConfig{
+		"anthropic":      &p.Anthropic,
+		"openai":         &p.OpenAI,
+		"openrouter":     &p.OpenRouter,
+		"groq":           &p.Groq,
+		"zhipu":          &p.Zhipu,
+		"vllm":           &p.VLLM,
+		"gemini":         &p.Gemini,
+		"nvidia":         &p.Nvidia,
+		"moonshot":       &p.Moonshot,
+		"shengsuanyun":   &p.ShengSuanYun,
+		"deepseek":       &p.DeepSeek,
+		"github_copilot": &p.GitHubCopilot,
+		"github_models":  &p.GitHubModels,
+		"cohere":         &p.Cohere,
+		"together":       &p.Together,
+		"fireworks":      &p.Fireworks,
+		"perplexity":     &p.Perplexity,
+		"dashscope":      &p.DashScope,
+		"azure_foundry":  &p.AzureFoundry,
+		"ollama":         &p.Ollama,
+		"lmstudio":       &p.LMStudio,
+		"llamacpp":       &p.LlamaCpp,
+	}
+}
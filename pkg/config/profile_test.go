@@ -0,0 +1,192 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const yamlProfilesConfig = `
+agents:
+  defaults:
+    model: glm-4.7
+    max_tokens: 4096
+default_profile: home
+profiles:
+  home:
+    provider: openrouter
+    model: anthropic/claude-sonnet-4
+  work:
+    provider: openai
+    model: gpt-5.2-chat
+    endpoint: https://work.openai.azure.com
+    auth_method: oauth
+    max_tokens: 16000
+    default_models:
+      anthropic: claude-opus-4-1
+`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_YAMLDefaultProfile(t *testing.T) {
+	t.Setenv("PICOCLAW_PROFILE", "")
+	path := writeConfigFile(t, "config.yaml", yamlProfilesConfig)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.ActiveProfile() != "home" {
+		t.Errorf("ActiveProfile() = %q, want %q", cfg.ActiveProfile(), "home")
+	}
+	if cfg.Agents.Defaults.Model != "anthropic/claude-sonnet-4" {
+		t.Errorf("Model = %q, want %q", cfg.Agents.Defaults.Model, "anthropic/claude-sonnet-4")
+	}
+	if cfg.Agents.Defaults.MaxTokens != 4096 {
+		t.Errorf("MaxTokens = %d, want 4096", cfg.Agents.Defaults.MaxTokens)
+	}
+}
+
+func TestLoadConfigWithProfile_ExplicitProfile(t *testing.T) {
+	t.Setenv("PICOCLAW_PROFILE", "home")
+	path := writeConfigFile(t, "config.yaml", yamlProfilesConfig)
+
+	cfg, err := LoadConfigWithProfile(path, "work")
+	if err != nil {
+		t.Fatalf("LoadConfigWithProfile() error: %v", err)
+	}
+	if cfg.Agents.Defaults.Provider != "openai" {
+		t.Errorf("Provider = %q, want %q", cfg.Agents.Defaults.Provider, "openai")
+	}
+	if cfg.Providers.OpenAI.APIBase != "https://work.openai.azure.com" {
+		t.Errorf("OpenAI.APIBase = %q", cfg.Providers.OpenAI.APIBase)
+	}
+	if cfg.Providers.OpenAI.AuthMethod != "oauth" {
+		t.Errorf("OpenAI.AuthMethod = %q, want %q", cfg.Providers.OpenAI.AuthMethod, "oauth")
+	}
+	if cfg.Agents.Defaults.MaxTokens != 16000 {
+		t.Errorf("MaxTokens = %d, want 16000", cfg.Agents.Defaults.MaxTokens)
+	}
+	if cfg.Providers.Anthropic.DefaultModel != "claude-opus-4-1" {
+		t.Errorf("Anthropic.DefaultModel = %q, want %q", cfg.Providers.Anthropic.DefaultModel, "claude-opus-4-1")
+	}
+}
+
+func TestLoadConfigWithProfile_EnvOverridesProfile(t *testing.T) {
+	t.Setenv("PICOCLAW_AGENTS_DEFAULTS_MODEL", "gpt-4o")
+	path := writeConfigFile(t, "config.yaml", yamlProfilesConfig)
+
+	cfg, err := LoadConfigWithProfile(path, "work")
+	if err != nil {
+		t.Fatalf("LoadConfigWithProfile() error: %v", err)
+	}
+	if cfg.Agents.Defaults.Model != "gpt-4o" {
+		t.Errorf("Model = %q, want env override %q", cfg.Agents.Defaults.Model, "gpt-4o")
+	}
+}
+
+func TestLoadConfigWithProfile_UnknownProfile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", yamlProfilesConfig)
+
+	if _, err := LoadConfigWithProfile(path, "missing"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestLoadConfig_TOML(t *testing.T) {
+	t.Setenv("PICOCLAW_PROFILE", "")
+	path := writeConfigFile(t, "config.toml", `
+[agents.defaults]
+model = "deepseek-chat"
+max_tool_iterations = 5
+
+[channels.telegram]
+allow_from = ["123", "456"]
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.Agents.Defaults.Model != "deepseek-chat" {
+		t.Errorf("Model = %q, want %q", cfg.Agents.Defaults.Model, "deepseek-chat")
+	}
+	if cfg.Agents.Defaults.MaxToolIterations != 5 {
+		t.Errorf("MaxToolIterations = %d, want 5", cfg.Agents.Defaults.MaxToolIterations)
+	}
+	if len(cfg.Channels.Telegram.AllowFrom) != 2 {
+		t.Errorf("len(Telegram.AllowFrom) = %d, want 2", len(cfg.Channels.Telegram.AllowFrom))
+	}
+}
+
+func TestSaveConfig_YAMLRoundTrip(t *testing.T) {
+	t.Setenv("PICOCLAW_PROFILE", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4.1"
+	if err := SaveConfig(path, cfg); err != nil {
+		t.Fatalf("SaveConfig() error: %v", err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if loaded.Agents.Defaults.Model != "gpt-4.1" {
+		t.Errorf("Model = %q, want %q", loaded.Agents.Defaults.Model, "gpt-4.1")
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	if got := ResolveConfigPath(dir); got != filepath.Join(dir, "config.json") {
+		t.Errorf("ResolveConfigPath() = %q, want config.json default", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.toml"), []byte(""), 0644)
+	if got := ResolveConfigPath(dir); got != filepath.Join(dir, "config.toml") {
+		t.Errorf("ResolveConfigPath() = %q, want config.toml", got)
+	}
+}
+
+func TestLoadConfigWithProfile_Policy(t *testing.T) {
+	t.Setenv("PICOCLAW_PROFILE", "")
+	path := writeConfigFile(t, "config.yaml", `
+policy:
+  tools: [read_file, exec]
+  deny_commands: ["rm -rf"]
+profiles:
+  readonly:
+    policy:
+      tools: [read_file, list_dir]
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if len(cfg.Policy.Tools) != 2 || cfg.Policy.Tools[1] != "exec" || len(cfg.Policy.DenyCommands) != 1 {
+		t.Errorf("Policy = %+v, want the top-level policy", cfg.Policy)
+	}
+
+	cfg, err = LoadConfigWithProfile(path, "readonly")
+	if err != nil {
+		t.Fatalf("LoadConfigWithProfile() error: %v", err)
+	}
+	if len(cfg.Policy.Tools) != 2 || cfg.Policy.Tools[1] != "list_dir" || len(cfg.Policy.DenyCommands) != 0 {
+		t.Errorf("Policy = %+v, want the profile's policy in place of the top-level one", cfg.Policy)
+	}
+
+	bad := writeConfigFile(t, "config.yaml", "policy:\n  allow_commands: [\"(\"]\n")
+	if _, err := LoadConfig(bad); err == nil {
+		t.Error("LoadConfig() accepted an invalid command pattern")
+	}
+}
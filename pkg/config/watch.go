@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// defaultWatchInterval is how often a Watcher checks its files.
+const defaultWatchInterval = 2 * time.Second
+
+// Watcher reloads the config of a long-running process when its file or
+// one of Files changes, or when the process receives SIGHUP, so new API
+// keys, model aliases and budgets apply without a restart. Files are
+// checked for a new modification time or size every Interval.
+type Watcher struct {
+	Path     string
+	Profile  string
+	Files    []string      // more files that trigger a reload, such as the credential store
+	Interval time.Duration // defaults to 2s
+
+	// OnReload receives each reloaded config. OnError, if set, receives
+	// the error of a config that fails to load, leaving the last one in
+	// effect.
+	OnReload func(*Config)
+	OnError  func(error)
+}
+
+// fileStamp tells whether a file changed; the zero stamp is a missing
+// file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{info.ModTime(), info.Size()}
+}
+
+// Run watches until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	files := append([]string{w.Path}, w.Files...)
+	stamps := make([]fileStamp, len(files))
+	for i, path := range files {
+		stamps[i] = statFile(path)
+	}
+
+	signals := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(signals, reloadSignals...)
+		defer signal.Stop(signals)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		case <-ticker.C:
+			changed := false
+			for i, path := range files {
+				if stamp := statFile(path); stamp != stamps[i] {
+					stamps[i], changed = stamp, true
+				}
+			}
+			if !changed {
+				continue
+			}
+		}
+		w.reload()
+	}
+}
+
+func (w *Watcher) reload() {
+	cfg, err := LoadConfigWithProfile(w.Path, w.Profile)
+	if err != nil {
+		if w.OnError != nil {
+			w.OnError(err)
+		}
+		return
+	}
+	w.OnReload(cfg)
+}
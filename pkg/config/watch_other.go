@@ -0,0 +1,9 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// reloadSignals make a Watcher reload the config at once; there is no
+// SIGHUP here, so changes are only picked up by watching the files.
+var reloadSignals []os.Signal
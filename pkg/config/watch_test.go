@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatcher_ReloadsOnChange(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"agents": {"defaults": {"model": "first"}}}`)
+	reloaded := make(chan *Config, 1)
+	failed := make(chan error, 1)
+	w := &Watcher{
+		Path:     path,
+		Interval: 10 * time.Millisecond,
+		OnReload: func(cfg *Config) { reloaded <- cfg },
+		OnError:  func(err error) { failed <- err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// Let the watcher take its first look before changing the file
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"agents": {"defaults": {"model": "second-model"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-reloaded:
+		if cfg.Agents.Defaults.Model != "second-model" {
+			t.Errorf("reloaded model = %q, want second-model", cfg.Agents.Defaults.Model)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"agents": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-failed:
+	case cfg := <-reloaded:
+		t.Fatalf("broken config reloaded as %+v", cfg.Agents.Defaults)
+	case <-time.After(5 * time.Second):
+		t.Fatal("broken config was not reported")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals make a Watcher reload the config at once.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
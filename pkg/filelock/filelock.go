@@ -0,0 +1,74 @@
+// Package filelock serializes changes to shared files across processes
+// with advisory locks, which the system releases when a process exits, so
+// a crash never leaves a stale lock behind.
+package filelock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Lock takes the exclusive lock on the file at path, creating it and its
+// directory if needed, and waits for other processes to release theirs.
+// Lock on a separate file from the data it guards when that is replaced
+// on write.
+func Lock(path string) (unlock func(), err error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return unlocker(f), nil
+}
+
+// LockContext is Lock that stops waiting when ctx is done, returning its
+// error. The lock is polled, backing off from 1ms to 50ms between tries.
+func LockContext(ctx context.Context, path string) (unlock func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	backoff := time.Millisecond
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		if locked {
+			return unlocker(f), nil
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			f.Close()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, 50*time.Millisecond)
+	}
+}
+
+func open(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+}
+
+func unlocker(f *os.File) func() {
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}
+}
@@ -0,0 +1,71 @@
+//go:build unix || windows
+
+package filelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockExcludes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "data.lock")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	released := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Each Lock opens the file anew, so it waits even in one process
+		unlock, err := Lock(path)
+		if err != nil {
+			t.Errorf("second Lock() error = %v", err)
+			return
+		}
+		defer unlock()
+		mu.Lock()
+		defer mu.Unlock()
+		if !released {
+			t.Error("second Lock() returned while the first was held")
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	released = true
+	mu.Unlock()
+	unlock()
+	<-done
+}
+
+func TestLockContextStopsWaiting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.lock")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := LockContext(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LockContext() error = %v, want the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("LockContext() waited %v past its deadline", elapsed)
+	}
+
+	unlock()
+	unlock, err = LockContext(context.Background(), path)
+	if err != nil {
+		t.Fatalf("LockContext() after unlock error = %v", err)
+	}
+	unlock()
+}
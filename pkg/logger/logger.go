@@ -105,8 +105,8 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		Level:     logLevelNames[level],
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Component: component,
-		Message:   message,
-		Fields:    fields,
+		Message:   Redact(message),
+		Fields:    redactFields(fields),
 	}
 
 	if pc, file, line, ok := runtime.Caller(2); ok {
@@ -124,15 +124,15 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 	}
 
 	var fieldStr string
-	if len(fields) > 0 {
-		fieldStr = " " + formatFields(fields)
+	if len(entry.Fields) > 0 {
+		fieldStr = " " + formatFields(entry.Fields)
 	}
 
 	logLine := fmt.Sprintf("[%s] [%s]%s %s%s",
 		entry.Timestamp,
 		logLevelNames[level],
 		formatComponent(component),
-		message,
+		entry.Message,
 		fieldStr,
 	)
 
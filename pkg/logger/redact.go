@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every secret Redact finds.
+const Redacted = "[REDACTED]"
+
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// API keys with a recognizable prefix: Anthropic, OpenAI, Groq, xAI,
+	// Gemini, GitHub, Hugging Face, Perplexity and Slack.
+	{regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{16,}|gsk_[A-Za-z0-9]{16,}|xai-[A-Za-z0-9]{16,}|AIza[0-9A-Za-z_-]{30,}|gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|hf_[A-Za-z0-9]{20,}|pplx-[A-Za-z0-9]{20,}|xox[abprs]-[A-Za-z0-9-]{10,})`), Redacted},
+	// JSON web tokens, such as OAuth access and ID tokens.
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]*`), Redacted},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]{8,}`), "$1 " + Redacted},
+	// Header values, as in "x-api-key: ..." or "Chatgpt-Account-Id: ...".
+	{regexp.MustCompile(`(?i)\b((?:x-api-key|api-key|x-goog-api-key|chatgpt-account-id)\s*[:=]\s*)[^\s,;"']+`), "${1}" + Redacted},
+	// JSON fields, as in the token responses of OAuth servers.
+	{regexp.MustCompile(`(?i)("(?:access_token|refresh_token|id_token|api_key|apikey|client_secret|password|secret|account_id|chatgpt_account_id)"\s*:\s*")[^"]*"`), "${1}" + Redacted + `"`},
+	// Passwords in URLs, as in redis://:password@host.
+	{regexp.MustCompile(`(://[^:/@\s]*:)[^@\s/]+@`), "${1}" + Redacted + "@"},
+	// URL query parameters.
+	{regexp.MustCompile(`(?i)([?&](?:key|api_key|apikey|access_token|refresh_token|token|client_secret|code|sig)=)[^&\s"'#]+`), "${1}" + Redacted},
+}
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecret makes Redact mask values wherever they appear, for credentials
+// no pattern recognizes, such as configured API keys and account IDs.
+// Values shorter than 8 characters are ignored, as masking them would mangle
+// ordinary text.
+func AddSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < 8 || containsString(secrets, v) {
+			continue
+		}
+		secrets = append(secrets, v)
+	}
+	// Longest first, so a secret that contains another is masked whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// Redact masks the API keys, bearer tokens, JSON web tokens, account IDs and
+// secrets registered with AddSecret in s. Log lines are redacted before they
+// are written; use Redact for other output that may carry credentials, such
+// as verbose output and error messages.
+func Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	secretsMu.RUnlock()
+	for _, r := range redactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// redactFields returns a copy of fields with their values redacted.
+func redactFields(fields map[string]interface{}) map[string]interface{} {
+	if len(fields) == 0 {
+		return fields
+	}
+	redacted := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		redacted[k] = redactValue(v, 0)
+	}
+	return redacted
+}
+
+// maxRedactDepth bounds the recursion of redactValue into nested maps and
+// slices; deeper values are redacted in their formatted form.
+const maxRedactDepth = 8
+
+// redactValue redacts strings, errors and Stringers, and the elements of maps
+// and slices holding them. Other values that format with a secret, such as
+// structs, are replaced by their redacted formatted form.
+func redactValue(v interface{}, depth int) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return Redact(v)
+	case error:
+		return Redact(v.Error())
+	case fmt.Stringer:
+		return Redact(v.String())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	if depth < maxRedactDepth {
+		switch rv.Kind() {
+		case reflect.Map:
+			redacted := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				redacted[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value().Interface(), depth+1)
+			}
+			return redacted
+		case reflect.Slice, reflect.Array:
+			if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+				break // []byte formats as numbers; redact it as text below
+			}
+			redacted := make([]interface{}, rv.Len())
+			for i := range redacted {
+				redacted[i] = redactValue(rv.Index(i).Interface(), depth+1)
+			}
+			return redacted
+		}
+	}
+	if formatted := fmt.Sprintf("%+v", v); Redact(formatted) != formatted {
+		return Redact(formatted)
+	}
+	if b, ok := v.([]byte); ok {
+		if text := string(b); Redact(text) != text {
+			return Redact(text)
+		}
+	}
+	return v
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,370 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultRequestTimeout bounds requests when the server config sets none.
+const defaultRequestTimeout = 60 * time.Second
+
+// ErrClosed is returned for requests on a closed client or after the
+// transport ended.
+var ErrClosed = errors.New("mcp: connection closed")
+
+// Transport carries JSON-RPC messages to and from a server.
+type Transport interface {
+	// Start connects to the server.
+	Start(ctx context.Context) error
+	// Send delivers one message to the server.
+	Send(ctx context.Context, msg *Message) error
+	// Receive returns the messages sent by the server. The channel is
+	// closed when the transport can deliver no more.
+	Receive() <-chan *Message
+	// Close disconnects and releases the transport's resources.
+	Close() error
+}
+
+// Client speaks MCP to one server.
+type Client struct {
+	name      string
+	transport Transport
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *Message
+	closed  bool // Close was called
+	ended   bool // the transport stopped delivering messages
+	done    chan struct{}
+
+	serverInfo   Implementation
+	capabilities ServerCapabilities
+	instructions string
+}
+
+// NewClient creates a client for the server called name over transport,
+// which must already be started. Call Initialize before anything else.
+func NewClient(name string, transport Transport, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	c := &Client{
+		name:      name,
+		transport: transport,
+		timeout:   timeout,
+		pending:   make(map[string]chan *Message),
+		done:      make(chan struct{}),
+	}
+	go c.dispatch()
+	return c
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ServerInfo returns the name and version the server reported.
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// Instructions returns the usage hints the server sent on initialize.
+func (c *Client) Instructions() string {
+	return c.instructions
+}
+
+// HasTools and HasResources report the server's capabilities.
+func (c *Client) HasTools() bool     { return c.capabilities.Tools != nil }
+func (c *Client) HasResources() bool { return c.capabilities.Resources != nil }
+
+// Initialize performs the MCP handshake.
+func (c *Client) Initialize(ctx context.Context) error {
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: "picoclaw", Version: "1.0"},
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.instructions = result.Instructions
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+
+	logger.InfoCF("mcp", "Connected to MCP server",
+		map[string]interface{}{
+			"server":   c.name,
+			"name":     result.ServerInfo.Name,
+			"version":  result.ServerInfo.Version,
+			"protocol": result.ProtocolVersion,
+		})
+	return nil
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	err := paginate("tools/list", func(cursor string) (string, error) {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", cursorParams{Cursor: cursor}, &result); err != nil {
+			return "", err
+		}
+		all = append(all, result.Tools...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// CallTool runs the named tool on the server.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, fmt.Errorf("tools/call %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// ListResources returns every resource the server offers.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var all []Resource
+	err := paginate("resources/list", func(cursor string) (string, error) {
+		var result listResourcesResult
+		if err := c.call(ctx, "resources/list", cursorParams{Cursor: cursor}, &result); err != nil {
+			return "", err
+		}
+		all = append(all, result.Resources...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// maxListPages bounds how many pages a list request follows.
+const maxListPages = 1000
+
+// paginate calls page with each cursor the server returns, starting from
+// none, until it returns no next cursor. A server that repeats a cursor or
+// keeps paging past maxListPages gets an error instead of being followed
+// forever.
+func paginate(method string, page func(cursor string) (string, error)) error {
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 1; ; pages++ {
+		next, err := page(cursor)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		if next == "" {
+			return nil
+		}
+		if seen[next] {
+			return fmt.Errorf("%s: the server repeated cursor %q", method, next)
+		}
+		if pages >= maxListPages {
+			return fmt.Errorf("%s: the server sent more than %d pages", method, maxListPages)
+		}
+		seen[next] = true
+		cursor = next
+	}
+}
+
+// ReadResource returns the contents of the resource at uri.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result readResourceResult
+	if err := c.call(ctx, "resources/read", readResourceParams{URI: uri}, &result); err != nil {
+		return nil, fmt.Errorf("resources/read %s: %w", uri, err)
+	}
+	return result.Contents, nil
+}
+
+// Close disconnects from the server and fails pending requests.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock()
+	return c.transport.Close()
+}
+
+// Call sends a request for any method and decodes its result into out. It
+// lets protocols built on the same JSON-RPC framing, such as tool plugins,
+// reuse the client.
+func (c *Client) Call(ctx context.Context, method string, params, out interface{}) error {
+	return c.call(ctx, method, params, out)
+}
+
+// call sends a request and decodes its result into out.
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	if c.closed || c.ended {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	replies := make(chan *Message, 1)
+	c.pending[id] = replies
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	msg := &Message{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal params: %w", err)
+		}
+		msg.Params = raw
+	}
+	if err := c.transport.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	select {
+	case reply, ok := <-replies:
+		if !ok {
+			return ErrClosed
+		}
+		if reply.Error != nil {
+			return reply.Error
+		}
+		if out == nil || len(reply.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(reply.Result, out); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		// Let the server stop working on a request nobody waits for
+		notifyCtx, cancelNotify := context.WithTimeout(context.Background(), 5*time.Second)
+		c.notify(notifyCtx, "notifications/cancelled", map[string]interface{}{
+			"requestId": json.RawMessage(id),
+			"reason":    ctx.Err().Error(),
+		})
+		cancelNotify()
+		return ctx.Err()
+	case <-c.done:
+		return ErrClosed
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	msg := &Message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	return c.transport.Send(ctx, msg)
+}
+
+// dispatch routes responses to waiting calls and answers server requests
+// until the transport ends.
+func (c *Client) dispatch() {
+	for msg := range c.transport.Receive() {
+		switch {
+		case msg.isResponse():
+			c.mu.Lock()
+			replies, ok := c.pending[idKey(msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case replies <- msg:
+				default: // duplicate response
+				}
+			}
+		case len(msg.ID) > 0:
+			c.answer(msg)
+		default:
+			logger.DebugCF("mcp", "Server notification",
+				map[string]interface{}{
+					"server": c.name,
+					"method": msg.Method,
+				})
+		}
+	}
+
+	// The transport ended: fail everything still waiting
+	c.mu.Lock()
+	for id, replies := range c.pending {
+		close(replies)
+		delete(c.pending, id)
+	}
+	c.ended = true
+	closed := c.closed
+	c.mu.Unlock()
+	if !closed {
+		logger.WarnCF("mcp", "MCP server disconnected",
+			map[string]interface{}{
+				"server": c.name,
+			})
+	}
+}
+
+// answer responds to a request from the server. Only ping is supported;
+// the client advertises no other capabilities.
+func (c *Client) answer(req *Message) {
+	reply := &Message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: errMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.transport.Send(ctx, reply); err != nil {
+		logger.WarnCF("mcp", "Failed to answer server request",
+			map[string]interface{}{
+				"server": c.name,
+				"method": req.Method,
+				"error":  err.Error(),
+			})
+	}
+}
+
+// idKey normalizes a JSON-RPC id so numeric and string ids match the keys
+// the client generated.
+func idKey(id json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(id, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(id, &n); err == nil {
+		return n.String()
+	}
+	return string(id)
+}
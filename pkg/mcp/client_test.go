@@ -0,0 +1,409 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeServer answers the requests a test server needs to support.
+func fakeServer(req *Message) *Message {
+	if len(req.ID) == 0 {
+		return nil
+	}
+	reply := &Message{JSONRPC: "2.0", ID: req.ID}
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}, "resources": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "0.1"},
+		}
+	case "tools/list":
+		var params cursorParams
+		json.Unmarshal(req.Params, &params)
+		if params.Cursor == "" {
+			result = map[string]interface{}{
+				"tools": []map[string]interface{}{{
+					"name":        "echo",
+					"description": "Echo the text back",
+					"inputSchema": map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+						"required":   []string{"text"},
+					},
+				}},
+				"nextCursor": "page2",
+			}
+		} else {
+			result = map[string]interface{}{
+				"tools": []map[string]interface{}{{"name": "fail", "inputSchema": map[string]interface{}{}}},
+			}
+		}
+	case "tools/call":
+		var params callToolParams
+		json.Unmarshal(req.Params, &params)
+		if params.Name == "fail" {
+			result = map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": "boom"}},
+				"isError": true,
+			}
+		} else {
+			result = map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": fmt.Sprint(params.Arguments["text"])}},
+			}
+		}
+	case "resources/list":
+		result = map[string]interface{}{
+			"resources": []map[string]interface{}{{"uri": "file:///notes.txt", "name": "notes"}},
+		}
+	case "resources/read":
+		result = map[string]interface{}{
+			"contents": []map[string]interface{}{{"uri": "file:///notes.txt", "text": "remember the milk"}},
+		}
+	default:
+		reply.Error = &RPCError{Code: errMethodNotFound, Message: "method not found"}
+		return reply
+	}
+	reply.Result, _ = json.Marshal(result)
+	return reply
+}
+
+// TestMain lets the test binary double as a stdio MCP server.
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_FAKE_SERVER") == "1" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			var msg Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				continue
+			}
+			if reply := fakeServer(&msg); reply != nil {
+				data, _ := json.Marshal(reply)
+				fmt.Println(string(data))
+			}
+		}
+		os.Exit(0)
+	}
+	// Keep tests away from the developer's stored credentials
+	loadOAuthTokens = func() map[string]interface{} { return map[string]interface{}{} }
+	saveOAuthToken = func(string, map[string]interface{}) error { return nil }
+	os.Exit(m.Run())
+}
+
+func checkServerTools(t *testing.T, m *Manager) {
+	t.Helper()
+	ctx := context.Background()
+
+	names := map[string]bool{}
+	for _, tool := range m.Tools() {
+		names[tool.Name()] = true
+	}
+	for _, want := range []string{"mcp_fake_echo", "mcp_fake_fail", "mcp_fake_resource"} {
+		if !names[want] {
+			t.Fatalf("tools = %v, want %s", names, want)
+		}
+	}
+
+	for _, tool := range m.Tools() {
+		switch tool.Name() {
+		case "mcp_fake_echo":
+			result := tool.Execute(ctx, map[string]interface{}{"text": "hello"})
+			if result.IsError || result.ForLLM != "hello" {
+				t.Errorf("echo result = %+v, want hello", result)
+			}
+		case "mcp_fake_fail":
+			result := tool.Execute(ctx, map[string]interface{}{})
+			if !result.IsError || !strings.Contains(result.ForLLM, "boom") {
+				t.Errorf("fail result = %+v, want error containing boom", result)
+			}
+		case "mcp_fake_resource":
+			result := tool.Execute(ctx, map[string]interface{}{})
+			if !strings.Contains(result.ForLLM, "file:///notes.txt") {
+				t.Errorf("resource list = %q, want notes uri", result.ForLLM)
+			}
+			result = tool.Execute(ctx, map[string]interface{}{"uri": "file:///notes.txt"})
+			if result.ForLLM != "remember the milk" {
+				t.Errorf("resource read = %q, want remember the milk", result.ForLLM)
+			}
+		}
+	}
+}
+
+func TestConnect_Stdio(t *testing.T) {
+	m := Connect(context.Background(), config.MCPConfig{
+		Servers: map[string]config.MCPServerConfig{
+			"fake": {
+				Command: os.Args[0],
+				Args:    []string{"-test.run=^$"},
+				Env:     map[string]string{"MCP_FAKE_SERVER": "1"},
+			},
+		},
+	})
+	defer m.Close()
+	checkServerTools(t, m)
+}
+
+func TestConnect_StreamableHTTP(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sse=%v", stream), func(t *testing.T) {
+			var deleted bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodDelete {
+					deleted = r.Header.Get("Mcp-Session-Id") == "s1"
+					return
+				}
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				var msg Message
+				json.NewDecoder(r.Body).Decode(&msg)
+				if msg.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "s1" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Mcp-Session-Id", "s1")
+				reply := fakeServer(&msg)
+				if reply == nil {
+					w.WriteHeader(http.StatusAccepted)
+					return
+				}
+				data, _ := json.Marshal(reply)
+				if stream {
+					w.Header().Set("Content-Type", "text/event-stream")
+					fmt.Fprintf(w, ": keep-alive\n\nevent: message\ndata: %s\n\n", data)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(data)
+			}))
+			defer srv.Close()
+
+			t.Setenv("MCP_TEST_TOKEN", "secret")
+			m := Connect(context.Background(), config.MCPConfig{
+				Servers: map[string]config.MCPServerConfig{
+					"fake": {URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer ${MCP_TEST_TOKEN}"}},
+				},
+			})
+			checkServerTools(t, m)
+			m.Close()
+			if !deleted {
+				t.Error("session was not ended on close")
+			}
+		})
+	}
+}
+
+func TestConnect_LegacySSE(t *testing.T) {
+	messages := make(chan []byte, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case data := <-messages:
+					fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/messages":
+			var msg Message
+			json.NewDecoder(r.Body).Decode(&msg)
+			if reply := fakeServer(&msg); reply != nil {
+				data, _ := json.Marshal(reply)
+				messages <- data
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	m := Connect(context.Background(), config.MCPConfig{
+		Servers: map[string]config.MCPServerConfig{
+			"fake": {Transport: "sse", URL: srv.URL + "/sse"},
+		},
+	})
+	defer m.Close()
+	checkServerTools(t, m)
+}
+
+func TestConnect_SkipsBrokenAndFiltersTools(t *testing.T) {
+	m := Connect(context.Background(), config.MCPConfig{
+		Servers: map[string]config.MCPServerConfig{
+			"broken":   {Command: "/nonexistent/mcp-server"},
+			"disabled": {Disabled: true, Command: os.Args[0]},
+			"fake": {
+				Command:         os.Args[0],
+				Args:            []string{"-test.run=^$"},
+				Env:             map[string]string{"MCP_FAKE_SERVER": "1"},
+				Tools:           []string{"echo"},
+				RequireApproval: true,
+			},
+		},
+	})
+	defer m.Close()
+
+	var names []string
+	for _, tool := range m.Tools() {
+		names = append(names, tool.Name())
+	}
+	if strings.Join(names, ",") != "mcp_fake_echo,mcp_fake_resource" {
+		t.Fatalf("tools = %v, want [mcp_fake_echo mcp_fake_resource]", names)
+	}
+	if !m.Tools()[0].(*Tool).RequiresApproval() {
+		t.Error("RequiresApproval() = false, want true")
+	}
+}
+
+func TestClient_CallTimesOut(t *testing.T) {
+	transport := &silentTransport{incoming: make(chan *Message), sent: make(chan string, 16)}
+	client := NewClient("slow", transport, 50*time.Millisecond)
+	defer client.Close()
+
+	start := time.Now()
+	_, err := client.ListTools(context.Background())
+	if err == nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("ListTools() err = %v after %v, want timeout", err, time.Since(start))
+	}
+	if transport.lastMethod() != "notifications/cancelled" {
+		t.Errorf("last sent = %q, want notifications/cancelled", transport.lastMethod())
+	}
+}
+
+// silentTransport accepts messages and never answers.
+type silentTransport struct {
+	incoming chan *Message
+	sent     chan string
+}
+
+func (t *silentTransport) Start(ctx context.Context) error { return nil }
+func (t *silentTransport) Send(ctx context.Context, msg *Message) error {
+	t.sent <- msg.Method
+	return nil
+}
+func (t *silentTransport) Receive() <-chan *Message { return t.incoming }
+func (t *silentTransport) Close() error {
+	close(t.incoming)
+	return nil
+}
+func (t *silentTransport) lastMethod() string {
+	var last string
+	for {
+		select {
+		case m := <-t.sent:
+			last = m
+		default:
+			return last
+		}
+	}
+}
+
+func TestClient_StopsEndlessPaging(t *testing.T) {
+	pages := 0
+	transport := &replyTransport{incoming: make(chan *Message, 1), reply: func(req *Message) interface{} {
+		pages++
+		if req.Method == "tools/list" {
+			// A server stuck on one cursor
+			return map[string]interface{}{"tools": []interface{}{}, "nextCursor": "again"}
+		}
+		return map[string]interface{}{"resources": []interface{}{}, "nextCursor": fmt.Sprint("page", pages)}
+	}}
+	client := NewClient("looping", transport, time.Second)
+	defer client.Close()
+
+	if _, err := client.ListTools(context.Background()); err == nil || !strings.Contains(err.Error(), "repeated cursor") {
+		t.Errorf("ListTools() err = %v, want a repeated cursor error", err)
+	}
+	pages = 0
+	if _, err := client.ListResources(context.Background()); err == nil || pages != maxListPages {
+		t.Errorf("ListResources() err = %v after %d pages, want an error after %d", err, pages, maxListPages)
+	}
+}
+
+// replyTransport answers every request with the result of reply.
+type replyTransport struct {
+	incoming chan *Message
+	reply    func(req *Message) interface{}
+}
+
+func (t *replyTransport) Start(ctx context.Context) error { return nil }
+func (t *replyTransport) Send(ctx context.Context, msg *Message) error {
+	if len(msg.ID) == 0 {
+		return nil
+	}
+	result, _ := json.Marshal(t.reply(msg))
+	t.incoming <- &Message{JSONRPC: "2.0", ID: msg.ID, Result: result}
+	return nil
+}
+func (t *replyTransport) Receive() <-chan *Message { return t.incoming }
+func (t *replyTransport) Close() error {
+	close(t.incoming)
+	return nil
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct {
+		server, tool, want string
+	}{
+		{"github", "create_issue", "mcp_github_create_issue"},
+		{"my server", "read.file", "mcp_my_server_read_file"},
+		{"s", strings.Repeat("x", 100), "mcp_s_" + strings.Repeat("x", 58)},
+	}
+	for _, tt := range tests {
+		if got := ToolName(tt.server, tt.tool); got != tt.want {
+			t.Errorf("ToolName(%q, %q) = %q, want %q", tt.server, tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestFormatContent(t *testing.T) {
+	result := &CallToolResult{
+		Content: []Content{
+			{Type: "text", Text: "first"},
+			{Type: "image", MimeType: "image/png", Data: "aGVsbG8="},
+			{Type: "resource", Resource: &ResourceContents{URI: "file:///a", Text: "embedded"}},
+			{Type: "resource_link", URI: "file:///b", Name: "b"},
+		},
+	}
+	got := FormatContent(result)
+	for _, want := range []string{"first", "[image content: image/png, 8 bytes base64]", "embedded", "[resource b: file:///b]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatContent() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	structured := &CallToolResult{StructuredContent: map[string]interface{}{"temp": 21}}
+	if got := FormatContent(structured); !strings.Contains(got, `"temp": 21`) {
+		t.Errorf("FormatContent() = %q, want structured content", got)
+	}
+}
+
+func TestReadSSE_MultilineData(t *testing.T) {
+	var events []string
+	input := "event: message\r\ndata: a\r\ndata: b\r\n\r\ndata: tail"
+	readSSE(strings.NewReader(input), func(event, data string) error {
+		events = append(events, event+"|"+data)
+		return nil
+	})
+	if strings.Join(events, ";") != "message|a\nb;|tail" {
+		t.Errorf("events = %q", events)
+	}
+}
@@ -0,0 +1,369 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPTransport speaks the streamable HTTP transport: every message is
+// POSTed to one endpoint, which answers with JSON or with a stream of
+// server-sent events carrying the response.
+type HTTPTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	tokens  TokenSource
+
+	mu        sync.RWMutex
+	sessionID string
+	closed    bool
+	incoming  chan *Message
+	done      chan struct{}
+}
+
+// NewHTTPTransport creates a transport for the server at url. headers are
+// sent with every request; their values may reference ${VAR}.
+func NewHTTPTransport(url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{
+		url:      url,
+		headers:  expandHeaders(headers),
+		client:   &http.Client{},
+		incoming: make(chan *Message, 16),
+		done:     make(chan struct{}),
+	}
+}
+
+// SetTokenSource authenticates requests with OAuth bearer tokens.
+func (t *HTTPTransport) SetTokenSource(tokens TokenSource) {
+	t.tokens = tokens
+}
+
+func (t *HTTPTransport) Start(ctx context.Context) error {
+	return nil
+}
+
+func (t *HTTPTransport) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		t.setHeaders(req)
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readSSE(resp.Body, func(event, data string) error {
+			if event != "" && event != "message" {
+				return nil
+			}
+			return t.deliverJSON([]byte(data))
+		})
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return t.deliverJSON(data)
+}
+
+// deliverJSON decodes a single message or a batch and hands it to the
+// client.
+func (t *HTTPTransport) deliverJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	var msgs []*Message
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	} else {
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+		msgs = append(msgs, &msg)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrClosed
+	}
+	for _, msg := range msgs {
+		select {
+		case t.incoming <- msg:
+		case <-t.done:
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+func (t *HTTPTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.RLock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.RUnlock()
+}
+
+func (t *HTTPTransport) Receive() <-chan *Message {
+	return t.incoming
+}
+
+// Close ends the session on the server, if it issued one.
+func (t *HTTPTransport) Close() error {
+	t.mu.RLock()
+	closed := t.closed
+	t.mu.RUnlock()
+	if closed {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.mu.RLock()
+	hasSession := t.sessionID != ""
+	t.mu.RUnlock()
+	if hasSession {
+		resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+			if err == nil {
+				t.setHeaders(req)
+			}
+			return req, err
+		})
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	close(t.done)
+	t.mu.Lock()
+	t.closed = true
+	close(t.incoming)
+	t.mu.Unlock()
+	return nil
+}
+
+// SSETransport speaks the older HTTP+SSE transport: the server streams
+// messages over a long-lived GET and names, in its first event, the URL the
+// client POSTs its messages to.
+type SSETransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	tokens  TokenSource
+
+	endpoint string
+	cancel   context.CancelFunc
+	incoming chan *Message
+}
+
+// NewSSETransport creates a transport for the SSE stream at url.
+func NewSSETransport(url string, headers map[string]string) *SSETransport {
+	return &SSETransport{
+		url:      url,
+		headers:  expandHeaders(headers),
+		client:   &http.Client{},
+		incoming: make(chan *Message, 16),
+	}
+}
+
+// SetTokenSource authenticates requests with OAuth bearer tokens.
+func (t *SSETransport) SetTokenSource(tokens TokenSource) {
+	t.tokens = tokens
+}
+
+func (t *SSETransport) Start(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		for k, v := range t.headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	})
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("event stream returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	t.cancel = cancel
+
+	endpoints := make(chan string, 1)
+	go func() {
+		defer close(t.incoming)
+		defer resp.Body.Close()
+		readSSE(resp.Body, func(event, data string) error {
+			switch event {
+			case "endpoint":
+				select {
+				case endpoints <- strings.TrimSpace(data):
+				default:
+				}
+			case "", "message":
+				var msg Message
+				if err := json.Unmarshal([]byte(data), &msg); err == nil {
+					t.incoming <- &msg
+				}
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case endpoint := <-endpoints:
+		base, _ := url.Parse(t.url)
+		ref, err := url.Parse(endpoint)
+		if err != nil {
+			t.Close()
+			return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+		return nil
+	case <-ctx.Done():
+		t.Close()
+		return fmt.Errorf("waiting for endpoint: %w", ctx.Err())
+	}
+}
+
+func (t *SSETransport) Send(ctx context.Context, msg *Message) error {
+	if t.endpoint == "" {
+		return errors.New("transport is not started")
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range t.headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+func (t *SSETransport) Receive() <-chan *Message {
+	return t.incoming
+}
+
+func (t *SSETransport) Close() error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return nil
+}
+
+// readSSE calls fn for every event in a server-sent event stream until the
+// stream ends or fn returns an error.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	var event string
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if fnErr := fn(event, strings.Join(data, "\n")); fnErr != nil {
+					return fnErr
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err != nil {
+			if len(data) > 0 {
+				if fnErr := fn(event, strings.Join(data, "\n")); fnErr != nil {
+					return fnErr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func expandHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = os.ExpandEnv(v)
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// Manager holds the connections to the configured MCP servers and the tools
+// they expose.
+type Manager struct {
+	clients    []*Client
+	tools      []tools.Tool
+	connectors []connector
+}
+
+// connector is a server Anthropic connects to for Claude requests.
+type connector struct {
+	name   string
+	server config.MCPServerConfig
+	tokens TokenSource
+}
+
+// Connect connects to every enabled server in cfg. A server that fails to
+// start or initialize is logged and skipped, so one broken server does not
+// keep the agent from running. Servers marked as connectors are left to
+// Anthropic; see ConnectorServers.
+func Connect(ctx context.Context, cfg config.MCPConfig) *Manager {
+	m := &Manager{}
+
+	names := make([]string, 0, len(cfg.Servers))
+	for name := range cfg.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Stored OAuth tokens are read once, and only if a server may need one
+	var storedTokens map[string]interface{}
+	tokensFor := func(name string, server config.MCPServerConfig) TokenSource {
+		if server.URL == "" || hasHeader(server.Headers, "Authorization") {
+			return nil
+		}
+		if storedTokens == nil {
+			storedTokens = loadOAuthTokens()
+		}
+		if src := findOAuthToken(storedTokens, name, server.URL); src != nil {
+			return src
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		server := cfg.Servers[name]
+		if server.Disabled {
+			continue
+		}
+		if server.Connector {
+			m.connectors = append(m.connectors, connector{name: name, server: server, tokens: tokensFor(name, server)})
+			continue
+		}
+		client, serverTools, err := connectServer(ctx, name, server, tokensFor(name, server))
+		if err != nil {
+			logger.WarnCF("mcp", "Failed to connect to MCP server",
+				map[string]interface{}{
+					"server": name,
+					"error":  err.Error(),
+				})
+			continue
+		}
+		m.clients = append(m.clients, client)
+		m.tools = append(m.tools, serverTools...)
+
+		logger.InfoCF("mcp", "MCP server tools registered",
+			map[string]interface{}{
+				"server": name,
+				"tools":  len(serverTools),
+			})
+	}
+	return m
+}
+
+func connectServer(ctx context.Context, name string, server config.MCPServerConfig, tokens TokenSource) (*Client, []tools.Tool, error) {
+	transport, err := NewTransport(server)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tokens != nil {
+		if t, ok := transport.(interface{ SetTokenSource(TokenSource) }); ok {
+			t.SetTokenSource(tokens)
+			logger.DebugCF("mcp", "Using stored OAuth token",
+				map[string]interface{}{
+					"server": name,
+				})
+		}
+	}
+	if err := transport.Start(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	client := NewClient(name, transport, time.Duration(server.Timeout)*time.Second)
+	if err := client.Initialize(ctx); err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+
+	var serverTools []tools.Tool
+	if client.HasTools() {
+		infos, err := client.ListTools(ctx)
+		if err != nil {
+			client.Close()
+			return nil, nil, err
+		}
+		allowed := make(map[string]bool, len(server.Tools))
+		for _, t := range server.Tools {
+			allowed[t] = true
+		}
+		for _, info := range infos {
+			if len(allowed) > 0 && !allowed[info.Name] {
+				continue
+			}
+			serverTools = append(serverTools, NewTool(client, info, server.RequireApproval))
+		}
+	}
+	if client.HasResources() {
+		serverTools = append(serverTools, NewResourceTool(client))
+	}
+	return client, serverTools, nil
+}
+
+// NewTransport creates the transport server describes. Without an explicit
+// transport, Command selects stdio and URL selects streamable HTTP.
+func NewTransport(server config.MCPServerConfig) (Transport, error) {
+	kind := server.Transport
+	if kind == "" {
+		switch {
+		case server.Command != "":
+			kind = "stdio"
+		case server.URL != "":
+			kind = "http"
+		}
+	}
+
+	switch kind {
+	case "stdio":
+		if server.Command == "" {
+			return nil, fmt.Errorf("stdio transport needs a command")
+		}
+		return NewStdioTransport(server.Command, server.Args, server.Env, server.Dir), nil
+	case "http", "sse":
+		if server.URL == "" {
+			return nil, fmt.Errorf("%s transport needs a url", kind)
+		}
+		if kind == "sse" {
+			return NewSSETransport(server.URL, server.Headers), nil
+		}
+		return NewHTTPTransport(server.URL, server.Headers), nil
+	case "":
+		return nil, fmt.Errorf("server needs a command or a url")
+	default:
+		return nil, fmt.Errorf("unknown transport %q", kind)
+	}
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tools returns the tools of every connected server.
+func (m *Manager) Tools() []tools.Tool {
+	if m == nil {
+		return nil
+	}
+	return m.tools
+}
+
+// ConnectorServers returns the servers for Anthropic's MCP connector, with
+// the token each authorizes with: the bearer token of its Authorization
+// header, or the stored OAuth token for it.
+func (m *Manager) ConnectorServers(ctx context.Context) []providers.MCPServer {
+	if m == nil {
+		return nil
+	}
+	servers := make([]providers.MCPServer, 0, len(m.connectors))
+	for _, c := range m.connectors {
+		server := providers.MCPServer{Name: c.name, URL: c.server.URL, Tools: c.server.Tools}
+		for k, v := range c.server.Headers {
+			if strings.EqualFold(k, "Authorization") {
+				server.AuthorizationToken = strings.TrimPrefix(os.ExpandEnv(v), "Bearer ")
+			}
+		}
+		if server.AuthorizationToken == "" && c.tokens != nil {
+			token, err := c.tokens.Token(ctx)
+			if err != nil {
+				logger.WarnCF("mcp", "No token for MCP connector server",
+					map[string]interface{}{
+						"server": c.name,
+						"error":  err.Error(),
+					})
+			}
+			server.AuthorizationToken = token
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// Close disconnects from every server.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
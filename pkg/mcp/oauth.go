@@ -0,0 +1,304 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// TokenSource supplies bearer tokens for a remote server.
+type TokenSource interface {
+	// Token returns a valid access token, refreshing it when it is about to
+	// expire.
+	Token(ctx context.Context) (string, error)
+	// Refresh obtains a new access token after the server rejected the
+	// current one.
+	Refresh(ctx context.Context) (string, error)
+}
+
+// tokenExpiryMargin refreshes tokens slightly before they expire.
+const tokenExpiryMargin = time.Minute
+
+// The stored OAuth tokens come from Claude Code, which authorizes remote MCP
+// servers with its own OAuth flow. Tests replace these.
+var (
+	loadOAuthTokens = func() map[string]interface{} {
+		return providers.LoadClaudeMCPOAuthTokens(providers.TokenManagerConfig{})
+	}
+	saveOAuthToken = func(key string, entry map[string]interface{}) error {
+		return providers.SaveClaudeMCPOAuthToken(providers.TokenManagerConfig{}, key, entry)
+	}
+)
+
+// OAuthTokenSource serves a stored OAuth token for one server and refreshes
+// it with the server's authorization server. Refreshed tokens are written
+// back to the store they came from.
+type OAuthTokenSource struct {
+	key      string
+	client   *http.Client
+	tokenURL string
+
+	mu    sync.Mutex
+	entry map[string]interface{}
+}
+
+// findOAuthToken returns a token source for the stored token of the server
+// called name at serverURL, or nil when none is stored. Tokens are matched by
+// URL first and by server name second.
+func findOAuthToken(tokens map[string]interface{}, name, serverURL string) *OAuthTokenSource {
+	var byName *OAuthTokenSource
+	for key, raw := range tokens {
+		entry, ok := raw.(map[string]interface{})
+		if !ok || stringField(entry, "accessToken") == "" {
+			continue
+		}
+		src := &OAuthTokenSource{key: key, entry: entry, client: &http.Client{Timeout: 30 * time.Second}}
+		if sameURL(stringField(entry, "serverUrl"), serverURL) {
+			return src
+		}
+		if byName == nil && stringField(entry, "serverName") == name {
+			byName = src
+		}
+	}
+	return byName
+}
+
+func sameURL(a, b string) bool {
+	return a != "" && strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+func (s *OAuthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.expiresAt()
+	if expiresAt.IsZero() || time.Until(expiresAt) > tokenExpiryMargin || stringField(s.entry, "refreshToken") == "" {
+		return stringField(s.entry, "accessToken"), nil
+	}
+	return s.refreshLocked(ctx)
+}
+
+func (s *OAuthTokenSource) Refresh(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshLocked(ctx)
+}
+
+func (s *OAuthTokenSource) expiresAt() time.Time {
+	// Claude Code stores expiry as milliseconds since the epoch
+	if ms, ok := s.entry["expiresAt"].(float64); ok && ms > 0 {
+		return time.UnixMilli(int64(ms))
+	}
+	return time.Time{}
+}
+
+func (s *OAuthTokenSource) refreshLocked(ctx context.Context) (string, error) {
+	refreshToken := stringField(s.entry, "refreshToken")
+	if refreshToken == "" {
+		return "", errors.New("token expired and no refresh token is stored")
+	}
+
+	tokenURL, err := s.tokenEndpoint(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	if clientID := stringField(s.entry, "clientId"); clientID != "" {
+		form.Set("client_id", clientID)
+	}
+	if secret := stringField(s.entry, "clientSecret"); secret != "" {
+		form.Set("client_secret", secret)
+	}
+	if resource := stringField(s.entry, "serverUrl"); resource != "" {
+		form.Set("resource", resource)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create refresh request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("refreshing token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token refresh failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("parsing token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("no access token in refresh response")
+	}
+
+	entry := make(map[string]interface{}, len(s.entry))
+	for k, v := range s.entry {
+		entry[k] = v
+	}
+	entry["accessToken"] = tokenResp.AccessToken
+	if tokenResp.RefreshToken != "" {
+		entry["refreshToken"] = tokenResp.RefreshToken
+	}
+	if tokenResp.ExpiresIn > 0 {
+		entry["expiresAt"] = float64(time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second).UnixMilli())
+	} else {
+		delete(entry, "expiresAt")
+	}
+	if tokenResp.Scope != "" {
+		entry["scope"] = tokenResp.Scope
+	}
+	s.entry = entry
+
+	if err := saveOAuthToken(s.key, entry); err != nil {
+		logger.WarnCF("mcp", "Failed to save refreshed MCP OAuth token",
+			map[string]interface{}{
+				"server": stringField(entry, "serverName"),
+				"error":  err.Error(),
+			})
+	}
+	logger.InfoCF("mcp", "Refreshed MCP OAuth token",
+		map[string]interface{}{
+			"server": stringField(entry, "serverName"),
+		})
+	return tokenResp.AccessToken, nil
+}
+
+// tokenEndpoint finds the token endpoint of the server's authorization
+// server: from the stored discovery state, the server's protected resource
+// metadata, or the server's own origin, in that order.
+func (s *OAuthTokenSource) tokenEndpoint(ctx context.Context) (string, error) {
+	if s.tokenURL != "" {
+		return s.tokenURL, nil
+	}
+
+	issuer := ""
+	if discovery, ok := s.entry["discoveryState"].(map[string]interface{}); ok {
+		issuer = stringField(discovery, "authorizationServerUrl")
+	}
+	serverURL, err := url.Parse(stringField(s.entry, "serverUrl"))
+	if err != nil || serverURL.Host == "" {
+		return "", fmt.Errorf("stored token has no usable serverUrl")
+	}
+	origin := serverURL.Scheme + "://" + serverURL.Host
+
+	if issuer == "" {
+		var resource struct {
+			AuthorizationServers []string `json:"authorization_servers"`
+		}
+		if s.getJSON(ctx, origin+"/.well-known/oauth-protected-resource"+strings.TrimRight(serverURL.Path, "/"), &resource) == nil ||
+			s.getJSON(ctx, origin+"/.well-known/oauth-protected-resource", &resource) == nil {
+			if len(resource.AuthorizationServers) > 0 {
+				issuer = resource.AuthorizationServers[0]
+			}
+		}
+	}
+	if issuer == "" {
+		issuer = origin
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil || issuerURL.Host == "" {
+		return "", fmt.Errorf("invalid authorization server %q", issuer)
+	}
+	issuerOrigin := issuerURL.Scheme + "://" + issuerURL.Host
+	issuerPath := strings.TrimRight(issuerURL.Path, "/")
+
+	var metadata struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	for _, candidate := range []string{
+		issuerOrigin + "/.well-known/oauth-authorization-server" + issuerPath,
+		issuerOrigin + "/.well-known/openid-configuration" + issuerPath,
+		strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration",
+	} {
+		if s.getJSON(ctx, candidate, &metadata) == nil && metadata.TokenEndpoint != "" {
+			s.tokenURL = metadata.TokenEndpoint
+			return s.tokenURL, nil
+		}
+	}
+
+	// Servers predating metadata discovery serve /token at their origin
+	return issuerOrigin + "/token", nil
+}
+
+func (s *OAuthTokenSource) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// doWithToken sends the request built by newRequest with the source's
+// bearer token, and retries once with a refreshed token if the server
+// answers 401. A nil source sends the request as is.
+func doWithToken(ctx context.Context, client *http.Client, tokens TokenSource, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		return client.Do(req)
+	}
+
+	token, err := tokens.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OAuth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	token, err = tokens.Refresh(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server rejected the stored OAuth token and refreshing it failed: %w", err)
+	}
+	if req, err = newRequest(); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return client.Do(req)
+}
@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestConnect_UsesStoredOAuthToken(t *testing.T) {
+	var refreshes atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-protected-resource/mcp":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"authorization_servers": []string{srv.URL + "/auth"},
+			})
+		case "/.well-known/oauth-authorization-server/auth":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token_endpoint": srv.URL + "/auth/token",
+			})
+		case "/auth/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" || r.Form.Get("client_id") != "client" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshes.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "fresh",
+				"refresh_token": "refresh-2",
+				"expires_in":    3600,
+			})
+		case "/mcp":
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var msg Message
+			json.NewDecoder(r.Body).Decode(&msg)
+			reply := fakeServer(&msg)
+			if reply == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(reply)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+	}{
+		// An expired token is refreshed before the first request
+		{"expired", time.Now().Add(-time.Hour)},
+		// A token the server rejects is refreshed after the 401
+		{"revoked", time.Now().Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshes.Store(0)
+			var saved map[string]interface{}
+			loadOAuthTokens = func() map[string]interface{} {
+				return map[string]interface{}{
+					"other|1": map[string]interface{}{"serverName": "other", "serverUrl": "https://example.com", "accessToken": "x"},
+					"remote|abc": map[string]interface{}{
+						"serverName":   "remote",
+						"serverUrl":    srv.URL + "/mcp/",
+						"clientId":     "client",
+						"accessToken":  "stale",
+						"refreshToken": "refresh-1",
+						"expiresAt":    float64(tt.expiresAt.UnixMilli()),
+					},
+				}
+			}
+			saveOAuthToken = func(key string, entry map[string]interface{}) error {
+				if key == "remote|abc" {
+					saved = entry
+				}
+				return nil
+			}
+			defer func() {
+				loadOAuthTokens = func() map[string]interface{} { return map[string]interface{}{} }
+				saveOAuthToken = func(string, map[string]interface{}) error { return nil }
+			}()
+
+			m := Connect(context.Background(), config.MCPConfig{
+				Servers: map[string]config.MCPServerConfig{
+					"fake": {URL: srv.URL + "/mcp"},
+				},
+			})
+			defer m.Close()
+			checkServerTools(t, m)
+
+			if refreshes.Load() != 1 {
+				t.Errorf("refreshes = %d, want 1", refreshes.Load())
+			}
+			if saved["accessToken"] != "fresh" || saved["refreshToken"] != "refresh-2" || saved["serverName"] != "remote" {
+				t.Errorf("saved entry = %v, want refreshed tokens", saved)
+			}
+		})
+	}
+}
+
+func TestFindOAuthToken(t *testing.T) {
+	tokens := map[string]interface{}{
+		"a|1": map[string]interface{}{"serverName": "github", "serverUrl": "https://api.example.com/mcp", "accessToken": "t1"},
+		"b|2": map[string]interface{}{"serverName": "linear", "serverUrl": "https://mcp.linear.app/sse", "accessToken": "t2"},
+		"c|3": map[string]interface{}{"serverName": "empty", "serverUrl": "https://empty.example.com"},
+	}
+
+	tests := []struct {
+		name, url, wantKey string
+	}{
+		{"anything", "https://api.example.com/mcp/", "a|1"},
+		{"linear", "https://other.example.com", "b|2"},
+		{"empty", "https://empty.example.com", ""},
+		{"unknown", "https://unknown.example.com", ""},
+	}
+	for _, tt := range tests {
+		src := findOAuthToken(tokens, tt.name, tt.url)
+		got := ""
+		if src != nil {
+			got = src.key
+		}
+		if got != tt.wantKey {
+			t.Errorf("findOAuthToken(%q, %q) = %q, want %q", tt.name, tt.url, got, tt.wantKey)
+		}
+	}
+}
+
+func TestConnectorServers(t *testing.T) {
+	t.Setenv("DOCS_TOKEN", "docs-secret")
+	loadOAuthTokens = func() map[string]interface{} {
+		return map[string]interface{}{
+			"issues|1": map[string]interface{}{
+				"serverName":  "issues",
+				"serverUrl":   "https://issues.example.com/mcp",
+				"accessToken": "stored",
+				"expiresAt":   float64(time.Now().Add(time.Hour).UnixMilli()),
+			},
+		}
+	}
+	defer func() { loadOAuthTokens = func() map[string]interface{} { return map[string]interface{}{} } }()
+
+	m := Connect(context.Background(), config.MCPConfig{
+		Servers: map[string]config.MCPServerConfig{
+			"docs":   {URL: "https://docs.example.com/mcp", Connector: true, Headers: map[string]string{"Authorization": "Bearer ${DOCS_TOKEN}"}},
+			"issues": {URL: "https://issues.example.com/mcp", Connector: true, Tools: []string{"search"}},
+			"off":    {URL: "https://off.example.com/mcp", Connector: true, Disabled: true},
+		},
+	})
+	defer m.Close()
+
+	if len(m.Tools()) != 0 {
+		t.Errorf("Tools() = %d tools, want none from connector servers", len(m.Tools()))
+	}
+	servers := m.ConnectorServers(context.Background())
+	if len(servers) != 2 {
+		t.Fatalf("ConnectorServers() = %+v, want docs and issues", servers)
+	}
+	if servers[0].Name != "docs" || servers[0].AuthorizationToken != "docs-secret" {
+		t.Errorf("docs = %+v, want the header's token", servers[0])
+	}
+	if servers[1].Name != "issues" || servers[1].AuthorizationToken != "stored" || len(servers[1].Tools) != 1 {
+		t.Errorf("issues = %+v, want the stored token and the tool filter", servers[1])
+	}
+}
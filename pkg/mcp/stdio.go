@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// StdioTransport runs a local MCP server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout.
+type StdioTransport struct {
+	command string
+	args    []string
+	env     map[string]string
+	dir     string
+
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	writeMu  sync.Mutex
+	incoming chan *Message
+	exited   chan struct{}
+}
+
+// NewStdioTransport prepares to run command with args. env is added to the
+// current environment; dir is the working directory (empty inherits it).
+func NewStdioTransport(command string, args []string, env map[string]string, dir string) *StdioTransport {
+	return &StdioTransport{
+		command:  command,
+		args:     args,
+		env:      env,
+		dir:      dir,
+		incoming: make(chan *Message, 16),
+		exited:   make(chan struct{}),
+	}
+}
+
+func (t *StdioTransport) Start(ctx context.Context) error {
+	// The server outlives ctx, which only bounds connecting
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Dir = t.dir
+	cmd.Env = os.Environ()
+	for k, v := range t.env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.command, err)
+	}
+	t.cmd = cmd
+	t.stdin = stdin
+
+	go t.logStderr(stderr)
+	go func() {
+		// Wait must not run before stdout is fully read
+		t.readMessages(stdout)
+		cmd.Wait()
+		close(t.exited)
+	}()
+	return nil
+}
+
+func (t *StdioTransport) readMessages(stdout io.Reader) {
+	defer close(t.incoming)
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var msg Message
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+				logger.DebugCF("mcp", "Ignoring non-JSON output from server",
+					map[string]interface{}{
+						"command": t.command,
+						"line":    strings.TrimSpace(string(line)),
+					})
+			} else {
+				t.incoming <- &msg
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		logger.DebugCF("mcp", "Server stderr",
+			map[string]interface{}{
+				"command": t.command,
+				"line":    scanner.Text(),
+			})
+	}
+}
+
+func (t *StdioTransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	data = append(data, '\n')
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if t.stdin == nil {
+		return ErrClosed
+	}
+	if _, err := t.stdin.Write(data); err != nil {
+		return fmt.Errorf("failed to write to server: %w", err)
+	}
+	return nil
+}
+
+func (t *StdioTransport) Receive() <-chan *Message {
+	return t.incoming
+}
+
+// Close closes the server's stdin, which asks it to exit, and kills it if
+// it is still running shortly after.
+func (t *StdioTransport) Close() error {
+	t.writeMu.Lock()
+	stdin := t.stdin
+	t.stdin = nil
+	t.writeMu.Unlock()
+	if stdin == nil || t.cmd == nil {
+		return nil
+	}
+	stdin.Close()
+
+	select {
+	case <-t.exited:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+		select {
+		case <-t.exited:
+		case <-time.After(2 * time.Second):
+			// A child of the server may still hold stdout open
+		}
+	}
+	return nil
+}
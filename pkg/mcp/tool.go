@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// maxToolNameLen is the longest function name providers accept.
+const maxToolNameLen = 64
+
+// Tool exposes one MCP server tool to the agent as mcp_<server>_<tool>.
+type Tool struct {
+	client   *Client
+	info     ToolInfo
+	name     string
+	approval bool
+}
+
+// NewTool adapts info, a tool offered by client's server. When
+// requireApproval is set the tool asks before running.
+func NewTool(client *Client, info ToolInfo, requireApproval bool) *Tool {
+	return &Tool{
+		client:   client,
+		info:     info,
+		name:     ToolName(client.Name(), info.Name),
+		approval: requireApproval,
+	}
+}
+
+// ToolName builds the registry name for a server's tool. Characters
+// providers reject are replaced with underscores and the result is cut to
+// 64 characters.
+func ToolName(server, tool string) string {
+	name := sanitizeName("mcp_" + server + "_" + tool)
+	if len(name) > maxToolNameLen {
+		name = name[:maxToolNameLen]
+	}
+	return name
+}
+
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func (t *Tool) Name() string {
+	return t.name
+}
+
+func (t *Tool) Description() string {
+	desc := t.info.Description
+	if desc == "" {
+		desc = t.info.Name
+	}
+	return fmt.Sprintf("[MCP %s] %s", t.client.Name(), desc)
+}
+
+func (t *Tool) Parameters() map[string]interface{} {
+	schema := make(map[string]interface{}, len(t.info.InputSchema)+1)
+	for k, v := range t.info.InputSchema {
+		schema[k] = v
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+	return schema
+}
+
+func (t *Tool) RequiresApproval() bool {
+	return t.approval
+}
+
+func (t *Tool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	result, err := t.client.CallTool(ctx, t.info.Name, args)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("MCP tool %s failed: %v", t.info.Name, err)).WithError(err)
+	}
+
+	text := FormatContent(result)
+	if result.IsError {
+		if text == "" {
+			text = "tool reported an error"
+		}
+		return tools.ErrorResult(fmt.Sprintf("MCP tool %s failed: %s", t.info.Name, text))
+	}
+	if text == "" {
+		text = "(no output)"
+	}
+	return tools.SilentResult(text)
+}
+
+// FormatContent renders a tool result as text for the model. Binary content
+// is replaced by a short placeholder; structured content is used when the
+// server sent no text.
+func FormatContent(result *CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s content: %s, %d bytes base64]", c.Type, c.MimeType, len(c.Data)))
+		case "resource":
+			if c.Resource != nil {
+				parts = append(parts, formatResource(*c.Resource))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource %s: %s]", c.Name, c.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[unsupported content type %q]", c.Type))
+		}
+	}
+
+	hasText := false
+	for _, c := range result.Content {
+		if c.Type == "text" && c.Text != "" {
+			hasText = true
+			break
+		}
+	}
+	if !hasText && result.StructuredContent != nil {
+		if data, err := json.MarshalIndent(result.StructuredContent, "", "  "); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatResource(rc ResourceContents) string {
+	if rc.Text != "" {
+		return rc.Text
+	}
+	if rc.Blob != "" {
+		return fmt.Sprintf("[binary resource %s: %s, %d bytes base64]", rc.URI, rc.MimeType, len(rc.Blob))
+	}
+	return fmt.Sprintf("[empty resource %s]", rc.URI)
+}
+
+// ResourceTool lets the agent list and read a server's resources.
+type ResourceTool struct {
+	client *Client
+	name   string
+}
+
+// NewResourceTool creates the mcp_<server>_resource tool for client.
+func NewResourceTool(client *Client) *ResourceTool {
+	return &ResourceTool{
+		client: client,
+		name:   ToolName(client.Name(), "resource"),
+	}
+}
+
+func (t *ResourceTool) Name() string {
+	return t.name
+}
+
+func (t *ResourceTool) Description() string {
+	return fmt.Sprintf("[MCP %s] List the server's resources, or read one by URI.", t.client.Name())
+}
+
+func (t *ResourceTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"uri": map[string]interface{}{
+				"type":        "string",
+				"description": "URI of the resource to read. Omit to list available resources.",
+			},
+		},
+	}
+}
+
+func (t *ResourceTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	uri, _ := args["uri"].(string)
+	if uri == "" {
+		resources, err := t.client.ListResources(ctx)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("failed to list resources: %v", err)).WithError(err)
+		}
+		if len(resources) == 0 {
+			return tools.SilentResult("The server has no resources.")
+		}
+		var sb strings.Builder
+		for _, r := range resources {
+			fmt.Fprintf(&sb, "- %s (%s)", r.URI, r.Name)
+			if r.MimeType != "" {
+				fmt.Fprintf(&sb, " [%s]", r.MimeType)
+			}
+			if r.Description != "" {
+				fmt.Fprintf(&sb, ": %s", r.Description)
+			}
+			sb.WriteString("\n")
+		}
+		return tools.SilentResult(sb.String())
+	}
+
+	contents, err := t.client.ReadResource(ctx, uri)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("failed to read resource: %v", err)).WithError(err)
+	}
+	parts := make([]string, 0, len(contents))
+	for _, rc := range contents {
+		parts = append(parts, formatResource(rc))
+	}
+	return tools.SilentResult(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,221 @@
+// Package plugins loads tool plugins: executables that speak a small
+// JSON-RPC 2.0 protocol over stdin and stdout, one message per line, so
+// teams can add tools without rebuilding picoclaw.
+//
+// Each plugin is started once, in the workspace with PICOCLAW_WORKSPACE
+// set, and asked for its tools:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"describe"}
+//	{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"weather","description":"Current weather for a city","parameters":{"type":"object","properties":{"city":{"type":"string"}}},"requires_approval":false}]}}
+//
+// Tool calls are then sent as
+//
+//	{"jsonrpc":"2.0","id":2,"method":"call","params":{"tool":"weather","arguments":{"city":"Oslo"}}}
+//	{"jsonrpc":"2.0","id":2,"result":{"output":"12°C, light rain","is_error":false}}
+//
+// Calls may overlap, so replies are matched by id; a JSON-RPC error fails
+// the call. Closing stdin asks the plugin to exit, and what it writes to
+// stderr is logged at debug level.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// Plugin is an executable found in the plugins directory.
+type Plugin struct {
+	Name string // file name without extension
+	Path string
+}
+
+// Manager holds the running plugins and the tools they offer.
+type Manager struct {
+	clients []*mcp.Client
+	tools   []tools.Tool
+}
+
+// Load starts every plugin in the configured directory and collects its
+// tools. A plugin that fails to start or describe itself is logged and
+// skipped, like an MCP server, so one broken plugin does not keep the agent
+// from running.
+func Load(ctx context.Context, cfg config.PluginsConfig, workspace string) *Manager {
+	m := &Manager{}
+	if !cfg.Enabled {
+		return m
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return m
+		}
+		dir = filepath.Join(home, ".picoclaw", "plugins")
+	} else if strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	}
+	timeout := 60 * time.Second
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	found, err := Discover(dir)
+	if err != nil {
+		logger.WarnCF("plugins", "Failed to read plugins directory",
+			map[string]interface{}{
+				"dir":   dir,
+				"error": err.Error(),
+			})
+		return m
+	}
+
+	seen := map[string]string{}
+	for _, p := range found {
+		if slices.Contains(cfg.Disabled, p.Name) {
+			continue
+		}
+		client, infos, err := start(ctx, p, workspace, timeout)
+		if err != nil {
+			logger.WarnCF("plugins", "Failed to load plugin",
+				map[string]interface{}{
+					"plugin": p.Name,
+					"error":  err.Error(),
+				})
+			continue
+		}
+		m.clients = append(m.clients, client)
+
+		for _, info := range infos {
+			if other, ok := seen[info.Name]; ok {
+				logger.WarnCF("plugins", "Plugin tool name already taken, skipped",
+					map[string]interface{}{
+						"plugin": p.Name,
+						"tool":   info.Name,
+						"owner":  other,
+					})
+				continue
+			}
+			seen[info.Name] = p.Name
+			m.tools = append(m.tools, &Tool{client: client, plugin: p.Name, info: info, timeout: timeout})
+		}
+		logger.InfoCF("plugins", "Plugin tools registered",
+			map[string]interface{}{
+				"plugin": p.Name,
+				"tools":  len(infos),
+			})
+	}
+	return m
+}
+
+// Discover lists the plugins in dir: its executable files, by name. A
+// missing directory has none.
+func Discover(dir string) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Plugin
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path) // follows symlinks
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		ext := filepath.Ext(name)
+		if runtime.GOOS == "windows" {
+			switch strings.ToLower(ext) {
+			case ".exe", ".bat", ".cmd":
+			default:
+				continue
+			}
+		} else if info.Mode()&0o111 == 0 {
+			continue
+		}
+		found = append(found, Plugin{Name: strings.TrimSuffix(name, ext), Path: path})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found, nil
+}
+
+// start runs plugin p and asks it for its tools.
+func start(ctx context.Context, p Plugin, workspace string, timeout time.Duration) (*mcp.Client, []toolInfo, error) {
+	transport := mcp.NewStdioTransport(p.Path, nil, map[string]string{"PICOCLAW_WORKSPACE": workspace}, workspace)
+	if err := transport.Start(ctx); err != nil {
+		return nil, nil, err
+	}
+	client := mcp.NewClient("plugin:"+p.Name, transport, timeout)
+
+	var result describeResult
+	if err := client.Call(ctx, "describe", nil, &result); err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("describe failed: %w", err)
+	}
+	infos := make([]toolInfo, 0, len(result.Tools))
+	for _, info := range result.Tools {
+		if !validToolName(info.Name) {
+			logger.WarnCF("plugins", "Skipping plugin tool with an invalid name",
+				map[string]interface{}{
+					"plugin": p.Name,
+					"tool":   info.Name,
+				})
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return client, infos, nil
+}
+
+// validToolName reports whether name is one providers accept: letters,
+// digits, underscores and hyphens, at most 64 of them.
+func validToolName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Tools returns the tools of every loaded plugin.
+func (m *Manager) Tools() []tools.Tool {
+	if m == nil {
+		return nil
+	}
+	return m.tools
+}
+
+// Close stops every plugin.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
@@ -0,0 +1,141 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+)
+
+// fakePlugin answers one request the way a plugin would.
+func fakePlugin(req *mcp.Message) *mcp.Message {
+	reply := &mcp.Message{JSONRPC: "2.0", ID: req.ID}
+	var result interface{}
+	switch req.Method {
+	case "describe":
+		result = describeResult{Tools: []toolInfo{
+			{
+				Name:        "shout",
+				Description: "Upper-case the text",
+				Parameters: map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+				},
+			},
+			{Name: "deploy", RequiresApproval: true},
+			{Name: "bad name"},
+		}}
+	case "call":
+		var params callParams
+		json.Unmarshal(req.Params, &params)
+		switch params.Tool {
+		case "shout":
+			result = callResult{Output: strings.ToUpper(fmt.Sprint(params.Arguments["text"]))}
+		default:
+			result = callResult{Output: "no deploys today", IsError: true}
+		}
+	default:
+		reply.Error = &mcp.RPCError{Code: -32601, Message: "method not found"}
+		return reply
+	}
+	reply.Result, _ = json.Marshal(result)
+	return reply
+}
+
+// TestMain lets the test binary double as a plugin.
+func TestMain(m *testing.M) {
+	if os.Getenv("PLUGIN_FAKE") == "1" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			var msg mcp.Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || len(msg.ID) == 0 {
+				continue
+			}
+			data, _ := json.Marshal(fakePlugin(&msg))
+			fmt.Println(string(data))
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// writePlugin installs a script in dir that runs the test binary as a
+// plugin.
+func writePlugin(t *testing.T, dir, name string) {
+	t.Helper()
+	script := fmt.Sprintf("#!/bin/sh\nPLUGIN_FAKE=1 exec %q -test.run='^$'\n", os.Args[0])
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "greeter.sh")
+	writePlugin(t, dir, "copycat")
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0o644)
+
+	m := Load(context.Background(), config.PluginsConfig{Enabled: true, Dir: dir}, t.TempDir())
+	defer m.Close()
+
+	got := map[string]*Tool{}
+	for _, tool := range m.Tools() {
+		got[tool.Name()] = tool.(*Tool)
+	}
+	if len(got) != 2 || got["shout"] == nil || got["deploy"] == nil {
+		t.Fatalf("tools = %v, want shout and deploy once each", got)
+	}
+	// copycat sorts first and keeps the names
+	if got["shout"].plugin != "copycat" {
+		t.Errorf("shout comes from %s, want copycat", got["shout"].plugin)
+	}
+	if !got["deploy"].RequiresApproval() || got["shout"].RequiresApproval() {
+		t.Error("approval should follow what the plugin declared")
+	}
+
+	result := got["shout"].Execute(context.Background(), map[string]interface{}{"text": "hello"})
+	if result.IsError || result.ForLLM != "HELLO" {
+		t.Errorf("shout result = %+v, want HELLO", result)
+	}
+	result = got["deploy"].Execute(context.Background(), nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "no deploys today") {
+		t.Errorf("deploy result = %+v, want the plugin's error", result)
+	}
+}
+
+func TestLoad_Disabled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "greeter.sh")
+
+	m := Load(context.Background(), config.PluginsConfig{Enabled: true, Dir: dir, Disabled: []string{"greeter"}}, t.TempDir())
+	defer m.Close()
+	if len(m.Tools()) != 0 {
+		t.Errorf("disabled plugin loaded: %v", m.Tools())
+	}
+
+	m = Load(context.Background(), config.PluginsConfig{Dir: dir}, t.TempDir())
+	if len(m.Tools()) != 0 {
+		t.Errorf("plugins loaded while disabled: %v", m.Tools())
+	}
+}
+
+func TestDiscover_MissingDir(t *testing.T) {
+	found, err := Discover(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(found) != 0 {
+		t.Errorf("Discover = %v, %v; want nothing", found, err)
+	}
+}
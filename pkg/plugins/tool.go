@@ -0,0 +1,97 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// toolInfo is a tool as a plugin describes it.
+type toolInfo struct {
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description,omitempty"`
+	Parameters       map[string]interface{} `json:"parameters,omitempty"`
+	RequiresApproval bool                   `json:"requires_approval,omitempty"`
+}
+
+type describeResult struct {
+	Tools []toolInfo `json:"tools"`
+}
+
+type callParams struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type callResult struct {
+	Output  string `json:"output"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Tool exposes one plugin tool to the agent under the name the plugin
+// gave it.
+type Tool struct {
+	client  *mcp.Client
+	plugin  string
+	info    toolInfo
+	timeout time.Duration
+}
+
+func (t *Tool) Name() string {
+	return t.info.Name
+}
+
+func (t *Tool) Description() string {
+	desc := t.info.Description
+	if desc == "" {
+		desc = t.info.Name
+	}
+	return fmt.Sprintf("[plugin %s] %s", t.plugin, desc)
+}
+
+func (t *Tool) Parameters() map[string]interface{} {
+	schema := make(map[string]interface{}, len(t.info.Parameters)+1)
+	for k, v := range t.info.Parameters {
+		schema[k] = v
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+	return schema
+}
+
+// RequiresApproval is what the plugin declared for the tool.
+func (t *Tool) RequiresApproval() bool {
+	return t.info.RequiresApproval
+}
+
+// Timeout is the plugin call timeout, which bounds the call already.
+func (t *Tool) Timeout() time.Duration {
+	return t.timeout
+}
+
+func (t *Tool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result callResult
+	if err := t.client.Call(ctx, "call", callParams{Tool: t.info.Name, Arguments: args}, &result); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("plugin %s: %s failed: %v", t.plugin, t.info.Name, err)).WithError(err)
+	}
+	if result.IsError {
+		if result.Output == "" {
+			result.Output = "tool reported an error"
+		}
+		return tools.ErrorResult(fmt.Sprintf("plugin %s: %s failed: %s", t.plugin, t.info.Name, result.Output))
+	}
+	if result.Output == "" {
+		result.Output = "(no output)"
+	}
+	return tools.SilentResult(result.Output)
+}
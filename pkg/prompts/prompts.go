@@ -0,0 +1,104 @@
+// Package prompts renders the prompts picoclaw sends to models from Go
+// templates. A prompt named "summarize" is the file summarize.tmpl; a file
+// summarize.anthropic.tmpl, when present, is used instead for the
+// anthropic provider. Templates can render other prompts with
+// {{include "name" .}}. Directories given to New are searched before the
+// built-in prompts, so a workspace can override any of them.
+package prompts
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+)
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// maxIncludeDepth stops templates that include each other in a cycle.
+const maxIncludeDepth = 10
+
+// Library finds and renders prompt templates.
+type Library struct {
+	sources []fs.FS
+}
+
+// New returns a library that looks for prompts in dirs, in order, before
+// the built-in ones. Missing directories are skipped.
+func New(dirs ...string) *Library {
+	l := &Library{}
+	for _, dir := range dirs {
+		if dir != "" {
+			l.sources = append(l.sources, os.DirFS(dir))
+		}
+	}
+	builtin, _ := fs.Sub(defaults, "defaults")
+	l.sources = append(l.sources, builtin)
+	return l
+}
+
+// Default returns a library of the built-in prompts only.
+func Default() *Library {
+	return New()
+}
+
+// Render renders the prompt name with data.
+func (l *Library) Render(name string, data interface{}) (string, error) {
+	return l.RenderFor(name, "", data)
+}
+
+// RenderFor renders the prompt name with data, preferring the variant for
+// provider.
+func (l *Library) RenderFor(name, provider string, data interface{}) (string, error) {
+	return l.render(name, provider, data, 0)
+}
+
+func (l *Library) render(name, provider string, data interface{}, depth int) (string, error) {
+	if depth > maxIncludeDepth {
+		return "", fmt.Errorf("prompt %q: includes nested more than %d deep", name, maxIncludeDepth)
+	}
+	text, err := l.load(name, provider)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"include": func(include string, data interface{}) (string, error) {
+			return l.render(include, provider, data, depth+1)
+		},
+		"join": strings.Join,
+		"trim": strings.TrimSpace,
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("prompt %q: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("prompt %q: %w", name, err)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// load returns the first template found for name, checking every source
+// for the provider's variant before the generic one.
+func (l *Library) load(name, provider string) (string, error) {
+	files := []string{name + ".tmpl"}
+	if provider != "" {
+		files = append([]string{name + "." + provider + ".tmpl"}, files...)
+	}
+	for _, file := range files {
+		for _, source := range l.sources {
+			data, err := fs.ReadFile(source, file)
+			if err == nil {
+				return string(data), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("prompt %q: %w", name, err)
+			}
+		}
+	}
+	return "", fmt.Errorf("prompt %q not found", name)
+}
@@ -0,0 +1,83 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePrompt(t *testing.T, dir, file, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRender_Builtin(t *testing.T) {
+	got, err := Default().Render("summary_merge", map[string]interface{}{"First": "a", "Second": "b"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "Merge these two conversation summaries into one cohesive summary:\n\n1: a\n\n2: b"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	identity, err := Default().Render("identity", map[string]interface{}{
+		"Time": "now", "Runtime": "linux", "Workspace": "/ws", "Tools": []string{"- read_file: Read a file"},
+	})
+	if err != nil {
+		t.Fatalf("Render(identity) error = %v", err)
+	}
+	for _, want := range []string{"Your workspace is at: /ws", "## Available Tools", "- read_file: Read a file", "write to /ws/memory/MEMORY.md"} {
+		if !strings.Contains(identity, want) {
+			t.Errorf("identity missing %q:\n%s", want, identity)
+		}
+	}
+}
+
+func TestRender_OverridesAndProviderVariants(t *testing.T) {
+	global, workspace := t.TempDir(), t.TempDir()
+	writePrompt(t, global, "greeting.tmpl", `Hello {{.Name}}. {{include "sign" .}}`)
+	writePrompt(t, global, "sign.tmpl", "-- picoclaw")
+	writePrompt(t, workspace, "sign.anthropic.tmpl", "-- claw for Claude\n")
+	lib := New(workspace, global)
+
+	tests := []struct {
+		provider string
+		want     string
+	}{
+		{"", "Hello Ana. -- picoclaw"},
+		{"openai", "Hello Ana. -- picoclaw"},
+		{"anthropic", "Hello Ana. -- claw for Claude"},
+	}
+	for _, tt := range tests {
+		got, err := lib.RenderFor("greeting", tt.provider, map[string]string{"Name": "Ana"})
+		if err != nil || got != tt.want {
+			t.Errorf("RenderFor(greeting, %q) = %q, %v; want %q", tt.provider, got, err, tt.want)
+		}
+	}
+
+	// A workspace prompt replaces the built-in one
+	writePrompt(t, workspace, "summary_merge.tmpl", "{{.First}}+{{.Second}}")
+	if got, _ := lib.Render("summary_merge", map[string]string{"First": "a", "Second": "b"}); got != "a+b" {
+		t.Errorf("Render(summary_merge) = %q, want the workspace version", got)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "loop.tmpl", `{{include "loop" .}}`)
+	lib := New(dir)
+
+	if _, err := lib.Render("missing", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Render(missing) error = %v, want not found", err)
+	}
+	if _, err := lib.Render("loop", nil); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Render(loop) error = %v, want an include depth error", err)
+	}
+	if _, err := lib.Render("summary_merge", map[string]string{"First": "a"}); err == nil {
+		t.Error("Render() with a missing variable succeeded")
+	}
+}
@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decode GIFs to downscale them
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedFile is returned for files no provider takes as content,
+// or that are too large to send. Check for it with errors.Is.
+var ErrUnsupportedFile = errors.New("unsupported file")
+
+// Limits of attached files, the strictest among the providers: Anthropic
+// takes images of up to 5 MB, and every provider scales images down to
+// about 2048 pixels on their longer side anyway.
+const (
+	maxImageBytes = 5 << 20
+	maxImageEdge  = 2048
+	maxFileBytes  = 32 << 20
+)
+
+// FilePart reads the file at path into a content part of its kind: an
+// image, a document or a recording. See DataPart.
+func FilePart(path string) (ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, err
+	}
+	return DataPart(filepath.Base(path), data)
+}
+
+// DataPart makes a content part of the file name holding data. Its type is
+// detected from the name's extension or, failing that, the data. Images
+// larger than providers take are scaled down; PDFs and text become
+// documents and WAV and MP3 recordings audio. Other files are refused with
+// ErrUnsupportedFile.
+func DataPart(name string, data []byte) (ContentPart, error) {
+	if len(data) > maxFileBytes {
+		return ContentPart{}, fmt.Errorf("%w: %s is %d MB, more than the %d MB providers take",
+			ErrUnsupportedFile, name, len(data)>>20, maxFileBytes>>20)
+	}
+	mediaType := detectMediaType(name, data)
+	switch {
+	case mediaType == "image/png" || mediaType == "image/jpeg" || mediaType == "image/gif" || mediaType == "image/webp":
+		return imageFilePart(name, mediaType, data)
+	case mediaType == "application/pdf" || strings.HasPrefix(mediaType, "text/"):
+		return DocumentPart(name, mediaType, data), nil
+	case mediaType == "audio/wav" || mediaType == "audio/mpeg":
+		return AudioPart(mediaType, data), nil
+	}
+	return ContentPart{}, fmt.Errorf("%w: %s is %s", ErrUnsupportedFile, name, mediaType)
+}
+
+// fileTypes are the media types of extensions that Go's own table lacks
+// and systems may not define.
+var fileTypes = map[string]string{
+	".txt": "text/plain",
+	".md":  "text/markdown",
+	".csv": "text/csv",
+	".wav": "audio/wav",
+	".mp3": "audio/mpeg",
+}
+
+// detectMediaType returns the media type of a file, without parameters,
+// from its extension or else its first bytes.
+func detectMediaType(name string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	mediaType, ok := fileTypes[ext]
+	if !ok {
+		mediaType = mime.TypeByExtension(ext)
+	}
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	switch mediaType {
+	case "audio/x-wav", "audio/wave":
+		return "audio/wav"
+	case "audio/mp3":
+		return "audio/mpeg"
+	}
+	return mediaType
+}
+
+// imageFilePart returns an image part of data, scaled down to fit
+// maxImageEdge and maxImageBytes when it does not.
+func imageFilePart(name, mediaType string, data []byte) (ContentPart, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil && len(data) <= maxImageBytes {
+		// Formats the standard library cannot read, such as WebP, are sent
+		// as they are when small enough
+		return ImagePart(mediaType, data), nil
+	}
+	if err == nil && len(data) <= maxImageBytes && max(config.Width, config.Height) <= maxImageEdge {
+		return ImagePart(mediaType, data), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("%w: %s is too large to send and cannot be scaled down: %v", ErrUnsupportedFile, name, err)
+	}
+	img = downscale(img, maxImageEdge)
+
+	var buf bytes.Buffer
+	if mediaType == "image/png" {
+		if err := png.Encode(&buf, img); err == nil && buf.Len() <= maxImageBytes {
+			return ImagePart("image/png", buf.Bytes()), nil
+		}
+		buf.Reset()
+	}
+	// Photos and images PNG cannot fit become JPEGs
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return ContentPart{}, fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return ImagePart("image/jpeg", buf.Bytes()), nil
+}
+
+// downscale returns img scaled to fit edge pixels on its longer side,
+// averaging the pixels each one covers, or img when it already fits.
+func downscale(img image.Image, edge int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= edge && h <= edge {
+		return img
+	}
+	dw, dh := edge, h*edge/w
+	if h > w {
+		dw, dh = w*edge/h, edge
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	src := image.NewRGBA(b)
+	draw.Draw(src, b, img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(sx, sy)
+					r, g, bl, a = r+uint32(c.R), g+uint32(c.G), bl+uint32(c.B), a+uint32(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilePart(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("remember the milk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := FilePart(notes)
+	if err != nil {
+		t.Fatalf("FilePart() error: %v", err)
+	}
+	if part.Type != ContentDocument || part.Name != "notes.txt" || part.MediaType != "text/plain" {
+		t.Errorf("FilePart() = %+v, want a text document", part)
+	}
+
+	if _, err := FilePart(filepath.Join(dir, "missing.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("FilePart(missing) error = %v, want not exist", err)
+	}
+}
+
+func TestDataPart_DownscalesImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4096, 1024))); err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := DataPart("wide.png", buf.Bytes())
+	if err != nil {
+		t.Fatalf("DataPart() error: %v", err)
+	}
+	if part.Type != ContentImage || part.MediaType != "image/png" {
+		t.Fatalf("DataPart() = %s %s, want a PNG image", part.Type, part.MediaType)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(part.Data))
+	if err != nil {
+		t.Fatalf("decoding the scaled image: %v", err)
+	}
+	if config.Width != 2048 || config.Height != 512 {
+		t.Errorf("scaled to %dx%d, want 2048x512", config.Width, config.Height)
+	}
+}
+
+func TestDataPart_Unsupported(t *testing.T) {
+	if _, err := DataPart("archive.zip", []byte("PK\x03\x04")); !errors.Is(err, ErrUnsupportedFile) {
+		t.Errorf("DataPart(zip) error = %v, want ErrUnsupportedFile", err)
+	}
+	if _, err := DataPart("huge.pdf", make([]byte, maxFileBytes+1)); !errors.Is(err, ErrUnsupportedFile) {
+		t.Errorf("DataPart(huge) error = %v, want ErrUnsupportedFile", err)
+	}
+}
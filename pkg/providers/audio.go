@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Audio-capable chat models, such as gpt-4o-audio-preview, take audio parts
+// as input and, when OptionAudioVoice is set, answer with speech as well
+// as text.
+const (
+	// OptionAudioVoice (a string, such as "alloy") asks for a spoken reply
+	// in that voice.
+	OptionAudioVoice = "audio_voice"
+	// OptionAudioFormat (a string: "wav", "mp3", "flac", "opus" or
+	// "pcm16") is the format of the spoken reply: wav by default, and
+	// pcm16, the only one streamed, when streaming.
+	OptionAudioFormat = "audio_format"
+)
+
+// audioFormats maps the audio formats of the chat completions API to media
+// types.
+var audioFormats = map[string]string{
+	"wav":   "audio/wav",
+	"mp3":   "audio/mpeg",
+	"flac":  "audio/flac",
+	"opus":  "audio/opus",
+	"pcm16": "audio/pcm",
+}
+
+// openAIAudioFormat returns the API's name for the format of mediaType,
+// such as "wav" for audio/wav.
+func openAIAudioFormat(mediaType string) string {
+	for format, mt := range audioFormats {
+		if mt == mediaType {
+			return format
+		}
+	}
+	switch mediaType {
+	case "audio/x-wav", "audio/wave":
+		return "wav"
+	case "audio/mp3":
+		return "mp3"
+	}
+	return strings.TrimPrefix(mediaType, "audio/")
+}
+
+// applyAudioOutput asks for a spoken reply when options set a voice.
+// format is used when options set none.
+func applyAudioOutput(requestBody map[string]interface{}, options map[string]interface{}, format string) {
+	voice, _ := options[OptionAudioVoice].(string)
+	if voice == "" {
+		return
+	}
+	if f, ok := options[OptionAudioFormat].(string); ok && f != "" {
+		format = f
+	}
+	requestBody["modalities"] = []string{"text", "audio"}
+	requestBody["audio"] = map[string]interface{}{"voice": voice, "format": format}
+}
+
+// openAIAudio is the audio of a chat completions reply, or of a chunk of
+// a streamed one.
+type openAIAudio struct {
+	ID         string `json:"id"`
+	Data       string `json:"data"`
+	Transcript string `json:"transcript"`
+}
+
+// audioPart returns the spoken reply as an audio part with its transcript.
+// Its media type is set by setAudioMediaType.
+func (a openAIAudio) audioPart(data []byte) ContentPart {
+	part := AudioPart("", data)
+	part.Text = a.Transcript
+	part.ID = a.ID
+	return part
+}
+
+// setAudioMediaType sets the media type of the audio parts of resp to the
+// format requestBody asked for.
+func setAudioMediaType(resp *LLMResponse, requestBody map[string]interface{}) {
+	audio, _ := requestBody["audio"].(map[string]interface{})
+	format, _ := audio["format"].(string)
+	mediaType, ok := audioFormats[format]
+	if !ok {
+		mediaType = "audio/" + format
+	}
+	for i := range resp.Parts {
+		if resp.Parts[i].Type == ContentAudio {
+			resp.Parts[i].MediaType = mediaType
+		}
+	}
+}
+
+// decodeAudio decodes the base64 audio of a reply, ignoring data that is
+// not valid base64 rather than failing the whole response.
+func decodeAudio(data string) []byte {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
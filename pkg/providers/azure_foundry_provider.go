@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// azureFoundryAPIVersion is the version of the Azure AI model inference API
+// that serverless endpoints and Foundry's /models endpoint serve.
+const azureFoundryAPIVersion = "2024-05-01-preview"
+
+// newAzureFoundryProvider creates the provider for an Azure AI Foundry
+// serverless endpoint, such as https://<name>.<region>.models.ai.azure.com
+// or https://<resource>.services.ai.azure.com/models, which serve catalog
+// models like Llama, Mistral and Phi over chat completions. It is called
+// with the endpoint's key, or with a Microsoft Entra token from the same
+// managed identity token source as Azure OpenAI when auth_method is
+// "managed_identity".
+func newAzureFoundryProvider(pc config.ProviderConfig) (*HTTPProvider, error) {
+	if pc.APIBase == "" {
+		return nil, fmt.Errorf("azure_foundry needs the endpoint as api_base")
+	}
+	p := NewHTTPProvider(pc.APIKey, pc.APIBase, pc.Proxy)
+	p.apiVersion = azureFoundryAPIVersion
+	if pc.AuthMethod == "managed_identity" {
+		p.apiKey = ""
+		p.tokenSource = createAzureManagedIdentityTokenSource(&AzureConfig{
+			Scope:              azureFoundryScope(pc.APIBase),
+			UseManagedIdentity: true,
+		})
+	}
+	return p, nil
+}
+
+// azureFoundryScope returns the Entra scope of endpoint: Azure Machine
+// Learning for serverless endpoints, Cognitive Services for Foundry
+// resources.
+func azureFoundryScope(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && strings.HasSuffix(u.Hostname(), ".models.ai.azure.com") {
+		return "https://ml.azure.com/.default"
+	}
+	return "https://cognitiveservices.azure.com/.default"
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestAzureFoundryProvider_Key(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/chat/completions" || r.URL.Query().Get("api-version") != azureFoundryAPIVersion {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer foundry-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.AzureFoundry = config.ProviderConfig{APIKey: "foundry-key", APIBase: server.URL + "/models"}
+	provider, err := CreateProviderFor(cfg, "", "foundry/Phi-4")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	resp, err := provider.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "foundry/Phi-4", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "Hi" || body["model"] != "Phi-4" {
+		t.Errorf("response = %q, model = %v, want Hi from Phi-4", resp.Content, body["model"])
+	}
+}
+
+func TestAzureFoundryScope(t *testing.T) {
+	tests := map[string]string{
+		"https://llama-ep.eastus2.models.ai.azure.com":    "https://ml.azure.com/.default",
+		"https://my-hub.services.ai.azure.com/models":     "https://cognitiveservices.azure.com/.default",
+		"https://my-resource.cognitiveservices.azure.com": "https://cognitiveservices.azure.com/.default",
+	}
+	for endpoint, want := range tests {
+		if got := azureFoundryScope(endpoint); got != want {
+			t.Errorf("azureFoundryScope(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
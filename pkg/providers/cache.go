@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// CacheBackend stores cached responses by key. Implementations must be safe
+// for concurrent use.
+type CacheBackend interface {
+	// Get returns the value stored under key, with ok=false on a miss or
+	// once the entry has expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key for ttl; zero keeps it until evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// NewCacheBackend returns the backend cfg selects. The file backend
+// defaults to <workspace>/cache/responses.
+func NewCacheBackend(cfg config.CacheConfig, workspace string) (CacheBackend, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", "memory":
+		return NewMemoryCache(cfg.MaxEntries), nil
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = filepath.Join(workspace, "cache", "responses")
+		}
+		return NewFileCache(dir, cfg.MaxEntries)
+	case "redis":
+		return NewRedisCache(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q (want memory, file or redis)", cfg.Backend)
+	}
+}
+
+// CachingProvider answers repeated requests from a cache. Requests match
+// when the provider, model, messages, tools and options are the same;
+// message text is compared with line endings and surrounding whitespace
+// normalized. Cached responses carry no usage, since they cost nothing, and
+// no request metadata or timing, since no request was made.
+type CachingProvider struct {
+	provider LLMProvider
+	name     string
+	backend  CacheBackend
+	ttl      time.Duration
+}
+
+// NewCachingProvider wraps provider, which name identifies in cache keys.
+func NewCachingProvider(provider LLMProvider, name string, backend CacheBackend, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{provider: provider, name: name, backend: backend, ttl: ttl}
+}
+
+// Unwrap returns the provider behind the cache.
+func (p *CachingProvider) Unwrap() LLMProvider {
+	return p.provider
+}
+
+func (p *CachingProvider) GetDefaultModel() string {
+	return p.provider.GetDefaultModel()
+}
+
+func (p *CachingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	key := p.key(messages, tools, model, options)
+	if resp, ok := p.lookup(ctx, key); ok {
+		return resp, nil
+	}
+
+	resp, err := p.provider.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	p.store(ctx, key, resp)
+	return resp, nil
+}
+
+// ChatStream replays a cached response in a few events, or streams from
+// the provider and caches the final response.
+func (p *CachingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	key := p.key(messages, tools, model, options)
+	if resp, ok := p.lookup(ctx, key); ok {
+		return replayResponse(resp), nil
+	}
+
+	upstream, err := ChatStream(ctx, p.provider, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		for ev := range upstream {
+			if ev.Type == StreamEventDone && ev.Response != nil {
+				p.store(ctx, key, ev.Response)
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				// Drain so the provider's goroutine can finish
+				for range upstream {
+				}
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (p *CachingProvider) lookup(ctx context.Context, key string) (*LLMResponse, bool) {
+	data, ok, err := p.backend.Get(ctx, key)
+	if err != nil {
+		logger.WarnCF("cache", "Response cache read failed",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var resp LLMResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	resp.Usage = nil
+	resp.Metadata = nil
+	resp.Timing = nil
+	logger.DebugCF("cache", "Response cache hit", map[string]interface{}{"key": key[:12]})
+	return &resp, true
+}
+
+func (p *CachingProvider) store(ctx context.Context, key string, resp *LLMResponse) {
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = p.backend.Set(ctx, key, data, p.ttl)
+	}
+	if err != nil {
+		logger.WarnCF("cache", "Response cache write failed",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+	}
+}
+
+// key hashes everything that determines a response.
+func (p *CachingProvider) key(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) string {
+	normalized := make([]Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = strings.TrimSpace(strings.ReplaceAll(msg.Content, "\r\n", "\n"))
+		// Not sent to the model
+		msg.CreatedAt, msg.Metadata = time.Time{}, nil
+		normalized[i] = msg
+	}
+	// encoding/json sorts map keys, so equal options encode identically
+	data, _ := json.Marshal(struct {
+		Provider string                 `json:"provider"`
+		Model    string                 `json:"model"`
+		Messages []Message              `json:"messages"`
+		Tools    []ToolDefinition       `json:"tools,omitempty"`
+		Options  map[string]interface{} `json:"options,omitempty"`
+	}{p.name, model, normalized, tools, options})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryCache is an in-process least-recently-used cache.
+type MemoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache holds up to maxEntries values; 0 means no limit.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet
+// evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileCache keeps one JSON file per entry in a directory, so cached
+// responses survive restarts and can be shared by several processes. When
+// there are more than maxEntries files, the least recently used are
+// removed.
+type FileCache struct {
+	dir        string
+	maxEntries int
+
+	mu sync.Mutex
+}
+
+type fileEntry struct {
+	Expires time.Time       `json:"expires,omitempty"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// NewFileCache stores entries in dir; maxEntries 0 means no limit.
+func NewFileCache(dir string, maxEntries int) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir, maxEntries: maxEntries}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *FileCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry fileEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	// The modification time orders entries for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry.Value, true, nil
+}
+
+func (c *FileCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := fileEntry{Value: value}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used entries beyond maxEntries.
+func (c *FileCache) evict() error {
+	if c.maxEntries <= 0 {
+		return nil
+	}
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		path string
+		used time.Time
+	}
+	var entries []entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{filepath.Join(c.dir, f.Name()), info.ModTime()})
+	}
+	if len(entries) <= c.maxEntries {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries[:len(entries)-c.maxEntries] {
+		os.Remove(e.path)
+	}
+	return nil
+}
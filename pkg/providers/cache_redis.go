@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisKeyPrefix namespaces cache entries in a shared Redis database.
+const redisKeyPrefix = "picoclaw:cache:"
+
+// redisTimeout bounds each command when the context has no deadline.
+const redisTimeout = 5 * time.Second
+
+// errRedisNil is the reply to GET for a missing key.
+var errRedisNil = errors.New("redis: nil")
+
+// RedisCache stores entries in Redis, so several gateways can share one
+// cache. It speaks the RESP protocol directly over a single connection.
+type RedisCache struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisCache connects lazily to rawURL, in the form
+// redis://[[user]:password@]host[:port][/db]; rediss:// uses TLS.
+func NewRedisCache(rawURL string) (*RedisCache, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("redis cache requires redis_url")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis_url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid redis_url scheme %q (want redis or rediss)", u.Scheme)
+	}
+
+	c := &RedisCache{addr: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", redisKeyPrefix+key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", redisKeyPrefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// Close drops the connection.
+func (c *RedisCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *RedisCache) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.r = nil, nil
+	return err
+}
+
+// do sends one command and reads its reply. A connection that fails is
+// dropped and redialed by the next command.
+func (c *RedisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if c.conn == nil {
+		if err := c.connectLocked(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(deadline, args)
+	var redisErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &redisErr) {
+		c.closeLocked()
+	}
+	return reply, err
+}
+
+func (c *RedisCache) connectLocked(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		host, _, _ := net.SplitHostPort(c.addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to connect to %s: %w", c.addr, err)
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := c.roundTrip(deadline, auth); err != nil {
+			c.closeLocked()
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip(deadline, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			c.closeLocked()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisCache) roundTrip(deadline time.Time, args []string) (interface{}, error) {
+	c.conn.SetDeadline(deadline)
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, cmd.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return readRESP(c.r)
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readRESP reads one reply: simple strings and bulk strings as []byte,
+// integers as int64, arrays as []interface{}.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readRESP(r)
+			if err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,252 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	return &LLMResponse{
+		Content:      "answer",
+		FinishReason: "stop",
+		Usage:        &UsageInfo{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}, nil
+}
+
+func (p *countingProvider) GetDefaultModel() string {
+	return "test"
+}
+
+func TestCachingProvider_Chat(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewCachingProvider(inner, "test", NewMemoryCache(10), time.Hour)
+	ctx := context.Background()
+	opts := map[string]interface{}{"temperature": 0.0, "max_tokens": 100}
+
+	first, err := p.Chat(ctx, []Message{{Role: "user", Content: "hello"}}, nil, "m", opts)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if first.Usage == nil {
+		t.Error("first response has no usage, want the provider's")
+	}
+
+	// Whitespace and line endings do not change the key
+	second, err := p.Chat(ctx, []Message{{Role: "user", Content: " hello\r\n"}}, nil, "m",
+		map[string]interface{}{"max_tokens": 100, "temperature": 0.0})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", inner.calls)
+	}
+	if second.Content != "answer" || second.Usage != nil {
+		t.Errorf("cached response = %+v, want the answer without usage", second)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		model string
+		opts  map[string]interface{}
+		tools []ToolDefinition
+	}{
+		{"model", "other", opts, nil},
+		{"options", "m", map[string]interface{}{"temperature": 1.0}, nil},
+		{"tools", "m", opts, []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "t"}}}},
+	} {
+		calls := inner.calls
+		if _, err := p.Chat(ctx, []Message{{Role: "user", Content: "hello"}}, tc.tools, tc.model, tc.opts); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+		if inner.calls != calls+1 {
+			t.Errorf("changing %s: provider not called, want a cache miss", tc.name)
+		}
+	}
+}
+
+func TestCachingProvider_ChatStream(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewCachingProvider(inner, "test", NewMemoryCache(10), 0)
+	messages := []Message{{Role: "user", Content: "hi"}}
+
+	for i := 0; i < 2; i++ {
+		events, err := p.ChatStream(context.Background(), messages, nil, "m", nil)
+		if err != nil {
+			t.Fatalf("ChatStream() error = %v", err)
+		}
+		var text strings.Builder
+		resp, err := CollectStream(events, func(s string) { text.WriteString(s) })
+		if err != nil {
+			t.Fatalf("CollectStream() error = %v", err)
+		}
+		if text.String() != "answer" || resp.Content != "answer" {
+			t.Errorf("stream %d: text %q, response %q; want answer", i, text.String(), resp.Content)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", inner.calls)
+	}
+}
+
+func TestMemoryCache_EvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	c.Get(ctx, "a") // a is now more recently used than b
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b still cached, want it evicted as least recently used")
+	}
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Error("a evicted, want it kept")
+	}
+
+	c.Set(ctx, "short", []byte("x"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := NewFileCache(dir, 2)
+	if err != nil {
+		t.Fatalf("NewFileCache() error = %v", err)
+	}
+	c.Set(ctx, "a", []byte(`{"content":"1"}`), 0)
+	time.Sleep(10 * time.Millisecond)
+	c.Set(ctx, "b", []byte(`{"content":"2"}`), 0)
+	time.Sleep(10 * time.Millisecond)
+	c.Set(ctx, "c", []byte(`{"content":"3"}`), 0)
+
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("a still cached, want it evicted")
+	}
+
+	// Entries persist across instances
+	reopened, _ := NewFileCache(dir, 2)
+	value, ok, err := reopened.Get(ctx, "c")
+	if err != nil || !ok || string(value) != `{"content":"3"}` {
+		t.Errorf("Get(c) = %s, %v, %v; want the stored value", value, ok, err)
+	}
+
+	c.Set(ctx, "short", []byte(`{}`), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+// fakeRedis serves GET, SET (with PX), AUTH and SELECT from a map.
+func fakeRedis(t *testing.T, password string) (addr string, commands func() []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	data := map[string]string{}
+	var seen []string
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					reply, err := readRESP(r)
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, a := range reply.([]interface{}) {
+						args = append(args, string(a.([]byte)))
+					}
+					mu.Lock()
+					seen = append(seen, strings.Join(args, " "))
+					switch strings.ToUpper(args[0]) {
+					case "AUTH":
+						if args[len(args)-1] == password {
+							conn.Write([]byte("+OK\r\n"))
+						} else {
+							conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+						}
+					case "SELECT":
+						conn.Write([]byte("+OK\r\n"))
+					case "SET":
+						data[args[1]] = args[2]
+						conn.Write([]byte("+OK\r\n"))
+					case "GET":
+						if v, ok := data[args[1]]; ok {
+							conn.Write([]byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"))
+						} else {
+							conn.Write([]byte("$-1\r\n"))
+						}
+					case "INCR":
+						n, _ := strconv.Atoi(data[args[1]])
+						data[args[1]] = strconv.Itoa(n + 1)
+						conn.Write([]byte(":" + data[args[1]] + "\r\n"))
+					case "PEXPIRE":
+						conn.Write([]byte(":1\r\n"))
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	addr, commands := fakeRedis(t, "secret")
+	c, err := NewRedisCache("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatalf("NewRedisCache() error = %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, ok, err := c.Get(ctx, "k"); ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v; want a miss", ok, err)
+	}
+	if err := c.Set(ctx, "k", []byte("v"), 1500*time.Millisecond); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	value, ok, err := c.Get(ctx, "k")
+	if err != nil || !ok || string(value) != "v" {
+		t.Errorf("Get(k) = %q, %v, %v; want v", value, ok, err)
+	}
+
+	want := []string{"AUTH secret", "SELECT 2", "GET picoclaw:cache:k", "SET picoclaw:cache:k v PX 1500", "GET picoclaw:cache:k"}
+	if got := commands(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+
+	bad, _ := NewRedisCache("redis://:wrong@" + addr)
+	if _, _, err := bad.Get(ctx, "k"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Get() with a wrong password: error = %v, want WRONGPASS", err)
+	}
+}
@@ -0,0 +1,149 @@
+package providers
+
+import "strings"
+
+// Capabilities is what a provider supports for a model, so callers can
+// leave out what it cannot take, such as images for a model that cannot
+// see. MaxContext and MaxOutput are in tokens and zero when unknown.
+type Capabilities struct {
+	Tools      bool `json:"tools"`
+	Vision     bool `json:"vision"`
+	Streaming  bool `json:"streaming"`
+	JSONMode   bool `json:"json_mode"`
+	Caching    bool `json:"caching"` // the vendor caches prompt prefixes
+	MaxContext int  `json:"max_context,omitempty"`
+	MaxOutput  int  `json:"max_output,omitempty"`
+}
+
+// CapabilityReporter is implemented by providers that know what they
+// support.
+type CapabilityReporter interface {
+	Capabilities(model string) Capabilities
+}
+
+// CapabilitiesOf returns what provider supports for model, or its default
+// model when model is empty. Wrappers such as the cache are looked
+// through. Providers that do not report their capabilities are described
+// by the model catalog.
+func CapabilitiesOf(provider LLMProvider, model string) Capabilities {
+	if model == "" && provider != nil {
+		model = provider.GetDefaultModel()
+	}
+	for p := provider; p != nil; {
+		if r, ok := p.(CapabilityReporter); ok {
+			return r.Capabilities(model)
+		}
+		w, ok := p.(interface{ Unwrap() LLMProvider })
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	caps := modelCapabilities(model)
+	_, caps.Streaming = provider.(StreamingProvider)
+	return caps
+}
+
+// modelCapabilities describes model from the model catalog. Tools and
+// vision are assumed for models missing from it, so they are not taken
+// away from a model only because it is unknown.
+func modelCapabilities(model string) Capabilities {
+	entry, ok := LookupModel(model)
+	if !ok {
+		return Capabilities{Tools: true, Vision: true}
+	}
+	caps := Capabilities{MaxContext: entry.ContextWindow, MaxOutput: entry.MaxOutputTokens}
+	for _, c := range entry.Capabilities {
+		switch c {
+		case CapabilityTools:
+			caps.Tools = true
+		case CapabilityVision:
+			caps.Vision = true
+		}
+	}
+	return caps
+}
+
+// promptCachingHosts are OpenAI-compatible APIs that cache prompt prefixes
+// without being asked to.
+var promptCachingHosts = []string{"api.openai.com", "api.deepseek.com", "openrouter.ai"}
+
+func (p *HTTPProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	if _, refused := p.refusedTools.Load(model); refused {
+		caps.Tools = false
+	}
+	caps.Streaming = true
+	caps.JSONMode = true
+	for _, host := range promptCachingHosts {
+		if strings.Contains(p.apiBase, host) {
+			caps.Caching = true
+		}
+	}
+	return caps
+}
+
+func (p *ClaudeProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	caps.Streaming = true
+	caps.Caching = true
+	return caps
+}
+
+func (p *CodexProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	caps.Streaming = true
+	caps.JSONMode = true
+	caps.Caching = true
+	return caps
+}
+
+func (p *OpenAIProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	caps.Streaming = true
+	caps.JSONMode = true
+	caps.Caching = true
+	return caps
+}
+
+// Capabilities reports what the CLI takes: text, with tools described in
+// the system prompt.
+func (p *ClaudeCliProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	caps.Vision = false
+	caps.Tools = true
+	return caps
+}
+
+// Capabilities reports that Copilot sessions are sent the conversation as
+// text only.
+func (p *GitHubCopilotProvider) Capabilities(model string) Capabilities {
+	caps := modelCapabilities(model)
+	caps.Tools, caps.Vision = false, false
+	return caps
+}
+
+// AdaptMessages returns messages without the parts caps cannot take:
+// images for a model that cannot see are replaced with a note saying so.
+// messages is returned as is when nothing needs to change.
+func AdaptMessages(messages []Message, caps Capabilities) []Message {
+	if caps.Vision || !HasImages(messages) {
+		return messages
+	}
+	out := make([]Message, len(messages))
+	for i, msg := range messages {
+		out[i] = msg
+		if len(msg.Parts) == 0 {
+			continue
+		}
+		parts := make([]ContentPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			if part.Type == ContentImage {
+				part = TextPart("[image omitted: the model cannot view images]")
+			}
+			parts = append(parts, part)
+		}
+		out[i].Parts = parts
+	}
+	return out
+}
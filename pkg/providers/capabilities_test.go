@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	http := NewHTTPProvider("key", "https://api.openai.com/v1", "")
+	wrapped := NewCachingProvider(NewScheduledProvider(http, NewScheduler(1)), "openai", NewMemoryCache(10), time.Minute)
+
+	caps := CapabilitiesOf(wrapped, "gpt-4o")
+	want := Capabilities{Tools: true, Vision: true, Streaming: true, JSONMode: true, Caching: true, MaxContext: 128000, MaxOutput: 16384}
+	if caps != want {
+		t.Errorf("CapabilitiesOf(gpt-4o) = %+v, want %+v", caps, want)
+	}
+	if caps := CapabilitiesOf(http, "gpt-4"); caps.Vision {
+		t.Errorf("CapabilitiesOf(gpt-4) = %+v, want no vision", caps)
+	}
+
+	// Providers that do not report are described by the model table
+	caps = CapabilitiesOf(&staticProvider{}, "deepseek-reasoner")
+	if caps.Tools || caps.Streaming || caps.MaxContext != 128000 {
+		t.Errorf("CapabilitiesOf(deepseek-reasoner) = %+v", caps)
+	}
+	if caps := CapabilitiesOf(&staticProvider{}, "my-local-model"); !caps.Tools || !caps.Vision {
+		t.Errorf("CapabilitiesOf(unknown) = %+v, want tools and vision assumed", caps)
+	}
+}
+
+func TestAdaptMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "be brief"},
+		NewMessage("user", TextPart("What is this?"), ImagePart("image/png", []byte("png"))),
+	}
+	if got := AdaptMessages(messages, Capabilities{Vision: true}); &got[0] != &messages[0] {
+		t.Error("AdaptMessages() copied messages a vision model can take")
+	}
+
+	got := AdaptMessages(messages, Capabilities{})
+	if part := got[1].Parts[1]; part.Type != ContentText {
+		t.Errorf("image part = %+v, want a text note", part)
+	}
+	if messages[1].Parts[1].Type != ContentImage {
+		t.Error("AdaptMessages() modified its input")
+	}
+}
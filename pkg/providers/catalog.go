@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ModelEntry is what the model catalog knows about a model family. Zero
+// values are unknown; see config.ModelCatalogEntry for the fields.
+type ModelEntry struct {
+	Prefix          string   `json:"prefix"`
+	ContextWindow   int      `json:"context_window,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+	FixedSampling   bool     `json:"fixed_sampling,omitempty"`
+	LegacyMaxTokens bool     `json:"legacy_max_tokens,omitempty"`
+	Temperature     float64  `json:"temperature,omitempty"`
+	InputPrice      float64  `json:"input_price,omitempty"`
+	OutputPrice     float64  `json:"output_price,omitempty"`
+	Deprecated      string   `json:"deprecated,omitempty"`
+}
+
+var (
+	capsChat      = []string{CapabilityTools}
+	capsVision    = []string{CapabilityTools, CapabilityVision}
+	capsReasoning = []string{CapabilityTools, CapabilityVision, CapabilityReasoning}
+)
+
+// builtinModels is the catalog shipped with picoclaw. Prices are the
+// vendors' list prices for standard, uncached requests.
+var builtinModels = []ModelEntry{
+	{Prefix: "claude-opus-4", ContextWindow: 200000, MaxOutputTokens: 32000, Capabilities: capsReasoning, InputPrice: 15, OutputPrice: 75},
+	{Prefix: "claude-opus-4-5", ContextWindow: 200000, MaxOutputTokens: 64000, Capabilities: capsReasoning, InputPrice: 5, OutputPrice: 25},
+	{Prefix: "claude-sonnet-4", ContextWindow: 200000, MaxOutputTokens: 64000, Capabilities: capsReasoning, InputPrice: 3, OutputPrice: 15},
+	{Prefix: "claude-haiku-4", ContextWindow: 200000, MaxOutputTokens: 64000, Capabilities: capsReasoning, InputPrice: 1, OutputPrice: 5},
+	{Prefix: "claude-3-7-sonnet", ContextWindow: 200000, MaxOutputTokens: 64000, Capabilities: capsReasoning, InputPrice: 3, OutputPrice: 15},
+	{Prefix: "claude-3-5", ContextWindow: 200000, MaxOutputTokens: 8192, Capabilities: capsVision, InputPrice: 3, OutputPrice: 15, Deprecated: "2025-10-22"},
+	{Prefix: "claude-3", ContextWindow: 200000, MaxOutputTokens: 4096, Capabilities: capsVision},
+	{Prefix: "gpt-5", ContextWindow: 400000, MaxOutputTokens: 128000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 1.25, OutputPrice: 10},
+	{Prefix: "gpt-5-mini", ContextWindow: 400000, MaxOutputTokens: 128000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 0.25, OutputPrice: 2},
+	{Prefix: "gpt-5-nano", ContextWindow: 400000, MaxOutputTokens: 128000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 0.05, OutputPrice: 0.4},
+	{Prefix: "gpt-4.1", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: capsVision, InputPrice: 2, OutputPrice: 8},
+	{Prefix: "gpt-4.1-mini", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: capsVision, InputPrice: 0.4, OutputPrice: 1.6},
+	{Prefix: "gpt-4.1-nano", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: capsVision, InputPrice: 0.1, OutputPrice: 0.4},
+	{Prefix: "gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: capsVision, InputPrice: 2.5, OutputPrice: 10},
+	{Prefix: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: capsVision, InputPrice: 0.15, OutputPrice: 0.6},
+	{Prefix: "gpt-4o-audio", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: []string{CapabilityTools, CapabilityAudio}, InputPrice: 2.5, OutputPrice: 10},
+	{Prefix: "gpt-4o-mini-audio", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: []string{CapabilityTools, CapabilityAudio}, InputPrice: 0.15, OutputPrice: 0.6},
+	{Prefix: "gpt-4-turbo", ContextWindow: 128000, MaxOutputTokens: 4096, Capabilities: capsVision, LegacyMaxTokens: true, InputPrice: 10, OutputPrice: 30},
+	{Prefix: "gpt-4", ContextWindow: 8192, MaxOutputTokens: 8192, Capabilities: capsChat, LegacyMaxTokens: true, InputPrice: 30, OutputPrice: 60},
+	{Prefix: "gpt-3.5-turbo", ContextWindow: 16385, MaxOutputTokens: 4096, Capabilities: capsChat, LegacyMaxTokens: true, InputPrice: 0.5, OutputPrice: 1.5},
+	{Prefix: "gpt-35-turbo", ContextWindow: 16385, MaxOutputTokens: 4096, Capabilities: capsChat, LegacyMaxTokens: true},
+	{Prefix: "o1", ContextWindow: 200000, MaxOutputTokens: 100000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 15, OutputPrice: 60},
+	{Prefix: "o3", ContextWindow: 200000, MaxOutputTokens: 100000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 2, OutputPrice: 8},
+	{Prefix: "o4", ContextWindow: 200000, MaxOutputTokens: 100000, Capabilities: capsReasoning, FixedSampling: true, InputPrice: 1.1, OutputPrice: 4.4},
+	{Prefix: "text-embedding", ContextWindow: 8191, Capabilities: []string{CapabilityEmbedding}},
+	{Prefix: "gemini-2.5", ContextWindow: 1048576, MaxOutputTokens: 65536, Capabilities: capsReasoning},
+	{Prefix: "gemini-2.5-pro", ContextWindow: 1048576, MaxOutputTokens: 65536, Capabilities: capsReasoning, InputPrice: 1.25, OutputPrice: 10},
+	{Prefix: "gemini-2.5-flash", ContextWindow: 1048576, MaxOutputTokens: 65536, Capabilities: capsReasoning, InputPrice: 0.3, OutputPrice: 2.5},
+	{Prefix: "gemini-2.0", ContextWindow: 1048576, MaxOutputTokens: 8192, Capabilities: capsVision, InputPrice: 0.1, OutputPrice: 0.4},
+	{Prefix: "gemini-1.5-pro", ContextWindow: 2097152, MaxOutputTokens: 8192, Capabilities: capsVision, Deprecated: "2025-09-24"},
+	{Prefix: "gemini-1.5", ContextWindow: 1048576, MaxOutputTokens: 8192, Capabilities: capsVision, Deprecated: "2025-09-24"},
+	{Prefix: "deepseek-chat", ContextWindow: 128000, MaxOutputTokens: 8192, Capabilities: capsChat, InputPrice: 0.28, OutputPrice: 0.42},
+	{Prefix: "deepseek-reasoner", ContextWindow: 128000, MaxOutputTokens: 65536, Capabilities: []string{CapabilityReasoning}, InputPrice: 0.28, OutputPrice: 0.42},
+	{Prefix: "command-a", ContextWindow: 256000, MaxOutputTokens: 8192, Capabilities: capsChat, InputPrice: 2.5, OutputPrice: 10},
+	{Prefix: "command-r", ContextWindow: 128000, MaxOutputTokens: 4096, Capabilities: capsChat, InputPrice: 0.15, OutputPrice: 0.6},
+	{Prefix: "command-r-plus", ContextWindow: 128000, MaxOutputTokens: 4096, Capabilities: capsChat, InputPrice: 2.5, OutputPrice: 10},
+	{Prefix: "sonar", ContextWindow: 127072, InputPrice: 1, OutputPrice: 1},
+	{Prefix: "sonar-pro", ContextWindow: 200000, MaxOutputTokens: 8000, Capabilities: []string{CapabilityVision}, InputPrice: 3, OutputPrice: 15},
+	{Prefix: "sonar-reasoning", ContextWindow: 127072, Capabilities: []string{CapabilityReasoning}, InputPrice: 1, OutputPrice: 5},
+	{Prefix: "sonar-reasoning-pro", ContextWindow: 127072, Capabilities: []string{CapabilityReasoning}, InputPrice: 2, OutputPrice: 8},
+	{Prefix: "sonar-deep-research", ContextWindow: 127072, Capabilities: []string{CapabilityReasoning}, InputPrice: 2, OutputPrice: 8},
+	{Prefix: "glm-4", ContextWindow: 128000, Capabilities: capsChat},
+	{Prefix: "kimi-k2", ContextWindow: 131072, Capabilities: capsChat, Temperature: 1},
+	{Prefix: "moonshot-v1-128k", ContextWindow: 131072, Capabilities: capsChat},
+	{Prefix: "moonshot-v1-32k", ContextWindow: 32768, Capabilities: capsChat},
+	{Prefix: "moonshot-v1-8k", ContextWindow: 8192, Capabilities: capsChat},
+	{Prefix: "qwen-max", ContextWindow: 32768, MaxOutputTokens: 8192, Capabilities: capsChat},
+	{Prefix: "qwen-plus", ContextWindow: 131072, MaxOutputTokens: 16384, Capabilities: capsChat},
+	{Prefix: "qwen-turbo", ContextWindow: 1000000, MaxOutputTokens: 16384, Capabilities: capsChat},
+	{Prefix: "qwen-vl", ContextWindow: 131072, MaxOutputTokens: 8192, Capabilities: capsVision},
+	{Prefix: "qwen3", ContextWindow: 131072, MaxOutputTokens: 16384, Capabilities: []string{CapabilityTools, CapabilityReasoning}},
+	{Prefix: "qwq", ContextWindow: 131072, MaxOutputTokens: 8192, Capabilities: []string{CapabilityTools, CapabilityReasoning}},
+	{Prefix: "llama-3.1", ContextWindow: 131072, Capabilities: capsChat},
+	{Prefix: "llama-3.3", ContextWindow: 131072, Capabilities: capsChat},
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = builtinModels
+
+	// deprecationWarnings remembers the models already warned about.
+	deprecationWarnings sync.Map
+)
+
+// ConfigureCatalog applies the model_catalog entries of cfg on top of the
+// built-in catalog. CreateProvider calls it, so callers only need to when
+// they look models up without creating a provider.
+func ConfigureCatalog(cfg *config.Config) {
+	entries := append([]ModelEntry(nil), builtinModels...)
+	for _, override := range cfg.ModelCatalog {
+		prefix := strings.ToLower(override.Prefix)
+		i := 0
+		for i < len(entries) && entries[i].Prefix != prefix {
+			i++
+		}
+		if i == len(entries) {
+			entries = append(entries, ModelEntry{Prefix: prefix})
+		}
+		entry := &entries[i]
+		if override.ContextWindow > 0 {
+			entry.ContextWindow = override.ContextWindow
+		}
+		if override.MaxOutputTokens > 0 {
+			entry.MaxOutputTokens = override.MaxOutputTokens
+		}
+		if override.Capabilities != nil {
+			entry.Capabilities = override.Capabilities
+		}
+		if override.FixedSampling != nil {
+			entry.FixedSampling = *override.FixedSampling
+		}
+		if override.LegacyMaxTokens != nil {
+			entry.LegacyMaxTokens = *override.LegacyMaxTokens
+		}
+		if override.Temperature != nil {
+			entry.Temperature = *override.Temperature
+		}
+		if override.InputPrice > 0 {
+			entry.InputPrice = override.InputPrice
+		}
+		if override.OutputPrice > 0 {
+			entry.OutputPrice = override.OutputPrice
+		}
+		if override.Deprecated != "" {
+			entry.Deprecated = override.Deprecated
+		}
+	}
+
+	catalogMu.Lock()
+	catalog = entries
+	catalogMu.Unlock()
+}
+
+// LookupModel returns the catalog entry with the longest prefix of the
+// lowercased model name, without any "vendor/" prefix.
+func LookupModel(model string) (ModelEntry, bool) {
+	name := strings.ToLower(model)
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	var best ModelEntry
+	for _, entry := range catalog {
+		if strings.HasPrefix(name, entry.Prefix) && len(entry.Prefix) > len(best.Prefix) {
+			best = entry
+		}
+	}
+	return best, best.Prefix != ""
+}
+
+// Cost estimates the price in USD of usage on model. ok is false when the
+// catalog has no prices for the model.
+func Cost(model string, usage UsageInfo) (cost float64, ok bool) {
+	entry, _ := LookupModel(model)
+	if entry.InputPrice == 0 && entry.OutputPrice == 0 {
+		return 0, false
+	}
+	cost = float64(usage.PromptTokens)*entry.InputPrice + float64(usage.CompletionTokens)*entry.OutputPrice
+	return cost / 1e6, true
+}
+
+// warnIfDeprecated logs once per model when the vendor has retired it, or
+// will within a month.
+func warnIfDeprecated(model string) {
+	entry, _ := LookupModel(model)
+	if entry.Deprecated == "" {
+		return
+	}
+	retires, err := time.Parse("2006-01-02", entry.Deprecated)
+	if err != nil || time.Until(retires) > 30*24*time.Hour {
+		return
+	}
+	if _, seen := deprecationWarnings.LoadOrStore(model, struct{}{}); seen {
+		return
+	}
+	logger.WarnCF("provider", "Model is deprecated",
+		map[string]interface{}{
+			"model":      model,
+			"retirement": entry.Deprecated,
+		})
+}
+
+// minDefaultMaxTokens is the least defaultMaxTokens asks for, so a nearly
+// full context still leaves room for a short answer.
+const minDefaultMaxTokens = 1024
+
+// defaultMaxTokens returns the max_tokens to request from model when the
+// caller sets none: its maximum output from the catalog, or fallback when
+// that is unknown, limited to what the context window leaves after
+// messages, as estimated by EstimatePromptTokens.
+func defaultMaxTokens(model string, messages []Message, fallback int) int {
+	entry, _ := LookupModel(model)
+	maxTokens := entry.MaxOutputTokens
+	if maxTokens == 0 {
+		maxTokens = fallback
+	}
+	if entry.ContextWindow > 0 {
+		remaining := entry.ContextWindow - EstimatePromptTokens(messages)
+		maxTokens = min(maxTokens, max(remaining, minDefaultMaxTokens))
+	}
+	return maxTokens
+}
+
+// mediaTokens is roughly what a large image costs the common providers;
+// it is also the least a document or audio clip is counted as.
+const mediaTokens = 1600
+
+// EstimatePromptTokens estimates the tokens of messages, for when the
+// provider reports none. Text, tool calls and text documents count three
+// characters to the token, images mediaTokens each, and other documents
+// and audio one token per 32 bytes of data.
+func EstimatePromptTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		for _, part := range m.ContentParts() {
+			switch part.Type {
+			case ContentImage:
+				total += mediaTokens
+			case ContentDocument, ContentAudio:
+				if strings.HasPrefix(part.MediaType, "text/") {
+					total += utf8.RuneCount(part.Data) / 3
+				} else {
+					total += max(len(part.Data)/32, mediaTokens)
+				}
+				total += utf8.RuneCountInString(part.Text) / 3
+			case ContentToolUse:
+				if part.ToolCall != nil {
+					args, _ := json.Marshal(part.ToolCall.Arguments)
+					total += (len(part.ToolCall.Name) + len(args)) / 3
+				}
+			default:
+				total += utf8.RuneCountInString(part.Text) / 3
+			}
+		}
+	}
+	return total
+}
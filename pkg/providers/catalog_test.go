@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestConfigureCatalog(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fixed := true
+	cfg.ModelCatalog = []config.ModelCatalogEntry{
+		{Prefix: "gpt-4o", InputPrice: 2},
+		{Prefix: "My-Local", ContextWindow: 32768, FixedSampling: &fixed},
+	}
+	ConfigureCatalog(cfg)
+	t.Cleanup(func() { ConfigureCatalog(config.DefaultConfig()) })
+
+	entry, ok := LookupModel("openai/gpt-4o-2024-08-06")
+	if !ok || entry.InputPrice != 2 || entry.OutputPrice != 10 || entry.ContextWindow != 128000 {
+		t.Errorf("LookupModel(gpt-4o) = %+v, want the price overridden and the rest kept", entry)
+	}
+	entry, ok = LookupModel("my-local-13b")
+	if !ok || entry.ContextWindow != 32768 {
+		t.Errorf("LookupModel(my-local-13b) = %+v, %v, want the added entry", entry, ok)
+	}
+	if support := lookupModelParams("my-local-13b"); support.temperature || !support.maxCompletionTokens {
+		t.Errorf("lookupModelParams(my-local-13b) = %+v, want fixed sampling", support)
+	}
+
+	ConfigureCatalog(config.DefaultConfig())
+	if _, ok := LookupModel("my-local-13b"); ok {
+		t.Error("a removed override stayed in the catalog")
+	}
+}
+
+func TestCost(t *testing.T) {
+	cost, ok := Cost("claude-sonnet-4-5", UsageInfo{PromptTokens: 1000000, CompletionTokens: 100000})
+	if !ok || math.Abs(cost-4.5) > 1e-9 {
+		t.Errorf("Cost() = %v, %v, want $4.50", cost, ok)
+	}
+	if _, ok := Cost("llama-3.1-8b", UsageInfo{PromptTokens: 10}); ok {
+		t.Error("Cost() of a model without prices reported one")
+	}
+}
+
+func TestDefaultMaxTokens(t *testing.T) {
+	short := []Message{{Role: "user", Content: "Write a parser"}}
+	long := []Message{{Role: "user", Content: strings.Repeat("x", 3*150000)}}
+	// Three dozen screenshots and a 4 MB PDF, with hardly any text
+	var parts []ContentPart
+	for i := 0; i < 36; i++ {
+		parts = append(parts, ImagePart("image/png", []byte("png")))
+	}
+	parts = append(parts, DocumentPart("spec.pdf", "application/pdf", make([]byte, 4<<20)))
+	media := []Message{NewMessage("user", append(parts, TextPart("Review these"))...)}
+
+	tests := []struct {
+		model    string
+		messages []Message
+		want     int
+	}{
+		{"claude-sonnet-4-5", short, 64000},
+		{"claude-sonnet-4-5", long, 50000},
+		{"claude-3-haiku", short, 4096},
+		{"gpt-4", long, minDefaultMaxTokens},
+		{"claude-sonnet-4-5", media, 200000 - 36*mediaTokens - (4<<20)/32 - len("Review these")/3},
+		{"my-local-13b", short, 4096},
+	}
+	for _, tt := range tests {
+		if got := defaultMaxTokens(tt.model, tt.messages, 4096); got != tt.want {
+			t.Errorf("defaultMaxTokens(%s, %d messages) = %d, want %d", tt.model, len(tt.messages), got, tt.want)
+		}
+	}
+}
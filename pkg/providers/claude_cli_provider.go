@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // ClaudeCliProvider implements LLMProvider using the claude CLI as a subprocess.
 type ClaudeCliProvider struct {
 	command   string
 	workspace string
+	defaultModel
 }
 
 // NewClaudeCliProvider creates a new Claude CLI provider.
@@ -25,6 +27,7 @@ func NewClaudeCliProvider(workspace string) *ClaudeCliProvider {
 
 // Chat implements LLMProvider.Chat by executing the claude CLI.
 func (p *ClaudeCliProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
 	systemPrompt := p.buildSystemPrompt(messages, tools)
 	prompt := p.messagesToPrompt(messages)
 
@@ -54,12 +57,14 @@ func (p *ClaudeCliProvider) Chat(ctx context.Context, messages []Message, tools
 		return nil, fmt.Errorf("claude cli error: %w", err)
 	}
 
-	return p.parseClaudeCliResponse(stdout.String())
+	resp, err := p.parseClaudeCliResponse(stdout.String())
+	setDuration(resp, start)
+	return resp, err
 }
 
 // GetDefaultModel returns the default model identifier.
 func (p *ClaudeCliProvider) GetDefaultModel() string {
-	return "claude-code"
+	return p.defaultModel.or("claude-code")
 }
 
 // messagesToPrompt converts messages to a CLI-compatible prompt string.
@@ -147,10 +152,10 @@ func (p *ClaudeCliProvider) parseClaudeCliResponse(output string) (*LLMResponse,
 
 	toolCalls := p.extractToolCalls(resp.Result)
 
-	finishReason := "stop"
+	finishReason := FinishReasonStop
 	content := resp.Result
 	if len(toolCalls) > 0 {
-		finishReason = "tool_calls"
+		finishReason = FinishReasonToolCalls
 		content = p.stripToolCallsJSON(resp.Result)
 	}
 
@@ -202,19 +207,13 @@ func (p *ClaudeCliProvider) extractToolCalls(text string) []ToolCall {
 
 	var result []ToolCall
 	for _, tc := range wrapper.ToolCalls {
-		var args map[string]interface{}
-		json.Unmarshal([]byte(tc.Function.Arguments), &args)
-
-		result = append(result, ToolCall{
-			ID:        tc.ID,
-			Type:      tc.Type,
+		call := newToolCall(tc.ID, tc.Function.Name, tc.Function.Arguments)
+		call.Type = tc.Type
+		call.Function = &FunctionCall{
 			Name:      tc.Function.Name,
-			Arguments: args,
-			Function: &FunctionCall{
-				Name:      tc.Function.Name,
-				Arguments: tc.Function.Arguments,
-			},
-		})
+			Arguments: tc.Function.Arguments,
+		}
+		result = append(result, call)
 	}
 
 	return result
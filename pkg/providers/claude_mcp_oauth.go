@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+)
+
+// claudeCodeCredentialsService is the keychain entry where Claude Code keeps
+// its credentials, including the OAuth tokens of the MCP servers it has
+// authorized. On Linux it uses ~/.claude/.credentials.json instead.
+const claudeCodeCredentialsService = "Claude Code-credentials"
+
+// LoadClaudeMCPOAuthTokens returns the MCP OAuth tokens stored by Claude
+// Code, keyed the way Claude Code keys them. Each entry holds fields such as
+// serverName, serverUrl, accessToken, refreshToken, expiresAt and clientId.
+func LoadClaudeMCPOAuthTokens(config TokenManagerConfig) map[string]interface{} {
+	credsData, _ := loadClaudeCodeCredentials(config)
+	if mcpOAuth, ok := credsData["mcpOAuth"].(map[string]interface{}); ok {
+		return mcpOAuth
+	}
+	return map[string]interface{}{}
+}
+
+// SaveClaudeMCPOAuthToken replaces one MCP OAuth entry in Claude Code's
+// credentials, keeping everything else as it was. It is used after a refresh
+// so that a rotated refresh token stays usable by Claude Code as well.
+func SaveClaudeMCPOAuthToken(config TokenManagerConfig, key string, entry map[string]interface{}) error {
+	credsData, save := loadClaudeCodeCredentials(config)
+	if save == nil {
+		return fmt.Errorf("no Claude Code credentials found")
+	}
+	mcpOAuth, ok := credsData["mcpOAuth"].(map[string]interface{})
+	if !ok {
+		mcpOAuth = map[string]interface{}{}
+		credsData["mcpOAuth"] = mcpOAuth
+	}
+	mcpOAuth[key] = entry
+
+	data, err := json.Marshal(credsData)
+	if err != nil {
+		return fmt.Errorf("encoding credentials: %w", err)
+	}
+	return save(data)
+}
+
+// loadClaudeCodeCredentials reads Claude Code's credentials from the system
+// credential store, falling back to its credentials file, and returns a
+// function that writes them back to where they were found.
+func loadClaudeCodeCredentials(config TokenManagerConfig) (map[string]interface{}, func([]byte) error) {
+	if store := auth.SystemCredentialStore(); store != nil {
+		if credsJSON := getKeychainPassword(store, claudeCodeCredentialsService, config.Account); credsJSON != "" {
+			var credsData map[string]interface{}
+			if err := json.Unmarshal([]byte(credsJSON), &credsData); err == nil {
+				return credsData, func(data []byte) error {
+					return store.Set(claudeCodeCredentialsService, config.Account, string(data))
+				}
+			}
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil
+	}
+	path := filepath.Join(home, ".claude", ".credentials.json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	var credsData map[string]interface{}
+	if err := json.Unmarshal(raw, &credsData); err != nil {
+		if config.Verbose {
+			verbosef("[TokenManager] Ignoring unreadable %s: %v\n", path, err)
+		}
+		return nil, nil
+	}
+	return credsData, func(data []byte) error {
+		return os.WriteFile(path, data, 0600)
+	}
+}
@@ -2,22 +2,32 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
-	"os/exec"
-	"runtime"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/shared/constant"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 // TokenManagerConfig holds configuration for token retrieval
 type TokenManagerConfig struct {
-	Verbose bool
-	Account string
+	Verbose     bool
+	Account     string
+	AuthAccount string // picoclaw auth store account; empty selects the default
+}
+
+// verbosef prints verbose token manager output to stdout, with credentials
+// redacted so it is safe to keep in CI logs.
+func verbosef(format string, args ...interface{}) {
+	fmt.Print(logger.Redact(fmt.Sprintf(format, args...)))
 }
 
 // ClaudeCredentials represents authentication credentials from various sources
@@ -31,12 +41,43 @@ type ClaudeProvider struct {
 	client      *anthropic.Client
 	tokenSource func() (string, error)
 	config      TokenManagerConfig
+	betas       []string
+	defaultModel
+}
+
+// claudeBaseURL is where the Messages API is served.
+const claudeBaseURL = "https://api.anthropic.com"
+
+// claudeOAuthBeta is the beta flag the Messages API requires for claude.ai
+// OAuth access tokens (Claude Pro/Max subscriptions).
+const claudeOAuthBeta = "oauth-2025-04-20"
+
+// isClaudeOAuthToken reports whether token is a claude.ai OAuth access token
+// rather than an sk-ant-api key.
+func isClaudeOAuthToken(token string) bool {
+	return strings.HasPrefix(token, "sk-ant-oat")
+}
+
+// claudeAuthOptions sends API keys as x-api-key and OAuth access tokens as a
+// bearer token with the OAuth beta flag, clearing whichever header an earlier
+// token of the other kind may have set.
+func claudeAuthOptions(token string) []option.RequestOption {
+	if isClaudeOAuthToken(token) {
+		return []option.RequestOption{
+			option.WithHeaderDel("X-Api-Key"),
+			option.WithAuthToken(token),
+			option.WithHeaderAdd("anthropic-beta", claudeOAuthBeta),
+		}
+	}
+	return []option.RequestOption{
+		option.WithHeaderDel("Authorization"),
+		option.WithAPIKey(token),
+	}
 }
 
 func NewClaudeProvider(token string) *ClaudeProvider {
 	client := anthropic.NewClient(
-		option.WithAPIKey(token),
-		option.WithBaseURL("https://api.anthropic.com"),
+		append(claudeAuthOptions(token), option.WithBaseURL(claudeBaseURL))...,
 	)
 	return &ClaudeProvider{
 		client: &client,
@@ -66,8 +107,7 @@ func NewClaudeProviderWithDynamicToken(config TokenManagerConfig) (*ClaudeProvid
 	}
 
 	client := anthropic.NewClient(
-		option.WithAPIKey(token),
-		option.WithBaseURL("https://api.anthropic.com"),
+		append(claudeAuthOptions(token), option.WithBaseURL(claudeBaseURL))...,
 	)
 
 	return &ClaudeProvider{
@@ -85,31 +125,159 @@ func NewClaudeProviderAuto() (*ClaudeProvider, error) {
 	})
 }
 
+// SetBetas sets anthropic-beta flags sent with every request, in addition
+// to those of OptionBetas and of the tools a request uses.
+func (p *ClaudeProvider) SetBetas(betas []string) {
+	p.betas = betas
+}
+
 func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
 	var opts []option.RequestOption
 	if p.tokenSource != nil {
 		tok, err := p.tokenSource()
 		if err != nil {
 			return nil, fmt.Errorf("refreshing token: %w", err)
 		}
-		opts = append(opts, option.WithAPIKey(tok))
+		opts = append(opts, claudeAuthOptions(tok)...)
 	}
 
 	params, err := buildClaudeParams(messages, tools, model, options)
 	if err != nil {
 		return nil, err
 	}
+	if _, set := options["max_tokens"]; !set && params.MaxTokens > claudeNonStreamingMaxTokens(model) {
+		params.MaxTokens = claudeNonStreamingMaxTokens(model)
+	}
+	opts = append(opts, claudeRequestOptions(tools, options, p.betas)...)
+	if dryRun(options) {
+		opts = append(opts, option.WithMiddleware(dryRunMiddleware), option.WithMaxRetries(0))
+	}
 
+	var httpResp *http.Response
+	opts = append(opts, option.WithResponseInto(&httpResp))
 	resp, err := p.client.Messages.New(ctx, params, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("claude API call: %w", err)
+		return nil, fmt.Errorf("claude API call: %w", sdkAPIError(err))
 	}
 
-	return parseClaudeResponse(resp), nil
+	llmResp := parseClaudeResponse(resp)
+	setResponseMetadata(llmResp, httpResp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// ChatStream streams a response through the Messages streaming API.
+func (p *ClaudeProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	if dryRun(options) {
+		// The SDK would report the dry run as the stream's error
+		_, err := p.Chat(ctx, messages, tools, model, options)
+		return nil, err
+	}
+	timer := newStreamTimer()
+	var opts []option.RequestOption
+	if p.tokenSource != nil {
+		tok, err := p.tokenSource()
+		if err != nil {
+			return nil, fmt.Errorf("refreshing token: %w", err)
+		}
+		opts = append(opts, claudeAuthOptions(tok)...)
+	}
+
+	params, err := buildClaudeParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, claudeRequestOptions(tools, options, p.betas)...)
+
+	var httpResp *http.Response
+	opts = append(opts, option.WithResponseInto(&httpResp))
+	stream := p.client.Messages.NewStreaming(ctx, params, opts...)
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer stream.Close()
+
+		var message anthropic.Message
+		toolCalls := newToolCallStream()
+		// The accumulator keeps only the fields the SDK knows of server
+		// tool blocks and the container, so they are kept from the events
+		serverBlocks := map[int]string{}
+		container := ""
+		for stream.Next() {
+			event := stream.Current()
+			if err := message.Accumulate(event); err != nil {
+				sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("claude stream: %w", err)})
+				return
+			}
+
+			var out []StreamEvent
+			switch ev := event.AsAny().(type) {
+			case anthropic.ContentBlockStartEvent:
+				if ev.ContentBlock.Type == "tool_use" {
+					out = toolCalls.start(int(ev.Index), ev.ContentBlock.ID, ev.ContentBlock.Name)
+				} else if strings.HasSuffix(ev.ContentBlock.Type, "_tool_use") || strings.HasSuffix(ev.ContentBlock.Type, "_tool_result") {
+					serverBlocks[int(ev.Index)] = ev.ContentBlock.RawJSON()
+				}
+			case anthropic.MessageDeltaEvent:
+				if id := claudeContainerID(ev.Delta.JSON.ExtraFields); id != "" {
+					container = id
+				}
+			case anthropic.ContentBlockDeltaEvent:
+				switch delta := ev.Delta.AsAny().(type) {
+				case anthropic.TextDelta:
+					if delta.Text != "" {
+						out = []StreamEvent{{Type: StreamEventText, Text: delta.Text}}
+					}
+				case anthropic.InputJSONDelta:
+					out = toolCalls.add(int(ev.Index), delta.PartialJSON)
+				}
+			case anthropic.ContentBlockStopEvent:
+				if _, ok := toolCalls.calls[int(ev.Index)]; ok {
+					out = toolCalls.finish(int(ev.Index))
+				}
+			}
+			for _, e := range out {
+				timer.observe(e)
+				if !sendStreamEvent(ctx, events, e) {
+					return
+				}
+			}
+		}
+		if err := stream.Err(); err != nil {
+			sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("claude API call: %w", sdkAPIError(err))})
+			return
+		}
+		for i, raw := range serverBlocks {
+			if i < len(message.Content) {
+				message.Content[i].UnmarshalJSON(withStreamedInput(raw, message.Content[i].Input))
+			}
+		}
+		final := parseClaudeResponse(&message)
+		if container != "" {
+			final.Metadata = map[string]string{MetadataContainer: container}
+		}
+		setResponseMetadata(final, httpResp)
+		timer.finish(final)
+		sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventDone, Response: final})
+	}()
+	return events, nil
 }
 
 func (p *ClaudeProvider) GetDefaultModel() string {
-	return "claude-sonnet-4-5-20250929"
+	return p.defaultModel.or("claude-sonnet-4-5-20250929")
+}
+
+// claudeNonStreamingMaxTokens is the most max_tokens the SDK lets a
+// non-streaming request of model ask for: it refuses requests that may take
+// longer than ten minutes, at an hour per 128k tokens, and lower limits for
+// some models.
+func claudeNonStreamingMaxTokens(model string) int64 {
+	limit := int64(128000 / 6)
+	if modelLimit, ok := constant.ModelNonStreamingTokens[model]; ok && int64(modelLimit) < limit {
+		limit = int64(modelLimit)
+	}
+	return limit
 }
 
 func buildClaudeParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (anthropic.MessageNewParams, error) {
@@ -120,39 +288,14 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 		switch msg.Role {
 		case "system":
 			system = append(system, anthropic.TextBlockParam{Text: msg.Content})
-		case "user":
-			if msg.ToolCallID != "" {
-				anthropicMessages = append(anthropicMessages,
-					anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
-				)
-			} else {
-				anthropicMessages = append(anthropicMessages,
-					anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)),
-				)
-			}
 		case "assistant":
-			if len(msg.ToolCalls) > 0 {
-				var blocks []anthropic.ContentBlockParamUnion
-				if msg.Content != "" {
-					blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
-				}
-				for _, tc := range msg.ToolCalls {
-					blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, tc.Arguments, tc.Name))
-				}
-				anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(blocks...))
-			} else {
-				anthropicMessages = append(anthropicMessages,
-					anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)),
-				)
-			}
-		case "tool":
-			anthropicMessages = append(anthropicMessages,
-				anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
-			)
+			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(claudeBlocks(msg)...))
+		case "user", "tool":
+			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(claudeBlocks(msg)...))
 		}
 	}
 
-	maxTokens := int64(4096)
+	maxTokens := int64(defaultMaxTokens(model, messages, 4096))
 	if mt, ok := options["max_tokens"].(int); ok {
 		maxTokens = int64(mt)
 	}
@@ -170,17 +313,88 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 	if temp, ok := options["temperature"].(float64); ok {
 		params.Temperature = anthropic.Float(temp)
 	}
+	if topP, ok := options["top_p"].(float64); ok {
+		params.TopP = anthropic.Float(topP)
+	}
+	if topK, ok := options[OptionTopK].(int); ok {
+		params.TopK = anthropic.Int(int64(topK))
+	}
+	if stop, ok := options[OptionStop].([]string); ok && len(stop) > 0 {
+		params.StopSequences = stop
+	}
 
 	if len(tools) > 0 {
 		params.Tools = translateToolsForClaude(tools)
 	}
+	if codeExecution, _ := options[OptionCodeExecution].(bool); codeExecution {
+		params.Tools = append(params.Tools, claudeCodeExecutionTool())
+	}
 
 	return params, nil
 }
 
+// claudeBlocks converts a message's content parts to Anthropic blocks.
+func claudeBlocks(msg Message) []anthropic.ContentBlockParamUnion {
+	var blocks []anthropic.ContentBlockParamUnion
+	parts := msg.ContentParts()
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		switch part.Type {
+		case ContentText:
+			blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+		case ContentImage:
+			blocks = append(blocks, claudeImageBlock(part))
+		case ContentDocument:
+			blocks = append(blocks, claudeDocumentBlock(part))
+		case ContentToolUse:
+			blocks = append(blocks, anthropic.NewToolUseBlock(part.ToolCall.ID, part.ToolCall.Arguments, part.ToolCall.Name))
+		case ContentToolResult:
+			block := anthropic.NewToolResultBlock(part.ToolCallID, part.Text, part.IsError)
+			// Images right after a result, such as screenshots, belong to it
+			for ; i+1 < len(parts) && parts[i+1].Type == ContentImage; i++ {
+				image := claudeImageBlock(parts[i+1]).OfImage
+				block.OfToolResult.Content = append(block.OfToolResult.Content,
+					anthropic.ToolResultBlockParamContentUnion{OfImage: image})
+			}
+			blocks = append(blocks, block)
+		case ContentThinking:
+			blocks = append(blocks, anthropic.NewThinkingBlock(part.Signature, part.Text))
+		}
+	}
+	return blocks
+}
+
+func claudeImageBlock(part ContentPart) anthropic.ContentBlockParamUnion {
+	if part.URL != "" {
+		return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: part.URL})
+	}
+	return anthropic.NewImageBlockBase64(part.MediaType, base64.StdEncoding.EncodeToString(part.Data))
+}
+
+// claudeDocumentBlock sends PDFs as PDFs and anything else as plain text.
+func claudeDocumentBlock(part ContentPart) anthropic.ContentBlockParamUnion {
+	var block anthropic.ContentBlockParamUnion
+	switch {
+	case part.URL != "":
+		block = anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: part.URL})
+	case part.MediaType == "application/pdf":
+		block = anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: base64.StdEncoding.EncodeToString(part.Data)})
+	default:
+		block = anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(part.Data)})
+	}
+	if part.Name != "" {
+		block.OfDocument.Title = anthropic.String(part.Name)
+	}
+	return block
+}
+
 func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam {
 	result := make([]anthropic.ToolUnionParam, 0, len(tools))
 	for _, t := range tools {
+		if t.Computer != nil {
+			result = append(result, claudeComputerTool(t))
+			continue
+		}
 		tool := anthropic.ToolParam{
 			Name: t.Function.Name,
 			InputSchema: anthropic.ToolInputSchemaParam{
@@ -207,50 +421,50 @@ func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam
 func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 	var content string
 	var toolCalls []ToolCall
+	var parts []ContentPart
+	serverTools := false
 
 	for _, block := range resp.Content {
+		if part, ok := claudeServerPart(block.RawJSON()); ok {
+			parts = append(parts, part)
+			serverTools = true
+			continue
+		}
 		switch block.Type {
 		case "text":
 			tb := block.AsText()
 			content += tb.Text
+			parts = append(parts, TextPart(tb.Text))
 		case "tool_use":
 			tu := block.AsToolUse()
-			var args map[string]interface{}
-			if err := json.Unmarshal(tu.Input, &args); err != nil {
-				args = map[string]interface{}{"raw": string(tu.Input)}
-			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        tu.ID,
-				Name:      tu.Name,
-				Arguments: args,
-			})
+			toolCalls = append(toolCalls, newToolCall(tu.ID, tu.Name, string(tu.Input)))
+			parts = append(parts, ToolUsePart(toolCalls[len(toolCalls)-1]))
 		}
 	}
 
-	finishReason := "stop"
-	switch resp.StopReason {
-	case anthropic.StopReasonToolUse:
-		finishReason = "tool_calls"
-	case anthropic.StopReasonMaxTokens:
-		finishReason = "length"
-	case anthropic.StopReasonEndTurn:
-		finishReason = "stop"
-	}
-
-	return &LLMResponse{
-		Content:      content,
-		ToolCalls:    toolCalls,
-		FinishReason: finishReason,
+	llmResp := &LLMResponse{
+		Content:         content,
+		ToolCalls:       toolCalls,
+		FinishReason:    normalizeFinishReason(string(resp.StopReason)),
+		RawFinishReason: string(resp.StopReason),
+		StopSequence:    resp.StopSequence,
 		Usage: &UsageInfo{
 			PromptTokens:     int(resp.Usage.InputTokens),
 			CompletionTokens: int(resp.Usage.OutputTokens),
 			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
 		},
 	}
+	if serverTools {
+		llmResp.Parts = parts
+	}
+	if container := claudeContainerID(resp.JSON.ExtraFields); container != "" {
+		llmResp.Metadata = map[string]string{MetadataContainer: container}
+	}
+	return llmResp
 }
 
-func createClaudeTokenSource() func() (string, error) {
-	return createDynamicTokenSource(TokenManagerConfig{Verbose: false})
+func createClaudeTokenSource(account string) func() (string, error) {
+	return createDynamicTokenSource(TokenManagerConfig{Verbose: false, AuthAccount: account})
 }
 
 // createDynamicTokenSource creates a token source with multiple fallback mechanisms
@@ -260,24 +474,24 @@ func createDynamicTokenSource(config TokenManagerConfig) func() (string, error)
 		// 1. Try environment variable first (highest priority)
 		if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
 			if config.Verbose {
-				fmt.Println("[TokenManager] Using ANTHROPIC_API_KEY from environment")
+				verbosef("[TokenManager] Using ANTHROPIC_API_KEY from environment\n")
 			}
 			return apiKey, nil
 		}
 
-		// 2. Try macOS keychain (if on macOS)
-		if runtime.GOOS == "darwin" {
+		// 2. Try the system credential store (Keychain, Credential Manager, Secret Service)
+		if store := auth.SystemCredentialStore(); store != nil {
 			credentials := getClaudeCredentialsFromKeychain(config)
 			if credentials.APIKey != "" {
 				if config.Verbose {
-					fmt.Println("[TokenManager] Retrieved API key from keychain")
+					verbosef("[TokenManager] Retrieved API key from %s\n", store.Name())
 				}
 				return credentials.APIKey, nil
 			}
 		}
 
 		// 3. Fallback to auth package (existing mechanism)
-		cred, err := auth.GetCredential("anthropic")
+		cred, err := auth.GetCredential("anthropic", config.AuthAccount)
 		if err != nil {
 			return "", fmt.Errorf("loading auth credentials: %w", err)
 		}
@@ -285,24 +499,36 @@ func createDynamicTokenSource(config TokenManagerConfig) func() (string, error)
 			return "", fmt.Errorf("no credentials for anthropic. Run: picoclaw auth login --provider anthropic")
 		}
 
+		// claude.ai OAuth access tokens expire after a few hours
+		if cred.AuthMethod == "oauth" && cred.NeedsRefresh() && cred.RefreshToken != "" {
+			refreshed, err := auth.RefreshStoredCredential("anthropic", config.AuthAccount, auth.AnthropicOAuthConfig())
+			if err != nil {
+				return "", err
+			}
+			if config.Verbose {
+				verbosef("[TokenManager] Refreshed OAuth token from auth package\n")
+			}
+			return refreshed.AccessToken, nil
+		}
+
 		if config.Verbose {
-			fmt.Println("[TokenManager] Using credential from auth package")
+			verbosef("[TokenManager] Using credential from auth package\n")
 		}
 		return cred.AccessToken, nil
 	}
 }
 
-// getClaudeCredentialsFromKeychain retrieves credentials from macOS keychain
+// getClaudeCredentialsFromKeychain retrieves credentials from the system credential store
 // Similar to token-manager.ts getClaudeCredentials()
 func getClaudeCredentialsFromKeychain(config TokenManagerConfig) ClaudeCredentials {
 	credentials := ClaudeCredentials{
 		MCPOAuthTokens: make(map[string]interface{}),
 	}
 
-	// Only attempt keychain access on macOS
-	if runtime.GOOS != "darwin" {
+	store := auth.SystemCredentialStore()
+	if store == nil {
 		if config.Verbose {
-			fmt.Println("[TokenManager] Not on macOS, skipping keychain access")
+			verbosef("[TokenManager] No system credential store on this platform, skipping keychain access\n")
 		}
 		return credentials
 	}
@@ -316,21 +542,21 @@ func getClaudeCredentialsFromKeychain(config TokenManagerConfig) ClaudeCredentia
 	}
 
 	for _, service := range keychainServices {
-		if apiKey := getKeychainPassword(service, config.Account); apiKey != "" {
+		if apiKey := getKeychainPassword(store, service, config.Account); apiKey != "" {
 			if strings.HasPrefix(apiKey, "sk-ant-") {
 				if config.Verbose {
-					fmt.Printf("[TokenManager] Found Anthropic API key in '%s' keychain service\n", service)
+					verbosef("[TokenManager] Found Anthropic API key in '%s' keychain service\n", service)
 				}
 				credentials.APIKey = apiKey
 				return credentials
 			} else if config.Verbose {
-				fmt.Printf("[TokenManager] Found credential in '%s' but not a valid API key format (starts with: %s)\n", service, apiKey[:min(10, len(apiKey))])
+				verbosef("[TokenManager] Found credential in '%s' but not a valid API key format\n", service)
 			}
 		}
 	}
 
 	// Try "Claude Code-credentials" (contains MCP OAuth tokens)
-	if credsJSON := getKeychainPassword("Claude Code-credentials", config.Account); credsJSON != "" {
+	if credsJSON := getKeychainPassword(store, "Claude Code-credentials", config.Account); credsJSON != "" {
 		var credsData map[string]interface{}
 		if err := json.Unmarshal([]byte(credsJSON), &credsData); err == nil {
 			// Extract MCP OAuth tokens
@@ -346,7 +572,7 @@ func getClaudeCredentialsFromKeychain(config TokenManagerConfig) ClaudeCredentia
 	}
 
 	// Try "Claude Safe Storage" (encryption keys/session tokens)
-	if safeStorage := getKeychainPassword("Claude Safe Storage", ""); safeStorage != "" {
+	if safeStorage := getKeychainPassword(store, "Claude Safe Storage", ""); safeStorage != "" {
 		if credentials.APIKey == "" {
 			credentials.SessionToken = safeStorage
 		}
@@ -355,22 +581,14 @@ func getClaudeCredentialsFromKeychain(config TokenManagerConfig) ClaudeCredentia
 	return credentials
 }
 
-// getKeychainPassword retrieves a password from macOS keychain
+// getKeychainPassword retrieves a password from the system credential store
 // Similar to token-manager.ts getKeychainPassword()
-func getKeychainPassword(service, account string) string {
-	// Build command
-	args := []string{"find-generic-password", "-s", service, "-w"}
-	if account != "" {
-		args = append(args, "-a", account)
-	}
-
-	cmd := exec.Command("security", args...)
-	output, err := cmd.Output()
+func getKeychainPassword(store auth.CredentialStore, service, account string) string {
+	secret, err := store.Get(service, account)
 	if err != nil {
 		return ""
 	}
-
-	return strings.TrimSpace(string(output))
+	return secret
 }
 
 // extractAPIKeyFromMCPCredentials extracts API key from MCP credentials structure
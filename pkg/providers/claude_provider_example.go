@@ -64,26 +64,15 @@ func ExampleWithToolCalls() {
 		log.Fatalf("Failed to create provider: %v", err)
 	}
 
-	// Define a tool
-	tools := []ToolDefinition{
-		{
-			Type: "function",
-			Function: ToolFunctionDefinition{
-				Name:        "get_weather",
-				Description: "Get the current weather for a location",
-				Parameters: map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"location": map[string]interface{}{
-							"type":        "string",
-							"description": "The city and state, e.g. San Francisco, CA",
-						},
-					},
-					"required": []interface{}{"location"},
-				},
-			},
-		},
+	// Define a tool; the parameters schema is generated from the struct
+	type weatherArgs struct {
+		Location string `json:"location" description:"The city and state, e.g. San Francisco, CA"`
 	}
+	weatherTool, err := NewToolDefinition("get_weather", "Get the current weather for a location", weatherArgs{})
+	if err != nil {
+		log.Fatalf("Failed to define tool: %v", err)
+	}
+	tools := []ToolDefinition{weatherTool}
 
 	ctx := context.Background()
 	messages := []Message{
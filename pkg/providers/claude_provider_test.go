@@ -2,8 +2,11 @@ package providers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -76,6 +79,41 @@ func TestBuildClaudeParams_ToolCallMessage(t *testing.T) {
 	}
 }
 
+func TestBuildClaudeParams_ContentParts(t *testing.T) {
+	messages := []Message{
+		NewMessage("user", TextPart("What is in this picture?"), ImagePart("image/png", []byte("png")), DocumentPart("notes.txt", "text/plain", []byte("notes"))),
+		NewMessage("assistant", ThinkingPart("Look closer.", "sig"), TextPart("Let me zoom."), ToolUsePart(ToolCall{ID: "call_1", Name: "zoom"})),
+		NewMessage("user", ToolResultPart("call_1", "zoomed", false), TextPart("Well?")),
+	}
+	params, err := buildClaudeParams(messages, nil, "claude-sonnet-4-5-20250929", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+
+	var got [][]string
+	for _, msg := range params.Messages {
+		var wire struct {
+			Content []struct {
+				Type string `json:"type"`
+			} `json:"content"`
+		}
+		data, _ := json.Marshal(msg)
+		json.Unmarshal(data, &wire)
+		var types []string
+		for _, block := range wire.Content {
+			types = append(types, block.Type)
+		}
+		got = append(got, types)
+	}
+	want := [][]string{{"text", "image", "document"}, {"thinking", "text", "tool_use"}, {"tool_result", "text"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("block types = %v, want %v", got, want)
+	}
+	if data := params.Messages[0].Content[1].OfImage.Source.OfBase64.Data; data != "cG5n" {
+		t.Errorf("image data = %q, want base64", data)
+	}
+}
+
 func TestBuildClaudeParams_WithTools(t *testing.T) {
 	tools := []ToolDefinition{
 		{
@@ -102,6 +140,36 @@ func TestBuildClaudeParams_WithTools(t *testing.T) {
 	}
 }
 
+func TestBuildClaudeParams_Sampling(t *testing.T) {
+	params, err := buildClaudeParams([]Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4-5-20250929", map[string]interface{}{
+		"top_p":    0.9,
+		OptionTopK: 40,
+		OptionStop: []string{"###", "END"},
+	})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	if params.TopP.Value != 0.9 {
+		t.Errorf("TopP = %v, want 0.9", params.TopP.Value)
+	}
+	if params.TopK.Value != 40 {
+		t.Errorf("TopK = %v, want 40", params.TopK.Value)
+	}
+	if len(params.StopSequences) != 2 || params.StopSequences[0] != "###" || params.StopSequences[1] != "END" {
+		t.Errorf("StopSequences = %v", params.StopSequences)
+	}
+}
+
+func TestParseClaudeResponse_StopSequence(t *testing.T) {
+	result := parseClaudeResponse(&anthropic.Message{
+		StopReason:   anthropic.StopReasonStopSequence,
+		StopSequence: "###",
+	})
+	if result.StopSequence != "###" {
+		t.Errorf("StopSequence = %q, want %q", result.StopSequence, "###")
+	}
+}
+
 func TestParseClaudeResponse_TextOnly(t *testing.T) {
 	resp := &anthropic.Message{
 		Content: []anthropic.ContentBlockUnion{},
@@ -125,11 +193,13 @@ func TestParseClaudeResponse_TextOnly(t *testing.T) {
 func TestParseClaudeResponse_StopReasons(t *testing.T) {
 	tests := []struct {
 		stopReason anthropic.StopReason
-		want       string
+		want       FinishReason
 	}{
-		{anthropic.StopReasonEndTurn, "stop"},
-		{anthropic.StopReasonMaxTokens, "length"},
-		{anthropic.StopReasonToolUse, "tool_calls"},
+		{anthropic.StopReasonEndTurn, FinishReasonStop},
+		{anthropic.StopReasonMaxTokens, FinishReasonLength},
+		{anthropic.StopReasonToolUse, FinishReasonToolCalls},
+		{anthropic.StopReasonStopSequence, FinishReasonStop},
+		{anthropic.StopReasonRefusal, FinishReasonRefusal},
 	}
 	for _, tt := range tests {
 		resp := &anthropic.Message{
@@ -139,6 +209,9 @@ func TestParseClaudeResponse_StopReasons(t *testing.T) {
 		if result.FinishReason != tt.want {
 			t.Errorf("StopReason %q: FinishReason = %q, want %q", tt.stopReason, result.FinishReason, tt.want)
 		}
+		if result.RawFinishReason != string(tt.stopReason) {
+			t.Errorf("StopReason %q: RawFinishReason = %q", tt.stopReason, result.RawFinishReason)
+		}
 	}
 }
 
@@ -171,6 +244,7 @@ func TestClaudeProvider_ChatRoundTrip(t *testing.T) {
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", "req_011")
 		json.NewEncoder(w).Encode(resp)
 	}))
 	defer server.Close()
@@ -192,6 +266,31 @@ func TestClaudeProvider_ChatRoundTrip(t *testing.T) {
 	if resp.Usage.PromptTokens != 15 {
 		t.Errorf("PromptTokens = %d, want 15", resp.Usage.PromptTokens)
 	}
+	if resp.Metadata[MetadataRequestID] != "req_011" || resp.Metadata[MetadataHTTPStatus] != "200" {
+		t.Errorf("Metadata = %v, want the request ID and status", resp.Metadata)
+	}
+}
+
+func TestClaudeProvider_ErrorCarriesRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", "req_overloaded")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-token")
+	provider.client = createAnthropicTestClient(server.URL, "test-token")
+	_, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Hello"}}, nil, "claude-sonnet-4-5-20250929", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.RequestID != "req_overloaded" {
+		t.Fatalf("Chat() error = %v, want an APIError with status 400 and the request ID", err)
+	}
+	if !strings.Contains(err.Error(), "req_overloaded") {
+		t.Errorf("error %q does not mention the request ID", err)
+	}
 }
 
 func TestClaudeProvider_GetDefaultModel(t *testing.T) {
@@ -208,3 +307,265 @@ func createAnthropicTestClient(baseURL, token string) *anthropic.Client {
 	)
 	return &c
 }
+
+func TestClaudeProvider_OAuthTokenHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-ant-oat01-abc" {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "" {
+			http.Error(w, "unexpected x-api-key", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Anthropic-Beta") != claudeOAuthBeta {
+			http.Error(w, "missing oauth beta header", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"stop_reason": "end_turn",
+			"content":     []map[string]interface{}{{"type": "text", "text": "ok"}},
+			"usage":       map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProviderWithTokenSource("sk-ant-api03-initial", func() (string, error) {
+		return "sk-ant-oat01-abc", nil
+	})
+	c := anthropic.NewClient(
+		anthropicoption.WithAPIKey("sk-ant-api03-initial"),
+		anthropicoption.WithBaseURL(server.URL),
+	)
+	provider.client = &c
+
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4-5-20250929", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("Content = %q, want %q", resp.Content, "ok")
+	}
+}
+
+func TestClaudeProvider_ComputerUse(t *testing.T) {
+	var beta string
+	var reqBody struct {
+		Tools    []map[string]interface{} `json:"tools"`
+		Messages []struct {
+			Content []struct {
+				Type    string `json:"type"`
+				Content []struct {
+					Type string `json:"type"`
+				} `json:"content"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"stop_reason": "end_turn",
+			"content":     []map[string]interface{}{{"type": "text", "text": "done"}},
+			"usage":       map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-token")
+	provider.client = createAnthropicTestClient(server.URL, "test-token")
+
+	tools := []ToolDefinition{{
+		Type:     "function",
+		Function: ToolFunctionDefinition{Name: "computer", Parameters: map[string]interface{}{"type": "object"}},
+		Computer: &ComputerDisplay{Width: 1280, Height: 800},
+	}}
+	messages := []Message{
+		{Role: "user", Content: "Open the browser"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "computer", Arguments: map[string]interface{}{"action": "screenshot"}}}},
+		NewMessage("tool", ToolResultPart("call_1", "Screenshot taken", false), ImagePart("image/png", []byte("png"))),
+	}
+	if _, err := provider.Chat(t.Context(), messages, tools, "claude-sonnet-4-5-20250929", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if beta != claudeComputerUseBeta {
+		t.Errorf("anthropic-beta = %q, want %q", beta, claudeComputerUseBeta)
+	}
+	if len(reqBody.Tools) != 1 {
+		t.Fatalf("tools = %v, want the computer tool", reqBody.Tools)
+	}
+	tool := reqBody.Tools[0]
+	if tool["type"] != claudeComputerToolType || tool["name"] != "computer" || tool["display_width_px"] != 1280.0 || tool["display_height_px"] != 800.0 {
+		t.Errorf("tool = %v, want the computer-use tool for a 1280x800 display", tool)
+	}
+	result := reqBody.Messages[2].Content[0]
+	if result.Type != "tool_result" || len(result.Content) != 2 || result.Content[1].Type != "image" {
+		t.Errorf("tool result = %+v, want the screenshot inside it", result)
+	}
+}
+
+func TestClaudeProvider_CodeExecution(t *testing.T) {
+	var beta string
+	var reqBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"stop_reason": "end_turn",
+			"container":   map[string]interface{}{"id": "container_2", "expires_at": "2026-01-01T00:00:00Z"},
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Let me compute that."},
+				{"type": "server_tool_use", "id": "srvtoolu_1", "name": "code_execution", "input": map[string]interface{}{"code": "print(6*7)"}},
+				{"type": "code_execution_tool_result", "tool_use_id": "srvtoolu_1", "content": map[string]interface{}{
+					"type": "code_execution_result", "stdout": "42\n", "stderr": "", "return_code": 0, "content": []interface{}{},
+				}},
+				{"type": "text", "text": "It is 42."},
+			},
+			"usage": map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-token")
+	provider.client = createAnthropicTestClient(server.URL, "test-token")
+
+	options := map[string]interface{}{OptionCodeExecution: true, OptionContainer: "container_1"}
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "What is 6*7?"}}, nil, "claude-sonnet-4-5-20250929", options)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if beta != claudeCodeExecutionBeta {
+		t.Errorf("anthropic-beta = %q, want %q", beta, claudeCodeExecutionBeta)
+	}
+	if reqBody["container"] != "container_1" {
+		t.Errorf("container = %v, want container_1", reqBody["container"])
+	}
+	tools, _ := reqBody["tools"].([]interface{})
+	if len(tools) != 1 || tools[0].(map[string]interface{})["type"] != claudeCodeExecutionToolType {
+		t.Errorf("tools = %v, want the code execution tool", tools)
+	}
+
+	if resp.Content != "Let me compute that.It is 42." || len(resp.ToolCalls) != 0 {
+		t.Errorf("Content = %q, ToolCalls = %v, want the text alone", resp.Content, resp.ToolCalls)
+	}
+	if resp.Metadata[MetadataContainer] != "container_2" {
+		t.Errorf("Metadata = %v, want container_2", resp.Metadata)
+	}
+	var types []ContentPartType
+	for _, part := range resp.Parts {
+		types = append(types, part.Type)
+	}
+	want := []ContentPartType{ContentText, ContentServerToolUse, ContentServerToolResult, ContentText}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("part types = %v, want %v", types, want)
+	}
+	if code := resp.Parts[1].ToolCall.Arguments["code"]; code != "print(6*7)" {
+		t.Errorf("code = %v, want print(6*7)", code)
+	}
+	if result := resp.Parts[2]; result.Text != "42" || result.IsError || result.ToolCallID != "srvtoolu_1" {
+		t.Errorf("result = %+v, want stdout 42", result)
+	}
+}
+
+func TestClaudeProvider_MCPConnector(t *testing.T) {
+	var beta string
+	var reqBody struct {
+		MCPServers []map[string]interface{} `json:"mcp_servers"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"stop_reason": "end_turn",
+			"content": []map[string]interface{}{
+				{"type": "mcp_tool_use", "id": "mcptoolu_1", "name": "search", "server_name": "issues", "input": map[string]interface{}{"q": "crash"}},
+				{"type": "mcp_tool_result", "tool_use_id": "mcptoolu_1", "is_error": false, "content": []map[string]interface{}{{"type": "text", "text": "#12 Crash on start"}}},
+				{"type": "text", "text": "Issue #12 matches."},
+			},
+			"usage": map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-token")
+	provider.client = createAnthropicTestClient(server.URL, "test-token")
+
+	options := map[string]interface{}{OptionMCPServers: []MCPServer{
+		{Name: "issues", URL: "https://issues.example.com/mcp", AuthorizationToken: "secret", Tools: []string{"search"}},
+	}}
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Any crash reports?"}}, nil, "claude-sonnet-4-5-20250929", options)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if beta != claudeMCPClientBeta {
+		t.Errorf("anthropic-beta = %q, want %q", beta, claudeMCPClientBeta)
+	}
+	if len(reqBody.MCPServers) != 1 {
+		t.Fatalf("mcp_servers = %v, want one server", reqBody.MCPServers)
+	}
+	if s := reqBody.MCPServers[0]; s["type"] != "url" || s["name"] != "issues" || s["authorization_token"] != "secret" || s["tool_configuration"] == nil {
+		t.Errorf("mcp server = %v", s)
+	}
+
+	if resp.Content != "Issue #12 matches." || len(resp.Parts) != 3 {
+		t.Fatalf("Content = %q, Parts = %+v", resp.Content, resp.Parts)
+	}
+	if use := resp.Parts[0]; use.Type != ContentServerToolUse || use.ToolCall.Name != "issues.search" {
+		t.Errorf("tool use = %+v, want issues.search", use)
+	}
+	if result := resp.Parts[1]; result.Type != ContentServerToolResult || result.Text != "#12 Crash on start" {
+		t.Errorf("tool result = %+v", result)
+	}
+}
+
+func TestClaudeProvider_Betas(t *testing.T) {
+	var beta string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"stop_reason": "end_turn",
+			"content":     []map[string]interface{}{{"type": "text", "text": "ok"}},
+			"usage":       map[string]interface{}{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-token")
+	provider.client = createAnthropicTestClient(server.URL, "test-token")
+	provider.SetBetas([]string{"context-1m-2025-08-07"})
+
+	options := map[string]interface{}{
+		OptionCodeExecution: true,
+		OptionBetas:         []string{"fine-grained-tool-streaming-2025-05-14", "context-1m-2025-08-07"},
+	}
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4-5-20250929", options); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	want := "context-1m-2025-08-07," + claudeCodeExecutionBeta + ",fine-grained-tool-streaming-2025-05-14"
+	if beta != want {
+		t.Errorf("anthropic-beta = %q, want %q", beta, want)
+	}
+}
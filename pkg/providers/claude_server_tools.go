@@ -0,0 +1,225 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/anthropics/anthropic-sdk-go/packages/respjson"
+)
+
+// Request options for Anthropic's code execution tool, which runs Python
+// in a sandbox on Anthropic's side. OptionCodeExecution (a bool) offers the
+// tool; OptionContainer (a string) reuses the sandbox of an earlier
+// response, as recorded in its MetadataContainer, keeping its files.
+const (
+	OptionCodeExecution = "code_execution"
+	OptionContainer     = "container"
+)
+
+// MetadataContainer is the ID of the code execution container a response
+// ran in.
+const MetadataContainer = "container"
+
+// OptionMCPServers (a []MCPServer) lists remote MCP servers Anthropic
+// connects to itself for the request, calling their tools without a round
+// trip through the client.
+const OptionMCPServers = "mcp_servers"
+
+// MCPServer is a remote MCP server for Anthropic's MCP connector. Tools,
+// when set, limits the server's tools the model may call.
+type MCPServer struct {
+	Name               string
+	URL                string
+	AuthorizationToken string
+	Tools              []string
+}
+
+// OptionBetas (a []string) lists further anthropic-beta flags to send with
+// the request, such as "context-1m-2025-08-07", for betas picoclaw does not
+// turn on by itself.
+const OptionBetas = "betas"
+
+const (
+	claudeCodeExecutionToolType = "code_execution_20250522"
+	claudeCodeExecutionBeta     = "code-execution-2025-05-22"
+	claudeMCPClientBeta         = "mcp-client-2025-04-04"
+)
+
+// claudeRequestOptions returns the request options the tools and options
+// of a request need: the betas of the tools it uses and of OptionBetas,
+// added to the configured ones, the container it reuses and the MCP
+// servers it lists. The SDK's stable message params have neither of the
+// latter, so they are set on the JSON body.
+func claudeRequestOptions(tools []ToolDefinition, options map[string]interface{}, configured []string) []option.RequestOption {
+	betas := append([]string(nil), configured...)
+	for _, t := range tools {
+		if t.Computer != nil {
+			betas = append(betas, claudeComputerUseBeta)
+			break
+		}
+	}
+	if codeExecution, _ := options[OptionCodeExecution].(bool); codeExecution {
+		betas = append(betas, claudeCodeExecutionBeta)
+	}
+	mcpServers, _ := options[OptionMCPServers].([]MCPServer)
+	if len(mcpServers) > 0 {
+		betas = append(betas, claudeMCPClientBeta)
+	}
+	requested, _ := options[OptionBetas].([]string)
+	betas = append(betas, requested...)
+
+	var opts []option.RequestOption
+	if betas = uniqueBetas(betas); len(betas) > 0 {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", strings.Join(betas, ",")))
+	}
+	if container, _ := options[OptionContainer].(string); container != "" {
+		opts = append(opts, option.WithJSONSet("container", container))
+	}
+	if len(mcpServers) > 0 {
+		opts = append(opts, option.WithJSONSet("mcp_servers", claudeMCPServers(mcpServers)))
+	}
+	return opts
+}
+
+// uniqueBetas drops empty and repeated beta flags, keeping their order.
+func uniqueBetas(betas []string) []string {
+	seen := make(map[string]bool, len(betas))
+	out := betas[:0]
+	for _, b := range betas {
+		b = strings.TrimSpace(b)
+		if b == "" || seen[b] {
+			continue
+		}
+		seen[b] = true
+		out = append(out, b)
+	}
+	return out
+}
+
+func claudeMCPServers(servers []MCPServer) []map[string]interface{} {
+	wire := make([]map[string]interface{}, 0, len(servers))
+	for _, s := range servers {
+		server := map[string]interface{}{"type": "url", "name": s.Name, "url": s.URL}
+		if s.AuthorizationToken != "" {
+			server["authorization_token"] = s.AuthorizationToken
+		}
+		if len(s.Tools) > 0 {
+			server["tool_configuration"] = map[string]interface{}{"enabled": true, "allowed_tools": s.Tools}
+		}
+		wire = append(wire, server)
+	}
+	return wire
+}
+
+// claudeCodeExecutionTool is the code execution server tool, sent as raw
+// JSON as the SDK's stable tool union has no variant for it.
+func claudeCodeExecutionTool() anthropic.ToolUnionParam {
+	tool := param.Override[anthropic.ToolParam](map[string]interface{}{
+		"type": claudeCodeExecutionToolType,
+		"name": "code_execution",
+	})
+	return anthropic.ToolUnionParam{OfTool: &tool}
+}
+
+// claudeServerPart converts a server tool block, which the SDK's stable
+// types do not model, from its raw JSON. It returns false for blocks of
+// other types.
+func claudeServerPart(raw string) (ContentPart, bool) {
+	var block struct {
+		Type       string                 `json:"type"`
+		ID         string                 `json:"id"`
+		Name       string                 `json:"name"`
+		ServerName string                 `json:"server_name"`
+		Input      map[string]interface{} `json:"input"`
+		ToolUseID  string                 `json:"tool_use_id"`
+		IsError    bool                   `json:"is_error"`
+		Content    json.RawMessage        `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(raw), &block); err != nil {
+		return ContentPart{}, false
+	}
+
+	switch block.Type {
+	case "server_tool_use", "mcp_tool_use":
+		name := block.Name
+		if block.ServerName != "" {
+			name = block.ServerName + "." + name
+		}
+		return ContentPart{
+			Type:     ContentServerToolUse,
+			ToolCall: &ToolCall{ID: block.ID, Type: block.Type, Name: name, Arguments: block.Input},
+		}, true
+	case "mcp_tool_result":
+		// Content is text blocks, or occasionally a plain string
+		var blocks []struct {
+			Text string `json:"text"`
+		}
+		var texts []string
+		if err := json.Unmarshal(block.Content, &blocks); err == nil {
+			for _, b := range blocks {
+				texts = append(texts, b.Text)
+			}
+		} else {
+			var text string
+			json.Unmarshal(block.Content, &text)
+			texts = append(texts, text)
+		}
+		return ContentPart{
+			Type:       ContentServerToolResult,
+			ToolCallID: block.ToolUseID,
+			Text:       strings.Join(texts, "\n"),
+			IsError:    block.IsError,
+		}, true
+	case "code_execution_tool_result":
+		var result struct {
+			Type       string `json:"type"`
+			Stdout     string `json:"stdout"`
+			Stderr     string `json:"stderr"`
+			ReturnCode int    `json:"return_code"`
+			ErrorCode  string `json:"error_code"`
+		}
+		json.Unmarshal(block.Content, &result)
+		part := ContentPart{Type: ContentServerToolResult, ToolCallID: block.ToolUseID}
+		if result.Type == "code_execution_tool_result_error" {
+			part.Text, part.IsError = "code execution failed: "+result.ErrorCode, true
+			return part, true
+		}
+		part.Text = strings.TrimRight(result.Stdout, "\n")
+		if stderr := strings.TrimRight(result.Stderr, "\n"); stderr != "" {
+			part.Text = strings.TrimPrefix(part.Text+"\n"+stderr, "\n")
+		}
+		part.IsError = result.ReturnCode != 0
+		return part, true
+	}
+	return ContentPart{}, false
+}
+
+// claudeContainerID reads the ID of the container a response ran in, which
+// the SDK's stable types leave among a message's extra fields.
+func claudeContainerID(extra map[string]respjson.Field) string {
+	// Fields the SDK does not know are marked invalid, though their raw
+	// JSON is intact
+	var container struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(extra["container"].Raw()), &container)
+	return container.ID
+}
+
+// withStreamedInput puts the input streamed for a server tool use into
+// the block as it started, whose input is empty.
+func withStreamedInput(raw string, input json.RawMessage) []byte {
+	var block map[string]json.RawMessage
+	if len(input) == 0 || json.Unmarshal([]byte(raw), &block) != nil {
+		return []byte(raw)
+	}
+	if _, ok := block["input"]; !ok {
+		return []byte(raw)
+	}
+	block["input"] = input
+	data, _ := json.Marshal(block)
+	return data
+}
@@ -192,7 +192,7 @@ func ExampleAzureConfigValidation() {
 	if azureConfig == nil {
 		log.Println("Azure not configured, using standard OpenAI")
 		// Create standard OpenAI provider
-		tokenSource := createCodexTokenSource()
+		tokenSource := createCodexTokenSource("")
 		token, accountID, _ := tokenSource()
 		provider := NewCodexProvider(token, accountID)
 		_ = provider
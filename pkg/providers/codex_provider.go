@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
@@ -13,6 +16,7 @@ import (
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
 	"github.com/sipeed/picoclaw/pkg/auth"
 )
 
@@ -33,13 +37,17 @@ type CodexProvider struct {
 	accountID   string
 	tokenSource func() (string, string, error)
 	azureConfig *AzureConfig // Azure-specific configuration
+	defaultModel
 }
 
 const defaultCodexInstructions = "You are Codex, a coding assistant."
 
+// codexBaseURL is the ChatGPT backend that OAuth logins are served by.
+const codexBaseURL = "https://chatgpt.com/backend-api/codex"
+
 func NewCodexProvider(token, accountID string) *CodexProvider {
 	opts := []option.RequestOption{
-		option.WithBaseURL("https://chatgpt.com/backend-api/codex"),
+		option.WithBaseURL(codexBaseURL),
 		option.WithAPIKey(token),
 	}
 	if accountID != "" {
@@ -105,13 +113,13 @@ func NewCodexProviderAuto() (*CodexProvider, error) {
 	// If Azure is configured, use Azure provider
 	if azureConfig != nil {
 		if azureConfig.Verbose {
-			fmt.Println("[CodexProvider] Using Azure OpenAI configuration - codex_provider.go:109")
+			verbosef("[CodexProvider] Using Azure OpenAI configuration - codex_provider.go:109\n")
 		}
 		return NewCodexProviderWithAzure(azureConfig, "")
 	}
 
 	// Otherwise, use standard OpenAI with dynamic token source
-	tokenSource := createCodexTokenSource()
+	tokenSource := createCodexTokenSource("")
 	token, accountID, err := tokenSource()
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve OpenAI token: %w", err)
@@ -131,82 +139,163 @@ func NewCodexProviderWithDynamicAuth(azureConfig *AzureConfig) (*CodexProvider,
 	return NewCodexProviderAuto()
 }
 
+// OptionAccount (a string) names the picoclaw auth store account a request
+// is sent as, in place of the provider's own, e.g. a ChatGPT team
+// workspace next to a personal one.
+const OptionAccount = "account"
+
+// authOptions returns the credentials for a request: those of the account
+// named in options, or the provider's own.
+func (p *CodexProvider) authOptions(options map[string]interface{}) ([]option.RequestOption, error) {
+	tokenSource := p.tokenSource
+	if account, _ := options[OptionAccount].(string); account != "" && p.azureConfig == nil {
+		tokenSource = createCodexTokenSource(account)
+	}
+	if tokenSource == nil {
+		return nil, nil
+	}
+	tok, accID, err := tokenSource()
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	opts := []option.RequestOption{option.WithAPIKey(tok)}
+	if accID != "" {
+		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accID))
+	}
+	return opts, nil
+}
+
 func (p *CodexProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	var opts []option.RequestOption
-	if p.tokenSource != nil {
-		tok, accID, err := p.tokenSource()
-		if err != nil {
-			return nil, fmt.Errorf("refreshing token: %w", err)
-		}
-		opts = append(opts, option.WithAPIKey(tok))
-		if accID != "" {
-			opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accID))
-		}
+	start := time.Now()
+	opts, err := p.authOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun(options) {
+		opts = append(opts, option.WithMiddleware(dryRunMiddleware), option.WithMaxRetries(0))
 	}
 
 	// Azure OpenAI uses Chat Completions API, not Responses API
 	if p.azureConfig != nil {
 		if p.azureConfig.Verbose {
-			fmt.Println("[CodexProvider] Using Azure OpenAI Chat Completions API - codex_provider.go:151")
+			verbosef("[CodexProvider] Using Azure OpenAI Chat Completions API - codex_provider.go:151\n")
 		}
-		return p.chatAzure(ctx, messages, tools, model, options, opts)
+		resp, err := p.chatAzure(ctx, messages, tools, model, options, opts)
+		setDuration(resp, start)
+		return resp, err
 	}
 
 	// Standard OpenAI uses Responses API
 	params := buildCodexParams(messages, tools, model, options)
 
+	var httpResp *http.Response
+	opts = append(opts, option.WithResponseInto(&httpResp))
 	resp, err := p.client.Responses.New(ctx, params, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("codex API call: %w", err)
+		return nil, fmt.Errorf("codex API call: %w", sdkAPIError(err))
 	}
 
-	return parseCodexResponse(resp), nil
+	llmResp := parseCodexResponse(resp)
+	setResponseMetadata(llmResp, httpResp)
+	setDuration(llmResp, start)
+	return llmResp, nil
 }
 
 // chatAzure handles Azure OpenAI Chat Completions API
 func (p *CodexProvider) chatAzure(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, opts []option.RequestOption) (*LLMResponse, error) {
 	// Build chat completion parameters for Azure
-	chatMessages := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
-
-	for _, msg := range messages {
-		switch msg.Role {
-		case "system":
-			chatMessages = append(chatMessages, openai.SystemMessage(msg.Content))
-		case "user":
-			chatMessages = append(chatMessages, openai.UserMessage(msg.Content))
-		case "assistant":
-			chatMessages = append(chatMessages, openai.AssistantMessage(msg.Content))
-		case "tool":
-			chatMessages = append(chatMessages, openai.ToolMessage(msg.ToolCallID, msg.Content))
-		}
-	}
+	chatMessages := azureChatMessages(messages)
 
 	params := openai.ChatCompletionNewParams{
 		Messages: chatMessages,
 		Model:    model,
 	}
 
-	// Azure OpenAI uses max_completion_tokens instead of max_tokens
-	if maxTokens, ok := options["max_tokens"].(int); ok {
-		params.MaxCompletionTokens = openai.Int(int64(maxTokens))
+	// Pick max_tokens vs max_completion_tokens and drop sampling parameters
+	// the model rejects (e.g. gpt-5 and o-series only accept the defaults)
+	paramModel := model
+	if paramModel == "" {
+		paramModel = p.azureConfig.Deployment
 	}
-
-	// Skip temperature for Azure OpenAI gpt-5.2-chat (only supports default value of 1)
-	// if temp, ok := options["temperature"].(float64); ok {
-	// 	params.Temperature = openai.Float(temp)
-	// }
+	applyChatModelParams(&params, paramModel, options)
 
 	// Add api-version query parameter (required by Azure OpenAI)
 	opts = append(opts, option.WithQuery("api-version", p.azureConfig.APIVersion))
 
 	// Call Azure OpenAI Chat Completions API
+	var httpResp *http.Response
+	opts = append(opts, option.WithResponseInto(&httpResp))
 	resp, err := p.client.Chat.Completions.New(ctx, params, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("Azure OpenAI API call: %w", err)
+		return nil, fmt.Errorf("Azure OpenAI API call: %w", sdkAPIError(err))
 	}
 
 	// Parse Azure response
-	return parseChatCompletionResponse(resp), nil
+	llmResp := parseChatCompletionResponse(resp)
+	setResponseMetadata(llmResp, httpResp)
+	return llmResp, nil
+}
+
+// azureChatMessages converts messages to chat completion messages, sending
+// a user message's images and documents as content parts.
+func azureChatMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	chatMessages := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			chatMessages = append(chatMessages, openai.SystemMessage(msg.Content))
+			continue
+		}
+		var content []openai.ChatCompletionContentPartUnionParam
+		var toolCalls []openai.ChatCompletionMessageToolCallUnionParam
+		for _, part := range msg.ContentParts() {
+			switch part.Type {
+			case ContentText:
+				content = append(content, openai.TextContentPart(part.Text))
+			case ContentImage:
+				content = append(content, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: part.dataURL()}))
+			case ContentDocument:
+				file := openai.ChatCompletionContentPartFileFileParam{FileData: openai.Opt(part.dataURL())}
+				if part.Name != "" {
+					file.Filename = openai.Opt(part.Name)
+				}
+				content = append(content, openai.FileContentPart(file))
+			case ContentToolUse:
+				argsJSON, _ := json.Marshal(part.ToolCall.Arguments)
+				toolCalls = append(toolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID: part.ToolCall.ID,
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+							Name:      part.ToolCall.Name,
+							Arguments: string(argsJSON),
+						},
+					},
+				})
+			case ContentToolResult:
+				chatMessages = append(chatMessages, openai.ToolMessage(part.Text, part.ToolCallID))
+			}
+		}
+		var chatMessage openai.ChatCompletionMessageParamUnion
+		switch {
+		case msg.Role == "assistant":
+			chatMessage = openai.AssistantMessage(msg.textContent())
+			chatMessage.OfAssistant.ToolCalls = toolCalls
+		case len(content) == 1 && content[0].OfText != nil:
+			chatMessage = openai.UserMessage(content[0].OfText.Text)
+		case len(content) > 0:
+			chatMessage = openai.UserMessage(content)
+		default:
+			continue
+		}
+		if name := openAIName(msg.Name); name != "" {
+			if chatMessage.OfAssistant != nil {
+				chatMessage.OfAssistant.Name = openai.Opt(name)
+			} else {
+				chatMessage.OfUser.Name = openai.Opt(name)
+			}
+		}
+		chatMessages = append(chatMessages, chatMessage)
+	}
+	return chatMessages
 }
 
 // parseChatCompletionResponse converts Azure OpenAI chat completion response to LLMResponse
@@ -214,7 +303,7 @@ func parseChatCompletionResponse(resp *openai.ChatCompletion) *LLMResponse {
 	if len(resp.Choices) == 0 {
 		return &LLMResponse{
 			Content:      "",
-			FinishReason: "error",
+			FinishReason: FinishReasonError,
 		}
 	}
 
@@ -225,15 +314,7 @@ func parseChatCompletionResponse(resp *openai.ChatCompletion) *LLMResponse {
 	if len(message.ToolCalls) > 0 {
 		toolCalls = make([]ToolCall, 0, len(message.ToolCalls))
 		for _, tc := range message.ToolCalls {
-			var args map[string]interface{}
-			if tc.Function.Arguments != "" {
-				json.Unmarshal([]byte(tc.Function.Arguments), &args)
-			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        tc.ID,
-				Name:      tc.Function.Name,
-				Arguments: args,
-			})
+			toolCalls = append(toolCalls, newToolCall(tc.ID, tc.Function.Name, tc.Function.Arguments))
 		}
 	}
 
@@ -246,77 +327,110 @@ func parseChatCompletionResponse(resp *openai.ChatCompletion) *LLMResponse {
 		}
 	}
 
+	finishReason := normalizeFinishReason(choice.FinishReason)
+	if message.Refusal != "" {
+		finishReason = FinishReasonRefusal
+	}
 	return &LLMResponse{
-		Content:      message.Content,
-		ToolCalls:    toolCalls,
-		FinishReason: string(choice.FinishReason),
-		Usage:        usage,
+		Content:         message.Content,
+		ToolCalls:       toolCalls,
+		FinishReason:    finishReason,
+		RawFinishReason: choice.FinishReason,
+		Usage:           usage,
 	}
 }
 
 func (p *CodexProvider) GetDefaultModel() string {
-	return "gpt-4o"
+	return p.defaultModel.or("gpt-4o")
 }
 
+// buildCodexParams builds a request for the ChatGPT Codex backend, which
+// requires instructions.
 func buildCodexParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) responses.ResponseNewParams {
+	params := buildResponsesParams(messages, tools, model, options)
+	if !params.Instructions.Valid() {
+		params.Instructions = openai.Opt(defaultCodexInstructions)
+	}
+	return params
+}
+
+// buildResponsesParams builds a Responses API request. Nothing is stored
+// on OpenAI's side, so each request carries the whole conversation.
+func buildResponsesParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) responses.ResponseNewParams {
 	var inputItems responses.ResponseInputParam
 	var instructions string
 
 	for _, msg := range messages {
-		switch msg.Role {
-		case "system":
+		if msg.Role == "system" {
 			instructions = msg.Content
-		case "user":
-			if msg.ToolCallID != "" {
+			continue
+		}
+		role := responses.EasyInputMessageRoleUser
+		if msg.Role == "assistant" {
+			role = responses.EasyInputMessageRoleAssistant
+		}
+		// Text, images and documents the user sent go in one message after
+		// any tool results, which is where a tool's screenshots end up
+		var content responses.ResponseInputMessageContentListParam
+		for _, part := range msg.ContentParts() {
+			switch part.Type {
+			case ContentText:
+				if role == responses.EasyInputMessageRoleUser {
+					content = append(content, responses.ResponseInputContentParamOfInputText(part.Text))
+					continue
+				}
 				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-					OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
-						CallID: msg.ToolCallID,
-						Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
+					OfMessage: &responses.EasyInputMessageParam{
+						Role:    role,
+						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(part.Text)},
 					},
 				})
-			} else {
-				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-					OfMessage: &responses.EasyInputMessageParam{
-						Role:    responses.EasyInputMessageRoleUser,
-						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(msg.Content)},
+			case ContentImage:
+				content = append(content, responses.ResponseInputContentUnionParam{
+					OfInputImage: &responses.ResponseInputImageParam{
+						ImageURL: openai.Opt(part.dataURL()),
+						Detail:   responses.ResponseInputImageDetailAuto,
 					},
 				})
-			}
-		case "assistant":
-			if len(msg.ToolCalls) > 0 {
-				if msg.Content != "" {
-					inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-						OfMessage: &responses.EasyInputMessageParam{
-							Role:    responses.EasyInputMessageRoleAssistant,
-							Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(msg.Content)},
-						},
-					})
+			case ContentDocument:
+				file := &responses.ResponseInputFileParam{}
+				if part.URL != "" {
+					file.FileURL = openai.Opt(part.URL)
+				} else {
+					file.FileData = openai.Opt(part.dataURL())
 				}
-				for _, tc := range msg.ToolCalls {
-					argsJSON, _ := json.Marshal(tc.Arguments)
-					inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-						OfFunctionCall: &responses.ResponseFunctionToolCallParam{
-							CallID:    tc.ID,
-							Name:      tc.Name,
-							Arguments: string(argsJSON),
-						},
-					})
+				if part.Name != "" {
+					file.Filename = openai.Opt(part.Name)
 				}
-			} else {
+				content = append(content, responses.ResponseInputContentUnionParam{OfInputFile: file})
+			case ContentToolUse:
+				argsJSON, _ := json.Marshal(part.ToolCall.Arguments)
 				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-					OfMessage: &responses.EasyInputMessageParam{
-						Role:    responses.EasyInputMessageRoleAssistant,
-						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(msg.Content)},
+					OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+						CallID:    part.ToolCall.ID,
+						Name:      part.ToolCall.Name,
+						Arguments: string(argsJSON),
+					},
+				})
+			case ContentToolResult:
+				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
+					OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
+						CallID: part.ToolCallID,
+						Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(part.Text)},
 					},
 				})
+			case ContentReasoning:
+				inputItems = append(inputItems, codexReasoningItem(part))
+			}
+		}
+		if len(content) > 0 {
+			item := &responses.EasyInputMessageParam{Role: responses.EasyInputMessageRoleUser}
+			if len(content) == 1 && content[0].OfInputText != nil {
+				item.Content.OfString = openai.Opt(content[0].OfInputText.Text)
+			} else {
+				item.Content.OfInputItemContentList = content
 			}
-		case "tool":
-			inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-				OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
-					CallID: msg.ToolCallID,
-					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
-				},
-			})
+			inputItems = append(inputItems, responses.ResponseInputItemUnionParam{OfMessage: item})
 		}
 	}
 
@@ -327,25 +441,38 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 		},
 		Store: openai.Opt(false),
 	}
+	if codexReasons(model) {
+		// Nothing is stored, so reasoning is replayed from the client
+		params.Include = []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent}
+	}
 
 	if instructions != "" {
 		params.Instructions = openai.Opt(instructions)
-	} else {
-		// ChatGPT Codex backend requires instructions to be present.
-		params.Instructions = openai.Opt(defaultCodexInstructions)
 	}
 
-	if maxTokens, ok := options["max_tokens"].(int); ok {
-		params.MaxOutputTokens = openai.Opt(int64(maxTokens))
+	// The Responses API has no stop sequences
+	resolved := resolveModelParams(model, options)
+	if resolved.maxTokens > 0 {
+		params.MaxOutputTokens = openai.Opt(int64(resolved.maxTokens))
 	}
-
-	if temp, ok := options["temperature"].(float64); ok {
-		params.Temperature = openai.Opt(temp)
+	if resolved.temperature != nil {
+		params.Temperature = openai.Opt(*resolved.temperature)
+	}
+	if resolved.topP != nil {
+		params.TopP = openai.Opt(*resolved.topP)
+	}
+	if resolved.reasoningEffort != "" {
+		params.Reasoning.Effort = shared.ReasoningEffort(resolved.reasoningEffort)
 	}
 
 	if len(tools) > 0 {
 		params.Tools = translateToolsForCodex(tools)
 	}
+	if webSearch, _ := options[OptionWebSearch].(bool); webSearch {
+		params.Tools = append(params.Tools, responses.ToolUnionParam{
+			OfWebSearch: &responses.WebSearchToolParam{Type: responses.WebSearchToolTypeWebSearch},
+		})
+	}
 
 	return params
 }
@@ -369,34 +496,55 @@ func translateToolsForCodex(tools []ToolDefinition) []responses.ToolUnionParam {
 func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	var content strings.Builder
 	var toolCalls []ToolCall
+	var refused bool
+	var parts []ContentPart
+	var citations []Citation
+	keepParts := false
+	cited := map[string]bool{}
 
 	for _, item := range resp.Output {
 		switch item.Type {
 		case "message":
 			for _, c := range item.Content {
-				if c.Type == "output_text" {
+				switch c.Type {
+				case "output_text":
 					content.WriteString(c.Text)
+					parts = append(parts, TextPart(c.Text))
+					citations = append(citations, codexCitations(c.Annotations, cited)...)
+				case "refusal":
+					content.WriteString(c.Refusal)
+					parts = append(parts, TextPart(c.Refusal))
+					refused = true
 				}
 			}
 		case "function_call":
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(item.Arguments), &args); err != nil {
-				args = map[string]interface{}{"raw": item.Arguments}
+			toolCalls = append(toolCalls, newToolCall(item.CallID, item.Name, item.Arguments))
+			parts = append(parts, ToolUsePart(toolCalls[len(toolCalls)-1]))
+		case "web_search_call":
+			parts = append(parts, codexWebSearchPart(item))
+			keepParts = true
+		case "reasoning":
+			if item.EncryptedContent != "" {
+				parts = append(parts, codexReasoningPart(item))
+				keepParts = true
 			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        item.CallID,
-				Name:      item.Name,
-				Arguments: args,
-			})
 		}
 	}
 
-	finishReason := "stop"
+	finishReason := FinishReasonStop
 	if len(toolCalls) > 0 {
-		finishReason = "tool_calls"
+		finishReason = FinishReasonToolCalls
 	}
+	if refused {
+		finishReason = FinishReasonRefusal
+	}
+	rawFinishReason := string(resp.Status)
 	if resp.Status == "incomplete" {
-		finishReason = "length"
+		rawFinishReason = resp.IncompleteDetails.Reason
+		finishReason = FinishReasonLength
+		if rawFinishReason != "" {
+			finishReason = normalizeFinishReason(rawFinishReason)
+		}
 	}
 
 	var usage *UsageInfo
@@ -408,32 +556,34 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 		}
 	}
 
-	return &LLMResponse{
-		Content:      content.String(),
-		ToolCalls:    toolCalls,
-		FinishReason: finishReason,
-		Usage:        usage,
+	llmResp := &LLMResponse{
+		Content:         content.String(),
+		ToolCalls:       toolCalls,
+		FinishReason:    finishReason,
+		RawFinishReason: rawFinishReason,
+		Usage:           usage,
+		Citations:       citations,
 	}
+	if keepParts {
+		llmResp.Parts = parts
+	}
+	return llmResp
 }
 
-func createCodexTokenSource() func() (string, string, error) {
+func createCodexTokenSource(account string) func() (string, string, error) {
 	return func() (string, string, error) {
-		cred, err := auth.GetCredential("openai")
+		cred, err := auth.GetCredential("openai", account)
 		if err != nil {
 			return "", "", fmt.Errorf("loading auth credentials: %w", err)
 		}
 		if cred == nil {
-			return "", "", fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
+			return "", "", fmt.Errorf("no credentials for openai%s. Run: picoclaw auth login --provider openai%s", accountSuffix(account), accountFlag(account))
 		}
 
 		if cred.AuthMethod == "oauth" && cred.NeedsRefresh() && cred.RefreshToken != "" {
-			oauthCfg := auth.OpenAIOAuthConfig()
-			refreshed, err := auth.RefreshAccessToken(cred, oauthCfg)
+			refreshed, err := auth.RefreshStoredCredential("openai", account, auth.OpenAIOAuthConfig())
 			if err != nil {
-				return "", "", fmt.Errorf("refreshing token: %w", err)
-			}
-			if err := auth.SetCredential("openai", refreshed); err != nil {
-				return "", "", fmt.Errorf("saving refreshed token: %w", err)
+				return "", "", err
 			}
 			return refreshed.AccessToken, refreshed.AccountID, nil
 		}
@@ -472,7 +622,7 @@ func LoadAzureConfigFromEnv() (*AzureConfig, error) {
 	}
 
 	if len(missing) > 0 {
-		return nil, fmt.Errorf("missing required Azure OpenAI environment variables: %v\nPlease set them in your .env file. See .env.example for reference", missing)
+		return nil, fmt.Errorf("missing required Azure OpenAI environment variables: %v\nPlease set them in the environment or a .env file. See .env.example for reference", missing)
 	}
 
 	return &AzureConfig{
@@ -489,10 +639,20 @@ func LoadAzureConfigFromEnv() (*AzureConfig, error) {
 // createAzureManagedIdentityTokenSource creates a token source using Azure Managed Identity
 // This requires the Azure Identity SDK to be installed
 func createAzureManagedIdentityTokenSource(config *AzureConfig) func() (string, string, error) {
+	// The credential and its token are kept, so requests and Warmup do not
+	// each pay for a token; tokens last an hour or more
+	var mu sync.Mutex
+	var cred azcore.TokenCredential
+	var cached azcore.AccessToken
 	return func() (string, string, error) {
 		if config == nil {
 			return "", "", fmt.Errorf("Azure configuration is nil")
 		}
+		mu.Lock()
+		defer mu.Unlock()
+		if cached.Token != "" && time.Until(cached.ExpiresOn) > 5*time.Minute {
+			return cached.Token, "", nil
+		}
 
 		// NOTE: This is a placeholder implementation
 		// To fully implement Azure Managed Identity, you need to:
@@ -501,32 +661,33 @@ func createAzureManagedIdentityTokenSource(config *AzureConfig) func() (string,
 		// 3. Implement token retrieval using DefaultAzureCredential or ManagedIdentityCredential
 
 		// Azure authentication using DefaultAzureCredential or ManagedIdentityCredential
-		var cred azcore.TokenCredential
-		var err error
-
-		if config.ManagedIdentityID != "" {
-			// User-assigned managed identity (for Azure deployment)
-			if config.Verbose {
-				fmt.Printf("[AzureAuth] Using userassigned managed identity: %s\n - codex_provider.go:540", config.ManagedIdentityID)
-			}
-			options := &azidentity.ManagedIdentityCredentialOptions{
-				ID: azidentity.ClientID(config.ManagedIdentityID),
-			}
-			cred, err = azidentity.NewManagedIdentityCredential(options)
-		} else {
-			// DefaultAzureCredential supports multiple auth methods:
-			// - Managed Identity (when running in Azure)
-			// - Azure CLI (local testing with 'az login')
-			// - Environment variables
-			// - Interactive browser (if needed)
-			if config.Verbose {
-				fmt.Println("[AzureAuth] Using DefaultAzureCredential (supports local Azure CLI auth) - codex_provider.go:553")
+		if cred == nil {
+			var err error
+			if config.ManagedIdentityID != "" {
+				// User-assigned managed identity (for Azure deployment)
+				if config.Verbose {
+					verbosef("[AzureAuth] Using userassigned managed identity: %s\n - codex_provider.go:540", config.ManagedIdentityID)
+				}
+				options := &azidentity.ManagedIdentityCredentialOptions{
+					ID: azidentity.ClientID(config.ManagedIdentityID),
+				}
+				cred, err = azidentity.NewManagedIdentityCredential(options)
+			} else {
+				// DefaultAzureCredential supports multiple auth methods:
+				// - Managed Identity (when running in Azure)
+				// - Azure CLI (local testing with 'az login')
+				// - Environment variables
+				// - Interactive browser (if needed)
+				if config.Verbose {
+					verbosef("[AzureAuth] Using DefaultAzureCredential (supports local Azure CLI auth) - codex_provider.go:553\n")
+				}
+				cred, err = azidentity.NewDefaultAzureCredential(nil)
 			}
-			cred, err = azidentity.NewDefaultAzureCredential(nil)
-		}
 
-		if err != nil {
-			return "", "", fmt.Errorf("failed to create Azure credential: %w", err)
+			if err != nil {
+				cred = nil
+				return "", "", fmt.Errorf("failed to create Azure credential: %w", err)
+			}
 		}
 
 		// Get access token for the specified scope
@@ -536,9 +697,10 @@ func createAzureManagedIdentityTokenSource(config *AzureConfig) func() (string,
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get Azure access token: %w", err)
 		}
+		cached = token
 
 		if config.Verbose {
-			fmt.Printf("[AzureAuth] Retrieved token for scope: %s\n - codex_provider.go:571", config.Scope)
+			verbosef("[AzureAuth] Retrieved token for scope: %s\n - codex_provider.go:571", config.Scope)
 		}
 
 		return token.Token, "", nil
@@ -552,23 +714,23 @@ func createDynamicCodexTokenSource(azureConfig *AzureConfig) func() (string, str
 		// 1. Try Azure Managed Identity first (if configured)
 		if azureConfig != nil && azureConfig.UseManagedIdentity {
 			if azureConfig.Verbose {
-				fmt.Println("[CodexProvider] Attempting Azure Managed Identity authentication - codex_provider.go:585")
+				verbosef("[CodexProvider] Attempting Azure Managed Identity authentication - codex_provider.go:585\n")
 			}
 			tokenSource := createAzureManagedIdentityTokenSource(azureConfig)
 			token, accountID, err := tokenSource()
 			if err == nil && token != "" {
 				if azureConfig.Verbose {
-					fmt.Println("[CodexProvider] Successfully authenticated with Azure Managed Identity - codex_provider.go:591")
+					verbosef("[CodexProvider] Successfully authenticated with Azure Managed Identity - codex_provider.go:591\n")
 				}
 				return token, accountID, nil
 			}
 			if azureConfig.Verbose {
-				fmt.Printf("[CodexProvider] Azure Managed Identity failed: %v\n - codex_provider.go:596", err)
+				verbosef("[CodexProvider] Azure Managed Identity failed: %v\n - codex_provider.go:596", err)
 			}
 		}
 
 		// 2. Fallback to standard OpenAI authentication
-		cred, err := auth.GetCredential("openai")
+		cred, err := auth.GetCredential("openai", "")
 		if err != nil {
 			return "", "", fmt.Errorf("loading auth credentials: %w", err)
 		}
@@ -578,13 +740,9 @@ func createDynamicCodexTokenSource(azureConfig *AzureConfig) func() (string, str
 
 		// 3. Try OAuth token refresh if needed
 		if cred.AuthMethod == "oauth" && cred.NeedsRefresh() && cred.RefreshToken != "" {
-			oauthCfg := auth.OpenAIOAuthConfig()
-			refreshed, err := auth.RefreshAccessToken(cred, oauthCfg)
+			refreshed, err := auth.RefreshStoredCredential("openai", "", auth.OpenAIOAuthConfig())
 			if err != nil {
-				return "", "", fmt.Errorf("refreshing token: %w", err)
-			}
-			if err := auth.SetCredential("openai", refreshed); err != nil {
-				return "", "", fmt.Errorf("saving refreshed token: %w", err)
+				return "", "", err
 			}
 			return refreshed.AccessToken, refreshed.AccountID, nil
 		}
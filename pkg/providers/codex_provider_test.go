@@ -2,6 +2,7 @@ package providers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/openai/openai-go/v3"
 	openaiopt "github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/sipeed/picoclaw/pkg/auth"
 )
 
 func TestBuildCodexParams_BasicMessage(t *testing.T) {
@@ -63,6 +65,37 @@ func TestBuildCodexParams_ToolCallConversation(t *testing.T) {
 	}
 }
 
+func TestBuildCodexParams_Images(t *testing.T) {
+	messages := []Message{
+		NewMessage("user", TextPart("What is this?"), ImagePart("image/png", []byte("png")), ImageURLPart("https://example.com/cat.jpg")),
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "computer", Arguments: map[string]interface{}{"action": "screenshot"}}}},
+		NewMessage("tool", ToolResultPart("call_1", "Screenshot taken", false), ImagePart("image/png", []byte("shot"))),
+	}
+	items := buildCodexParams(messages, nil, "gpt-5", nil).Input.OfInputItemList
+	if len(items) != 4 {
+		t.Fatalf("len(Input items) = %d, want 4", len(items))
+	}
+
+	question := items[0].OfMessage.Content.OfInputItemContentList
+	if len(question) != 3 || question[0].OfInputText == nil || question[1].OfInputImage == nil {
+		t.Fatalf("question content = %+v", question)
+	}
+	if url := question[1].OfInputImage.ImageURL.Value; url != "data:image/png;base64,cG5n" {
+		t.Errorf("inline image URL = %q", url)
+	}
+	if url := question[2].OfInputImage.ImageURL.Value; url != "https://example.com/cat.jpg" {
+		t.Errorf("image URL = %q", url)
+	}
+
+	if items[2].OfFunctionCallOutput == nil || items[2].OfFunctionCallOutput.Output.OfString.Value != "Screenshot taken" {
+		t.Errorf("items[2] = %+v, want the tool result", items[2])
+	}
+	screenshot := items[3].OfMessage
+	if screenshot == nil || screenshot.Role != "user" || len(screenshot.Content.OfInputItemContentList) != 1 || screenshot.Content.OfInputItemContentList[0].OfInputImage == nil {
+		t.Errorf("items[3] = %+v, want the screenshot in a user message", items[3])
+	}
+}
+
 func TestBuildCodexParams_WithTools(t *testing.T) {
 	tools := []ToolDefinition{
 		{
@@ -140,6 +173,23 @@ func TestParseCodexResponse_TextOutput(t *testing.T) {
 	}
 }
 
+func TestParseCodexResponse_Incomplete(t *testing.T) {
+	for reason, want := range map[string]FinishReason{
+		"max_output_tokens": FinishReasonLength,
+		"content_filter":    FinishReasonContentFilter,
+	} {
+		var resp responses.Response
+		respJSON := `{"id": "resp_test", "status": "incomplete", "incomplete_details": {"reason": "` + reason + `"}, "output": []}`
+		if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		result := parseCodexResponse(&resp)
+		if result.FinishReason != want || result.RawFinishReason != reason {
+			t.Errorf("incomplete %s: FinishReason = %q (raw %q), want %q", reason, result.FinishReason, result.RawFinishReason, want)
+		}
+	}
+}
+
 func TestParseCodexResponse_FunctionCall(t *testing.T) {
 	respJSON := `{
 		"id": "resp_test",
@@ -188,6 +238,95 @@ func TestParseCodexResponse_FunctionCall(t *testing.T) {
 	}
 }
 
+func TestBuildCodexParams_WebSearch(t *testing.T) {
+	params := buildCodexParams([]Message{{Role: "user", Content: "News?"}}, nil, "gpt-5", map[string]interface{}{OptionWebSearch: true})
+	if len(params.Tools) != 1 || params.Tools[0].OfWebSearch == nil {
+		t.Fatalf("Tools = %+v, want the web_search tool", params.Tools)
+	}
+}
+
+func TestParseCodexResponse_WebSearch(t *testing.T) {
+	respJSON := `{
+		"id": "resp_test",
+		"object": "response",
+		"status": "completed",
+		"output": [
+			{"id": "ws_1", "type": "web_search_call", "status": "completed", "action": {"type": "search", "query": "go 1.25 release"}},
+			{
+				"id": "msg_1",
+				"type": "message",
+				"role": "assistant",
+				"status": "completed",
+				"content": [{
+					"type": "output_text",
+					"text": "Go 1.25 was released in August.",
+					"annotations": [
+						{"type": "url_citation", "url": "https://go.dev/blog/go1.25", "title": "Go 1.25 is released", "start_index": 0, "end_index": 31},
+						{"type": "url_citation", "url": "https://go.dev/blog/go1.25", "title": "Go 1.25 is released", "start_index": 0, "end_index": 5}
+					]
+				}]
+			}
+		]
+	}`
+
+	var resp responses.Response
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	result := parseCodexResponse(&resp)
+	if result.Content != "Go 1.25 was released in August." || len(result.Parts) != 2 {
+		t.Fatalf("Content = %q, Parts = %+v", result.Content, result.Parts)
+	}
+	if search := result.Parts[0]; search.Type != ContentServerToolUse || search.ToolCall.Name != "web_search" || search.ToolCall.Arguments["query"] != "go 1.25 release" {
+		t.Errorf("search = %+v", search.ToolCall)
+	}
+	if len(result.Citations) != 1 || result.Citations[0].URL != "https://go.dev/blog/go1.25" || result.Citations[0].Title != "Go 1.25 is released" {
+		t.Errorf("Citations = %+v", result.Citations)
+	}
+}
+
+func TestCodexEncryptedReasoning(t *testing.T) {
+	respJSON := `{
+		"id": "resp_test",
+		"object": "response",
+		"status": "completed",
+		"output": [
+			{"id": "rs_1", "type": "reasoning", "summary": [{"type": "summary_text", "text": "Need the weather."}], "encrypted_content": "gAAAA-secret"},
+			{"id": "fc_1", "type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{}", "status": "completed"}
+		]
+	}`
+	var resp responses.Response
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	result := parseCodexResponse(&resp)
+
+	msg := WithReasoning(Message{Role: "assistant", ToolCalls: result.ToolCalls}, result)
+	if len(msg.Parts) != 2 || msg.Parts[0].Type != ContentReasoning || msg.Parts[1].Type != ContentToolUse {
+		t.Fatalf("Parts = %+v, want the reasoning before the tool call", msg.Parts)
+	}
+
+	messages := []Message{{Role: "user", Content: "Weather?"}, msg, {Role: "tool", Content: "Sunny", ToolCallID: "call_1"}}
+	params := buildCodexParams(messages, nil, "gpt-5", nil)
+	if len(params.Include) != 1 || params.Include[0] != responses.ResponseIncludableReasoningEncryptedContent {
+		t.Errorf("Include = %v, want encrypted reasoning", params.Include)
+	}
+	data, _ := json.Marshal(params.Input.OfInputItemList[1])
+	var item map[string]interface{}
+	json.Unmarshal(data, &item)
+	if item["type"] != "reasoning" || item["id"] != "rs_1" || item["encrypted_content"] != "gAAAA-secret" {
+		t.Errorf("reasoning item = %s", data)
+	}
+	if summary, _ := item["summary"].([]interface{}); len(summary) != 1 {
+		t.Errorf("summary = %v, want one entry", item["summary"])
+	}
+
+	if params := buildCodexParams(messages, nil, "gpt-4o", nil); len(params.Include) != 0 {
+		t.Errorf("Include = %v for a model that does not reason", params.Include)
+	}
+}
+
 func TestCodexProvider_ChatRoundTrip(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/responses" {
@@ -250,6 +389,114 @@ func TestCodexProvider_ChatRoundTrip(t *testing.T) {
 	}
 }
 
+func TestCodexProvider_ChatStream(t *testing.T) {
+	var stream bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		stream = body.Stream
+		w.Header().Set("Content-Type", "text/event-stream")
+		call := map[string]interface{}{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "get_weather", "arguments": "", "status": "in_progress"}
+		done := map[string]interface{}{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "get_weather", "arguments": `{"city":"Oslo"}`, "status": "completed"}
+		message := map[string]interface{}{"type": "message", "id": "msg_1", "role": "assistant", "status": "completed",
+			"content": []map[string]interface{}{{"type": "output_text", "text": "Checking.", "annotations": []interface{}{}}}}
+		for _, ev := range []map[string]interface{}{
+			{"type": "response.output_text.delta", "output_index": 0, "delta": "Check"},
+			{"type": "response.output_text.delta", "output_index": 0, "delta": "ing."},
+			{"type": "response.output_item.done", "output_index": 0, "item": message},
+			{"type": "response.output_item.added", "output_index": 1, "item": call},
+			{"type": "response.function_call_arguments.delta", "output_index": 1, "delta": `{"city":`},
+			{"type": "response.function_call_arguments.delta", "output_index": 1, "delta": `"Oslo"}`},
+			{"type": "response.output_item.done", "output_index": 1, "item": done},
+			{"type": "response.completed", "response": map[string]interface{}{
+				"id": "resp_1", "object": "response", "status": "completed", "output": []interface{}{},
+				"usage": map[string]interface{}{"input_tokens": 5, "output_tokens": 3, "total_tokens": 8},
+			}},
+		} {
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev["type"], data)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewCodexProvider("test-token", "")
+	provider.client = createOpenAITestClient(server.URL, "test-token", "")
+
+	events, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "Weather in Oslo?"}}, nil, "gpt-5", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+	var text string
+	var kinds []StreamEventType
+	resp, err := ConsumeStream(events, func(ev StreamEvent) {
+		kinds = append(kinds, ev.Type)
+		text += ev.Text
+	})
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if !stream {
+		t.Error("request did not ask for a stream")
+	}
+	if text != "Checking." {
+		t.Errorf("streamed text = %q, want %q", text, "Checking.")
+	}
+	want := []StreamEventType{StreamEventText, StreamEventText, StreamEventToolCallStart, StreamEventToolCallDelta, StreamEventToolCallDelta, StreamEventToolCallDone}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", kinds, want)
+	}
+	if resp.Content != "Checking." || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["city"] != "Oslo" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.FinishReason != FinishReasonToolCalls || resp.Usage.TotalTokens != 8 {
+		t.Errorf("FinishReason = %q, Usage = %+v", resp.FinishReason, resp.Usage)
+	}
+}
+
+func TestCodexProvider_AccountOption(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.SetCredential("openai", "work", &auth.AuthCredential{
+		AccessToken: "work-token",
+		AccountID:   "acc-work",
+		Provider:    "openai",
+		AuthMethod:  "token",
+	}); err != nil {
+		t.Fatalf("SetCredential: %v", err)
+	}
+
+	var token, accountID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, accountID = r.Header.Get("Authorization"), r.Header.Get("Chatgpt-Account-Id")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "resp_test", "object": "response", "status": "completed", "output": []interface{}{}})
+	}))
+	defer server.Close()
+
+	provider := NewCodexProvider("personal-token", "acc-personal")
+	provider.client = createOpenAITestClient(server.URL, "personal-token", "acc-personal")
+	messages := []Message{{Role: "user", Content: "Hello"}}
+
+	if _, err := provider.Chat(t.Context(), messages, nil, "gpt-5", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if token != "Bearer personal-token" || accountID != "acc-personal" {
+		t.Errorf("default account sent %q, %q", token, accountID)
+	}
+
+	if _, err := provider.Chat(t.Context(), messages, nil, "gpt-5", map[string]interface{}{OptionAccount: "work"}); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if token != "Bearer work-token" || accountID != "acc-work" {
+		t.Errorf("work account sent %q, %q", token, accountID)
+	}
+
+	if _, err := provider.Chat(t.Context(), messages, nil, "gpt-5", map[string]interface{}{OptionAccount: "missing"}); err == nil {
+		t.Error("Chat() with an unknown account succeeded")
+	}
+}
+
 func TestCodexProvider_GetDefaultModel(t *testing.T) {
 	p := NewCodexProvider("test-token", "")
 	if got := p.GetDefaultModel(); got != "gpt-4o" {
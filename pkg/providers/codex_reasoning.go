@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// codexReasons reports whether model is a reasoning model, whose encrypted
+// reasoning is asked for so it can be replayed in the next request.
+func codexReasons(model string) bool {
+	entry, ok := LookupModel(model)
+	return ok && slices.Contains(entry.Capabilities, CapabilityReasoning)
+}
+
+// codexReasoningPart keeps a reasoning output item, with its summary as
+// the part's text.
+func codexReasoningPart(item responses.ResponseOutputItemUnion) ContentPart {
+	var summary []string
+	for _, s := range item.Summary {
+		summary = append(summary, s.Text)
+	}
+	return ReasoningPart(item.ID, strings.Join(summary, "\n\n"), item.EncryptedContent)
+}
+
+// codexReasoningItem replays a reasoning part as an input item.
+func codexReasoningItem(part ContentPart) responses.ResponseInputItemUnionParam {
+	summary := []responses.ResponseReasoningItemSummaryParam{}
+	if part.Text != "" {
+		summary = append(summary, responses.ResponseReasoningItemSummaryParam{Text: part.Text})
+	}
+	return responses.ResponseInputItemUnionParam{
+		OfReasoning: &responses.ResponseReasoningItemParam{
+			ID:               part.ID,
+			Summary:          summary,
+			EncryptedContent: openai.Opt(part.Signature),
+		},
+	}
+}
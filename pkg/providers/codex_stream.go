@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// ChatStream streams a response through the Responses API, which the
+// ChatGPT Codex backend and the standard OpenAI endpoint both serve. Azure
+// deployments use Chat Completions and are sent their whole reply at once.
+func (p *CodexProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	if p.azureConfig != nil {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		return replayResponse(resp), nil
+	}
+
+	if dryRun(options) {
+		// The SDK would report the dry run as the stream's error
+		_, err := p.Chat(ctx, messages, tools, model, options)
+		return nil, err
+	}
+
+	opts, err := p.authOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return streamResponses(ctx, p.client, buildCodexParams(messages, tools, model, options), opts, "codex"), nil
+}
+
+// streamResponses streams a Responses API request, naming the API in
+// errors as label.
+func streamResponses(ctx context.Context, client *openai.Client, params responses.ResponseNewParams, opts []option.RequestOption, label string) <-chan StreamEvent {
+	timer := newStreamTimer()
+	var httpResp *http.Response
+	opts = append(opts, option.WithResponseInto(&httpResp))
+	stream := client.Responses.NewStreaming(ctx, params, opts...)
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer stream.Close()
+
+		toolCalls := newToolCallStream()
+		// The Codex backend does not repeat the output in the final event
+		// when nothing is stored, so finished items are kept as they come
+		var items []responses.ResponseOutputItemUnion
+		var final *responses.Response
+		for stream.Next() {
+			event := stream.Current()
+
+			var out []StreamEvent
+			switch event.Type {
+			case "response.output_text.delta", "response.refusal.delta":
+				if event.Delta != "" {
+					out = []StreamEvent{{Type: StreamEventText, Text: event.Delta}}
+				}
+			case "response.output_item.added":
+				if event.Item.Type == "function_call" {
+					out = toolCalls.start(int(event.OutputIndex), event.Item.CallID, event.Item.Name)
+				}
+			case "response.function_call_arguments.delta":
+				out = toolCalls.add(int(event.OutputIndex), event.Delta)
+			case "response.output_item.done":
+				items = append(items, event.Item)
+				if event.Item.Type == "function_call" {
+					out = toolCalls.finish(int(event.OutputIndex))
+				}
+			case "response.completed", "response.incomplete":
+				resp := event.Response
+				final = &resp
+			case "response.failed":
+				msg := event.Response.Error.Message
+				if msg == "" {
+					msg = "response failed"
+				}
+				sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("%s stream: %s", label, msg)})
+				return
+			case "error":
+				sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("%s stream: %s (%s)", label, event.Message, event.Code)})
+				return
+			}
+			for _, e := range out {
+				timer.observe(e)
+				if !sendStreamEvent(ctx, events, e) {
+					return
+				}
+			}
+		}
+		if err := stream.Err(); err != nil {
+			sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("%s API call: %w", label, sdkAPIError(err))})
+			return
+		}
+		if final == nil {
+			sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: fmt.Errorf("%s stream ended without a response", label)})
+			return
+		}
+		if len(final.Output) == 0 {
+			final.Output = items
+		}
+		llmResp := parseCodexResponse(final)
+		setResponseMetadata(llmResp, httpResp)
+		timer.finish(llmResp)
+		sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventDone, Response: llmResp})
+	}()
+	return events
+}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// OptionWebSearch (a bool) offers the Responses API's built-in web_search
+// tool, which OpenAI runs itself; its searches come back as server tool
+// parts and the pages the answer cites as Citations.
+const OptionWebSearch = "web_search"
+
+// codexWebSearchPart converts a web_search_call output item to a server
+// tool part holding what it did: a query, or a page it opened or searched.
+func codexWebSearchPart(item responses.ResponseOutputItemUnion) ContentPart {
+	arguments := map[string]interface{}{}
+	switch action := item.Action; action.Type {
+	case "search":
+		arguments["query"] = action.Query
+	case "open_page", "find":
+		arguments["url"] = action.URL
+		if action.Pattern != "" {
+			arguments["pattern"] = action.Pattern
+		}
+	}
+	return ContentPart{
+		Type:     ContentServerToolUse,
+		ToolCall: &ToolCall{ID: item.ID, Type: item.Type, Name: "web_search", Arguments: arguments},
+	}
+}
+
+// codexCitations returns the URL citations of an output_text item, leaving
+// out pages already in seen.
+func codexCitations(annotations []responses.ResponseOutputTextAnnotationUnion, seen map[string]bool) []Citation {
+	var citations []Citation
+	for _, a := range annotations {
+		if a.Type != "url_citation" || a.URL == "" || seen[a.URL] {
+			continue
+		}
+		seen[a.URL] = true
+		citations = append(citations, Citation{URL: a.URL, Title: a.Title})
+	}
+	return citations
+}
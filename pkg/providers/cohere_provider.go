@@ -0,0 +1,296 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const cohereDefaultAPIBase = "https://api.cohere.com/v2"
+
+// OptionDocuments (a []Document) grounds the response in the documents.
+// Cohere answers from them and reports the passages it used as
+// LLMResponse.Citations; other providers ignore it.
+const OptionDocuments = "documents"
+
+// Document is a text a response can be grounded in and cite.
+type Document struct {
+	// ID is reported back in the citations of the document; Cohere numbers
+	// documents without one.
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Text  string `json:"text"`
+}
+
+// CohereProvider calls Cohere's v2 chat API.
+type CohereProvider struct {
+	apiKey     string
+	apiBase    string
+	httpClient *http.Client
+	defaultModel
+}
+
+func NewCohereProvider(apiKey, apiBase, proxy string) *CohereProvider {
+	if apiBase == "" {
+		apiBase = cohereDefaultAPIBase
+	}
+	client := &http.Client{Timeout: 120 * time.Second}
+	if proxy != "" {
+		if proxyURL, err := url.Parse(proxy); err == nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		}
+	}
+	return &CohereProvider{
+		apiKey:     apiKey,
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		httpClient: client,
+	}
+}
+
+func (p *CohereProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	jsonData, err := json.Marshal(buildCohereRequest(messages, tools, model, options))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiBase+"/chat", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if dryRun(options) {
+		return nil, newDryRunError(req)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, body)
+	}
+
+	llmResp, err := parseCohereResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	setResponseMetadata(llmResp, resp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// ChatStream sends the whole reply at once.
+func (p *CohereProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+func (p *CohereProvider) GetDefaultModel() string {
+	return p.defaultModel.or("command-a-03-2025")
+}
+
+// buildCohereRequest builds the body of a v2 chat request.
+func buildCohereRequest(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    strings.TrimPrefix(model, "cohere/"),
+		"messages": cohereMessages(messages),
+	}
+	if len(tools) > 0 {
+		wire := make([]map[string]interface{}, 0, len(tools))
+		for _, t := range tools {
+			wire = append(wire, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        t.Function.Name,
+					"description": t.Function.Description,
+					"parameters":  t.Function.Parameters,
+				},
+			})
+		}
+		body["tools"] = wire
+	}
+	if docs, ok := options[OptionDocuments].([]Document); ok && len(docs) > 0 {
+		wire := make([]map[string]interface{}, 0, len(docs))
+		for _, d := range docs {
+			data := map[string]string{"text": d.Text}
+			if d.Title != "" {
+				data["title"] = d.Title
+			}
+			if d.URL != "" {
+				data["url"] = d.URL
+			}
+			doc := map[string]interface{}{"data": data}
+			if d.ID != "" {
+				doc["id"] = d.ID
+			}
+			wire = append(wire, doc)
+		}
+		body["documents"] = wire
+	}
+
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		body["max_tokens"] = maxTokens
+	}
+	if temperature, ok := options["temperature"].(float64); ok {
+		body["temperature"] = temperature
+	}
+	if topP, ok := options["top_p"].(float64); ok {
+		body["p"] = topP
+	}
+	if topK, ok := options[OptionTopK].(int); ok && topK > 0 {
+		body["k"] = topK
+	}
+	if stop, ok := options[OptionStop].([]string); ok && len(stop) > 0 {
+		body["stop_sequences"] = stop
+	}
+	return body
+}
+
+// cohereMessages converts messages to v2 chat messages. Cohere takes text
+// and images; other parts are left out.
+func cohereMessages(messages []Message) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		var content []map[string]interface{}
+		var toolCalls []map[string]interface{}
+		for _, part := range msg.ContentParts() {
+			switch part.Type {
+			case ContentText:
+				if part.Text != "" {
+					content = append(content, map[string]interface{}{"type": "text", "text": part.Text})
+				}
+			case ContentImage:
+				content = append(content, map[string]interface{}{
+					"type":      "image_url",
+					"image_url": map[string]interface{}{"url": part.dataURL()},
+				})
+			case ContentToolUse:
+				args, _ := json.Marshal(part.ToolCall.Arguments)
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"id":       part.ToolCall.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": part.ToolCall.Name, "arguments": string(args)},
+				})
+			case ContentToolResult:
+				out = append(out, map[string]interface{}{"role": "tool", "tool_call_id": part.ToolCallID, "content": part.Text})
+			}
+		}
+		if len(content) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		wire := map[string]interface{}{"role": msg.Role}
+		if len(content) > 0 {
+			wire["content"] = content
+		}
+		if len(toolCalls) > 0 {
+			wire["tool_calls"] = toolCalls
+		}
+		out = append(out, wire)
+	}
+	return out
+}
+
+type cohereResponse struct {
+	FinishReason string `json:"finish_reason"`
+	Message      struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		ToolCalls []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+		Citations []cohereCitation `json:"citations"`
+	} `json:"message"`
+	Usage struct {
+		Tokens struct {
+			InputTokens  float64 `json:"input_tokens"`
+			OutputTokens float64 `json:"output_tokens"`
+		} `json:"tokens"`
+	} `json:"usage"`
+}
+
+// cohereCitation is a span of the answer and the documents or tool
+// results it came from.
+type cohereCitation struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Sources []struct {
+		Type     string                 `json:"type"`
+		ID       string                 `json:"id"`
+		Document map[string]interface{} `json:"document"`
+	} `json:"sources"`
+}
+
+func parseCohereResponse(body []byte) (*LLMResponse, error) {
+	var resp cohereResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var texts []string
+	for _, c := range resp.Message.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+
+	var toolCalls []ToolCall
+	for _, tc := range resp.Message.ToolCalls {
+		toolCalls = append(toolCalls, newToolCall(tc.ID, tc.Function.Name, tc.Function.Arguments))
+	}
+
+	var citations []Citation
+	for _, c := range resp.Message.Citations {
+		for _, source := range c.Sources {
+			citation := Citation{SourceID: source.ID, Text: c.Text, Start: c.Start, End: c.End}
+			if source.Type == "document" {
+				citation.URL, _ = source.Document["url"].(string)
+				citation.Title, _ = source.Document["title"].(string)
+				if id, _ := source.Document["id"].(string); id != "" {
+					citation.SourceID = id
+				}
+			}
+			citations = append(citations, citation)
+		}
+	}
+
+	var usage *UsageInfo
+	if tokens := resp.Usage.Tokens; tokens.InputTokens > 0 || tokens.OutputTokens > 0 {
+		usage = &UsageInfo{
+			PromptTokens:     int(tokens.InputTokens),
+			CompletionTokens: int(tokens.OutputTokens),
+			TotalTokens:      int(tokens.InputTokens + tokens.OutputTokens),
+		}
+	}
+
+	return &LLMResponse{
+		Content:         strings.Join(texts, ""),
+		ToolCalls:       toolCalls,
+		FinishReason:    normalizeFinishReason(resp.FinishReason),
+		RawFinishReason: resp.FinishReason,
+		Usage:           usage,
+		Citations:       citations,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCohereProvider_Documents(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/chat" || r.Header.Get("Authorization") != "Bearer co-test" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{
+			"id": "gen-1",
+			"finish_reason": "COMPLETE",
+			"message": {
+				"role": "assistant",
+				"content": [{"type": "text", "text": "Emperor penguins are the tallest."}],
+				"citations": [{
+					"start": 0, "end": 16, "text": "Emperor penguins",
+					"sources": [{"type": "document", "id": "penguins",
+						"document": {"id": "penguins", "title": "Tall penguins", "url": "https://example.com/penguins", "text": "Emperor penguins are the tallest."}}]
+				}]
+			},
+			"usage": {"tokens": {"input_tokens": 120, "output_tokens": 8}}
+		}`))
+	}))
+	defer server.Close()
+
+	p := NewCohereProvider("co-test", server.URL+"/v2", "")
+	docs := []Document{{ID: "penguins", Title: "Tall penguins", URL: "https://example.com/penguins", Text: "Emperor penguins are the tallest."}}
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Which penguins are the tallest?"}}, nil, "command-a-03-2025",
+		map[string]interface{}{OptionDocuments: docs, "top_p": 0.9})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	sent, _ := body["documents"].([]interface{})
+	if len(sent) != 1 || sent[0].(map[string]interface{})["id"] != "penguins" {
+		t.Errorf("documents = %v, want the penguins document", body["documents"])
+	}
+	if body["p"] != 0.9 {
+		t.Errorf("p = %v, want 0.9", body["p"])
+	}
+
+	if resp.Content != "Emperor penguins are the tallest." || resp.FinishReason != FinishReasonStop {
+		t.Errorf("response = %q (%s), want the grounded answer", resp.Content, resp.FinishReason)
+	}
+	want := []Citation{{URL: "https://example.com/penguins", Title: "Tall penguins", SourceID: "penguins", Text: "Emperor penguins", Start: 0, End: 16}}
+	if !reflect.DeepEqual(resp.Citations, want) {
+		t.Errorf("Citations = %+v, want %+v", resp.Citations, want)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 128 {
+		t.Errorf("Usage = %+v, want 128 total tokens", resp.Usage)
+	}
+}
+
+func TestCohereProvider_ToolUse(t *testing.T) {
+	var body struct {
+		Messages []map[string]interface{} `json:"messages"`
+		Tools    []map[string]interface{} `json:"tools"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{
+			"finish_reason": "TOOL_CALL",
+			"message": {
+				"role": "assistant",
+				"tool_plan": "I will check the weather.",
+				"tool_calls": [{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Oslo\"}"}}]
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	p := NewCohereProvider("co-test", server.URL, "")
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "get_weather", Parameters: map[string]interface{}{"type": "object"}}}}
+	messages := []Message{
+		{Role: "user", Content: "Weather in Bergen and Oslo?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{"city": "Bergen"}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "Rain"},
+	}
+	resp, err := p.Chat(context.Background(), messages, tools, "command-a-03-2025", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if len(body.Tools) != 1 || len(body.Messages) != 3 {
+		t.Fatalf("sent %d tools and %d messages, want 1 and 3", len(body.Tools), len(body.Messages))
+	}
+	if calls, _ := body.Messages[1]["tool_calls"].([]interface{}); len(calls) != 1 {
+		t.Errorf("assistant message = %v, want its tool call", body.Messages[1])
+	}
+	if body.Messages[2]["role"] != "tool" || body.Messages[2]["tool_call_id"] != "call_1" {
+		t.Errorf("tool message = %v, want the result of call_1", body.Messages[2])
+	}
+
+	if resp.FinishReason != FinishReasonToolCalls {
+		t.Errorf("FinishReason = %s, want tool_calls", resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_2" || resp.ToolCalls[0].Arguments["city"] != "Oslo" {
+		t.Errorf("ToolCalls = %+v, want get_weather for Oslo", resp.ToolCalls)
+	}
+}
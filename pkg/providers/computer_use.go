@@ -0,0 +1,37 @@
+package providers
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+)
+
+// Anthropic's computer-use tool and the beta it needs.
+const (
+	claudeComputerToolType = "computer_20250124"
+	claudeComputerUseBeta  = "computer-use-2025-01-24"
+)
+
+// ComputerDisplay is the screen a computer-use tool sees and drives.
+// Number is the X11 display number, or 0 when there is none.
+type ComputerDisplay struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Number int `json:"number,omitempty"`
+}
+
+// claudeComputerTool sends a tool with a ComputerDisplay as Anthropic's
+// built-in computer-use tool, whose schema the model already knows. The
+// SDK's stable tool union has no variant for it, so it goes as raw JSON.
+func claudeComputerTool(t ToolDefinition) anthropic.ToolUnionParam {
+	raw := map[string]interface{}{
+		"type":              claudeComputerToolType,
+		"name":              t.Function.Name,
+		"display_width_px":  t.Computer.Width,
+		"display_height_px": t.Computer.Height,
+	}
+	if t.Computer.Number > 0 {
+		raw["display_number"] = t.Computer.Number
+	}
+	tool := param.Override[anthropic.ToolParam](raw)
+	return anthropic.ToolUnionParam{OfTool: &tool}
+}
@@ -0,0 +1,182 @@
+package providers
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// ContentPartType is the kind of a ContentPart.
+type ContentPartType string
+
+const (
+	ContentText       ContentPartType = "text"
+	ContentImage      ContentPartType = "image"
+	ContentDocument   ContentPartType = "document"
+	ContentAudio      ContentPartType = "audio"
+	ContentToolUse    ContentPartType = "tool_use"
+	ContentToolResult ContentPartType = "tool_result"
+	ContentThinking   ContentPartType = "thinking"
+	// ContentReasoning is an OpenAI reasoning model's reasoning, encrypted,
+	// for the model to continue from in a later request. Other providers
+	// leave it out.
+	ContentReasoning ContentPartType = "reasoning"
+
+	// Tools the provider ran itself, such as Anthropic's code execution.
+	// Responses report them; they are not sent back to the model.
+	ContentServerToolUse    ContentPartType = "server_tool_use"
+	ContentServerToolResult ContentPartType = "server_tool_result"
+)
+
+// ContentPart is one piece of a message: text, an image, document or
+// audio, a tool call or its result, or the model's thinking. Build parts
+// with the constructors below.
+type ContentPart struct {
+	Type ContentPartType `json:"type"`
+	// Text is the text, the thinking, or the tool result.
+	Text string `json:"text,omitempty"`
+	// Images and documents carry either inline Data of MediaType or a URL,
+	// audio inline Data. The audio of a reply has its transcript as Text.
+	MediaType string `json:"media_type,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	// Name is the file name of a document.
+	Name       string    `json:"name,omitempty"`
+	ToolCall   *ToolCall `json:"tool_call,omitempty"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	IsError    bool      `json:"is_error,omitempty"`
+	// Signature verifies thinking returned to the model that produced it;
+	// for reasoning it is the encrypted reasoning itself.
+	Signature string `json:"signature,omitempty"`
+	// ID identifies a reasoning item or the audio of a reply.
+	ID string `json:"id,omitempty"`
+}
+
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentText, Text: text}
+}
+
+// ImagePart is an inline image, such as a PNG or JPEG.
+func ImagePart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentImage, MediaType: mediaType, Data: data}
+}
+
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentImage, URL: url}
+}
+
+// DocumentPart is an inline file, such as a PDF or plain text.
+func DocumentPart(name, mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentDocument, Name: name, MediaType: mediaType, Data: data}
+}
+
+// AudioPart is an inline recording, such as a WAV or MP3 file.
+func AudioPart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentAudio, MediaType: mediaType, Data: data}
+}
+
+func ToolUsePart(call ToolCall) ContentPart {
+	return ContentPart{Type: ContentToolUse, ToolCall: &call}
+}
+
+func ToolResultPart(toolCallID, content string, isError bool) ContentPart {
+	return ContentPart{Type: ContentToolResult, ToolCallID: toolCallID, Text: content, IsError: isError}
+}
+
+func ThinkingPart(text, signature string) ContentPart {
+	return ContentPart{Type: ContentThinking, Text: text, Signature: signature}
+}
+
+// ReasoningPart is encrypted reasoning with its item ID and summary.
+func ReasoningPart(id, summary, encrypted string) ContentPart {
+	return ContentPart{Type: ContentReasoning, ID: id, Text: summary, Signature: encrypted}
+}
+
+// dataURL returns the part's URL, or its data inlined as a data: URL.
+func (p ContentPart) dataURL() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return "data:" + p.MediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// NewMessage builds a message from parts. Content, ToolCalls and
+// ToolCallID mirror the parts for code that only reads those.
+func NewMessage(role string, parts ...ContentPart) Message {
+	msg := Message{Role: role, Parts: parts}
+	var texts []string
+	for _, part := range parts {
+		switch part.Type {
+		case ContentText:
+			texts = append(texts, part.Text)
+		case ContentToolUse:
+			msg.ToolCalls = append(msg.ToolCalls, *part.ToolCall)
+		case ContentToolResult:
+			texts = append(texts, part.Text)
+			if msg.ToolCallID == "" {
+				msg.ToolCallID = part.ToolCallID
+			}
+		}
+	}
+	msg.Content = strings.Join(texts, "\n")
+	return msg
+}
+
+// ContentParts returns the message's parts, deriving them from Content,
+// ToolCalls and ToolCallID for messages built without any.
+func (m Message) ContentParts() []ContentPart {
+	if len(m.Parts) > 0 {
+		return m.Parts
+	}
+	if m.Role == "tool" || m.ToolCallID != "" {
+		return []ContentPart{ToolResultPart(m.ToolCallID, m.Content, false)}
+	}
+	var parts []ContentPart
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		parts = append(parts, TextPart(m.Content))
+	}
+	for _, tc := range m.ToolCalls {
+		parts = append(parts, ToolUsePart(tc))
+	}
+	return parts
+}
+
+// textContent joins the message's text parts and the transcripts of its
+// audio.
+func (m Message) textContent() string {
+	var texts []string
+	for _, part := range m.ContentParts() {
+		if part.Type == ContentText || part.Type == ContentAudio && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// WithReasoning returns msg, built from resp, with the reasoning parts of
+// resp before its content, so a reasoning model keeps its chain of thought
+// across tool calls. msg is returned as is when resp has none.
+func WithReasoning(msg Message, resp *LLMResponse) Message {
+	var parts []ContentPart
+	for _, part := range resp.Parts {
+		if part.Type == ContentReasoning {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return msg
+	}
+	msg.Parts = append(parts, msg.ContentParts()...)
+	return msg
+}
+
+// HasImages reports whether any message includes an image.
+func HasImages(messages []Message) bool {
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if part.Type == ContentImage {
+				return true
+			}
+		}
+	}
+	return false
+}
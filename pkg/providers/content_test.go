@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewMessage_MirrorsParts(t *testing.T) {
+	msg := NewMessage("assistant", TextPart("Checking."), ToolUsePart(ToolCall{ID: "call_1", Name: "read_file"}))
+	if msg.Content != "Checking." || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "call_1" {
+		t.Errorf("NewMessage() = %+v, want the text and tool call mirrored", msg)
+	}
+
+	result := NewMessage("tool", ToolResultPart("call_1", "contents", false))
+	if result.Content != "contents" || result.ToolCallID != "call_1" {
+		t.Errorf("NewMessage() = %+v, want the tool result mirrored", result)
+	}
+}
+
+func TestContentParts_DerivedFromFields(t *testing.T) {
+	tests := []struct {
+		msg  Message
+		want []ContentPartType
+	}{
+		{Message{Role: "user", Content: "hi"}, []ContentPartType{ContentText}},
+		{Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "1"}, {ID: "2"}}}, []ContentPartType{ContentToolUse, ContentToolUse}},
+		{Message{Role: "assistant", Content: "ok", ToolCalls: []ToolCall{{ID: "1"}}}, []ContentPartType{ContentText, ContentToolUse}},
+		{Message{Role: "tool", Content: "42", ToolCallID: "1"}, []ContentPartType{ContentToolResult}},
+	}
+	for _, tt := range tests {
+		parts := tt.msg.ContentParts()
+		if len(parts) != len(tt.want) {
+			t.Errorf("ContentParts(%+v) = %+v, want %v", tt.msg, parts, tt.want)
+			continue
+		}
+		for i, part := range parts {
+			if part.Type != tt.want[i] {
+				t.Errorf("ContentParts(%+v)[%d] = %s, want %s", tt.msg, i, part.Type, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestOpenAIMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "plain"},
+		NewMessage("user", TextPart("Describe"), ImagePart("image/png", []byte("png"))),
+		NewMessage("assistant", TextPart("Reading."), ToolUsePart(ToolCall{ID: "call_1", Name: "read_file", Arguments: map[string]interface{}{"path": "a"}})),
+		NewMessage("user", ToolResultPart("call_1", "contents", false), TextPart("And?")),
+	}
+	data, _ := json.Marshal(openAIMessages(messages))
+	want := `[{"role":"user","content":"plain"},` +
+		`{"content":[{"text":"Describe","type":"text"},{"image_url":{"url":"data:image/png;base64,cG5n"},"type":"image_url"}],"role":"user"},` +
+		`{"content":"Reading.","role":"assistant","tool_calls":[{"function":{"arguments":"{\"path\":\"a\"}","name":"read_file"},"id":"call_1","type":"function"}]},` +
+		`{"content":"contents","role":"tool","tool_call_id":"call_1"},` +
+		`{"content":[{"text":"And?","type":"text"}],"role":"user"}]`
+	if string(data) != want {
+		t.Errorf("openAIMessages() =\n%s\nwant\n%s", data, want)
+	}
+	if !HasImages(messages) || HasImages(messages[:1]) {
+		t.Error("HasImages() did not find the image part")
+	}
+}
+
+func TestOpenAIMessages_Names(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "hi", Name: "Ana María", CreatedAt: time.Now(), Metadata: map[string]string{"sender_id": "42"}},
+		NewMessage("user", TextPart("hello")),
+	}
+	messages[1].Name = "bob"
+	data, _ := json.Marshal(openAIMessages(messages))
+	want := `[{"role":"user","content":"hi","name":"Ana_Mar_a"},{"content":[{"text":"hello","type":"text"}],"name":"bob","role":"user"}]`
+	if string(data) != want {
+		t.Errorf("openAIMessages() =\n%s\nwant\n%s", data, want)
+	}
+}
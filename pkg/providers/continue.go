@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"context"
+	"strconv"
+)
+
+// OptionAutoContinue (an int) is how many follow-up requests
+// ChatAutoContinue makes to finish a response cut off by max_tokens.
+const OptionAutoContinue = "auto_continue"
+
+// MetadataContinuations is the response metadata counting the follow-up
+// requests stitched into a response.
+const MetadataContinuations = "continuations"
+
+// continuePrompt asks the model to carry on from the cut off text.
+const continuePrompt = "Continue exactly where you left off, without repeating anything."
+
+// ChatAutoContinue calls provider.Chat and, while the response stops at the
+// token limit and OptionAutoContinue allows more rounds, asks the model to
+// continue: the text so far is sent back as the assistant's reply followed
+// by a request to go on. The pieces are joined into one response whose
+// usage adds up every call. A response that ends with tool calls is not
+// continued. Without the option this is provider.Chat.
+func ChatAutoContinue(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := provider.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	rounds, _ := options[OptionAutoContinue].(int)
+	for continuations := 1; continuations <= rounds && resp.FinishReason == FinishReasonLength && len(resp.ToolCalls) == 0; continuations++ {
+		continued := append(append([]Message(nil), messages...),
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: continuePrompt})
+		next, err := provider.Chat(ctx, continued, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		resp = mergeContinuation(resp, next)
+		if resp.Metadata == nil {
+			resp.Metadata = map[string]string{}
+		}
+		resp.Metadata[MetadataContinuations] = strconv.Itoa(continuations)
+	}
+	return resp, nil
+}
+
+// mergeContinuation appends next, which continues resp, to it: text, parts
+// and citations are joined, usage is added up, and how the response ended
+// is taken from next.
+func mergeContinuation(resp, next *LLMResponse) *LLMResponse {
+	merged := *next
+	merged.Content = resp.Content + next.Content
+	merged.Parts = append(append([]ContentPart(nil), resp.Parts...), next.Parts...)
+	merged.Citations = append(append([]Citation(nil), resp.Citations...), next.Citations...)
+	if resp.Usage != nil || next.Usage != nil {
+		usage := UsageInfo{}
+		usage.Add(resp.Usage)
+		usage.Add(next.Usage)
+		merged.Usage = &usage
+	}
+	merged.Metadata = make(map[string]string, len(resp.Metadata)+len(next.Metadata))
+	for _, m := range []map[string]string{resp.Metadata, next.Metadata} {
+		for k, v := range m {
+			merged.Metadata[k] = v
+		}
+	}
+	return &merged
+}
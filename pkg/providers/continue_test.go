@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"context"
+	"testing"
+)
+
+// truncatingProvider answers from replies in turn and records the
+// conversations it was sent.
+type truncatingProvider struct {
+	staticProvider
+	replies  []*LLMResponse
+	requests [][]Message
+}
+
+func (p *truncatingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.requests = append(p.requests, messages)
+	resp := p.replies[0]
+	p.replies = p.replies[1:]
+	return resp, nil
+}
+
+func TestChatAutoContinue(t *testing.T) {
+	provider := &truncatingProvider{replies: []*LLMResponse{
+		{Content: "The quick brown ", FinishReason: FinishReasonLength, Usage: &UsageInfo{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}},
+		{Content: "fox jumps ", FinishReason: FinishReasonLength, Usage: &UsageInfo{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}},
+		{Content: "over the dog.", FinishReason: FinishReasonStop, Usage: &UsageInfo{PromptTokens: 30, CompletionTokens: 4, TotalTokens: 34}},
+	}}
+	messages := []Message{{Role: "user", Content: "Write the pangram"}}
+
+	resp, err := ChatAutoContinue(context.Background(), provider, messages, nil, "static", map[string]interface{}{OptionAutoContinue: 5})
+	if err != nil {
+		t.Fatalf("ChatAutoContinue() error: %v", err)
+	}
+	if resp.Content != "The quick brown fox jumps over the dog." || resp.FinishReason != FinishReasonStop {
+		t.Errorf("response = %q (%s), want the stitched text", resp.Content, resp.FinishReason)
+	}
+	if resp.Usage.PromptTokens != 60 || resp.Usage.CompletionTokens != 11 || resp.Usage.TotalTokens != 71 {
+		t.Errorf("Usage = %+v, want the sum of the three calls", resp.Usage)
+	}
+	if resp.Metadata[MetadataContinuations] != "2" {
+		t.Errorf("Metadata = %v, want 2 continuations", resp.Metadata)
+	}
+	last := provider.requests[2]
+	if len(last) != 3 || last[1].Role != "assistant" || last[1].Content != "The quick brown fox jumps " {
+		t.Errorf("last request = %+v, want the text so far as the assistant's reply", last)
+	}
+}
+
+func TestChatAutoContinue_Cap(t *testing.T) {
+	provider := &truncatingProvider{replies: []*LLMResponse{
+		{Content: "one ", FinishReason: FinishReasonLength},
+		{Content: "two ", FinishReason: FinishReasonLength},
+	}}
+
+	resp, err := ChatAutoContinue(context.Background(), provider, nil, nil, "static", map[string]interface{}{OptionAutoContinue: 1})
+	if err != nil {
+		t.Fatalf("ChatAutoContinue() error: %v", err)
+	}
+	if resp.Content != "one two " || resp.FinishReason != FinishReasonLength || len(provider.requests) != 2 {
+		t.Errorf("response = %q (%s) after %d requests, want one continuation", resp.Content, resp.FinishReason, len(provider.requests))
+	}
+}
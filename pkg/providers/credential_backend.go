@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// newCredentialBackend builds the secret manager selected in the config, or
+// returns nil when none is configured.
+func newCredentialBackend(cfg config.CredentialsConfig) (auth.CredentialBackend, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", "file":
+		return nil, nil
+	case "vault":
+		v := cfg.Vault
+		return auth.NewVaultBackend(v.Address, v.Token, v.Namespace, v.Mount), nil
+	case "aws":
+		return auth.NewAWSSecretsBackend(cfg.AWS.Region, cfg.AWS.RoleARN), nil
+	case "gcp":
+		return auth.NewGCPSecretsBackend(cfg.GCP.Project), nil
+	}
+	return nil, fmt.Errorf("unknown credentials backend %q (supported: vault, aws, gcp)", cfg.Backend)
+}
+
+// resolveProviderSecrets fills in the API key of every provider that names
+// an api_key_secret and has no inline api_key, reading it from the
+// configured credentials backend.
+func resolveProviderSecrets(cfg *config.Config) error {
+	var pending []string
+	providers := cfg.Providers.All()
+	for name, pc := range providers {
+		if pc.APIKey == "" && pc.APIKeySecret != "" {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Strings(pending)
+
+	backend, err := newCredentialBackend(cfg.Credentials)
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		return fmt.Errorf("providers.%s.api_key_secret is set but no credentials.backend is configured", pending[0])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, name := range pending {
+		pc := providers[name]
+		key, err := backend.Secret(ctx, pc.APIKeySecret)
+		if err != nil {
+			return fmt.Errorf("loading %s API key from %s: %w", name, backend.Name(), err)
+		}
+		pc.APIKey = key
+		logger.AddSecret(key)
+	}
+	return nil
+}
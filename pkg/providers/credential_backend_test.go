@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestResolveProviderSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/llm/groq" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"api_key":"gsk-from-vault"}}}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Credentials.Backend = "vault"
+	cfg.Credentials.Vault.Address = server.URL
+	cfg.Credentials.Vault.Token = "t"
+	cfg.Providers.Groq.APIKeySecret = "llm/groq#api_key"
+	cfg.Providers.OpenAI.APIKey = "inline"
+	cfg.Providers.OpenAI.APIKeySecret = "llm/openai#api_key"
+
+	if err := resolveProviderSecrets(cfg); err != nil {
+		t.Fatalf("resolveProviderSecrets() error: %v", err)
+	}
+	if cfg.Providers.Groq.APIKey != "gsk-from-vault" {
+		t.Errorf("Groq.APIKey = %q, want %q", cfg.Providers.Groq.APIKey, "gsk-from-vault")
+	}
+	if cfg.Providers.OpenAI.APIKey != "inline" {
+		t.Errorf("OpenAI.APIKey = %q, inline key should win", cfg.Providers.OpenAI.APIKey)
+	}
+}
+
+func TestResolveProviderSecretsNoBackend(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Groq.APIKeySecret = "llm/groq"
+	if err := resolveProviderSecrets(cfg); err == nil {
+		t.Error("expected error when api_key_secret is set without a backend")
+	}
+}
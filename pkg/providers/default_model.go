@@ -0,0 +1,36 @@
+package providers
+
+import "github.com/sipeed/picoclaw/pkg/config"
+
+// defaultModel holds the model a provider reports from GetDefaultModel
+// when the config sets one, in place of the provider's built-in default.
+// Providers embed it.
+type defaultModel struct {
+	configured string
+}
+
+// SetDefaultModel overrides the provider's built-in default model; an
+// empty model restores it.
+func (d *defaultModel) SetDefaultModel(model string) {
+	d.configured = model
+}
+
+// or returns the configured default model, or builtin when none is set.
+func (d *defaultModel) or(builtin string) string {
+	if d.configured != "" {
+		return d.configured
+	}
+	return builtin
+}
+
+// applyDefaultModel sets on provider the default model configured for the
+// provider it was created from, when it takes one.
+func applyDefaultModel(cfg *config.Config, configured string, provider LLMProvider) {
+	pc := cfg.Providers.Get(configured)
+	if pc == nil || pc.DefaultModel == "" {
+		return
+	}
+	if p, ok := provider.(interface{ SetDefaultModel(string) }); ok {
+		p.SetDefaultModel(pc.DefaultModel)
+	}
+}
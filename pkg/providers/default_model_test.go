@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCreateProviderFor_ConfiguredDefaultModel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Groq.APIKey = "test-key"
+	cfg.Providers.Groq.DefaultModel = "llama-3.3-70b-versatile"
+
+	provider, err := CreateProviderFor(cfg, "groq", "")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	if got := provider.GetDefaultModel(); got != "llama-3.3-70b-versatile" {
+		t.Errorf("GetDefaultModel() = %q, want the configured default", got)
+	}
+}
+
+func TestDefaultModel_BuiltinUnlessConfigured(t *testing.T) {
+	p := NewCodexProvider("token", "")
+	if got := p.GetDefaultModel(); got != "gpt-4o" {
+		t.Errorf("GetDefaultModel() = %q, want the built-in gpt-4o", got)
+	}
+	p.SetDefaultModel("gpt-5")
+	if got := p.GetDefaultModel(); got != "gpt-5" {
+		t.Errorf("GetDefaultModel() = %q, want gpt-5", got)
+	}
+	p.SetDefaultModel("")
+	if got := p.GetDefaultModel(); got != "gpt-4o" {
+		t.Errorf("GetDefaultModel() = %q after reset, want gpt-4o", got)
+	}
+}
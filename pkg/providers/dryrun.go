@@ -0,0 +1,191 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// OptionDryRun (a bool) makes Chat and ChatStream build the request they
+// would send and fail with a *DryRunError holding it, instead of sending
+// it. The Claude CLI and Copilot CLI providers do not honor it; DryRun
+// refuses them rather than letting them send the request.
+const OptionDryRun = "dry_run"
+
+// ErrDryRunUnsupported is returned by DryRun for providers that do not
+// honor OptionDryRun.
+var ErrDryRunUnsupported = errors.New("provider does not support dry runs")
+
+// redacted replaces credentials in a DryRunRequest.
+const redacted = logger.Redacted
+
+// DryRunRequest is an HTTP request a provider would have sent, with its
+// credentials redacted.
+type DryRunRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// String renders the request as HTTP text, with the headers sorted and a
+// JSON body indented.
+func (r *DryRunRequest) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n", r.Method, r.URL)
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.Header[name] {
+			fmt.Fprintf(&sb, "%s: %s\n", name, value)
+		}
+	}
+	if len(r.Body) > 0 {
+		sb.WriteString("\n")
+		var indented bytes.Buffer
+		if json.Indent(&indented, r.Body, "", "  ") == nil {
+			sb.Write(indented.Bytes())
+		} else {
+			sb.Write(r.Body)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// DryRunError is the error of a request made with OptionDryRun. Check for
+// it with errors.As.
+type DryRunError struct {
+	Request *DryRunRequest
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dry run: %s %s not sent", e.Request.Method, e.Request.URL)
+}
+
+// dryRunner is implemented by the providers that honor OptionDryRun.
+// Providers embedding one must honor it too, as they inherit the method.
+type dryRunner interface {
+	honorsDryRun()
+}
+
+func (p *HTTPProvider) honorsDryRun()         {}
+func (p *PerplexityProvider) honorsDryRun()   {}
+func (p *ClaudeProvider) honorsDryRun()       {}
+func (p *CodexProvider) honorsDryRun()        {}
+func (p *OpenAIProvider) honorsDryRun()       {}
+func (p *GitHubModelsProvider) honorsDryRun() {}
+func (p *CopilotAPIProvider) honorsDryRun()   {}
+func (p *CohereProvider) honorsDryRun()       {}
+
+// DryRun returns the request provider would send for a Chat call, without
+// sending it. Routers and wrappers are looked through to the provider that
+// would send the request.
+func DryRun(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*DryRunRequest, error) {
+	p := provider
+	for {
+		if r, ok := p.(*Router); ok {
+			var err error
+			if p, model, err = r.Route(messages, tools, model, options); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := p.(dryRunner); ok {
+			break
+		}
+		w, ok := p.(interface{ Unwrap() LLMProvider })
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrDryRunUnsupported, p)
+		}
+		p = w.Unwrap()
+	}
+
+	dryOptions := make(map[string]interface{}, len(options)+1)
+	for key, value := range options {
+		dryOptions[key] = value
+	}
+	dryOptions[OptionDryRun] = true
+	_, err := p.Chat(ctx, messages, tools, model, dryOptions)
+	var dry *DryRunError
+	if errors.As(err, &dry) {
+		return dry.Request, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%T sent the request of a dry run", p)
+	}
+	return nil, err
+}
+
+// dryRun reports whether options ask for a dry run.
+func dryRun(options map[string]interface{}) bool {
+	v, _ := options[OptionDryRun].(bool)
+	return v
+}
+
+// newDryRunError captures req, redacting its credentials.
+func newDryRunError(req *http.Request) error {
+	captured := &DryRunRequest{Method: req.Method, Header: req.Header.Clone()}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("reading request body: %w", err)
+		}
+		captured.Body = body
+	}
+	for name, values := range captured.Header {
+		if !isSecretName(name) {
+			continue
+		}
+		for i, value := range values {
+			if scheme, _, ok := strings.Cut(value, " "); ok && strings.EqualFold(name, "Authorization") {
+				values[i] = scheme + " " + redacted
+			} else {
+				values[i] = redacted
+			}
+		}
+	}
+	u := *req.URL
+	query := u.Query()
+	for name := range query {
+		if isSecretName(name) || name == "sig" {
+			query.Set(name, redacted)
+		}
+	}
+	if len(query) > 0 {
+		u.RawQuery = strings.ReplaceAll(query.Encode(), url.QueryEscape(redacted), redacted)
+	}
+	captured.URL = u.String()
+	return &DryRunError{Request: captured}
+}
+
+// isSecretName reports whether a header or query parameter holds
+// credentials, such as Authorization, X-Api-Key or key.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"auth", "key", "token", "secret", "cookie", "password"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunMiddleware captures the requests of the SDK clients instead of
+// sending them. Both SDKs take it with option.WithMiddleware, along with
+// option.WithMaxRetries(0) so the failure is not retried.
+func dryRunMiddleware(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	return nil, newDryRunError(req)
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var dryRunMessages = []Message{
+	{Role: "system", Content: "You are terse."},
+	{Role: "user", Content: "hi"},
+}
+
+func TestDryRun_HTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent %s %s", r.Method, r.URL)
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("sk-secret", server.URL+"/v1", "")
+	wrapped := NewCachingProvider(p, "test", NewMemoryCache(10), time.Hour)
+	req, err := DryRun(context.Background(), wrapped, dryRunMessages, nil, "gpt-4o", map[string]interface{}{"max_tokens": 100})
+	if err != nil {
+		t.Fatalf("DryRun() error: %v", err)
+	}
+
+	if req.Method != http.MethodPost || req.URL != server.URL+"/v1/chat/completions" {
+		t.Errorf("request = %s %s", req.Method, req.URL)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer [REDACTED]" {
+		t.Errorf("Authorization = %q, want it redacted", got)
+	}
+	rendered := req.String()
+	if strings.Contains(rendered, "sk-secret") {
+		t.Errorf("rendered request holds the API key:\n%s", rendered)
+	}
+	for _, want := range []string{`"model": "gpt-4o"`, `"content": "You are terse."`, `"max_tokens": 100`} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered request lacks %s:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestDryRun_ClaudeProvider(t *testing.T) {
+	p := NewClaudeProvider("sk-ant-secret")
+	req, err := DryRun(context.Background(), p, dryRunMessages, nil, "claude-sonnet-4-5", nil)
+	if err != nil {
+		t.Fatalf("DryRun() error: %v", err)
+	}
+	if req.URL != "https://api.anthropic.com/v1/messages" {
+		t.Errorf("URL = %s", req.URL)
+	}
+	if got := req.Header.Get("X-Api-Key"); got != "[REDACTED]" {
+		t.Errorf("X-Api-Key = %q, want it redacted", got)
+	}
+	if !strings.Contains(string(req.Body), `"system"`) {
+		t.Errorf("body lacks the system prompt: %s", req.Body)
+	}
+}
+
+func TestDryRun_Option(t *testing.T) {
+	p := NewHTTPProvider("", "http://127.0.0.1:0/v1", "")
+	_, err := p.Chat(context.Background(), dryRunMessages, nil, "m", map[string]interface{}{OptionDryRun: true})
+	var dry *DryRunError
+	if !errors.As(err, &dry) {
+		t.Fatalf("Chat() error = %v, want a *DryRunError", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/v1/models/m:generate?alt=sse&key=secret", nil)
+	if errors.As(newDryRunError(req), &dry); dry.Request.URL != "https://example.com/v1/models/m:generate?alt=sse&key=[REDACTED]" {
+		t.Errorf("URL = %s, want the key redacted", dry.Request.URL)
+	}
+}
+
+func TestDryRun_Unsupported(t *testing.T) {
+	inner := &countingProvider{}
+	if _, err := DryRun(context.Background(), inner, dryRunMessages, nil, "m", nil); !errors.Is(err, ErrDryRunUnsupported) {
+		t.Errorf("DryRun() error = %v, want ErrDryRunUnsupported", err)
+	}
+	if inner.calls != 0 {
+		t.Error("DryRun() sent the request to a provider that cannot dry run")
+	}
+}
@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Embedder is implemented by providers that can turn text into embedding
+// vectors.
+type Embedder interface {
+	// Embed returns one vector per input text, in order.
+	Embed(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
+
+// Embed calls the OpenAI-compatible /embeddings endpoint.
+func (p *HTTPProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	req, err := p.newRequest(ctx, "/embeddings", map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, body)
+	}
+
+	return parseEmbeddings(body, len(texts))
+}
+
+func parseEmbeddings(body []byte, n int) ([][]float32, error) {
+	var apiResponse struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(apiResponse.Data) != n {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", n, len(apiResponse.Data))
+	}
+
+	// Entries carry their input index and are not guaranteed to be in order
+	vectors := make([][]float32, n)
+	for _, d := range apiResponse.Data {
+		if d.Index < 0 || d.Index >= n || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("invalid embedding index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// NewEmbedder returns the embedder for the RAG settings in cfg: a dedicated
+// OpenAI-compatible endpoint when APIBase is set, otherwise provider itself
+// if it can embed.
+func NewEmbedder(cfg config.RAGConfig, provider LLMProvider) (Embedder, error) {
+	if cfg.APIBase != "" {
+		return NewHTTPProvider(cfg.APIKey, cfg.APIBase, ""), nil
+	}
+	for {
+		wrapper, ok := provider.(interface{ Unwrap() LLMProvider })
+		if !ok {
+			break
+		}
+		provider = wrapper.Unwrap()
+	}
+	if embedder, ok := provider.(Embedder); ok {
+		return embedder, nil
+	}
+	return nil, fmt.Errorf("provider cannot create embeddings; set tools.rag.api_base")
+}
@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHTTPProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s, want /embeddings", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q, want Bearer key", got)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "embed-model" || len(req.Input) != 2 {
+			t.Errorf("request = %+v, want model embed-model with 2 inputs", req)
+		}
+		// Out of order on purpose: entries are matched by index
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	vectors, err := NewHTTPProvider("key", server.URL, "").Embed(context.Background(), []string{"a", "b"}, "embed-model")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v, want [[1 0] [0 1]]", vectors)
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	if _, err := NewEmbedder(config.RAGConfig{}, NewClaudeCliProvider(".")); err == nil {
+		t.Error("NewEmbedder() with a provider that cannot embed: want an error")
+	}
+	if _, err := NewEmbedder(config.RAGConfig{APIBase: "http://localhost:11434/v1"}, nil); err != nil {
+		t.Errorf("NewEmbedder() with api_base: error = %v", err)
+	}
+	embedder, err := NewEmbedder(config.RAGConfig{}, NewHTTPProvider("", "http://example", ""))
+	if err != nil || embedder == nil {
+		t.Errorf("NewEmbedder() with an HTTP provider = %v, %v; want the provider", embedder, err)
+	}
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Response metadata identifying the provider request, for support tickets.
+const (
+	MetadataRequestID  = "request_id"
+	MetadataHTTPStatus = "http_status"
+)
+
+// sdkRetryCountHeader numbers the attempts of a request made by the
+// Anthropic and OpenAI SDKs, starting at 0.
+const sdkRetryCountHeader = "X-Stainless-Retry-Count"
+
+// requestIDHeaders are the response headers vendors put request IDs in:
+// x-request-id (OpenAI and most compatible APIs) and request-id
+// (Anthropic).
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id"}
+
+// requestID returns the vendor request ID in header, or "".
+func requestID(header http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// APIError is an error response from a provider, with the status and the
+// request ID vendors ask for when investigating a failure.
+type APIError struct {
+	StatusCode int
+	RequestID  string
+	Body       string
+
+	err error // the SDK error this was made from, if any
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	return &APIError{StatusCode: resp.StatusCode, RequestID: requestID(resp.Header), Body: string(body)}
+}
+
+// Error describes the failure with any credentials the provider echoed back,
+// as in the request URL or body, redacted.
+func (e *APIError) Error() string {
+	if e.err != nil {
+		msg := e.err.Error()
+		if e.RequestID != "" && !strings.Contains(msg, e.RequestID) {
+			msg += fmt.Sprintf(" (request ID: %s)", e.RequestID)
+		}
+		return logger.Redact(msg)
+	}
+	msg := fmt.Sprintf("API request failed:\n  Status: %d\n  Body:   %s", e.StatusCode, e.Body)
+	if e.RequestID != "" {
+		msg += "\n  Request ID: " + e.RequestID
+	}
+	return logger.Redact(msg)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// RateLimitError is a request the provider refused for being over its rate
+// limit. RetryAfter is how long until requests are accepted again, or zero
+// when the provider did not say.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limit reached, retry in %s: %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limit reached: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// sdkAPIError converts the API errors of the Anthropic and OpenAI SDKs to
+// an APIError, returning other errors unchanged.
+func sdkAPIError(err error) error {
+	var resp *http.Response
+	var status int
+	var anthropicErr *anthropic.Error
+	var openaiErr *openai.Error
+	switch {
+	case errors.As(err, &anthropicErr):
+		resp, status = anthropicErr.Response, anthropicErr.StatusCode
+	case errors.As(err, &openaiErr):
+		resp, status = openaiErr.Response, openaiErr.StatusCode
+	default:
+		return err
+	}
+	apiErr := &APIError{StatusCode: status, err: err}
+	if resp != nil {
+		apiErr.RequestID = requestID(resp.Header)
+	}
+	return apiErr
+}
+
+// setResponseMetadata records the request ID and status of the HTTP
+// response resp came from, and how many times the SDKs retried it.
+func setResponseMetadata(resp *LLMResponse, httpResp *http.Response) {
+	if resp == nil || httpResp == nil {
+		return
+	}
+	if httpResp.Request != nil {
+		if retries, _ := strconv.Atoi(httpResp.Request.Header.Get(sdkRetryCountHeader)); retries > 0 {
+			resp.timing().Retries = retries
+		}
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	resp.Metadata[MetadataHTTPStatus] = strconv.Itoa(httpResp.StatusCode)
+	if id := requestID(httpResp.Header); id != "" {
+		resp.Metadata[MetadataRequestID] = id
+	}
+}
@@ -0,0 +1,46 @@
+package providers
+
+import "strings"
+
+// FinishReason is why a model stopped generating, normalized across
+// providers. LLMResponse.RawFinishReason keeps the vendor's own value.
+type FinishReason string
+
+const (
+	FinishReasonStop          FinishReason = "stop"
+	FinishReasonLength        FinishReason = "length"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
+	FinishReasonContentFilter FinishReason = "content_filter"
+	FinishReasonError         FinishReason = "error"
+	FinishReasonRefusal       FinishReason = "refusal"
+)
+
+// vendorFinishReasons maps the finish and stop reasons vendors send, in
+// lower case, to a FinishReason. Values missing here are treated as stop.
+var vendorFinishReasons = map[string]FinishReason{
+	// OpenAI chat completions and compatible APIs
+	"length":         FinishReasonLength,
+	"tool_calls":     FinishReasonToolCalls,
+	"function_call":  FinishReasonToolCalls,
+	"content_filter": FinishReasonContentFilter,
+	"sensitive":      FinishReasonContentFilter,
+	"safety":         FinishReasonContentFilter,
+	"error":          FinishReasonError,
+	"network_error":  FinishReasonError,
+	// Anthropic
+	"max_tokens": FinishReasonLength,
+	"tool_use":   FinishReasonToolCalls,
+	"refusal":    FinishReasonRefusal,
+	// OpenAI responses, as the reason a response is incomplete
+	"max_output_tokens": FinishReasonLength,
+	// Cohere
+	"tool_call": FinishReasonToolCalls,
+}
+
+// normalizeFinishReason maps a vendor finish reason to a FinishReason.
+func normalizeFinishReason(raw string) FinishReason {
+	if reason, ok := vendorFinishReasons[strings.ToLower(raw)]; ok {
+		return reason
+	}
+	return FinishReasonStop
+}
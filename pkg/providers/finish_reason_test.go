@@ -0,0 +1,24 @@
+package providers
+
+import "testing"
+
+func TestNormalizeFinishReason(t *testing.T) {
+	tests := map[string]FinishReason{
+		"":               FinishReasonStop,
+		"stop":           FinishReasonStop,
+		"eos":            FinishReasonStop,
+		"length":         FinishReasonLength,
+		"MAX_TOKENS":     FinishReasonLength,
+		"tool_calls":     FinishReasonToolCalls,
+		"function_call":  FinishReasonToolCalls,
+		"content_filter": FinishReasonContentFilter,
+		"sensitive":      FinishReasonContentFilter,
+		"network_error":  FinishReasonError,
+		"refusal":        FinishReasonRefusal,
+	}
+	for raw, want := range tests {
+		if got := normalizeFinishReason(raw); got != want {
+			t.Errorf("normalizeFinishReason(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+const (
+	copilotTokenURL       = "https://api.github.com/copilot_internal/v2/token"
+	copilotDefaultAPIBase = "https://api.githubcopilot.com"
+	// copilotEditorVersion identifies the client to the Copilot API, which
+	// rejects requests that do not name an editor
+	copilotEditorVersion = "picoclaw/1.0"
+)
+
+// CopilotAPIProvider talks to the Copilot chat API directly, exchanging a
+// GitHub token of a user with a Copilot subscription for the short-lived
+// token the API takes.
+type CopilotAPIProvider struct {
+	githubToken string
+	tokenURL    string
+	// apiBase overrides the endpoint the token exchange names
+	apiBase    string
+	httpClient *http.Client
+	client     *openai.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	// endpoint is the chat API of the user's plan
+	endpoint string
+	defaultModel
+}
+
+// NewCopilotAPIProvider creates a provider for githubToken, such as an
+// OAuth token of a Copilot-enabled app or the output of `gh auth token`.
+// An empty apiBase uses the endpoint GitHub assigns the account.
+func NewCopilotAPIProvider(githubToken, apiBase string) *CopilotAPIProvider {
+	client := openai.NewClient(
+		option.WithAPIKey("unused"),
+		option.WithHeader("Editor-Version", copilotEditorVersion),
+		option.WithHeader("Copilot-Integration-Id", "vscode-chat"),
+	)
+	return &CopilotAPIProvider{
+		githubToken: githubToken,
+		tokenURL:    copilotTokenURL,
+		apiBase:     strings.TrimRight(apiBase, "/"),
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		client:      &client,
+	}
+}
+
+// copilotToken is the reply of the Copilot token exchange.
+type copilotToken struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+	Endpoints struct {
+		API string `json:"api"`
+	} `json:"endpoints"`
+}
+
+// chatToken returns a Copilot token and the endpoint to use it with,
+// exchanging the GitHub token again a minute before the last one expires.
+func (p *CopilotAPIProvider) chatToken(ctx context.Context) (string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Add(time.Minute).Before(p.expires) {
+		return p.token, p.endpoint, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.tokenURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "token "+p.githubToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Editor-Version", copilotEditorVersion)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("exchanging GitHub token for Copilot token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("reading Copilot token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("exchanging GitHub token for Copilot token: %w", newAPIError(resp, body))
+	}
+
+	var tok copilotToken
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", "", fmt.Errorf("parsing Copilot token: %w", err)
+	}
+	if tok.Token == "" {
+		return "", "", fmt.Errorf("Copilot token exchange returned no token; check that the account has a Copilot subscription")
+	}
+
+	p.token = tok.Token
+	p.expires = time.Unix(tok.ExpiresAt, 0)
+	p.endpoint = p.apiBase
+	if p.endpoint == "" {
+		p.endpoint = strings.TrimRight(tok.Endpoints.API, "/")
+	}
+	if p.endpoint == "" {
+		p.endpoint = copilotDefaultAPIBase
+	}
+	return p.token, p.endpoint, nil
+}
+
+func (p *CopilotAPIProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	token, endpoint, err := p.chatToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Messages: azureChatMessages(messages),
+		Model:    model,
+		Tools:    translateToolsForChat(tools),
+	}
+	applyChatModelParams(&params, model, options)
+
+	var httpResp *http.Response
+	opts := []option.RequestOption{
+		option.WithBaseURL(endpoint),
+		option.WithAPIKey(token),
+		option.WithResponseInto(&httpResp),
+	}
+	if dryRun(options) {
+		opts = append(opts, option.WithMiddleware(dryRunMiddleware), option.WithMaxRetries(0))
+	}
+	resp, err := p.client.Chat.Completions.New(ctx, params, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("copilot API call: %w", sdkAPIError(err))
+	}
+	llmResp := parseChatCompletionResponse(resp)
+	setResponseMetadata(llmResp, httpResp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// ChatStream sends the whole reply at once.
+func (p *CopilotAPIProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+func (p *CopilotAPIProvider) GetDefaultModel() string {
+	return p.defaultModel.or("gpt-4.1")
+}
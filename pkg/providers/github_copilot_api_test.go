@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCopilotAPIProvider_Chat(t *testing.T) {
+	exchanges := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/copilot_internal/v2/token":
+			if r.Header.Get("Authorization") != "token gho_test" {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+			exchanges++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token":      "copilot-token",
+				"expires_at": time.Now().Add(30 * time.Minute).Unix(),
+				"endpoints":  map[string]string{"api": server.URL + "/api"},
+			})
+		case "/api/chat/completions":
+			if r.Header.Get("Authorization") != "Bearer copilot-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("Editor-Version") == "" || r.Header.Get("Copilot-Integration-Id") == "" {
+				http.Error(w, "missing editor headers", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt-4.1",
+				"choices": []map[string]interface{}{{
+					"index": 0, "finish_reason": "stop",
+					"message": map[string]interface{}{"role": "assistant", "content": "Hi from Copilot"},
+				}},
+			})
+		default:
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := NewCopilotAPIProvider("gho_test", "")
+	p.tokenURL = server.URL + "/copilot_internal/v2/token"
+	for i := 0; i < 2; i++ {
+		resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil)
+		if err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+		if resp.Content != "Hi from Copilot" {
+			t.Errorf("Content = %q, want %q", resp.Content, "Hi from Copilot")
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("token exchanges = %d, want 1 while the token is valid", exchanges)
+	}
+}
+
+func TestCopilotAPIProvider_ExchangeRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	p := NewCopilotAPIProvider("gho_test", "")
+	p.tokenURL = server.URL
+	if _, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil); err == nil {
+		t.Fatal("Chat() error = nil, want the failed token exchange")
+	}
+}
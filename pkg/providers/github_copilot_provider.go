@@ -14,6 +14,7 @@ type GitHubCopilotProvider struct {
 	connectMode string // `stdio` or `grpc``
 
 	session *copilot.Session
+	defaultModel
 }
 
 func NewGitHubCopilotProvider(uri string, connectMode string, model string) (*GitHubCopilotProvider, error) {
@@ -70,13 +71,12 @@ func (p *GitHubCopilotProvider) Chat(ctx context.Context, messages []Message, to
 	})
 
 	return &LLMResponse{
-		FinishReason: "stop",
+		FinishReason: FinishReasonStop,
 		Content:      content,
 	}, nil
 
 }
 
 func (p *GitHubCopilotProvider) GetDefaultModel() string {
-
-	return "gpt-4.1"
+	return p.defaultModel.or("gpt-4.1")
 }
@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const githubModelsDefaultAPIBase = "https://models.github.ai/inference"
+
+// Rate limited requests are sent again when the limit resets within
+// githubModelsMaxRetryWait, up to githubModelsRateLimitRetries times.
+// Longer waits, such as for the free tier's daily limits, fail at once.
+const (
+	githubModelsMaxRetryWait     = time.Minute
+	githubModelsRateLimitRetries = 3
+)
+
+// githubModelPublishers name the publisher of models given without one, by
+// the start of the model name, for the publisher/model IDs GitHub Models
+// takes.
+var githubModelPublishers = []struct{ prefix, publisher string }{
+	{"gpt-", "openai"},
+	{"o1", "openai"},
+	{"o3", "openai"},
+	{"o4", "openai"},
+	{"llama", "meta"},
+	{"meta-llama", "meta"},
+	{"phi-", "microsoft"},
+	{"mai-", "microsoft"},
+	{"deepseek", "deepseek"},
+	{"mistral", "mistral-ai"},
+	{"ministral", "mistral-ai"},
+	{"codestral", "mistral-ai"},
+	{"cohere", "cohere"},
+	{"grok", "xai"},
+	{"jamba", "ai21-labs"},
+}
+
+// GitHubModelsProvider calls the GitHub Models inference API with a GitHub
+// personal access token that has the models scope.
+type GitHubModelsProvider struct {
+	client  *openai.Client
+	apiBase string
+	defaultModel
+}
+
+// NewGitHubModelsProvider creates a provider for token. An empty apiBase
+// is models.github.ai; set it to .../orgs/<org>/inference to bill an
+// organization.
+func NewGitHubModelsProvider(token, apiBase string) *GitHubModelsProvider {
+	if apiBase == "" {
+		apiBase = githubModelsDefaultAPIBase
+	}
+	client := openai.NewClient(
+		option.WithBaseURL(apiBase),
+		option.WithAPIKey(token),
+		option.WithHeader("X-GitHub-Api-Version", "2022-11-28"),
+	)
+	return &GitHubModelsProvider{client: &client, apiBase: strings.TrimRight(apiBase, "/")}
+}
+
+// githubModelID returns model as the publisher/model ID GitHub Models
+// takes: a github/ routing prefix is dropped, and the publisher of known
+// model families is added when missing.
+func githubModelID(model string) string {
+	model = strings.TrimPrefix(model, "github/")
+	if strings.Contains(model, "/") {
+		return model
+	}
+	lower := strings.ToLower(model)
+	for _, p := range githubModelPublishers {
+		if strings.HasPrefix(lower, p.prefix) {
+			return p.publisher + "/" + model
+		}
+	}
+	return model
+}
+
+func (p *GitHubModelsProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	params := openai.ChatCompletionNewParams{
+		Messages: azureChatMessages(messages),
+		Model:    githubModelID(model),
+		Tools:    translateToolsForChat(tools),
+	}
+	applyChatModelParams(&params, model, options)
+
+	var httpResp *http.Response
+	opts := []option.RequestOption{option.WithResponseInto(&httpResp)}
+	if dryRun(options) {
+		opts = append(opts, option.WithMiddleware(dryRunMiddleware), option.WithMaxRetries(0))
+	}
+	var resp *openai.ChatCompletion
+	for attempt := 0; ; attempt++ {
+		var err error
+		resp, err = p.client.Chat.Completions.New(ctx, params, opts...)
+		if err == nil {
+			break
+		}
+		err = githubModelsError(err)
+		var limited *RateLimitError
+		if !errors.As(err, &limited) || limited.RetryAfter == 0 || limited.RetryAfter > githubModelsMaxRetryWait ||
+			attempt >= githubModelsRateLimitRetries || dryRun(options) {
+			return nil, err
+		}
+		logger.DebugCF("provider", "GitHub Models rate limit reached, waiting",
+			map[string]interface{}{"model": model, "wait": limited.RetryAfter.String()})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(limited.RetryAfter):
+		}
+	}
+	llmResp := parseChatCompletionResponse(resp)
+	setResponseMetadata(llmResp, httpResp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// githubModelsError returns a rate limit error, which on GitHub Models may
+// last until the next day for the free tier, as a *RateLimitError saying
+// when to try again.
+func githubModelsError(err error) error {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) && openaiErr.StatusCode == http.StatusTooManyRequests {
+		limited := &RateLimitError{Err: fmt.Errorf("github models: %w", sdkAPIError(err))}
+		if openaiErr.Response != nil {
+			limited.RetryAfter = githubModelsRetryAfter(openaiErr.Response.Header)
+		}
+		return limited
+	}
+	return fmt.Errorf("github models API call: %w", sdkAPIError(err))
+}
+
+// githubModelsRetryAfter reads how long to wait from the Retry-After
+// header, or from x-ratelimit-timeremaining which GitHub Models sends for
+// daily limits.
+func githubModelsRetryAfter(header http.Header) time.Duration {
+	for _, name := range []string{"Retry-After", "X-Ratelimit-Timeremaining"} {
+		if seconds, err := strconv.Atoi(header.Get(name)); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// ChatStream sends the whole reply at once.
+func (p *GitHubModelsProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+func (p *GitHubModelsProvider) GetDefaultModel() string {
+	return p.defaultModel.or("openai/gpt-4.1")
+}
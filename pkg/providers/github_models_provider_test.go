@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestGitHubModelID(t *testing.T) {
+	tests := []struct {
+		model, want string
+	}{
+		{"openai/gpt-4.1", "openai/gpt-4.1"},
+		{"github/meta/Llama-4-Scout-17B-16E-Instruct", "meta/Llama-4-Scout-17B-16E-Instruct"},
+		{"gpt-4o-mini", "openai/gpt-4o-mini"},
+		{"Phi-4", "microsoft/Phi-4"},
+		{"DeepSeek-R1", "deepseek/DeepSeek-R1"},
+		{"unknown-model", "unknown-model"},
+	}
+	for _, tt := range tests {
+		if got := githubModelID(tt.model); got != tt.want {
+			t.Errorf("githubModelID(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubModelsProvider_Chat(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference/chat/completions" || r.Header.Get("Authorization") != "Bearer github_pat_test" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt-4.1",
+			"choices": []map[string]interface{}{{
+				"index": 0, "finish_reason": "stop",
+				"message": map[string]interface{}{"role": "assistant", "content": "Hello from GitHub"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	p := NewGitHubModelsProvider("github_pat_test", server.URL+"/inference")
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "Hello from GitHub" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello from GitHub")
+	}
+	if body["model"] != "openai/gpt-4.1" {
+		t.Errorf("model = %v, want openai/gpt-4.1", body["model"])
+	}
+}
+
+func TestGitHubModelsProvider_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, `{"error":{"code":"RateLimitReached","message":"Rate limit of 150 per 86400s exceeded"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	p := NewGitHubModelsProvider("github_pat_test", server.URL)
+	client := openai.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("github_pat_test"), option.WithMaxRetries(0))
+	p.client = &client
+	_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil)
+	if err == nil {
+		t.Fatal("Chat() error = nil, want the rate limit")
+	}
+	if !strings.Contains(err.Error(), "retry in 1h0m0s") {
+		t.Errorf("error = %q, want when to retry", err)
+	}
+	var limited *RateLimitError
+	if !errors.As(err, &limited) || limited.RetryAfter != time.Hour {
+		t.Errorf("error = %v, want a RateLimitError retrying after an hour", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("error = %v, want an APIError with status 429", err)
+	}
+}
+
+func TestGitHubModelsProvider_RateLimitRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("X-Ratelimit-Timeremaining", "1")
+			http.Error(w, `{"error":{"code":"RateLimitReached","message":"Rate limit of 15 per 60s exceeded"}}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewGitHubModelsProvider("github_pat_test", server.URL)
+	client := openai.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("github_pat_test"), option.WithMaxRetries(0))
+	p.client = &client
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Content != "Hello" || requests != 2 {
+		t.Errorf("content = %q after %d requests, want Hello after 2", resp.Content, requests)
+	}
+}
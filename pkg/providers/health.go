@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Provider health states reported by CheckProviderHealth.
+const (
+	HealthOK          = "ok"
+	HealthAuthFailed  = "auth_failed"
+	HealthUnreachable = "unreachable"
+	HealthError       = "error"
+)
+
+// healthProbeTimeout bounds one provider probe.
+const healthProbeTimeout = 15 * time.Second
+
+// ProviderHealth is the outcome of probing one provider.
+type ProviderHealth struct {
+	Provider  string    `json:"provider"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Healthy reports whether the probe succeeded.
+func (h ProviderHealth) Healthy() bool {
+	return h.Status == HealthOK
+}
+
+// CheckProviderHealth probes a provider by listing its models, which checks
+// that the endpoint answers and the credentials are accepted without
+// spending tokens.
+func CheckProviderHealth(ctx context.Context, cfg *config.Config, name string) ProviderHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ListProviderModels(ctx, cfg, name)
+	health := ProviderHealth{
+		Provider:  name,
+		Status:    HealthOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		health.Status = healthStatus(err)
+		health.Error = err.Error()
+	}
+	return health
+}
+
+func healthStatus(err error) string {
+	var statusErr *modelsStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.status == http.StatusUnauthorized || statusErr.status == http.StatusForbidden {
+			return HealthAuthFailed
+		}
+		return HealthError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return HealthUnreachable
+	}
+	return HealthError
+}
+
+// HealthChecker probes every configured provider periodically and keeps the
+// latest result of each.
+type HealthChecker struct {
+	cfg      *config.Config
+	interval time.Duration
+	probe    func(ctx context.Context, cfg *config.Config, name string) ProviderHealth
+
+	mu      sync.Mutex
+	results map[string]ProviderHealth
+}
+
+// NewHealthChecker creates a checker for the providers configured in cfg.
+func NewHealthChecker(cfg *config.Config, interval time.Duration) *HealthChecker {
+	return &HealthChecker{
+		cfg:      cfg,
+		interval: interval,
+		probe:    CheckProviderHealth,
+		results:  map[string]ProviderHealth{},
+	}
+}
+
+// Run probes the providers now and then every interval until ctx is done.
+func (h *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check probes every configured provider concurrently and returns the
+// results sorted by provider.
+func (h *HealthChecker) Check(ctx context.Context) []ProviderHealth {
+	if err := resolveProviderSecrets(h.cfg); err != nil {
+		logger.WarnCF("health", "Failed to resolve provider secrets",
+			map[string]interface{}{"error": err.Error()})
+	}
+	names := configuredProviders(h.cfg)
+	results := make([]ProviderHealth, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = h.probe(ctx, h.cfg, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	previous := h.results
+	h.results = make(map[string]ProviderHealth, len(results))
+	for _, r := range results {
+		// Log when a provider becomes unhealthy, not on every probe
+		if prev, ok := previous[r.Provider]; !r.Healthy() && (!ok || prev.Status != r.Status) {
+			logger.WarnCF("health", "Provider is unhealthy",
+				map[string]interface{}{"provider": r.Provider, "status": r.Status, "error": r.Error})
+		}
+		h.results[r.Provider] = r
+	}
+	return results
+}
+
+// Results returns the latest result of each provider, sorted by provider.
+// It is empty until the first check completes.
+func (h *HealthChecker) Results() []ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ProviderHealth, 0, len(h.results))
+	for _, r := range h.results {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+// Healthy reports whether every provider passed its latest check.
+func (h *HealthChecker) Healthy() bool {
+	for _, r := range h.Results() {
+		if !r.Healthy() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCheckProviderHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"llama-3.1-8b"}]}`))
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		apiBase string
+		want    string
+	}{
+		{"ok", "good", server.URL, HealthOK},
+		{"bad key", "bad", server.URL, HealthAuthFailed},
+		{"down", "good", closed.URL, HealthUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Providers.Groq = config.ProviderConfig{APIKey: tt.apiKey, APIBase: tt.apiBase}
+			got := CheckProviderHealth(context.Background(), cfg, "groq")
+			if got.Status != tt.want {
+				t.Errorf("Status = %q, want %q (error: %s)", got.Status, tt.want, got.Error)
+			}
+			if got.Healthy() != (tt.want == HealthOK) {
+				t.Errorf("Healthy() = %v", got.Healthy())
+			}
+		})
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	cfg := &config.Config{}
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "k"}
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "k"}
+
+	h := NewHealthChecker(cfg, 0)
+	h.probe = func(ctx context.Context, cfg *config.Config, name string) ProviderHealth {
+		if name == "openai" {
+			return ProviderHealth{Provider: name, Status: HealthAuthFailed}
+		}
+		return ProviderHealth{Provider: name, Status: HealthOK}
+	}
+	if !h.Healthy() {
+		t.Error("Healthy() before the first check = false, want true")
+	}
+
+	h.Check(context.Background())
+	results := h.Results()
+	if len(results) != 2 || results[0].Provider != "groq" || results[1].Provider != "openai" {
+		t.Fatalf("Results() = %+v", results)
+	}
+	if h.Healthy() {
+		t.Error("Healthy() = true with a failing provider")
+	}
+}
@@ -9,22 +9,44 @@ package providers
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 type HTTPProvider struct {
 	apiKey     string
 	apiBase    string
 	httpClient *http.Client
+	// builtinModel is the default model of the API, if it has one
+	builtinModel string
+	// samplingExtensions sends the top_k, min_p and repetition_penalty
+	// options, which open model hosts take beyond the OpenAI parameters
+	samplingExtensions bool
+	// nonStreamParams are added to requests that are not streamed, such
+	// as DashScope's enable_thinking, which Qwen3 models need off there
+	nonStreamParams map[string]interface{}
+	// tokenSource, when set, supplies the bearer token of each request in
+	// place of apiKey, such as a Microsoft Entra token
+	tokenSource func() (string, string, error)
+	// apiVersion is sent as the api-version query parameter, for Azure
+	apiVersion string
+	// localServer marks a server on the user's machine, such as LM Studio,
+	// whose models may not take tools; refusedTools holds the models that
+	// refused them
+	localServer  bool
+	refusedTools sync.Map
+	defaultModel
 }
 
 func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
@@ -49,21 +71,86 @@ func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
 }
 
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	if p.apiBase == "" {
-		return nil, fmt.Errorf("API base not configured")
+	start := time.Now()
+	tools = p.supportedTools(tools, model)
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	for key, value := range p.nonStreamParams {
+		if _, ok := requestBody[key]; !ok {
+			requestBody[key] = value
+		}
+	}
+	if dryRun(options) {
+		return nil, p.dryRunChat(ctx, requestBody)
+	}
+	body, resp, err := p.postChat(ctx, requestBody)
+	if p.toolsRefused(err, tools, model) {
+		return p.Chat(ctx, messages, nil, model, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	llmResp, err := p.parseResponse(body)
+	if err != nil {
+		return nil, err
 	}
+	setAudioMediaType(llmResp, requestBody)
+	setResponseMetadata(llmResp, resp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// dryRunChat returns the *DryRunError of a chat completions request.
+func (p *HTTPProvider) dryRunChat(ctx context.Context, requestBody map[string]interface{}) error {
+	req, err := p.newChatRequest(ctx, requestBody)
+	if err != nil {
+		return err
+	}
+	return newDryRunError(req)
+}
 
+// postChat sends a chat completions request and returns the body of the
+// successful response.
+func (p *HTTPProvider) postChat(ctx context.Context, requestBody map[string]interface{}) ([]byte, *http.Response, error) {
+	req, err := p.newChatRequest(ctx, requestBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, newAPIError(resp, body)
+	}
+	return body, resp, nil
+}
+
+// buildRequestBody assembles the chat completions request for model.
+func (p *HTTPProvider) buildRequestBody(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) map[string]interface{} {
 	// Strip provider prefix from model name (e.g., moonshot/kimi-k2.5 -> kimi-k2.5)
 	if idx := strings.Index(model, "/"); idx != -1 {
 		prefix := model[:idx]
-		if prefix == "moonshot" || prefix == "nvidia" {
+		if prefix == "moonshot" || prefix == "nvidia" || prefix == "together" || prefix == "fireworks" || prefix == "perplexity" || prefix == "dashscope" || prefix == "foundry" {
 			model = model[idx+1:]
 		}
+		// Fireworks names its own models accounts/fireworks/models/<name>
+		if prefix == "fireworks" && !strings.Contains(model, "/") {
+			model = "accounts/fireworks/models/" + model
+		}
 	}
 
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": openAIMessages(messages),
 	}
 
 	if len(tools) > 0 {
@@ -80,54 +167,170 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		}
 	}
 
-	if temperature, ok := options["temperature"].(float64); ok {
-		lowerModel := strings.ToLower(model)
-		// Kimi k2 models only support temperature=1
-		if strings.Contains(lowerModel, "kimi") && strings.Contains(lowerModel, "k2") {
-			requestBody["temperature"] = 1.0
-		} else {
-			requestBody["temperature"] = temperature
+	applyAudioOutput(requestBody, options, "wav")
+
+	resolved := resolveModelParams(model, options)
+	if resolved.temperature != nil {
+		requestBody["temperature"] = *resolved.temperature
+	}
+	if resolved.topP != nil {
+		requestBody["top_p"] = *resolved.topP
+	}
+	if len(resolved.stop) > 0 {
+		requestBody["stop"] = resolved.stop
+	}
+	if resolved.reasoningEffort != "" {
+		requestBody["reasoning_effort"] = resolved.reasoningEffort
+	}
+	if p.samplingExtensions {
+		if topK, ok := options[OptionTopK].(int); ok && topK > 0 {
+			requestBody["top_k"] = topK
+		}
+		if minP, ok := options[OptionMinP].(float64); ok {
+			requestBody["min_p"] = minP
+		}
+		if penalty, ok := options[OptionRepetitionPenalty].(float64); ok {
+			requestBody["repetition_penalty"] = penalty
 		}
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	return requestBody
+}
+
+// openAIMessages converts messages to the chat completions format. Messages
+// without parts are sent as they are; a message's tool results become
+// separate tool messages, as the format requires.
+func openAIMessages(messages []Message) []interface{} {
+	out := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		msg.Name = openAIName(msg.Name)
+		if len(msg.Parts) == 0 {
+			// Only the fields the API knows
+			msg.CreatedAt, msg.Metadata = time.Time{}, nil
+			out = append(out, msg)
+			continue
+		}
+		var content []map[string]interface{}
+		var toolCalls []map[string]interface{}
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case ContentText:
+				content = append(content, map[string]interface{}{"type": "text", "text": part.Text})
+			case ContentImage:
+				content = append(content, map[string]interface{}{
+					"type":      "image_url",
+					"image_url": map[string]interface{}{"url": part.dataURL()},
+				})
+			case ContentDocument:
+				content = append(content, map[string]interface{}{
+					"type": "file",
+					"file": map[string]interface{}{"filename": part.Name, "file_data": part.dataURL()},
+				})
+			case ContentAudio:
+				// Assistant audio is sent back as its transcript, below
+				content = append(content, map[string]interface{}{
+					"type": "input_audio",
+					"input_audio": map[string]interface{}{
+						"data":   base64.StdEncoding.EncodeToString(part.Data),
+						"format": openAIAudioFormat(part.MediaType),
+					},
+				})
+			case ContentToolUse:
+				args, _ := json.Marshal(part.ToolCall.Arguments)
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"id":       part.ToolCall.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": part.ToolCall.Name, "arguments": string(args)},
+				})
+			case ContentToolResult:
+				out = append(out, map[string]interface{}{"role": "tool", "tool_call_id": part.ToolCallID, "content": part.Text})
+			}
+		}
+		if len(content) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		wire := map[string]interface{}{"role": msg.Role, "content": content}
+		if msg.Role == "tool" {
+			// Tool messages take only text; images a tool returned follow
+			// its result as a user message
+			wire["role"] = "user"
+		}
+		if msg.Role == "assistant" || msg.Role == "system" {
+			// Only user messages take content arrays everywhere
+			wire["content"] = msg.textContent()
+		}
+		if len(toolCalls) > 0 {
+			wire["tool_calls"] = toolCalls
+		}
+		if msg.Name != "" {
+			wire["name"] = msg.Name
+		}
+		out = append(out, wire)
 	}
+	return out
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// openAIName fits a participant name to the pattern OpenAI accepts: up to
+// 64 letters, digits, underscores and hyphens.
+func openAIName(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > 64 {
+		name = name[:64]
 	}
+	return name
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+// newChatRequest creates the POST to the chat completions endpoint.
+func (p *HTTPProvider) newChatRequest(ctx context.Context, requestBody map[string]interface{}) (*http.Request, error) {
+	return p.newRequest(ctx, "/chat/completions", requestBody)
+}
+
+// newRequest creates an authenticated JSON POST to endpoint under the API base.
+func (p *HTTPProvider) newRequest(ctx context.Context, endpoint string, requestBody interface{}) (*http.Request, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
 	}
 
-	resp, err := p.httpClient.Do(req)
+	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if p.apiVersion != "" {
+		endpoint += "?api-version=" + url.QueryEscape(p.apiVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+endpoint, bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	req.Header.Set("Content-Type", "application/json")
+	apiKey := p.apiKey
+	if p.tokenSource != nil {
+		if apiKey, _, err = p.tokenSource(); err != nil {
+			return nil, err
+		}
 	}
-
-	return p.parseResponse(body)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	return req, nil
 }
 
 func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
+				Content   string       `json:"content"`
+				Audio     *openAIAudio `json:"audio"`
 				ToolCalls []struct {
 					ID       string `json:"id"`
 					Type     string `json:"type"`
@@ -139,7 +342,8 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
-		Usage *UsageInfo `json:"usage"`
+		Usage   *UsageInfo    `json:"usage"`
+		Timings *llamaTimings `json:"timings"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
@@ -149,7 +353,7 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	if len(apiResponse.Choices) == 0 {
 		return &LLMResponse{
 			Content:      "",
-			FinishReason: "stop",
+			FinishReason: FinishReasonStop,
 		}, nil
 	}
 
@@ -157,78 +361,208 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 
 	toolCalls := make([]ToolCall, 0, len(choice.Message.ToolCalls))
 	for _, tc := range choice.Message.ToolCalls {
-		arguments := make(map[string]interface{})
-		name := ""
-
-		// Handle OpenAI format with nested function object
-		if tc.Type == "function" && tc.Function != nil {
-			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
-		} else if tc.Function != nil {
-			// Legacy format without type field
-			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
+		// Both the OpenAI format and the legacy one without a type field
+		// nest the call in a function object
+		name, arguments := "", ""
+		if tc.Function != nil {
+			name, arguments = tc.Function.Name, tc.Function.Arguments
 		}
-
-		toolCalls = append(toolCalls, ToolCall{
-			ID:        tc.ID,
-			Name:      name,
-			Arguments: arguments,
-		})
+		toolCalls = append(toolCalls, newToolCall(tc.ID, name, arguments))
 	}
 
-	return &LLMResponse{
-		Content:      choice.Message.Content,
-		ToolCalls:    toolCalls,
-		FinishReason: choice.FinishReason,
-		Usage:        apiResponse.Usage,
-	}, nil
+	llmResp := &LLMResponse{
+		Content:         choice.Message.Content,
+		ToolCalls:       toolCalls,
+		FinishReason:    normalizeFinishReason(choice.FinishReason),
+		RawFinishReason: choice.FinishReason,
+		Usage:           completeUsage(apiResponse.Usage, apiResponse.Timings),
+	}
+	if audio := choice.Message.Audio; audio != nil {
+		// A spoken reply has no content; its transcript is the text
+		if llmResp.Content == "" {
+			llmResp.Content = audio.Transcript
+		}
+		llmResp.Parts = []ContentPart{audio.audioPart(decodeAudio(audio.Data))}
+	}
+	return llmResp, nil
 }
 
 func (p *HTTPProvider) GetDefaultModel() string {
-	return ""
+	return p.defaultModel.or(p.builtinModel)
 }
 
-func createClaudeAuthProvider() (LLMProvider, error) {
+func createClaudeAuthProvider(account string) (LLMProvider, error) {
+	// A named account always comes from the auth store
+	if account != "" {
+		return NewClaudeProviderWithDynamicToken(TokenManagerConfig{AuthAccount: account})
+	}
+
 	// Use the new auto provider with dynamic token management
 	// This automatically checks: environment → keychain → auth package
 	provider, err := NewClaudeProviderAuto()
 	if err != nil {
 		// Fallback to traditional method if auto fails
-		cred, err := auth.GetCredential("anthropic")
+		cred, err := auth.GetCredential("anthropic", "")
 		if err != nil {
 			return nil, fmt.Errorf("loading auth credentials: %w", err)
 		}
 		if cred == nil {
 			return nil, fmt.Errorf("no credentials for anthropic. Run: picoclaw auth login --provider anthropic")
 		}
-		return NewClaudeProviderWithTokenSource(cred.AccessToken, createClaudeTokenSource()), nil
+		return NewClaudeProviderWithTokenSource(cred.AccessToken, createClaudeTokenSource("")), nil
 	}
 	return provider, nil
 }
 
-func createCodexAuthProvider() (LLMProvider, error) {
-	cred, err := auth.GetCredential("openai")
+// createCodexAuthProvider creates the provider for a stored OpenAI
+// credential: the ChatGPT Codex backend for an OAuth login, or the
+// platform API for a pasted API key.
+func createCodexAuthProvider(pc config.ProviderConfig) (LLMProvider, error) {
+	account := pc.Account
+	cred, err := auth.GetCredential("openai", account)
 	if err != nil {
 		return nil, fmt.Errorf("loading auth credentials: %w", err)
 	}
 	if cred == nil {
-		return nil, fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
+		return nil, fmt.Errorf("no credentials for openai%s. Run: picoclaw auth login --provider openai%s", accountSuffix(account), accountFlag(account))
+	}
+	if cred.AuthMethod != "oauth" && isOpenAIAPIKey(cred.AccessToken) {
+		return createOpenAIProvider(cred.AccessToken, pc), nil
+	}
+	logger.AddSecret(cred.AccountID)
+	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource(account)), nil
+}
+
+// createOpenAIProvider creates the platform API provider for apiKey with
+// the organization, project and API of pc.
+func createOpenAIProvider(apiKey string, pc config.ProviderConfig) *OpenAIProvider {
+	p := NewOpenAIProvider(apiKey, pc.APIBase, pc.Organization, pc.Project)
+	p.SetAPI(pc.API)
+	return p
+}
+
+// newOpenModelHost creates the provider for an OpenAI-compatible host of
+// open models, such as Together, which takes the sampling extensions and
+// has builtinModel as its default model.
+func newOpenModelHost(pc config.ProviderConfig, name, builtinModel string) *HTTPProvider {
+	apiBase := pc.APIBase
+	if apiBase == "" {
+		apiBase = defaultAPIBases[name]
+	}
+	p := NewHTTPProvider(pc.APIKey, apiBase, pc.Proxy)
+	p.builtinModel = builtinModel
+	p.samplingExtensions = true
+	return p
+}
+
+// newRegionalProvider creates the provider for an OpenAI-compatible API
+// served from the region pc names, such as Moonshot, with builtinModel as
+// its default model.
+func newRegionalProvider(pc config.ProviderConfig, name, builtinModel string) (*HTTPProvider, error) {
+	apiBase, err := configuredAPIBase(name, &pc)
+	if err != nil {
+		return nil, err
 	}
-	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource()), nil
+	p := NewHTTPProvider(pc.APIKey, apiBase, pc.Proxy)
+	p.builtinModel = builtinModel
+	return p, nil
 }
 
+// newDashScopeProvider creates the provider for Alibaba Cloud's DashScope,
+// whose Qwen3 models only think in streamed responses.
+func newDashScopeProvider(pc config.ProviderConfig) (*HTTPProvider, error) {
+	p, err := newRegionalProvider(pc, "dashscope", "qwen-plus")
+	if err != nil {
+		return nil, err
+	}
+	p.nonStreamParams = map[string]interface{}{"enable_thinking": false}
+	return p, nil
+}
+
+// usesCopilotAPI reports whether pc calls the Copilot API with a GitHub
+// token rather than going through a Copilot CLI server.
+func usesCopilotAPI(pc config.ProviderConfig) bool {
+	return pc.ConnectMode == "api" || (pc.ConnectMode == "" && pc.APIKey != "")
+}
+
+// usesOpenAIPlatform reports whether pc calls api.openai.com with an API
+// key, rather than another OpenAI-compatible API.
+func usesOpenAIPlatform(pc config.ProviderConfig) bool {
+	return isOpenAIAPIKey(pc.APIKey) && (pc.APIBase == "" || strings.Contains(pc.APIBase, "api.openai.com"))
+}
+
+// CreateProvider builds the provider the config selects, behind the
+// response cache when it is enabled and the router when routing rules, an
+// overflow model or model aliases are configured.
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
-	model := cfg.Agents.Defaults.Model
-	providerName := strings.ToLower(cfg.Agents.Defaults.Provider)
+	ConfigureCatalog(cfg)
+	if len(cfg.Routing.Rules) > 0 || cfg.Routing.OverflowModel != "" || len(cfg.ModelAliases) > 0 {
+		return NewRouter(cfg, CreateProviderFor)
+	}
+	return CreateProviderFor(cfg, cfg.Agents.Defaults.Provider, cfg.Agents.Defaults.Model)
+}
+
+// CreateProviderFor builds the provider for providerName and model, using
+// the credentials in cfg. An empty providerName picks one from the model
+// name, as CreateProvider does, and a model alias selects the provider it
+// names. Providers with max_concurrent set queue requests beyond it.
+func CreateProviderFor(cfg *config.Config, providerName, model string) (LLMProvider, error) {
+	ConfigureCatalog(cfg)
+	providerName, model = cfg.ResolveModel(providerName, model)
+	warnIfDeprecated(model)
+	provider, err := createProvider(cfg, providerName, model)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(providerName)
+	if name == "" {
+		name = fmt.Sprintf("%T", provider)
+	}
+	if base := providerAPIBase(provider); base != "" {
+		// The same model name may be served by several endpoints
+		name += " " + base
+	}
+	if configured := schedulerProviderName(cfg, providerName, provider); configured != "" {
+		applyDefaultModel(cfg, configured, provider)
+		if pc := cfg.Providers.Get(configured); pc != nil && len(pc.Betas) > 0 {
+			if cp, ok := provider.(*ClaudeProvider); ok {
+				cp.SetBetas(pc.Betas)
+			}
+		}
+		if pc := cfg.Providers.Get(configured); pc != nil && pc.StreamStallTimeout > 0 {
+			provider = NewStallGuardProvider(provider, time.Duration(pc.StreamStallTimeout)*time.Second, pc.StreamStallResumes)
+		}
+		if pc := cfg.Providers.Get(configured); pc != nil && pc.MaxConcurrent > 0 {
+			provider = NewScheduledProvider(provider, providerScheduler(configured, pc.MaxConcurrent))
+		}
+		if pc := cfg.Providers.Get(configured); pc != nil && pc.RequestsPerMinute > 0 {
+			limiter, err := NewRateLimiter(cfg.RateLimit, cfg.WorkspacePath())
+			if err != nil {
+				return nil, err
+			}
+			// Requests wait for the budget before taking a scheduler slot
+			provider = NewRateLimitedProvider(provider, limiter, rateLimitKey(configured, pc), pc.RequestsPerMinute)
+		}
+	}
+	if !cfg.Cache.Enabled {
+		return provider, nil
+	}
+
+	backend, err := NewCacheBackend(cfg.Cache, cfg.WorkspacePath())
+	if err != nil {
+		return nil, err
+	}
+	// Cache hits are answered without waiting for a scheduler slot
+	return NewCachingProvider(provider, name, backend, time.Duration(cfg.Cache.TTL)*time.Second), nil
+}
+
+func createProvider(cfg *config.Config, providerName, model string) (LLMProvider, error) {
+	if err := resolveProviderSecrets(cfg); err != nil {
+		return nil, err
+	}
+
+	providerName = strings.ToLower(providerName)
 
 	var apiKey, apiBase, proxy string
 
@@ -248,7 +582,10 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 		case "openai", "gpt":
 			if cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != "" {
 				if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-					return createCodexAuthProvider()
+					return createCodexAuthProvider(cfg.Providers.OpenAI)
+				}
+				if usesOpenAIPlatform(cfg.Providers.OpenAI) {
+					return createOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI), nil
 				}
 				apiKey = cfg.Providers.OpenAI.APIKey
 				apiBase = cfg.Providers.OpenAI.APIBase
@@ -259,7 +596,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 		case "anthropic", "claude":
 			if cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != "" {
 				if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-					return createClaudeAuthProvider()
+					return createClaudeAuthProvider(cfg.Providers.Anthropic.Account)
 				}
 				apiKey = cfg.Providers.Anthropic.APIKey
 				apiBase = cfg.Providers.Anthropic.APIBase
@@ -297,6 +634,19 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 				apiKey = cfg.Providers.VLLM.APIKey
 				apiBase = cfg.Providers.VLLM.APIBase
 			}
+		case "ollama":
+			// Ollama serves an OpenAI-compatible API and ignores the key
+			pc := cfg.Providers.Ollama
+			if pc.APIKey == "" {
+				pc.APIKey = "ollama"
+			}
+			return newLocalServerProvider(pc, "ollama"), nil
+		case "lmstudio", "lm_studio", "lm-studio":
+			return newLocalServerProvider(cfg.Providers.LMStudio, "lmstudio"), nil
+		case "llamacpp", "llama_cpp", "llama.cpp", "llama-server":
+			return newLocalServerProvider(cfg.Providers.LlamaCpp, "llamacpp"), nil
+		case "local":
+			return detectLocalServer(cfg)
 		case "shengsuanyun":
 			if cfg.Providers.ShengSuanYun.APIKey != "" {
 				apiKey = cfg.Providers.ShengSuanYun.APIKey
@@ -325,7 +675,42 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 					model = "deepseek-chat"
 				}
 			}
+		case "together":
+			if cfg.Providers.Together.APIKey != "" {
+				return newOpenModelHost(cfg.Providers.Together, "together", "meta-llama/Llama-3.3-70B-Instruct-Turbo"), nil
+			}
+		case "fireworks":
+			if cfg.Providers.Fireworks.APIKey != "" {
+				return newOpenModelHost(cfg.Providers.Fireworks, "fireworks", "accounts/fireworks/models/llama-v3p3-70b-instruct"), nil
+			}
+		case "moonshot", "kimi":
+			if cfg.Providers.Moonshot.APIKey != "" {
+				return newRegionalProvider(cfg.Providers.Moonshot, "moonshot", "kimi-k2-0905-preview")
+			}
+		case "dashscope", "qwen":
+			if cfg.Providers.DashScope.APIKey != "" {
+				return newDashScopeProvider(cfg.Providers.DashScope)
+			}
+		case "azure_foundry", "foundry":
+			if cfg.Providers.AzureFoundry.APIBase != "" {
+				return newAzureFoundryProvider(cfg.Providers.AzureFoundry)
+			}
+		case "perplexity":
+			if cfg.Providers.Perplexity.APIKey != "" {
+				return NewPerplexityProvider(cfg.Providers.Perplexity.APIKey, cfg.Providers.Perplexity.APIBase, cfg.Providers.Perplexity.Proxy), nil
+			}
+		case "cohere":
+			if cfg.Providers.Cohere.APIKey != "" {
+				return NewCohereProvider(cfg.Providers.Cohere.APIKey, cfg.Providers.Cohere.APIBase, cfg.Providers.Cohere.Proxy), nil
+			}
+		case "github_models", "github":
+			if cfg.Providers.GitHubModels.APIKey != "" {
+				return NewGitHubModelsProvider(cfg.Providers.GitHubModels.APIKey, cfg.Providers.GitHubModels.APIBase), nil
+			}
 		case "github_copilot", "copilot":
+			if usesCopilotAPI(cfg.Providers.GitHubCopilot) {
+				return NewCopilotAPIProvider(cfg.Providers.GitHubCopilot.APIKey, cfg.Providers.GitHubCopilot.APIBase), nil
+			}
 			if cfg.Providers.GitHubCopilot.APIBase != "" {
 				apiBase = cfg.Providers.GitHubCopilot.APIBase
 			} else {
@@ -341,12 +726,10 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 	if apiKey == "" && apiBase == "" {
 		switch {
 		case (strings.Contains(lowerModel, "kimi") || strings.Contains(lowerModel, "moonshot") || strings.HasPrefix(model, "moonshot/")) && cfg.Providers.Moonshot.APIKey != "":
-			apiKey = cfg.Providers.Moonshot.APIKey
-			apiBase = cfg.Providers.Moonshot.APIBase
-			proxy = cfg.Providers.Moonshot.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.moonshot.cn/v1"
-			}
+			return newRegionalProvider(cfg.Providers.Moonshot, "moonshot", "kimi-k2-0905-preview")
+
+		case (strings.HasPrefix(lowerModel, "qwen-") || strings.HasPrefix(lowerModel, "qwen3") || strings.HasPrefix(lowerModel, "qwq") || strings.HasPrefix(model, "dashscope/")) && cfg.Providers.DashScope.APIKey != "":
+			return newDashScopeProvider(cfg.Providers.DashScope)
 
 		case strings.HasPrefix(model, "openrouter/") || strings.HasPrefix(model, "anthropic/") || strings.HasPrefix(model, "openai/") || strings.HasPrefix(model, "meta-llama/") || strings.HasPrefix(model, "deepseek/") || strings.HasPrefix(model, "google/"):
 			apiKey = cfg.Providers.OpenRouter.APIKey
@@ -359,7 +742,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 
 		case (strings.Contains(lowerModel, "claude") || strings.HasPrefix(model, "anthropic/")) && (cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != ""):
 			if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-				return createClaudeAuthProvider()
+				return createClaudeAuthProvider(cfg.Providers.Anthropic.Account)
 			}
 			apiKey = cfg.Providers.Anthropic.APIKey
 			apiBase = cfg.Providers.Anthropic.APIBase
@@ -370,7 +753,10 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 
 		case (strings.Contains(lowerModel, "gpt") || strings.HasPrefix(model, "openai/")) && (cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != ""):
 			if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-				return createCodexAuthProvider()
+				return createCodexAuthProvider(cfg.Providers.OpenAI)
+			}
+			if usesOpenAIPlatform(cfg.Providers.OpenAI) {
+				return createOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI), nil
 			}
 			apiKey = cfg.Providers.OpenAI.APIKey
 			apiBase = cfg.Providers.OpenAI.APIBase
@@ -395,6 +781,24 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 				apiBase = "https://open.bigmodel.cn/api/paas/v4"
 			}
 
+		case strings.HasPrefix(model, "together/") && cfg.Providers.Together.APIKey != "":
+			return newOpenModelHost(cfg.Providers.Together, "together", "meta-llama/Llama-3.3-70B-Instruct-Turbo"), nil
+
+		case (strings.HasPrefix(model, "fireworks/") || strings.HasPrefix(model, "accounts/fireworks/")) && cfg.Providers.Fireworks.APIKey != "":
+			return newOpenModelHost(cfg.Providers.Fireworks, "fireworks", "accounts/fireworks/models/llama-v3p3-70b-instruct"), nil
+
+		case strings.HasPrefix(model, "foundry/") && cfg.Providers.AzureFoundry.APIBase != "":
+			return newAzureFoundryProvider(cfg.Providers.AzureFoundry)
+
+		case (strings.HasPrefix(lowerModel, "sonar") || strings.HasPrefix(model, "perplexity/")) && cfg.Providers.Perplexity.APIKey != "":
+			return NewPerplexityProvider(cfg.Providers.Perplexity.APIKey, cfg.Providers.Perplexity.APIBase, cfg.Providers.Perplexity.Proxy), nil
+
+		case (strings.HasPrefix(lowerModel, "command") || strings.HasPrefix(model, "cohere/")) && cfg.Providers.Cohere.APIKey != "":
+			return NewCohereProvider(cfg.Providers.Cohere.APIKey, cfg.Providers.Cohere.APIBase, cfg.Providers.Cohere.Proxy), nil
+
+		case strings.HasPrefix(model, "github/") && cfg.Providers.GitHubModels.APIKey != "":
+			return NewGitHubModelsProvider(cfg.Providers.GitHubModels.APIKey, cfg.Providers.GitHubModels.APIBase), nil
+
 		case (strings.Contains(lowerModel, "groq") || strings.HasPrefix(model, "groq/")) && cfg.Providers.Groq.APIKey != "":
 			apiKey = cfg.Providers.Groq.APIKey
 			apiBase = cfg.Providers.Groq.APIBase
@@ -441,3 +845,19 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 
 	return NewHTTPProvider(apiKey, apiBase, proxy), nil
 }
+
+// accountSuffix and accountFlag format a named auth account for error
+// messages; both are empty for the default account.
+func accountSuffix(account string) string {
+	if account == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (account %q)", account)
+}
+
+func accountFlag(account string) string {
+	if account == "" {
+		return ""
+	}
+	return " --account " + account
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHTTPProvider_SamplingExtensions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Fireworks.APIKey = "fw-test"
+
+	provider, err := CreateProviderFor(cfg, "fireworks", "")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	p, ok := provider.(*HTTPProvider)
+	if !ok {
+		t.Fatalf("CreateProviderFor(fireworks) returned %T, want *HTTPProvider", provider)
+	}
+	if got := p.GetDefaultModel(); got != "accounts/fireworks/models/llama-v3p3-70b-instruct" {
+		t.Errorf("GetDefaultModel() = %q, want the built-in Fireworks model", got)
+	}
+
+	options := map[string]interface{}{OptionTopK: 40, OptionMinP: 0.05, OptionRepetitionPenalty: 1.1}
+	body := p.buildRequestBody([]Message{{Role: "user", Content: "Hi"}}, nil, "fireworks/qwen3-235b-a22b", options)
+	if body["model"] != "accounts/fireworks/models/qwen3-235b-a22b" {
+		t.Errorf("model = %v, want the Fireworks model ID", body["model"])
+	}
+	if body["top_k"] != 40 || body["min_p"] != 0.05 || body["repetition_penalty"] != 1.1 {
+		t.Errorf("body = %v, want top_k, min_p and repetition_penalty", body)
+	}
+
+	// Other OpenAI-compatible APIs are not sent them
+	plain := NewHTTPProvider("key", "https://api.openai.com/v1", "")
+	body = plain.buildRequestBody([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", options)
+	for _, key := range []string{"top_k", "min_p", "repetition_penalty"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("%s sent to an API that does not take it", key)
+		}
+	}
+}
+
+func TestCreateProviderFor_Regions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Moonshot = config.ProviderConfig{APIKey: "sk-moonshot", Region: "intl"}
+	cfg.Providers.DashScope = config.ProviderConfig{APIKey: "sk-dashscope", Region: "eu"}
+
+	provider, err := CreateProviderFor(cfg, "kimi", "")
+	if err != nil {
+		t.Fatalf("CreateProviderFor(kimi) error: %v", err)
+	}
+	p, ok := provider.(*HTTPProvider)
+	if !ok || p.apiBase != "https://api.moonshot.ai/v1" {
+		t.Errorf("CreateProviderFor(kimi) = %+v, want the international Moonshot endpoint", provider)
+	}
+
+	if _, err := CreateProviderFor(cfg, "", "qwen-max"); err == nil {
+		t.Error("CreateProviderFor(qwen-max) succeeded, want an unknown region error")
+	}
+}
+
+func TestDashScope_DisablesThinkingOutsideStreams(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.DashScope = config.ProviderConfig{APIKey: "sk-dashscope", APIBase: server.URL}
+	provider, err := CreateProviderFor(cfg, "", "dashscope/qwen3-32b")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	if _, err := provider.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "dashscope/qwen3-32b", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if body["model"] != "qwen3-32b" || body["enable_thinking"] != false {
+		t.Errorf("body = %v, want qwen3-32b with enable_thinking false", body)
+	}
+}
+
+func TestHTTPProvider_Audio(t *testing.T) {
+	var body struct {
+		Modalities []string                 `json:"modalities"`
+		Audio      map[string]string        `json:"audio"`
+		Messages   []map[string]interface{} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"content":null,"audio":{"id":"audio_1","data":"UklGRg==","transcript":"Hello there"}},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	messages := []Message{NewMessage("user", AudioPart("audio/mpeg", []byte("ID3")))}
+	resp, err := p.Chat(context.Background(), messages, nil, "gpt-4o-audio-preview", map[string]interface{}{OptionAudioVoice: "alloy"})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if len(body.Modalities) != 2 || body.Audio["voice"] != "alloy" || body.Audio["format"] != "wav" {
+		t.Errorf("modalities = %v, audio = %v, want a wav reply in alloy", body.Modalities, body.Audio)
+	}
+	content, _ := body.Messages[0]["content"].([]interface{})
+	if len(content) != 1 {
+		t.Fatalf("content = %v, want one input_audio part", body.Messages[0]["content"])
+	}
+	input, _ := content[0].(map[string]interface{})["input_audio"].(map[string]interface{})
+	if input["data"] != "SUQz" || input["format"] != "mp3" {
+		t.Errorf("input_audio = %v, want the MP3 base64 encoded", input)
+	}
+
+	if resp.Content != "Hello there" {
+		t.Errorf("Content = %q, want the transcript", resp.Content)
+	}
+	if len(resp.Parts) != 1 {
+		t.Fatalf("Parts = %+v, want the audio", resp.Parts)
+	}
+	audio := resp.Parts[0]
+	if audio.Type != ContentAudio || audio.MediaType != "audio/wav" || string(audio.Data) != "RIFF" || audio.ID != "audio_1" {
+		t.Errorf("audio = %+v, want the decoded WAV", audio)
+	}
+
+	// The reply goes back to the model as its transcript
+	wire := openAIMessages([]Message{NewMessage("assistant", audio)})
+	if got := wire[0].(map[string]interface{})["content"]; got != "Hello there" {
+		t.Errorf("assistant content = %v, want the transcript", got)
+	}
+}
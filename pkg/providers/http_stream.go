@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ChatStream streams a chat completion using server-sent events.
+func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	timer := newStreamTimer()
+	tools = p.supportedTools(tools, model)
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+	requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	applyAudioOutput(requestBody, options, "pcm16")
+
+	req, err := p.newChatRequest(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if dryRun(options) {
+		return nil, newDryRunError(req)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		err = newAPIError(resp, body)
+		if p.toolsRefused(err, tools, model) {
+			return p.ChatStream(ctx, messages, nil, model, options)
+		}
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		acc := newChatStreamAccumulator()
+		err := readSSE(resp.Body, func(_, data string) error {
+			if data == "[DONE]" {
+				return errSSEDone
+			}
+			chunkEvents, err := acc.add([]byte(data))
+			if err != nil {
+				return err
+			}
+			for _, ev := range chunkEvents {
+				timer.observe(ev)
+				if !sendStreamEvent(ctx, events, ev) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: err})
+			return
+		}
+		for _, ev := range acc.toolCalls.finish(-1) {
+			if !sendStreamEvent(ctx, events, ev) {
+				return
+			}
+		}
+		final := acc.response()
+		setAudioMediaType(final, requestBody)
+		setResponseMetadata(final, resp)
+		timer.finish(final)
+		sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventDone, Response: final})
+	}()
+	return events, nil
+}
+
+// chatStreamAccumulator assembles chat completion chunks into a response.
+type chatStreamAccumulator struct {
+	content      strings.Builder
+	toolCalls    *toolCallStream
+	audio        *openAIAudio // the spoken reply, its data in audioData
+	audioData    []byte
+	finishReason string
+	usage        *UsageInfo
+	timings      *llamaTimings
+}
+
+func newChatStreamAccumulator() *chatStreamAccumulator {
+	return &chatStreamAccumulator{toolCalls: newToolCallStream()}
+}
+
+// add merges one chunk and returns the events it produced.
+func (a *chatStreamAccumulator) add(data []byte) ([]StreamEvent, error) {
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content   string       `json:"content"`
+				Audio     *openAIAudio `json:"audio"`
+				ToolCalls []struct {
+					Index    int    `json:"index"`
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage   *UsageInfo    `json:"usage"`
+		Timings *llamaTimings `json:"timings"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+	}
+
+	if chunk.Usage != nil {
+		a.usage = chunk.Usage
+	}
+	if chunk.Timings != nil {
+		a.timings = chunk.Timings
+	}
+	if len(chunk.Choices) == 0 {
+		return nil, nil
+	}
+
+	var events []StreamEvent
+	choice := chunk.Choices[0]
+	if choice.Delta.Content != "" {
+		a.content.WriteString(choice.Delta.Content)
+		events = append(events, StreamEvent{Type: StreamEventText, Text: choice.Delta.Content})
+	}
+	if audio := choice.Delta.Audio; audio != nil {
+		if a.audio == nil {
+			a.audio = &openAIAudio{}
+		}
+		if audio.ID != "" {
+			a.audio.ID = audio.ID
+		}
+		// Each chunk of audio is encoded on its own
+		a.audioData = append(a.audioData, decodeAudio(audio.Data)...)
+		if audio.Transcript != "" {
+			// The transcript is the text of a spoken reply
+			a.audio.Transcript += audio.Transcript
+			a.content.WriteString(audio.Transcript)
+			events = append(events, StreamEvent{Type: StreamEventText, Text: audio.Transcript})
+		}
+	}
+	for _, tc := range choice.Delta.ToolCalls {
+		if _, ok := a.toolCalls.calls[tc.Index]; !ok {
+			// Calls arrive one after another, so a new index ends the
+			// previous one
+			events = append(events, a.toolCalls.finish(-1)...)
+		}
+		events = append(events, a.toolCalls.start(tc.Index, tc.ID, tc.Function.Name)...)
+		events = append(events, a.toolCalls.add(tc.Index, tc.Function.Arguments)...)
+	}
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		a.finishReason = *choice.FinishReason
+		events = append(events, a.toolCalls.finish(-1)...)
+	}
+	return events, nil
+}
+
+func (a *chatStreamAccumulator) response() *LLMResponse {
+	indexes := append([]int(nil), a.toolCalls.order...)
+	sort.Ints(indexes)
+
+	toolCalls := make([]ToolCall, 0, len(indexes))
+	for _, i := range indexes {
+		call := a.toolCalls.calls[i]
+		toolCalls = append(toolCalls, newToolCall(call.delta.ID, call.delta.Name, call.arguments.String()))
+	}
+
+	resp := &LLMResponse{
+		Content:         a.content.String(),
+		ToolCalls:       toolCalls,
+		FinishReason:    normalizeFinishReason(a.finishReason),
+		RawFinishReason: a.finishReason,
+		Usage:           completeUsage(a.usage, a.timings),
+	}
+	if a.audio != nil {
+		resp.Parts = []ContentPart{a.audio.audioPart(a.audioData)}
+	}
+	return resp
+}
+
+// errSSEDone stops readSSE without reporting an error.
+var errSSEDone = errors.New("sse stream done")
+
+// readSSE parses a server-sent event stream, calling fn with the event name
+// and data of each event. Returning errSSEDone from fn ends the stream
+// successfully.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if err == errSSEDone {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment / keepalive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading stream: %w", err)
+	}
+	if err := dispatch(); err != nil && err != errSSEDone {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// localServers are the OpenAI-compatible model servers that run on the
+// user's machine, in the order the local provider looks for them at their
+// configured or default addresses.
+var localServers = []string{"lmstudio", "llamacpp", "ollama"}
+
+// localProbeTimeout bounds how long detectLocalServer waits for each server.
+const localProbeTimeout = 500 * time.Millisecond
+
+// newLocalServerProvider creates the provider for a model server on the
+// user's machine, such as LM Studio or llama.cpp's llama-server. They
+// usually run without a key, and the loaded model may not take tools.
+func newLocalServerProvider(pc config.ProviderConfig, name string) *HTTPProvider {
+	apiBase := pc.APIBase
+	if apiBase == "" {
+		apiBase = defaultAPIBases[name]
+	}
+	p := NewHTTPProvider(pc.APIKey, apiBase, pc.Proxy)
+	p.localServer = true
+	return p
+}
+
+// detectLocalServer returns the first of localServers that answers at its
+// address, with the first model it serves as the default model.
+func detectLocalServer(cfg *config.Config) (*HTTPProvider, error) {
+	client := &http.Client{Timeout: localProbeTimeout}
+	var tried []string
+	for _, name := range localServers {
+		pc := cfg.Providers.Get(name)
+		apiBase := pc.APIBase
+		if apiBase == "" {
+			apiBase = defaultAPIBases[name]
+		}
+		tried = append(tried, apiBase)
+		model, ok := probeLocalServer(client, apiBase, pc.APIKey)
+		if !ok {
+			continue
+		}
+		local := *pc
+		local.APIBase = apiBase
+		if name == "ollama" && local.APIKey == "" {
+			local.APIKey = "ollama"
+		}
+		p := newLocalServerProvider(local, name)
+		p.builtinModel = model
+		return p, nil
+	}
+	return nil, fmt.Errorf("no local model server found at %s", strings.Join(tried, ", "))
+}
+
+// probeLocalServer lists the models of the server at apiBase, reporting
+// whether it answered and the first model it serves.
+func probeLocalServer(client *http.Client, apiBase, apiKey string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), localProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(apiBase, "/")+"/models", nil)
+	if err != nil {
+		return "", false
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	var list openAIModelList
+	if err := getModelsJSON(client, req, &list); err != nil {
+		return "", false
+	}
+	if len(list.Data) == 0 {
+		return "", true
+	}
+	return list.Data[0].ID, true
+}
+
+// supportedTools returns tools, or none when model on a local server has
+// refused them before.
+func (p *HTTPProvider) supportedTools(tools []ToolDefinition, model string) []ToolDefinition {
+	if _, refused := p.refusedTools.Load(model); refused {
+		return nil
+	}
+	return tools
+}
+
+// toolsRefused reports whether err is a local server refusing the tools of
+// a request, as llama-server does without --jinja and LM Studio does for
+// models without tool support. The model is then asked without tools from
+// then on.
+func (p *HTTPProvider) toolsRefused(err error, tools []ToolDefinition, model string) bool {
+	if !p.localServer || len(tools) == 0 {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode < 400 || !strings.Contains(strings.ToLower(apiErr.Body), "tool") {
+		return false
+	}
+	if _, seen := p.refusedTools.LoadOrStore(model, struct{}{}); !seen {
+		logger.WarnCF("provider", "Local model does not take tools, continuing without them",
+			map[string]interface{}{
+				"model":    model,
+				"api_base": p.apiBase,
+			})
+	}
+	return true
+}
+
+// llamaTimings is the timings object llama-server sends in place of, or
+// besides, usage.
+type llamaTimings struct {
+	PromptN    int `json:"prompt_n"`
+	PredictedN int `json:"predicted_n"`
+}
+
+// completeUsage fills in what a server left out of usage: the total, or
+// with no usage at all, the token counts of llama-server's timings.
+func completeUsage(usage *UsageInfo, timings *llamaTimings) *UsageInfo {
+	if usage == nil && timings != nil {
+		usage = &UsageInfo{PromptTokens: timings.PromptN, CompletionTokens: timings.PredictedN}
+	}
+	if usage != nil && usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestLocalServer_RefusedToolsAndTimings(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-7b-instruct","object":"model"}]}`))
+		case "/v1/chat/completions":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			requests = append(requests, body)
+			if _, ok := body["tools"]; ok {
+				http.Error(w, `{"error":{"code":500,"message":"tools param requires --jinja flag"}}`, http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}],"timings":{"prompt_n":12,"predicted_n":3}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.LMStudio.APIBase = server.URL + "/v1"
+	provider, err := CreateProviderFor(cfg, "local", "")
+	if err != nil {
+		t.Fatalf("CreateProviderFor(local) error: %v", err)
+	}
+	if got := provider.GetDefaultModel(); got != "qwen2.5-7b-instruct" {
+		t.Errorf("GetDefaultModel() = %q, want the loaded model", got)
+	}
+
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "read_file"}}}
+	for i := 0; i < 2; i++ {
+		resp, err := provider.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, tools, "qwen2.5-7b-instruct", nil)
+		if err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+		if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.TotalTokens != 15 {
+			t.Errorf("Usage = %+v, want the token counts of the timings", resp.Usage)
+		}
+	}
+	// Tools are tried once, then left out
+	if len(requests) != 3 {
+		t.Errorf("sent %d requests, want 3", len(requests))
+	}
+	if CapabilitiesOf(provider, "qwen2.5-7b-instruct").Tools {
+		t.Error("Capabilities report tools after the model refused them")
+	}
+}
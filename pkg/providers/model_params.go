@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// OptionReasoningEffort (a string: "minimal", "low", "medium" or "high")
+// sets how hard an OpenAI reasoning model thinks. Other models do not take
+// it, so it is dropped for them.
+const OptionReasoningEffort = "reasoning_effort"
+
+// modelParamSupport describes which request parameters a model accepts on
+// the OpenAI APIs, from the model catalog. Reasoning models reject
+// max_tokens, stop sequences and sampling parameters other than the
+// defaults, and alone take a reasoning effort.
+type modelParamSupport struct {
+	maxCompletionTokens bool // send max_completion_tokens instead of max_tokens
+	temperature         bool
+	topP                bool
+	stop                bool
+	reasoningEffort     bool
+	// fixedTemperature, when set, is the only temperature the model takes
+	fixedTemperature float64
+}
+
+// modelParams are the generation parameters of a request as the model
+// takes them. Unset parameters are nil or zero.
+type modelParams struct {
+	maxTokens           int
+	maxCompletionTokens bool
+	temperature         *float64
+	topP                *float64
+	stop                []string
+	reasoningEffort     string
+}
+
+// droppedParamWarnings remembers which model/parameter pairs were already
+// reported so the warning is logged once instead of on every request.
+var droppedParamWarnings sync.Map
+
+// lookupModelParams reads the parameter support of model (or Azure
+// deployment name) from the catalog. Models missing from it are assumed to
+// accept everything but a reasoning effort, and newer API versions accept
+// max_completion_tokens for every model.
+func lookupModelParams(model string) modelParamSupport {
+	entry, _ := LookupModel(model)
+	return modelParamSupport{
+		maxCompletionTokens: !entry.LegacyMaxTokens,
+		temperature:         !entry.FixedSampling,
+		topP:                !entry.FixedSampling,
+		stop:                !entry.FixedSampling,
+		reasoningEffort:     entry.FixedSampling && slices.Contains(entry.Capabilities, CapabilityReasoning),
+		fixedTemperature:    entry.Temperature,
+	}
+}
+
+// resolveModelParams reads the generation parameters from options and
+// fits them to what model takes: unsupported parameters are dropped and
+// out of range ones clamped, with a warning, rather than sent and
+// rejected by the API.
+func resolveModelParams(model string, options map[string]interface{}) modelParams {
+	support := lookupModelParams(model)
+	params := modelParams{maxCompletionTokens: support.maxCompletionTokens}
+	params.maxTokens, _ = options["max_tokens"].(int)
+
+	if temp, ok := options["temperature"].(float64); ok {
+		switch {
+		case !support.temperature:
+			warnDroppedParam(model, "temperature")
+		case support.fixedTemperature > 0:
+			if temp != support.fixedTemperature {
+				warnAdjustedParam(model, "temperature", temp, support.fixedTemperature)
+			}
+			params.temperature = openai.Ptr(support.fixedTemperature)
+		default:
+			params.temperature = openai.Ptr(clampParam(model, "temperature", temp, 0, 2))
+		}
+	}
+
+	if topP, ok := options["top_p"].(float64); ok {
+		if support.topP {
+			params.topP = openai.Ptr(clampParam(model, "top_p", topP, 0, 1))
+		} else {
+			warnDroppedParam(model, "top_p")
+		}
+	}
+
+	if stop, ok := options[OptionStop].([]string); ok && len(stop) > 0 {
+		if support.stop {
+			params.stop = stop
+		} else {
+			warnDroppedParam(model, "stop")
+		}
+	}
+
+	if effort, ok := options[OptionReasoningEffort].(string); ok && effort != "" {
+		if support.reasoningEffort {
+			params.reasoningEffort = effort
+		} else {
+			warnDroppedParam(model, "reasoning_effort")
+		}
+	}
+	return params
+}
+
+// applyChatModelParams copies the generation parameters in options into
+// params according to what the model supports.
+func applyChatModelParams(params *openai.ChatCompletionNewParams, model string, options map[string]interface{}) {
+	resolved := resolveModelParams(model, options)
+
+	if resolved.maxTokens > 0 {
+		if resolved.maxCompletionTokens {
+			params.MaxCompletionTokens = openai.Int(int64(resolved.maxTokens))
+		} else {
+			params.MaxTokens = openai.Int(int64(resolved.maxTokens))
+		}
+	}
+	if resolved.temperature != nil {
+		params.Temperature = openai.Float(*resolved.temperature)
+	}
+	if resolved.topP != nil {
+		params.TopP = openai.Float(*resolved.topP)
+	}
+	if len(resolved.stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: resolved.stop}
+	}
+	if resolved.reasoningEffort != "" {
+		params.ReasoningEffort = shared.ReasoningEffort(resolved.reasoningEffort)
+	}
+}
+
+// clampParam returns value limited to [low, high].
+func clampParam(model, param string, value, low, high float64) float64 {
+	clamped := value
+	if clamped < low {
+		clamped = low
+	} else if clamped > high {
+		clamped = high
+	}
+	if clamped != value {
+		warnAdjustedParam(model, param, value, clamped)
+	}
+	return clamped
+}
+
+func warnDroppedParam(model, param string) {
+	if _, seen := droppedParamWarnings.LoadOrStore(model+"\x00"+param, struct{}{}); seen {
+		return
+	}
+	logger.WarnCF("provider", "Dropping parameter unsupported by model",
+		map[string]interface{}{
+			"model": model,
+			"param": param,
+		})
+}
+
+func warnAdjustedParam(model, param string, from, to float64) {
+	if _, seen := droppedParamWarnings.LoadOrStore(model+"\x00"+param, struct{}{}); seen {
+		return
+	}
+	logger.WarnCF("provider", "Adjusting parameter to what the model accepts",
+		map[string]interface{}{
+			"model": model,
+			"param": param,
+			"from":  from,
+			"to":    to,
+		})
+}
@@ -0,0 +1,117 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestLookupModelParams(t *testing.T) {
+	tests := []struct {
+		model               string
+		maxCompletionTokens bool
+		temperature         bool
+	}{
+		{"gpt-5.2-chat", true, false},
+		{"o3-mini", true, false},
+		{"gpt-4o-mini", true, true},
+		{"gpt-4.1", true, true},
+		{"gpt-4", false, true},
+		{"gpt-35-turbo", false, true},
+		{"azure/GPT-5-mini", true, false},
+		{"some-custom-deployment", true, true},
+	}
+	for _, tt := range tests {
+		got := lookupModelParams(tt.model)
+		if got.maxCompletionTokens != tt.maxCompletionTokens {
+			t.Errorf("%s: maxCompletionTokens = %v, want %v", tt.model, got.maxCompletionTokens, tt.maxCompletionTokens)
+		}
+		if got.temperature != tt.temperature {
+			t.Errorf("%s: temperature = %v, want %v", tt.model, got.temperature, tt.temperature)
+		}
+	}
+}
+
+func TestApplyChatModelParams_ReasoningModel(t *testing.T) {
+	params := openai.ChatCompletionNewParams{}
+	applyChatModelParams(&params, "gpt-5.2-chat", map[string]interface{}{
+		"max_tokens":  1024,
+		"temperature": 0.7,
+		"top_p":       0.9,
+	})
+	if params.MaxCompletionTokens.Or(0) != 1024 {
+		t.Errorf("MaxCompletionTokens = %d, want 1024", params.MaxCompletionTokens.Or(0))
+	}
+	if params.MaxTokens.Valid() {
+		t.Error("MaxTokens should not be set for gpt-5 models")
+	}
+	if params.Temperature.Valid() {
+		t.Error("Temperature should be dropped for gpt-5 models")
+	}
+	if params.TopP.Valid() {
+		t.Error("TopP should be dropped for gpt-5 models")
+	}
+}
+
+func TestApplyChatModelParams_LegacyModel(t *testing.T) {
+	params := openai.ChatCompletionNewParams{}
+	applyChatModelParams(&params, "gpt-35-turbo", map[string]interface{}{
+		"max_tokens":  512,
+		"temperature": 0.2,
+	})
+	if params.MaxTokens.Or(0) != 512 {
+		t.Errorf("MaxTokens = %d, want 512", params.MaxTokens.Or(0))
+	}
+	if params.MaxCompletionTokens.Valid() {
+		t.Error("MaxCompletionTokens should not be set for gpt-35-turbo")
+	}
+	if params.Temperature.Or(0) != 0.2 {
+		t.Errorf("Temperature = %v, want 0.2", params.Temperature.Or(0))
+	}
+}
+
+func TestResolveModelParams(t *testing.T) {
+	options := map[string]interface{}{
+		"temperature":         2.5,
+		"top_p":               0.9,
+		OptionStop:            []string{"END"},
+		OptionReasoningEffort: "low",
+	}
+
+	chat := resolveModelParams("gpt-4o", options)
+	if chat.temperature == nil || *chat.temperature != 2 {
+		t.Errorf("gpt-4o temperature = %v, want it clamped to 2", chat.temperature)
+	}
+	if chat.topP == nil || len(chat.stop) != 1 || chat.reasoningEffort != "" {
+		t.Errorf("gpt-4o params = %+v, want top_p and stop without a reasoning effort", chat)
+	}
+
+	reasoning := resolveModelParams("gpt-5-mini", options)
+	if reasoning.temperature != nil || reasoning.topP != nil || reasoning.stop != nil {
+		t.Errorf("gpt-5-mini params = %+v, want no sampling parameters or stop", reasoning)
+	}
+	if reasoning.reasoningEffort != "low" {
+		t.Errorf("gpt-5-mini reasoning effort = %q, want low", reasoning.reasoningEffort)
+	}
+
+	kimi := resolveModelParams("moonshotai/kimi-k2-instruct", map[string]interface{}{"temperature": 0.3})
+	if kimi.temperature == nil || *kimi.temperature != 1 {
+		t.Errorf("kimi-k2 temperature = %v, want 1", kimi.temperature)
+	}
+}
+
+func TestBuildCodexParams_ModelParams(t *testing.T) {
+	options := map[string]interface{}{"temperature": 0.7, OptionReasoningEffort: "high"}
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-5", options)
+	if params.Temperature.Valid() {
+		t.Error("Temperature should be dropped for gpt-5")
+	}
+	if params.Reasoning.Effort != "high" {
+		t.Errorf("Reasoning.Effort = %q, want high", params.Reasoning.Effort)
+	}
+
+	params = buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", options)
+	if params.Temperature.Or(0) != 0.7 || params.Reasoning.Effort != "" {
+		t.Errorf("gpt-4.1: Temperature = %v, Reasoning.Effort = %q", params.Temperature.Or(0), params.Reasoning.Effort)
+	}
+}
@@ -0,0 +1,688 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const ollamaDefaultAPIBase = "http://localhost:11434/v1"
+
+// Model capabilities reported by ListModels.
+const (
+	CapabilityTools     = "tools"
+	CapabilityVision    = "vision"
+	CapabilityReasoning = "reasoning"
+	CapabilityEmbedding = "embedding"
+	CapabilityAudio     = "audio"
+)
+
+// ModelInfo describes one model a provider offers. ContextWindow and
+// MaxOutputTokens are zero when neither the API nor the built-in table
+// knows them.
+type ModelInfo struct {
+	ID              string   `json:"id"`
+	Provider        string   `json:"provider"`
+	Name            string   `json:"name,omitempty"`
+	ContextWindow   int      `json:"context_window,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+}
+
+// ProviderModels is the model listing of one provider. Error is set instead
+// of Models when the provider could not be queried.
+type ProviderModels struct {
+	Provider string      `json:"provider"`
+	Models   []ModelInfo `json:"models"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// newModelInfo fills in what the model catalog knows about model; callers
+// override the fields their API reports.
+func newModelInfo(provider, id, specName string) ModelInfo {
+	entry, _ := LookupModel(specName)
+	return ModelInfo{
+		ID:              id,
+		Provider:        provider,
+		ContextWindow:   entry.ContextWindow,
+		MaxOutputTokens: entry.MaxOutputTokens,
+		Capabilities:    append([]string(nil), entry.Capabilities...),
+	}
+}
+
+func (m *ModelInfo) addCapability(capability string) {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return
+		}
+	}
+	m.Capabilities = append(m.Capabilities, capability)
+}
+
+// sortCapabilities puts capabilities in a fixed order for display.
+func (m *ModelInfo) sortCapabilities() {
+	order := map[string]int{CapabilityTools: 0, CapabilityVision: 1, CapabilityReasoning: 2, CapabilityEmbedding: 3}
+	sort.SliceStable(m.Capabilities, func(i, j int) bool {
+		return order[m.Capabilities[i]] < order[m.Capabilities[j]]
+	})
+}
+
+// defaultAPIBases are the endpoints CreateProvider uses when api_base is empty.
+var defaultAPIBases = map[string]string{
+	"anthropic":     "https://api.anthropic.com/v1",
+	"openai":        "https://api.openai.com/v1",
+	"openrouter":    "https://openrouter.ai/api/v1",
+	"groq":          "https://api.groq.com/openai/v1",
+	"github_models": githubModelsDefaultAPIBase,
+	"cohere":        cohereDefaultAPIBase,
+	"together":      "https://api.together.xyz/v1",
+	"fireworks":     "https://api.fireworks.ai/inference/v1",
+	"perplexity":    perplexityDefaultAPIBase,
+	"zhipu":         "https://open.bigmodel.cn/api/paas/v4",
+	"gemini":        "https://generativelanguage.googleapis.com/v1beta",
+	"nvidia":        "https://integrate.api.nvidia.com/v1",
+	"moonshot":      "https://api.moonshot.cn/v1",
+	"dashscope":     "https://dashscope.aliyuncs.com/compatible-mode/v1",
+	"shengsuanyun":  "https://router.shengsuanyun.com/api/v1",
+	"deepseek":      "https://api.deepseek.com/v1",
+	"ollama":        ollamaDefaultAPIBase,
+	"lmstudio":      "http://localhost:1234/v1",
+	"llamacpp":      "http://localhost:8080/v1",
+}
+
+// regionalAPIBases are the endpoints of APIs served from several regions,
+// by the region a provider config names.
+var regionalAPIBases = map[string]map[string]string{
+	"moonshot": {
+		"cn":   "https://api.moonshot.cn/v1",
+		"intl": "https://api.moonshot.ai/v1",
+	},
+	"dashscope": {
+		"cn":   "https://dashscope.aliyuncs.com/compatible-mode/v1",
+		"intl": "https://dashscope-intl.aliyuncs.com/compatible-mode/v1",
+		"us":   "https://dashscope-us.aliyuncs.com/compatible-mode/v1",
+	},
+}
+
+// configuredAPIBase returns the endpoint of provider name: the api_base of
+// pc, the endpoint of its region, or the default one.
+func configuredAPIBase(name string, pc *config.ProviderConfig) (string, error) {
+	if pc.APIBase != "" {
+		return strings.TrimRight(pc.APIBase, "/"), nil
+	}
+	if pc.Region == "" {
+		return defaultAPIBases[name], nil
+	}
+	apiBase, ok := regionalAPIBases[name][strings.ToLower(pc.Region)]
+	if !ok {
+		return "", fmt.Errorf("unknown region %q for %s", pc.Region, name)
+	}
+	return apiBase, nil
+}
+
+// ListModels queries every configured provider, plus Azure OpenAI when it is
+// configured through the environment, and returns one listing per provider
+// sorted by name. Providers are queried concurrently.
+func ListModels(ctx context.Context, cfg *config.Config) ([]ProviderModels, error) {
+	if err := resolveProviderSecrets(cfg); err != nil {
+		return nil, err
+	}
+
+	names := configuredProviders(cfg)
+	listings := make([]ProviderModels, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			listings[i] = ProviderModels{Provider: name}
+			models, err := ListProviderModels(ctx, cfg, name)
+			if err != nil {
+				listings[i].Error = err.Error()
+				return
+			}
+			listings[i].Models = models
+		}(i, name)
+	}
+	wg.Wait()
+	return listings, nil
+}
+
+// configuredProviders returns the names of the providers with credentials,
+// plus azure when it is configured through the environment, sorted.
+func configuredProviders(cfg *config.Config) []string {
+	var names []string
+	for name, pc := range cfg.Providers.All() {
+		if providerConfigured(name, pc) {
+			names = append(names, name)
+		}
+	}
+	if azureConfig, err := LoadAzureConfigFromEnv(); err == nil && azureConfig != nil {
+		names = append(names, "azure")
+	}
+	sort.Strings(names)
+	return names
+}
+
+func providerConfigured(name string, pc *config.ProviderConfig) bool {
+	if pc.APIKey != "" || pc.AuthMethod != "" {
+		return true
+	}
+	// Local servers usually run without a key
+	return (name == "vllm" || name == "ollama" || name == "lmstudio" || name == "llamacpp") && pc.APIBase != ""
+}
+
+// ListProviderModels lists the models of one provider by calling its models
+// endpoint: OpenAI-compatible /models, the Anthropic and Gemini models APIs,
+// Azure OpenAI deployments or Ollama tags.
+func ListProviderModels(ctx context.Context, cfg *config.Config, name string) ([]ModelInfo, error) {
+	ConfigureCatalog(cfg)
+	if name == "azure" {
+		azureConfig, err := LoadAzureConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if azureConfig == nil {
+			return nil, fmt.Errorf("Azure OpenAI is not configured (set AZURE_OPENAI_ENDPOINT)")
+		}
+		return listAzureDeployments(ctx, azureConfig)
+	}
+
+	pc := cfg.Providers.Get(name)
+	if pc == nil {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	apiBase, err := configuredAPIBase(name, pc)
+	if err != nil {
+		return nil, err
+	}
+	if apiBase == "" {
+		return nil, fmt.Errorf("no API base configured for %s", name)
+	}
+	client := newModelsHTTPClient(pc.Proxy)
+
+	var models []ModelInfo
+	switch name {
+	case "anthropic":
+		models, err = listAnthropicModels(ctx, client, apiBase, pc)
+	case "gemini":
+		models, err = listGeminiModels(ctx, client, apiBase, pc.APIKey)
+	case "ollama":
+		models, err = listOllamaModels(ctx, client, apiBase)
+	case "github_copilot", "perplexity", "azure_foundry":
+		return nil, fmt.Errorf("model listing is not supported for %s", name)
+	case "github_models":
+		models, err = listGitHubModels(ctx, client, apiBase, pc.APIKey)
+	case "cohere":
+		models, err = listCohereModels(ctx, client, apiBase, pc.APIKey)
+	case "openai":
+		apiKey := pc.APIKey
+		if apiKey == "" {
+			apiKey, err = openAIStoredToken(pc)
+			if err != nil {
+				return nil, err
+			}
+		}
+		models, err = listOpenAICompatibleModels(ctx, client, name, apiBase, apiKey)
+	default:
+		models, err = listOpenAICompatibleModels(ctx, client, name, apiBase, pc.APIKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range models {
+		models[i].sortCapabilities()
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+func newModelsHTTPClient(proxy string) *http.Client {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if proxy != "" {
+		if proxyURL, err := url.Parse(proxy); err == nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		}
+	}
+	return client
+}
+
+// openAIStoredToken returns an API key saved with `picoclaw auth login`.
+// ChatGPT OAuth tokens only work against the Codex backend, which has no
+// models endpoint.
+func openAIStoredToken(pc *config.ProviderConfig) (string, error) {
+	if pc.AuthMethod == "oauth" {
+		return "", fmt.Errorf("model listing needs an OpenAI API key; ChatGPT OAuth logins cannot list models")
+	}
+	cred, err := auth.GetCredential("openai", pc.Account)
+	if err != nil {
+		return "", fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil {
+		return "", fmt.Errorf("no credentials for openai%s. Run: picoclaw auth login --provider openai%s", accountSuffix(pc.Account), accountFlag(pc.Account))
+	}
+	return cred.AccessToken, nil
+}
+
+// modelsStatusError is a models request answered with an error status.
+type modelsStatusError struct {
+	status int
+	body   string
+}
+
+func (e *modelsStatusError) Error() string {
+	return fmt.Sprintf("models request failed (HTTP %d): %s", e.status, e.body)
+}
+
+// getModelsJSON performs a models request and decodes the JSON response.
+func getModelsJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("listing models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading models response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &modelsStatusError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing models response: %w", err)
+	}
+	return nil
+}
+
+// openAIModelList is the reply of GET /models. Besides the standard fields
+// it picks up the context window extensions of OpenRouter, Groq, vLLM and
+// Together.
+type openAIModelList struct {
+	Data []struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		DisplayName   string `json:"display_name"`   // Together
+		ContextLength int    `json:"context_length"` // OpenRouter, Together
+		ContextWindow int    `json:"context_window"` // Groq
+		MaxModelLen   int    `json:"max_model_len"`  // vLLM
+		Architecture  struct {
+			InputModalities []string `json:"input_modalities"`
+		} `json:"architecture"`
+		TopProvider struct {
+			MaxCompletionTokens int `json:"max_completion_tokens"`
+		} `json:"top_provider"`
+		SupportedParameters []string `json:"supported_parameters"`
+	} `json:"data"`
+}
+
+// UnmarshalJSON also takes the bare array of models Together sends.
+func (l *openAIModelList) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(data, &l.Data)
+	}
+	type plain openAIModelList
+	return json.Unmarshal(data, (*plain)(l))
+}
+
+// listOpenAICompatibleModels reads GET /models.
+func listOpenAICompatibleModels(ctx context.Context, client *http.Client, provider, apiBase, apiKey string) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	var list openAIModelList
+	if err := getModelsJSON(client, req, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		info := newModelInfo(provider, m.ID, m.ID)
+		info.Name = m.Name
+		if info.Name == "" {
+			info.Name = m.DisplayName
+		}
+		for _, window := range []int{m.ContextLength, m.ContextWindow, m.MaxModelLen} {
+			if window > 0 {
+				info.ContextWindow = window
+				break
+			}
+		}
+		if m.TopProvider.MaxCompletionTokens > 0 {
+			info.MaxOutputTokens = m.TopProvider.MaxCompletionTokens
+		}
+		for _, modality := range m.Architecture.InputModalities {
+			if modality == "image" {
+				info.addCapability(CapabilityVision)
+			}
+		}
+		for _, param := range m.SupportedParameters {
+			switch param {
+			case "tools":
+				info.addCapability(CapabilityTools)
+			case "reasoning":
+				info.addCapability(CapabilityReasoning)
+			}
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+func listAnthropicModels(ctx context.Context, client *http.Client, apiBase string, pc *config.ProviderConfig) ([]ModelInfo, error) {
+	token := pc.APIKey
+	if token == "" {
+		var err error
+		token, err = createClaudeTokenSource(pc.Account)()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/models?limit=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if isClaudeOAuthToken(token) {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("anthropic-beta", claudeOAuthBeta)
+	} else {
+		req.Header.Set("x-api-key", token)
+	}
+
+	var list struct {
+		Data []struct {
+			ID             string `json:"id"`
+			DisplayName    string `json:"display_name"`
+			MaxInputTokens int    `json:"max_input_tokens"`
+			MaxTokens      int    `json:"max_tokens"`
+		} `json:"data"`
+	}
+	if err := getModelsJSON(client, req, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		info := newModelInfo("anthropic", m.ID, m.ID)
+		info.Name = m.DisplayName
+		if m.MaxInputTokens > 0 {
+			info.ContextWindow = m.MaxInputTokens
+		}
+		if m.MaxTokens > 0 {
+			info.MaxOutputTokens = m.MaxTokens
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// listGeminiModels uses the native Gemini models API, which reports token
+// limits for every model.
+func listGeminiModels(ctx context.Context, client *http.Client, apiBase, apiKey string) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/models?pageSize=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", apiKey)
+
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			OutputTokenLimit           int      `json:"outputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			Thinking                   bool     `json:"thinking"`
+		} `json:"models"`
+	}
+	if err := getModelsJSON(client, req, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(list.Models))
+	for _, m := range list.Models {
+		id := strings.TrimPrefix(m.Name, "models/")
+		info := newModelInfo("gemini", id, id)
+		info.Name = m.DisplayName
+		if m.InputTokenLimit > 0 {
+			info.ContextWindow = m.InputTokenLimit
+		}
+		if m.OutputTokenLimit > 0 {
+			info.MaxOutputTokens = m.OutputTokenLimit
+		}
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "embedContent" {
+				info.addCapability(CapabilityEmbedding)
+			}
+		}
+		if m.Thinking {
+			info.addCapability(CapabilityReasoning)
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// listAzureDeployments lists the deployments of an Azure OpenAI resource. The
+// data-plane deployments API was only kept in preview API versions, so one
+// is pinned here regardless of the version used for chat requests.
+func listAzureDeployments(ctx context.Context, azureConfig *AzureConfig) ([]ModelInfo, error) {
+	token, _, err := createAzureManagedIdentityTokenSource(azureConfig)()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimRight(azureConfig.Endpoint, "/") + "/openai/deployments?api-version=2023-03-15-preview"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var list struct {
+		Data []struct {
+			ID    string `json:"id"`
+			Model string `json:"model"`
+		} `json:"data"`
+	}
+	if err := getModelsJSON(newModelsHTTPClient(""), req, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, d := range list.Data {
+		info := newModelInfo("azure", d.ID, d.Model)
+		info.Name = d.Model
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// listOllamaModels reads the installed tags and asks /api/show for each
+// model's context length and capabilities. apiBase is the OpenAI-compatible
+// /v1 base used for chat.
+func listOllamaModels(ctx context.Context, client *http.Client, apiBase string) ([]ModelInfo, error) {
+	root := strings.TrimSuffix(apiBase, "/v1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, root+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := getModelsJSON(client, req, &tags); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		info := ModelInfo{ID: m.Name, Provider: "ollama"}
+		// Details are best effort; older servers lack some fields.
+		if show, err := showOllamaModel(ctx, client, root, m.Name); err == nil {
+			info.ContextWindow = show.contextLength()
+			for _, c := range show.Capabilities {
+				switch c {
+				case "tools":
+					info.addCapability(CapabilityTools)
+				case "vision":
+					info.addCapability(CapabilityVision)
+				case "thinking":
+					info.addCapability(CapabilityReasoning)
+				case "embedding":
+					info.addCapability(CapabilityEmbedding)
+				}
+			}
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// listGitHubModels reads the GitHub Models catalog, which lives beside the
+// inference API rather than at its /models.
+func listGitHubModels(ctx context.Context, client *http.Client, apiBase, token string) ([]ModelInfo, error) {
+	root := apiBase
+	for _, sep := range []string{"/orgs/", "/inference"} {
+		if idx := strings.Index(root, sep); idx >= 0 {
+			root = root[:idx]
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, root+"/catalog/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	var catalog []struct {
+		ID           string   `json:"id"`
+		Name         string   `json:"name"`
+		Capabilities []string `json:"capabilities"`
+		Limits       struct {
+			MaxInputTokens  int `json:"max_input_tokens"`
+			MaxOutputTokens int `json:"max_output_tokens"`
+		} `json:"limits"`
+		InputModalities []string `json:"supported_input_modalities"`
+	}
+	if err := getModelsJSON(client, req, &catalog); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(catalog))
+	for _, m := range catalog {
+		info := ModelInfo{
+			ID:              m.ID,
+			Provider:        "github_models",
+			Name:            m.Name,
+			ContextWindow:   m.Limits.MaxInputTokens,
+			MaxOutputTokens: m.Limits.MaxOutputTokens,
+		}
+		for _, c := range m.Capabilities {
+			switch c {
+			case "tool-calling":
+				info.addCapability(CapabilityTools)
+			case "reasoning":
+				info.addCapability(CapabilityReasoning)
+			}
+		}
+		for _, modality := range m.InputModalities {
+			if modality == "image" {
+				info.addCapability(CapabilityVision)
+			}
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// listCohereModels reads Cohere's chat models from the v1 models API.
+func listCohereModels(ctx context.Context, client *http.Client, apiBase, apiKey string) ([]ModelInfo, error) {
+	root := strings.TrimSuffix(apiBase, "/v2")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, root+"/v1/models?endpoint=chat", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	var list struct {
+		Models []struct {
+			Name          string   `json:"name"`
+			ContextLength float64  `json:"context_length"`
+			Features      []string `json:"features"`
+		} `json:"models"`
+	}
+	if err := getModelsJSON(client, req, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(list.Models))
+	for _, m := range list.Models {
+		info := ModelInfo{ID: m.Name, Provider: "cohere", ContextWindow: int(m.ContextLength)}
+		for _, f := range m.Features {
+			switch f {
+			case "tools", "strict_tools":
+				info.addCapability(CapabilityTools)
+			case "vision":
+				info.addCapability(CapabilityVision)
+			case "reasoning":
+				info.addCapability(CapabilityReasoning)
+			}
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+type ollamaShowResponse struct {
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+// contextLength finds the "<architecture>.context_length" entry.
+func (r *ollamaShowResponse) contextLength() int {
+	for key, value := range r.ModelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if n, ok := value.(float64); ok {
+				return int(n)
+			}
+		}
+	}
+	return 0
+}
+
+func showOllamaModel(ctx context.Context, client *http.Client, root, model string) (*ollamaShowResponse, error) {
+	payload, _ := json.Marshal(map[string]string{"model": model})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, root+"/api/show", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var show ollamaShowResponse
+	if err := getModelsJSON(client, req, &show); err != nil {
+		return nil, err
+	}
+	return &show, nil
+}
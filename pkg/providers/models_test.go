@@ -0,0 +1,206 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model   string
+		context int
+	}{
+		{"claude-sonnet-4-5-20250929", 200000},
+		{"anthropic/claude-3-5-haiku-latest", 200000},
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"gpt-4.1-nano", 1047576},
+		{"unknown-model", 0},
+	}
+	for _, tt := range tests {
+		if entry, _ := LookupModel(tt.model); entry.ContextWindow != tt.context {
+			t.Errorf("LookupModel(%q).ContextWindow = %d, want %d", tt.model, entry.ContextWindow, tt.context)
+		}
+	}
+}
+
+func TestListProviderModels_OpenAICompatible(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("path = %q, want /models", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer or-key" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer or-key")
+		}
+		w.Write([]byte(`{"data":[
+			{"id":"openai/gpt-4o","name":"GPT-4o","context_length":128000,"top_provider":{"max_completion_tokens":16384}},
+			{"id":"meta-llama/custom","context_length":65536,"architecture":{"input_modalities":["text","image"]},"supported_parameters":["tools","temperature"]}
+		]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.OpenRouter = config.ProviderConfig{APIKey: "or-key", APIBase: server.URL}
+
+	models, err := ListProviderModels(context.Background(), cfg, "openrouter")
+	if err != nil {
+		t.Fatalf("ListProviderModels() error = %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("len(models) = %d, want 2", len(models))
+	}
+
+	custom := models[0]
+	if custom.ID != "meta-llama/custom" || custom.ContextWindow != 65536 {
+		t.Errorf("models[0] = %+v, want meta-llama/custom with 65536 context", custom)
+	}
+	if want := []string{CapabilityTools, CapabilityVision}; !reflect.DeepEqual(custom.Capabilities, want) {
+		t.Errorf("Capabilities = %v, want %v", custom.Capabilities, want)
+	}
+
+	gpt := models[1]
+	if gpt.Name != "GPT-4o" || gpt.MaxOutputTokens != 16384 {
+		t.Errorf("models[1] = %+v, want GPT-4o with 16384 max output", gpt)
+	}
+	if want := []string{CapabilityTools, CapabilityVision}; !reflect.DeepEqual(gpt.Capabilities, want) {
+		t.Errorf("Capabilities = %v, want %v", gpt.Capabilities, want)
+	}
+}
+
+func TestListProviderModels_Anthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != "sk-ant-api-test" {
+			t.Errorf("x-api-key = %q, want %q", got, "sk-ant-api-test")
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Error("anthropic-version header missing")
+		}
+		w.Write([]byte(`{"data":[{"id":"claude-opus-4-1-20250805","display_name":"Claude Opus 4.1"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.Anthropic = config.ProviderConfig{APIKey: "sk-ant-api-test", APIBase: server.URL}
+
+	models, err := ListProviderModels(context.Background(), cfg, "anthropic")
+	if err != nil {
+		t.Fatalf("ListProviderModels() error = %v", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("len(models) = %d, want 1", len(models))
+	}
+	m := models[0]
+	if m.Name != "Claude Opus 4.1" || m.ContextWindow != 200000 {
+		t.Errorf("model = %+v, want Claude Opus 4.1 with 200000 context", m)
+	}
+}
+
+func TestListProviderModels_Ollama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"qwen3:8b"}]}`))
+		case "/api/show":
+			w.Write([]byte(`{"model_info":{"qwen3.context_length":40960},"capabilities":["completion","tools","thinking"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.Ollama = config.ProviderConfig{APIBase: server.URL + "/v1"}
+
+	models, err := ListProviderModels(context.Background(), cfg, "ollama")
+	if err != nil {
+		t.Fatalf("ListProviderModels() error = %v", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("len(models) = %d, want 1", len(models))
+	}
+	m := models[0]
+	if m.ID != "qwen3:8b" || m.ContextWindow != 40960 {
+		t.Errorf("model = %+v, want qwen3:8b with 40960 context", m)
+	}
+	if want := []string{CapabilityTools, CapabilityReasoning}; !reflect.DeepEqual(m.Capabilities, want) {
+		t.Errorf("Capabilities = %v, want %v", m.Capabilities, want)
+	}
+}
+
+func TestListProviderModels_GitHubModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog/models" || r.Header.Get("Authorization") != "Bearer github_pat_test" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"id":"openai/gpt-4.1","name":"OpenAI GPT-4.1","capabilities":["streaming","tool-calling"],` +
+			`"limits":{"max_input_tokens":1048576,"max_output_tokens":32768},"supported_input_modalities":["text","image"]}]`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.GitHubModels = config.ProviderConfig{APIKey: "github_pat_test", APIBase: server.URL + "/inference"}
+
+	models, err := ListProviderModels(context.Background(), cfg, "github_models")
+	if err != nil {
+		t.Fatalf("ListProviderModels() error = %v", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("len(models) = %d, want 1", len(models))
+	}
+	m := models[0]
+	if m.ID != "openai/gpt-4.1" || m.ContextWindow != 1048576 || m.MaxOutputTokens != 32768 {
+		t.Errorf("model = %+v, want openai/gpt-4.1 with its limits", m)
+	}
+	if want := []string{CapabilityTools, CapabilityVision}; !reflect.DeepEqual(m.Capabilities, want) {
+		t.Errorf("Capabilities = %v, want %v", m.Capabilities, want)
+	}
+}
+
+func TestListProviderModels_Together(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"meta-llama/Llama-3.3-70B-Instruct-Turbo","type":"chat","display_name":"Llama 3.3 70B","context_length":131072}]`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.Together = config.ProviderConfig{APIKey: "together-test", APIBase: server.URL}
+
+	models, err := ListProviderModels(context.Background(), cfg, "together")
+	if err != nil {
+		t.Fatalf("ListProviderModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "Llama 3.3 70B" || models[0].ContextWindow != 131072 {
+		t.Errorf("models = %+v, want Llama 3.3 70B with 131072 context", models)
+	}
+}
+
+func TestListModels_ReportsProviderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "")
+	t.Setenv("AZURE_OPENAI_API_VERSION", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "bad", APIBase: server.URL}
+
+	listings, err := ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(listings) != 1 || listings[0].Provider != "groq" {
+		t.Fatalf("listings = %+v, want only groq", listings)
+	}
+	if listings[0].Error == "" {
+		t.Error("expected an error for the rejected key")
+	}
+}
@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+const openAIDefaultAPIBase = "https://api.openai.com/v1"
+
+// OpenAIAPIChatCompletions selects the Chat Completions API for an
+// OpenAIProvider instead of the Responses API.
+const OpenAIAPIChatCompletions = "chat_completions"
+
+// OpenAIProvider calls the OpenAI platform API with an API key, billed to
+// the key's organization and project unless others are named.
+type OpenAIProvider struct {
+	client  *openai.Client
+	apiBase string
+	// chatCompletions sends requests to Chat Completions rather than the
+	// Responses API
+	chatCompletions bool
+	defaultModel
+}
+
+// NewOpenAIProvider creates a provider for apiKey. An empty apiBase is
+// api.openai.com; organization and project may be empty.
+func NewOpenAIProvider(apiKey, apiBase, organization, project string) *OpenAIProvider {
+	if apiBase == "" {
+		apiBase = openAIDefaultAPIBase
+	}
+	opts := []option.RequestOption{
+		option.WithBaseURL(apiBase),
+		option.WithAPIKey(apiKey),
+	}
+	if organization != "" {
+		opts = append(opts, option.WithOrganization(organization))
+	}
+	if project != "" {
+		opts = append(opts, option.WithProject(project))
+	}
+	client := openai.NewClient(opts...)
+	return &OpenAIProvider{client: &client, apiBase: strings.TrimRight(apiBase, "/")}
+}
+
+// SetAPI selects the API requests go to: OpenAIAPIChatCompletions, or the
+// Responses API for anything else.
+func (p *OpenAIProvider) SetAPI(api string) {
+	p.chatCompletions = api == OpenAIAPIChatCompletions
+}
+
+// isOpenAIAPIKey reports whether token is a platform API key rather than a
+// ChatGPT OAuth access token.
+func isOpenAIAPIKey(token string) bool {
+	return strings.HasPrefix(token, "sk-")
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	var httpResp *http.Response
+	opts := []option.RequestOption{option.WithResponseInto(&httpResp)}
+	if dryRun(options) {
+		opts = append(opts, option.WithMiddleware(dryRunMiddleware), option.WithMaxRetries(0))
+	}
+
+	var llmResp *LLMResponse
+	if p.chatCompletions {
+		params := openai.ChatCompletionNewParams{
+			Messages: azureChatMessages(messages),
+			Model:    model,
+			Tools:    translateToolsForChat(tools),
+		}
+		applyChatModelParams(&params, model, options)
+		resp, err := p.client.Chat.Completions.New(ctx, params, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("openai API call: %w", sdkAPIError(err))
+		}
+		llmResp = parseChatCompletionResponse(resp)
+	} else {
+		resp, err := p.client.Responses.New(ctx, buildResponsesParams(messages, tools, model, options), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("openai API call: %w", sdkAPIError(err))
+		}
+		llmResp = parseCodexResponse(resp)
+	}
+	setResponseMetadata(llmResp, httpResp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// ChatStream streams responses from the Responses API. Chat Completions
+// replies are sent whole.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	if p.chatCompletions || dryRun(options) {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		return replayResponse(resp), nil
+	}
+	return streamResponses(ctx, p.client, buildResponsesParams(messages, tools, model, options), nil, "openai"), nil
+}
+
+func (p *OpenAIProvider) GetDefaultModel() string {
+	return p.defaultModel.or("gpt-4o")
+}
+
+// translateToolsForChat converts tool definitions to Chat Completions
+// function tools.
+func translateToolsForChat(tools []ToolDefinition) []openai.ChatCompletionToolUnionParam {
+	if len(tools) == 0 {
+		return nil
+	}
+	result := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		fn := shared.FunctionDefinitionParam{
+			Name:       t.Function.Name,
+			Parameters: t.Function.Parameters,
+		}
+		if t.Function.Description != "" {
+			fn.Description = openai.Opt(t.Function.Description)
+		}
+		result = append(result, openai.ChatCompletionFunctionTool(fn))
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenAIProvider_Responses(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("OpenAI-Organization") != "org-1" || r.Header.Get("OpenAI-Project") != "proj-1" {
+			http.Error(w, "missing organization or project", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "resp_1", "object": "response", "status": "completed",
+			"output": []map[string]interface{}{{
+				"id": "msg_1", "type": "message", "role": "assistant", "status": "completed",
+				"content": []map[string]interface{}{{"type": "output_text", "text": "Hello!"}},
+			}},
+			"usage": map[string]interface{}{"input_tokens": 4, "output_tokens": 2, "total_tokens": 6},
+		})
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("sk-test", server.URL, "org-1", "proj-1")
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "Hello!" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello!")
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 6 {
+		t.Errorf("Usage = %+v, want 6 total tokens", resp.Usage)
+	}
+	if _, ok := body["instructions"]; ok {
+		t.Errorf("instructions = %v, want none without a system message", body["instructions"])
+	}
+}
+
+func TestOpenAIProvider_ChatCompletions(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt-4o",
+			"choices": []map[string]interface{}{{
+				"index": 0, "finish_reason": "tool_calls",
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id": "call_1", "type": "function",
+						"function": map[string]interface{}{"name": "get_weather", "arguments": `{"city":"Oslo"}`},
+					}},
+				},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("sk-test", server.URL, "", "")
+	p.SetAPI(OpenAIAPIChatCompletions)
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{
+		Name:        "get_weather",
+		Description: "Get the weather",
+		Parameters:  map[string]interface{}{"type": "object"},
+	}}}
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Weather in Oslo?"}}, tools, "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_weather" || resp.ToolCalls[0].Arguments["city"] != "Oslo" {
+		t.Fatalf("ToolCalls = %+v, want get_weather for Oslo", resp.ToolCalls)
+	}
+	if sent, _ := body["tools"].([]interface{}); len(sent) != 1 {
+		t.Errorf("tools = %v, want the one function", body["tools"])
+	}
+}
+
+func TestCreateProvider_OpenAIAPIKey(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "sk-test"
+	cfg.Providers.OpenAI.Organization = "org-1"
+
+	provider, err := CreateProviderFor(cfg, "openai", "gpt-4o")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	if _, ok := provider.(*OpenAIProvider); !ok {
+		t.Errorf("CreateProviderFor(openai) returned %T, want *OpenAIProvider", provider)
+	}
+
+	cfg.Providers.OpenAI.APIBase = "http://localhost:8080/v1"
+	provider, err = CreateProviderFor(cfg, "openai", "gpt-4o")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	if _, ok := provider.(*HTTPProvider); !ok {
+		t.Errorf("CreateProviderFor(openai) with a custom api_base returned %T, want *HTTPProvider", provider)
+	}
+}
@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParsePartialJSON parses the arguments of a tool call that is still being
+// streamed. Open strings, objects and arrays are closed and a trailing
+// incomplete key or value is dropped, so `{"location": "Sea` yields
+// location=Sea. It returns nil until an object has started.
+func ParsePartialJSON(s string) map[string]interface{} {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return nil
+	}
+
+	var out map[string]interface{}
+	if json.Unmarshal([]byte(s), &out) == nil {
+		return out
+	}
+
+	// Cut back to ever earlier element boundaries until what remains closes
+	// into valid JSON
+	cut := len(s)
+	for cut > 0 {
+		prefix := s[:cut]
+		closed, boundary := closeJSON(prefix)
+		if json.Unmarshal([]byte(closed), &out) == nil {
+			return out
+		}
+		// `{"a":` has a key without a value yet
+		if strings.HasSuffix(strings.TrimSpace(prefix), ":") {
+			if json.Unmarshal([]byte(closeAfter(prefix, "null")), &out) == nil {
+				return out
+			}
+		}
+		if boundary >= cut {
+			break
+		}
+		cut = boundary
+	}
+	return map[string]interface{}{}
+}
+
+// closeJSON appends whatever closes the strings and containers open at the
+// end of s. It also returns the offset of the last element boundary (a
+// comma or opening bracket outside strings, exclusive of the comma) for the
+// caller to retry from.
+func closeJSON(s string) (string, int) {
+	var stack []byte
+	inString, escaped := false, false
+	boundary := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+			boundary = i + 1
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			boundary = i
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s)
+	if inString {
+		if escaped {
+			// Drop a dangling backslash
+			return closeJSON(s[:len(s)-1])
+		}
+		sb.WriteByte('"')
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			sb.WriteByte('}')
+		} else {
+			sb.WriteByte(']')
+		}
+	}
+	if boundary == len(s) {
+		boundary = 0
+	}
+	return sb.String(), boundary
+}
+
+func closeAfter(s, value string) string {
+	closed, _ := closeJSON(s + value)
+	return closed
+}
+
+// FormatToolCallPreview renders a call as name(key=value, …) with each value
+// cut to maxValue characters, for showing a call while it streams in.
+func FormatToolCallPreview(name string, args map[string]interface{}, maxValue int) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var value string
+		switch v := args[k].(type) {
+		case string:
+			value = v
+		case nil:
+			value = "…"
+		default:
+			data, _ := json.Marshal(v)
+			value = string(data)
+		}
+		if runes := []rune(value); maxValue > 0 && len(runes) > maxValue {
+			value = string(runes[:maxValue]) + "…"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", k, value))
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(parts, ", "))
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const perplexityDefaultAPIBase = "https://api.perplexity.ai"
+
+// PerplexityProvider calls Perplexity's chat completions API. Its sonar
+// models search the web for each answer and report the pages they used,
+// which come back as LLMResponse.Citations.
+type PerplexityProvider struct {
+	*HTTPProvider
+}
+
+func NewPerplexityProvider(apiKey, apiBase, proxy string) *PerplexityProvider {
+	if apiBase == "" {
+		apiBase = perplexityDefaultAPIBase
+	}
+	p := NewHTTPProvider(apiKey, apiBase, proxy)
+	p.builtinModel = "sonar"
+	return &PerplexityProvider{HTTPProvider: p}
+}
+
+func (p *PerplexityProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	start := time.Now()
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	if dryRun(options) {
+		return nil, p.dryRunChat(ctx, requestBody)
+	}
+	body, resp, err := p.postChat(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	llmResp, err := p.parseResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	if llmResp.Citations, err = parsePerplexityCitations(body); err != nil {
+		return nil, err
+	}
+	setResponseMetadata(llmResp, resp)
+	setDuration(llmResp, start)
+	return llmResp, nil
+}
+
+// ChatStream sends the whole reply at once, so that it carries the
+// citations.
+func (p *PerplexityProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+// parsePerplexityCitations reads the citations of a sonar response. The
+// citations array lists the URLs the answer's [1], [2], ... markers refer
+// to, in order, so each citation's SourceID is its marker number; the title,
+// snippet and date of a page come from search_results. Search results the
+// answer does not mark are listed after them.
+func parsePerplexityCitations(body []byte) ([]Citation, error) {
+	var resp struct {
+		Citations     []string `json:"citations"`
+		SearchResults []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Date    string `json:"date"`
+			Snippet string `json:"snippet"`
+		} `json:"search_results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var citations []Citation
+	cited := make(map[string]int)
+	for i, u := range resp.Citations {
+		cited[u] = len(citations)
+		citations = append(citations, Citation{URL: u, SourceID: strconv.Itoa(i + 1)})
+	}
+	for _, r := range resp.SearchResults {
+		i, ok := cited[r.URL]
+		if !ok {
+			i = len(citations)
+			citations = append(citations, Citation{URL: r.URL})
+		}
+		citations[i].Title = r.Title
+		citations[i].Text = r.Snippet
+		citations[i].Date = r.Date
+	}
+	return citations, nil
+}
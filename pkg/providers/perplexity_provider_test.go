@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPerplexityProvider_Citations(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer pplx-test" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{
+			"id": "resp-1",
+			"model": "sonar-pro",
+			"choices": [{"index": 0, "finish_reason": "stop",
+				"message": {"role": "assistant", "content": "Emperor penguins are the tallest [1]."}}],
+			"citations": ["https://example.com/penguins"],
+			"search_results": [
+				{"title": "Tall penguins", "url": "https://example.com/penguins", "date": "2025-01-10", "snippet": "Emperor penguins stand up to 1.3 m."},
+				{"title": "Penguin facts", "url": "https://example.com/facts"}
+			],
+			"usage": {"prompt_tokens": 9, "completion_tokens": 8, "total_tokens": 17}
+		}`))
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.Perplexity = config.ProviderConfig{APIKey: "pplx-test", APIBase: server.URL}
+	provider, err := CreateProviderFor(cfg, "", "perplexity/sonar-pro")
+	if err != nil {
+		t.Fatalf("CreateProviderFor() error: %v", err)
+	}
+	resp, err := provider.Chat(context.Background(), []Message{{Role: "user", Content: "Which penguins are the tallest?"}}, nil, "perplexity/sonar-pro", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if body["model"] != "sonar-pro" {
+		t.Errorf("model = %v, want sonar-pro", body["model"])
+	}
+	if resp.Content != "Emperor penguins are the tallest [1]." || resp.Usage == nil || resp.Usage.TotalTokens != 17 {
+		t.Errorf("response = %q (%+v), want the answer and its usage", resp.Content, resp.Usage)
+	}
+	want := []Citation{
+		{URL: "https://example.com/penguins", Title: "Tall penguins", SourceID: "1", Text: "Emperor penguins stand up to 1.3 m.", Date: "2025-01-10"},
+		{URL: "https://example.com/facts", Title: "Penguin facts"},
+	}
+	if !reflect.DeepEqual(resp.Citations, want) {
+		t.Errorf("Citations = %+v, want %+v", resp.Citations, want)
+	}
+}
@@ -0,0 +1,270 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/filelock"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// RateLimiter counts requests against a budget per time window. The file
+// and Redis limiters keep the counts outside the process, so several
+// picoclaw processes using one API key share its budget. Implementations
+// must be safe for concurrent use.
+type RateLimiter interface {
+	// Take counts a request against the budget of limit requests per
+	// window under key. When the budget is spent it counts nothing and
+	// returns how long until the next window.
+	Take(ctx context.Context, key string, limit int, window time.Duration) (wait time.Duration, err error)
+}
+
+// NewRateLimiter returns the limiter cfg selects. The memory limiter is
+// shared by the whole process; the file limiter defaults to
+// <workspace>/ratelimit.
+func NewRateLimiter(cfg config.RateLimitConfig, workspace string) (RateLimiter, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", "memory":
+		return processRateLimiter, nil
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = filepath.Join(workspace, "ratelimit")
+		}
+		return NewFileRateLimiter(dir)
+	case "redis":
+		return NewRedisRateLimiter(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q (want memory, file or redis)", cfg.Backend)
+	}
+}
+
+// rateWindow returns the fixed window now falls in, and how long until the
+// next one.
+func rateWindow(now time.Time, window time.Duration) (int64, time.Duration) {
+	n := now.UnixNano() / int64(window)
+	return n, time.Duration((n+1)*int64(window) - now.UnixNano())
+}
+
+// processRateLimiter is the memory limiter every provider in the process
+// shares.
+var processRateLimiter = NewMemoryRateLimiter()
+
+// MemoryRateLimiter counts requests within the process.
+type MemoryRateLimiter struct {
+	mu     sync.Mutex
+	counts map[string]rateCount
+}
+
+// rateCount is the requests counted in one window.
+type rateCount struct {
+	Window int64 `json:"window"`
+	Count  int   `json:"count"`
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{counts: map[string]rateCount{}}
+}
+
+func (l *MemoryRateLimiter) Take(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current, wait := rateWindow(time.Now(), window)
+	count := l.counts[key]
+	if count.Window != current {
+		count = rateCount{Window: current}
+	}
+	if count.Count >= limit {
+		return wait, nil
+	}
+	count.Count++
+	l.counts[key] = count
+	return 0, nil
+}
+
+// FileRateLimiter keeps one JSON file of counts per key in a directory,
+// guarded by an advisory lock, so processes on one machine share budgets.
+type FileRateLimiter struct {
+	dir string
+}
+
+// NewFileRateLimiter keeps counts in dir.
+func NewFileRateLimiter(dir string) (*FileRateLimiter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create rate limit directory: %w", err)
+	}
+	return &FileRateLimiter{dir: dir}, nil
+}
+
+func (l *FileRateLimiter) Take(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	path := filepath.Join(l.dir, key+".json")
+	unlock, err := filelock.LockContext(ctx, path+".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	current, wait := rateWindow(time.Now(), window)
+	var count rateCount
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &count)
+	}
+	if count.Window != current {
+		count = rateCount{Window: current}
+	}
+	if count.Count >= limit {
+		return wait, nil
+	}
+	count.Count++
+	data, _ := json.Marshal(count)
+	return 0, os.WriteFile(path, data, 0644)
+}
+
+// redisRateKeyPrefix namespaces budgets in a shared Redis database.
+const redisRateKeyPrefix = "picoclaw:ratelimit:"
+
+// RedisRateLimiter counts requests in Redis, so processes on several
+// machines share budgets. It talks to Redis as RedisCache does.
+type RedisRateLimiter struct {
+	client *RedisCache
+}
+
+// NewRedisRateLimiter connects lazily to rawURL; see NewRedisCache.
+func NewRedisRateLimiter(rawURL string) (*RedisRateLimiter, error) {
+	client, err := NewRedisCache(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisRateLimiter{client: client}, nil
+}
+
+func (l *RedisRateLimiter) Take(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	current, wait := rateWindow(time.Now(), window)
+	redisKey := redisRateKeyPrefix + key + ":" + strconv.FormatInt(current, 10)
+	reply, err := l.client.do(ctx, "INCR", redisKey)
+	if err != nil {
+		return 0, err
+	}
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply to INCR: %v", reply)
+	}
+	if count == 1 {
+		// The count outlives its window only briefly
+		if _, err := l.client.do(ctx, "PEXPIRE", redisKey, strconv.FormatInt((window+wait).Milliseconds(), 10)); err != nil {
+			return 0, err
+		}
+	}
+	if count > int64(limit) {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// RateLimitedProvider holds requests back until its budget of requests per
+// minute allows them. A limiter that fails lets requests through, with a
+// warning, rather than stopping them all.
+type RateLimitedProvider struct {
+	provider LLMProvider
+	limiter  RateLimiter
+	key      string
+	limit    int
+}
+
+// NewRateLimitedProvider wraps provider, whose budget of limit requests a
+// minute limiter counts under key.
+func NewRateLimitedProvider(provider LLMProvider, limiter RateLimiter, key string, limit int) *RateLimitedProvider {
+	return &RateLimitedProvider{provider: provider, limiter: limiter, key: key, limit: limit}
+}
+
+// Unwrap returns the provider behind the limiter.
+func (p *RateLimitedProvider) Unwrap() LLMProvider {
+	return p.provider
+}
+
+func (p *RateLimitedProvider) GetDefaultModel() string {
+	return p.provider.GetDefaultModel()
+}
+
+func (p *RateLimitedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	waited, err := p.wait(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.provider.Chat(ctx, messages, tools, model, options)
+	if resp != nil {
+		resp.timing().QueueTime += waited
+	}
+	return resp, err
+}
+
+func (p *RateLimitedProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	waited, err := p.wait(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := ChatStream(ctx, p.provider, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		for ev := range upstream {
+			if ev.Type == StreamEventDone && ev.Response != nil {
+				ev.Response.timing().QueueTime += waited
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				// Drain so the provider's goroutine can finish
+				for range upstream {
+				}
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// wait blocks until the budget allows a request, returning how long it
+// waited.
+func (p *RateLimitedProvider) wait(ctx context.Context, model string) (time.Duration, error) {
+	start := time.Now()
+	for {
+		wait, err := p.limiter.Take(ctx, p.key, p.limit, time.Minute)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			logger.WarnCF("provider", "Rate limiter failed, sending request anyway",
+				map[string]interface{}{"model": model, "error": err.Error()})
+			return time.Since(start), nil
+		}
+		if wait == 0 {
+			return time.Since(start), nil
+		}
+		logger.DebugCF("provider", "Request budget spent, waiting",
+			map[string]interface{}{"model": model, "wait": wait.String()})
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitKey identifies the budget of the configured provider name: the
+// account its API key belongs to, without revealing the key.
+func rateLimitKey(name string, pc *config.ProviderConfig) string {
+	sum := sha256.Sum256([]byte(pc.APIKey))
+	return name + "-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/filelock"
+)
+
+func TestFileRateLimiter_SharedBudget(t *testing.T) {
+	dir := t.TempDir()
+	// Two limiters on one directory stand for two processes
+	a, err := NewFileRateLimiter(dir)
+	if err != nil {
+		t.Fatalf("NewFileRateLimiter() error = %v", err)
+	}
+	b, _ := NewFileRateLimiter(dir)
+	ctx := context.Background()
+
+	for i, l := range []RateLimiter{a, b} {
+		if wait, err := l.Take(ctx, "openai", 2, time.Minute); wait != 0 || err != nil {
+			t.Fatalf("Take() #%d = %v, %v; want it allowed", i+1, wait, err)
+		}
+	}
+	wait, err := a.Take(ctx, "openai", 2, time.Minute)
+	if err != nil || wait <= 0 || wait > time.Minute {
+		t.Errorf("Take() over budget = %v, %v; want a wait until the next minute", wait, err)
+	}
+	if wait, _ := b.Take(ctx, "anthropic", 2, time.Minute); wait != 0 {
+		t.Errorf("Take() of another key waited %v", wait)
+	}
+}
+
+func TestFileRateLimiter_StopsWaitingForLock(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewFileRateLimiter(dir)
+	if err != nil {
+		t.Fatalf("NewFileRateLimiter() error = %v", err)
+	}
+	// Another process is stuck holding the budget's lock
+	unlock, err := filelock.Lock(filepath.Join(dir, "openai.json.lock"))
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Take(ctx, "openai", 2, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Take() error = %v, want the context's deadline", err)
+	}
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	addr, commands := fakeRedis(t, "")
+	l, err := NewRedisRateLimiter("redis://" + addr)
+	if err != nil {
+		t.Fatalf("NewRedisRateLimiter() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if wait, err := l.Take(ctx, "openai", 1, time.Minute); wait != 0 || err != nil {
+		t.Fatalf("Take() = %v, %v; want it allowed", wait, err)
+	}
+	if wait, err := l.Take(ctx, "openai", 1, time.Minute); wait <= 0 || err != nil {
+		t.Errorf("Take() over budget = %v, %v; want a wait", wait, err)
+	}
+
+	got := commands()
+	if len(got) != 3 || !strings.HasPrefix(got[0], "INCR picoclaw:ratelimit:openai:") || !strings.HasPrefix(got[1], "PEXPIRE ") {
+		t.Errorf("commands = %q, want INCR, PEXPIRE on the first request, then INCR", got)
+	}
+}
+
+// stepLimiter makes the first request wait briefly.
+type stepLimiter struct {
+	takes int
+}
+
+func (l *stepLimiter) Take(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	l.takes++
+	if l.takes == 1 {
+		return 20 * time.Millisecond, nil
+	}
+	return 0, nil
+}
+
+func TestRateLimitedProvider_Waits(t *testing.T) {
+	limiter := &stepLimiter{}
+	p := NewRateLimitedProvider(&countingProvider{}, limiter, "test", 1)
+
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "test", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if limiter.takes != 2 {
+		t.Errorf("Take() called %d times, want a retry after the wait", limiter.takes)
+	}
+	if resp.Timing == nil || resp.Timing.QueueTime < 20*time.Millisecond {
+		t.Errorf("Timing = %+v, want the wait as queue time", resp.Timing)
+	}
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"context"
+	"sync"
+)
+
+// ReloadableProvider sends requests to a provider that can be replaced
+// while in use, such as one created again from a reloaded config.
+// Requests already sent finish on the provider they started with.
+type ReloadableProvider struct {
+	mu       sync.RWMutex
+	provider LLMProvider
+}
+
+// NewReloadableProvider sends requests to provider until Swap replaces it.
+func NewReloadableProvider(provider LLMProvider) *ReloadableProvider {
+	return &ReloadableProvider{provider: provider}
+}
+
+// Swap sends later requests to provider.
+func (p *ReloadableProvider) Swap(provider LLMProvider) {
+	p.mu.Lock()
+	p.provider = provider
+	p.mu.Unlock()
+}
+
+// Unwrap returns the current provider.
+func (p *ReloadableProvider) Unwrap() LLMProvider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.provider
+}
+
+// Capabilities describes the current provider.
+func (p *ReloadableProvider) Capabilities(model string) Capabilities {
+	return CapabilitiesOf(p.Unwrap(), model)
+}
+
+func (p *ReloadableProvider) GetDefaultModel() string {
+	return p.Unwrap().GetDefaultModel()
+}
+
+func (p *ReloadableProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.Unwrap().Chat(ctx, messages, tools, model, options)
+}
+
+func (p *ReloadableProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	return ChatStream(ctx, p.Unwrap(), messages, tools, model, options)
+}
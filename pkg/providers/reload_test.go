@@ -0,0 +1,31 @@
+package providers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReloadableProvider_Swap(t *testing.T) {
+	first, second := &countingProvider{}, &countingProvider{}
+	p := NewReloadableProvider(first)
+	ctx := context.Background()
+
+	if _, err := p.Chat(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "test", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	p.Swap(second)
+	events, err := p.ChatStream(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "test", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+	if _, err := ConsumeStream(events, nil); err != nil {
+		t.Fatalf("ConsumeStream() error: %v", err)
+	}
+
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("calls = %d, %d; want 1, 1", first.calls, second.calls)
+	}
+	if p.Unwrap() != second {
+		t.Error("Unwrap() is not the swapped in provider")
+	}
+}
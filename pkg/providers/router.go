@@ -0,0 +1,401 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Request options the router reads. Callers set them to describe a request
+// the messages alone do not: "tags" is a []string such as {"cheap"} and
+// "has_images" a bool for requests with images sent outside the messages'
+// image parts.
+const (
+	OptionTags      = "tags"
+	OptionHasImages = "has_images"
+)
+
+// ProviderFactory creates the provider for providerName and model, as
+// CreateProviderFor does.
+type ProviderFactory func(cfg *config.Config, providerName, model string) (LLMProvider, error)
+
+// Router sends each request to the provider and model of the first routing
+// rule it matches, or to the default provider and model when none does.
+// Requests for a model other than the default bypass the rules, so an
+// explicitly chosen model is always honored; model aliases are resolved to
+// the provider and model they stand for.
+type Router struct {
+	cfg          *config.Config
+	create       ProviderFactory
+	rules        []*routeRule
+	fallback     LLMProvider
+	defaultModel string // as configured, possibly an alias
+	resolved     string // the model defaultModel stands for
+	now          func() time.Time
+
+	// Requests too long for the model they were routed to move here
+	overflow      LLMProvider
+	overflowModel string
+
+	mu      sync.Mutex             // guards aliases and the daily counts of the rules
+	aliases map[string]LLMProvider // providers of the aliases used so far
+}
+
+type routeRule struct {
+	config.RoutingRule
+	provider LLMProvider
+
+	// Hours the rule applies, from start up to end; end < start wraps
+	// past midnight
+	start, end int
+	hasHours   bool
+
+	day   string // date count belongs to
+	count int
+}
+
+// NewRouter creates the providers of the rules in cfg.Routing, along with
+// the default provider. Providers of other aliases are created when first
+// used.
+func NewRouter(cfg *config.Config, create ProviderFactory) (*Router, error) {
+	defaults := cfg.Agents.Defaults
+	providerName, resolved := cfg.ResolveModel(defaults.Provider, defaults.Model)
+	fallback, err := create(cfg, providerName, resolved)
+	if err != nil {
+		return nil, err
+	}
+	r := &Router{
+		cfg:          cfg,
+		create:       create,
+		fallback:     fallback,
+		defaultModel: defaults.Model,
+		resolved:     resolved,
+		now:          time.Now,
+		aliases:      map[string]LLMProvider{},
+	}
+
+	created := map[string]LLMProvider{}
+	for i, rc := range cfg.Routing.Rules {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			rc.Name = name
+		}
+		if rc.Model == "" {
+			return nil, fmt.Errorf("routing rule %s: model is required", name)
+		}
+		rc.Provider, rc.Model = cfg.ResolveModel(rc.Provider, rc.Model)
+		rule := &routeRule{RoutingRule: rc}
+		if rc.Hours != "" {
+			if rule.start, rule.end, err = parseHours(rc.Hours); err != nil {
+				return nil, fmt.Errorf("routing rule %s: %w", name, err)
+			}
+			rule.hasHours = true
+		}
+
+		key := strings.ToLower(rc.Provider) + "/" + rc.Model
+		if rule.provider = created[key]; rule.provider == nil {
+			if rule.provider, err = create(cfg, rc.Provider, rc.Model); err != nil {
+				return nil, fmt.Errorf("routing rule %s: %w", name, err)
+			}
+			created[key] = rule.provider
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	if cfg.Routing.OverflowModel != "" {
+		providerName, model := cfg.ResolveModel(cfg.Routing.OverflowProvider, cfg.Routing.OverflowModel)
+		if r.overflow, err = create(cfg, providerName, model); err != nil {
+			return nil, fmt.Errorf("routing overflow model: %w", err)
+		}
+		r.overflowModel = model
+	}
+	return r, nil
+}
+
+// parseHours parses an hour range such as "9-18" or "22-6".
+func parseHours(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if ok {
+		start, err = strconv.Atoi(strings.TrimSpace(from))
+	}
+	if ok && err == nil {
+		end, err = strconv.Atoi(strings.TrimSpace(to))
+	}
+	if !ok || err != nil || start < 0 || start > 23 || end < 0 || end > 24 || start == end {
+		return 0, 0, fmt.Errorf("invalid hours %q (want a range such as 9-18)", s)
+	}
+	return start, end, nil
+}
+
+// Unwrap returns the default provider.
+func (r *Router) Unwrap() LLMProvider {
+	return r.fallback
+}
+
+// Capabilities describes the provider a model or alias goes to. Rules are
+// not applied, as they depend on the request.
+func (r *Router) Capabilities(model string) Capabilities {
+	p, resolved, err := r.alias(model)
+	if err != nil {
+		return modelCapabilities(resolved)
+	}
+	return CapabilitiesOf(p, resolved)
+}
+
+func (r *Router) GetDefaultModel() string {
+	if r.resolved == "" {
+		return r.fallback.GetDefaultModel()
+	}
+	return r.resolved
+}
+
+func (r *Router) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	provider, model, err := r.Route(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if r.overflows(messages, model) {
+		return r.escalate(ctx, messages, tools, model, options, EscalationContextWindow)
+	}
+
+	resp, err := provider.Chat(ctx, messages, tools, model, options)
+	if err != nil && r.canEscalate(model) && IsContextLengthError(err) {
+		return r.escalate(ctx, messages, tools, model, options, EscalationContextLengthError)
+	}
+	return resp, err
+}
+
+func (r *Router) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	provider, model, err := r.Route(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if r.overflows(messages, model) {
+		return r.escalateStream(ctx, messages, tools, model, options, EscalationContextWindow)
+	}
+
+	events, err := ChatStream(ctx, provider, messages, tools, model, options)
+	if err != nil && r.canEscalate(model) && IsContextLengthError(err) {
+		return r.escalateStream(ctx, messages, tools, model, options, EscalationContextLengthError)
+	}
+	return events, err
+}
+
+// Response metadata recorded when a request moves to the overflow model.
+const (
+	MetadataEscalatedFrom    = "escalated_from"
+	MetadataEscalationReason = "escalation_reason"
+
+	EscalationContextWindow      = "context_window"
+	EscalationContextLengthError = "context_length_error"
+)
+
+// contextLengthErrors are fragments of the errors providers return for
+// prompts longer than the model's context window.
+var contextLengthErrors = []string{
+	"context_length_exceeded",
+	"context length",
+	"context window",
+	"maximum context",
+	"prompt is too long",
+	"input is too long",
+	"too many tokens",
+}
+
+// IsContextLengthError reports whether err says the prompt does not fit the
+// model's context window.
+func IsContextLengthError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range contextLengthErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) canEscalate(model string) bool {
+	return r.overflow != nil && model != r.overflowModel
+}
+
+// overflows reports whether messages are estimated to exceed the context
+// window of model, when it is known. Tokens are estimated as the agent does,
+// at three characters each.
+func (r *Router) overflows(messages []Message, model string) bool {
+	if !r.canEscalate(model) {
+		return false
+	}
+	entry, _ := LookupModel(model)
+	window := entry.ContextWindow
+	if window == 0 {
+		return false
+	}
+	tokens := 0
+	for _, m := range messages {
+		tokens += utf8.RuneCountInString(m.Content) / 3
+	}
+	return tokens > window
+}
+
+func (r *Router) escalate(ctx context.Context, messages []Message, tools []ToolDefinition, model string,
+	options map[string]interface{}, reason string) (*LLMResponse, error) {
+	r.logEscalation(model, reason)
+	resp, err := r.overflow.Chat(ctx, messages, tools, r.overflowModel, options)
+	if err != nil {
+		return nil, err
+	}
+	markEscalated(resp, model, reason)
+	return resp, nil
+}
+
+func (r *Router) escalateStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string,
+	options map[string]interface{}, reason string) (<-chan StreamEvent, error) {
+	r.logEscalation(model, reason)
+	upstream, err := ChatStream(ctx, r.overflow, messages, tools, r.overflowModel, options)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		for ev := range upstream {
+			if ev.Type == StreamEventDone && ev.Response != nil {
+				markEscalated(ev.Response, model, reason)
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				// Drain so the provider's goroutine can finish
+				for range upstream {
+				}
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *Router) logEscalation(model, reason string) {
+	logger.InfoCF("router", "Escalating to the overflow model",
+		map[string]interface{}{
+			"from":   model,
+			"to":     r.overflowModel,
+			"reason": reason,
+		})
+}
+
+func markEscalated(resp *LLMResponse, model, reason string) {
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	resp.Metadata[MetadataEscalatedFrom] = model
+	resp.Metadata[MetadataEscalationReason] = reason
+}
+
+// Route returns the provider and model for a request, counting it against
+// the daily quota of the rule it matches. It fails only when the provider
+// of an alias cannot be created.
+func (r *Router) Route(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (LLMProvider, string, error) {
+	if model != "" && model != r.defaultModel {
+		return r.alias(model)
+	}
+
+	promptChars := 0
+	for _, m := range messages {
+		promptChars += len(m.Content)
+	}
+	tags, _ := options[OptionTags].([]string)
+	hasImages, _ := options[OptionHasImages].(bool)
+	hasImages = hasImages || HasImages(messages)
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range r.rules {
+		if !rule.matches(promptChars, len(tools) > 0, hasImages, tags, now.Hour()) {
+			continue
+		}
+		if rule.DailyRequests > 0 {
+			day := now.Format("2006-01-02")
+			if rule.day != day {
+				rule.day, rule.count = day, 0
+			}
+			if rule.count >= rule.DailyRequests {
+				// Quota used up for today; let a later rule take it
+				continue
+			}
+			rule.count++
+		}
+		logger.DebugCF("router", "Routed request",
+			map[string]interface{}{
+				"rule":         rule.Name,
+				"model":        rule.Model,
+				"prompt_chars": promptChars,
+			})
+		return rule.provider, rule.Model, nil
+	}
+	return r.fallback, r.resolved, nil
+}
+
+// alias returns the provider and model for an explicitly requested model,
+// which goes to the default provider unless it is an alias.
+func (r *Router) alias(model string) (LLMProvider, string, error) {
+	providerName, resolved := r.cfg.ResolveModel("", model)
+	if resolved == model && providerName == "" {
+		return r.fallback, model, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.aliases[model]; ok {
+		return p, resolved, nil
+	}
+	p, err := r.create(r.cfg, providerName, resolved)
+	if err != nil {
+		return nil, "", fmt.Errorf("model alias %s: %w", model, err)
+	}
+	r.aliases[model] = p
+	return p, resolved, nil
+}
+
+func (rule *routeRule) matches(promptChars int, hasTools, hasImages bool, tags []string, hour int) bool {
+	if rule.MinPromptChars > 0 && promptChars < rule.MinPromptChars {
+		return false
+	}
+	if rule.MaxPromptChars > 0 && promptChars > rule.MaxPromptChars {
+		return false
+	}
+	if rule.HasTools != nil && *rule.HasTools != hasTools {
+		return false
+	}
+	if rule.HasImages != nil && *rule.HasImages != hasImages {
+		return false
+	}
+	if len(rule.Tags) > 0 && !hasAnyTag(rule.Tags, tags) {
+		return false
+	}
+	if rule.hasHours {
+		if rule.start < rule.end {
+			return hour >= rule.start && hour < rule.end
+		}
+		return hour >= rule.start || hour < rule.end
+	}
+	return true
+}
+
+func hasAnyTag(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if strings.EqualFold(w, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// namedProvider identifies which provider the router picked.
+type namedProvider struct {
+	countingProvider
+	name string
+}
+
+func newTestRouter(t *testing.T, rules []config.RoutingRule) *Router {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "default"
+	cfg.Agents.Defaults.Model = "default-model"
+	cfg.Routing.Rules = rules
+	return newTestRouterFor(t, cfg)
+}
+
+func newTestRouterFor(t *testing.T, cfg *config.Config) *Router {
+	t.Helper()
+	r, err := NewRouter(cfg, func(cfg *config.Config, providerName, model string) (LLMProvider, error) {
+		return &namedProvider{name: providerName + "/" + model}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	return r
+}
+
+func routedTo(r *Router, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) string {
+	provider, routed, err := r.Route(messages, tools, model, options)
+	if err != nil {
+		return err.Error()
+	}
+	return provider.(*namedProvider).name + " " + routed
+}
+
+func TestRouter_Rules(t *testing.T) {
+	yes, no := true, false
+	r := newTestRouter(t, []config.RoutingRule{
+		{Name: "vision", Provider: "openai", Model: "gpt-4o", HasImages: &yes},
+		{Name: "cheap", Provider: "groq", Model: "llama", Tags: []string{"cheap"}},
+		{Name: "long", Provider: "gemini", Model: "gemini-pro", MinPromptChars: 100},
+		{Name: "chat", Provider: "groq", Model: "llama-small", HasTools: &no, MaxPromptChars: 20},
+	})
+	short := []Message{{Role: "user", Content: "hi"}}
+	long := []Message{{Role: "user", Content: strings.Repeat("x", 200)}}
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "read_file"}}}
+
+	tests := []struct {
+		name     string
+		messages []Message
+		tools    []ToolDefinition
+		model    string
+		options  map[string]interface{}
+		want     string
+	}{
+		{"images", short, nil, "", map[string]interface{}{OptionHasImages: true}, "openai/gpt-4o gpt-4o"},
+		{"tag", long, nil, "", map[string]interface{}{OptionTags: []string{"CHEAP"}}, "groq/llama llama"},
+		{"long prompt", long, nil, "", nil, "gemini/gemini-pro gemini-pro"},
+		{"short without tools", short, nil, "default-model", nil, "groq/llama-small llama-small"},
+		{"short with tools", short, tools, "", nil, "default/default-model default-model"},
+		{"explicit model", long, nil, "other", nil, "default/default-model other"},
+	}
+	for _, tt := range tests {
+		if got := routedTo(r, tt.messages, tt.tools, tt.model, tt.options); got != tt.want {
+			t.Errorf("%s: routed to %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRouter_HoursAndDailyQuota(t *testing.T) {
+	r := newTestRouter(t, []config.RoutingRule{
+		{Name: "night", Provider: "local", Model: "small", Hours: "22-6"},
+		{Name: "smart", Provider: "anthropic", Model: "opus", DailyRequests: 2},
+	})
+	now := time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local)
+	r.now = func() time.Time { return now }
+	msgs := []Message{{Role: "user", Content: "hi"}}
+
+	if got := routedTo(r, msgs, nil, "", nil); got != "local/small small" {
+		t.Errorf("at 23:00 routed to %q, want the night rule", got)
+	}
+
+	now = now.Add(12 * time.Hour)
+	for i := 0; i < 2; i++ {
+		if got := routedTo(r, msgs, nil, "", nil); got != "anthropic/opus opus" {
+			t.Errorf("request %d routed to %q, want the smart rule", i+1, got)
+		}
+	}
+	if got := routedTo(r, msgs, nil, "", nil); got != "default/default-model default-model" {
+		t.Errorf("over quota routed to %q, want the default", got)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if got := routedTo(r, msgs, nil, "", nil); got != "anthropic/opus opus" {
+		t.Errorf("next day routed to %q, want the quota reset", got)
+	}
+}
+
+func TestNewRouter_InvalidRule(t *testing.T) {
+	cfg := config.DefaultConfig()
+	create := func(cfg *config.Config, providerName, model string) (LLMProvider, error) {
+		return &countingProvider{}, nil
+	}
+	for _, rule := range []config.RoutingRule{
+		{Name: "no model"},
+		{Model: "m", Hours: "9"},
+		{Model: "m", Hours: "25-3"},
+	} {
+		cfg.Routing.Rules = []config.RoutingRule{rule}
+		if _, err := NewRouter(cfg, create); err == nil {
+			t.Errorf("NewRouter(%+v) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestRouter_Aliases(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = ""
+	cfg.Agents.Defaults.Model = "deep"
+	cfg.ModelAliases = map[string]string{
+		"fast":   "groq/llama-3.3-70b",
+		"deep":   "anthropic/claude-opus",
+		"router": "openrouter/meta-llama/llama-3-8b",
+		"mini":   "gpt-4o-mini",
+	}
+	cfg.Routing.Rules = []config.RoutingRule{{Name: "cheap", Model: "fast", Tags: []string{"cheap"}}}
+	r := newTestRouterFor(t, cfg)
+	msgs := []Message{{Role: "user", Content: "hi"}}
+
+	tests := []struct {
+		model   string
+		options map[string]interface{}
+		want    string
+	}{
+		{"", nil, "anthropic/claude-opus claude-opus"},
+		{"deep", nil, "anthropic/claude-opus claude-opus"},
+		{"", map[string]interface{}{OptionTags: []string{"cheap"}}, "groq/llama-3.3-70b llama-3.3-70b"},
+		{"fast", nil, "groq/llama-3.3-70b llama-3.3-70b"},
+		{"router", nil, "openrouter/meta-llama/llama-3-8b meta-llama/llama-3-8b"},
+		{"mini", nil, "/gpt-4o-mini gpt-4o-mini"},
+		{"gpt-4.1", nil, "anthropic/claude-opus gpt-4.1"},
+	}
+	for _, tt := range tests {
+		if got := routedTo(r, msgs, nil, tt.model, tt.options); got != tt.want {
+			t.Errorf("model %q routed to %q, want %q", tt.model, got, tt.want)
+		}
+	}
+	if got := r.GetDefaultModel(); got != "claude-opus" {
+		t.Errorf("GetDefaultModel() = %q, want the resolved alias", got)
+	}
+}
+
+// overflowingProvider fails every request as too long for its model.
+type overflowingProvider struct {
+	countingProvider
+}
+
+func (p *overflowingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return nil, fmt.Errorf("API request failed:\n  Status: 400\n  Body:   {\"error\":{\"code\":\"context_length_exceeded\"}}")
+}
+
+func TestRouter_Overflow(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "openai"
+	cfg.Agents.Defaults.Model = "gpt-4"
+	cfg.Routing.OverflowProvider = "gemini"
+	cfg.Routing.OverflowModel = "gemini-2.5-pro"
+	overflow := &countingProvider{}
+	var fallback LLMProvider = &countingProvider{}
+	r, err := NewRouter(cfg, func(cfg *config.Config, providerName, model string) (LLMProvider, error) {
+		if providerName == "gemini" {
+			return overflow, nil
+		}
+		return fallback, nil
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// gpt-4 has an 8k window; 30000 characters are estimated at 10000 tokens
+	long := []Message{{Role: "user", Content: strings.Repeat("x", 30000)}}
+	resp, err := r.Chat(ctx, long, nil, "", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if overflow.calls != 1 || resp.Metadata[MetadataEscalatedFrom] != "gpt-4" ||
+		resp.Metadata[MetadataEscalationReason] != EscalationContextWindow {
+		t.Errorf("long prompt: overflow calls = %d, metadata = %v", overflow.calls, resp.Metadata)
+	}
+
+	resp, err = r.Chat(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "", nil)
+	if err != nil || overflow.calls != 1 || resp.Metadata != nil {
+		t.Errorf("short prompt escalated: calls = %d, metadata = %v, err = %v", overflow.calls, resp.Metadata, err)
+	}
+
+	r.fallback = &overflowingProvider{}
+	events, err := r.ChatStream(ctx, []Message{{Role: "user", Content: "hi"}}, nil, "", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	resp, err = CollectStream(events, nil)
+	if err != nil || overflow.calls != 2 || resp.Metadata[MetadataEscalationReason] != EscalationContextLengthError {
+		t.Errorf("context-length error: calls = %d, metadata = %v, err = %v", overflow.calls, resp.Metadata, err)
+	}
+}
@@ -0,0 +1,283 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Priority orders the requests waiting for a Scheduler slot.
+type Priority int
+
+const (
+	// PriorityInteractive is for requests someone is waiting on; it is the
+	// default.
+	PriorityInteractive Priority = iota
+	// PriorityBatch is for bulk jobs, which run only when no interactive
+	// request is waiting.
+	PriorityBatch
+
+	numPriorities
+)
+
+// ParsePriority parses "interactive" or "batch".
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(s) {
+	case "", "interactive":
+		return PriorityInteractive, nil
+	case "batch":
+		return PriorityBatch, nil
+	default:
+		return 0, fmt.Errorf("unknown priority %q (want interactive or batch)", s)
+	}
+}
+
+func (p Priority) String() string {
+	if p == PriorityBatch {
+		return "batch"
+	}
+	return "interactive"
+}
+
+type priorityContextKey struct{}
+
+// WithPriority returns a context whose requests wait at priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, p)
+}
+
+// PriorityFrom returns the priority set by WithPriority, or
+// PriorityInteractive.
+func PriorityFrom(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityContextKey{}).(Priority)
+	return p
+}
+
+// Scheduler bounds how many requests run at once. Requests beyond the limit
+// wait until one finishes: interactive requests first, then batch, each in
+// arrival order.
+type Scheduler struct {
+	limit int
+
+	mu      sync.Mutex
+	active  int
+	waiting [numPriorities]*list.List // of chan struct{}, closed when the waiter gets a slot
+}
+
+// NewScheduler creates a scheduler running at most limit requests at once.
+func NewScheduler(limit int) *Scheduler {
+	s := &Scheduler{limit: limit}
+	for i := range s.waiting {
+		s.waiting[i] = list.New()
+	}
+	return s
+}
+
+// Acquire waits for a free slot at the priority of ctx, or until ctx is
+// done. Every successful Acquire must be followed by a Release.
+func (s *Scheduler) Acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active < s.limit && s.queued() == 0 {
+		s.active++
+		s.mu.Unlock()
+		return nil
+	}
+	queue := s.waiting[PriorityFrom(ctx)]
+	ready := make(chan struct{})
+	elem := queue.PushBack(ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-ready:
+			// Got the slot while giving up; hand it on
+			s.release()
+		default:
+			queue.Remove(elem)
+		}
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot, handing it to the longest waiting request of the
+// highest priority.
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release()
+}
+
+func (s *Scheduler) release() {
+	for _, queue := range s.waiting {
+		if front := queue.Front(); front != nil {
+			queue.Remove(front)
+			close(front.Value.(chan struct{}))
+			return
+		}
+	}
+	s.active--
+}
+
+func (s *Scheduler) queued() int {
+	n := 0
+	for _, queue := range s.waiting {
+		n += queue.Len()
+	}
+	return n
+}
+
+// Stats returns the number of requests running and waiting.
+func (s *Scheduler) Stats() (active, waiting int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active, s.queued()
+}
+
+// ScheduledProvider runs requests through a Scheduler, so the goroutines
+// of a bulk job cannot exceed a provider's concurrency limit. A stream
+// holds its slot until it ends.
+type ScheduledProvider struct {
+	provider  LLMProvider
+	scheduler *Scheduler
+}
+
+// NewScheduledProvider wraps provider, queueing its requests on scheduler.
+func NewScheduledProvider(provider LLMProvider, scheduler *Scheduler) *ScheduledProvider {
+	return &ScheduledProvider{provider: provider, scheduler: scheduler}
+}
+
+// Unwrap returns the provider behind the scheduler.
+func (p *ScheduledProvider) Unwrap() LLMProvider {
+	return p.provider
+}
+
+func (p *ScheduledProvider) GetDefaultModel() string {
+	return p.provider.GetDefaultModel()
+}
+
+func (p *ScheduledProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	queued := time.Now()
+	if err := p.acquire(ctx, model); err != nil {
+		return nil, err
+	}
+	queueTime := time.Since(queued)
+	defer p.scheduler.Release()
+	resp, err := p.provider.Chat(ctx, messages, tools, model, options)
+	if resp != nil {
+		resp.timing().QueueTime = queueTime
+	}
+	return resp, err
+}
+
+func (p *ScheduledProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	queued := time.Now()
+	if err := p.acquire(ctx, model); err != nil {
+		return nil, err
+	}
+	queueTime := time.Since(queued)
+	upstream, err := ChatStream(ctx, p.provider, messages, tools, model, options)
+	if err != nil {
+		p.scheduler.Release()
+		return nil, err
+	}
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer p.scheduler.Release()
+		for ev := range upstream {
+			if ev.Type == StreamEventDone && ev.Response != nil {
+				ev.Response.timing().QueueTime = queueTime
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				// Drain so the provider's goroutine can finish
+				for range upstream {
+				}
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (p *ScheduledProvider) acquire(ctx context.Context, model string) error {
+	if active, waiting := p.scheduler.Stats(); active >= p.scheduler.limit {
+		logger.DebugCF("scheduler", "Request queued",
+			map[string]interface{}{"model": model, "priority": PriorityFrom(ctx).String(), "active": active, "waiting": waiting + 1})
+	}
+	return p.scheduler.Acquire(ctx)
+}
+
+var (
+	schedulersMu sync.Mutex
+	schedulers   = map[string]*Scheduler{}
+)
+
+// providerScheduler returns the scheduler shared by every provider created
+// for name with the same limit, so separate agents, chats and servers in one
+// process count against one account limit.
+func providerScheduler(name string, limit int) *Scheduler {
+	schedulersMu.Lock()
+	defer schedulersMu.Unlock()
+	key := fmt.Sprintf("%s/%d", name, limit)
+	s, ok := schedulers[key]
+	if !ok {
+		s = NewScheduler(limit)
+		schedulers[key] = s
+	}
+	return s
+}
+
+// providerAPIBase returns the endpoint an OpenAI-compatible provider calls,
+// or "" for other providers.
+func providerAPIBase(provider LLMProvider) string {
+	switch p := provider.(type) {
+	case *HTTPProvider:
+		return p.apiBase
+	case *OpenAIProvider:
+		return p.apiBase
+	case *GitHubModelsProvider:
+		return p.apiBase
+	case *CohereProvider:
+		return p.apiBase
+	case *PerplexityProvider:
+		return p.apiBase
+	}
+	return ""
+}
+
+// schedulerProviderName names the configured provider behind provider: the
+// requested one, or for a provider picked from the model name, the one whose
+// API base it calls.
+func schedulerProviderName(cfg *config.Config, providerName string, provider LLMProvider) string {
+	if providerName != "" {
+		pc := cfg.Providers.Get(providerName)
+		for name, candidate := range cfg.Providers.All() {
+			if candidate == pc {
+				return name
+			}
+		}
+		return strings.ToLower(providerName)
+	}
+	base := providerAPIBase(provider)
+	if base == "" {
+		return ""
+	}
+	for name, pc := range cfg.Providers.All() {
+		apiBase, _ := configuredAPIBase(name, pc)
+		if apiBase != "" && strings.TrimRight(apiBase, "/") == strings.TrimRight(base, "/") {
+			return name
+		}
+	}
+	return ""
+}
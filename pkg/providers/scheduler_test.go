@@ -0,0 +1,175 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingProvider holds each request until release is closed, tracking
+// the most requests in flight at once.
+type blockingProvider struct {
+	countingProvider
+	release  chan struct{}
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (p *blockingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-p.release
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func TestScheduledProvider_LimitsConcurrency(t *testing.T) {
+	inner := &blockingProvider{release: make(chan struct{})}
+	scheduler := NewScheduler(2)
+	p := NewScheduledProvider(inner, scheduler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Chat(context.Background(), nil, nil, "m", nil); err != nil {
+				t.Errorf("Chat() error = %v", err)
+			}
+		}()
+	}
+	waitFor(t, func() bool {
+		active, waiting := scheduler.Stats()
+		return active == 2 && waiting == 4
+	})
+	close(inner.release)
+	wg.Wait()
+
+	if peak := inner.peak.Load(); peak != 2 {
+		t.Errorf("peak in flight = %d, want 2", peak)
+	}
+	if active, waiting := scheduler.Stats(); active != 0 || waiting != 0 {
+		t.Errorf("after the burst: active = %d, waiting = %d", active, waiting)
+	}
+}
+
+func TestScheduledProvider_RecordsQueueTime(t *testing.T) {
+	scheduler := NewScheduler(1)
+	p := NewScheduledProvider(&staticProvider{resp: &LLMResponse{Content: "ok"}}, scheduler)
+	scheduler.Acquire(context.Background())
+	go func() {
+		waitFor(t, func() bool { _, waiting := scheduler.Stats(); return waiting == 1 })
+		time.Sleep(20 * time.Millisecond)
+		scheduler.Release()
+	}()
+
+	resp, err := p.Chat(context.Background(), nil, nil, "m", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Timing == nil || resp.Timing.QueueTime < 20*time.Millisecond {
+		t.Errorf("Timing = %+v, want at least 20ms queued", resp.Timing)
+	}
+}
+
+func TestScheduler_FIFOAndCancel(t *testing.T) {
+	s := NewScheduler(1)
+	ctx := context.Background()
+	if err := s.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// A waiter that gives up leaves the queue without taking a slot
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(cancelled); err == nil {
+		t.Fatal("Acquire() with a full scheduler succeeded before its deadline")
+	}
+
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Acquire(ctx)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			s.Release()
+		}(i)
+		// Queue them one at a time so arrival order is known
+		waitFor(t, func() bool { _, waiting := s.Stats(); return waiting == i+1 })
+	}
+	s.Release()
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("order = %v, want arrival order", order)
+	}
+}
+
+func TestScheduler_InteractiveBeforeBatch(t *testing.T) {
+	s := NewScheduler(1)
+	ctx := context.Background()
+	s.Acquire(ctx)
+
+	var order []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := func(name string, ctx context.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Acquire(ctx)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			s.Release()
+		}()
+	}
+	batch := WithPriority(ctx, PriorityBatch)
+	start("batch-1", batch)
+	waitFor(t, func() bool { _, waiting := s.Stats(); return waiting == 1 })
+	start("batch-2", batch)
+	waitFor(t, func() bool { _, waiting := s.Stats(); return waiting == 2 })
+	start("interactive", ctx)
+	waitFor(t, func() bool { _, waiting := s.Stats(); return waiting == 3 })
+	s.Release()
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "interactive,batch-1,batch-2" {
+		t.Errorf("order = %s, want the interactive request first", got)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for in, want := range map[string]Priority{"": PriorityInteractive, "Interactive": PriorityInteractive, "batch": PriorityBatch} {
+		if got, err := ParsePriority(in); err != nil || got != want {
+			t.Errorf("ParsePriority(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(urgent) succeeded")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
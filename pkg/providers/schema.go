@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewToolDefinition builds a function tool definition whose parameters
+// schema is derived from args, a struct value or pointer. See SchemaFor for
+// the supported struct tags.
+func NewToolDefinition(name, description string, args interface{}) (ToolDefinition, error) {
+	schema, err := SchemaFor(args)
+	if err != nil {
+		return ToolDefinition{}, fmt.Errorf("tool %s: %w", name, err)
+	}
+	return ToolDefinition{
+		Type: "function",
+		Function: ToolFunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  schema,
+		},
+	}, nil
+}
+
+// SchemaFor derives a JSON schema object from a struct type using
+// reflection. Property names follow the json tag. A field is required
+// unless it is a pointer or its json tag has omitempty; the required tag
+// ("true" or "false") overrides that. Other tags:
+//
+//	description:"..."  property description
+//	enum:"a,b,c"       allowed values
+//	default:"..."      default value, parsed according to the field type
+//
+// For example:
+//
+//	type WeatherArgs struct {
+//		Location string `json:"location" description:"City and state, e.g. San Francisco, CA"`
+//		Unit     string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+func SchemaFor(v interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema source must be a struct, got %v", t)
+	}
+	return structSchema(t, map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]interface{}, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("recursive type %v is not supported", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs without a json name contribute their fields
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				sub, err := structSchema(embedded, seen)
+				if err != nil {
+					return nil, err
+				}
+				for k, v := range sub["properties"].(map[string]interface{}) {
+					properties[k] = v
+				}
+				required = append(required, sub["required"].([]string)...)
+				continue
+			}
+		}
+
+		prop, err := typeSchema(field.Type, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := []interface{}{}
+			for _, s := range strings.Split(enum, ",") {
+				value, err := parseTagValue(field.Type, strings.TrimSpace(s))
+				if err != nil {
+					return nil, fmt.Errorf("field %s: enum: %w", field.Name, err)
+				}
+				values = append(values, value)
+			}
+			prop["enum"] = values
+		}
+		if def, ok := field.Tag.Lookup("default"); ok {
+			value, err := parseTagValue(field.Type, def)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: default: %w", field.Name, err)
+			}
+			prop["default"] = value
+		}
+		properties[name] = prop
+
+		isRequired := !omitEmpty && field.Type.Kind() != reflect.Pointer
+		if tag, ok := field.Tag.Lookup("required"); ok {
+			isRequired = tag == "true"
+		}
+		if isRequired {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, nil
+}
+
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings, got %v", t.Key())
+		}
+		values, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return structSchema(t, seen)
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// jsonFieldName returns the property name of field as encoding/json would.
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// parseTagValue converts an enum or default tag value to the JSON type of t.
+func parseTagValue(t reflect.Type, s string) (interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.String:
+		return s, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
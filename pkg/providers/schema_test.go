@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type scheduleArgs struct {
+	Title    string            `json:"title" description:"What to schedule"`
+	When     time.Time         `json:"when"`
+	Repeat   int               `json:"repeat,omitempty" default:"1"`
+	Priority string            `json:"priority" enum:"low,high" required:"false"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Notify   *bool             `json:"notify"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor(&scheduleArgs{})
+	if err != nil {
+		t.Fatalf("SchemaFor() error = %v", err)
+	}
+
+	if schema["type"] != "object" {
+		t.Errorf("type = %v, want object", schema["type"])
+	}
+	if want := []string{"title", "when"}; !reflect.DeepEqual(schema["required"], want) {
+		t.Errorf("required = %v, want %v", schema["required"], want)
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	if len(props) != 7 {
+		t.Errorf("len(properties) = %d, want 7", len(props))
+	}
+
+	tests := []struct {
+		name string
+		want map[string]interface{}
+	}{
+		{"title", map[string]interface{}{"type": "string", "description": "What to schedule"}},
+		{"when", map[string]interface{}{"type": "string", "format": "date-time"}},
+		{"repeat", map[string]interface{}{"type": "integer", "default": int64(1)}},
+		{"priority", map[string]interface{}{"type": "string", "enum": []interface{}{"low", "high"}}},
+		{"tags", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}},
+		{"labels", map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}},
+		{"notify", map[string]interface{}{"type": "boolean"}},
+	}
+	for _, tt := range tests {
+		if got := props[tt.name]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("properties[%q] = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSchemaFor_Errors(t *testing.T) {
+	type recursive struct {
+		Next *recursive `json:"next"`
+	}
+	type badMap struct {
+		M map[int]string `json:"m"`
+	}
+
+	for _, v := range []interface{}{"not a struct", recursive{}, badMap{}} {
+		if _, err := SchemaFor(v); err == nil {
+			t.Errorf("SchemaFor(%T) succeeded, want error", v)
+		}
+	}
+}
+
+func TestNewToolDefinition(t *testing.T) {
+	type weatherArgs struct {
+		Location string `json:"location" description:"City"`
+	}
+	def, err := NewToolDefinition("get_weather", "Get the weather", weatherArgs{})
+	if err != nil {
+		t.Fatalf("NewToolDefinition() error = %v", err)
+	}
+	if def.Type != "function" || def.Function.Name != "get_weather" {
+		t.Errorf("definition = %+v, want function get_weather", def)
+	}
+	if got := def.Function.Parameters["required"]; !reflect.DeepEqual(got, []string{"location"}) {
+		t.Errorf("required = %v, want [location]", got)
+	}
+}
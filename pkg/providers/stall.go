@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrStreamStalled ends a stream that sent no event for longer than its
+// stall timeout, as long Azure streams sometimes do when the connection
+// dies silently. Check for it with errors.Is.
+var ErrStreamStalled = errors.New("stream stalled")
+
+// MetadataStreamResumes is the response metadata counting how many times a
+// stalled stream was resumed from the text received so far.
+const MetadataStreamResumes = "stream_resumes"
+
+// StallGuardProvider watches the streams of a provider. When one sends no
+// event for the timeout it is abandoned and, with resumes left, requested
+// again with the text received so far as the start of the assistant's
+// reply; otherwise it ends with ErrStreamStalled. A stream that has begun a
+// tool call is not resumed. Chat calls are passed through.
+type StallGuardProvider struct {
+	provider LLMProvider
+	timeout  time.Duration
+	resumes  int
+}
+
+// NewStallGuardProvider wraps provider, abandoning streams quiet for longer
+// than timeout and resuming each up to resumes times.
+func NewStallGuardProvider(provider LLMProvider, timeout time.Duration, resumes int) *StallGuardProvider {
+	return &StallGuardProvider{provider: provider, timeout: timeout, resumes: resumes}
+}
+
+// Unwrap returns the provider behind the guard.
+func (p *StallGuardProvider) Unwrap() LLMProvider {
+	return p.provider
+}
+
+func (p *StallGuardProvider) GetDefaultModel() string {
+	return p.provider.GetDefaultModel()
+}
+
+func (p *StallGuardProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.provider.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *StallGuardProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	upstream, err := ChatStream(attemptCtx, p.provider, messages, tools, model, options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		var text strings.Builder
+		for resumes := 0; ; resumes++ {
+			final, stalled, resumable := p.relay(ctx, upstream, events, &text)
+			// Ends the provider's request and goroutine if still running
+			cancel()
+			if !stalled {
+				if final.Type == StreamEventDone && resumes > 0 {
+					final.Response.Content = text.String()
+					if final.Response.Metadata == nil {
+						final.Response.Metadata = map[string]string{}
+					}
+					final.Response.Metadata[MetadataStreamResumes] = strconv.Itoa(resumes)
+				}
+				if final.Type != "" {
+					sendStreamEvent(ctx, events, final)
+				}
+				return
+			}
+			if !resumable || resumes >= p.resumes {
+				sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError,
+					Err: fmt.Errorf("%w: no events for %s", ErrStreamStalled, p.timeout)})
+				return
+			}
+
+			logger.WarnCF("provider", "Stream stalled, resuming",
+				map[string]interface{}{
+					"model":    model,
+					"timeout":  p.timeout.String(),
+					"received": text.Len(),
+				})
+			continued := append(append([]Message(nil), messages...), Message{Role: "assistant", Content: text.String()})
+			attemptCtx, cancel = context.WithCancel(ctx)
+			upstream, err = ChatStream(attemptCtx, p.provider, continued, tools, model, options)
+			if err != nil {
+				cancel()
+				sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventError, Err: err})
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// relay passes the events of upstream on to events, adding their text to
+// text, until the final Done or Error event, which it returns unsent, or
+// until upstream is quiet for the timeout. resumable turns false once a
+// tool call has begun, as one cannot be picked up midway.
+func (p *StallGuardProvider) relay(ctx context.Context, upstream <-chan StreamEvent, events chan<- StreamEvent, text *strings.Builder) (final StreamEvent, stalled, resumable bool) {
+	resumable = true
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case ev, ok := <-upstream:
+			if !ok {
+				return StreamEvent{}, false, resumable
+			}
+			switch ev.Type {
+			case StreamEventDone, StreamEventError:
+				return ev, false, resumable
+			case StreamEventText:
+				text.WriteString(ev.Text)
+			default:
+				resumable = false
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				return StreamEvent{}, false, resumable
+			}
+			timer.Reset(p.timeout)
+		case <-timer.C:
+			return StreamEvent{}, true, resumable
+		case <-ctx.Done():
+			return StreamEvent{}, false, resumable
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stallingProvider streams "Hello, " and then goes quiet on its first
+// stream, and finishes the reply on later ones.
+type stallingProvider struct {
+	staticProvider
+	requests [][]Message
+}
+
+func (p *stallingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	p.requests = append(p.requests, messages)
+	events := make(chan StreamEvent)
+	first := len(p.requests) == 1
+	go func() {
+		defer close(events)
+		if first {
+			sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventText, Text: "Hello, "})
+			<-ctx.Done()
+			return
+		}
+		sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventText, Text: "world"})
+		sendStreamEvent(ctx, events, StreamEvent{Type: StreamEventDone, Response: &LLMResponse{Content: "world", FinishReason: FinishReasonStop}})
+	}()
+	return events, nil
+}
+
+func TestStallGuardProvider_Resumes(t *testing.T) {
+	upstream := &stallingProvider{}
+	provider := NewStallGuardProvider(upstream, 50*time.Millisecond, 1)
+
+	events, err := provider.ChatStream(context.Background(), []Message{{Role: "user", Content: "Greet the world"}}, nil, "static", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	var text string
+	resp, err := CollectStream(events, func(s string) { text += s })
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+
+	if text != "Hello, world" || resp.Content != "Hello, world" {
+		t.Errorf("streamed %q, Content %q, want the text of both streams", text, resp.Content)
+	}
+	if resp.Metadata[MetadataStreamResumes] != "1" {
+		t.Errorf("Metadata = %v, want one resume", resp.Metadata)
+	}
+	if len(upstream.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(upstream.requests))
+	}
+	last := upstream.requests[1][len(upstream.requests[1])-1]
+	if last.Role != "assistant" || last.Content != "Hello, " {
+		t.Errorf("resumed with %+v, want the text received so far", last)
+	}
+}
+
+func TestStallGuardProvider_Stalled(t *testing.T) {
+	provider := NewStallGuardProvider(&stallingProvider{}, 50*time.Millisecond, 0)
+
+	events, err := provider.ChatStream(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "static", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if _, err := CollectStream(events, nil); !errors.Is(err, ErrStreamStalled) {
+		t.Errorf("CollectStream() error = %v, want ErrStreamStalled", err)
+	}
+}
@@ -0,0 +1,268 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StreamEventType identifies the kind of a StreamEvent.
+type StreamEventType string
+
+const (
+	// StreamEventText carries a piece of assistant text in Text.
+	StreamEventText StreamEventType = "text"
+	// StreamEventDone is the last event of a successful stream; Response
+	// holds the assembled response including tool calls and usage.
+	StreamEventDone StreamEventType = "done"
+	// StreamEventError ends a failed stream; Err holds the cause.
+	StreamEventError StreamEventType = "error"
+	// StreamEventToolCallStart announces a tool call in ToolCall, with its
+	// ID and name but no arguments yet.
+	StreamEventToolCallStart StreamEventType = "tool_call_start"
+	// StreamEventToolCallDelta carries the next fragment of a call's
+	// arguments, along with everything received so far parsed as far as
+	// it goes.
+	StreamEventToolCallDelta StreamEventType = "tool_call_delta"
+	// StreamEventToolCallDone ends a tool call; its arguments are complete.
+	StreamEventToolCallDone StreamEventType = "tool_call_done"
+)
+
+// StreamEvent is one increment of a streamed chat response.
+type StreamEvent struct {
+	Type     StreamEventType
+	Text     string
+	ToolCall *ToolCallDelta
+	Response *LLMResponse
+	Err      error
+}
+
+// ToolCallDelta describes a tool call as it streams in. Index orders the
+// calls of one response.
+type ToolCallDelta struct {
+	Index int
+	ID    string
+	Name  string
+	// ArgumentsDelta is the raw JSON fragment this event added.
+	ArgumentsDelta string
+	// Arguments holds the arguments received so far, with unfinished
+	// strings and containers closed; complete on StreamEventToolCallDone.
+	Arguments map[string]interface{}
+}
+
+// StreamingProvider is implemented by providers that can deliver a response
+// incrementally. The returned channel is closed after a Done or Error event.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error)
+}
+
+// ChatStream streams a response from provider. Providers without streaming
+// support are called with Chat and their whole reply is sent as one event.
+func ChatStream(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	if sp, ok := provider.(StreamingProvider); ok {
+		return sp.ChatStream(ctx, messages, tools, model, options)
+	}
+
+	resp, err := provider.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+// replayResponse returns a closed channel holding resp as a stream: its
+// text in one event, a start and done event per tool call, then Done.
+func replayResponse(resp *LLMResponse) <-chan StreamEvent {
+	events := make(chan StreamEvent, 2+2*len(resp.ToolCalls))
+	if resp.Content != "" {
+		events <- StreamEvent{Type: StreamEventText, Text: resp.Content}
+	}
+	for i, tc := range resp.ToolCalls {
+		name := tc.Name
+		if name == "" && tc.Function != nil {
+			name = tc.Function.Name
+		}
+		start := ToolCallDelta{Index: i, ID: tc.ID, Name: name}
+		done := start
+		done.Arguments = tc.Arguments
+		events <- StreamEvent{Type: StreamEventToolCallStart, ToolCall: &start}
+		events <- StreamEvent{Type: StreamEventToolCallDone, ToolCall: &done}
+	}
+	events <- StreamEvent{Type: StreamEventDone, Response: resp}
+	close(events)
+	return events
+}
+
+// CollectStream drains events, calling onText for each text delta, and
+// returns the final response.
+func CollectStream(events <-chan StreamEvent, onText func(string)) (*LLMResponse, error) {
+	return ConsumeStream(events, func(ev StreamEvent) {
+		if ev.Type == StreamEventText && onText != nil {
+			onText(ev.Text)
+		}
+	})
+}
+
+// ConsumeStream drains events, passing each text and tool call event to
+// onEvent, and returns the final response.
+func ConsumeStream(events <-chan StreamEvent, onEvent func(StreamEvent)) (*LLMResponse, error) {
+	for ev := range events {
+		switch ev.Type {
+		case StreamEventDone:
+			return ev.Response, nil
+		case StreamEventError:
+			return nil, ev.Err
+		default:
+			if onEvent != nil {
+				onEvent(ev)
+			}
+		}
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
+
+// StreamCallbacks receive a streamed response piece by piece. They are
+// called one at a time on the goroutine that called ChatStreamCallbacks;
+// nil callbacks are skipped.
+type StreamCallbacks struct {
+	// OnTextDelta is called with each piece of assistant text.
+	OnTextDelta func(text string)
+	// OnToolCall is called with each tool call once its arguments are
+	// complete.
+	OnToolCall func(call ToolCall)
+	// OnUsage is called with the tokens used, when the provider reports
+	// them, just before OnDone.
+	OnUsage func(usage UsageInfo)
+	// OnDone is called with the assembled response.
+	OnDone func(resp *LLMResponse)
+}
+
+// ChatStreamCallbacks streams a response from provider like ChatStream,
+// but delivers it to callbacks instead of a channel. It returns when the
+// response is complete, so embedders need not run or drain anything
+// themselves.
+func ChatStreamCallbacks(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, callbacks StreamCallbacks) (*LLMResponse, error) {
+	events, err := ChatStream(ctx, provider, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ConsumeStream(events, func(ev StreamEvent) {
+		switch {
+		case ev.Type == StreamEventText && callbacks.OnTextDelta != nil:
+			callbacks.OnTextDelta(ev.Text)
+		case ev.Type == StreamEventToolCallDone && callbacks.OnToolCall != nil:
+			callbacks.OnToolCall(ToolCall{ID: ev.ToolCall.ID, Name: ev.ToolCall.Name, Arguments: ev.ToolCall.Arguments})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Usage != nil && callbacks.OnUsage != nil {
+		callbacks.OnUsage(*resp.Usage)
+	}
+	if callbacks.OnDone != nil {
+		callbacks.OnDone(resp)
+	}
+	return resp, nil
+}
+
+// toolCallStream turns provider-specific tool call chunks into tool call
+// events, assembling each call's arguments as they arrive.
+type toolCallStream struct {
+	calls map[int]*streamingCall
+	order []int
+}
+
+type streamingCall struct {
+	delta     ToolCallDelta
+	arguments strings.Builder
+	done      bool
+}
+
+func newToolCallStream() *toolCallStream {
+	return &toolCallStream{calls: make(map[int]*streamingCall)}
+}
+
+// start records a new call and returns its start event. Calls already
+// started are only updated with a late ID or name.
+func (s *toolCallStream) start(index int, id, name string) []StreamEvent {
+	if call, ok := s.calls[index]; ok {
+		if id != "" {
+			call.delta.ID = id
+		}
+		if name != "" {
+			call.delta.Name = name
+		}
+		return nil
+	}
+	call := &streamingCall{delta: ToolCallDelta{Index: index, ID: id, Name: name}}
+	s.calls[index] = call
+	s.order = append(s.order, index)
+	d := call.delta
+	return []StreamEvent{{Type: StreamEventToolCallStart, ToolCall: &d}}
+}
+
+// add appends an arguments fragment and returns its delta event.
+func (s *toolCallStream) add(index int, fragment string) []StreamEvent {
+	call, ok := s.calls[index]
+	if !ok || fragment == "" {
+		return nil
+	}
+	call.arguments.WriteString(fragment)
+	d := call.delta
+	d.ArgumentsDelta = fragment
+	d.Arguments = ParsePartialJSON(call.arguments.String())
+	return []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &d}}
+}
+
+// finish completes the call at index, or every open call when index is
+// negative, and returns their done events.
+func (s *toolCallStream) finish(index int) []StreamEvent {
+	var events []StreamEvent
+	for _, i := range s.order {
+		call := s.calls[i]
+		if call.done || (index >= 0 && i != index) {
+			continue
+		}
+		call.done = true
+		d := call.delta
+		d.Arguments, _ = parseToolArguments(call.arguments.String())
+		events = append(events, StreamEvent{Type: StreamEventToolCallDone, ToolCall: &d})
+	}
+	return events
+}
+
+// parseToolArguments decodes complete tool call arguments, keeping text
+// that is not a JSON object under "raw" and returning why it is not.
+func parseToolArguments(raw string) (map[string]interface{}, error) {
+	arguments := make(map[string]interface{})
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+			return map[string]interface{}{"raw": raw}, err
+		}
+	}
+	return arguments, nil
+}
+
+// newToolCall returns a call with the complete arguments the model sent,
+// recording in ArgumentsError when they could not be decoded.
+func newToolCall(id, name, raw string) ToolCall {
+	call := ToolCall{ID: id, Name: name}
+	var err error
+	if call.Arguments, err = parseToolArguments(raw); err != nil {
+		call.ArgumentsError = err.Error()
+	}
+	return call
+}
+
+// sendStreamEvent delivers ev unless ctx is cancelled first.
+func sendStreamEvent(ctx context.Context, events chan<- StreamEvent, ev StreamEvent) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,264 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	input := ": keepalive\n\nevent: message\ndata: first\ndata: second\n\ndata: third\n\ndata: [DONE]\n\ndata: ignored\n\n"
+
+	var got []string
+	err := readSSE(strings.NewReader(input), func(event, data string) error {
+		if data == "[DONE]" {
+			return errSSEDone
+		}
+		got = append(got, event+"|"+data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE() error = %v", err)
+	}
+
+	want := []string{"message|first\nsecond", "|third"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHTTPProvider_ChatStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_file","arguments":"{\"path\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.txt\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+	}
+
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Request-Id", "req_stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	events, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+
+	var text strings.Builder
+	resp, err := CollectStream(events, func(s string) { text.WriteString(s) })
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+
+	if requestBody["stream"] != true {
+		t.Errorf("request stream = %v, want true", requestBody["stream"])
+	}
+	if text.String() != "Hello" {
+		t.Errorf("streamed text = %q, want %q", text.String(), "Hello")
+	}
+	if resp.Content != "Hello" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello")
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "tool_calls")
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID != "call_1" || tc.Name != "read_file" || tc.Arguments["path"] != "a.txt" {
+		t.Errorf("ToolCall = %+v, want call_1 read_file path=a.txt", tc)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 15 {
+		t.Errorf("Usage = %+v, want total 15", resp.Usage)
+	}
+	if resp.Metadata[MetadataRequestID] != "req_stream" {
+		t.Errorf("Metadata = %v, want the request ID", resp.Metadata)
+	}
+	if resp.Timing == nil || resp.Timing.FirstToken <= 0 || resp.Timing.Duration < resp.Timing.FirstToken {
+		t.Errorf("Timing = %+v, want a first token time within the duration", resp.Timing)
+	}
+}
+
+func TestHTTPProvider_ChatStreamHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req_abc")
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	_, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "req_abc") {
+		t.Errorf("ChatStream() error = %v, want status 401 and the request ID", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.RequestID != "req_abc" {
+		t.Errorf("ChatStream() error = %#v, want an APIError", err)
+	}
+}
+
+type staticProvider struct {
+	resp *LLMResponse
+}
+
+func (p *staticProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.resp, nil
+}
+
+func (p *staticProvider) GetDefaultModel() string {
+	return "static"
+}
+
+func TestChatStream_FallsBackToChat(t *testing.T) {
+	provider := &staticProvider{resp: &LLMResponse{Content: "whole reply", FinishReason: "stop"}}
+
+	events, err := ChatStream(context.Background(), provider, nil, nil, "static", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+
+	var deltas []string
+	resp, err := CollectStream(events, func(s string) { deltas = append(deltas, s) })
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+	if len(deltas) != 1 || deltas[0] != "whole reply" {
+		t.Errorf("deltas = %q, want [\"whole reply\"]", deltas)
+	}
+	if resp != provider.resp {
+		t.Errorf("CollectStream() returned %+v, want the Chat response", resp)
+	}
+}
+
+func TestChatStreamCallbacks(t *testing.T) {
+	provider := &staticProvider{resp: &LLMResponse{
+		Content:   "Checking.",
+		ToolCalls: []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{"location": "Seattle"}}},
+		Usage:     &UsageInfo{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}}
+
+	var calls []string
+	resp, err := ChatStreamCallbacks(context.Background(), provider, nil, nil, "static", nil, StreamCallbacks{
+		OnTextDelta: func(text string) { calls = append(calls, "text "+text) },
+		OnToolCall: func(call ToolCall) {
+			calls = append(calls, fmt.Sprintf("tool %s %s(%v)", call.ID, call.Name, call.Arguments["location"]))
+		},
+		OnUsage: func(usage UsageInfo) { calls = append(calls, fmt.Sprintf("usage %d", usage.TotalTokens)) },
+		OnDone:  func(resp *LLMResponse) { calls = append(calls, "done") },
+	})
+	if err != nil {
+		t.Fatalf("ChatStreamCallbacks() error = %v", err)
+	}
+	if resp != provider.resp {
+		t.Errorf("ChatStreamCallbacks() returned %+v, want the Chat response", resp)
+	}
+	want := []string{"text Checking.", "tool call_1 get_weather(Seattle)", "usage 15", "done"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("callbacks = %q, want %q", calls, want)
+	}
+}
+
+func TestHTTPProvider_ChatStreamToolCallEvents(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"location\": \"Sea"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ttle\"}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"get_time","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	events, err := NewHTTPProvider("key", server.URL, "").ChatStream(context.Background(), nil, nil, "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	var got []string
+	if _, err := ConsumeStream(events, func(ev StreamEvent) {
+		if ev.ToolCall != nil {
+			got = append(got, fmt.Sprintf("%s %s", ev.Type, FormatToolCallPreview(ev.ToolCall.Name, ev.ToolCall.Arguments, 0)))
+		}
+	}); err != nil {
+		t.Fatalf("ConsumeStream() error = %v", err)
+	}
+
+	want := []string{
+		"tool_call_start get_weather()",
+		"tool_call_delta get_weather(location=Sea)",
+		"tool_call_delta get_weather(location=Seattle)",
+		"tool_call_done get_weather(location=Seattle)",
+		"tool_call_start get_time()",
+		"tool_call_delta get_time()",
+		"tool_call_done get_time()",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParsePartialJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{``, `null`},
+		{`{`, `{}`},
+		{`{"loc`, `{}`},
+		{`{"location"`, `{}`},
+		{`{"location":`, `{"location":null}`},
+		{`{"location": "Sea`, `{"location":"Sea"}`},
+		{`{"location": "Sea\`, `{"location":"Sea"}`},
+		{`{"a": 1, "b": tr`, `{"a":1}`},
+		{`{"a": [1, 2`, `{"a":[1,2]}`},
+		{`{"a": {"b": "c`, `{"a":{"b":"c"}}`},
+		{`{"a": "x, y", "b`, `{"a":"x, y"}`},
+		{`{"a": 1}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		data, _ := json.Marshal(ParsePartialJSON(tt.in))
+		if string(data) != tt.want {
+			t.Errorf("ParsePartialJSON(%q) = %s, want %s", tt.in, data, tt.want)
+		}
+	}
+}
+
+func TestNewToolCall(t *testing.T) {
+	call := newToolCall("call_1", "exec", `{"command": "ls"}`)
+	if call.ArgumentsError != "" || call.Arguments["command"] != "ls" {
+		t.Errorf("valid arguments = %+v", call)
+	}
+	call = newToolCall("call_1", "exec", "")
+	if call.ArgumentsError != "" || call.Arguments == nil {
+		t.Errorf("empty arguments = %+v, want an empty object", call)
+	}
+	call = newToolCall("call_1", "exec", `{"command": "ls"`)
+	if call.ArgumentsError == "" || call.Arguments["raw"] != `{"command": "ls"` {
+		t.Errorf("malformed arguments = %+v, want the error and the raw text", call)
+	}
+}
@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// TextWriter writes the text deltas of a stream to an io.Writer, flushing
+// it after each one when it buffers, such as a bufio.Writer or an
+// http.ResponseWriter.
+//
+// An ANSI-safe TextWriter holds back the end of a delta that stops inside
+// an ANSI escape sequence or a UTF-8 character until the next delta
+// completes it, so a terminal never renders half of one.
+type TextWriter struct {
+	w        io.Writer
+	ansiSafe bool
+	pending  []byte
+}
+
+func NewTextWriter(w io.Writer, ansiSafe bool) *TextWriter {
+	return &TextWriter{w: w, ansiSafe: ansiSafe}
+}
+
+// WriteDelta writes text, or as much of it as is safe to show.
+func (t *TextWriter) WriteDelta(text string) error {
+	if !t.ansiSafe {
+		return t.write([]byte(text))
+	}
+	t.pending = append(t.pending, text...)
+	n := completePrefix(t.pending)
+	if n == 0 {
+		return nil
+	}
+	err := t.write(t.pending[:n])
+	t.pending = append(t.pending[:0], t.pending[n:]...)
+	return err
+}
+
+// Close writes whatever was held back. It does not close the underlying
+// writer.
+func (t *TextWriter) Close() error {
+	if len(t.pending) == 0 {
+		return nil
+	}
+	err := t.write(t.pending)
+	t.pending = t.pending[:0]
+	return err
+}
+
+func (t *TextWriter) write(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if _, err := t.w.Write(b); err != nil {
+		return err
+	}
+	switch f := t.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case http.Flusher:
+		f.Flush()
+	}
+	return nil
+}
+
+// completePrefix returns how much of b can be written without splitting an
+// ANSI escape sequence or a UTF-8 character.
+func completePrefix(b []byte) int {
+	if i := bytes.LastIndexByte(b, 0x1b); i >= 0 && !escapeComplete(b[i:]) {
+		return i
+	}
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// escapeComplete reports whether seq, starting with ESC, holds a whole
+// escape sequence: a CSI sequence up to its final byte, an OSC sequence up
+// to BEL (one ended by ESC \ is found from that ESC), or ESC and one byte.
+func escapeComplete(seq []byte) bool {
+	if len(seq) < 2 {
+		return false
+	}
+	switch seq[1] {
+	case '[':
+		for _, c := range seq[2:] {
+			if c >= 0x40 && c <= 0x7e {
+				return true
+			}
+		}
+		return false
+	case ']':
+		return bytes.IndexByte(seq, 0x07) >= 0
+	}
+	return true
+}
+
+// StreamTo streams the reply of provider's default model to messages into
+// w as it arrives, ANSI-safe, and returns the whole response:
+//
+//	resp, err := providers.StreamTo(ctx, provider, messages, os.Stdout)
+func StreamTo(ctx context.Context, provider LLMProvider, messages []Message, w io.Writer) (*LLMResponse, error) {
+	tw := NewTextWriter(w, true)
+	var writeErr error
+	resp, err := ChatStreamCallbacks(ctx, provider, messages, nil, provider.GetDefaultModel(), nil, StreamCallbacks{
+		OnTextDelta: func(text string) {
+			if writeErr == nil {
+				writeErr = tw.WriteDelta(text)
+			}
+		},
+	})
+	if writeErr == nil {
+		writeErr = tw.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	return resp, nil
+}
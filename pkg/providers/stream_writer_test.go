@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeRecorder keeps each write separately.
+type writeRecorder struct {
+	writes []string
+}
+
+func (r *writeRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func TestTextWriter_ANSISafe(t *testing.T) {
+	rec := &writeRecorder{}
+	tw := NewTextWriter(rec, true)
+	for _, delta := range []string{"plain \x1b[3", "1mred\x1b[0m caf\xc3", "\xa9 \x1b]8;;https://example.com\x07link", "\x1b"} {
+		if err := tw.WriteDelta(delta); err != nil {
+			t.Fatalf("WriteDelta(%q) error: %v", delta, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	want := []string{"plain ", "\x1b[31mred\x1b[0m caf", "\xc3\xa9 \x1b]8;;https://example.com\x07link", "\x1b"}
+	if !reflect.DeepEqual(rec.writes, want) {
+		t.Errorf("writes = %q, want %q", rec.writes, want)
+	}
+}
+
+func TestStreamTo(t *testing.T) {
+	provider := &staticProvider{resp: &LLMResponse{Content: "whole reply", FinishReason: "stop"}}
+
+	var out strings.Builder
+	resp, err := StreamTo(context.Background(), provider, []Message{{Role: "user", Content: "Hi"}}, &out)
+	if err != nil {
+		t.Fatalf("StreamTo() error: %v", err)
+	}
+	if out.String() != "whole reply" || resp != provider.resp {
+		t.Errorf("StreamTo() wrote %q and returned %+v, want the reply", out.String(), resp)
+	}
+}
@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OptionResponseSchema (a JSON schema as map[string]interface{}) asks
+// ChatStructured for a final reply that is JSON matching the schema.
+const OptionResponseSchema = "response_schema"
+
+// OptionSchemaRetries (an int) is how many times ChatStructured asks the
+// model again after a reply that does not match the schema. Zero uses
+// DefaultSchemaRetries; a negative value disables retries.
+const OptionSchemaRetries = "schema_retries"
+
+// DefaultSchemaRetries is the number of re-prompts when OptionSchemaRetries
+// is not set.
+const DefaultSchemaRetries = 2
+
+// SchemaValidationError is returned by ChatStructured when the model's
+// reply still does not match the schema after every retry.
+type SchemaValidationError struct {
+	// Content is the last reply.
+	Content string
+	// Errors lists where the last reply differs from the schema.
+	Errors []string
+	// Attempts counts the replies that were checked.
+	Attempts int
+}
+
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("response does not match the schema after %d attempts: %s", e.Attempts, strings.Join(e.Errors, "; "))
+}
+
+// ChatStructured calls the provider like ChatAutoContinue and, when options
+// carry OptionResponseSchema, checks that a final reply is JSON matching
+// the schema. The schema is added to the system prompt; a reply that does
+// not match is sent back with the validation errors and a request to answer
+// again, up to OptionSchemaRetries times, before a *SchemaValidationError
+// is returned along with the last response. The content of a valid reply
+// is the bare JSON, without code fences; usage adds up every call.
+// Responses with tool calls are returned unchecked.
+func ChatStructured(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	schema, _ := options[OptionResponseSchema].(map[string]interface{})
+	if schema == nil {
+		return ChatAutoContinue(ctx, provider, messages, tools, model, options)
+	}
+	retries, _ := options[OptionSchemaRetries].(int)
+	if retries == 0 {
+		retries = DefaultSchemaRetries
+	}
+
+	messages = withSchemaInstruction(messages, schema)
+	var usage *UsageInfo
+	for attempt := 1; ; attempt++ {
+		resp, err := ChatAutoContinue(ctx, provider, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			if usage == nil {
+				usage = &UsageInfo{}
+			}
+			usage.Add(resp.Usage)
+		}
+		resp.Usage = usage
+		if len(resp.ToolCalls) > 0 {
+			return resp, nil
+		}
+
+		content := stripCodeFence(resp.Content)
+		var value interface{}
+		var problems []string
+		if err := json.Unmarshal([]byte(content), &value); err != nil {
+			problems = []string{fmt.Sprintf("the reply is not valid JSON: %v", err)}
+		} else {
+			problems = ValidateSchema(value, schema)
+		}
+		if len(problems) == 0 {
+			resp.Content = content
+			return resp, nil
+		}
+		if attempt > retries {
+			return resp, &SchemaValidationError{Content: resp.Content, Errors: problems, Attempts: attempt}
+		}
+		messages = append(append([]Message(nil), messages...),
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: "Your reply does not match the required JSON schema:\n- " +
+				strings.Join(problems, "\n- ") + "\nReply again with only the corrected JSON."})
+	}
+}
+
+// withSchemaInstruction adds the schema to the system prompt, creating one
+// when messages have none.
+func withSchemaInstruction(messages []Message, schema map[string]interface{}) []Message {
+	data, _ := json.Marshal(schema)
+	instruction := "Reply with only a JSON value, without any other text, that matches this JSON schema:\n" + string(data)
+	out := append([]Message(nil), messages...)
+	if len(out) > 0 && out[0].Role == "system" {
+		out[0].Content += "\n\n" + instruction
+		return out
+	}
+	return append([]Message{{Role: "system", Content: instruction}}, out...)
+}
+
+// stripCodeFence returns the JSON inside a markdown code block, which
+// models often wrap their replies in.
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(content, "```"), "```")
+	if newline := strings.IndexByte(inner, '\n'); newline >= 0 && !strings.ContainsAny(inner[:newline], "{[\"") {
+		inner = inner[newline+1:] // the language tag, e.g. json
+	}
+	return strings.TrimSpace(inner)
+}
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var personSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"name": map[string]interface{}{"type": "string", "minLength": 1},
+		"age":  map[string]interface{}{"type": "integer", "minimum": 0},
+		"role": map[string]interface{}{"type": "string", "enum": []string{"admin", "user"}},
+		"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+	},
+	"required":             []interface{}{"name", "age"},
+	"additionalProperties": false,
+}
+
+func TestChatStructured_RetriesWithErrors(t *testing.T) {
+	provider := &truncatingProvider{replies: []*LLMResponse{
+		{Content: `Sure! {"name": "Ann"}`, Usage: &UsageInfo{TotalTokens: 10}},
+		{Content: `{"name": "Ann", "age": 1.5}`, Usage: &UsageInfo{TotalTokens: 20}},
+		{Content: "```json\n{\"name\": \"Ann\", \"age\": 31}\n```", Usage: &UsageInfo{TotalTokens: 30}},
+	}}
+	messages := []Message{{Role: "system", Content: "You extract people."}, {Role: "user", Content: "Ann is 31"}}
+
+	resp, err := ChatStructured(context.Background(), provider, messages, nil, "static",
+		map[string]interface{}{OptionResponseSchema: personSchema})
+	if err != nil {
+		t.Fatalf("ChatStructured() error: %v", err)
+	}
+	if resp.Content != `{"name": "Ann", "age": 31}` {
+		t.Errorf("Content = %q, want the JSON without its code fence", resp.Content)
+	}
+	if resp.Usage.TotalTokens != 60 {
+		t.Errorf("Usage = %+v, want the sum of the three calls", resp.Usage)
+	}
+	if !strings.Contains(provider.requests[0][0].Content, `"additionalProperties":false`) || len(provider.requests[0]) != 2 {
+		t.Errorf("first request = %+v, want the schema in the system prompt", provider.requests[0])
+	}
+	retry := provider.requests[2]
+	if last := retry[len(retry)-1]; last.Role != "user" || !strings.Contains(last.Content, "age: expected integer, got number") {
+		t.Errorf("retry prompt = %+v, want the validation errors", last)
+	}
+}
+
+func TestChatStructured_GivesUp(t *testing.T) {
+	provider := &truncatingProvider{replies: []*LLMResponse{{Content: "no"}, {Content: "still no"}}}
+
+	resp, err := ChatStructured(context.Background(), provider, []Message{{Role: "user", Content: "Ann is 31"}}, nil, "static",
+		map[string]interface{}{OptionResponseSchema: personSchema, OptionSchemaRetries: 1})
+	var invalid *SchemaValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v, want a SchemaValidationError", err)
+	}
+	if invalid.Attempts != 2 || invalid.Content != "still no" || resp == nil || resp.Content != "still no" {
+		t.Errorf("error = %+v, response = %+v", invalid, resp)
+	}
+}
@@ -0,0 +1,54 @@
+package providers
+
+import "time"
+
+// ResponseTiming is how long a response took. QueueTime is spent waiting
+// for a provider's max_concurrent slot and requests_per_minute budget, and
+// Duration in the provider call after it. FirstToken, set for streamed responses, is the time until the
+// first text or tool call arrived. Retries counts requests the SDK repeated
+// after transient failures.
+type ResponseTiming struct {
+	QueueTime  time.Duration `json:"queue_time,omitempty"`
+	FirstToken time.Duration `json:"first_token,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Retries    int           `json:"retries,omitempty"`
+}
+
+// timing returns the timing of r, adding it if missing.
+func (r *LLMResponse) timing() *ResponseTiming {
+	if r.Timing == nil {
+		r.Timing = &ResponseTiming{}
+	}
+	return r.Timing
+}
+
+// setDuration records on resp the time since start, when its call began.
+func setDuration(resp *LLMResponse, start time.Time) {
+	if resp != nil {
+		resp.timing().Duration = time.Since(start)
+	}
+}
+
+// streamTimer times a streamed response.
+type streamTimer struct {
+	start      time.Time
+	firstToken time.Duration
+}
+
+func newStreamTimer() *streamTimer {
+	return &streamTimer{start: time.Now()}
+}
+
+// observe notes when the first text or tool call arrives.
+func (t *streamTimer) observe(ev StreamEvent) {
+	if t.firstToken == 0 && (ev.Type == StreamEventText || ev.Type == StreamEventToolCallStart) {
+		t.firstToken = time.Since(t.start)
+	}
+}
+
+// finish records the stream's timing on its final response.
+func (t *streamTimer) finish(resp *LLMResponse) {
+	timing := resp.timing()
+	timing.FirstToken = t.firstToken
+	timing.Duration = time.Since(t.start)
+}
@@ -1,6 +1,9 @@
 package providers
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ToolCall struct {
 	ID        string                 `json:"id"`
@@ -8,6 +11,10 @@ type ToolCall struct {
 	Function  *FunctionCall          `json:"function,omitempty"`
 	Name      string                 `json:"name,omitempty"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	// ArgumentsError is set when the arguments the model sent were not a
+	// JSON object; Arguments then holds the text under "raw". The agent
+	// asks the model to send such calls again instead of running them.
+	ArgumentsError string `json:"-"`
 }
 
 type FunctionCall struct {
@@ -16,10 +23,39 @@ type FunctionCall struct {
 }
 
 type LLMResponse struct {
-	Content      string     `json:"content"`
-	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
-	FinishReason string     `json:"finish_reason"`
+	Content      string       `json:"content"`
+	ToolCalls    []ToolCall   `json:"tool_calls,omitempty"`
+	FinishReason FinishReason `json:"finish_reason"`
+	// RawFinishReason is the finish or stop reason as the vendor sent it.
+	RawFinishReason string `json:"raw_finish_reason,omitempty"`
+	// StopSequence is the OptionStop sequence that ended the response,
+	// when the provider reports it.
+	StopSequence string     `json:"stop_sequence,omitempty"`
 	Usage        *UsageInfo `json:"usage,omitempty"`
+	// Metadata records how the response was produced, such as an
+	// escalation to another model.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Timing   *ResponseTiming   `json:"timing,omitempty"`
+	// Parts is the response's content in order when it holds more than
+	// text and tool calls, such as tools the provider ran itself.
+	Parts []ContentPart `json:"parts,omitempty"`
+	// Citations lists the sources the response cites, such as pages found
+	// by a web search the provider ran.
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation is a source a response cites. Grounded answers, such as
+// Cohere's from OptionDocuments, also name the document or tool result
+// and the span of Content it supports, as byte offsets. Date is when a
+// cited web page was published, as the provider reports it.
+type Citation struct {
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title,omitempty"`
+	SourceID string `json:"source_id,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Start    int    `json:"start,omitempty"`
+	End      int    `json:"end,omitempty"`
+	Date     string `json:"date,omitempty"`
 }
 
 type UsageInfo struct {
@@ -28,13 +64,46 @@ type UsageInfo struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// Add adds u, when reported, to the counts.
+func (usage *UsageInfo) Add(u *UsageInfo) {
+	if u == nil {
+		return
+	}
+	usage.PromptTokens += u.PromptTokens
+	usage.CompletionTokens += u.CompletionTokens
+	usage.TotalTokens += u.TotalTokens
+}
+
 type Message struct {
 	Role       string     `json:"role"`
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Parts, when set, is the full content of the message, including
+	// images and documents; see NewMessage and ContentParts.
+	Parts []ContentPart `json:"parts,omitempty"`
+	// Name tells apart participants sharing a role, such as the users of a
+	// group chat. Providers that support it (OpenAI) send it; CreatedAt and
+	// Metadata are only kept in the session.
+	Name      string            `json:"name,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitzero"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
+// Sampling options besides max_tokens, temperature (a float64) and top_p (a
+// float64). OptionStop is a []string of sequences that end the response;
+// OptionTopK (an int) samples from that many likeliest tokens, where the
+// provider supports it. OptionMinP (a float64) drops tokens less likely
+// than that fraction of the likeliest, and OptionRepetitionPenalty (a
+// float64, 1 for none) discourages repeating tokens; open model hosts such
+// as Together and Fireworks take them.
+const (
+	OptionStop              = "stop"
+	OptionTopK              = "top_k"
+	OptionMinP              = "min_p"
+	OptionRepetitionPenalty = "repetition_penalty"
+)
+
 type LLMProvider interface {
 	Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error)
 	GetDefaultModel() string
@@ -43,6 +112,11 @@ type LLMProvider interface {
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
+
+	// Computer marks a computer-use tool. Claude takes it as Anthropic's
+	// built-in computer tool for this display; other providers see the
+	// function above.
+	Computer *ComputerDisplay `json:"-"`
 }
 
 type ToolFunctionDefinition struct {
@@ -0,0 +1,283 @@
+package providers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateSchema checks value, as decoded by encoding/json, against a JSON
+// schema and describes each mismatch, naming the property it is in, as in
+// "items[0].name: required". It supports the keywords tool parameters and
+// SchemaFor use: type, enum, const, properties, required,
+// additionalProperties, items, anyOf, oneOf, minimum, maximum, minLength,
+// maxLength, minItems, maxItems and pattern. Other keywords are ignored.
+// Optional properties that are null are taken to be absent, as models often
+// send them that way.
+func ValidateSchema(value interface{}, schema map[string]interface{}) []string {
+	var problems []string
+	validateValue("", schema, value, &problems)
+	return problems
+}
+
+func validateValue(path string, schema map[string]interface{}, value interface{}, problems *[]string) {
+	addf := func(format string, a ...interface{}) {
+		msg := fmt.Sprintf(format, a...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		*problems = append(*problems, msg)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			addf("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+			return
+		}
+	}
+
+	if enum := toSlice(schema["enum"]); enum != nil {
+		found := false
+		for _, e := range enum {
+			if valuesEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addf("must be one of %s", formatEnum(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !valuesEqual(c, value) {
+		addf("must be %s", formatValue(c))
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		options := toSlice(schema[key])
+		if options == nil {
+			continue
+		}
+		matched := 0
+		for _, option := range options {
+			if sub, ok := option.(map[string]interface{}); ok && len(ValidateSchema(value, sub)) == 0 {
+				matched++
+			}
+		}
+		if matched == 0 {
+			addf("must match one of %d schemas", len(options))
+		} else if key == "oneOf" && matched > 1 {
+			addf("must match exactly one of %d schemas, matched %d", len(options), matched)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if n, ok := toFloat(schema["minLength"]); ok && float64(len([]rune(v))) < n {
+			addf("must be at least %v characters", n)
+		}
+		if n, ok := toFloat(schema["maxLength"]); ok && float64(len([]rune(v))) > n {
+			addf("must be at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				addf("must match pattern %q", pattern)
+			}
+		}
+	case map[string]interface{}:
+		validateObject(path, schema, v, problems)
+	case []interface{}:
+		if n, ok := toFloat(schema["minItems"]); ok && float64(len(v)) < n {
+			addf("must have at least %v items", n)
+		}
+		if n, ok := toFloat(schema["maxItems"]); ok && float64(len(v)) > n {
+			addf("must have at most %v items", n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(fmt.Sprintf("%s[%d]", path, i), items, item, problems)
+			}
+		}
+	default:
+		if f, ok := toFloat(value); ok {
+			if n, ok := toFloat(schema["minimum"]); ok && f < n {
+				addf("must be >= %v", n)
+			}
+			if n, ok := toFloat(schema["maximum"]); ok && f > n {
+				addf("must be <= %v", n)
+			}
+		}
+	}
+}
+
+func validateObject(path string, schema map[string]interface{}, obj map[string]interface{}, problems *[]string) {
+	props, _ := schema["properties"].(map[string]interface{})
+
+	for _, req := range toSlice(schema["required"]) {
+		key, _ := req.(string)
+		if v, ok := obj[key]; !ok || v == nil {
+			*problems = append(*problems, fmt.Sprintf("%s: required", joinPath(path, key)))
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := obj[key]
+		propSchema, known := props[key].(map[string]interface{})
+		if !known {
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*problems = append(*problems, fmt.Sprintf("%s: unknown property", joinPath(path, key)))
+				}
+			case map[string]interface{}:
+				validateValue(joinPath(path, key), extra, value, problems)
+			}
+			continue
+		}
+		// Models often send null for optional parameters; treat it as absent
+		if value == nil {
+			continue
+		}
+		validateValue(joinPath(path, key), propSchema, value, problems)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var types []string
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				types = append(types, str)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if f, ok := toFloat(value); ok {
+		if f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toFloat converts JSON numbers, and the Go numeric types used in
+// hand-written schemas, to float64.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// toSlice returns the elements of any slice, such as the []string enums
+// tools declare or the []interface{} a decoded schema holds.
+func toSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func valuesEqual(a, b interface{}) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatEnum(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = formatValue(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
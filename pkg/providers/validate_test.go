@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		json string
+		want []string
+	}{
+		{`{"name": "Ann", "age": 31, "role": "admin", "tags": ["a"]}`, nil},
+		{`[]`, []string{"expected object, got array"}},
+		{`{"name": "", "age": -1}`, []string{"age: must be >= 0", "name: must be at least 1 characters"}},
+		{`{"name": "Ann", "age": null}`, []string{"age: required"}},
+		{`{"name": "Ann", "age": 3, "role": "root", "tags": [1], "x": 1}`, []string{
+			`role: must be one of ["admin", "user"]`, "tags[0]: expected string, got integer", "x: unknown property",
+		}},
+	}
+	for _, tt := range tests {
+		var value interface{}
+		if err := json.Unmarshal([]byte(tt.json), &value); err != nil {
+			t.Fatal(err)
+		}
+		got := ValidateSchema(value, personSchema)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("ValidateSchema(%s) = %q, want %q", tt.json, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSchema_Combinators(t *testing.T) {
+	schema := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "pattern": "^[a-z]+$"},
+			map[string]interface{}{"type": "object", "properties": map[string]interface{}{"kind": map[string]interface{}{"const": "person"}}},
+		},
+	}
+	tests := []struct {
+		value interface{}
+		want  []string
+	}{
+		{"ann", nil},
+		{map[string]interface{}{"kind": "person"}, nil},
+		{"Ann", []string{"must match one of 2 schemas"}},
+		{map[string]interface{}{"kind": "robot"}, []string{"must match one of 2 schemas"}},
+	}
+	for _, tt := range tests {
+		got := ValidateSchema(tt.value, schema)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("ValidateSchema(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Warmer is implemented by providers that can get ready for their first
+// request before it is made.
+type Warmer interface {
+	// Warmup fetches the credentials requests need and, with connect set,
+	// opens a connection to the API.
+	Warmup(ctx context.Context, connect bool) error
+}
+
+// Warmup gets provider ready for its first request, so the request does
+// not wait seconds for authentication: OAuth and Microsoft Entra tokens
+// are fetched, and refreshed when about to expire, and API keys are read
+// from the system credential store. With connect set, a connection to the
+// API is also opened, so the first request skips the TLS handshake. The
+// providers behind wrappers and routers are warmed up; providers that do
+// not implement Warmer are left as they are.
+func Warmup(ctx context.Context, provider LLMProvider, connect bool) error {
+	for p := provider; p != nil; {
+		if w, ok := p.(Warmer); ok {
+			return w.Warmup(ctx, connect)
+		}
+		u, ok := p.(interface{ Unwrap() LLMProvider })
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	return nil
+}
+
+// preconnect opens a connection to baseURL that client keeps for the next
+// request. Any response will do, as only the connection is wanted.
+func preconnect(ctx context.Context, client *http.Client, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", baseURL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Warmup fetches a token from the token source, if any, and opens a
+// connection through the provider's client.
+func (p *HTTPProvider) Warmup(ctx context.Context, connect bool) error {
+	if p.tokenSource != nil {
+		if _, _, err := p.tokenSource(); err != nil {
+			return err
+		}
+	}
+	if connect {
+		return preconnect(ctx, p.httpClient, p.apiBase)
+	}
+	return nil
+}
+
+// Warmup refreshes the token and opens a connection through
+// http.DefaultClient, which the SDK's requests are sent with.
+func (p *ClaudeProvider) Warmup(ctx context.Context, connect bool) error {
+	if p.tokenSource != nil {
+		if _, err := p.tokenSource(); err != nil {
+			return fmt.Errorf("refreshing token: %w", err)
+		}
+	}
+	if connect {
+		return preconnect(ctx, http.DefaultClient, claudeBaseURL)
+	}
+	return nil
+}
+
+// Warmup refreshes the token and opens a connection through
+// http.DefaultClient, which the SDK's requests are sent with.
+func (p *CodexProvider) Warmup(ctx context.Context, connect bool) error {
+	if _, err := p.authOptions(nil); err != nil {
+		return err
+	}
+	if !connect {
+		return nil
+	}
+	if p.azureConfig != nil {
+		return preconnect(ctx, http.DefaultClient, p.azureConfig.Endpoint)
+	}
+	return preconnect(ctx, http.DefaultClient, codexBaseURL)
+}
+
+// Warmup warms up the default provider and those of the routing rules and
+// the overflow model. Providers of aliases are created when first used,
+// so they are not.
+func (r *Router) Warmup(ctx context.Context, connect bool) error {
+	seen := map[LLMProvider]bool{}
+	var errs []error
+	warm := func(p LLMProvider) {
+		if p == nil || seen[p] {
+			return
+		}
+		seen[p] = true
+		if err := Warmup(ctx, p, connect); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	warm(r.fallback)
+	for _, rule := range r.rules {
+		warm(rule.provider)
+	}
+	warm(r.overflow)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tokens := 0
+	p := NewHTTPProvider("", server.URL, "")
+	p.tokenSource = func() (string, string, error) {
+		tokens++
+		return "token", "", nil
+	}
+	wrapped := NewCachingProvider(p, "test", NewMemoryCache(10), time.Hour)
+
+	if err := Warmup(context.Background(), wrapped, false); err != nil {
+		t.Fatalf("Warmup() error: %v", err)
+	}
+	if tokens != 1 || len(methods) != 0 {
+		t.Errorf("without connect: %d tokens, %d requests; want 1 token and no requests", tokens, len(methods))
+	}
+
+	// Any response means the connection is open
+	if err := Warmup(context.Background(), wrapped, true); err != nil {
+		t.Fatalf("Warmup(connect) error: %v", err)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Errorf("requests = %v, want one HEAD", methods)
+	}
+}
+
+func TestWarmup_TokenError(t *testing.T) {
+	p := NewHTTPProvider("", "http://127.0.0.1:0", "")
+	p.tokenSource = func() (string, string, error) {
+		return "", "", errors.New("login expired")
+	}
+	if err := Warmup(context.Background(), p, false); err == nil {
+		t.Error("Warmup() succeeded with a failing token source")
+	}
+	// Providers that cannot warm up are left alone
+	if err := Warmup(context.Background(), &countingProvider{}, true); err != nil {
+		t.Errorf("Warmup(countingProvider) error: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package rag
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is a span of a document that is embedded and retrieved as a unit.
+// Lines are 1-based and inclusive.
+type Chunk struct {
+	Source    string `json:"source"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+	Vector    Vector `json:"vector,omitempty"`
+}
+
+// SplitText cuts text into chunks of about size characters along line
+// boundaries. Consecutive chunks repeat up to overlap characters of trailing
+// lines so a passage split between them is still found whole. Lines longer
+// than size are cut into several chunks.
+func SplitText(text string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = 1000
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	type line struct {
+		num  int
+		text string
+	}
+	var lines []line
+	for i, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		l = strings.TrimRight(l, "\r")
+		for utf8.RuneCountInString(l) > size {
+			cut := runeOffset(l, size)
+			lines = append(lines, line{i + 1, l[:cut]})
+			l = l[cut:]
+		}
+		lines = append(lines, line{i + 1, l})
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(lines); {
+		end, length := start, 0
+		for end < len(lines) {
+			n := utf8.RuneCountInString(lines[end].text) + 1
+			if length > 0 && length+n > size {
+				break
+			}
+			length += n
+			end++
+		}
+
+		parts := make([]string, 0, end-start)
+		for _, l := range lines[start:end] {
+			parts = append(parts, l.text)
+		}
+		if body := strings.Join(parts, "\n"); strings.TrimSpace(body) != "" {
+			chunks = append(chunks, Chunk{
+				StartLine: lines[start].num,
+				EndLine:   lines[end-1].num,
+				Text:      body,
+			})
+		}
+		if end >= len(lines) {
+			break
+		}
+
+		// Step back over trailing lines that fit in the overlap, always
+		// moving forward by at least one line
+		next, kept := end, 0
+		for next-1 > start {
+			n := utf8.RuneCountInString(lines[next-1].text) + 1
+			if kept+n > overlap {
+				break
+			}
+			kept += n
+			next--
+		}
+		start = next
+	}
+	return chunks
+}
+
+// runeOffset returns the byte offset of the n-th rune in s.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
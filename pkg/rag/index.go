@@ -0,0 +1,252 @@
+package rag
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Vector is an embedding. It is stored as base64 of little-endian float32s,
+// a third the size of a JSON number array.
+type Vector []float32
+
+func (v Vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(buf)%4 != 0 {
+		return errors.New("vector length is not a multiple of 4 bytes")
+	}
+	out := make(Vector, len(buf)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = out
+	return nil
+}
+
+// Document is an ingested file and its embedded chunks.
+type Document struct {
+	Path     string    `json:"path"`
+	Hash     string    `json:"hash"`
+	Ingested time.Time `json:"ingested"`
+	Chunks   []Chunk   `json:"chunks"`
+}
+
+// Result is a chunk returned by Search with its cosine similarity to the
+// query.
+type Result struct {
+	Chunk
+	Score float32
+}
+
+// indexFile is the on-disk layout of an Index.
+type indexFile struct {
+	Model     string               `json:"model"`
+	Documents map[string]*Document `json:"documents"`
+}
+
+// Index is a local vector index kept in <dir>/index.json. Search compares
+// the query with every chunk, which is exact and fast enough for the tens of
+// thousands of chunks a personal document set produces, without a database
+// dependency. The file is reloaded when another process, such as
+// "picoclaw rag ingest", rewrites it.
+type Index struct {
+	path string
+
+	mu       sync.RWMutex
+	data     indexFile
+	modified time.Time
+}
+
+// OpenIndex loads the index in dir, starting an empty one if none exists.
+func OpenIndex(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	ix := &Index{path: filepath.Join(dir, "index.json")}
+	ix.data.Documents = map[string]*Document{}
+	if err := ix.reload(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// reload reads the index file if it changed since it was last read.
+func (ix *Index) reload() error {
+	info, err := os.Stat(ix.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	ix.mu.RLock()
+	current := info.ModTime().Equal(ix.modified)
+	ix.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse index %s: %w", ix.path, err)
+	}
+	if file.Documents == nil {
+		file.Documents = map[string]*Document{}
+	}
+
+	ix.mu.Lock()
+	ix.data = file
+	ix.modified = info.ModTime()
+	ix.mu.Unlock()
+	return nil
+}
+
+// Save writes the index to disk.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	data, err := json.Marshal(ix.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if info, err := os.Stat(ix.path); err == nil {
+		ix.modified = info.ModTime()
+	}
+	return nil
+}
+
+// Model returns the embedding model the stored vectors came from.
+func (ix *Index) Model() string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.data.Model
+}
+
+// SetModel records the embedding model. Changing it drops every document,
+// since vectors from different models cannot be compared.
+func (ix *Index) SetModel(model string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.data.Model != model {
+		ix.data.Model = model
+		ix.data.Documents = map[string]*Document{}
+	}
+}
+
+// Document returns the stored document for path.
+func (ix *Index) Document(path string) (*Document, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	doc, ok := ix.data.Documents[path]
+	return doc, ok
+}
+
+// Documents returns the stored documents sorted by path.
+func (ix *Index) Documents() []*Document {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	docs := make([]*Document, 0, len(ix.data.Documents))
+	for _, doc := range ix.data.Documents {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+	return docs
+}
+
+// Put adds or replaces doc.
+func (ix *Index) Put(doc *Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.data.Documents[doc.Path] = doc
+}
+
+// Remove drops the document for path and reports whether it was stored.
+func (ix *Index) Remove(path string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	_, ok := ix.data.Documents[path]
+	delete(ix.data.Documents, path)
+	return ok
+}
+
+// Search returns the k chunks most similar to query, best first.
+func (ix *Index) Search(query []float32, k int) ([]Result, error) {
+	if err := ix.reload(); err != nil {
+		return nil, err
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var results []Result
+	for _, doc := range ix.data.Documents {
+		for _, chunk := range doc.Chunks {
+			if len(chunk.Vector) != len(query) {
+				continue
+			}
+			results = append(results, Result{Chunk: chunk, Score: cosine(query, chunk.Vector)})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Source != results[j].Source {
+			return results[i].Source < results[j].Source
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
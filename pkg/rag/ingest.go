@@ -0,0 +1,203 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+const (
+	// maxIngestFileSize skips files too large to be worth embedding whole.
+	maxIngestFileSize = 2 << 20
+	// embedBatchSize is how many chunks are sent per Embed call.
+	embedBatchSize = 64
+)
+
+// skippedIngestDirs are never descended into.
+var skippedIngestDirs = map[string]bool{
+	"node_modules": true,
+	"__pycache__":  true,
+	"vendor":       true,
+}
+
+// Ingester chunks files, embeds the chunks and stores them in an index.
+type Ingester struct {
+	Index        *Index
+	Embedder     providers.Embedder
+	Model        string
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+// IngestStats counts what one Ingest call did.
+type IngestStats struct {
+	Files     int // new or changed files indexed
+	Chunks    int // chunks sent to the embedder
+	Reused    int // chunks of changed files whose vectors were already indexed
+	Unchanged int // files skipped because their content was already indexed
+	Skipped   int // binary, oversized or unreadable files
+	Removed   int // indexed files under the path that no longer exist
+}
+
+// Ingest indexes the file or directory tree at root and saves the index.
+// Files whose content is already indexed are not embedded again, and
+// indexed files under root that have been deleted are dropped. Hidden
+// files and directories are skipped.
+func (in *Ingester) Ingest(ctx context.Context, root string) (IngestStats, error) {
+	var stats IngestStats
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return stats, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return stats, err
+	}
+	in.Index.SetModel(in.Model)
+	cache := in.vectorCache()
+
+	seen := map[string]bool{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			stats.Skipped++
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || d.IsDir() && skippedIngestDirs[d.Name()]) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		seen[path] = true
+		embedded, reused, ok, err := in.ingestFile(ctx, path, cache)
+		switch {
+		case err != nil:
+			return err
+		case !ok:
+			stats.Skipped++
+		case embedded < 0:
+			stats.Unchanged++
+		default:
+			stats.Files++
+			stats.Chunks += embedded
+			stats.Reused += reused
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	prefix := root + string(filepath.Separator)
+	for _, doc := range in.Index.Documents() {
+		if (doc.Path == root || strings.HasPrefix(doc.Path, prefix)) && !seen[doc.Path] {
+			in.Index.Remove(doc.Path)
+			stats.Removed++
+		}
+	}
+
+	return stats, in.Index.Save()
+}
+
+// vectorCache maps the text hash of every indexed chunk to its vector. The
+// index holds vectors of a single model, so the hash alone is enough of a
+// key.
+func (in *Ingester) vectorCache() map[string]Vector {
+	cache := map[string]Vector{}
+	for _, doc := range in.Index.Documents() {
+		for _, c := range doc.Chunks {
+			if len(c.Vector) > 0 {
+				cache[textHash(c.Text)] = c.Vector
+			}
+		}
+	}
+	return cache
+}
+
+// ingestFile embeds path into the index, taking vectors for chunks whose
+// text is already indexed from cache. It returns the number of chunks
+// embedded (-1 if the file was unchanged) and reused, with ok=false when
+// the file was not indexable text.
+func (in *Ingester) ingestFile(ctx context.Context, path string, cache map[string]Vector) (embedded, reused int, ok bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxIngestFileSize {
+		return 0, 0, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !isText(data) {
+		return 0, 0, false, nil
+	}
+
+	hash := textHash(string(data))
+	if doc, ok := in.Index.Document(path); ok && doc.Hash == hash {
+		return -1, 0, true, nil
+	}
+
+	chunks := SplitText(string(data), in.ChunkSize, in.ChunkOverlap)
+	var missing []int
+	for i := range chunks {
+		chunks[i].Source = path
+		if v, ok := cache[textHash(chunks[i].Text)]; ok {
+			chunks[i].Vector = v
+			reused++
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		batch := missing[start:min(start+embedBatchSize, len(missing))]
+		texts := make([]string, 0, len(batch))
+		for _, i := range batch {
+			texts = append(texts, chunks[i].Text)
+		}
+		vectors, err := in.Embedder.Embed(ctx, texts, in.Model)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("failed to embed %s: %w", path, err)
+		}
+		if len(vectors) != len(batch) {
+			return 0, 0, false, fmt.Errorf("failed to embed %s: got %d vectors for %d chunks", path, len(vectors), len(batch))
+		}
+		for j, i := range batch {
+			chunks[i].Vector = vectors[j]
+			cache[textHash(chunks[i].Text)] = vectors[j]
+		}
+	}
+
+	in.Index.Put(&Document{Path: path, Hash: hash, Ingested: time.Now(), Chunks: chunks})
+	return len(missing), reused, true, nil
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// isText reports whether data looks like UTF-8 text rather than a binary
+// file.
+func isText(data []byte) bool {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) < 0 && utf8.Valid(data)
+}
@@ -0,0 +1,191 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds text as a bag of hashed words, so texts sharing words
+// are similar.
+type wordEmbedder struct {
+	calls int
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, 64)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			h.Write([]byte(strings.Trim(word, ".,?")))
+			v[h.Sum32()%64]++
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func TestSplitText(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, strings.Repeat("x", 9)) // 10 characters with the newline
+	}
+	chunks := SplitText(strings.Join(lines, "\n"), 50, 20)
+
+	if len(chunks) < 2 {
+		t.Fatalf("len(chunks) = %d, want several", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 5 {
+		t.Errorf("first chunk lines = %d-%d, want 1-5", chunks[0].StartLine, chunks[0].EndLine)
+	}
+	if chunks[1].StartLine != 4 {
+		t.Errorf("second chunk starts at line %d, want 4 (two lines of overlap)", chunks[1].StartLine)
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != 20 {
+		t.Errorf("last chunk ends at line %d, want 20", last.EndLine)
+	}
+}
+
+func TestSplitText_LongLine(t *testing.T) {
+	chunks := SplitText("short\n"+strings.Repeat("é", 25), 10, 0)
+	for _, c := range chunks {
+		if n := len([]rune(c.Text)); n > 10 {
+			t.Errorf("chunk %q has %d characters, want at most 10", c.Text, n)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.StartLine != 2 {
+		t.Errorf("last chunk line = %d, want 2", last.StartLine)
+	}
+}
+
+func TestVector_JSONRoundTrip(t *testing.T) {
+	in := Vector{0.5, -1.25, 3}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out Vector
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(out) != len(in) || out[0] != in[0] || out[1] != in[1] || out[2] != in[2] {
+		t.Errorf("round trip = %v, want %v", out, in)
+	}
+}
+
+func TestIngestAndRetrieve(t *testing.T) {
+	docs := t.TempDir()
+	os.WriteFile(filepath.Join(docs, "garden.md"), []byte("Tomatoes need full sun and regular watering.\n"), 0644)
+	os.WriteFile(filepath.Join(docs, "car.md"), []byte("Change the engine oil every ten thousand kilometers.\n"), 0644)
+	os.WriteFile(filepath.Join(docs, "blob.bin"), []byte{0, 1, 2, 3}, 0644)
+	os.MkdirAll(filepath.Join(docs, ".git"), 0755)
+	os.WriteFile(filepath.Join(docs, ".git", "config"), []byte("ignored"), 0644)
+
+	indexDir := t.TempDir()
+	index, err := OpenIndex(indexDir)
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	embedder := &wordEmbedder{}
+	ingester := &Ingester{Index: index, Embedder: embedder, Model: "test", ChunkSize: 200}
+
+	stats, err := ingester.Ingest(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.Files != 2 || stats.Skipped != 1 {
+		t.Errorf("stats = %+v, want 2 files and 1 skipped", stats)
+	}
+
+	// A second pass finds nothing new to embed
+	calls := embedder.calls
+	stats, err = ingester.Ingest(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.Unchanged != 2 || embedder.calls != calls {
+		t.Errorf("re-ingest stats = %+v with %d new embed calls, want 2 unchanged and none", stats, embedder.calls-calls)
+	}
+
+	// The tool reads the index from disk, as the agent would
+	reopened, err := OpenIndex(indexDir)
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	tool := NewRetrieveTool(reopened, embedder, "test", 1)
+	result := tool.Execute(context.Background(), map[string]interface{}{"query": "how often should I water tomatoes?"})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	want := filepath.Join(docs, "garden.md") + ":1-1"
+	if !strings.Contains(result.ForLLM, want) || strings.Contains(result.ForLLM, "engine oil") {
+		t.Errorf("result = %q, want only the garden chunk attributed to %s", result.ForLLM, want)
+	}
+
+	os.Remove(filepath.Join(docs, "car.md"))
+	stats, err = ingester.Ingest(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if stats.Removed != 1 || len(index.Documents()) != 1 {
+		t.Errorf("after deleting a file: stats = %+v, %d documents; want 1 removed, 1 left", stats, len(index.Documents()))
+	}
+}
+
+func TestRetrieveTool_ModelMismatch(t *testing.T) {
+	index, err := OpenIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	index.SetModel("old-model")
+
+	tool := NewRetrieveTool(index, &wordEmbedder{}, "new-model", 5)
+	result := tool.Execute(context.Background(), map[string]interface{}{"query": "anything"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "old-model") {
+		t.Errorf("result = %+v, want an error naming the index's model", result)
+	}
+}
+
+func TestIngest_ReusesUnchangedChunks(t *testing.T) {
+	docs := t.TempDir()
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("paragraph %d %s", i, strings.Repeat("word ", 15)))
+	}
+	path := filepath.Join(docs, "notes.md")
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+
+	index, err := OpenIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	ingester := &Ingester{Index: index, Embedder: &wordEmbedder{}, Model: "test", ChunkSize: 100}
+	first, err := ingester.Ingest(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+
+	// Changing the last line leaves every earlier chunk as it was
+	lines[9] = "a new ending"
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	second, err := ingester.Ingest(context.Background(), docs)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if second.Chunks != 1 || second.Reused != first.Chunks-1 {
+		t.Errorf("re-ingest embedded %d and reused %d chunks, want 1 and %d", second.Chunks, second.Reused, first.Chunks-1)
+	}
+	doc, _ := index.Document(path)
+	for _, c := range doc.Chunks {
+		if len(c.Vector) == 0 {
+			t.Errorf("chunk at line %d has no vector", c.StartLine)
+		}
+	}
+}
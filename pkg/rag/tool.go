@@ -0,0 +1,119 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// maxTopK bounds how many chunks one retrieve call may return.
+const maxTopK = 20
+
+// RetrieveTool searches the document index for passages relevant to a query.
+type RetrieveTool struct {
+	index    *Index
+	embedder providers.Embedder
+	model    string
+	topK     int
+}
+
+// NewRetrieveTool searches index, embedding queries with model. topK is the
+// number of chunks returned when the call does not ask for a count.
+func NewRetrieveTool(index *Index, embedder providers.Embedder, model string, topK int) *RetrieveTool {
+	if topK <= 0 {
+		topK = 5
+	}
+	return &RetrieveTool{index: index, embedder: embedder, model: model, topK: topK}
+}
+
+// NewRetrieveToolFromConfig opens the workspace's document index and picks
+// the embedder cfg selects, falling back to provider.
+func NewRetrieveToolFromConfig(cfg config.RAGConfig, workspace string, provider providers.LLMProvider) (*RetrieveTool, error) {
+	embedder, err := providers.NewEmbedder(cfg, provider)
+	if err != nil {
+		return nil, err
+	}
+	index, err := OpenIndex(IndexDir(workspace))
+	if err != nil {
+		return nil, err
+	}
+	return NewRetrieveTool(index, embedder, cfg.Model, cfg.TopK), nil
+}
+
+// IndexDir is where a workspace's document index is kept.
+func IndexDir(workspace string) string {
+	return filepath.Join(workspace, "rag")
+}
+
+func (t *RetrieveTool) Name() string {
+	return "retrieve"
+}
+
+func (t *RetrieveTool) Description() string {
+	return "Search the user's ingested documents for passages relevant to a query. Returns the best matching excerpts with their file and line range; cite the source when using them."
+}
+
+func (t *RetrieveTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to look for, phrased as a question or description",
+			},
+			"top_k": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of passages to return (default %d, max %d)", t.topK, maxTopK),
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *RetrieveTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	query, ok := args["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return tools.ErrorResult("query is required")
+	}
+	k := t.topK
+	if n, ok := args["top_k"].(float64); ok && n > 0 {
+		k = min(int(n), maxTopK)
+	}
+
+	if model := t.index.Model(); model != "" && model != t.model {
+		return tools.ErrorResult(fmt.Sprintf("the index was built with embedding model %s, not %s; re-run picoclaw rag ingest", model, t.model))
+	}
+	vectors, err := t.embedder.Embed(ctx, []string{query}, t.model)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("failed to embed query: %v", err)).WithError(err)
+	}
+	if len(vectors) != 1 {
+		return tools.ErrorResult("failed to embed query: no embedding returned")
+	}
+	results, err := t.index.Search(vectors[0], k)
+	if err != nil {
+		return tools.ErrorResult(err.Error()).WithError(err)
+	}
+	if len(results) == 0 {
+		return tools.NewToolResult("No documents have been ingested. The user can add some with: picoclaw rag ingest <path>")
+	}
+	return tools.NewToolResult(FormatResults(results))
+}
+
+// FormatResults renders results as numbered excerpts headed by their source
+// file, line range and score.
+func FormatResults(results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%d] %s:%d-%d (score %.2f)\n%s", i+1, r.Source, r.StartLine, r.EndLine, r.Score, r.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,296 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// messagesRequest is the subset of an Anthropic Messages API request that is
+// passed on to providers. Parameters other backends have no equivalent for,
+// such as thinking and tool_choice, are accepted and ignored.
+type messagesRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        json.RawMessage    `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	Stream        bool               `json:"stream"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	TopK          *int               `json:"top_k,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	// Betas are the client's anthropic-beta header flags, passed on to
+	// Anthropic.
+	Betas []string `json:"-"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicTool struct {
+	Type        string                 `json:"type,omitempty"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// contentBlock is one block of message content, in requests and responses.
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      *string         `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// contentBlocks decodes content given as a string or as an array of blocks.
+func contentBlocks(raw json.RawMessage) ([]contentBlock, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []contentBlock{{Type: "text", Text: &text}}, nil
+	}
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("want a string or an array of content blocks")
+	}
+	return blocks, nil
+}
+
+// blocksText joins the text blocks of content. Other block types are
+// rejected, except thinking, which no other backend can take back.
+func blocksText(raw json.RawMessage) (string, error) {
+	blocks, err := contentBlocks(raw)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if b.Text != nil {
+				texts = append(texts, *b.Text)
+			}
+		case "thinking", "redacted_thinking":
+		default:
+			return "", fmt.Errorf("content blocks of type %q are not supported", b.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// messages converts the system prompt and messages. A user turn holding
+// tool results becomes one tool message per result, followed by any text.
+func (r *messagesRequest) messages() ([]providers.Message, error) {
+	var out []providers.Message
+	system, err := blocksText(r.System)
+	if err != nil {
+		return nil, fmt.Errorf("system: %w", err)
+	}
+	if system != "" {
+		out = append(out, providers.Message{Role: "system", Content: system})
+	}
+
+	for i, m := range r.Messages {
+		blocks, err := contentBlocks(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d].content: %w", i, err)
+		}
+		msg := providers.Message{Role: m.Role}
+		var texts []string
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				if b.Text != nil {
+					texts = append(texts, *b.Text)
+				}
+			case "thinking", "redacted_thinking":
+			case "tool_use":
+				arguments := map[string]interface{}{}
+				if len(b.Input) > 0 {
+					if err := json.Unmarshal(b.Input, &arguments); err != nil {
+						return nil, fmt.Errorf("messages[%d].content: tool_use input is not a JSON object", i)
+					}
+				}
+				msg.ToolCalls = append(msg.ToolCalls, providers.ToolCall{
+					ID:        b.ID,
+					Type:      "function",
+					Name:      b.Name,
+					Arguments: arguments,
+					Function:  &providers.FunctionCall{Name: b.Name, Arguments: argumentsJSON(arguments)},
+				})
+			case "tool_result":
+				result, err := blocksText(b.Content)
+				if err != nil {
+					return nil, fmt.Errorf("messages[%d].content: tool_result: %w", i, err)
+				}
+				if b.IsError {
+					result = "Error: " + result
+				}
+				out = append(out, providers.Message{Role: "tool", Content: result, ToolCallID: b.ToolUseID})
+			default:
+				return nil, fmt.Errorf("messages[%d].content: content blocks of type %q are not supported", i, b.Type)
+			}
+		}
+		msg.Content = strings.Join(texts, "\n")
+		if msg.Content != "" || len(msg.ToolCalls) > 0 || len(blocks) == 0 {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+// tools converts the custom tools. Anthropic server tools, such as
+// web_search, have no schema and no equivalent elsewhere, so they are
+// dropped.
+func (r *messagesRequest) tools() []providers.ToolDefinition {
+	var out []providers.ToolDefinition
+	for _, t := range r.Tools {
+		if t.Type != "" && t.Type != "custom" {
+			continue
+		}
+		out = append(out, providers.ToolDefinition{
+			Type: "function",
+			Function: providers.ToolFunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.InputSchema,
+			},
+		})
+	}
+	return out
+}
+
+func (r *messagesRequest) options() map[string]interface{} {
+	options := map[string]interface{}{}
+	if r.MaxTokens > 0 {
+		options["max_tokens"] = r.MaxTokens
+	}
+	if r.Temperature != nil {
+		options["temperature"] = *r.Temperature
+	}
+	if r.TopP != nil {
+		options["top_p"] = *r.TopP
+	}
+	if r.TopK != nil {
+		options[providers.OptionTopK] = *r.TopK
+	}
+	if len(r.StopSequences) > 0 {
+		options[providers.OptionStop] = r.StopSequences
+	}
+	if len(r.Betas) > 0 {
+		options[providers.OptionBetas] = r.Betas
+	}
+	return options
+}
+
+// estimateTokens approximates a token count for count_tokens requests,
+// which no other backend can answer exactly.
+func (r *messagesRequest) estimateTokens() int {
+	data, _ := json.Marshal(struct {
+		System   json.RawMessage    `json:"system,omitempty"`
+		Messages []anthropicMessage `json:"messages"`
+		Tools    []anthropicTool    `json:"tools,omitempty"`
+	}{r.System, r.Messages, r.Tools})
+	return utf8.RuneCount(data) / 3
+}
+
+type messagesResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []contentBlock `json:"content"`
+	StopReason   *string        `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func toAnthropicUsage(u *providers.UsageInfo) anthropicUsage {
+	if u == nil {
+		return anthropicUsage{}
+	}
+	return anthropicUsage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+// stopReason maps a finish reason to the Anthropic stop reasons.
+func stopReason(resp *providers.LLMResponse) string {
+	if len(resp.ToolCalls) > 0 {
+		return "tool_use"
+	}
+	if resp.StopSequence != "" {
+		return "stop_sequence"
+	}
+	switch resp.FinishReason {
+	case providers.FinishReasonLength:
+		return "max_tokens"
+	case providers.FinishReasonRefusal, providers.FinishReasonContentFilter:
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// stopSequence returns the matched stop sequence, or nil for JSON null.
+func stopSequence(resp *providers.LLMResponse) *string {
+	if resp.StopSequence == "" {
+		return nil
+	}
+	return &resp.StopSequence
+}
+
+func textBlock(text string) contentBlock {
+	return contentBlock{Type: "text", Text: &text}
+}
+
+func toolUseBlock(id, name string, input json.RawMessage) contentBlock {
+	return contentBlock{Type: "tool_use", ID: id, Name: name, Input: input}
+}
+
+// message renders a whole response.
+func message(id, model string, resp *providers.LLMResponse) messagesResponse {
+	content := []contentBlock{}
+	if resp.Content != "" {
+		content = append(content, textBlock(resp.Content))
+	}
+	for _, tc := range resp.ToolCalls {
+		content = append(content, toolUseBlock(tc.ID, toolCallName(tc), json.RawMessage(toolCallArguments(tc))))
+	}
+	reason := stopReason(resp)
+	return messagesResponse{
+		ID:           id,
+		Type:         "message",
+		Role:         "assistant",
+		Model:        model,
+		Content:      content,
+		StopReason:   &reason,
+		StopSequence: stopSequence(resp),
+		Usage:        toAnthropicUsage(resp.Usage),
+	}
+}
+
+func anthropicErrorBody(code, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    code,
+			"message": message,
+		},
+	}
+}
@@ -0,0 +1,468 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/serve/picoclawv1"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// SetAgentTools provides the tools of RunAgent on the gRPC API, which
+// serve.remote_agent enables. newTools is called for every run with the
+// current config, so each run gets its own tools and approvals.
+func (s *Server) SetAgentTools(newTools func(cfg *config.Config) *tools.ToolRegistry) {
+	s.agentTools = newTools
+}
+
+// GRPCServer returns a gRPC server offering the picoclaw.v1.PicoClaw service,
+// authenticated like the HTTP API with the authorization or x-api-key
+// metadata.
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := s.authorizeRPC(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := s.authorizeRPC(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+		}),
+	)
+	gs := grpc.NewServer(opts...)
+	picoclawv1.RegisterPicoClawServer(gs, &grpcService{s: s})
+	return gs
+}
+
+// authorizeRPC returns ctx carrying the virtual key of the call, or the
+// status to fail it with.
+func (s *Server) authorizeRPC(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if v := md.Get("authorization"); len(v) > 0 {
+		token = strings.TrimPrefix(v[0], "Bearer ")
+	} else if v := md.Get("x-api-key"); len(v) > 0 {
+		token = v[0]
+	}
+	key, authErr := s.authorize(token, true)
+	if authErr != nil {
+		code := codes.Unauthenticated
+		if authErr.status == http.StatusTooManyRequests {
+			code = codes.ResourceExhausted
+		}
+		return nil, status.Error(code, authErr.message)
+	}
+	if key != nil {
+		ctx = context.WithValue(ctx, apiKeyContextKey{}, key)
+	}
+	if v := md.Get(strings.ToLower(priorityHeader)); len(v) > 0 {
+		priority, err := providers.ParsePriority(v[0])
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		ctx = providers.WithPriority(ctx, priority)
+	}
+	return ctx, nil
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+type grpcService struct {
+	picoclawv1.UnimplementedPicoClawServer
+	s *Server
+}
+
+func (g *grpcService) Chat(ctx context.Context, req *picoclawv1.ChatRequest) (*picoclawv1.ChatResponse, error) {
+	model, provider, upstream, messages, toolDefs, err := g.prepareChat(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.Chat(ctx, messages, toolDefs, upstream, chatOptions(req))
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	g.s.record(ctx, resp.Usage)
+	return toPBResponse(model, resp), nil
+}
+
+func (g *grpcService) ChatStream(req *picoclawv1.ChatRequest, stream picoclawv1.PicoClaw_ChatStreamServer) error {
+	model, provider, upstream, messages, toolDefs, err := g.prepareChat(req)
+	if err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	events, err := providers.ChatStream(ctx, provider, messages, toolDefs, upstream, chatOptions(req))
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	usage := &streamUsage{messages: messages}
+	defer func() { g.s.record(ctx, usage.usage()) }()
+
+	// Provider indexes may count other content blocks; number calls from zero
+	toolIndex := map[int]int32{}
+	for ev := range events {
+		usage.add(ev)
+		var out *picoclawv1.ChatEvent
+		switch ev.Type {
+		case providers.StreamEventText:
+			out = &picoclawv1.ChatEvent{Event: &picoclawv1.ChatEvent_Text{Text: ev.Text}}
+		case providers.StreamEventToolCallStart:
+			toolIndex[ev.ToolCall.Index] = int32(len(toolIndex))
+			out = &picoclawv1.ChatEvent{Event: &picoclawv1.ChatEvent_ToolCallStart{ToolCallStart: &picoclawv1.ToolCallDelta{
+				Index: toolIndex[ev.ToolCall.Index], Id: ev.ToolCall.ID, Name: ev.ToolCall.Name,
+			}}}
+		case providers.StreamEventToolCallDelta:
+			out = &picoclawv1.ChatEvent{Event: &picoclawv1.ChatEvent_ToolCallDelta{ToolCallDelta: &picoclawv1.ToolCallDelta{
+				Index: toolIndex[ev.ToolCall.Index], ArgumentsDelta: ev.ToolCall.ArgumentsDelta,
+			}}}
+		case providers.StreamEventToolCallDone:
+			out = &picoclawv1.ChatEvent{Event: &picoclawv1.ChatEvent_ToolCallDone{ToolCallDone: &picoclawv1.ToolCall{
+				Id: ev.ToolCall.ID, Name: ev.ToolCall.Name, ArgumentsJson: argumentsJSON(ev.ToolCall.Arguments),
+			}}}
+		case providers.StreamEventError:
+			return status.Error(codes.Unavailable, ev.Err.Error())
+		case providers.StreamEventDone:
+			out = &picoclawv1.ChatEvent{Event: &picoclawv1.ChatEvent_Done{Done: toPBResponse(model, ev.Response)}}
+		}
+		if out == nil {
+			continue
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareChat resolves the provider of req and converts its messages and
+// tools.
+func (g *grpcService) prepareChat(req *picoclawv1.ChatRequest) (string, providers.LLMProvider, string, []providers.Message, []providers.ToolDefinition, error) {
+	model := req.GetModel()
+	if model == "" {
+		model = g.s.config().Agents.Defaults.Model
+	}
+	if len(req.GetMessages()) == 0 {
+		return "", nil, "", nil, nil, status.Error(codes.InvalidArgument, "messages is required")
+	}
+	messages, err := fromPBMessages(req.GetMessages())
+	if err != nil {
+		return "", nil, "", nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	toolDefs, err := fromPBTools(req.GetTools())
+	if err != nil {
+		return "", nil, "", nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	provider, upstream, err := g.s.route(model)
+	if err != nil {
+		return "", nil, "", nil, nil, status.Errorf(codes.NotFound, "no provider for model %s: %v", model, err)
+	}
+	logger.DebugCF("serve", "gRPC chat",
+		map[string]interface{}{
+			"model":    model,
+			"upstream": upstream,
+			"messages": len(messages),
+		})
+	return model, provider, upstream, messages, toolDefs, nil
+}
+
+func (g *grpcService) RunAgent(stream picoclawv1.PicoClaw_RunAgentServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := first.GetStart()
+	if start == nil {
+		return status.Error(codes.InvalidArgument, "the first request must be a start")
+	}
+	cfg := g.s.config()
+	if g.s.agentTools == nil || !cfg.Serve.RemoteAgent {
+		return status.Error(codes.Unimplemented, "agent runs are not enabled on this server (see serve.remote_agent)")
+	}
+
+	model := start.GetModel()
+	if model == "" {
+		model = cfg.Agents.Defaults.Model
+	}
+	provider, upstream, err := g.s.route(model)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "no provider for model %s: %v", model, err)
+	}
+	messages, err := fromPBMessages(start.GetMessages())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if start.GetSystemPrompt() != "" {
+		messages = append([]providers.Message{{Role: "system", Content: start.GetSystemPrompt()}}, messages...)
+	}
+	if start.GetGoal() != "" {
+		messages = append(messages, providers.Message{Role: "user", Content: start.GetGoal()})
+	}
+	if len(messages) == 0 {
+		return status.Error(codes.InvalidArgument, "a goal or messages is required")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	run := &agentRun{stream: stream, pending: map[string]chan picoclawv1.ApprovalDecision{}}
+	go run.receive(cancel)
+
+	// The client approves every tool call of the run. Its "always"
+	// decisions last for this run only, and are never saved where they
+	// would approve tools for other callers.
+	registry := g.s.agentTools(cfg)
+	gate := tools.NewApprovalGate(tools.ApproverFunc(run.approve), "")
+	gate.Require(registry.List()...)
+	registry.SetApprovalGate(gate)
+	runner := agent.NewRunner(provider, registry, upstream)
+	runner.MaxIterations = int(start.GetMaxIterations())
+	if runner.MaxIterations == 0 {
+		runner.MaxIterations = cfg.Agents.Defaults.MaxToolIterations
+	}
+	runner.MaxParallelTools = cfg.Agents.Defaults.MaxParallelTools
+	runner.Hooks = agent.RunnerHooks{
+		OnMessage: func(msg providers.Message) {
+			if msg.Role == "assistant" {
+				run.send(&picoclawv1.RunAgentEvent{Event: &picoclawv1.RunAgentEvent_Message{Message: toPBMessage(msg)}})
+			}
+		},
+		AfterToolCall: func(ctx context.Context, call providers.ToolCall, result *tools.ToolResult) {
+			content := result.ForLLM
+			if content == "" && result.Err != nil {
+				content = result.Err.Error()
+			}
+			run.send(&picoclawv1.RunAgentEvent{Event: &picoclawv1.RunAgentEvent_ToolResult{ToolResult: &picoclawv1.ToolResult{
+				ToolCallId: call.ID, Name: call.Name, Content: content, IsError: result.IsError,
+			}}})
+		},
+		OnToolOutput: func(call providers.ToolCall, chunk string) {
+			run.send(&picoclawv1.RunAgentEvent{Event: &picoclawv1.RunAgentEvent_ToolOutput{ToolOutput: &picoclawv1.ToolOutput{
+				ToolCallId: call.ID, Name: call.Name, Chunk: chunk,
+			}}})
+		},
+	}
+
+	result, err := runner.RunMessages(ctx, messages)
+	g.s.record(ctx, &result.Usage)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return run.send(&picoclawv1.RunAgentEvent{Event: &picoclawv1.RunAgentEvent_Result{Result: &picoclawv1.RunResult{
+		Content:              result.Content,
+		Iterations:           int32(result.Iterations),
+		Usage:                toPBUsage(&result.Usage),
+		MaxIterationsReached: result.MaxIterationsReached,
+	}}})
+}
+
+// agentRun relays the tool approvals of one RunAgent call.
+type agentRun struct {
+	stream picoclawv1.PicoClaw_RunAgentServer
+
+	sendMu sync.Mutex // tool calls may finish concurrently
+
+	mu      sync.Mutex
+	pending map[string]chan picoclawv1.ApprovalDecision // by approval request ID
+	closed  bool                                        // the client sent its last request
+}
+
+func (r *agentRun) send(ev *picoclawv1.RunAgentEvent) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+	return r.stream.Send(ev)
+}
+
+// receive delivers approval responses until the client closes its side of
+// the stream, cancelling the run if the stream breaks.
+func (r *agentRun) receive(cancel context.CancelFunc) {
+	for {
+		req, err := r.stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				cancel()
+			}
+			r.mu.Lock()
+			r.closed = true
+			for id, ch := range r.pending {
+				close(ch)
+				delete(r.pending, id)
+			}
+			r.mu.Unlock()
+			return
+		}
+		approval := req.GetApproval()
+		if approval == nil {
+			continue
+		}
+		r.mu.Lock()
+		if ch, ok := r.pending[approval.GetId()]; ok {
+			ch <- approval.GetDecision()
+			delete(r.pending, approval.GetId())
+		}
+		r.mu.Unlock()
+	}
+}
+
+// approve asks the client about a tool call and waits for its answer.
+func (r *agentRun) approve(ctx context.Context, req tools.ApprovalRequest) (tools.ApprovalDecision, error) {
+	id := "appr_" + randomID()
+	ch := make(chan picoclawv1.ApprovalDecision, 1)
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return tools.ApprovalDeny, fmt.Errorf("the client closed the stream")
+	}
+	r.pending[id] = ch
+	r.mu.Unlock()
+
+	err := r.send(&picoclawv1.RunAgentEvent{Event: &picoclawv1.RunAgentEvent_ApprovalRequest{ApprovalRequest: &picoclawv1.ApprovalRequest{
+		Id: id, Tool: req.Tool, ArgumentsJson: argumentsJSON(req.Arguments),
+	}}})
+	if err != nil {
+		return tools.ApprovalDeny, err
+	}
+
+	select {
+	case decision, ok := <-ch:
+		if !ok {
+			return tools.ApprovalDeny, fmt.Errorf("the client closed the stream")
+		}
+		switch decision {
+		case picoclawv1.ApprovalDecision_APPROVAL_DECISION_ALLOW_ONCE:
+			return tools.ApprovalAllowOnce, nil
+		case picoclawv1.ApprovalDecision_APPROVAL_DECISION_ALLOW_ALWAYS:
+			return tools.ApprovalAllowAlways, nil
+		default:
+			return tools.ApprovalDeny, nil
+		}
+	case <-ctx.Done():
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+		return tools.ApprovalDeny, ctx.Err()
+	}
+}
+
+func chatOptions(req *picoclawv1.ChatRequest) map[string]interface{} {
+	options := map[string]interface{}{}
+	if req.MaxTokens != nil {
+		options["max_tokens"] = int(req.GetMaxTokens())
+	}
+	if req.Temperature != nil {
+		options["temperature"] = req.GetTemperature()
+	}
+	return options
+}
+
+func fromPBMessages(in []*picoclawv1.Message) ([]providers.Message, error) {
+	out := make([]providers.Message, 0, len(in))
+	for i, m := range in {
+		msg := providers.Message{Role: m.GetRole(), Content: m.GetContent(), ToolCallID: m.GetToolCallId()}
+		for _, tc := range m.GetToolCalls() {
+			arguments := map[string]interface{}{}
+			if tc.GetArgumentsJson() != "" {
+				if err := json.Unmarshal([]byte(tc.GetArgumentsJson()), &arguments); err != nil {
+					return nil, fmt.Errorf("messages[%d].tool_calls: arguments are not a JSON object", i)
+				}
+			}
+			msg.ToolCalls = append(msg.ToolCalls, providers.ToolCall{
+				ID:        tc.GetId(),
+				Type:      "function",
+				Name:      tc.GetName(),
+				Arguments: arguments,
+				Function:  &providers.FunctionCall{Name: tc.GetName(), Arguments: tc.GetArgumentsJson()},
+			})
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+func fromPBTools(in []*picoclawv1.Tool) ([]providers.ToolDefinition, error) {
+	var out []providers.ToolDefinition
+	for _, t := range in {
+		parameters := map[string]interface{}{"type": "object"}
+		if t.GetParametersJson() != "" {
+			if err := json.Unmarshal([]byte(t.GetParametersJson()), &parameters); err != nil {
+				return nil, fmt.Errorf("tools: parameters of %s are not a JSON object", t.GetName())
+			}
+		}
+		out = append(out, providers.ToolDefinition{
+			Type: "function",
+			Function: providers.ToolFunctionDefinition{
+				Name:        t.GetName(),
+				Description: t.GetDescription(),
+				Parameters:  parameters,
+			},
+		})
+	}
+	return out, nil
+}
+
+func toPBToolCalls(calls []providers.ToolCall) []*picoclawv1.ToolCall {
+	var out []*picoclawv1.ToolCall
+	for _, tc := range calls {
+		out = append(out, &picoclawv1.ToolCall{Id: tc.ID, Name: toolCallName(tc), ArgumentsJson: toolCallArguments(tc)})
+	}
+	return out
+}
+
+func toPBMessage(msg providers.Message) *picoclawv1.Message {
+	return &picoclawv1.Message{
+		Role:       msg.Role,
+		Content:    msg.Content,
+		ToolCalls:  toPBToolCalls(msg.ToolCalls),
+		ToolCallId: msg.ToolCallID,
+	}
+}
+
+func toPBUsage(u *providers.UsageInfo) *picoclawv1.Usage {
+	if u == nil {
+		return nil
+	}
+	return &picoclawv1.Usage{
+		PromptTokens:     int32(u.PromptTokens),
+		CompletionTokens: int32(u.CompletionTokens),
+		TotalTokens:      int32(u.TotalTokens),
+	}
+}
+
+func toPBResponse(model string, resp *providers.LLMResponse) *picoclawv1.ChatResponse {
+	return &picoclawv1.ChatResponse{
+		Model:        model,
+		Content:      resp.Content,
+		ToolCalls:    toPBToolCalls(resp.ToolCalls),
+		FinishReason: finishReason(resp),
+		Usage:        toPBUsage(resp.Usage),
+	}
+}
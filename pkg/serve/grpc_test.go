@@ -0,0 +1,251 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/serve/picoclawv1"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// scriptedProvider returns its responses in order, repeating the last one.
+type scriptedProvider struct {
+	responses []*providers.LLMResponse
+	calls     int
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	i := min(p.calls, len(p.responses)-1)
+	p.calls++
+	return p.responses[i], nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string {
+	return ""
+}
+
+// guardedTool needs approval and records whether it ran.
+type guardedTool struct {
+	ran bool
+}
+
+func (t *guardedTool) Name() string           { return "deploy" }
+func (t *guardedTool) Description() string    { return "Deploys" }
+func (t *guardedTool) RequiresApproval() bool { return true }
+func (t *guardedTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *guardedTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	t.ran = true
+	if w := tools.StreamWriter(ctx); w != nil {
+		w.Write([]byte("rolling out\n"))
+	}
+	return tools.NewToolResult("deployed")
+}
+
+func newTestGRPCClient(t *testing.T, server *Server) picoclawv1.PicoClawClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := server.GRPCServer()
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return picoclawv1.NewPicoClawClient(conn)
+}
+
+func TestGRPCChat(t *testing.T) {
+	cfg := testConfig()
+	cfg.Serve.APIKeys = []string{"secret"}
+	provider := &fakeProvider{response: &providers.LLMResponse{
+		Content: "Bonjour",
+		Usage:   &providers.UsageInfo{PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5},
+	}}
+	client := newTestGRPCClient(t, NewServer(cfg, func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return provider, nil
+	}))
+
+	req := &picoclawv1.ChatRequest{Messages: []*picoclawv1.Message{{Role: "user", Content: "Say hello in French"}}}
+	if _, err := client.Chat(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without key: %v, want Unauthenticated", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	resp, err := client.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.GetContent() != "Bonjour" || resp.GetModel() != "gpt-4o" || resp.GetUsage().GetTotalTokens() != 5 {
+		t.Errorf("response = %v", resp)
+	}
+	if provider.model != "gpt-4o" || provider.messages[0].Content != "Say hello in French" {
+		t.Errorf("provider got model %q, messages %v", provider.model, provider.messages)
+	}
+}
+
+func TestGRPCRunAgentApproval(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "deploy", Arguments: map[string]interface{}{}}}},
+		{Content: "Deployed."},
+	}}
+	tool := &guardedTool{}
+	approvals := filepath.Join(t.TempDir(), "approvals.json")
+	cfg := testConfig()
+	cfg.Serve.RemoteAgent = true
+	server := NewServer(cfg, func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return provider, nil
+	})
+	server.SetAgentTools(func(cfg *config.Config) *tools.ToolRegistry {
+		registry := tools.NewToolRegistry()
+		registry.Register(tool)
+		// Approvals configured for local use must not reach remote runs
+		registry.SetApprovalGate(tools.NewApprovalGate(nil, approvals))
+		return registry
+	})
+	client := newTestGRPCClient(t, server)
+
+	stream, err := client.RunAgent(context.Background())
+	if err != nil {
+		t.Fatalf("RunAgent: %v", err)
+	}
+	err = stream.Send(&picoclawv1.RunAgentRequest{Request: &picoclawv1.RunAgentRequest_Start{Start: &picoclawv1.StartRun{Goal: "deploy it"}}})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var result *picoclawv1.RunResult
+	var toolResult *picoclawv1.ToolResult
+	var toolOutput string
+	for result == nil {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		switch e := ev.GetEvent().(type) {
+		case *picoclawv1.RunAgentEvent_ApprovalRequest:
+			if e.ApprovalRequest.GetTool() != "deploy" {
+				t.Errorf("approval request = %v", e.ApprovalRequest)
+			}
+			stream.Send(&picoclawv1.RunAgentRequest{Request: &picoclawv1.RunAgentRequest_Approval{Approval: &picoclawv1.ApprovalResponse{
+				Id:       e.ApprovalRequest.GetId(),
+				Decision: picoclawv1.ApprovalDecision_APPROVAL_DECISION_ALLOW_ALWAYS,
+			}}})
+		case *picoclawv1.RunAgentEvent_ToolOutput:
+			if toolResult != nil || e.ToolOutput.GetToolCallId() != "call_1" {
+				t.Errorf("unexpected tool output %v", e.ToolOutput)
+			}
+			toolOutput += e.ToolOutput.GetChunk()
+		case *picoclawv1.RunAgentEvent_ToolResult:
+			toolResult = e.ToolResult
+		case *picoclawv1.RunAgentEvent_Result:
+			result = e.Result
+		}
+	}
+
+	if !tool.ran {
+		t.Error("approved tool did not run")
+	}
+	if toolResult.GetToolCallId() != "call_1" || toolResult.GetContent() != "deployed" {
+		t.Errorf("tool result = %v", toolResult)
+	}
+	if toolOutput != "rolling out\n" {
+		t.Errorf("tool output = %q", toolOutput)
+	}
+	if result.GetContent() != "Deployed." || result.GetIterations() != 2 {
+		t.Errorf("result = %v", result)
+	}
+	if _, err := os.Stat(approvals); !os.IsNotExist(err) {
+		t.Errorf("a remote allow-always decision was saved: %v", err)
+	}
+}
+
+func TestGRPCRunAgentOptIn(t *testing.T) {
+	provider := &scriptedProvider{responses: []*providers.LLMResponse{
+		{ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "count", Arguments: map[string]interface{}{}}}},
+		{Content: "Counted."},
+	}}
+	tool := &countTool{}
+	server := NewServer(testConfig(), func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return provider, nil
+	})
+	server.SetAgentTools(func(cfg *config.Config) *tools.ToolRegistry {
+		registry := tools.NewToolRegistry()
+		registry.Register(tool)
+		return registry
+	})
+	client := newTestGRPCClient(t, server)
+	start := &picoclawv1.RunAgentRequest{Request: &picoclawv1.RunAgentRequest_Start{Start: &picoclawv1.StartRun{Goal: "count"}}}
+
+	stream, err := client.RunAgent(context.Background())
+	if err != nil {
+		t.Fatalf("RunAgent: %v", err)
+	}
+	stream.Send(start)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("RunAgent without serve.remote_agent: %v, want Unimplemented", err)
+	}
+
+	// Enabled by a config reload; tools that need no approval locally still
+	// need the client's
+	cfg := testConfig()
+	cfg.Serve.RemoteAgent = true
+	server.SetConfig(cfg)
+	stream, err = client.RunAgent(context.Background())
+	if err != nil {
+		t.Fatalf("RunAgent: %v", err)
+	}
+	stream.Send(start)
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if req := ev.GetApprovalRequest(); req != nil {
+			stream.Send(&picoclawv1.RunAgentRequest{Request: &picoclawv1.RunAgentRequest_Approval{Approval: &picoclawv1.ApprovalResponse{
+				Id:       req.GetId(),
+				Decision: picoclawv1.ApprovalDecision_APPROVAL_DECISION_DENY,
+			}}})
+		}
+		if ev.GetResult() != nil {
+			break
+		}
+	}
+	if tool.runs != 0 {
+		t.Errorf("denied tool ran %d times", tool.runs)
+	}
+}
+
+// countTool needs no approval and counts its runs.
+type countTool struct {
+	runs int
+}
+
+func (t *countTool) Name() string        { return "count" }
+func (t *countTool) Description() string { return "Counts" }
+func (t *countTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *countTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	t.runs++
+	return tools.NewToolResult("counted")
+}
@@ -0,0 +1,388 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// secretPrefix starts every generated key so they are easy to recognize.
+const secretPrefix = "sk-picoclaw-"
+
+var (
+	// ErrRateLimited is returned by Allow when a key has used its requests
+	// for the current minute.
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrQuotaExceeded is returned by Allow when a key has used its token
+	// budget for the month.
+	ErrQuotaExceeded = errors.New("monthly token budget exhausted")
+)
+
+// APIKey is a virtual key for the serve API. Only the hash of the secret is
+// stored.
+type APIKey struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Hash              string     `json:"hash"`
+	Prefix            string     `json:"prefix"`
+	Created           time.Time  `json:"created"`
+	Revoked           *time.Time `json:"revoked,omitempty"`
+	RequestsPerMinute int        `json:"requests_per_minute,omitempty"`
+	MonthlyTokens     int        `json:"monthly_tokens,omitempty"`
+}
+
+// KeyUsage is what a key used in one month.
+type KeyUsage struct {
+	Requests         int `json:"requests"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Tokens returns the prompt and completion tokens together.
+func (u KeyUsage) Tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// KeyStore keeps virtual keys in <dir>/keys.json and their usage in
+// <dir>/usage.json. The CLI writes the keys and the server writes the
+// usage, so neither overwrites the other's changes; the server reloads the
+// keys when "picoclaw serve keys" changes them. Rate limits and usage are
+// counted in memory; the server writes the usage out with Flush.
+type KeyStore struct {
+	keysPath  string
+	usagePath string
+
+	mu       sync.Mutex
+	keys     []*APIKey
+	modified time.Time
+	usage    map[string]map[string]*KeyUsage // by key ID, then month
+	dirty    bool                            // usage changed since the last Flush
+	recent   map[string][]time.Time          // request times in the last minute, by key ID
+}
+
+// KeyDir returns where the keys of the serve API live in workspace.
+func KeyDir(workspace string) string {
+	return filepath.Join(workspace, "serve")
+}
+
+// OpenKeyStore loads the keys and usage in dir.
+func OpenKeyStore(dir string) (*KeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	ks := &KeyStore{
+		keysPath:  filepath.Join(dir, "keys.json"),
+		usagePath: filepath.Join(dir, "usage.json"),
+		usage:     map[string]map[string]*KeyUsage{},
+		recent:    map[string][]time.Time{},
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.reloadLocked(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(ks.usagePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read key usage: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &ks.usage); err != nil {
+			return nil, fmt.Errorf("failed to parse key usage %s: %w", ks.usagePath, err)
+		}
+	}
+	return ks, nil
+}
+
+// reloadLocked reads the keys file if it changed since it was last read.
+func (ks *KeyStore) reloadLocked() error {
+	info, err := os.Stat(ks.keysPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read keys: %w", err)
+	}
+	if info.ModTime().Equal(ks.modified) {
+		return nil
+	}
+	data, err := os.ReadFile(ks.keysPath)
+	if err != nil {
+		return fmt.Errorf("failed to read keys: %w", err)
+	}
+	var keys []*APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("failed to parse keys %s: %w", ks.keysPath, err)
+	}
+	ks.keys = keys
+	ks.modified = info.ModTime()
+	return nil
+}
+
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (ks *KeyStore) saveKeysLocked() error {
+	if err := writeFileAtomic(ks.keysPath, ks.keys); err != nil {
+		return fmt.Errorf("failed to write keys: %w", err)
+	}
+	if info, err := os.Stat(ks.keysPath); err == nil {
+		ks.modified = info.ModTime()
+	}
+	return nil
+}
+
+// Create adds a key and returns it with its secret, which is not stored and
+// cannot be shown again. Zero limits mean unlimited.
+func (ks *KeyStore) Create(name string, requestsPerMinute, monthlyTokens int) (*APIKey, string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.reloadLocked(); err != nil {
+		return nil, "", err
+	}
+	for _, k := range ks.keys {
+		if k.Name == name && k.Revoked == nil {
+			return nil, "", fmt.Errorf("a key named %q already exists", name)
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	secret := secretPrefix + hex.EncodeToString(b)
+	// The ID is listed and logged, so it shares nothing with the secret
+	id, err := ks.newIDLocked()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &APIKey{
+		ID:                id,
+		Name:              name,
+		Hash:              hashSecret(secret),
+		Prefix:            secret[:len(secretPrefix)+6],
+		Created:           time.Now(),
+		RequestsPerMinute: requestsPerMinute,
+		MonthlyTokens:     monthlyTokens,
+	}
+	ks.keys = append(ks.keys, key)
+	if err := ks.saveKeysLocked(); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// newIDLocked returns a random key ID that no key has yet.
+func (ks *KeyStore) newIDLocked() (string, error) {
+	b := make([]byte, 4)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate key ID: %w", err)
+		}
+		id := "key_" + hex.EncodeToString(b)
+		taken := false
+		for _, k := range ks.keys {
+			taken = taken || k.ID == id
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
+// Revoke disables the key with the given ID or name. Its usage is kept.
+func (ks *KeyStore) Revoke(idOrName string) (*APIKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.reloadLocked(); err != nil {
+		return nil, err
+	}
+	for _, k := range ks.keys {
+		if (k.ID == idOrName || k.Name == idOrName) && k.Revoked == nil {
+			now := time.Now()
+			k.Revoked = &now
+			if err := ks.saveKeysLocked(); err != nil {
+				return nil, err
+			}
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("no active key %q", idOrName)
+}
+
+// Keys returns every key, revoked ones included, oldest first.
+func (ks *KeyStore) Keys() []APIKey {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.reloadLocked()
+	out := make([]APIKey, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		out = append(out, *k)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
+
+// Len returns the number of keys ever created. Once there is one, the
+// server requires a key even after every key is revoked.
+func (ks *KeyStore) Len() int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.reloadLocked()
+	return len(ks.keys)
+}
+
+// Lookup returns the active key with the given secret.
+func (ks *KeyStore) Lookup(secret string) (*APIKey, bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.reloadLocked()
+	hash := hashSecret(secret)
+	for _, k := range ks.keys {
+		if k.Hash == hash && k.Revoked == nil {
+			key := *k
+			return &key, true
+		}
+	}
+	return nil, false
+}
+
+// Allow counts a request against key's limits, returning ErrRateLimited or
+// ErrQuotaExceeded when it is over one. The token budget is checked before
+// the request, so the request that crosses it still completes.
+func (ks *KeyStore) Allow(key *APIKey) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key.MonthlyTokens > 0 {
+		if u := ks.usage[key.ID][month(time.Now())]; u != nil && u.Tokens() >= key.MonthlyTokens {
+			return ErrQuotaExceeded
+		}
+	}
+	if key.RequestsPerMinute > 0 {
+		now := time.Now()
+		recent := ks.recent[key.ID]
+		for len(recent) > 0 && now.Sub(recent[0]) >= time.Minute {
+			recent = recent[1:]
+		}
+		if len(recent) >= key.RequestsPerMinute {
+			ks.recent[key.ID] = recent
+			return ErrRateLimited
+		}
+		ks.recent[key.ID] = append(recent, now)
+	}
+	return nil
+}
+
+// Record attributes a completed request and its token usage to key. The
+// counts are kept in memory until the next Flush.
+func (ks *KeyStore) Record(key *APIKey, usage *providers.UsageInfo) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	months := ks.usage[key.ID]
+	if months == nil {
+		months = map[string]*KeyUsage{}
+		ks.usage[key.ID] = months
+	}
+	m := month(time.Now())
+	u := months[m]
+	if u == nil {
+		u = &KeyUsage{}
+		months[m] = u
+	}
+	u.Requests++
+	if usage != nil {
+		u.PromptTokens += usage.PromptTokens
+		u.CompletionTokens += usage.CompletionTokens
+	}
+	ks.dirty = true
+}
+
+// Flush writes the usage recorded since the last Flush to usage.json.
+func (ks *KeyStore) Flush() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if !ks.dirty {
+		return nil
+	}
+	if err := writeFileAtomic(ks.usagePath, ks.usage); err != nil {
+		return fmt.Errorf("failed to write key usage: %w", err)
+	}
+	ks.dirty = false
+	return nil
+}
+
+// FlushEvery flushes the usage every interval until ctx is done, and once
+// more then.
+func (ks *KeyStore) FlushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ks.flushLogged()
+			return
+		case <-ticker.C:
+			ks.flushLogged()
+		}
+	}
+}
+
+func (ks *KeyStore) flushLogged() {
+	if err := ks.Flush(); err != nil {
+		logger.WarnCF("serve", "Failed to save key usage",
+			map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// Usage returns what the key with id used in the month containing t. The
+// server answers from memory; other processes read the latest usage the
+// server flushed.
+func (ks *KeyStore) Usage(id string, t time.Time) KeyUsage {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if data, err := os.ReadFile(ks.usagePath); err == nil && !ks.dirty {
+		var usage map[string]map[string]*KeyUsage
+		if json.Unmarshal(data, &usage) == nil {
+			ks.usage = usage
+		}
+	}
+	if u := ks.usage[id][month(t)]; u != nil {
+		return *u
+	}
+	return KeyUsage{}
+}
+
+func month(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
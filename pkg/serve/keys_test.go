@@ -0,0 +1,198 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestKeyStoreCreateRevoke(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := OpenKeyStore(dir)
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, secret, err := ks.Create("alice", 0, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, _, err := ks.Create("alice", 0, 0); err == nil {
+		t.Error("Create accepted a duplicate name")
+	}
+	if strings.Contains(secret, strings.TrimPrefix(key.ID, "key_")) {
+		t.Errorf("ID %s is part of the secret", key.ID)
+	}
+
+	// A second store, as the CLI and server have, sees the key
+	other, err := OpenKeyStore(dir)
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	if got, ok := other.Lookup(secret); !ok || got.ID != key.ID {
+		t.Fatalf("Lookup = %v, %v; want %s", got, ok, key.ID)
+	}
+	if _, ok := other.Lookup(secret + "x"); ok {
+		t.Error("Lookup accepted a wrong secret")
+	}
+
+	if _, err := ks.Revoke("alice"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	// Make sure the modification time moves on coarse filesystems
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(ks.keysPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := other.Lookup(secret); ok {
+		t.Error("Lookup accepted a revoked key")
+	}
+	if other.Len() != 1 {
+		t.Errorf("Len = %d, want 1", other.Len())
+	}
+}
+
+func TestKeyStoreLimits(t *testing.T) {
+	ks, err := OpenKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, _, err := ks.Create("bob", 2, 100)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ks.Allow(key); err != nil {
+			t.Fatalf("Allow %d: %v", i, err)
+		}
+	}
+	if err := ks.Allow(key); err != ErrRateLimited {
+		t.Fatalf("Allow = %v, want ErrRateLimited", err)
+	}
+
+	key.RequestsPerMinute = 0
+	ks.Record(key, &providers.UsageInfo{PromptTokens: 80, CompletionTokens: 30})
+	if err := ks.Allow(key); err != ErrQuotaExceeded {
+		t.Fatalf("Allow = %v, want ErrQuotaExceeded", err)
+	}
+	if u := ks.Usage(key.ID, time.Now()); u.Requests != 1 || u.Tokens() != 110 {
+		t.Errorf("Usage = %+v", u)
+	}
+}
+
+func TestKeyStoreFlush(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := OpenKeyStore(dir)
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, _, err := ks.Create("carol", 0, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ks.Record(key, &providers.UsageInfo{PromptTokens: 5, CompletionTokens: 2})
+	if _, err := os.Stat(ks.usagePath); !os.IsNotExist(err) {
+		t.Fatalf("usage written before Flush: %v", err)
+	}
+	if u := ks.Usage(key.ID, time.Now()); u.Requests != 1 {
+		t.Errorf("Usage before Flush = %+v, want the counts in memory", u)
+	}
+	if err := ks.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// The CLI reads what the server flushed
+	other, err := OpenKeyStore(dir)
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	if u := other.Usage(key.ID, time.Now()); u.Requests != 1 || u.Tokens() != 7 {
+		t.Errorf("Usage after Flush = %+v", u)
+	}
+}
+
+func TestServerVirtualKeys(t *testing.T) {
+	ks, err := OpenKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, secret, err := ks.Create("team", 1, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	resp := &providers.LLMResponse{
+		Content: "hi",
+		Usage:   &providers.UsageInfo{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10},
+	}
+	server := NewServer(testConfig(), func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return &fakeProvider{name: providerName, response: resp}, nil
+	})
+	server.SetKeyStore(ks)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`
+	if resp := post(t, srv.URL, "", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no key: status %d, want 401", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL, secret, body); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL, secret, body); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("over limit: status %d, want 429", resp.StatusCode)
+	}
+	if u := ks.Usage(key.ID, time.Now()); u.Requests != 1 || u.PromptTokens != 7 || u.CompletionTokens != 3 {
+		t.Errorf("Usage = %+v", u)
+	}
+}
+
+// failingStreamProvider streams some text, then fails before reporting usage.
+type failingStreamProvider struct {
+	fakeProvider
+}
+
+func (p *failingStreamProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (<-chan providers.StreamEvent, error) {
+	events := make(chan providers.StreamEvent, 2)
+	events <- providers.StreamEvent{Type: providers.StreamEventText, Text: strings.Repeat("word ", 60)}
+	events <- providers.StreamEvent{Type: providers.StreamEventError, Err: errors.New("connection reset")}
+	close(events)
+	return events, nil
+}
+
+func TestServerVirtualKeysFailedStream(t *testing.T) {
+	ks, err := OpenKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, secret, err := ks.Create("team", 0, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	server := NewServer(testConfig(), func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return &failingStreamProvider{}, nil
+	})
+	server.SetKeyStore(ks)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+
+	prompt := strings.Repeat("hello ", 50)
+	resp := post(t, srv.URL, secret, `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"`+prompt+`"}]}`)
+	io.Copy(io.Discard, resp.Body)
+	resp = postMessages(t, srv.URL, secret, `{"model":"gpt-4o","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"`+prompt+`"}]}`)
+	io.Copy(io.Discard, resp.Body)
+
+	// Both failed streams count, with their usage estimated
+	if u := ks.Usage(key.ID, time.Now()); u.Requests != 2 || u.PromptTokens != 200 || u.CompletionTokens != 200 {
+		t.Errorf("Usage = %+v, want 2 requests with estimated tokens", u)
+	}
+}
@@ -0,0 +1,190 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func writeAnthropicError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, anthropicErrorBody(code, message))
+}
+
+// decodeMessagesRequest reads a Messages API request, writing the error
+// response itself when the request is invalid.
+func (s *Server) decodeMessagesRequest(w http.ResponseWriter, r *http.Request) (*messagesRequest, bool) {
+	var req messagesRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid request body: %v", err))
+		return nil, false
+	}
+	if req.Model == "" {
+		req.Model = s.config().Agents.Defaults.Model
+	}
+	for _, header := range r.Header.Values("anthropic-beta") {
+		req.Betas = append(req.Betas, strings.Split(header, ",")...)
+	}
+	if len(req.Messages) == 0 {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "messages is required")
+		return nil, false
+	}
+	return &req, true
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeMessagesRequest(w, r)
+	if !ok {
+		return
+	}
+	messages, err := req.messages()
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	provider, upstream, err := s.route(req.Model)
+	if err != nil {
+		writeAnthropicError(w, http.StatusNotFound, "not_found_error", fmt.Sprintf("No provider for model %s: %v", req.Model, err))
+		return
+	}
+
+	id := "msg_" + randomID()
+	logger.DebugCF("serve", "Messages request",
+		map[string]interface{}{
+			"model":    req.Model,
+			"upstream": upstream,
+			"stream":   req.Stream,
+			"messages": len(messages),
+		})
+
+	if req.Stream {
+		s.streamMessages(r.Context(), w, req, provider, upstream, messages, id)
+		return
+	}
+
+	resp, err := provider.Chat(r.Context(), messages, req.tools(), upstream, req.options())
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	s.record(r.Context(), resp.Usage)
+	writeJSON(w, http.StatusOK, message(id, req.Model, resp))
+}
+
+func (s *Server) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeMessagesRequest(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"input_tokens": req.estimateTokens()})
+}
+
+// streamMessages relays a provider stream as Messages API events. Blocks
+// are sent one after another: text, then each tool call as it arrives.
+func (s *Server) streamMessages(ctx context.Context, w http.ResponseWriter, req *messagesRequest, provider providers.LLMProvider,
+	upstream string, messages []providers.Message, id string) {
+	events, err := providers.ChatStream(ctx, provider, messages, req.tools(), upstream, req.options())
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	usage := &streamUsage{messages: messages}
+	defer func() { s.record(ctx, usage.usage()) }()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	send := func(event string, data map[string]interface{}) {
+		data["type"] = event
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send("message_start", map[string]interface{}{
+		"message": messagesResponse{
+			ID:      id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   req.Model,
+			Content: []contentBlock{},
+		},
+	})
+
+	// open is the index of the block being streamed, or -1
+	open, next := -1, 0
+	start := func(block contentBlock) int {
+		if open >= 0 {
+			send("content_block_stop", map[string]interface{}{"index": open})
+		}
+		open = next
+		next++
+		send("content_block_start", map[string]interface{}{"index": open, "content_block": block})
+		return open
+	}
+	inputDelta := func(index int, partial string) {
+		send("content_block_delta", map[string]interface{}{
+			"index": index,
+			"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": partial},
+		})
+	}
+
+	textOpen := false
+	toolBlock := map[int]int{}
+	sentArguments := map[int]bool{}
+	for ev := range events {
+		usage.add(ev)
+		switch ev.Type {
+		case providers.StreamEventText:
+			if !textOpen {
+				start(textBlock(""))
+				textOpen = true
+			}
+			send("content_block_delta", map[string]interface{}{
+				"index": open,
+				"delta": map[string]interface{}{"type": "text_delta", "text": ev.Text},
+			})
+		case providers.StreamEventToolCallStart:
+			tc := ev.ToolCall
+			toolBlock[tc.Index] = start(toolUseBlock(tc.ID, tc.Name, json.RawMessage("{}")))
+			textOpen = false
+		case providers.StreamEventToolCallDelta:
+			sentArguments[ev.ToolCall.Index] = true
+			inputDelta(toolBlock[ev.ToolCall.Index], ev.ToolCall.ArgumentsDelta)
+		case providers.StreamEventToolCallDone:
+			index := toolBlock[ev.ToolCall.Index]
+			if !sentArguments[ev.ToolCall.Index] {
+				inputDelta(index, argumentsJSON(ev.ToolCall.Arguments))
+			}
+			if index == open {
+				send("content_block_stop", map[string]interface{}{"index": open})
+				open = -1
+			}
+		case providers.StreamEventError:
+			// Headers are sent, so the error goes in the stream
+			send("error", anthropicErrorBody("api_error", ev.Err.Error()))
+			return
+		case providers.StreamEventDone:
+			if open >= 0 {
+				send("content_block_stop", map[string]interface{}{"index": open})
+				open = -1
+			}
+			usage := toAnthropicUsage(ev.Response.Usage)
+			send("message_delta", map[string]interface{}{
+				"delta": map[string]interface{}{"stop_reason": stopReason(ev.Response), "stop_sequence": stopSequence(ev.Response)},
+				"usage": map[string]int{"output_tokens": usage.OutputTokens},
+			})
+			send("message_stop", map[string]interface{}{})
+		}
+	}
+}
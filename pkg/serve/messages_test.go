@@ -0,0 +1,150 @@
+package serve
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func postMessages(t *testing.T, url, apiKey, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if apiKey != "" {
+		req.Header.Set("x-api-key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestMessages(t *testing.T) {
+	cfg := testConfig()
+	cfg.Serve.APIKeys = []string{"secret"}
+	cfg.Serve.Models = map[string]config.ServeModelConfig{"claude-sonnet-4-5": {Provider: "openai"}}
+	created := map[string]*fakeProvider{}
+	srv := newTestServer(t, cfg, created, &providers.LLMResponse{
+		Content:   "Let me check.",
+		ToolCalls: []providers.ToolCall{{ID: "toolu_2", Name: "get_weather", Arguments: map[string]interface{}{"city": "Oslo"}}},
+		Usage:     &providers.UsageInfo{PromptTokens: 12, CompletionTokens: 4},
+	})
+
+	body := `{
+		"model": "claude-sonnet-4-5",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"tools": [
+			{"name": "get_weather", "input_schema": {"type": "object"}},
+			{"type": "web_search_20250305", "name": "web_search"}
+		],
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "..."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "Sunny"}]},
+				{"type": "text", "text": "And Oslo?"}
+			]}
+		]
+	}`
+	if resp := postMessages(t, srv.URL, "wrong", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong key: status = %d, want 401", resp.StatusCode)
+	}
+
+	resp := postMessages(t, srv.URL, "secret", body)
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, data)
+	}
+	var got messagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Type != "message" || got.Model != "claude-sonnet-4-5" || *got.StopReason != "tool_use" {
+		t.Errorf("response = %+v", got)
+	}
+	if len(got.Content) != 2 || *got.Content[0].Text != "Let me check." ||
+		got.Content[1].Name != "get_weather" || string(got.Content[1].Input) != `{"city":"Oslo"}` {
+		t.Errorf("content = %+v", got.Content)
+	}
+	if got.Usage.InputTokens != 12 || got.Usage.OutputTokens != 4 {
+		t.Errorf("usage = %+v", got.Usage)
+	}
+
+	p := created["openai"]
+	var roles []string
+	for _, m := range p.messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool,user" {
+		t.Fatalf("roles = %v", roles)
+	}
+	if tc := p.messages[2].ToolCalls[0]; tc.ID != "toolu_1" || tc.Arguments["city"] != "Paris" {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if m := p.messages[3]; m.ToolCallID != "toolu_1" || m.Content != "Sunny" {
+		t.Errorf("tool result = %+v", m)
+	}
+	if p.options["max_tokens"] != 1024 {
+		t.Errorf("options = %v", p.options)
+	}
+}
+
+func TestMessages_Stream(t *testing.T) {
+	srv := newTestServer(t, testConfig(), map[string]*fakeProvider{}, &providers.LLMResponse{
+		Content:   "Checking.",
+		ToolCalls: []providers.ToolCall{{ID: "toolu_1", Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}},
+		Usage:     &providers.UsageInfo{CompletionTokens: 9},
+	})
+
+	resp := postMessages(t, srv.URL, "", `{"max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	data, _ := io.ReadAll(resp.Body)
+
+	var types []string
+	var text, input, reason string
+	for _, line := range strings.Split(string(data), "\n") {
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			Type  string `json:"type"`
+			Index int    `json:"index"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			t.Fatalf("event %q: %v", payload, err)
+		}
+		types = append(types, ev.Type)
+		text += ev.Delta.Text
+		input += ev.Delta.PartialJSON
+		if ev.Delta.StopReason != "" {
+			reason = ev.Delta.StopReason
+		}
+	}
+
+	want := "message_start,content_block_start,content_block_delta,content_block_stop," +
+		"content_block_start,content_block_delta,content_block_stop,message_delta,message_stop"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	if text != "Checking." || input != `{"city":"Paris"}` || reason != "tool_use" {
+		t.Errorf("text = %q, input = %q, stop_reason = %q", text, input, reason)
+	}
+}
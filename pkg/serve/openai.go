@@ -0,0 +1,269 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// chatRequest is the subset of an OpenAI chat completions request that is
+// passed on to providers.
+type chatRequest struct {
+	Model               string                     `json:"model"`
+	Messages            []chatMessage              `json:"messages"`
+	Tools               []providers.ToolDefinition `json:"tools,omitempty"`
+	Stream              bool                       `json:"stream"`
+	StreamOptions       *streamOptions             `json:"stream_options,omitempty"`
+	MaxTokens           *int                       `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                       `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64                   `json:"temperature,omitempty"`
+	TopP                *float64                   `json:"top_p,omitempty"`
+	Stop                json.RawMessage            `json:"stop,omitempty"`
+	ResponseFormat      *responseFormat            `json:"response_format,omitempty"`
+}
+
+// responseFormat requests structured output. Only json_schema is acted on:
+// replies are checked against the schema and the model is asked again when
+// they do not match.
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string                 `json:"name"`
+		Schema map[string]interface{} `json:"schema"`
+	} `json:"json_schema,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"`
+	ToolCalls  []wireToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+}
+
+type wireToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// messages converts the request's messages. Content given as an array of
+// parts is flattened to its text parts.
+func (r *chatRequest) messages() ([]providers.Message, error) {
+	out := make([]providers.Message, 0, len(r.Messages))
+	for i, m := range r.Messages {
+		content, err := messageText(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d].content: %w", i, err)
+		}
+		msg := providers.Message{Role: m.Role, Content: content, ToolCallID: m.ToolCallID, Name: m.Name}
+		if m.Role == "developer" {
+			msg.Role = "system"
+		}
+		for _, tc := range m.ToolCalls {
+			arguments := map[string]interface{}{}
+			if tc.Function.Arguments != "" {
+				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
+					return nil, fmt.Errorf("messages[%d].tool_calls: arguments are not a JSON object", i)
+				}
+			}
+			msg.ToolCalls = append(msg.ToolCalls, providers.ToolCall{
+				ID:        tc.ID,
+				Type:      "function",
+				Name:      tc.Function.Name,
+				Arguments: arguments,
+				Function:  &providers.FunctionCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments},
+			})
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+func messageText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("want a string or an array of content parts")
+	}
+	var texts []string
+	for _, p := range parts {
+		switch p.Type {
+		case "text", "input_text":
+			texts = append(texts, p.Text)
+		default:
+			return "", fmt.Errorf("content parts of type %q are not supported", p.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// options converts the sampling parameters to provider options.
+func (r *chatRequest) options() map[string]interface{} {
+	options := map[string]interface{}{}
+	if r.MaxCompletionTokens != nil {
+		options["max_tokens"] = *r.MaxCompletionTokens
+	} else if r.MaxTokens != nil {
+		options["max_tokens"] = *r.MaxTokens
+	}
+	if r.Temperature != nil {
+		options["temperature"] = *r.Temperature
+	}
+	if r.TopP != nil {
+		options["top_p"] = *r.TopP
+	}
+	if stop := stopSequences(r.Stop); len(stop) > 0 {
+		options[providers.OptionStop] = stop
+	}
+	if schema := r.responseSchema(); schema != nil {
+		options[providers.OptionResponseSchema] = schema
+	}
+	return options
+}
+
+// responseSchema returns the schema of a json_schema response format.
+func (r *chatRequest) responseSchema() map[string]interface{} {
+	if r.ResponseFormat == nil || r.ResponseFormat.Type != "json_schema" || r.ResponseFormat.JSONSchema == nil {
+		return nil
+	}
+	return r.ResponseFormat.JSONSchema.Schema
+}
+
+// stopSequences reads stop, which OpenAI accepts as a string or an array of
+// strings.
+func stopSequences(raw json.RawMessage) []string {
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		if one == "" {
+			return nil
+		}
+		return []string{one}
+	}
+	var many []string
+	_ = json.Unmarshal(raw, &many)
+	return many
+}
+
+type chatResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *wireUsage   `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int          `json:"index"`
+	Message      *wireMessage `json:"message,omitempty"`
+	Delta        *wireMessage `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+type wireMessage struct {
+	Role      string         `json:"role,omitempty"`
+	Content   *string        `json:"content,omitempty"`
+	ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
+}
+
+type wireUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func toWireUsage(u *providers.UsageInfo) *wireUsage {
+	if u == nil {
+		return nil
+	}
+	return &wireUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+}
+
+// finishReason reports "tool_calls" whenever the response calls tools, as
+// OpenAI clients expect. OpenAI has no finish reason for refusals or
+// errors, so those are reported as content_filter and stop.
+func finishReason(resp *providers.LLMResponse) string {
+	if len(resp.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	switch resp.FinishReason {
+	case providers.FinishReasonLength, providers.FinishReasonContentFilter:
+		return string(resp.FinishReason)
+	case providers.FinishReasonRefusal:
+		return string(providers.FinishReasonContentFilter)
+	default:
+		return "stop"
+	}
+}
+
+func toolCallName(tc providers.ToolCall) string {
+	if tc.Name == "" && tc.Function != nil {
+		return tc.Function.Name
+	}
+	return tc.Name
+}
+
+func toolCallArguments(tc providers.ToolCall) string {
+	if tc.Arguments == nil && tc.Function != nil && tc.Function.Arguments != "" {
+		return tc.Function.Arguments
+	}
+	return argumentsJSON(tc.Arguments)
+}
+
+func argumentsJSON(arguments map[string]interface{}) string {
+	if arguments == nil {
+		return "{}"
+	}
+	data, _ := json.Marshal(arguments)
+	return string(data)
+}
+
+func newWireToolCall(index int, id, name, arguments string) wireToolCall {
+	tc := wireToolCall{Index: &index, ID: id}
+	if id != "" {
+		tc.Type = "function"
+	}
+	tc.Function.Name = name
+	tc.Function.Arguments = arguments
+	return tc
+}
+
+// completion renders a whole response.
+func completion(id string, created int64, model string, resp *providers.LLMResponse) chatResponse {
+	content := resp.Content
+	msg := &wireMessage{Role: "assistant", Content: &content}
+	for _, tc := range resp.ToolCalls {
+		call := newWireToolCall(0, tc.ID, toolCallName(tc), toolCallArguments(tc))
+		call.Index = nil
+		call.Type = "function"
+		msg.ToolCalls = append(msg.ToolCalls, call)
+	}
+	reason := finishReason(resp)
+	return chatResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: []chatChoice{{Message: msg, FinishReason: &reason}},
+		Usage:   toWireUsage(resp.Usage),
+	}
+}
@@ -0,0 +1,213 @@
+// The picoclaw gRPC API lets other services use picoclaw as a sidecar: chat
+// with any configured provider, stream responses, and run the agent with its
+// tools while answering tool approvals over the same stream.
+//
+// The Go code in pkg/serve/picoclawv1 is generated with "make proto".
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: proto/picoclaw/v1/picoclaw.proto
+
+package picoclawv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PicoClaw_Chat_FullMethodName       = "/picoclaw.v1.PicoClaw/Chat"
+	PicoClaw_ChatStream_FullMethodName = "/picoclaw.v1.PicoClaw/ChatStream"
+	PicoClaw_RunAgent_FullMethodName   = "/picoclaw.v1.PicoClaw/RunAgent"
+)
+
+// PicoClawClient is the client API for PicoClaw service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PicoClawClient interface {
+	// Chat sends one request to the provider serving model.
+	Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error)
+	// ChatStream sends one request and streams the response as it arrives.
+	ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatEvent], error)
+	// RunAgent runs the agent loop. The first request must be a start; after
+	// that the client answers each ApprovalRequest with an ApprovalResponse.
+	// Cancelling the call stops the run; once the client closes its side,
+	// tool calls that need approval are denied.
+	RunAgent(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RunAgentRequest, RunAgentEvent], error)
+}
+
+type picoClawClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPicoClawClient(cc grpc.ClientConnInterface) PicoClawClient {
+	return &picoClawClient{cc}
+}
+
+func (c *picoClawClient) Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, PicoClaw_Chat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *picoClawClient) ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PicoClaw_ServiceDesc.Streams[0], PicoClaw_ChatStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChatRequest, ChatEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PicoClaw_ChatStreamClient = grpc.ServerStreamingClient[ChatEvent]
+
+func (c *picoClawClient) RunAgent(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RunAgentRequest, RunAgentEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PicoClaw_ServiceDesc.Streams[1], PicoClaw_RunAgent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunAgentRequest, RunAgentEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PicoClaw_RunAgentClient = grpc.BidiStreamingClient[RunAgentRequest, RunAgentEvent]
+
+// PicoClawServer is the server API for PicoClaw service.
+// All implementations must embed UnimplementedPicoClawServer
+// for forward compatibility.
+type PicoClawServer interface {
+	// Chat sends one request to the provider serving model.
+	Chat(context.Context, *ChatRequest) (*ChatResponse, error)
+	// ChatStream sends one request and streams the response as it arrives.
+	ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatEvent]) error
+	// RunAgent runs the agent loop. The first request must be a start; after
+	// that the client answers each ApprovalRequest with an ApprovalResponse.
+	// Cancelling the call stops the run; once the client closes its side,
+	// tool calls that need approval are denied.
+	RunAgent(grpc.BidiStreamingServer[RunAgentRequest, RunAgentEvent]) error
+	mustEmbedUnimplementedPicoClawServer()
+}
+
+// UnimplementedPicoClawServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPicoClawServer struct{}
+
+func (UnimplementedPicoClawServer) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedPicoClawServer) ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatStream not implemented")
+}
+func (UnimplementedPicoClawServer) RunAgent(grpc.BidiStreamingServer[RunAgentRequest, RunAgentEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method RunAgent not implemented")
+}
+func (UnimplementedPicoClawServer) mustEmbedUnimplementedPicoClawServer() {}
+func (UnimplementedPicoClawServer) testEmbeddedByValue()                  {}
+
+// UnsafePicoClawServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PicoClawServer will
+// result in compilation errors.
+type UnsafePicoClawServer interface {
+	mustEmbedUnimplementedPicoClawServer()
+}
+
+func RegisterPicoClawServer(s grpc.ServiceRegistrar, srv PicoClawServer) {
+	// If the following call pancis, it indicates UnimplementedPicoClawServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PicoClaw_ServiceDesc, srv)
+}
+
+func _PicoClaw_Chat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PicoClawServer).Chat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PicoClaw_Chat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PicoClawServer).Chat(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PicoClaw_ChatStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChatRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PicoClawServer).ChatStream(m, &grpc.GenericServerStream[ChatRequest, ChatEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PicoClaw_ChatStreamServer = grpc.ServerStreamingServer[ChatEvent]
+
+func _PicoClaw_RunAgent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PicoClawServer).RunAgent(&grpc.GenericServerStream[RunAgentRequest, RunAgentEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PicoClaw_RunAgentServer = grpc.BidiStreamingServer[RunAgentRequest, RunAgentEvent]
+
+// PicoClaw_ServiceDesc is the grpc.ServiceDesc for PicoClaw service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PicoClaw_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "picoclaw.v1.PicoClaw",
+	HandlerType: (*PicoClawServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Chat",
+			Handler:    _PicoClaw_Chat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ChatStream",
+			Handler:       _PicoClaw_ChatStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RunAgent",
+			Handler:       _PicoClaw_RunAgent_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/picoclaw/v1/picoclaw.proto",
+}
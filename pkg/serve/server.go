@@ -0,0 +1,508 @@
+// Package serve exposes the configured providers through OpenAI- and
+// Anthropic-compatible HTTP APIs, so tools that only speak one of those APIs
+// can use any provider picoclaw can authenticate to.
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// maxRequestBody bounds the size of a request body.
+const maxRequestBody = 32 << 20
+
+// ProviderFactory creates the provider for a provider name and model, such
+// as providers.CreateProviderFor.
+type ProviderFactory func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error)
+
+// Server handles the OpenAI- and Anthropic-compatible endpoints and the
+// gRPC API.
+type Server struct {
+	cfg     atomic.Pointer[config.Config]
+	factory ProviderFactory
+
+	keys       *KeyStore
+	agentTools func(cfg *config.Config) *tools.ToolRegistry // nil disables RunAgent
+	health     *providers.HealthChecker
+
+	mu        sync.Mutex
+	providers map[string]providers.LLMProvider // by provider name and upstream model
+}
+
+// NewServer routes requests to providers created by factory from cfg.
+func NewServer(cfg *config.Config, factory ProviderFactory) *Server {
+	s := &Server{factory: factory, providers: map[string]providers.LLMProvider{}}
+	s.cfg.Store(cfg)
+	return s
+}
+
+// config returns the config in effect.
+func (s *Server) config() *config.Config {
+	return s.cfg.Load()
+}
+
+// SetConfig puts cfg into effect, such as one reloaded after its file
+// changed. Providers are created again from it as requests need them;
+// requests already being answered finish with the old ones.
+func (s *Server) SetConfig(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg.Store(cfg)
+	s.providers = map[string]providers.LLMProvider{}
+}
+
+// SetHealthChecker reports the provider probes of h on /healthz.
+func (s *Server) SetHealthChecker(h *providers.HealthChecker) {
+	s.health = h
+}
+
+// SetKeyStore accepts the virtual keys in ks alongside the configured API
+// keys, enforcing their limits and recording their usage.
+func (s *Server) SetKeyStore(ks *KeyStore) {
+	s.keys = ks
+}
+
+type apiKeyContextKey struct{}
+
+// requestKey returns the virtual key the request authenticated with, if any.
+func requestKey(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+// record attributes usage to the virtual key of the request.
+func (s *Server) record(ctx context.Context, usage *providers.UsageInfo) {
+	key := requestKey(ctx)
+	if key == nil || s.keys == nil {
+		return
+	}
+	s.keys.Record(key, usage)
+}
+
+// streamUsage follows a streamed reply, so its usage is recorded however
+// the stream ends. A stream that fails or is abandoned before the provider
+// reports usage is estimated from the prompt and the output relayed so far.
+type streamUsage struct {
+	messages []providers.Message
+	output   int // characters of text and tool arguments relayed
+	done     bool
+	final    *providers.UsageInfo
+}
+
+func (u *streamUsage) add(ev providers.StreamEvent) {
+	switch ev.Type {
+	case providers.StreamEventText:
+		u.output += utf8.RuneCountInString(ev.Text)
+	case providers.StreamEventToolCallDelta:
+		u.output += utf8.RuneCountInString(ev.ToolCall.ArgumentsDelta)
+	case providers.StreamEventDone:
+		u.done = true
+		u.final = ev.Response.Usage
+	}
+}
+
+func (u *streamUsage) usage() *providers.UsageInfo {
+	if u.done {
+		return u.final
+	}
+	usage := &providers.UsageInfo{PromptTokens: providers.EstimatePromptTokens(u.messages), CompletionTokens: u.output / 3}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	api.HandleFunc("GET /v1/models", s.handleModels)
+	api.HandleFunc("POST /v1/messages", s.handleMessages)
+	api.HandleFunc("POST /v1/messages/count_tokens", s.handleCountTokens)
+	api.HandleFunc("GET /v1/ws", s.handleWebSocket)
+
+	// Orchestrators probe /healthz without a key
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.Handle("/", s.authenticate(api))
+	return mux
+}
+
+// authError is why a request was refused, in the terms of each API.
+type authError struct {
+	status        int
+	code          string // OpenAI error code
+	anthropicCode string
+	message       string
+}
+
+var errInvalidKey = &authError{http.StatusUnauthorized, "invalid_api_key", "authentication_error", "Invalid API key"}
+
+// authorize checks token against the configured API keys and the virtual
+// keys. Requests with a virtual key are counted against its limits when
+// counted is set; the key is returned so usage can be recorded.
+func (s *Server) authorize(token string, counted bool) (*APIKey, *authError) {
+	if len(s.config().Serve.APIKeys) == 0 && (s.keys == nil || s.keys.Len() == 0) {
+		return nil, nil
+	}
+	for _, key := range s.config().Serve.APIKeys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return nil, nil
+		}
+	}
+	if s.keys == nil || token == "" {
+		return nil, errInvalidKey
+	}
+	key, ok := s.keys.Lookup(token)
+	if !ok {
+		return nil, errInvalidKey
+	}
+	if !counted {
+		return key, nil
+	}
+	switch err := s.keys.Allow(key); err {
+	case nil:
+		return key, nil
+	case ErrRateLimited:
+		return nil, &authError{http.StatusTooManyRequests, "rate_limit_exceeded", "rate_limit_error",
+			fmt.Sprintf("Key %s is limited to %d requests per minute", key.Name, key.RequestsPerMinute)}
+	default:
+		return nil, &authError{http.StatusTooManyRequests, "insufficient_quota", "rate_limit_error",
+			fmt.Sprintf("Key %s has used its %d tokens for this month", key.Name, key.MonthlyTokens)}
+	}
+}
+
+// priorityHeader marks a request "batch", so it waits behind interactive
+// requests when a provider's max_concurrent is reached.
+const priorityHeader = "X-Picoclaw-Priority"
+
+// authenticate rejects requests without one of the configured API keys or
+// an active virtual key, sent as a bearer token or, as Anthropic clients do,
+// in x-api-key. Browsers cannot set headers on WebSockets, so /v1/ws also
+// takes the key in the api_key query parameter.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.Header.Get("X-Api-Key")
+		}
+		if token == "" && r.URL.Path == "/v1/ws" {
+			token = r.URL.Query().Get("api_key")
+		}
+		// WebSocket chats count each response instead of the connection
+		counted := r.URL.Path != "/v1/models" && r.URL.Path != "/v1/messages/count_tokens" && r.URL.Path != "/v1/ws"
+		key, authErr := s.authorize(token, counted)
+		if authErr != nil {
+			if authErr.status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "60")
+			}
+			if strings.HasPrefix(r.URL.Path, "/v1/messages") {
+				writeAnthropicError(w, authErr.status, authErr.anthropicCode, authErr.message)
+			} else {
+				writeError(w, authErr.status, authErr.code, authErr.message)
+			}
+			return
+		}
+		if key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key))
+		}
+		if header := r.Header.Get(priorityHeader); header != "" {
+			priority, err := providers.ParsePriority(header)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+				return
+			}
+			r = r.WithContext(providers.WithPriority(r.Context(), priority))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Warmup gets the provider of the default model ready for the first
+// request; see providers.Warmup.
+func (s *Server) Warmup(ctx context.Context, connect bool) error {
+	provider, _, err := s.route(s.config().Agents.Defaults.Model)
+	if err != nil {
+		return err
+	}
+	return providers.Warmup(ctx, provider, connect)
+}
+
+// route resolves a requested model to its provider and upstream model name.
+// Only the names /v1/models lists are served, so clients cannot fill the
+// provider cache with names of their own.
+func (s *Server) route(model string) (providers.LLMProvider, string, error) {
+	cfg := s.config()
+	var providerName, upstream string
+	if m, ok := cfg.Serve.Models[model]; ok {
+		providerName, upstream = m.Provider, model
+		if m.Model != "" {
+			upstream = m.Model
+		}
+	} else if _, ok := cfg.ModelAliases[model]; ok || model == cfg.Agents.Defaults.Model {
+		providerName, upstream = cfg.ResolveModel(cfg.Agents.Defaults.Provider, model)
+	} else {
+		return nil, "", errors.New("not listed in serve.models or model_aliases")
+	}
+
+	key := providerName + "\x00" + upstream
+	s.mu.Lock()
+	p, ok := s.providers[key]
+	s.mu.Unlock()
+	if ok {
+		return p, upstream, nil
+	}
+
+	// Creating a provider may read credentials or start a process, so it
+	// is done unlocked; a request that raced this one keeps its provider
+	p, err := s.factory(cfg, providerName, upstream)
+	if err != nil {
+		return nil, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.providers[key]; ok {
+		return existing, upstream, nil
+	}
+	// Providers of a config replaced meanwhile serve this request only
+	if s.config() == cfg {
+		s.providers[key] = p
+	}
+	return p, upstream, nil
+}
+
+// handleHealth answers 200 while every provider passed its latest probe and
+// 503 otherwise, listing the probes.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.health == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+		return
+	}
+	status, code := "ok", http.StatusOK
+	if !s.health.Healthy() {
+		status, code = "degraded", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "providers": s.health.Results()})
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	seen := map[string]bool{}
+	for name := range s.config().Serve.Models {
+		seen[name] = true
+	}
+	for alias := range s.config().ModelAliases {
+		seen[alias] = true
+	}
+	if def := s.config().Agents.Defaults.Model; def != "" {
+		seen[def] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	}
+	data := make([]model, 0, len(names))
+	for _, name := range names {
+		owner := s.config().Agents.Defaults.Provider
+		if m, ok := s.config().Serve.Models[name]; ok {
+			owner = m.Provider
+		} else {
+			owner, _ = s.config().ResolveModel(owner, name)
+		}
+		if owner == "" {
+			owner = "picoclaw"
+		}
+		data = append(data, model{ID: name, Object: "model", OwnedBy: owner})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": data})
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.config().Agents.Defaults.Model
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages is required")
+		return
+	}
+	messages, err := req.messages()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	provider, upstream, err := s.route(req.Model)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "model_not_found", fmt.Sprintf("No provider for model %s: %v", req.Model, err))
+		return
+	}
+
+	id := "chatcmpl-" + randomID()
+	created := time.Now().Unix()
+	logger.DebugCF("serve", "Chat completion",
+		map[string]interface{}{
+			"model":    req.Model,
+			"upstream": upstream,
+			"stream":   req.Stream,
+			"messages": len(messages),
+		})
+
+	if req.Stream {
+		if req.responseSchema() != nil {
+			// Replies are checked as a whole, so they cannot be streamed
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "response_format json_schema is not supported with stream")
+			return
+		}
+		s.stream(r.Context(), w, &req, provider, upstream, messages, id, created)
+		return
+	}
+
+	resp, err := providers.ChatStructured(r.Context(), provider, messages, req.Tools, upstream, req.options())
+	var invalid *providers.SchemaValidationError
+	if errors.As(err, &invalid) {
+		s.record(r.Context(), resp.Usage)
+		writeError(w, http.StatusUnprocessableEntity, "invalid_response", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+	s.record(r.Context(), resp.Usage)
+	writeJSON(w, http.StatusOK, completion(id, created, req.Model, resp))
+}
+
+// stream relays a provider stream as chat.completion.chunk events.
+func (s *Server) stream(ctx context.Context, w http.ResponseWriter, req *chatRequest, provider providers.LLMProvider,
+	upstream string, messages []providers.Message, id string, created int64) {
+	events, err := providers.ChatStream(ctx, provider, messages, req.Tools, upstream, req.options())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+
+	usage := &streamUsage{messages: messages}
+	defer func() { s.record(ctx, usage.usage()) }()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	send := func(choice *chatChoice, usage *wireUsage) {
+		chunk := chatResponse{ID: id, Object: "chat.completion.chunk", Created: created, Model: req.Model, Choices: []chatChoice{}, Usage: usage}
+		if choice != nil {
+			chunk.Choices = append(chunk.Choices, *choice)
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(m wireMessage) {
+		send(&chatChoice{Delta: &m}, nil)
+	}
+
+	empty := ""
+	delta(wireMessage{Role: "assistant", Content: &empty})
+
+	// Provider indexes may count other content blocks; clients expect tool
+	// calls numbered from zero. Providers that do not stream arguments only
+	// report them on done.
+	toolIndex := map[int]int{}
+	sentArguments := map[int]bool{}
+	for ev := range events {
+		usage.add(ev)
+		switch ev.Type {
+		case providers.StreamEventText:
+			text := ev.Text
+			delta(wireMessage{Content: &text})
+		case providers.StreamEventToolCallStart:
+			tc := ev.ToolCall
+			toolIndex[tc.Index] = len(toolIndex)
+			delta(wireMessage{ToolCalls: []wireToolCall{newWireToolCall(toolIndex[tc.Index], tc.ID, tc.Name, "")}})
+		case providers.StreamEventToolCallDelta:
+			sentArguments[ev.ToolCall.Index] = true
+			delta(wireMessage{ToolCalls: []wireToolCall{newWireToolCall(toolIndex[ev.ToolCall.Index], "", "", ev.ToolCall.ArgumentsDelta)}})
+		case providers.StreamEventToolCallDone:
+			if !sentArguments[ev.ToolCall.Index] {
+				delta(wireMessage{ToolCalls: []wireToolCall{newWireToolCall(toolIndex[ev.ToolCall.Index], "", "", argumentsJSON(ev.ToolCall.Arguments))}})
+			}
+		case providers.StreamEventError:
+			// Headers are sent, so the error goes in the stream
+			data, _ := json.Marshal(errorBody("upstream_error", ev.Err.Error()))
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		case providers.StreamEventDone:
+			reason := finishReason(ev.Response)
+			send(&chatChoice{Delta: &wireMessage{}, FinishReason: &reason}, nil)
+			if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+				usage := toWireUsage(ev.Response.Usage)
+				if usage == nil {
+					usage = &wireUsage{}
+				}
+				send(nil, usage)
+			}
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func errorBody(code, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    code,
+			"code":    code,
+		},
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody(code, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,383 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type fakeProvider struct {
+	name     string
+	response *providers.LLMResponse
+
+	model    string
+	messages []providers.Message
+	options  map[string]interface{}
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.model, p.messages, p.options = model, messages, options
+	return p.response, nil
+}
+
+func (p *fakeProvider) GetDefaultModel() string {
+	return ""
+}
+
+func newTestServer(t *testing.T, cfg *config.Config, created map[string]*fakeProvider, resp *providers.LLMResponse) *httptest.Server {
+	t.Helper()
+	factory := func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		if providerName == "missing" {
+			return nil, fmt.Errorf("not configured")
+		}
+		p := &fakeProvider{name: providerName, response: resp}
+		created[providerName] = p
+		return p, nil
+	}
+	srv := httptest.NewServer(NewServer(cfg, factory).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "openai"
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	return cfg
+}
+
+func post(t *testing.T, url, key, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions(t *testing.T) {
+	created := map[string]*fakeProvider{}
+	srv := newTestServer(t, testConfig(), created, &providers.LLMResponse{
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}},
+		Usage:     &providers.UsageInfo{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	})
+
+	resp := post(t, srv.URL, "", `{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "developer", "content": "Be brief."},
+			{"role": "user", "content": [{"type": "text", "text": "Weather?"}]}
+		],
+		"max_tokens": 64,
+		"temperature": 0.2
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, body)
+	}
+
+	var got chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Object != "chat.completion" || got.Model != "gpt-4o" || !strings.HasPrefix(got.ID, "chatcmpl-") {
+		t.Errorf("response = %+v", got)
+	}
+	choice := got.Choices[0]
+	if *choice.FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q, want tool_calls", *choice.FinishReason)
+	}
+	tc := choice.Message.ToolCalls[0]
+	if tc.ID != "call_1" || tc.Function.Name != "get_weather" || tc.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if got.Usage == nil || got.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v, want total 15", got.Usage)
+	}
+
+	p := created["openai"]
+	if p.messages[0].Role != "system" || p.messages[1].Content != "Weather?" {
+		t.Errorf("messages = %+v", p.messages)
+	}
+	if p.options["max_tokens"] != 64 || p.options["temperature"] != 0.2 {
+		t.Errorf("options = %v", p.options)
+	}
+}
+
+func TestChatCompletions_Stream(t *testing.T) {
+	created := map[string]*fakeProvider{}
+	srv := newTestServer(t, testConfig(), created, &providers.LLMResponse{
+		Content:   "Checking.",
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}},
+		Usage:     &providers.UsageInfo{TotalTokens: 7},
+	})
+
+	resp := post(t, srv.URL, "", `{"messages": [{"role": "user", "content": "hi"}], "stream": true, "stream_options": {"include_usage": true}}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	var chunks []chatResponse
+	done := false
+	for _, line := range strings.Split(string(body), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if !done {
+		t.Error("stream did not end with [DONE]")
+	}
+
+	var text, args, reason string
+	var total int
+	for _, c := range chunks {
+		if c.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q, want chat.completion.chunk", c.Object)
+		}
+		if c.Usage != nil {
+			total = c.Usage.TotalTokens
+		}
+		for _, choice := range c.Choices {
+			if choice.Delta.Content != nil {
+				text += *choice.Delta.Content
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				if *tc.Index != 0 {
+					t.Errorf("tool call index = %d, want 0", *tc.Index)
+				}
+				args += tc.Function.Arguments
+			}
+			if choice.FinishReason != nil {
+				reason = *choice.FinishReason
+			}
+		}
+	}
+	if text != "Checking." || args != `{"city":"Paris"}` || reason != "tool_calls" || total != 7 {
+		t.Errorf("text = %q, args = %q, reason = %q, total = %d", text, args, reason, total)
+	}
+	if created["openai"].model != "gpt-4o" {
+		t.Errorf("model = %q, want the default gpt-4o", created["openai"].model)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	cfg := testConfig()
+	cfg.Serve.APIKeys = []string{"secret"}
+	srv := newTestServer(t, cfg, map[string]*fakeProvider{}, &providers.LLMResponse{Content: "ok"})
+
+	body := `{"messages": [{"role": "user", "content": "hi"}]}`
+	if resp := post(t, srv.URL, "", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no key: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL, "wrong", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong key: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL, "secret", body); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid key: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestModelRouting(t *testing.T) {
+	cfg := testConfig()
+	cfg.Serve.Models = map[string]config.ServeModelConfig{
+		"fast":   {Provider: "groq", Model: "llama-3.1-8b-instant"},
+		"broken": {Provider: "missing"},
+	}
+	cfg.ModelAliases = map[string]string{"deep": "anthropic/claude-opus-4-1"}
+	created := map[string]*fakeProvider{}
+	srv := newTestServer(t, cfg, created, &providers.LLMResponse{Content: "ok"})
+
+	post(t, srv.URL, "", `{"model": "fast", "messages": [{"role": "user", "content": "hi"}]}`)
+	if p := created["groq"]; p == nil || p.model != "llama-3.1-8b-instant" {
+		t.Fatalf("fast was not routed to groq/llama-3.1-8b-instant: %+v", p)
+	}
+	post(t, srv.URL, "", `{"model": "deep", "messages": [{"role": "user", "content": "hi"}]}`)
+	if p := created["anthropic"]; p == nil || p.model != "claude-opus-4-1" {
+		t.Fatalf("the deep alias was not resolved to anthropic/claude-opus-4-1: %+v", p)
+	}
+
+	if resp := post(t, srv.URL, "", `{"model": "broken", "messages": [{"role": "user", "content": "hi"}]}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("broken: status = %d, want 404", resp.StatusCode)
+	}
+	// Names that are not configured are refused, not passed to the default provider
+	if resp := post(t, srv.URL, "", `{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "hi"}]}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown model: status = %d, want 404", resp.StatusCode)
+	}
+	if p := created["openai"]; p != nil {
+		t.Errorf("a provider was created for an unknown model: %+v", p)
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("GET /v1/models: %v", err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	var ids []string
+	for _, m := range list.Data {
+		ids = append(ids, m.ID+"/"+m.OwnedBy)
+	}
+	if got := strings.Join(ids, ","); got != "broken/missing,deep/anthropic,fast/groq,gpt-4o/openai" {
+		t.Errorf("models = %s", got)
+	}
+}
+
+func TestRouteCreatesProvidersUnlocked(t *testing.T) {
+	cfg := testConfig()
+	cfg.Serve.Models = map[string]config.ServeModelConfig{"slow": {Provider: "slow"}}
+	release := make(chan struct{})
+	server := NewServer(cfg, func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		if providerName == "slow" {
+			<-release
+		}
+		return &fakeProvider{name: providerName}, nil
+	})
+
+	slow := make(chan providers.LLMProvider)
+	go func() {
+		p, _, _ := server.route("slow")
+		slow <- p
+	}()
+	// A provider that is slow to create does not hold up other models
+	if _, _, err := server.route("gpt-4o"); err != nil {
+		t.Fatalf("route(gpt-4o): %v", err)
+	}
+	close(release)
+	first := <-slow
+	if again, _, _ := server.route("slow"); again != first {
+		t.Error("the created provider was not cached")
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	created := 0
+	factory := func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		created++
+		return &fakeProvider{name: providerName, response: &providers.LLMResponse{Content: "ok"}}, nil
+	}
+	server := NewServer(testConfig(), factory)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+
+	body := `{"messages": [{"role": "user", "content": "hi"}]}`
+	post(t, srv.URL, "", body)
+	post(t, srv.URL, "", body)
+	if created != 1 {
+		t.Fatalf("created %d providers before the reload, want 1", created)
+	}
+
+	cfg := testConfig()
+	cfg.Serve.APIKeys = []string{"rotated"}
+	server.SetConfig(cfg)
+	if resp := post(t, srv.URL, "", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no key after the reload: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := post(t, srv.URL, "rotated", body); resp.StatusCode != http.StatusOK {
+		t.Errorf("new key: status = %d, want 200", resp.StatusCode)
+	}
+	if created != 2 {
+		t.Errorf("created %d providers, want the provider created again after the reload", created)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer upstream.Close()
+
+	cfg := testConfig()
+	cfg.Serve.APIKeys = []string{"secret"}
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "bad", APIBase: upstream.URL}
+	server := NewServer(cfg, nil)
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(srv.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("GET /healthz: %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Status
+	}
+
+	// Without probes, and without a key
+	if code, status := get(); code != http.StatusOK || status != "ok" {
+		t.Errorf("without checker: %d %s", code, status)
+	}
+
+	health := providers.NewHealthChecker(cfg, 0)
+	health.Check(context.Background())
+	server.SetHealthChecker(health)
+	if code, status := get(); code != http.StatusServiceUnavailable || status != "degraded" {
+		t.Errorf("with failing provider: %d %s", code, status)
+	}
+}
+
+func TestChatCompletions_ResponseSchema(t *testing.T) {
+	const body = `{
+		"messages": [{"role": "user", "content": "Ann is 31"}],
+		"response_format": {"type": "json_schema", "json_schema": {"name": "person", "schema": {
+			"type": "object", "properties": {"age": {"type": "integer"}}, "required": ["age"]
+		}}}%s
+	}`
+	created := map[string]*fakeProvider{}
+	srv := newTestServer(t, testConfig(), created, &providers.LLMResponse{Content: `{"age": 31}`})
+	resp := post(t, srv.URL, "", fmt.Sprintf(body, ""))
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, body)
+	}
+	if p := created["openai"]; !strings.Contains(p.messages[0].Content, "JSON schema") {
+		t.Errorf("messages = %+v, want the schema in a system prompt", p.messages)
+	}
+
+	srv = newTestServer(t, testConfig(), created, &providers.LLMResponse{Content: `{"age": "old"}`})
+	resp = post(t, srv.URL, "", fmt.Sprintf(body, ""))
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(string(data), "age: expected integer") {
+		t.Errorf("status = %d, body = %s; want 422 with the validation errors", resp.StatusCode, data)
+	}
+
+	resp = post(t, srv.URL, "", fmt.Sprintf(body, `, "stream": true`))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("streamed status = %d, want 400", resp.StatusCode)
+	}
+}
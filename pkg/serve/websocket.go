@@ -0,0 +1,274 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// wsRequest is a message from a WebSocket client: "chat" starts a response,
+// taking the fields of a chat completions request, and "interrupt" stops the
+// response in progress. A chat sent while a response is streaming
+// interrupts it first.
+type wsRequest struct {
+	Type string `json:"type"`
+	// ID is echoed in every event of the response; one is made up when empty.
+	ID string `json:"id,omitempty"`
+	chatRequest
+}
+
+// wsEvent is a message to a WebSocket client.
+type wsEvent struct {
+	Type         string         `json:"type"`
+	ID           string         `json:"id,omitempty"`
+	Text         string         `json:"text,omitempty"`
+	Index        *int           `json:"index,omitempty"`
+	ToolCallID   string         `json:"tool_call_id,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Arguments    string         `json:"arguments,omitempty"`
+	Content      *string        `json:"content,omitempty"`
+	ToolCalls    []wireToolCall `json:"tool_calls,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        *wireUsage     `json:"usage,omitempty"`
+	Error        *wsError       `json:"error,omitempty"`
+}
+
+type wsError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// wsConn serializes writes to a WebSocket and tracks its one response in
+// progress.
+type wsConn struct {
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // stops the response in progress
+	done   chan struct{}      // closed when the response in progress ends
+}
+
+func (c *wsConn) send(ev wsEvent) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteJSON(ev)
+}
+
+func (c *wsConn) sendError(id, code, message string) {
+	c.send(wsEvent{Type: "error", ID: id, Error: &wsError{Code: code, Message: message}})
+}
+
+// interrupt stops the response in progress, if any, and waits for it to
+// report that it was interrupted.
+func (c *wsConn) interrupt() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// checkOrigin accepts same-origin connections, clients that send no Origin
+// and the origins in serve.allowed_origins, so other sites cannot use the
+// API from their visitors' browsers.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.config().Serve.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// handleWebSocket streams chat responses over a WebSocket, for browsers that
+// want to interrupt a response without dropping the connection.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has written the error response
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxRequestBody)
+
+	c := &wsConn{conn: conn}
+	defer c.interrupt()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.sendError("", "invalid_request_error", fmt.Sprintf("Invalid message: %v", err))
+			continue
+		}
+
+		switch req.Type {
+		case "interrupt":
+			c.interrupt()
+		case "chat":
+			c.interrupt()
+			s.startWebSocketChat(r.Context(), c, &req)
+		default:
+			c.sendError(req.ID, "invalid_request_error", fmt.Sprintf("Unknown message type %q", req.Type))
+		}
+	}
+}
+
+// startWebSocketChat validates req and streams its response in the
+// background, so interruptions can be read meanwhile.
+func (s *Server) startWebSocketChat(ctx context.Context, c *wsConn, req *wsRequest) {
+	if req.ID == "" {
+		req.ID = "chatcmpl-" + randomID()
+	}
+	if req.Model == "" {
+		req.Model = s.config().Agents.Defaults.Model
+	}
+	if len(req.Messages) == 0 {
+		c.sendError(req.ID, "invalid_request_error", "messages is required")
+		return
+	}
+	messages, err := req.messages()
+	if err != nil {
+		c.sendError(req.ID, "invalid_request_error", err.Error())
+		return
+	}
+	provider, upstream, err := s.route(req.Model)
+	if err != nil {
+		c.sendError(req.ID, "model_not_found", fmt.Sprintf("No provider for model %s: %v", req.Model, err))
+		return
+	}
+	// The connection was authorized once; each response counts as a request
+	if key := requestKey(ctx); key != nil {
+		if err := s.keys.Allow(key); err != nil {
+			c.sendError(req.ID, "rate_limit_exceeded", err.Error())
+			return
+		}
+	}
+
+	logger.DebugCF("serve", "WebSocket chat",
+		map[string]interface{}{
+			"model":    req.Model,
+			"upstream": upstream,
+			"messages": len(messages),
+		})
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	c.mu.Lock()
+	c.cancel, c.done = cancel, done
+	c.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		s.streamWebSocketChat(ctx, c, req, provider, upstream, messages)
+		c.mu.Lock()
+		if c.done == done {
+			c.cancel, c.done = nil, nil
+		}
+		c.mu.Unlock()
+	}()
+}
+
+func (s *Server) streamWebSocketChat(ctx context.Context, c *wsConn, req *wsRequest, provider providers.LLMProvider,
+	upstream string, messages []providers.Message) {
+	var text strings.Builder
+	var events <-chan providers.StreamEvent
+	interrupted := func() {
+		// Drain so the provider can finish, and report what was said
+		if events != nil {
+			go func() {
+				for range events {
+				}
+			}()
+		}
+		partial := text.String()
+		c.send(wsEvent{Type: "interrupted", ID: req.ID, Content: &partial})
+	}
+
+	events, err := providers.ChatStream(ctx, provider, messages, req.Tools, upstream, req.options())
+	if err != nil {
+		if ctx.Err() != nil {
+			interrupted()
+			return
+		}
+		c.sendError(req.ID, "upstream_error", err.Error())
+		return
+	}
+	usage := &streamUsage{messages: messages}
+	defer func() { s.record(ctx, usage.usage()) }()
+
+	// Provider indexes may count other content blocks; number calls from zero
+	toolIndex := map[int]int{}
+	for {
+		select {
+		case <-ctx.Done():
+			interrupted()
+			return
+		case ev, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					interrupted()
+				}
+				return
+			}
+			usage.add(ev)
+			switch ev.Type {
+			case providers.StreamEventText:
+				text.WriteString(ev.Text)
+				c.send(wsEvent{Type: "text", ID: req.ID, Text: ev.Text})
+			case providers.StreamEventToolCallStart:
+				index := len(toolIndex)
+				toolIndex[ev.ToolCall.Index] = index
+				c.send(wsEvent{Type: "tool_call_start", ID: req.ID, Index: &index, ToolCallID: ev.ToolCall.ID, Name: ev.ToolCall.Name})
+			case providers.StreamEventToolCallDelta:
+				index := toolIndex[ev.ToolCall.Index]
+				c.send(wsEvent{Type: "tool_call_delta", ID: req.ID, Index: &index, Arguments: ev.ToolCall.ArgumentsDelta})
+			case providers.StreamEventToolCallDone:
+				index := toolIndex[ev.ToolCall.Index]
+				c.send(wsEvent{Type: "tool_call_done", ID: req.ID, Index: &index, ToolCallID: ev.ToolCall.ID,
+					Name: ev.ToolCall.Name, Arguments: argumentsJSON(ev.ToolCall.Arguments)})
+			case providers.StreamEventError:
+				if ctx.Err() != nil {
+					// Reported as interrupted
+					continue
+				}
+				c.sendError(req.ID, "upstream_error", ev.Err.Error())
+				return
+			case providers.StreamEventDone:
+				resp := completion(req.ID, 0, req.Model, ev.Response)
+				msg := resp.Choices[0].Message
+				c.send(wsEvent{
+					Type:         "done",
+					ID:           req.ID,
+					Content:      msg.Content,
+					ToolCalls:    msg.ToolCalls,
+					FinishReason: finishReason(ev.Response),
+					Usage:        resp.Usage,
+				})
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// hangingProvider streams one text delta, then waits until it is cancelled.
+type hangingProvider struct {
+	fakeProvider
+}
+
+func (p *hangingProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (<-chan providers.StreamEvent, error) {
+	events := make(chan providers.StreamEvent, 1)
+	events <- providers.StreamEvent{Type: providers.StreamEventText, Text: "Once upon"}
+	go func() {
+		defer close(events)
+		<-ctx.Done()
+		events <- providers.StreamEvent{Type: providers.StreamEventError, Err: ctx.Err()}
+	}()
+	return events, nil
+}
+
+func dialWebSocket(t *testing.T, provider providers.LLMProvider, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	server := NewServer(testConfig(), func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return provider, nil
+	})
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+func TestWebSocketChat(t *testing.T) {
+	conn, _, err := dialWebSocket(t, &fakeProvider{response: &providers.LLMResponse{
+		Content: "Hello!",
+		Usage:   &providers.UsageInfo{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":     "chat",
+		"id":       "req-1",
+		"messages": []map[string]string{{"role": "user", "content": "hi"}},
+	})
+	var text string
+	for {
+		var ev wsEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("ReadJSON: %v", err)
+		}
+		if ev.ID != "req-1" {
+			t.Errorf("event ID = %q, want req-1", ev.ID)
+		}
+		if ev.Type == "text" {
+			text += ev.Text
+			continue
+		}
+		if ev.Type != "done" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		if ev.FinishReason != "stop" || ev.Usage == nil || ev.Usage.TotalTokens != 5 {
+			t.Errorf("done = %+v", ev)
+		}
+		break
+	}
+	if text != "Hello!" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestWebSocketInterrupt(t *testing.T) {
+	conn, _, err := dialWebSocket(t, &hangingProvider{}, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":     "chat",
+		"messages": []map[string]string{{"role": "user", "content": "tell me a story"}},
+	})
+	var ev wsEvent
+	if err := conn.ReadJSON(&ev); err != nil || ev.Type != "text" {
+		t.Fatalf("first event = %+v, %v", ev, err)
+	}
+
+	conn.WriteJSON(map[string]string{"type": "interrupt"})
+	ev = wsEvent{}
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if ev.Type != "interrupted" || ev.Content == nil || *ev.Content != "Once upon" {
+		t.Errorf("event = %+v, want interrupted with the partial text", ev)
+	}
+}
+
+func TestWebSocketInterruptRecordsUsage(t *testing.T) {
+	ks, err := OpenKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenKeyStore: %v", err)
+	}
+	key, secret, err := ks.Create("team", 0, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	server := NewServer(testConfig(), func(cfg *config.Config, providerName, model string) (providers.LLMProvider, error) {
+		return &hangingProvider{}, nil
+	})
+	server.SetKeyStore(ks)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws",
+		http.Header{"Authorization": {"Bearer " + secret}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":     "chat",
+		"messages": []map[string]string{{"role": "user", "content": "tell me a long story, please"}},
+	})
+	var ev wsEvent
+	if err := conn.ReadJSON(&ev); err != nil || ev.Type != "text" {
+		t.Fatalf("first event = %+v, %v", ev, err)
+	}
+	conn.WriteJSON(map[string]string{"type": "interrupt"})
+	if err := conn.ReadJSON(&ev); err != nil || ev.Type != "interrupted" {
+		t.Fatalf("event = %+v, %v", ev, err)
+	}
+
+	// The interrupted reply is recorded once the stream has wound down
+	deadline := time.Now().Add(2 * time.Second)
+	for ks.Usage(key.ID, time.Now()).Requests == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if u := ks.Usage(key.ID, time.Now()); u.Requests != 1 || u.PromptTokens == 0 || u.CompletionTokens == 0 {
+		t.Errorf("Usage = %+v, want the interrupted request with estimated tokens", u)
+	}
+}
+
+func TestWebSocketRejectsOtherOrigins(t *testing.T) {
+	header := http.Header{"Origin": []string{"https://evil.example"}}
+	_, resp, err := dialWebSocket(t, &fakeProvider{}, header)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Dial from another origin: %v, %v", resp, err)
+	}
+}
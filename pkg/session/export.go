@@ -0,0 +1,323 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ExportOptions adjusts how a session is rendered.
+type ExportOptions struct {
+	// Title heads the transcript; empty uses the session key.
+	Title string
+	// SystemPrompt is shown before the first turn when set.
+	SystemPrompt string
+	// MaxToolOutput cuts each tool output to this many characters; 0 keeps
+	// it whole. Outputs are collapsed either way.
+	MaxToolOutput int
+}
+
+// transcript is a session regrouped into turns for rendering.
+type transcript struct {
+	Title   string
+	Key     string
+	ID      string
+	Model   string
+	Summary string
+	System  string
+	Created time.Time
+	Updated time.Time
+	Usage   providers.UsageInfo
+	Cost    string // estimated from the model catalog's prices, if known
+	Turns   []turn
+}
+
+// turn is a user message and everything the agent did to answer it.
+type turn struct {
+	Number int
+	User   string
+	Sender string // the user's name, or "User"
+	Steps  []step
+	Usage  *providers.UsageInfo
+}
+
+// step is either assistant text or one tool call with its output.
+type step struct {
+	Text string
+	Tool *toolStep
+}
+
+type toolStep struct {
+	Name      string
+	Arguments string
+	Output    string
+	Truncated bool
+	Answered  bool
+}
+
+func buildTranscript(s *Session, opts ExportOptions) transcript {
+	t := transcript{
+		Title:   opts.Title,
+		Key:     s.Key,
+		ID:      s.ID,
+		Model:   s.Model,
+		Summary: s.Summary,
+		System:  opts.SystemPrompt,
+		Created: s.Created,
+		Updated: s.Updated,
+		Usage:   s.Usage,
+	}
+	if t.Title == "" {
+		t.Title = "Session " + s.Key
+	}
+	if cost, ok := providers.Cost(s.Model, s.Usage); ok && s.Usage.TotalTokens > 0 {
+		t.Cost = fmt.Sprintf("$%.4f", cost)
+	}
+
+	usageAt := make(map[int]providers.UsageInfo, len(s.Turns))
+	for _, u := range s.Turns {
+		usageAt[u.Message] = u.Usage
+	}
+
+	calls := map[string]*toolStep{}
+	var current *turn
+	for i, msg := range s.Messages {
+		if msg.Role == "user" || current == nil {
+			t.Turns = append(t.Turns, turn{Number: len(t.Turns) + 1})
+			current = &t.Turns[len(t.Turns)-1]
+			if msg.Role == "user" {
+				current.User = msg.Content
+				current.Sender = msg.Name
+				if current.Sender == "" {
+					current.Sender = "User"
+				}
+				if u, ok := usageAt[i]; ok {
+					current.Usage = &u
+				}
+				continue
+			}
+		}
+
+		switch msg.Role {
+		case "assistant":
+			if strings.TrimSpace(msg.Content) != "" {
+				current.Steps = append(current.Steps, step{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				ts := &toolStep{Name: toolCallName(tc), Arguments: toolCallArguments(tc)}
+				calls[tc.ID] = ts
+				current.Steps = append(current.Steps, step{Tool: ts})
+			}
+		case "tool":
+			output := msg.Content
+			truncated := false
+			if opts.MaxToolOutput > 0 && len([]rune(output)) > opts.MaxToolOutput {
+				output = string([]rune(output)[:opts.MaxToolOutput])
+				truncated = true
+			}
+			if ts, ok := calls[msg.ToolCallID]; ok {
+				ts.Output, ts.Truncated, ts.Answered = output, truncated, true
+			} else {
+				current.Steps = append(current.Steps, step{Tool: &toolStep{
+					Name: "tool", Output: output, Truncated: truncated, Answered: true,
+				}})
+			}
+		}
+	}
+	return t
+}
+
+func toolCallName(tc providers.ToolCall) string {
+	if tc.Name != "" {
+		return tc.Name
+	}
+	if tc.Function != nil {
+		return tc.Function.Name
+	}
+	return "tool"
+}
+
+func toolCallArguments(tc providers.ToolCall) string {
+	if len(tc.Arguments) > 0 {
+		data, _ := json.Marshal(tc.Arguments)
+		return string(data)
+	}
+	if tc.Function != nil {
+		return tc.Function.Arguments
+	}
+	return ""
+}
+
+func formatUsage(u providers.UsageInfo) string {
+	return fmt.Sprintf("%d tokens (%d prompt, %d completion)", u.TotalTokens, u.PromptTokens, u.CompletionTokens)
+}
+
+// ExportMarkdown writes s as a Markdown transcript. Tool outputs are folded
+// into <details> blocks, which GitHub and most Markdown viewers collapse.
+func ExportMarkdown(w io.Writer, s *Session, opts ExportOptions) error {
+	t := buildTranscript(s, opts)
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# %s\n\n", t.Title)
+	if t.ID != "" {
+		fmt.Fprintf(&sb, "- **ID:** `%s`\n", t.ID)
+	}
+	if t.Model != "" {
+		fmt.Fprintf(&sb, "- **Model:** `%s`\n", t.Model)
+	}
+	if !t.Created.IsZero() {
+		fmt.Fprintf(&sb, "- **Started:** %s\n", t.Created.Format(time.RFC1123))
+	}
+	if t.Usage.TotalTokens > 0 {
+		fmt.Fprintf(&sb, "- **Usage:** %s\n", formatUsage(t.Usage))
+	}
+	if t.Cost != "" {
+		fmt.Fprintf(&sb, "- **Estimated cost:** %s\n", t.Cost)
+	}
+	sb.WriteString("\n")
+
+	if t.System != "" {
+		fmt.Fprintf(&sb, "## System\n\n%s\n\n", t.System)
+	}
+	if t.Summary != "" {
+		fmt.Fprintf(&sb, "> **Earlier conversation (summarized):** %s\n\n", strings.ReplaceAll(t.Summary, "\n", "\n> "))
+	}
+
+	for _, tr := range t.Turns {
+		fmt.Fprintf(&sb, "## Turn %d\n\n", tr.Number)
+		if tr.User != "" {
+			fmt.Fprintf(&sb, "**%s**\n\n%s\n\n", tr.Sender, tr.User)
+		}
+		for _, st := range tr.Steps {
+			if st.Tool == nil {
+				fmt.Fprintf(&sb, "**Assistant**\n\n%s\n\n", st.Text)
+				continue
+			}
+			fmt.Fprintf(&sb, "<details>\n<summary>Tool <code>%s</code>", template.HTMLEscapeString(st.Tool.Name))
+			if st.Tool.Arguments != "" {
+				fmt.Fprintf(&sb, " <code>%s</code>", template.HTMLEscapeString(st.Tool.Arguments))
+			}
+			sb.WriteString("</summary>\n\n")
+			switch {
+			case !st.Tool.Answered:
+				sb.WriteString("*No result recorded.*\n\n")
+			default:
+				fence := codeFence(st.Tool.Output)
+				fmt.Fprintf(&sb, "%s\n%s\n%s\n\n", fence, st.Tool.Output, fence)
+				if st.Tool.Truncated {
+					sb.WriteString("*Output truncated.*\n\n")
+				}
+			}
+			sb.WriteString("</details>\n\n")
+		}
+		if tr.Usage != nil {
+			fmt.Fprintf(&sb, "*%s*\n\n", formatUsage(*tr.Usage))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in s.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"usage": formatUsage,
+	"time":  func(t time.Time) string { return t.Format(time.RFC1123) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #1f2328; line-height: 1.5; }
+header dl { display: grid; grid-template-columns: max-content auto; gap: .2em 1em; color: #59636e; }
+dt { font-weight: 600; }
+dd { margin: 0; }
+section.turn { border-top: 1px solid #d1d9e0; padding-top: .5em; margin-top: 1.5em; }
+.role { font-weight: 600; margin: 1em 0 .25em; }
+.message { white-space: pre-wrap; }
+.user .message { background: #f6f8fa; border-radius: 6px; padding: .5em .75em; }
+details { border: 1px solid #d1d9e0; border-radius: 6px; margin: .5em 0; padding: .25em .75em; }
+summary { cursor: pointer; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: .9em; }
+pre { overflow-x: auto; background: #f6f8fa; padding: .75em; border-radius: 6px; }
+.usage, .note { color: #59636e; font-size: .9em; }
+blockquote { color: #59636e; border-left: 3px solid #d1d9e0; margin: 1em 0; padding-left: 1em; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<dl>
+{{- if .ID}}<dt>ID</dt><dd><code>{{.ID}}</code></dd>{{end}}
+{{- if .Model}}<dt>Model</dt><dd><code>{{.Model}}</code></dd>{{end}}
+{{- if not .Created.IsZero}}<dt>Started</dt><dd>{{time .Created}}</dd>{{end}}
+{{- if .Usage.TotalTokens}}<dt>Usage</dt><dd>{{usage .Usage}}</dd>{{end}}
+{{- if .Cost}}<dt>Estimated cost</dt><dd>{{.Cost}}</dd>{{end}}
+</dl>
+</header>
+{{- if .System}}
+<section class="system"><div class="role">System</div><div class="message">{{.System}}</div></section>
+{{- end}}
+{{- if .Summary}}
+<blockquote><strong>Earlier conversation (summarized):</strong> {{.Summary}}</blockquote>
+{{- end}}
+{{- range .Turns}}
+<section class="turn">
+<h2>Turn {{.Number}}</h2>
+{{- if .User}}
+<div class="user"><div class="role">{{.Sender}}</div><div class="message">{{.User}}</div></div>
+{{- end}}
+{{- range .Steps}}
+{{- if .Tool}}
+<details>
+<summary>Tool <code>{{.Tool.Name}}</code>{{if .Tool.Arguments}} <code>{{.Tool.Arguments}}</code>{{end}}</summary>
+{{- if .Tool.Answered}}
+<pre>{{.Tool.Output}}</pre>
+{{- if .Tool.Truncated}}<p class="note">Output truncated.</p>{{end}}
+{{- else}}
+<p class="note">No result recorded.</p>
+{{- end}}
+</details>
+{{- else}}
+<div class="assistant"><div class="role">Assistant</div><div class="message">{{.Text}}</div></div>
+{{- end}}
+{{- end}}
+{{- if .Usage}}
+<p class="usage">{{usage .Usage}}</p>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+// ExportHTML writes s as a self-contained HTML page with tool outputs
+// collapsed.
+func ExportHTML(w io.Writer, s *Session, opts ExportOptions) error {
+	return htmlTranscript.Execute(w, buildTranscript(s, opts))
+}
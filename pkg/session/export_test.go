@@ -0,0 +1,101 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func exportFixture() *Session {
+	sm := NewSessionManager("")
+	key := "cli:default"
+	sm.AddMessage(key, "user", "What is in the workspace?")
+	sm.AddFullMessage(key, providers.Message{
+		Role:      "assistant",
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "list_dir", Arguments: map[string]interface{}{"path": "."}}},
+	})
+	sm.AddFullMessage(key, providers.Message{Role: "tool", Content: "notes.md\n```\n<script>", ToolCallID: "call_1"})
+	sm.AddUsage(key, providers.UsageInfo{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110})
+	sm.AddMessage(key, "assistant", "One file: notes.md")
+	sm.AddFullMessage(key, providers.Message{Role: "user", Content: "Thanks", Name: "alice"})
+	sm.AddUsage(key, providers.UsageInfo{PromptTokens: 150, CompletionTokens: 5, TotalTokens: 155})
+	sm.AddMessage(key, "assistant", "You're welcome")
+	sm.SetModel(key, "gpt-4o")
+	s, _ := sm.Get(key)
+	return s
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportMarkdown(&buf, exportFixture(), ExportOptions{}); err != nil {
+		t.Fatalf("ExportMarkdown() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Session cli:default",
+		"- **Model:** `gpt-4o`",
+		"- **Usage:** 265 tokens (250 prompt, 15 completion)",
+		"- **Estimated cost:** $0.0008",
+		"## Turn 1",
+		"<summary>Tool <code>list_dir</code> <code>{&#34;path&#34;:&#34;.&#34;}</code></summary>",
+		"````\nnotes.md\n```\n<script>\n````",
+		"*110 tokens (100 prompt, 10 completion)*",
+		"## Turn 2",
+		"**alice**\n\nThanks",
+		"*155 tokens (150 prompt, 5 completion)*",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "One file: notes.md") > strings.Index(out, "## Turn 2") {
+		t.Error("assistant reply rendered in the wrong turn")
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	var buf bytes.Buffer
+	opts := ExportOptions{Title: "Run report", MaxToolOutput: 8}
+	if err := ExportHTML(&buf, exportFixture(), opts); err != nil {
+		t.Fatalf("ExportHTML() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<title>Run report</title>",
+		"<details>",
+		"<pre>notes.md</pre>",
+		"Output truncated.",
+		"110 tokens (100 prompt, 10 completion)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("html missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("tool output was not escaped")
+	}
+}
+
+func TestTruncateHistory_KeepsTurnUsage(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "k"
+	sm.AddMessage(key, "user", "one")
+	sm.AddUsage(key, providers.UsageInfo{TotalTokens: 1})
+	sm.AddMessage(key, "assistant", "1")
+	sm.AddMessage(key, "user", "two")
+	sm.AddUsage(key, providers.UsageInfo{TotalTokens: 2})
+	sm.AddMessage(key, "assistant", "2")
+
+	sm.TruncateHistory(key, 2)
+	s, _ := sm.Get(key)
+	if len(s.Turns) != 1 || s.Turns[0].Message != 0 || s.Turns[0].Usage.TotalTokens != 2 {
+		t.Errorf("Turns = %+v, want the second turn at message 0", s.Turns)
+	}
+	if s.Usage.TotalTokens != 3 {
+		t.Errorf("Usage.TotalTokens = %d, want 3", s.Usage.TotalTokens)
+	}
+}
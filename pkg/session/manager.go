@@ -1,9 +1,10 @@
 package session
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,34 +12,97 @@ import (
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
+// Session is one conversation: its messages plus the model that answered
+// and the tokens it used. Key names the conversation within the agent
+// ("telegram:123456", "cli:default"); ID is a short random handle for
+// resuming it from the CLI or an embedding app.
 type Session struct {
 	Key      string              `json:"key"`
+	ID       string              `json:"id,omitempty"`
+	Model    string              `json:"model,omitempty"`
 	Messages []providers.Message `json:"messages"`
 	Summary  string              `json:"summary,omitempty"`
+	Usage    providers.UsageInfo `json:"usage"`
+	Turns    []TurnUsage         `json:"turns,omitempty"`
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
 }
 
+// TurnUsage is the token usage of one turn: the user message at index
+// Message in Messages and everything the agent did to answer it.
+type TurnUsage struct {
+	Message int                 `json:"message"`
+	Usage   providers.UsageInfo `json:"usage"`
+}
+
+func newSession(key string) *Session {
+	return &Session{
+		Key:      key,
+		ID:       newSessionID(),
+		Messages: []providers.Message{},
+		Created:  time.Now(),
+		Updated:  time.Now(),
+	}
+}
+
+// clone returns a copy of s that shares no messages with it.
+func (s *Session) clone() *Session {
+	c := *s
+	c.Messages = make([]providers.Message, len(s.Messages))
+	copy(c.Messages, s.Messages)
+	c.Turns = append([]TurnUsage(nil), s.Turns...)
+	return &c
+}
+
+// newSessionID returns 12 random hex characters.
+func newSessionID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
-	storage  string
+	store    Store // nil keeps sessions in memory only
 }
 
+// NewSessionManager keeps sessions as JSON files in the storage directory,
+// or only in memory when storage is empty.
 func NewSessionManager(storage string) *SessionManager {
+	if storage == "" {
+		return NewSessionManagerWithStore(nil)
+	}
+	return NewSessionManagerWithStore(NewJSONStore(storage))
+}
+
+// NewSessionManagerWithStore loads the sessions in store and saves to it.
+// A nil store keeps sessions in memory only.
+func NewSessionManagerWithStore(store Store) *SessionManager {
 	sm := &SessionManager{
 		sessions: make(map[string]*Session),
-		storage:  storage,
+		store:    store,
 	}
 
-	if storage != "" {
-		os.MkdirAll(storage, 0755)
+	if store != nil {
 		sm.loadSessions()
 	}
 
 	return sm
 }
 
+// OpenSessionManager loads the sessions stored in dir in the given format
+// ("json" or "jsonl"; empty selects JSON).
+func OpenSessionManager(format, dir string) (*SessionManager, error) {
+	store, err := NewStore(format, dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewSessionManagerWithStore(store), nil
+}
+
 func (sm *SessionManager) GetOrCreate(key string) *Session {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -48,12 +112,7 @@ func (sm *SessionManager) GetOrCreate(key string) *Session {
 		return session
 	}
 
-	session = &Session{
-		Key:      key,
-		Messages: []providers.Message{},
-		Created:  time.Now(),
-		Updated:  time.Now(),
-	}
+	session = newSession(key)
 	sm.sessions[key] = session
 
 	return session
@@ -68,20 +127,20 @@ func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
 
 // AddFullMessage adds a complete message with tool calls and tool call ID to the session.
 // This is used to save the full conversation flow including tool calls and tool results.
+// Messages without a CreatedAt are stamped with the current time.
 func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Message) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	session, ok := sm.sessions[sessionKey]
 	if !ok {
-		session = &Session{
-			Key:      sessionKey,
-			Messages: []providers.Message{},
-			Created:  time.Now(),
-		}
+		session = newSession(sessionKey)
 		sm.sessions[sessionKey] = session
 	}
 
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
 	session.Messages = append(session.Messages, msg)
 	session.Updated = time.Now()
 }
@@ -133,6 +192,7 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 
 	if keepLast <= 0 {
 		session.Messages = []providers.Message{}
+		session.Turns = nil
 		session.Updated = time.Now()
 		return
 	}
@@ -141,10 +201,142 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 		return
 	}
 
-	session.Messages = session.Messages[len(session.Messages)-keepLast:]
+	dropped := len(session.Messages) - keepLast
+	session.Messages = session.Messages[dropped:]
+	// Per-turn usage follows the messages it belongs to
+	turns := session.Turns[:0]
+	for _, t := range session.Turns {
+		if t.Message >= dropped {
+			t.Message -= dropped
+			turns = append(turns, t)
+		}
+	}
+	session.Turns = turns
 	session.Updated = time.Now()
 }
 
+// SetModel records the model answering in the session.
+func (sm *SessionManager) SetModel(key, model string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if session, ok := sm.sessions[key]; ok {
+		session.Model = model
+	}
+}
+
+// AddUsage adds tokens spent answering the latest user message to that
+// turn and to the session's running total.
+func (sm *SessionManager) AddUsage(key string, usage providers.UsageInfo) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return
+	}
+	session.Usage.Add(&usage)
+
+	turn := -1
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if session.Messages[i].Role == "user" {
+			turn = i
+			break
+		}
+	}
+	if turn < 0 {
+		return
+	}
+	if n := len(session.Turns); n > 0 && session.Turns[n-1].Message == turn {
+		session.Turns[n-1].Usage.Add(&usage)
+		return
+	}
+	session.Turns = append(session.Turns, TurnUsage{Message: turn, Usage: usage})
+}
+
+// Get returns a copy of the session stored under key.
+func (sm *SessionManager) Get(key string) (*Session, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return nil, false
+	}
+	return session.clone(), true
+}
+
+// Info summarizes a session for listings.
+type Info struct {
+	Key      string
+	ID       string
+	Model    string
+	Messages int
+	Usage    providers.UsageInfo
+	Created  time.Time
+	Updated  time.Time
+}
+
+// List returns every session, most recently updated first.
+func (sm *SessionManager) List() []Info {
+	sm.mu.RLock()
+	infos := make([]Info, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		infos = append(infos, Info{
+			Key:      s.Key,
+			ID:       s.ID,
+			Model:    s.Model,
+			Messages: len(s.Messages),
+			Usage:    s.Usage,
+			Created:  s.Created,
+			Updated:  s.Updated,
+		})
+	}
+	sm.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Updated.After(infos[j].Updated)
+	})
+	return infos
+}
+
+// Resolve finds the key of a session from its ID, a unique prefix of its ID
+// of at least four characters, or its key.
+func (sm *SessionManager) Resolve(idOrKey string) (string, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if idOrKey == "" {
+		return "", false
+	}
+	if _, ok := sm.sessions[idOrKey]; ok {
+		return idOrKey, true
+	}
+	match, matches := "", 0
+	for key, s := range sm.sessions {
+		if s.ID == idOrKey {
+			return key, true
+		}
+		if len(idOrKey) >= 4 && strings.HasPrefix(s.ID, idOrKey) {
+			match = key
+			matches++
+		}
+	}
+	return match, matches == 1
+}
+
+// Delete forgets the session stored under key and removes it from storage.
+func (sm *SessionManager) Delete(key string) error {
+	sm.mu.Lock()
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+
+	if sm.store == nil {
+		return nil
+	}
+	return sm.store.Delete(key)
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
@@ -154,21 +346,13 @@ func sanitizeFilename(key string) string {
 	return strings.ReplaceAll(key, ":", "_")
 }
 
+// Save persists the session stored under key. It does nothing for a manager
+// without storage.
 func (sm *SessionManager) Save(key string) error {
-	if sm.storage == "" {
+	if sm.store == nil {
 		return nil
 	}
 
-	filename := sanitizeFilename(key)
-
-	// filepath.IsLocal rejects empty names, "..", absolute paths, and
-	// OS-reserved device names (NUL, COM1 … on Windows).
-	// The extra checks reject "." and any directory separators so that
-	// the session file is always written directly inside sm.storage.
-	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
-		return os.ErrInvalid
-	}
-
 	// Snapshot under read lock, then perform slow file I/O after unlock.
 	sm.mu.RLock()
 	stored, ok := sm.sessions[key]
@@ -176,90 +360,24 @@ func (sm *SessionManager) Save(key string) error {
 		sm.mu.RUnlock()
 		return nil
 	}
-
-	snapshot := Session{
-		Key:     stored.Key,
-		Summary: stored.Summary,
-		Created: stored.Created,
-		Updated: stored.Updated,
-	}
-	if len(stored.Messages) > 0 {
-		snapshot.Messages = make([]providers.Message, len(stored.Messages))
-		copy(snapshot.Messages, stored.Messages)
-	} else {
-		snapshot.Messages = []providers.Message{}
-	}
+	snapshot := stored.clone()
 	sm.mu.RUnlock()
 
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	sessionPath := filepath.Join(sm.storage, filename+".json")
-	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
-	if err != nil {
-		return err
-	}
-
-	tmpPath := tmpFile.Name()
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.Remove(tmpPath)
-		}
-	}()
-
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Chmod(0644); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Close(); err != nil {
-		return err
-	}
-
-	if err := os.Rename(tmpPath, sessionPath); err != nil {
-		return err
-	}
-	cleanup = false
-	return nil
+	return sm.store.Save(snapshot)
 }
 
 func (sm *SessionManager) loadSessions() error {
-	files, err := os.ReadDir(sm.storage)
+	sessions, err := sm.store.Load()
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	for _, session := range sessions {
+		// Sessions saved before IDs existed get one now
+		if session.ID == "" {
+			session.ID = newSessionID()
 		}
-
-		if filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
-
-		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
-		if err != nil {
-			continue
-		}
-
-		var session Session
-		if err := json.Unmarshal(data, &session); err != nil {
-			continue
-		}
-
-		sm.sessions[session.Key] = &session
+		sm.sessions[session.Key] = session
 	}
 
 	return nil
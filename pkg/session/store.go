@@ -0,0 +1,311 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Storage formats accepted by NewStore.
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+// Store persists sessions. Implementations must be safe to call from
+// several goroutines for different sessions.
+type Store interface {
+	// Load returns every stored session.
+	Load() ([]*Session, error)
+	// Save writes s, replacing any stored copy.
+	Save(s *Session) error
+	// Delete removes the session stored under key, if any.
+	Delete(key string) error
+}
+
+// NewStore returns a store for format ("json" or "jsonl") in dir. An empty
+// format selects JSON.
+func NewStore(format, dir string) (Store, error) {
+	switch format {
+	case "", FormatJSON:
+		return NewJSONStore(dir), nil
+	case FormatJSONL:
+		return NewJSONLStore(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown session format %q (want json or jsonl)", format)
+	}
+}
+
+// JSONStore keeps each session as an indented JSON document in
+// <dir>/<key>.json.
+type JSONStore struct {
+	dir string
+}
+
+func NewJSONStore(dir string) *JSONStore {
+	os.MkdirAll(dir, 0755)
+	return &JSONStore{dir: dir}
+}
+
+func (s *JSONStore) Load() ([]*Session, error) {
+	return loadDir(s.dir, ".json", decodeJSONSession)
+}
+
+func (s *JSONStore) Save(session *Session) error {
+	path, err := sessionPath(s.dir, session.Key, ".json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.dir, path, data)
+}
+
+func (s *JSONStore) Delete(key string) error {
+	return removeSession(s.dir, key, ".json")
+}
+
+// JSONLStore keeps each session in <dir>/<key>.jsonl: a first line with the
+// session's metadata followed by one line per message, so transcripts can be
+// streamed and grepped. Sessions saved by JSONStore in the same directory are
+// loaded too and converted on their next save.
+type JSONLStore struct {
+	dir string
+}
+
+func NewJSONLStore(dir string) *JSONLStore {
+	os.MkdirAll(dir, 0755)
+	return &JSONLStore{dir: dir}
+}
+
+// sessionMeta is the first line of a JSONL session file.
+type sessionMeta struct {
+	Type    string              `json:"type"` // always "session"
+	Key     string              `json:"key"`
+	ID      string              `json:"id,omitempty"`
+	Model   string              `json:"model,omitempty"`
+	Summary string              `json:"summary,omitempty"`
+	Usage   providers.UsageInfo `json:"usage"`
+	Turns   []TurnUsage         `json:"turns,omitempty"`
+	Created time.Time           `json:"created"`
+	Updated time.Time           `json:"updated"`
+}
+
+// messageLine is every following line of a JSONL session file.
+type messageLine struct {
+	Type    string            `json:"type"` // always "message"
+	Message providers.Message `json:"message"`
+}
+
+func (s *JSONLStore) Load() ([]*Session, error) {
+	sessions, err := loadDir(s.dir, ".jsonl", decodeJSONLSession)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		seen[session.Key] = true
+	}
+	legacy, _ := loadDir(s.dir, ".json", decodeJSONSession)
+	for _, session := range legacy {
+		if !seen[session.Key] {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *JSONLStore) Save(session *Session) error {
+	path, err := sessionPath(s.dir, session.Key, ".jsonl")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(sessionMeta{
+		Type:    "session",
+		Key:     session.Key,
+		ID:      session.ID,
+		Model:   session.Model,
+		Summary: session.Summary,
+		Usage:   session.Usage,
+		Turns:   session.Turns,
+		Created: session.Created,
+		Updated: session.Updated,
+	}); err != nil {
+		return err
+	}
+	for _, msg := range session.Messages {
+		if err := enc.Encode(messageLine{Type: "message", Message: msg}); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(s.dir, path, buf.Bytes()); err != nil {
+		return err
+	}
+	// The JSONL file supersedes a session saved in the JSON format
+	removeSession(s.dir, session.Key, ".json")
+	return nil
+}
+
+func (s *JSONLStore) Delete(key string) error {
+	err := removeSession(s.dir, key, ".jsonl")
+	if legacyErr := removeSession(s.dir, key, ".json"); err == nil {
+		err = legacyErr
+	}
+	return err
+}
+
+func decodeJSONSession(data []byte) (*Session, error) {
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func decodeJSONLSession(data []byte) (*Session, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var session *Session
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if session == nil {
+			var meta sessionMeta
+			if err := json.Unmarshal(line, &meta); err != nil || meta.Type != "session" {
+				return nil, errors.New("missing session header")
+			}
+			session = &Session{
+				Key:      meta.Key,
+				ID:       meta.ID,
+				Model:    meta.Model,
+				Summary:  meta.Summary,
+				Usage:    meta.Usage,
+				Turns:    meta.Turns,
+				Created:  meta.Created,
+				Updated:  meta.Updated,
+				Messages: []providers.Message{},
+			}
+			continue
+		}
+		var msg messageLine
+		if err := json.Unmarshal(line, &msg); err != nil {
+			// Keep what was readable of a partially written file
+			break
+		}
+		if msg.Type == "message" {
+			session.Messages = append(session.Messages, msg.Message)
+		}
+	}
+	if session == nil {
+		return nil, errors.New("empty session file")
+	}
+	return session, nil
+}
+
+// loadDir decodes every file with extension ext in dir, skipping files that
+// cannot be read.
+func loadDir(dir, ext string, decode func([]byte) (*Session, error)) ([]*Session, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ext {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		session, err := decode(data)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// sessionPath returns where the session key is stored in dir.
+func sessionPath(dir, key, ext string) (string, error) {
+	filename := sanitizeFilename(key)
+
+	// filepath.IsLocal rejects empty names, "..", absolute paths, and
+	// OS-reserved device names (NUL, COM1 … on Windows).
+	// The extra checks reject "." and any directory separators so that
+	// the session file is always written directly inside dir.
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return "", os.ErrInvalid
+	}
+	return filepath.Join(dir, filename+ext), nil
+}
+
+func removeSession(dir, key, ext string) error {
+	path, err := sessionPath(dir, key, ext)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file in dir,
+// so a crash never leaves a half-written session behind.
+func writeFileAtomic(dir, path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(dir, "session-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(0644); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
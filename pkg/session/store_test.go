@@ -0,0 +1,124 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestJSONLStore_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManagerWithStore(NewJSONLStore(tmpDir))
+
+	key := "cli:default"
+	sm.AddFullMessage(key, providers.Message{Role: "user", Content: "list files", Name: "alice", Metadata: map[string]string{"sender_id": "42"}})
+	sm.AddFullMessage(key, providers.Message{
+		Role: "assistant",
+		ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Name:      "list_dir",
+			Arguments: map[string]interface{}{"path": "."},
+		}},
+	})
+	sm.AddFullMessage(key, providers.Message{Role: "tool", Content: "a.txt\nb.txt", ToolCallID: "call_1"})
+	sm.AddMessage(key, "assistant", "Two files.")
+	sm.SetModel(key, "gpt-4o")
+	sm.AddUsage(key, providers.UsageInfo{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+	sm.AddUsage(key, providers.UsageInfo{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25})
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "cli_default.jsonl")); err != nil {
+		t.Fatalf("session file missing: %v", err)
+	}
+
+	original, _ := sm.Get(key)
+	loaded, ok := NewSessionManagerWithStore(NewJSONLStore(tmpDir)).Get(key)
+	if !ok {
+		t.Fatal("session not found after reload")
+	}
+	if loaded.ID != original.ID || loaded.Model != "gpt-4o" || loaded.Usage.TotalTokens != 40 {
+		t.Errorf("loaded = %+v, want id %s, model gpt-4o, 40 tokens", loaded, original.ID)
+	}
+	if len(loaded.Messages) != 4 {
+		t.Fatalf("len(Messages) = %d, want 4", len(loaded.Messages))
+	}
+	if tc := loaded.Messages[1].ToolCalls; len(tc) != 1 || tc[0].Name != "list_dir" {
+		t.Errorf("tool calls = %+v, want list_dir", tc)
+	}
+	if loaded.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("ToolCallID = %q, want call_1", loaded.Messages[2].ToolCallID)
+	}
+	if first := loaded.Messages[0]; first.Name != "alice" || first.Metadata["sender_id"] != "42" || !first.CreatedAt.Equal(original.Messages[0].CreatedAt) || first.CreatedAt.IsZero() {
+		t.Errorf("first message = %+v, want its name, metadata and timestamp kept", first)
+	}
+}
+
+func TestJSONLStore_ConvertsJSONSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	legacy := NewSessionManager(tmpDir)
+	legacy.AddMessage("telegram:1", "user", "hello")
+	legacy.Save("telegram:1")
+
+	sm := NewSessionManagerWithStore(NewJSONLStore(tmpDir))
+	if history := sm.GetHistory("telegram:1"); len(history) != 1 || history[0].Content != "hello" {
+		t.Fatalf("history = %+v, want the JSON session", history)
+	}
+
+	sm.AddMessage("telegram:1", "assistant", "hi")
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "telegram_1.json")); !os.IsNotExist(err) {
+		t.Errorf("JSON session still exists after conversion (err = %v)", err)
+	}
+	if history := NewSessionManagerWithStore(NewJSONLStore(tmpDir)).GetHistory("telegram:1"); len(history) != 2 {
+		t.Errorf("len(history) = %d after conversion, want 2", len(history))
+	}
+}
+
+func TestSessionManager_ResolveListDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	older := sm.GetOrCreate("cli:older")
+	older.Updated = time.Now().Add(-time.Hour)
+	sm.AddMessage("cli:newer", "user", "hi")
+	newer, _ := sm.Get("cli:newer")
+	sm.Save("cli:older")
+	sm.Save("cli:newer")
+
+	if key, ok := sm.Resolve(newer.ID); !ok || key != "cli:newer" {
+		t.Errorf("Resolve(id) = %q, %v, want cli:newer", key, ok)
+	}
+	if key, ok := sm.Resolve(older.ID[:6]); !ok || key != "cli:older" {
+		t.Errorf("Resolve(prefix) = %q, %v, want cli:older", key, ok)
+	}
+	if key, ok := sm.Resolve("cli:older"); !ok || key != "cli:older" {
+		t.Errorf("Resolve(key) = %q, %v, want cli:older", key, ok)
+	}
+	if _, ok := sm.Resolve("ab"); ok {
+		t.Error("Resolve() accepted a two-character prefix")
+	}
+
+	infos := sm.List()
+	if len(infos) != 2 || infos[0].Key != "cli:newer" || infos[0].Messages != 1 {
+		t.Fatalf("List() = %+v, want cli:newer first", infos)
+	}
+
+	if err := sm.Delete("cli:older"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := NewSessionManager(tmpDir).Get("cli:older"); ok {
+		t.Error("deleted session was loaded again")
+	}
+}
+
+func TestNewStore_RejectsUnknownFormat(t *testing.T) {
+	if _, err := NewStore("sqlite", t.TempDir()); err == nil {
+		t.Error("NewStore(sqlite) error = nil, want error")
+	}
+}
@@ -0,0 +1,336 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ApprovalDecision is the answer to an approval request.
+type ApprovalDecision string
+
+const (
+	ApprovalDeny        ApprovalDecision = "deny"
+	ApprovalAllowOnce   ApprovalDecision = "allow_once"
+	ApprovalAllowAlways ApprovalDecision = "allow_always"
+)
+
+// ErrApprovalDenied is returned (wrapped) when a tool call was not approved.
+var ErrApprovalDenied = errors.New("tool call was not approved")
+
+// ApprovalRequest describes a tool call waiting for confirmation.
+type ApprovalRequest struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Channel   string                 `json:"channel,omitempty"`
+	ChatID    string                 `json:"chat_id,omitempty"`
+}
+
+// Approver decides whether a tool call may run, e.g. by prompting on the
+// terminal or asking a webhook.
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// ApproverFunc adapts a function to the Approver interface.
+type ApproverFunc func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+
+func (f ApproverFunc) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	return f(ctx, req)
+}
+
+// ConfirmableTool is an optional interface for tools that change the
+// system, such as exec and the file writers. When RequiresApproval returns
+// true and the registry has an ApprovalGate, the call is confirmed first.
+type ConfirmableTool interface {
+	Tool
+	RequiresApproval() bool
+}
+
+// ApprovalGate asks an Approver before running tools that need
+// confirmation. "Allow always" decisions are remembered per tool name and
+// persisted to a JSON file so they survive restarts.
+type ApprovalGate struct {
+	approver  Approver
+	storePath string
+
+	// asking serializes prompts when tool calls run in parallel
+	asking sync.Mutex
+
+	mu       sync.Mutex
+	required map[string]bool
+	always   map[string]bool
+}
+
+type approvalStore struct {
+	AlwaysAllow []string `json:"always_allow"`
+}
+
+// NewApprovalGate creates a gate that consults approver. storePath is where
+// allow-always decisions are kept; empty keeps them in memory only. A nil
+// approver denies every call that needs approval.
+func NewApprovalGate(approver Approver, storePath string) *ApprovalGate {
+	g := &ApprovalGate{
+		approver:  approver,
+		storePath: storePath,
+		required:  make(map[string]bool),
+		always:    make(map[string]bool),
+	}
+	if err := g.load(); err != nil {
+		logger.WarnCF("approval", "Failed to load approval decisions",
+			map[string]interface{}{
+				"path":  storePath,
+				"error": err.Error(),
+			})
+	}
+	return g
+}
+
+// NewApprovalGateFromConfig returns the gate described by cfg, or nil when
+// approvals are disabled. Calls are sent to cfg.WebhookURL when set;
+// otherwise they are denied until SetApprover installs an approver, such as
+// a terminal prompt.
+func NewApprovalGateFromConfig(cfg config.ApprovalConfig, storePath string) *ApprovalGate {
+	if !cfg.Enabled {
+		return nil
+	}
+	var approver Approver
+	if cfg.WebhookURL != "" {
+		approver = NewWebhookApprover(cfg.WebhookURL, time.Duration(cfg.Timeout)*time.Second)
+	}
+	gate := NewApprovalGate(approver, storePath)
+	gate.Require(cfg.Tools...)
+	return gate
+}
+
+// SetApprover replaces the approver consulted by the gate.
+func (g *ApprovalGate) SetApprover(approver Approver) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.approver = approver
+}
+
+// Require marks additional tools, by name, as needing approval.
+func (g *ApprovalGate) Require(names ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, name := range names {
+		g.required[name] = true
+	}
+}
+
+// NeedsApproval reports whether calls to tool must be confirmed.
+func (g *ApprovalGate) NeedsApproval(tool Tool) bool {
+	g.mu.Lock()
+	required := g.required[tool.Name()]
+	g.mu.Unlock()
+	if required {
+		return true
+	}
+	ct, ok := tool.(ConfirmableTool)
+	return ok && ct.RequiresApproval()
+}
+
+// Check returns nil when the call described by req may run. Calls to tools
+// that do not need approval, or that were allowed always, pass without
+// asking the approver.
+func (g *ApprovalGate) Check(ctx context.Context, tool Tool, req ApprovalRequest) error {
+	_, err := g.Decide(ctx, tool, req)
+	return err
+}
+
+// Decide is Check that also returns the decision: "" for tools that do not
+// need approval, ApprovalAllowAlways for tools allowed always before, and
+// ApprovalDeny along with the error when the call may not run.
+func (g *ApprovalGate) Decide(ctx context.Context, tool Tool, req ApprovalRequest) (ApprovalDecision, error) {
+	if !g.NeedsApproval(tool) {
+		return "", nil
+	}
+
+	g.asking.Lock()
+	defer g.asking.Unlock()
+
+	g.mu.Lock()
+	approver := g.approver
+	allowed := g.always[req.Tool]
+	g.mu.Unlock()
+	if allowed {
+		return ApprovalAllowAlways, nil
+	}
+	if approver == nil {
+		return ApprovalDeny, fmt.Errorf("%w: no approver is configured", ErrApprovalDenied)
+	}
+
+	decision, err := approver.Approve(ctx, req)
+	if err != nil {
+		return ApprovalDeny, fmt.Errorf("%w: %v", ErrApprovalDenied, err)
+	}
+
+	logger.InfoCF("approval", "Tool call decision",
+		map[string]interface{}{
+			"tool":     req.Tool,
+			"decision": string(decision),
+		})
+
+	switch decision {
+	case ApprovalAllowOnce:
+		return decision, nil
+	case ApprovalAllowAlways:
+		if err := g.AllowAlways(req.Tool); err != nil {
+			logger.WarnCF("approval", "Failed to persist approval decision",
+				map[string]interface{}{
+					"tool":  req.Tool,
+					"error": err.Error(),
+				})
+		}
+		return decision, nil
+	default:
+		return ApprovalDeny, ErrApprovalDenied
+	}
+}
+
+// AllowAlways records that calls to the named tool no longer need approval.
+func (g *ApprovalGate) AllowAlways(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.always[name] = true
+	return g.save()
+}
+
+// Revoke forgets an allow-always decision for the named tool.
+func (g *ApprovalGate) Revoke(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.always, name)
+	return g.save()
+}
+
+// AlwaysAllowed returns the tools that were allowed always, sorted by name.
+func (g *ApprovalGate) AlwaysAllowed() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.always))
+	for name := range g.always {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *ApprovalGate) load() error {
+	if g.storePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(g.storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var store approvalStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return err
+	}
+	for _, name := range store.AlwaysAllow {
+		g.always[name] = true
+	}
+	return nil
+}
+
+// save writes the allow-always decisions. Must be called with the lock held.
+func (g *ApprovalGate) save() error {
+	if g.storePath == "" {
+		return nil
+	}
+	store := approvalStore{AlwaysAllow: make([]string, 0, len(g.always))}
+	for name := range g.always {
+		store.AlwaysAllow = append(store.AlwaysAllow, name)
+	}
+	sort.Strings(store.AlwaysAllow)
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.storePath), 0755); err != nil {
+		return err
+	}
+	tmp := g.storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, g.storePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// WebhookApprover asks an HTTP endpoint to approve tool calls. The request
+// is POSTed as JSON and the endpoint answers with
+// {"decision": "allow_once" | "allow_always" | "deny"}.
+type WebhookApprover struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookApprover(url string, timeout time.Duration) *WebhookApprover {
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return &WebhookApprover{
+		URL:    url,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *WebhookApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return ApprovalDeny, fmt.Errorf("failed to marshal approval request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return ApprovalDeny, fmt.Errorf("failed to create approval request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(httpReq)
+	if err != nil {
+		return ApprovalDeny, fmt.Errorf("approval webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return ApprovalDeny, fmt.Errorf("failed to read approval response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ApprovalDeny, fmt.Errorf("approval webhook returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	var out struct {
+		Decision ApprovalDecision `json:"decision"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return ApprovalDeny, fmt.Errorf("invalid approval response: %w", err)
+	}
+	switch out.Decision {
+	case ApprovalAllowOnce, ApprovalAllowAlways, ApprovalDeny:
+		return out.Decision, nil
+	}
+	return ApprovalDeny, fmt.Errorf("unknown approval decision %q", out.Decision)
+}
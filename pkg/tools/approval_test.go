@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingTool is a harmless tool that counts its runs.
+type recordingTool struct {
+	name    string
+	confirm bool
+	runs    int
+}
+
+func (t *recordingTool) Name() string        { return t.name }
+func (t *recordingTool) Description() string { return "records calls" }
+func (t *recordingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (t *recordingTool) RequiresApproval() bool { return t.confirm }
+
+func (t *recordingTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.runs++
+	return NewToolResult("ran")
+}
+
+func staticApprover(decision ApprovalDecision, asked *int) Approver {
+	return ApproverFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		*asked++
+		return decision, nil
+	})
+}
+
+func TestApprovalGate_Decisions(t *testing.T) {
+	tests := []struct {
+		decision ApprovalDecision
+		wantRuns int
+	}{
+		{ApprovalAllowOnce, 2},
+		{ApprovalAllowAlways, 2},
+		{ApprovalDeny, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.decision), func(t *testing.T) {
+			tool := &recordingTool{name: "danger", confirm: true}
+			asked := 0
+			registry := NewToolRegistry()
+			registry.Register(tool)
+			registry.SetApprovalGate(NewApprovalGate(staticApprover(tt.decision, &asked), ""))
+
+			for i := 0; i < 2; i++ {
+				result := registry.Execute(context.Background(), "danger", nil)
+				if tt.decision == ApprovalDeny && !errors.Is(result.Err, ErrApprovalDenied) {
+					t.Errorf("Expected ErrApprovalDenied, got: %v", result.Err)
+				}
+			}
+			if tool.runs != tt.wantRuns {
+				t.Errorf("Expected %d runs, got %d", tt.wantRuns, tool.runs)
+			}
+			wantAsked := 2
+			if tt.decision == ApprovalAllowAlways {
+				wantAsked = 1
+			}
+			if asked != wantAsked {
+				t.Errorf("Expected approver to be asked %d times, got %d", wantAsked, asked)
+			}
+		})
+	}
+}
+
+func TestApprovalGate_OnlyConfirmableOrRequiredTools(t *testing.T) {
+	asked := 0
+	gate := NewApprovalGate(staticApprover(ApprovalDeny, &asked), "")
+	gate.Require("listed")
+
+	if err := gate.Check(context.Background(), &recordingTool{name: "safe"}, ApprovalRequest{Tool: "safe"}); err != nil {
+		t.Errorf("Expected safe tool to pass, got: %v", err)
+	}
+	if err := gate.Check(context.Background(), &recordingTool{name: "listed"}, ApprovalRequest{Tool: "listed"}); err == nil {
+		t.Error("Expected listed tool to need approval")
+	}
+	if asked != 1 {
+		t.Errorf("Expected approver to be asked once, got %d", asked)
+	}
+
+	if !gate.NeedsApproval(NewExecTool("", false)) || !gate.NeedsApproval(NewWriteFileTool("", false)) {
+		t.Error("Expected exec and write_file to need approval")
+	}
+	if gate.NeedsApproval(NewReadFileTool("", false)) {
+		t.Error("Expected read_file not to need approval")
+	}
+}
+
+func TestApprovalGate_NoApproverDenies(t *testing.T) {
+	gate := NewApprovalGate(nil, "")
+	err := gate.Check(context.Background(), &recordingTool{name: "danger", confirm: true}, ApprovalRequest{Tool: "danger"})
+	if !errors.Is(err, ErrApprovalDenied) {
+		t.Errorf("Expected ErrApprovalDenied, got: %v", err)
+	}
+}
+
+func TestApprovalGate_PersistsAllowAlways(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "state", "approvals.json")
+	asked := 0
+	gate := NewApprovalGate(staticApprover(ApprovalAllowAlways, &asked), store)
+	tool := &recordingTool{name: "danger", confirm: true}
+	if err := gate.Check(context.Background(), tool, ApprovalRequest{Tool: "danger"}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	reloaded := NewApprovalGate(nil, store)
+	if got := reloaded.AlwaysAllowed(); len(got) != 1 || got[0] != "danger" {
+		t.Fatalf("Expected [danger] to be persisted, got %v", got)
+	}
+	if err := reloaded.Check(context.Background(), tool, ApprovalRequest{Tool: "danger"}); err != nil {
+		t.Errorf("Expected persisted decision to allow the call, got: %v", err)
+	}
+
+	if err := reloaded.Revoke("danger"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if got := NewApprovalGate(nil, store).AlwaysAllowed(); len(got) != 0 {
+		t.Errorf("Expected no decisions after revoke, got %v", got)
+	}
+}
+
+func TestWebhookApprover(t *testing.T) {
+	var got ApprovalRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"decision":"allow_once"}`))
+	}))
+	defer server.Close()
+
+	approver := NewWebhookApprover(server.URL, time.Second)
+	decision, err := approver.Approve(context.Background(), ApprovalRequest{
+		Tool:      "exec",
+		Arguments: map[string]interface{}{"command": "ls"},
+		Channel:   "telegram",
+	})
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if decision != ApprovalAllowOnce {
+		t.Errorf("Expected allow_once, got %q", decision)
+	}
+	if got.Tool != "exec" || got.Arguments["command"] != "ls" || got.Channel != "telegram" {
+		t.Errorf("Expected request to be forwarded, got %+v", got)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"decision":"maybe"}`))
+	}))
+	defer bad.Close()
+	if decision, err := NewWebhookApprover(bad.URL, time.Second).Approve(context.Background(), ApprovalRequest{}); err == nil || decision != ApprovalDeny {
+		t.Errorf("Expected unknown decision to deny with an error, got %q, %v", decision, err)
+	}
+}
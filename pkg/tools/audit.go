@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Audit events.
+const (
+	AuditModelResponse = "model_response"
+	AuditToolCall      = "tool_call"
+)
+
+// AuditEntry is one line of the audit log. Tool arguments are recorded as a
+// hash so the log does not hold file contents or credentials passed to
+// tools.
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Session string    `json:"session,omitempty"`
+	Model   string    `json:"model,omitempty"`
+
+	// Model responses
+	ToolCalls    []string `json:"tool_calls,omitempty"` // tools the model asked for
+	FinishReason string   `json:"finish_reason,omitempty"`
+	Tokens       int      `json:"tokens,omitempty"`
+
+	// Tool calls
+	Tool          string           `json:"tool,omitempty"`
+	ArgumentsHash string           `json:"arguments_sha256,omitempty"`
+	ResultSize    int              `json:"result_size,omitempty"` // characters returned to the model
+	Error         bool             `json:"error,omitempty"`
+	Approval      ApprovalDecision `json:"approval,omitempty"` // empty when no approval was needed
+	PolicyDenied  bool             `json:"policy_denied,omitempty"`
+}
+
+// AuditLog appends AuditEntry records to a JSONL file. Registries with an
+// audit log record every tool call they execute; agent loops record the
+// model responses with RecordResponse.
+type AuditLog struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it and
+// its directory if needed. The file is readable by its owner only.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{path: path, file: file}, nil
+}
+
+// NewAuditLogFromConfig opens the audit log described by cfg, or returns
+// nil when it is disabled or cannot be opened.
+func NewAuditLogFromConfig(cfg config.AuditConfig, workspace string) *AuditLog {
+	if !cfg.Enabled {
+		return nil
+	}
+	path := cfg.Path
+	if path == "" {
+		path = filepath.Join("state", "audit.jsonl")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspace, path)
+	}
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		logger.ErrorCF("audit", "Failed to open audit log",
+			map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+		return nil
+	}
+	return audit
+}
+
+// Path returns the file the log is written to.
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Record appends entry, timestamped now when Time is zero.
+func (a *AuditLog) Record(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return errors.New("audit log is closed")
+	}
+	_, err = a.file.Write(line)
+	return err
+}
+
+// Close closes the file; later records fail.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// RecordResponse records a model response: the tools it asked for, why it
+// stopped and the tokens it used. The session and model come from ctx, as
+// set with WithAuditSession.
+func (a *AuditLog) RecordResponse(ctx context.Context, response *providers.LLMResponse) {
+	session := auditSessionFrom(ctx)
+	entry := AuditEntry{
+		Event:        AuditModelResponse,
+		Session:      session.key,
+		Model:        session.model,
+		FinishReason: string(response.FinishReason),
+	}
+	for _, tc := range response.ToolCalls {
+		entry.ToolCalls = append(entry.ToolCalls, tc.Name)
+	}
+	if response.Usage != nil {
+		entry.Tokens = response.Usage.TotalTokens
+	}
+	a.record(entry)
+}
+
+func (a *AuditLog) recordToolCall(ctx context.Context, name string, args map[string]interface{}, result *ToolResult, approval ApprovalDecision, denied bool) {
+	session := auditSessionFrom(ctx)
+	entry := AuditEntry{
+		Event:         AuditToolCall,
+		Session:       session.key,
+		Model:         session.model,
+		Tool:          name,
+		ArgumentsHash: hashArguments(args),
+		Approval:      approval,
+		PolicyDenied:  denied,
+	}
+	if result != nil {
+		entry.ResultSize = len(result.ForLLM)
+		entry.Error = result.IsError
+	}
+	a.record(entry)
+}
+
+// record is Record that logs failures; a full disk should not stop the
+// agent.
+func (a *AuditLog) record(entry AuditEntry) {
+	if err := a.Record(entry); err != nil {
+		logger.ErrorCF("audit", "Failed to write audit log",
+			map[string]interface{}{
+				"path":  a.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// hashArguments returns the SHA-256 of args as JSON, whose object keys are
+// sorted, so equal arguments hash alike.
+func hashArguments(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type auditSessionKey struct{}
+
+type auditSession struct {
+	key, model string
+}
+
+// WithAuditSession returns a context whose audit log records name session
+// and model. Subagents started from a tool call inherit them.
+func WithAuditSession(ctx context.Context, session, model string) context.Context {
+	return context.WithValue(ctx, auditSessionKey{}, auditSession{key: session, model: model})
+}
+
+func auditSessionFrom(ctx context.Context) auditSession {
+	session, _ := ctx.Value(auditSessionKey{}).(auditSession)
+	return session
+}
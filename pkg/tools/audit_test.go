@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func readAuditLog(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLog_ToolCalls(t *testing.T) {
+	workspace := t.TempDir()
+	audit := NewAuditLogFromConfig(config.AuditConfig{Enabled: true}, workspace)
+	if audit == nil {
+		t.Fatal("audit log was not opened")
+	}
+	defer audit.Close()
+
+	registry := NewToolRegistry()
+	registry.Register(&recordingTool{name: "safe"})
+	registry.Register(&recordingTool{name: "danger", confirm: true})
+	asked := 0
+	registry.SetApprovalGate(NewApprovalGate(staticApprover(ApprovalDeny, &asked), ""))
+	registry.SetAuditLog(audit)
+
+	ctx := WithAuditSession(context.Background(), "cli:default", "gpt-4o")
+	audit.RecordResponse(ctx, &providers.LLMResponse{
+		ToolCalls:    []providers.ToolCall{{Name: "safe"}, {Name: "danger"}},
+		FinishReason: providers.FinishReasonToolCalls,
+		Usage:        &providers.UsageInfo{TotalTokens: 42},
+	})
+	registry.Execute(ctx, "safe", map[string]interface{}{"secret": "s3cr3t"})
+	registry.Execute(ctx, "danger", nil)
+	registry.Execute(ctx, "missing", nil)
+
+	entries := readAuditLog(t, filepath.Join(workspace, "state", "audit.jsonl"))
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(entries), entries)
+	}
+	for _, e := range entries {
+		if e.Session != "cli:default" || e.Model != "gpt-4o" || e.Time.IsZero() {
+			t.Errorf("entry = %+v, want the session, model and time", e)
+		}
+	}
+	if got := entries[0]; got.Event != AuditModelResponse || len(got.ToolCalls) != 2 || got.FinishReason != "tool_calls" || got.Tokens != 42 {
+		t.Errorf("model response = %+v", got)
+	}
+	if got := entries[1]; got.Event != AuditToolCall || got.Tool != "safe" || got.ResultSize != 3 || got.Error || got.Approval != "" {
+		t.Errorf("safe call = %+v", got)
+	}
+	if entries[1].ArgumentsHash != hashArguments(map[string]interface{}{"secret": "s3cr3t"}) || len(entries[1].ArgumentsHash) != 64 {
+		t.Errorf("arguments hash = %q", entries[1].ArgumentsHash)
+	}
+	if got := entries[2]; got.Tool != "danger" || got.Approval != ApprovalDeny || !got.Error {
+		t.Errorf("denied call = %+v", got)
+	}
+	if got := entries[3]; got.Tool != "missing" || !got.Error {
+		t.Errorf("unknown tool = %+v", got)
+	}
+}
+
+func TestAuditLog_Disabled(t *testing.T) {
+	if audit := NewAuditLogFromConfig(config.AuditConfig{}, t.TempDir()); audit != nil {
+		t.Error("audit log opened while disabled")
+	}
+}
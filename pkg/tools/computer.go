@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Computer is implemented by the host application to let the model see and
+// drive a screen through the computer tool.
+type Computer interface {
+	// Display returns the size of the screen the model sees.
+	Display() providers.ComputerDisplay
+	// Screenshot captures the screen as an image, such as a PNG.
+	Screenshot(ctx context.Context) (data []byte, mediaType string, err error)
+	// Perform carries out an action other than taking a screenshot and
+	// returns any text it produces, such as the cursor position.
+	Perform(ctx context.Context, action ComputerAction) (string, error)
+}
+
+// ComputerAction is one step the model asks the computer to take. Action
+// is one of those of Anthropic's computer-use tool, e.g. "left_click",
+// "type", "key", "mouse_move", "scroll" or "cursor_position".
+type ComputerAction struct {
+	Action          string
+	Coordinate      []int   // x, y target of clicks and moves
+	StartCoordinate []int   // x, y a left_click_drag starts from
+	Text            string  // text to type, or keys such as "ctrl+s"
+	ScrollDirection string  // up, down, left or right
+	ScrollAmount    int     // scroll wheel clicks
+	Duration        float64 // seconds, for wait and hold_key
+}
+
+var computerActions = []string{
+	"key", "hold_key", "type", "cursor_position", "mouse_move",
+	"left_mouse_down", "left_mouse_up", "left_click", "left_click_drag",
+	"right_click", "middle_click", "double_click", "triple_click",
+	"scroll", "wait", "screenshot",
+}
+
+// ComputerTool lets the model use a Computer. Claude sees it as Anthropic's
+// computer-use tool; other models call it as a function with the same
+// arguments. Every action but cursor_position returns a screenshot.
+type ComputerTool struct {
+	computer Computer
+}
+
+func NewComputerTool(computer Computer) *ComputerTool {
+	return &ComputerTool{computer: computer}
+}
+
+func (t *ComputerTool) Name() string {
+	return "computer"
+}
+
+// Actions follow one another on the one screen.
+func (t *ComputerTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *ComputerTool) Display() providers.ComputerDisplay {
+	return t.computer.Display()
+}
+
+func (t *ComputerTool) Description() string {
+	d := t.computer.Display()
+	return fmt.Sprintf("Use a mouse and keyboard to interact with a %dx%d screen, and take screenshots to see it.", d.Width, d.Height)
+}
+
+func (t *ComputerTool) Parameters() map[string]interface{} {
+	coordinate := map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "integer"},
+		"description": "The x, y pixel position on the screen",
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        computerActions,
+				"description": "The action to take",
+			},
+			"coordinate":       coordinate,
+			"start_coordinate": coordinate,
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to type, or keys to press such as \"ctrl+s\"",
+			},
+			"scroll_direction": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"up", "down", "left", "right"},
+			},
+			"scroll_amount": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of scroll wheel clicks",
+			},
+			"duration": map[string]interface{}{
+				"type":        "number",
+				"description": "Seconds to wait or hold a key",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ComputerTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, err := parseComputerAction(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	output := ""
+	if action.Action != "screenshot" {
+		if output, err = t.computer.Perform(ctx, action); err != nil {
+			return ErrorResult(fmt.Sprintf("computer %s: %v", action.Action, err)).WithError(err)
+		}
+		if action.Action == "cursor_position" {
+			return SilentResult(output)
+		}
+	}
+
+	data, mediaType, err := t.computer.Screenshot(ctx)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("taking screenshot: %v", err)).WithError(err)
+	}
+	if output == "" {
+		output = "Screenshot taken"
+	}
+	result := SilentResult(output)
+	result.Media = []providers.ContentPart{providers.ImagePart(mediaType, data)}
+	return result
+}
+
+func parseComputerAction(args map[string]interface{}) (ComputerAction, error) {
+	var action ComputerAction
+	action.Action, _ = args["action"].(string)
+	known := false
+	for _, a := range computerActions {
+		known = known || a == action.Action
+	}
+	if !known {
+		return action, fmt.Errorf("unknown computer action %q", action.Action)
+	}
+
+	var err error
+	if action.Coordinate, err = computerCoordinate(args, "coordinate"); err != nil {
+		return action, err
+	}
+	if action.StartCoordinate, err = computerCoordinate(args, "start_coordinate"); err != nil {
+		return action, err
+	}
+	action.Text, _ = args["text"].(string)
+	action.ScrollDirection, _ = args["scroll_direction"].(string)
+	if amount, ok := args["scroll_amount"].(float64); ok {
+		action.ScrollAmount = int(amount)
+	}
+	action.Duration, _ = args["duration"].(float64)
+	return action, nil
+}
+
+func computerCoordinate(args map[string]interface{}, key string) ([]int, error) {
+	raw, ok := args[key]
+	if !ok {
+		return nil, nil
+	}
+	values, ok := raw.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, fmt.Errorf("%s must be an [x, y] pair", key)
+	}
+	coordinate := make([]int, 2)
+	for i, v := range values {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an [x, y] pair", key)
+		}
+		coordinate[i] = int(n)
+	}
+	return coordinate, nil
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type fakeComputer struct {
+	performed []ComputerAction
+	err       error
+}
+
+func (c *fakeComputer) Display() providers.ComputerDisplay {
+	return providers.ComputerDisplay{Width: 1024, Height: 768}
+}
+
+func (c *fakeComputer) Screenshot(ctx context.Context) ([]byte, string, error) {
+	return []byte("png"), "image/png", nil
+}
+
+func (c *fakeComputer) Perform(ctx context.Context, action ComputerAction) (string, error) {
+	c.performed = append(c.performed, action)
+	if action.Action == "cursor_position" {
+		return "X=10,Y=20", nil
+	}
+	return "", c.err
+}
+
+func TestComputerTool_ClickReturnsScreenshot(t *testing.T) {
+	computer := &fakeComputer{}
+	tool := NewComputerTool(computer)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action":     "left_click",
+		"coordinate": []interface{}{100.0, 200.0},
+	})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	if len(computer.performed) != 1 || computer.performed[0].Coordinate[0] != 100 || computer.performed[0].Coordinate[1] != 200 {
+		t.Errorf("performed = %+v, want a left_click at 100,200", computer.performed)
+	}
+	if len(result.Media) != 1 || result.Media[0].MediaType != "image/png" {
+		t.Fatalf("Media = %+v, want the screenshot", result.Media)
+	}
+
+	msg := result.ToolMessage("call_1")
+	if msg.ToolCallID != "call_1" || len(msg.Parts) != 2 || msg.Parts[1].Type != providers.ContentImage {
+		t.Errorf("ToolMessage() = %+v, want the result followed by the screenshot", msg)
+	}
+}
+
+func TestComputerTool_CursorPositionHasNoScreenshot(t *testing.T) {
+	result := NewComputerTool(&fakeComputer{}).Execute(context.Background(), map[string]interface{}{"action": "cursor_position"})
+	if result.ForLLM != "X=10,Y=20" || len(result.Media) != 0 {
+		t.Errorf("result = %+v, want the position alone", result)
+	}
+}
+
+func TestComputerTool_Errors(t *testing.T) {
+	tool := NewComputerTool(&fakeComputer{err: errors.New("display locked")})
+	if result := tool.Execute(context.Background(), map[string]interface{}{"action": "fly"}); !result.IsError {
+		t.Error("unknown action: want an error")
+	}
+	if result := tool.Execute(context.Background(), map[string]interface{}{"action": "mouse_move", "coordinate": []interface{}{1.0}}); !result.IsError {
+		t.Error("bad coordinate: want an error")
+	}
+	if result := tool.Execute(context.Background(), map[string]interface{}{"action": "type", "text": "hi"}); !result.IsError || result.Err == nil {
+		t.Errorf("failed action: result = %+v, want an error", result)
+	}
+}
+
+func TestToolRegistry_ComputerToolDefinition(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(NewComputerTool(&fakeComputer{}))
+	defs := registry.ToProviderDefs()
+	if len(defs) != 1 || defs[0].Computer == nil || defs[0].Computer.Width != 1024 {
+		t.Errorf("ToProviderDefs() = %+v, want the computer display", defs)
+	}
+}
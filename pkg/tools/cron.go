@@ -42,6 +42,10 @@ func (t *CronTool) Name() string {
 	return "cron"
 }
 
+func (t *CronTool) MaxConcurrency() int {
+	return 1
+}
+
 // Description returns the tool description
 func (t *CronTool) Description() string {
 	return "Schedule reminders, tasks, or system commands. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules. Use 'command' to execute shell commands directly."
@@ -91,6 +95,12 @@ func (t *CronTool) Parameters() map[string]interface{} {
 }
 
 // SetContext sets the current session context for job creation
+// SetExecTool sets the tool scheduled commands run with, so they get the
+// same limits and sandbox as the agent's exec tool. nil disables them.
+func (t *CronTool) SetExecTool(execTool *ExecTool) {
+	t.execTool = execTool
+}
+
 func (t *CronTool) SetContext(channel, chatID string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -279,9 +289,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 			"command": job.Payload.Command,
 		}
 
-		result := t.execTool.Execute(ctx, args)
 		var output string
-		if result.IsError {
+		if t.execTool == nil {
+			output = "Error executing scheduled command: shell commands are disabled"
+		} else if result := t.execTool.Execute(ctx, args); result.IsError {
 			output = fmt.Sprintf("Error executing scheduled command: %s", result.ForLLM)
 		} else {
 			output = fmt.Sprintf("Scheduled command '%s' executed:\n%s", job.Payload.Command, result.ForLLM)
@@ -10,15 +10,13 @@ import (
 // EditFileTool edits a file by replacing old_text with new_text.
 // The old_text must exist exactly in the file.
 type EditFileTool struct {
-	allowedDir string
-	restrict   bool
+	workspace *Workspace
 }
 
 // NewEditFileTool creates a new EditFileTool with optional directory restriction.
 func NewEditFileTool(allowedDir string, restrict bool) *EditFileTool {
 	return &EditFileTool{
-		allowedDir: allowedDir,
-		restrict:   restrict,
+		workspace: NewWorkspace(allowedDir, restrict),
 	}
 }
 
@@ -26,6 +24,15 @@ func (t *EditFileTool) Name() string {
 	return "edit_file"
 }
 
+// Edits are serialized so concurrent calls cannot clobber the same file.
+func (t *EditFileTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *EditFileTool) RequiresApproval() bool {
+	return true
+}
+
 func (t *EditFileTool) Description() string {
 	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
 }
@@ -46,6 +53,10 @@ func (t *EditFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The text to replace with",
 			},
+			"replace_all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace every occurrence instead of requiring old_text to be unique",
+			},
 		},
 		"required": []string{"path", "old_text", "new_text"},
 	}
@@ -67,18 +78,18 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("new_text is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.allowedDir, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
 	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
-		return ErrorResult(fmt.Sprintf("file not found: %s", path))
+		return ErrorResult(fmt.Sprintf("file not found: %s", t.workspace.Rel(resolvedPath)))
 	}
 
 	content, err := os.ReadFile(resolvedPath)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", t.workspace.RelError(err)))
 	}
 
 	contentStr := string(content)
@@ -87,33 +98,41 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("old_text not found in file. Make sure it matches exactly")
 	}
 
+	replaceAll, _ := args["replace_all"].(bool)
 	count := strings.Count(contentStr, oldText)
-	if count > 1 {
-		return ErrorResult(fmt.Sprintf("old_text appears %d times. Please provide more context to make it unique", count))
+	if count > 1 && !replaceAll {
+		return ErrorResult(fmt.Sprintf("old_text appears %d times. Please provide more context to make it unique, or set replace_all", count))
 	}
 
-	newContent := strings.Replace(contentStr, oldText, newText, 1)
+	newContent := strings.Replace(contentStr, oldText, newText, -1)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
+	if err := writeFileNoFollow(resolvedPath, []byte(newContent), 0644); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write file: %v", t.workspace.RelError(err)))
 	}
 
-	return SilentResult(fmt.Sprintf("File edited: %s", path))
+	return SilentResult(fmt.Sprintf("File edited: %s", t.workspace.Rel(resolvedPath)))
 }
 
 type AppendFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
 func NewAppendFileTool(workspace string, restrict bool) *AppendFileTool {
-	return &AppendFileTool{workspace: workspace, restrict: restrict}
+	return &AppendFileTool{workspace: NewWorkspace(workspace, restrict)}
 }
 
 func (t *AppendFileTool) Name() string {
 	return "append_file"
 }
 
+func (t *AppendFileTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *AppendFileTool) RequiresApproval() bool {
+	return true
+}
+
 func (t *AppendFileTool) Description() string {
 	return "Append content to the end of a file"
 }
@@ -146,14 +165,14 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := openNoFollow(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to open file: %v", t.workspace.RelError(err)))
 	}
 	defer f.Close()
 
@@ -161,5 +180,5 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult(fmt.Sprintf("failed to append to file: %v", err))
 	}
 
-	return SilentResult(fmt.Sprintf("Appended to %s", path))
+	return SilentResult(fmt.Sprintf("Appended to %s", t.workspace.Rel(resolvedPath)))
 }
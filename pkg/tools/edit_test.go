@@ -287,3 +287,26 @@ func TestEditTool_AppendFile_MissingContent(t *testing.T) {
 		t.Errorf("Expected error when content is missing")
 	}
 }
+
+// TestEditTool_EditFile_ReplaceAll verifies replace_all replaces every occurrence
+func TestEditTool_EditFile_ReplaceAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	os.WriteFile(testFile, []byte("test test test"), 0644)
+
+	tool := NewEditFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        testFile,
+		"old_text":    "test",
+		"new_text":    "done",
+		"replace_all": true,
+	})
+
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+	data, _ := os.ReadFile(testFile)
+	if string(data) != "done done done" {
+		t.Errorf("Expected 'done done done', got: %s", data)
+	}
+}
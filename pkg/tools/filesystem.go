@@ -8,41 +8,12 @@ import (
 	"strings"
 )
 
-// validatePath ensures the given path is within the workspace if restrict is true.
-func validatePath(path, workspace string, restrict bool) (string, error) {
-	if workspace == "" {
-		return path, nil
-	}
-
-	absWorkspace, err := filepath.Abs(workspace)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve workspace path: %w", err)
-	}
-
-	var absPath string
-	if filepath.IsAbs(path) {
-		absPath = filepath.Clean(path)
-	} else {
-		absPath, err = filepath.Abs(filepath.Join(absWorkspace, path))
-		if err != nil {
-			return "", fmt.Errorf("failed to resolve file path: %w", err)
-		}
-	}
-
-	if restrict && !strings.HasPrefix(absPath, absWorkspace) {
-		return "", fmt.Errorf("access denied: path is outside the workspace")
-	}
-
-	return absPath, nil
-}
-
 type ReadFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
 func NewReadFileTool(workspace string, restrict bool) *ReadFileTool {
-	return &ReadFileTool{workspace: workspace, restrict: restrict}
+	return &ReadFileTool{workspace: NewWorkspace(workspace, restrict)}
 }
 
 func (t *ReadFileTool) Name() string {
@@ -61,6 +32,14 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional first line to read (1-based). Lines are returned numbered when a range is given",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional last line to read (inclusive)",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -72,32 +51,79 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("path is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
 	content, err := os.ReadFile(resolvedPath)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", t.workspace.RelError(err)))
+	}
+
+	startLine, hasStart := args["start_line"].(float64)
+	endLine, hasEnd := args["end_line"].(float64)
+	if !hasStart && !hasEnd {
+		return NewToolResult(string(content))
 	}
 
-	return NewToolResult(string(content))
+	return NewToolResult(readLineRange(string(content), int(startLine), int(endLine)))
+}
+
+// readLineRange returns lines start..end (1-based, inclusive) prefixed with
+// their line numbers. start <= 0 means the first line, end <= 0 the last.
+func readLineRange(content string, start, end int) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > total {
+		end = total
+	}
+	if start > total {
+		return fmt.Sprintf("(file has %d lines; start_line %d is past the end)", total, start)
+	}
+	if start > end {
+		return fmt.Sprintf("(empty range: start_line %d is after end_line %d)", start, end)
+	}
+
+	var sb strings.Builder
+	width := len(fmt.Sprint(end))
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&sb, "%*d\t%s\n", width, i, lines[i-1])
+	}
+	if end < total {
+		fmt.Fprintf(&sb, "... (%d more lines)\n", total-end)
+	}
+	return sb.String()
 }
 
 type WriteFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
 func NewWriteFileTool(workspace string, restrict bool) *WriteFileTool {
-	return &WriteFileTool{workspace: workspace, restrict: restrict}
+	return &WriteFileTool{workspace: NewWorkspace(workspace, restrict)}
 }
 
 func (t *WriteFileTool) Name() string {
 	return "write_file"
 }
 
+// Writes are serialized so concurrent calls cannot clobber the same file.
+func (t *WriteFileTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *WriteFileTool) RequiresApproval() bool {
+	return true
+}
+
 func (t *WriteFileTool) Description() string {
 	return "Write content to a file"
 }
@@ -130,30 +156,29 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
 	dir := filepath.Dir(resolvedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to create directory: %v", t.workspace.RelError(err)))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
+	if err := writeFileNoFollow(resolvedPath, []byte(content), 0644); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write file: %v", t.workspace.RelError(err)))
 	}
 
-	return SilentResult(fmt.Sprintf("File written: %s", path))
+	return SilentResult(fmt.Sprintf("File written: %s", t.workspace.Rel(resolvedPath)))
 }
 
 type ListDirTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
 func NewListDirTool(workspace string, restrict bool) *ListDirTool {
-	return &ListDirTool{workspace: workspace, restrict: restrict}
+	return &ListDirTool{workspace: NewWorkspace(workspace, restrict)}
 }
 
 func (t *ListDirTool) Name() string {
@@ -183,14 +208,14 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		path = "."
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
 	entries, err := os.ReadDir(resolvedPath)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read directory: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to read directory: %v", t.workspace.RelError(err)))
 	}
 
 	result := ""
@@ -247,3 +247,49 @@ func TestFilesystemTool_ListDir_DefaultPath(t *testing.T) {
 		t.Errorf("Expected success with default path '.', got IsError=true: %s", result.ForLLM)
 	}
 }
+
+// TestFilesystemTool_ReadFile_LineRange verifies reading a numbered line range
+func TestFilesystemTool_ReadFile_LineRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "lines.txt")
+	os.WriteFile(testFile, []byte("one\ntwo\nthree\nfour\n"), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":       "lines.txt",
+		"start_line": float64(2),
+		"end_line":   float64(3),
+	})
+
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+	want := "2\ttwo\n3\tthree\n... (1 more lines)\n"
+	if result.ForLLM != want {
+		t.Errorf("Expected %q, got %q", want, result.ForLLM)
+	}
+}
+
+// TestValidatePath_Jail verifies sibling directories and symlinks cannot escape the workspace
+func TestValidatePath_Jail(t *testing.T) {
+	base := t.TempDir()
+	workspace := filepath.Join(base, "ws")
+	sibling := filepath.Join(base, "ws2")
+	os.MkdirAll(workspace, 0755)
+	os.MkdirAll(sibling, 0755)
+	os.WriteFile(filepath.Join(sibling, "secret.txt"), []byte("secret"), 0644)
+
+	if _, err := validatePath(filepath.Join(sibling, "secret.txt"), workspace, true); err == nil {
+		t.Error("Expected sibling directory with a common prefix to be rejected")
+	}
+
+	if err := os.Symlink(sibling, filepath.Join(workspace, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if _, err := validatePath("link/secret.txt", workspace, true); err == nil {
+		t.Error("Expected symlink pointing outside the workspace to be rejected")
+	}
+	if _, err := validatePath("new/dir/file.txt", workspace, true); err != nil {
+		t.Errorf("Expected new file inside workspace to be allowed, got: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// FuncTool adapts a Go function taking a typed argument struct to the Tool
+// interface. Its parameters schema is generated from T with
+// providers.SchemaFor, and the model's arguments are decoded into T with
+// encoding/json before fn is called.
+type FuncTool[T any] struct {
+	name        string
+	description string
+	schema      map[string]interface{}
+	fn          func(ctx context.Context, args T) *ToolResult
+}
+
+// NewFuncTool wraps fn as a tool. The string fn returns is sent to the
+// model; a non-nil error is reported as an error result.
+//
+// Example:
+//
+//	type WeatherArgs struct {
+//		Location string `json:"location" description:"City and state, e.g. San Francisco, CA"`
+//	}
+//
+//	tool, err := NewFuncTool("get_weather", "Get the current weather for a location",
+//		func(ctx context.Context, args WeatherArgs) (string, error) {
+//			return lookupWeather(ctx, args.Location)
+//		})
+func NewFuncTool[T any](name, description string, fn func(ctx context.Context, args T) (string, error)) (*FuncTool[T], error) {
+	return NewFuncToolWithResult(name, description, func(ctx context.Context, args T) *ToolResult {
+		out, err := fn(ctx, args)
+		if err != nil {
+			return ErrorResult(err.Error()).WithError(err)
+		}
+		return NewToolResult(out)
+	})
+}
+
+// NewFuncToolWithResult wraps fn as a tool that builds its own ToolResult,
+// e.g. to return user-facing or silent results.
+func NewFuncToolWithResult[T any](name, description string, fn func(ctx context.Context, args T) *ToolResult) (*FuncTool[T], error) {
+	var zero T
+	schema, err := providers.SchemaFor(zero)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	return &FuncTool[T]{
+		name:        name,
+		description: description,
+		schema:      schema,
+		fn:          fn,
+	}, nil
+}
+
+// RegisterFunc registers fn as a tool in r. See NewFuncTool.
+func RegisterFunc[T any](r *ToolRegistry, name, description string, fn func(ctx context.Context, args T) (string, error)) error {
+	tool, err := NewFuncTool(name, description, fn)
+	if err != nil {
+		return err
+	}
+	r.Register(tool)
+	return nil
+}
+
+func (t *FuncTool[T]) Name() string {
+	return t.name
+}
+
+func (t *FuncTool[T]) Description() string {
+	return t.description
+}
+
+func (t *FuncTool[T]) Parameters() map[string]interface{} {
+	return t.schema
+}
+
+func (t *FuncTool[T]) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	var typed T
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid arguments: %v", err))
+	}
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid arguments for %s: %v", t.name, err))
+	}
+	return t.fn(ctx, typed)
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type greetArgs struct {
+	Name  string `json:"name" description:"Who to greet"`
+	Times int    `json:"times,omitempty"`
+}
+
+func greet(ctx context.Context, args greetArgs) (string, error) {
+	if args.Name == "" {
+		return "", fmt.Errorf("name must not be empty")
+	}
+	times := args.Times
+	if times == 0 {
+		times = 1
+	}
+	return strings.Repeat("hello "+args.Name+" ", times), nil
+}
+
+func TestFuncTool_SchemaAndExecute(t *testing.T) {
+	registry := NewToolRegistry()
+	if err := RegisterFunc(registry, "greet", "Greet someone", greet); err != nil {
+		t.Fatalf("RegisterFunc() error = %v", err)
+	}
+
+	defs := registry.ToProviderDefs()
+	if len(defs) != 1 || defs[0].Function.Name != "greet" {
+		t.Fatalf("definitions = %+v, want greet", defs)
+	}
+	props := defs[0].Function.Parameters["properties"].(map[string]interface{})
+	if props["times"].(map[string]interface{})["type"] != "integer" {
+		t.Errorf("times schema = %v, want integer", props["times"])
+	}
+
+	result := registry.Execute(context.Background(), "greet", map[string]interface{}{
+		"name":  "pico",
+		"times": float64(2),
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if result.ForLLM != "hello pico hello pico " {
+		t.Errorf("Expected greeting twice, got: %q", result.ForLLM)
+	}
+}
+
+func TestFuncTool_Errors(t *testing.T) {
+	tool, err := NewFuncTool("greet", "Greet someone", greet)
+	if err != nil {
+		t.Fatalf("NewFuncTool() error = %v", err)
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"name": ""})
+	if !result.IsError || result.Err == nil {
+		t.Errorf("Expected function error to be reported, got: %+v", result)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"name": 42})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid arguments") {
+		t.Errorf("Expected invalid arguments error, got: %s", result.ForLLM)
+	}
+
+	if _, err := NewFuncTool("bad", "Not a struct", func(ctx context.Context, args string) (string, error) {
+		return args, nil
+	}); err == nil {
+		t.Error("Expected non-struct argument type to be rejected")
+	}
+}
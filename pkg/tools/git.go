@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const (
+	gitTimeout   = 60 * time.Second
+	gitMaxOutput = 20000
+)
+
+// NewGitTools returns the git tools enabled by cfg for repositories in
+// workspace: git_status and git_diff, plus git_commit and git_branch when
+// cfg allows writes.
+func NewGitTools(workspace string, restrict bool, cfg config.GitToolsConfig) []Tool {
+	if !cfg.Enabled {
+		return nil
+	}
+	ws := NewWorkspace(workspace, restrict)
+	tools := []Tool{&GitStatusTool{workspace: ws}, &GitDiffTool{workspace: ws}}
+	if cfg.Write {
+		tools = append(tools, &GitCommitTool{workspace: ws}, &GitBranchTool{workspace: ws})
+	}
+	return tools
+}
+
+// gitRepoParameter is the optional repository argument every git tool takes.
+var gitRepoParameter = map[string]interface{}{
+	"type":        "string",
+	"description": "Optional repository directory, relative to the workspace (default: workspace)",
+}
+
+// runGit runs git with args in the repository named by the "repo" argument
+// and returns its combined output, limited for the model.
+func runGit(ctx context.Context, ws *Workspace, tool string, args map[string]interface{}, gitArgs ...string) (string, error) {
+	repo, _ := args["repo"].(string)
+	if repo == "" {
+		repo = "."
+	}
+	dir, err := ws.Resolve(repo)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--no-pager"}, gitArgs...)...)
+	cmd.Dir = dir
+	// Never wait for credentials or an editor
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_EDITOR=true")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+
+	output := strings.TrimRight(out.String(), "\n")
+	if err != nil {
+		if output == "" {
+			return "", fmt.Errorf("git %s failed: %w", gitArgs[0], err)
+		}
+		return "", fmt.Errorf("git %s failed: %s", gitArgs[0], output)
+	}
+	return LimitOutput(ctx, tool, output, gitMaxOutput), nil
+}
+
+// gitPaths resolves the "paths" argument against the workspace, so the
+// tools cannot name files outside it.
+func gitPaths(ws *Workspace, args map[string]interface{}) ([]string, error) {
+	raw, _ := args["paths"].([]interface{})
+	paths := make([]string, 0, len(raw))
+	for _, p := range raw {
+		s, ok := p.(string)
+		if !ok || s == "" {
+			continue
+		}
+		resolved, err := ws.Resolve(s)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, resolved)
+	}
+	return paths, nil
+}
+
+// gitRevision checks that a ref or branch name from the model cannot be
+// taken for an option.
+func gitRevision(name string) error {
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid revision %q", name)
+	}
+	return nil
+}
+
+// GitStatusTool shows the working tree status.
+type GitStatusTool struct {
+	workspace *Workspace
+}
+
+func (t *GitStatusTool) Name() string {
+	return "git_status"
+}
+
+func (t *GitStatusTool) Description() string {
+	return "Show the current branch and the changed, staged and untracked files of a git repository."
+}
+
+func (t *GitStatusTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": gitRepoParameter,
+		},
+	}
+}
+
+func (t *GitStatusTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	output, err := runGit(ctx, t.workspace, t.Name(), args, "status", "--short", "--branch")
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(output)
+}
+
+// GitDiffTool shows unstaged, staged or committed changes.
+type GitDiffTool struct {
+	workspace *Workspace
+}
+
+func (t *GitDiffTool) Name() string {
+	return "git_diff"
+}
+
+func (t *GitDiffTool) Description() string {
+	return "Show changes in a git repository as a unified diff: unstaged changes by default, staged ones with staged, or changes since a revision."
+}
+
+func (t *GitDiffTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": gitRepoParameter,
+			"staged": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Show staged changes instead of unstaged ones",
+			},
+			"revision": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional revision to compare against, e.g. HEAD~1 or main",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional files or directories to limit the diff to",
+			},
+			"stat": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Only summarize changed files and line counts",
+			},
+		},
+	}
+}
+
+func (t *GitDiffTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	gitArgs := []string{"diff"}
+	if staged, _ := args["staged"].(bool); staged {
+		gitArgs = append(gitArgs, "--cached")
+	}
+	if stat, _ := args["stat"].(bool); stat {
+		gitArgs = append(gitArgs, "--stat")
+	}
+	if revision, _ := args["revision"].(string); revision != "" {
+		if err := gitRevision(revision); err != nil {
+			return ErrorResult(err.Error())
+		}
+		gitArgs = append(gitArgs, revision)
+	}
+	paths, err := gitPaths(t.workspace, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	gitArgs = append(append(gitArgs, "--"), paths...)
+
+	output, err := runGit(ctx, t.workspace, t.Name(), args, gitArgs...)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if output == "" {
+		output = "No changes"
+	}
+	return NewToolResult(output)
+}
+
+// GitCommitTool stages files and commits them.
+type GitCommitTool struct {
+	workspace *Workspace
+}
+
+func (t *GitCommitTool) Name() string {
+	return "git_commit"
+}
+
+func (t *GitCommitTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *GitCommitTool) RequiresApproval() bool {
+	return true
+}
+
+func (t *GitCommitTool) Description() string {
+	return "Commit changes to a git repository. Stages the given paths first, or every tracked change with all; otherwise commits what is already staged."
+}
+
+func (t *GitCommitTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": gitRepoParameter,
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "The commit message",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional files or directories to stage before committing, including new files",
+			},
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Stage all changes to tracked files before committing",
+			},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *GitCommitTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return ErrorResult("message is required")
+	}
+	paths, err := gitPaths(t.workspace, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if len(paths) > 0 {
+		if _, err := runGit(ctx, t.workspace, t.Name(), args, append([]string{"add", "--"}, paths...)...); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+
+	gitArgs := []string{"commit", "--message", message}
+	if all, _ := args["all"].(bool); all {
+		gitArgs = append(gitArgs, "--all")
+	}
+	output, err := runGit(ctx, t.workspace, t.Name(), args, gitArgs...)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(output)
+}
+
+// GitBranchTool creates and switches branches.
+type GitBranchTool struct {
+	workspace *Workspace
+}
+
+func (t *GitBranchTool) Name() string {
+	return "git_branch"
+}
+
+func (t *GitBranchTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *GitBranchTool) RequiresApproval() bool {
+	return true
+}
+
+func (t *GitBranchTool) Description() string {
+	return "Switch a git repository to a branch, creating it first with create. Uncommitted changes are carried over."
+}
+
+func (t *GitBranchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"repo": gitRepoParameter,
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "The branch to switch to",
+			},
+			"create": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Create the branch",
+			},
+			"start_point": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional revision a new branch starts at (default: HEAD)",
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+func (t *GitBranchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return ErrorResult("name is required")
+	}
+	if err := gitRevision(name); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	gitArgs := []string{"switch"}
+	if create, _ := args["create"].(bool); create {
+		gitArgs = append(gitArgs, "--create", name)
+		if start, _ := args["start_point"].(string); start != "" {
+			if err := gitRevision(start); err != nil {
+				return ErrorResult(err.Error())
+			}
+			gitArgs = append(gitArgs, start)
+		}
+	} else {
+		gitArgs = append(gitArgs, name)
+	}
+
+	if _, err := runGit(ctx, t.workspace, t.Name(), args, gitArgs...); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(fmt.Sprintf("Switched to branch %s", name))
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// newGitRepo initializes a repository with one commit in a temp dir.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"add", "README.md"},
+		{"commit", "--quiet", "--message", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func gitToolsByName(workspace string, cfg config.GitToolsConfig) map[string]Tool {
+	byName := make(map[string]Tool)
+	for _, tool := range NewGitTools(workspace, true, cfg) {
+		byName[tool.Name()] = tool
+	}
+	return byName
+}
+
+func TestNewGitTools_ReadOnlyByDefault(t *testing.T) {
+	tools := gitToolsByName(t.TempDir(), config.GitToolsConfig{Enabled: true})
+	if len(tools) != 2 || tools["git_status"] == nil || tools["git_diff"] == nil {
+		t.Errorf("tools = %v, want git_status and git_diff", tools)
+	}
+	if tools := NewGitTools(t.TempDir(), true, config.GitToolsConfig{}); len(tools) != 0 {
+		t.Errorf("disabled git tools = %v", tools)
+	}
+	writers := gitToolsByName(t.TempDir(), config.GitToolsConfig{Enabled: true, Write: true})
+	for _, name := range []string{"git_commit", "git_branch"} {
+		if ct, ok := writers[name].(ConfirmableTool); !ok || !ct.RequiresApproval() {
+			t.Errorf("%s does not require approval", name)
+		}
+	}
+}
+
+func TestGitTools_StatusDiffCommit(t *testing.T) {
+	repo := newGitRepo(t)
+	tools := gitToolsByName(repo, config.GitToolsConfig{Enabled: true, Write: true})
+	ctx := context.Background()
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello world\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0644)
+
+	result := tools["git_status"].Execute(ctx, nil)
+	if result.IsError || !strings.Contains(result.ForLLM, "## main") || !strings.Contains(result.ForLLM, "?? new.txt") {
+		t.Errorf("git_status = %q", result.ForLLM)
+	}
+	result = tools["git_diff"].Execute(ctx, map[string]interface{}{"paths": []interface{}{"README.md"}})
+	if result.IsError || !strings.Contains(result.ForLLM, "+hello world") {
+		t.Errorf("git_diff = %q", result.ForLLM)
+	}
+
+	result = tools["git_branch"].Execute(ctx, map[string]interface{}{"name": "feature", "create": true})
+	if result.IsError {
+		t.Fatalf("git_branch: %s", result.ForLLM)
+	}
+	result = tools["git_commit"].Execute(ctx, map[string]interface{}{
+		"message": "Add new.txt",
+		"paths":   []interface{}{"new.txt"},
+		"all":     true,
+	})
+	if result.IsError {
+		t.Fatalf("git_commit: %s", result.ForLLM)
+	}
+
+	result = tools["git_status"].Execute(ctx, nil)
+	if !strings.HasPrefix(result.ForLLM, "## feature") || strings.Contains(result.ForLLM, "new.txt") {
+		t.Errorf("git_status after commit = %q", result.ForLLM)
+	}
+	result = tools["git_diff"].Execute(ctx, map[string]interface{}{"revision": "main", "stat": true})
+	if !strings.Contains(result.ForLLM, "2 files changed") {
+		t.Errorf("git_diff against main = %q", result.ForLLM)
+	}
+}
+
+func TestGitTools_RejectsEscapes(t *testing.T) {
+	repo := newGitRepo(t)
+	tools := gitToolsByName(repo, config.GitToolsConfig{Enabled: true, Write: true})
+	ctx := context.Background()
+
+	calls := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"git_status", map[string]interface{}{"repo": ".."}},
+		{"git_diff", map[string]interface{}{"paths": []interface{}{"../etc/passwd"}}},
+		{"git_diff", map[string]interface{}{"revision": "--output=/tmp/x"}},
+		{"git_branch", map[string]interface{}{"name": "-D"}},
+		{"git_commit", map[string]interface{}{"message": " "}},
+	}
+	for _, c := range calls {
+		if result := tools[c.tool].Execute(ctx, c.args); !result.IsError {
+			t.Errorf("%s %v succeeded: %s", c.tool, c.args, result.ForLLM)
+		}
+	}
+}
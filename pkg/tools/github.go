@@ -0,0 +1,545 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultGitHubAPI = "https://api.github.com"
+	githubMaxOutput  = 20000
+	githubListLimit  = 30
+)
+
+// NewGitHubTools returns the GitHub tools enabled by cfg: github, plus
+// github_write when cfg allows writes. The token is read from the
+// credential store on each call, so logging in takes effect without a
+// restart.
+func NewGitHubTools(cfg config.GitHubConfig) []Tool {
+	if !cfg.Enabled {
+		return nil
+	}
+	client := &githubClient{
+		apiBase: strings.TrimRight(cfg.APIBase, "/"),
+		repo:    cfg.Repo,
+		token:   func() (string, error) { return githubToken(cfg.Account) },
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if client.apiBase == "" {
+		client.apiBase = defaultGitHubAPI
+	}
+	tools := []Tool{&GitHubTool{client: client}}
+	if cfg.Write {
+		tools = append(tools, &GitHubWriteTool{client: client})
+	}
+	return tools
+}
+
+// githubToken returns the token stored for the github provider.
+func githubToken(account string) (string, error) {
+	cred, err := auth.GetCredential("github", account)
+	if err != nil {
+		return "", fmt.Errorf("loading GitHub token: %w", err)
+	}
+	if cred == nil || cred.AccessToken == "" {
+		return "", errors.New("no GitHub token stored; run: picoclaw auth login --provider github")
+	}
+	logger.AddSecret(cred.AccessToken)
+	return cred.AccessToken, nil
+}
+
+// githubClient makes GitHub REST API calls for the github tools.
+type githubClient struct {
+	apiBase string
+	repo    string // default owner/name
+	token   func() (string, error)
+	http    *http.Client
+}
+
+// repoPath returns "/repos/owner/name" for the "repo" argument, or the
+// configured default.
+func (c *githubClient) repoPath(args map[string]interface{}) (string, error) {
+	repo, _ := args["repo"].(string)
+	if repo == "" {
+		repo = c.repo
+	}
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", errors.New("repo is required as owner/name")
+	}
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name), nil
+}
+
+// do sends a request to path and decodes the JSON response into out.
+func (c *githubClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.apiBase+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "picoclaw")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("reading GitHub response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("GitHub API error (HTTP %d)", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubIssue struct {
+	Number      int          `json:"number"`
+	Title       string       `json:"title"`
+	State       string       `json:"state"`
+	Body        string       `json:"body"`
+	User        githubUser   `json:"user"`
+	HTMLURL     string       `json:"html_url"`
+	Labels      []githubName `json:"labels"`
+	PullRequest *struct{}    `json:"pull_request,omitempty"`
+}
+
+type githubName struct {
+	Name string `json:"name"`
+}
+
+type githubPull struct {
+	githubIssue
+	Draft  bool `json:"draft"`
+	Merged bool `json:"merged"`
+	Head   struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Mergeable *bool `json:"mergeable"`
+}
+
+type githubComment struct {
+	ID        int64      `json:"id"`
+	User      githubUser `json:"user"`
+	Body      string     `json:"body"`
+	Path      string     `json:"path"`
+	Line      int        `json:"line"`
+	InReplyTo int64      `json:"in_reply_to_id"`
+	State     string     `json:"state"` // reviews only
+}
+
+// githubNumberArg returns the issue or pull request number in args.
+func githubNumberArg(args map[string]interface{}) (int, error) {
+	n, ok := args["number"].(float64)
+	if !ok || n < 1 {
+		return 0, errors.New("number is required")
+	}
+	return int(n), nil
+}
+
+// githubRepoParameter is the optional repository argument both tools take.
+var githubRepoParameter = map[string]interface{}{
+	"type":        "string",
+	"description": "Repository as owner/name (default: the configured repository)",
+}
+
+// GitHubTool reads issues, pull requests, reviews and checks.
+type GitHubTool struct {
+	client *githubClient
+}
+
+func (t *GitHubTool) Name() string {
+	return "github"
+}
+
+func (t *GitHubTool) Description() string {
+	return "Read GitHub issues, pull requests, their review comments and CI check results."
+}
+
+func (t *GitHubTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list_issues", "get_issue", "list_prs", "get_pr", "list_reviews", "checks"},
+				"description": "list_issues/list_prs list open items; get_issue and get_pr show one with its comments; list_reviews shows a pull request's reviews and review comments with their IDs; checks shows its CI results",
+			},
+			"repo": githubRepoParameter,
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Issue or pull request number, for the get, list_reviews and checks actions",
+			},
+			"state": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"open", "closed", "all"},
+				"description": "Which issues or pull requests to list (default: open)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GitHubTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	repo, err := t.client.repoPath(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	action, _ := args["action"].(string)
+
+	var output string
+	switch action {
+	case "list_issues", "list_prs":
+		output, err = t.list(ctx, repo, action == "list_prs", args)
+	case "get_issue", "get_pr":
+		output, err = t.get(ctx, repo, action == "get_pr", args)
+	case "list_reviews":
+		output, err = t.reviews(ctx, repo, args)
+	case "checks":
+		output, err = t.checks(ctx, repo, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(LimitOutput(ctx, t.Name(), output, githubMaxOutput))
+}
+
+func (t *GitHubTool) list(ctx context.Context, repo string, pulls bool, args map[string]interface{}) (string, error) {
+	state, _ := args["state"].(string)
+	if state == "" {
+		state = "open"
+	}
+	query := url.Values{"state": {state}, "per_page": {fmt.Sprint(githubListLimit)}}
+	endpoint := "/issues"
+	if pulls {
+		endpoint = "/pulls"
+	}
+	var items []githubPull
+	if err := t.client.do(ctx, http.MethodGet, repo+endpoint+"?"+query.Encode(), nil, &items); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, item := range items {
+		// The issues endpoint lists pull requests too
+		if !pulls && item.PullRequest != nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "#%d [%s] %s (@%s)", item.Number, item.State, item.Title, item.User.Login)
+		if pulls {
+			fmt.Fprintf(&sb, " %s -> %s", item.Head.Ref, item.Base.Ref)
+			if item.Draft {
+				sb.WriteString(" draft")
+			}
+		}
+		for _, label := range item.Labels {
+			fmt.Fprintf(&sb, " [%s]", label.Name)
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return "None found", nil
+	}
+	return sb.String(), nil
+}
+
+func (t *GitHubTool) get(ctx context.Context, repo string, pull bool, args map[string]interface{}) (string, error) {
+	number, err := githubNumberArg(args)
+	if err != nil {
+		return "", err
+	}
+	var item githubPull
+	endpoint := fmt.Sprintf("%s/issues/%d", repo, number)
+	if pull {
+		endpoint = fmt.Sprintf("%s/pulls/%d", repo, number)
+	}
+	if err := t.client.do(ctx, http.MethodGet, endpoint, nil, &item); err != nil {
+		return "", err
+	}
+	var comments []githubComment
+	if err := t.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d/comments?per_page=100", repo, number), nil, &comments); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#%d %s\nState: %s\nAuthor: @%s\nURL: %s\n", item.Number, item.Title, item.State, item.User.Login, item.HTMLURL)
+	if pull {
+		fmt.Fprintf(&sb, "Branch: %s -> %s\nHead: %s\n", item.Head.Ref, item.Base.Ref, item.Head.SHA)
+		switch {
+		case item.Merged:
+			sb.WriteString("Merged: yes\n")
+		case item.Mergeable != nil:
+			fmt.Fprintf(&sb, "Mergeable: %v\n", *item.Mergeable)
+		}
+	}
+	fmt.Fprintf(&sb, "\n%s\n", item.Body)
+	for _, c := range comments {
+		fmt.Fprintf(&sb, "\n--- comment %d by @%s\n%s\n", c.ID, c.User.Login, c.Body)
+	}
+	return sb.String(), nil
+}
+
+func (t *GitHubTool) reviews(ctx context.Context, repo string, args map[string]interface{}) (string, error) {
+	number, err := githubNumberArg(args)
+	if err != nil {
+		return "", err
+	}
+	var reviews, comments []githubComment
+	if err := t.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d/reviews?per_page=100", repo, number), nil, &reviews); err != nil {
+		return "", err
+	}
+	if err := t.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d/comments?per_page=100", repo, number), nil, &comments); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, r := range reviews {
+		fmt.Fprintf(&sb, "Review %d by @%s: %s\n", r.ID, r.User.Login, r.State)
+		if r.Body != "" {
+			fmt.Fprintf(&sb, "%s\n", r.Body)
+		}
+	}
+	for _, c := range comments {
+		fmt.Fprintf(&sb, "\n--- review comment %d by @%s on %s:%d", c.ID, c.User.Login, c.Path, c.Line)
+		if c.InReplyTo != 0 {
+			fmt.Fprintf(&sb, " (reply to %d)", c.InReplyTo)
+		}
+		fmt.Fprintf(&sb, "\n%s\n", c.Body)
+	}
+	if sb.Len() == 0 {
+		return "No reviews", nil
+	}
+	return sb.String(), nil
+}
+
+func (t *GitHubTool) checks(ctx context.Context, repo string, args map[string]interface{}) (string, error) {
+	number, err := githubNumberArg(args)
+	if err != nil {
+		return "", err
+	}
+	var pull githubPull
+	if err := t.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repo, number), nil, &pull); err != nil {
+		return "", err
+	}
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := t.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/commits/%s/check-runs?per_page=100", repo, pull.Head.SHA), nil, &runs); err != nil {
+		return "", err
+	}
+
+	if len(runs.CheckRuns) == 0 {
+		return fmt.Sprintf("No checks for %s", pull.Head.SHA), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Checks for %s:\n", pull.Head.SHA)
+	for _, run := range runs.CheckRuns {
+		result := run.Conclusion
+		if result == "" {
+			result = run.Status
+		}
+		fmt.Fprintf(&sb, "%s: %s %s\n", run.Name, result, run.HTMLURL)
+	}
+	return sb.String(), nil
+}
+
+// GitHubWriteTool opens issues and pull requests, comments and reviews.
+type GitHubWriteTool struct {
+	client *githubClient
+}
+
+func (t *GitHubWriteTool) Name() string {
+	return "github_write"
+}
+
+func (t *GitHubWriteTool) RequiresApproval() bool {
+	return true
+}
+
+func (t *GitHubWriteTool) Description() string {
+	return "Change GitHub: open issues and pull requests, comment on them, reply to review comments and submit reviews."
+}
+
+func (t *GitHubWriteTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"create_issue", "create_pr", "comment", "reply_review_comment", "review"},
+				"description": "create_issue and create_pr open one; comment adds a comment to an issue or pull request; reply_review_comment answers a review comment; review submits a review",
+			},
+			"repo": githubRepoParameter,
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Issue or pull request number, for comment, reply_review_comment and review",
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Title, for create_issue and create_pr",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown text of the issue, pull request, comment or review",
+			},
+			"head": map[string]interface{}{
+				"type":        "string",
+				"description": "Branch with the changes, for create_pr (push it first)",
+			},
+			"base": map[string]interface{}{
+				"type":        "string",
+				"description": "Branch to merge into, for create_pr",
+			},
+			"draft": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Open the pull request as a draft",
+			},
+			"comment_id": map[string]interface{}{
+				"type":        "integer",
+				"description": "Review comment to reply to, for reply_review_comment",
+			},
+			"event": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"COMMENT", "APPROVE", "REQUEST_CHANGES"},
+				"description": "Review verdict, for review (default: COMMENT)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GitHubWriteTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	repo, err := t.client.repoPath(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	action, _ := args["action"].(string)
+	title, _ := args["title"].(string)
+	body, _ := args["body"].(string)
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	switch action {
+	case "create_issue":
+		if title == "" {
+			return ErrorResult("title is required")
+		}
+		err = t.client.do(ctx, http.MethodPost, repo+"/issues",
+			map[string]interface{}{"title": title, "body": body}, &created)
+		if err == nil {
+			return NewToolResult(fmt.Sprintf("Opened issue #%d: %s", created.Number, created.HTMLURL))
+		}
+	case "create_pr":
+		head, _ := args["head"].(string)
+		base, _ := args["base"].(string)
+		if title == "" || head == "" || base == "" {
+			return ErrorResult("title, head and base are required")
+		}
+		draft, _ := args["draft"].(bool)
+		err = t.client.do(ctx, http.MethodPost, repo+"/pulls",
+			map[string]interface{}{"title": title, "body": body, "head": head, "base": base, "draft": draft}, &created)
+		if err == nil {
+			return NewToolResult(fmt.Sprintf("Opened pull request #%d: %s", created.Number, created.HTMLURL))
+		}
+	case "comment", "reply_review_comment", "review":
+		return t.respond(ctx, repo, action, body, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+	return ErrorResult(err.Error())
+}
+
+// respond posts a comment, a reply to a review comment or a review.
+func (t *GitHubWriteTool) respond(ctx context.Context, repo, action, body string, args map[string]interface{}) *ToolResult {
+	number, err := githubNumberArg(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var endpoint string
+	payload := map[string]interface{}{"body": body}
+	switch action {
+	case "comment":
+		endpoint = fmt.Sprintf("%s/issues/%d/comments", repo, number)
+	case "reply_review_comment":
+		id, ok := args["comment_id"].(float64)
+		if !ok || id < 1 {
+			return ErrorResult("comment_id is required")
+		}
+		endpoint = fmt.Sprintf("%s/pulls/%d/comments/%d/replies", repo, number, int64(id))
+	case "review":
+		event, _ := args["event"].(string)
+		if event == "" {
+			event = "COMMENT"
+		}
+		payload["event"] = event
+		endpoint = fmt.Sprintf("%s/pulls/%d/reviews", repo, number)
+	}
+	if body == "" && action != "review" {
+		return ErrorResult("body is required")
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := t.client.do(ctx, http.MethodPost, endpoint, payload, &created); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(fmt.Sprintf("Posted: %s", created.HTMLURL))
+}
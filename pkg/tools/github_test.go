@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// newGitHubTestClient serves the given paths, keyed by "METHOD /path",
+// recording request bodies in posted.
+func newGitHubTestClient(t *testing.T, routes map[string]string, posted map[string]map[string]interface{}) *githubClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		key := r.Method + " " + r.URL.Path
+		body, ok := routes[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		if r.Method == http.MethodPost && posted != nil {
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			posted[key] = payload
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return &githubClient{
+		apiBase: srv.URL,
+		repo:    "acme/app",
+		token:   func() (string, error) { return "ghp_test", nil },
+		http:    srv.Client(),
+	}
+}
+
+func TestGitHubTool_Read(t *testing.T) {
+	client := newGitHubTestClient(t, map[string]string{
+		"GET /repos/acme/app/issues": `[
+			{"number": 1, "title": "Crash on start", "state": "open", "user": {"login": "ann"}, "labels": [{"name": "bug"}]},
+			{"number": 2, "title": "Fix crash", "state": "open", "user": {"login": "bob"}, "pull_request": {}}
+		]`,
+		"GET /repos/acme/app/pulls/2":                   `{"number": 2, "title": "Fix crash", "head": {"ref": "fix", "sha": "abc123"}, "base": {"ref": "main"}}`,
+		"GET /repos/acme/app/commits/abc123/check-runs": `{"check_runs": [{"name": "test", "status": "completed", "conclusion": "failure"}, {"name": "lint", "status": "in_progress"}]}`,
+		"GET /repos/acme/app/pulls/2/reviews":           `[{"id": 7, "user": {"login": "ann"}, "state": "CHANGES_REQUESTED", "body": "Needs a test"}]`,
+		"GET /repos/acme/app/pulls/2/comments":          `[{"id": 9, "user": {"login": "ann"}, "path": "main.go", "line": 12, "body": "Handle nil here"}]`,
+	}, nil)
+	tool := &GitHubTool{client: client}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "list_issues"})
+	if result.IsError || !strings.Contains(result.ForLLM, "#1 [open] Crash on start (@ann) [bug]") || strings.Contains(result.ForLLM, "#2") {
+		t.Errorf("list_issues = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "checks", "number": float64(2)})
+	if result.IsError || !strings.Contains(result.ForLLM, "test: failure") || !strings.Contains(result.ForLLM, "lint: in_progress") {
+		t.Errorf("checks = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "list_reviews", "number": float64(2)})
+	if result.IsError || !strings.Contains(result.ForLLM, "CHANGES_REQUESTED") || !strings.Contains(result.ForLLM, "review comment 9 by @ann on main.go:12") {
+		t.Errorf("list_reviews = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "get_issue", "number": float64(5)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "HTTP 404") {
+		t.Errorf("missing issue = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "list_prs", "repo": "not-a-repo"})
+	if !result.IsError {
+		t.Errorf("invalid repo accepted: %q", result.ForLLM)
+	}
+}
+
+func TestGitHubWriteTool(t *testing.T) {
+	posted := make(map[string]map[string]interface{})
+	client := newGitHubTestClient(t, map[string]string{
+		"POST /repos/acme/app/pulls":                      `{"number": 3, "html_url": "https://github.com/acme/app/pull/3"}`,
+		"POST /repos/acme/app/pulls/3/comments/9/replies": `{"html_url": "https://github.com/acme/app/pull/3#r10"}`,
+	}, posted)
+	tool := &GitHubWriteTool{client: client}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"action": "create_pr", "title": "Fix crash", "head": "fix", "base": "main", "body": "Fixes #1",
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "#3") {
+		t.Errorf("create_pr = %q", result.ForLLM)
+	}
+	if got := posted["POST /repos/acme/app/pulls"]; got["head"] != "fix" || got["body"] != "Fixes #1" {
+		t.Errorf("create_pr payload = %v", got)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"action": "reply_review_comment", "number": float64(3), "comment_id": float64(9), "body": "Done",
+	})
+	if result.IsError || posted["POST /repos/acme/app/pulls/3/comments/9/replies"]["body"] != "Done" {
+		t.Errorf("reply_review_comment = %q", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "comment", "number": float64(3)}); !result.IsError {
+		t.Error("comment without a body was accepted")
+	}
+	if !tool.RequiresApproval() {
+		t.Error("github_write does not require approval")
+	}
+}
+
+func TestGitHubTool_NoToken(t *testing.T) {
+	tool := &GitHubTool{client: &githubClient{
+		apiBase: "http://127.0.0.1:0",
+		repo:    "acme/app",
+		token:   func() (string, error) { return "", errors.New("no GitHub token stored") },
+		http:    http.DefaultClient,
+	}}
+	result := tool.Execute(context.Background(), map[string]interface{}{"action": "list_prs"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "no GitHub token") {
+		t.Errorf("result = %q", result.ForLLM)
+	}
+}
+
+func TestNewGitHubTools(t *testing.T) {
+	if tools := NewGitHubTools(config.GitHubConfig{}); len(tools) != 0 {
+		t.Errorf("disabled github tools = %v", tools)
+	}
+	if tools := NewGitHubTools(config.GitHubConfig{Enabled: true}); len(tools) != 1 || tools[0].Name() != "github" {
+		t.Errorf("read-only github tools = %v", tools)
+	}
+	if tools := NewGitHubTools(config.GitHubConfig{Enabled: true, Write: true}); len(tools) != 2 {
+		t.Errorf("github tools with writes = %v", tools)
+	}
+}
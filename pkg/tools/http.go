@@ -0,0 +1,317 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// httpMaxBody caps how much of a response is read.
+const httpMaxBody = 1 << 20
+
+// httpProfile is a configured profile with its credentials expanded.
+type httpProfile struct {
+	baseURL *url.URL
+	domains []string
+	methods map[string]bool
+	headers map[string]string
+	query   map[string]string
+}
+
+// HTTPRequestTool calls APIs through the profiles configured in tools.http.
+// Requests may only go to a profile's allowed domains, redirects included,
+// and the profile's headers and query parameters are added to each one.
+type HTTPRequestTool struct {
+	profiles  map[string]*httpProfile
+	timeout   time.Duration
+	maxOutput int
+	approval  bool
+	client    *http.Client
+}
+
+// NewHTTPRequestToolFromConfig returns the http_request tool described by
+// cfg, or nil when it is disabled or names no profiles.
+func NewHTTPRequestToolFromConfig(cfg config.HTTPToolsConfig) (*HTTPRequestTool, error) {
+	if !cfg.Enabled || len(cfg.Profiles) == 0 {
+		return nil, nil
+	}
+	t := &HTTPRequestTool{
+		profiles:  make(map[string]*httpProfile, len(cfg.Profiles)),
+		timeout:   30 * time.Second,
+		maxOutput: 20000,
+	}
+	if cfg.Timeout > 0 {
+		t.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.MaxOutput > 0 {
+		t.maxOutput = cfg.MaxOutput
+	}
+
+	for name, pc := range cfg.Profiles {
+		p := &httpProfile{
+			methods: map[string]bool{},
+			headers: expandValues(pc.Headers),
+			query:   expandValues(pc.Query),
+		}
+		if pc.BaseURL != "" {
+			base, err := url.Parse(pc.BaseURL)
+			if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+				return nil, fmt.Errorf("http profile %q: invalid base_url %q", name, pc.BaseURL)
+			}
+			p.baseURL = base
+		}
+		for _, d := range pc.AllowedDomains {
+			p.domains = append(p.domains, strings.ToLower(strings.TrimSpace(d)))
+		}
+		if len(p.domains) == 0 {
+			if p.baseURL == nil {
+				return nil, fmt.Errorf("http profile %q needs allowed_domains or a base_url", name)
+			}
+			p.domains = []string{strings.ToLower(p.baseURL.Hostname())}
+		}
+		methods := pc.Methods
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet, http.MethodHead}
+		}
+		for _, m := range methods {
+			m = strings.ToUpper(m)
+			p.methods[m] = true
+			if m != http.MethodGet && m != http.MethodHead && m != http.MethodOptions {
+				t.approval = true
+			}
+		}
+		t.profiles[name] = p
+	}
+
+	t.client = &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			p, _ := req.Context().Value(httpProfileKey{}).(*httpProfile)
+			if p == nil || !p.allows(req.URL) {
+				return fmt.Errorf("redirect to %s is outside the allowed domains", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return t, nil
+}
+
+// httpProfileKey carries the request's profile to CheckRedirect.
+type httpProfileKey struct{}
+
+func expandValues(values map[string]string) map[string]string {
+	expanded := make(map[string]string, len(values))
+	for k, v := range values {
+		expanded[k] = os.ExpandEnv(v)
+	}
+	return expanded
+}
+
+// allows reports whether u is an http(s) URL on one of the profile's
+// domains.
+func (p *httpProfile) allows(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range p.domains {
+		if suffix, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == d {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve parses rawURL, taking a relative one to be a path under the
+// profile's base URL.
+func (p *httpProfile) resolve(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		return nil, fmt.Errorf("invalid url %q", rawURL)
+	}
+	if !u.IsAbs() && p.baseURL != nil {
+		rel := u
+		u = p.baseURL.JoinPath(rel.Path)
+		u.RawQuery = rel.RawQuery
+	}
+	return u, nil
+}
+
+// resolveURL returns the URL a call would request, for the policy's
+// allowed hosts to be checked against.
+func (t *HTTPRequestTool) resolveURL(args map[string]interface{}) string {
+	rawURL, _ := args["url"].(string)
+	name, _ := args["profile"].(string)
+	if p, ok := t.profiles[name]; ok {
+		if u, err := p.resolve(rawURL); err == nil {
+			return u.String()
+		}
+	}
+	return rawURL
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+// RequiresApproval is true when a profile allows methods that can change
+// things, such as POST or DELETE.
+func (t *HTTPRequestTool) RequiresApproval() bool {
+	return t.approval
+}
+
+func (t *HTTPRequestTool) Timeout() time.Duration {
+	return t.timeout + 10*time.Second
+}
+
+func (t *HTTPRequestTool) Description() string {
+	return "Make an HTTP request to an API through a configured profile, which sets the allowed hosts and adds credentials. Profiles: " +
+		strings.Join(t.profileNames(), ", ")
+}
+
+func (t *HTTPRequestTool) profileNames() []string {
+	names := make([]string, 0, len(t.profiles))
+	for name := range t.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"profile": map[string]interface{}{
+				"type":        "string",
+				"enum":        t.profileNames(),
+				"description": "The configured profile to use",
+			},
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "The URL, or a path relative to the profile's base URL",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"description": "The HTTP method (default: GET)",
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Optional request headers",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional request body, e.g. JSON",
+			},
+		},
+		"required": []string{"profile", "url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	name, _ := args["profile"].(string)
+	p, ok := t.profiles[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("unknown profile %q; configured profiles: %s", name, strings.Join(t.profileNames(), ", ")))
+	}
+
+	rawURL, _ := args["url"].(string)
+	u, err := p.resolve(rawURL)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if !p.allows(u) {
+		return ErrorResult(fmt.Sprintf("%s is not in the allowed domains of profile %s", u.Redacted(), name))
+	}
+
+	method, _ := args["method"].(string)
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !p.methods[method] {
+		return ErrorResult(fmt.Sprintf("method %s is not allowed for profile %s", method, name))
+	}
+
+	if len(p.query) > 0 {
+		q := u.Query()
+		for k, v := range p.query {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	if b, _ := args["body"].(string); b != "" {
+		body = strings.NewReader(b)
+	}
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, httpProfileKey{}, p), t.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+	}
+	if headers, ok := args["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			if s, ok := v.(string); ok && !strings.EqualFold(k, "Host") {
+				req.Header.Set(k, s)
+			}
+		}
+	}
+	// The profile's headers win over the model's
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrorResult(fmt.Sprintf("Request timed out after %v", t.timeout))
+		}
+		// The URL in the error may carry injected query credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return ErrorResult(fmt.Sprintf("request failed: %v", err))
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxBody+1))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %s\n", resp.Status)
+	for _, h := range []string{"Content-Type", "Location", "Retry-After"} {
+		if v := resp.Header.Get(h); v != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", h, v)
+		}
+	}
+	sb.WriteString("\n")
+	if len(data) > httpMaxBody {
+		sb.Write(data[:httpMaxBody])
+		sb.WriteString("\n... (response body truncated at 1 MB)")
+	} else {
+		sb.Write(data)
+	}
+
+	output := LimitOutput(ctx, t.Name(), sb.String(), t.maxOutput)
+	return &ToolResult{ForLLM: output, ForUser: output, IsError: resp.StatusCode >= 400}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestHTTPTool(t *testing.T, handler http.HandlerFunc, profile config.HTTPProfileConfig) *HTTPRequestTool {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	profile.BaseURL = server.URL + "/api"
+	tool, err := NewHTTPRequestToolFromConfig(config.HTTPToolsConfig{
+		Enabled:  true,
+		Profiles: map[string]config.HTTPProfileConfig{"internal": profile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tool
+}
+
+func TestHTTPRequestTool_InjectsCredentials(t *testing.T) {
+	t.Setenv("TEST_HTTP_TOKEN", "s3cret-token")
+	tool := newTestHTTPTool(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/users" || r.URL.Query().Get("team") != "ops" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret-token" {
+			t.Errorf("Authorization = %q, want the profile's token", got)
+		}
+		if got := r.URL.Query().Get("api_key"); got != "k1" {
+			t.Errorf("api_key = %q", got)
+		}
+		if got := r.Header.Get("X-Trace"); got != "abc" {
+			t.Errorf("X-Trace = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"users":["ann"]}`))
+	}, config.HTTPProfileConfig{
+		Headers: map[string]string{"Authorization": "Bearer ${TEST_HTTP_TOKEN}"},
+		Query:   map[string]string{"api_key": "k1"},
+	})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"profile": "internal",
+		"url":     "users?team=ops",
+		"headers": map[string]interface{}{"Authorization": "Bearer model-token", "X-Trace": "abc"},
+	})
+	if result.IsError {
+		t.Fatalf("request failed: %s", result.ForLLM)
+	}
+	if !strings.HasPrefix(result.ForLLM, "HTTP 200 OK\nContent-Type: application/json\n") ||
+		!strings.HasSuffix(result.ForLLM, `{"users":["ann"]}`) {
+		t.Errorf("unexpected result %q", result.ForLLM)
+	}
+	if tool.RequiresApproval() {
+		t.Error("a GET-only profile should not need approval")
+	}
+}
+
+func TestHTTPRequestTool_Restrictions(t *testing.T) {
+	tool := newTestHTTPTool(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://elsewhere.example.com/", http.StatusFound)
+	}, config.HTTPProfileConfig{})
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"other domain", map[string]interface{}{"profile": "internal", "url": "https://example.com/"}, "not in the allowed domains"},
+		{"method", map[string]interface{}{"profile": "internal", "url": "users", "method": "DELETE"}, "not allowed"},
+		{"profile", map[string]interface{}{"profile": "public", "url": "users"}, "unknown profile"},
+		{"redirect", map[string]interface{}{"profile": "internal", "url": "users"}, "outside the allowed domains"},
+	}
+	for _, tt := range tests {
+		result := tool.Execute(context.Background(), tt.args)
+		if !result.IsError || !strings.Contains(result.ForLLM, tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %q", tt.name, tt.want, result.ForLLM)
+		}
+	}
+}
+
+func TestHTTPProfileAllows(t *testing.T) {
+	tool, err := NewHTTPRequestToolFromConfig(config.HTTPToolsConfig{
+		Enabled: true,
+		Profiles: map[string]config.HTTPProfileConfig{
+			"corp": {AllowedDomains: []string{"*.corp.example", "api.example.com"}, Methods: []string{"get", "post"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tool.RequiresApproval() {
+		t.Error("a profile allowing POST should need approval")
+	}
+	p := tool.profiles["corp"]
+	for raw, want := range map[string]bool{
+		"https://billing.corp.example/x": true,
+		"https://corp.example/x":         false,
+		"https://evilcorp.example/x":     false,
+		"http://API.example.com:8080/":   true,
+		"ftp://api.example.com/":         false,
+	} {
+		u, _ := url.Parse(raw)
+		if got := p.allows(u); got != want {
+			t.Errorf("allows(%s) = %v, want %v", raw, got, want)
+		}
+	}
+
+	if _, err := NewHTTPRequestToolFromConfig(config.HTTPToolsConfig{
+		Enabled:  true,
+		Profiles: map[string]config.HTTPProfileConfig{"x": {}},
+	}); err == nil {
+		t.Error("expected an error for a profile without domains")
+	}
+}
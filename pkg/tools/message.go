@@ -22,6 +22,11 @@ func (t *MessageTool) Name() string {
 	return "message"
 }
 
+// Messages are sent one at a time so they arrive in the order requested.
+func (t *MessageTool) MaxConcurrency() int {
+	return 1
+}
+
 func (t *MessageTool) Description() string {
 	return "Send a message to user on a chat channel. Use this when you want to communicate something."
 }
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package tools
+
+// oNoFollow is not available here; paths are still checked by Resolve.
+const oNoFollow = 0
@@ -0,0 +1,9 @@
+//go:build unix
+
+package tools
+
+import "syscall"
+
+// oNoFollow makes opening a symlink fail, so a link swapped in after a
+// path was checked cannot redirect a write.
+const oNoFollow = syscall.O_NOFOLLOW
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// artifactMaxAge is how long spilled outputs are kept before being pruned.
+const artifactMaxAge = 7 * 24 * time.Hour
+
+// outputLimit is the registry's limit on tool results sent to the model.
+type outputLimit struct {
+	maxTokens   int
+	artifactDir string
+}
+
+type artifactDirKey struct{}
+
+// SetOutputLimit truncates tool results longer than roughly maxTokens,
+// keeping their head and tail. When artifactDir is set the full output is
+// written to a file there and its path is reported to the model, which can
+// read it piecewise. maxTokens <= 0 disables the limit.
+func (r *ToolRegistry) SetOutputLimit(maxTokens int, artifactDir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.output = outputLimit{maxTokens: maxTokens, artifactDir: artifactDir}
+}
+
+func (r *ToolRegistry) outputLimit() outputLimit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.output
+}
+
+// limitResult applies the registry's output limit to result.ForLLM.
+func (r *ToolRegistry) limitResult(ctx context.Context, name string, result *ToolResult) {
+	limit := r.outputLimit()
+	if limit.maxTokens <= 0 || estimateTokens(result.ForLLM) <= limit.maxTokens {
+		return
+	}
+	// Tokens are estimated at three characters each
+	result.ForLLM = LimitOutput(withArtifactDir(ctx, limit.artifactDir), name, result.ForLLM, limit.maxTokens*3)
+}
+
+func withArtifactDir(ctx context.Context, dir string) context.Context {
+	if dir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, artifactDirKey{}, dir)
+}
+
+// LimitOutput shortens output to about maxChars characters, keeping the
+// head and tail. When the tool runs in a registry with an artifact directory
+// the full output is saved there first and the note in the middle says
+// where. Tools with their own output caps, like exec, use it so nothing is
+// lost when they truncate.
+func LimitOutput(ctx context.Context, tool, output string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(output) <= maxChars {
+		return output
+	}
+
+	dir, _ := ctx.Value(artifactDirKey{}).(string)
+	if dir == "" {
+		return truncateOutput(output, maxChars)
+	}
+	path, err := writeArtifact(dir, tool, output)
+	if err != nil {
+		logger.WarnCF("tool", "Failed to save full tool output",
+			map[string]interface{}{
+				"tool":  tool,
+				"error": err.Error(),
+			})
+		return truncateOutput(output, maxChars)
+	}
+
+	runes := []rune(output)
+	head := maxChars / 2
+	tail := maxChars - head
+	omitted := len(runes) - head - tail
+	note := fmt.Sprintf("\n... (truncated, %d chars omitted; full output of %d lines saved to %s — use read_file with start_line/end_line or grep to inspect it) ...\n",
+		omitted, strings.Count(output, "\n")+1, path)
+	return string(runes[:head]) + note + string(runes[len(runes)-tail:])
+}
+
+// writeArtifact saves output to a new file in dir and prunes old ones.
+func writeArtifact(dir, tool, output string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	pruneArtifacts(dir)
+
+	prefix := fmt.Sprintf("%s-%s-*.txt", sanitizeArtifactName(tool), time.Now().Format("20060102-150405"))
+	f, err := os.CreateTemp(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(output); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func pruneArtifacts(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-artifactMaxAge)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+func sanitizeArtifactName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// estimateTokens approximates the token count of s the same way the agent
+// does when deciding to summarize.
+func estimateTokens(s string) int {
+	return utf8.RuneCountInString(s) / 3
+}
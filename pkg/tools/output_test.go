@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// verboseTool returns a large, numbered output.
+type verboseTool struct{ lines int }
+
+func (t *verboseTool) Name() string        { return "verbose" }
+func (t *verboseTool) Description() string { return "prints a lot" }
+func (t *verboseTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *verboseTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	var sb strings.Builder
+	for i := 0; i < t.lines; i++ {
+		sb.WriteString(strings.Repeat("x", 20))
+		sb.WriteString("\n")
+	}
+	return NewToolResult("BEGIN\n" + sb.String() + "END")
+}
+
+var artifactPathPattern = regexp.MustCompile(`saved to (\S+)`)
+
+func TestRegistry_OutputSpill(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewToolRegistry()
+	registry.Register(&verboseTool{lines: 1000})
+	registry.SetOutputLimit(100, dir)
+
+	result := registry.Execute(context.Background(), "verbose", nil)
+	if len(result.ForLLM) > 1000 {
+		t.Errorf("Expected output to be truncated, got %d chars", len(result.ForLLM))
+	}
+	if !strings.HasPrefix(result.ForLLM, "BEGIN") || !strings.HasSuffix(result.ForLLM, "END") {
+		t.Errorf("Expected head and tail to be kept, got: %q", result.ForLLM)
+	}
+
+	m := artifactPathPattern.FindStringSubmatch(result.ForLLM)
+	if m == nil {
+		t.Fatalf("Expected artifact path in output, got: %s", result.ForLLM)
+	}
+	data, err := os.ReadFile(m[1])
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	if want := (&verboseTool{lines: 1000}).Execute(context.Background(), nil).ForLLM; string(data) != want {
+		t.Errorf("Expected artifact to hold the full output (%d chars), got %d chars", len(want), len(data))
+	}
+}
+
+func TestRegistry_OutputWithinLimit(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&verboseTool{lines: 2})
+	registry.SetOutputLimit(100, t.TempDir())
+
+	result := registry.Execute(context.Background(), "verbose", nil)
+	if strings.Contains(result.ForLLM, "truncated") {
+		t.Errorf("Expected short output to pass unchanged, got: %s", result.ForLLM)
+	}
+}
+
+func TestLimitOutput_WithoutArtifactDir(t *testing.T) {
+	out := LimitOutput(context.Background(), "tool", strings.Repeat("a", 50)+strings.Repeat("b", 50), 20)
+	if !strings.HasPrefix(out, "aaaaaaaaaa") || !strings.HasSuffix(out, "bbbbbbbbbb") {
+		t.Errorf("Expected head and tail to be kept, got: %q", out)
+	}
+	if strings.Contains(out, "saved to") {
+		t.Errorf("Expected no artifact without a directory, got: %q", out)
+	}
+}
+
+func TestShellTool_OutputSpill(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewToolRegistry()
+	tool := NewExecTool("", false)
+	tool.SetMaxOutput(100)
+	registry.Register(tool)
+	registry.SetOutputLimit(0, dir)
+
+	result := registry.Execute(context.Background(), "exec", map[string]interface{}{
+		"command": "seq 1 5000",
+	})
+	m := artifactPathPattern.FindStringSubmatch(result.ForLLM)
+	if m == nil {
+		t.Fatalf("Expected exec to report the artifact path, got: %s", result.ForLLM)
+	}
+	data, err := os.ReadFile(m[1])
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	if !strings.Contains(string(data), "\n2500\n") {
+		t.Error("Expected artifact to contain the middle of the output")
+	}
+}
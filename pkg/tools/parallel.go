@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// DefaultMaxParallelTools bounds how many tool calls from one response run
+// at once when no other limit is configured.
+const DefaultMaxParallelTools = 4
+
+// ConcurrentTool is an optional interface for tools that limit how many of
+// their calls may run at the same time, across every agent sharing the
+// registry. A limit of 1 serializes the tool; zero or less is unlimited.
+// Contextual and async tools that do not implement it are serialized.
+type ConcurrentTool interface {
+	Tool
+	MaxConcurrency() int
+}
+
+// SetConcurrencyLimit overrides the concurrency limit of the named tool.
+// A limit of 1 serializes its calls; zero or less removes the limit.
+func (r *ToolRegistry) SetConcurrencyLimit(name string, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.limits == nil {
+		r.limits = make(map[string]int)
+	}
+	r.limits[name] = limit
+	delete(r.slots, name)
+}
+
+// concurrencySlots returns the semaphore bounding calls to the named tool,
+// or nil when the tool is unlimited.
+func (r *ToolRegistry) concurrencySlots(name string) chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if slots, ok := r.slots[name]; ok {
+		return slots
+	}
+	limit, ok := r.limits[name]
+	if !ok {
+		switch tool := r.tools[name].(type) {
+		case ConcurrentTool:
+			limit = tool.MaxConcurrency()
+		case ContextualTool, AsyncTool:
+			// These keep per-call state on the shared instance (SetContext,
+			// SetCallback), so overlapping calls would race
+			limit = 1
+		}
+	}
+	if limit <= 0 {
+		return nil
+	}
+	if r.slots == nil {
+		r.slots = make(map[string]chan struct{})
+	}
+	slots := make(chan struct{}, limit)
+	r.slots[name] = slots
+	return slots
+}
+
+// ExecuteParallel runs calls through run with at most maxParallel running
+// at once, honouring each tool's concurrency limit. Results are returned in
+// the order of calls, whatever order they finish in. A maxParallel of 1 runs
+// the calls one after another.
+func (r *ToolRegistry) ExecuteParallel(ctx context.Context, calls []providers.ToolCall, maxParallel int, run func(ctx context.Context, call providers.ToolCall) *ToolResult) []*ToolResult {
+	if maxParallel <= 0 {
+		maxParallel = DefaultMaxParallelTools
+	}
+	results := make([]*ToolResult, len(calls))
+
+	runOne := func(i int) {
+		call := calls[i]
+		if slots := r.concurrencySlots(call.Name); slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				results[i] = ErrorResult(fmt.Sprintf("tool %s was not run: %v", call.Name, ctx.Err())).WithError(ctx.Err())
+				return
+			}
+		}
+		results[i] = run(ctx, call)
+	}
+
+	if maxParallel == 1 || len(calls) <= 1 {
+		for i := range calls {
+			runOne(i)
+		}
+		return results
+	}
+
+	workers := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i := range calls {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			runOne(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// concurrencyProbe tracks how many calls overlap.
+type concurrencyProbe struct {
+	mu      sync.Mutex
+	running map[string]int
+	peak    map[string]int
+	total   atomic.Int32
+	peakAll atomic.Int32
+}
+
+func newConcurrencyProbe() *concurrencyProbe {
+	return &concurrencyProbe{running: map[string]int{}, peak: map[string]int{}}
+}
+
+func (p *concurrencyProbe) run(ctx context.Context, call providers.ToolCall) *ToolResult {
+	p.mu.Lock()
+	p.running[call.Name]++
+	if p.running[call.Name] > p.peak[call.Name] {
+		p.peak[call.Name] = p.running[call.Name]
+	}
+	p.mu.Unlock()
+	if n := p.total.Add(1); n > p.peakAll.Load() {
+		p.peakAll.Store(n)
+	}
+
+	// Later calls finish first, so ordering has to be restored
+	delay, _ := call.Arguments["delay"].(int)
+	time.Sleep(time.Duration(delay) * time.Millisecond)
+
+	p.total.Add(-1)
+	p.mu.Lock()
+	p.running[call.Name]--
+	p.mu.Unlock()
+	return NewToolResult(call.ID)
+}
+
+func probeCalls(name string, n int) []providers.ToolCall {
+	calls := make([]providers.ToolCall, n)
+	for i := range calls {
+		calls[i] = providers.ToolCall{
+			ID:        fmt.Sprintf("%s_%d", name, i),
+			Name:      name,
+			Arguments: map[string]interface{}{"delay": (n - i) * 10},
+		}
+	}
+	return calls
+}
+
+func TestExecuteParallel_PreservesOrder(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&recordingTool{name: "fast"})
+	probe := newConcurrencyProbe()
+
+	calls := probeCalls("fast", 6)
+	results := registry.ExecuteParallel(context.Background(), calls, 3, probe.run)
+
+	for i, result := range results {
+		if result.ForLLM != calls[i].ID {
+			t.Errorf("Expected result %d to be %s, got %s", i, calls[i].ID, result.ForLLM)
+		}
+	}
+	if peak := probe.peakAll.Load(); peak < 2 || peak > 3 {
+		t.Errorf("Expected between 2 and 3 calls to overlap, got %d", peak)
+	}
+}
+
+func TestExecuteParallel_PerToolLimits(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(NewWriteFileTool("", false)) // serialized by MaxConcurrency
+	registry.Register(&recordingTool{name: "limited"})
+	registry.SetConcurrencyLimit("limited", 2)
+	probe := newConcurrencyProbe()
+
+	calls := append(probeCalls("write_file", 3), probeCalls("limited", 4)...)
+	registry.ExecuteParallel(context.Background(), calls, 8, probe.run)
+
+	if got := probe.peak["write_file"]; got != 1 {
+		t.Errorf("Expected write_file to run serially, peak %d", got)
+	}
+	if got := probe.peak["limited"]; got != 2 {
+		t.Errorf("Expected at most 2 concurrent limited calls, peak %d", got)
+	}
+}
+
+func TestExecuteParallel_Sequential(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&recordingTool{name: "fast"})
+	probe := newConcurrencyProbe()
+
+	registry.ExecuteParallel(context.Background(), probeCalls("fast", 3), 1, probe.run)
+	if peak := probe.peakAll.Load(); peak != 1 {
+		t.Errorf("Expected calls to run one at a time, peak %d", peak)
+	}
+}
@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrPolicyDenied is returned (wrapped) when the tool policy forbids a call.
+var ErrPolicyDenied = errors.New("tool call denied by policy")
+
+// policyWriters name the argument holding the path each file writer
+// changes.
+var policyWriters = map[string]string{
+	"write_file":  "path",
+	"edit_file":   "path",
+	"append_file": "path",
+}
+
+// policyFetchers name the argument holding the URL each network tool
+// requests.
+var policyFetchers = map[string]string{
+	"web_fetch":    "url",
+	"http_request": "url",
+}
+
+// urlResolver is implemented by tools whose URL argument may be relative
+// to configuration the policy does not see, such as an http_request
+// profile's base URL.
+type urlResolver interface {
+	resolveURL(args map[string]interface{}) string
+}
+
+// Policy decides which tool calls may run, as configured by
+// config.PolicyConfig. Registries with a policy check it before validating
+// arguments or asking for approval, and hide the tools it forbids from the
+// model.
+type Policy struct {
+	workspace     string
+	tools         []string
+	allowCommands []*regexp.Regexp
+	denyCommands  []*regexp.Regexp
+	writablePaths []string // absolute, slash-separated globs
+	allowHosts    []string
+
+	invalid error // set when the config could not be compiled; denies everything
+}
+
+// NewPolicy compiles cfg. Relative writable paths are resolved against
+// workspace. It returns nil when cfg restricts nothing.
+func NewPolicy(cfg config.PolicyConfig, workspace string) (*Policy, error) {
+	if len(cfg.Tools) == 0 && len(cfg.AllowCommands) == 0 && len(cfg.DenyCommands) == 0 &&
+		len(cfg.WritablePaths) == 0 && len(cfg.AllowHosts) == 0 {
+		return nil, nil
+	}
+	p := &Policy{workspace: workspace, tools: cfg.Tools}
+	var err error
+	if p.allowCommands, err = compilePatterns(cfg.AllowCommands); err != nil {
+		return nil, err
+	}
+	if p.denyCommands, err = compilePatterns(cfg.DenyCommands); err != nil {
+		return nil, err
+	}
+
+	root := workspace
+	if abs, err := filepath.Abs(workspace); err == nil {
+		root = resolveExisting(abs)
+	}
+	for _, glob := range cfg.WritablePaths {
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(root, glob)
+		}
+		p.writablePaths = append(p.writablePaths, filepath.ToSlash(filepath.Clean(glob)))
+	}
+	for _, host := range cfg.AllowHosts {
+		p.allowHosts = append(p.allowHosts, strings.ToLower(host))
+	}
+	return p, nil
+}
+
+// NewPolicyFromConfig returns the policy described by cfg, or nil when it
+// restricts nothing. A policy that cannot be compiled is logged and denies
+// every call, rather than letting tools run unchecked.
+func NewPolicyFromConfig(cfg config.PolicyConfig, workspace string) *Policy {
+	p, err := NewPolicy(cfg, workspace)
+	if err != nil {
+		logger.ErrorCF("policy", "Invalid tool policy, denying all tool calls",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		return &Policy{invalid: err}
+	}
+	return p
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// AllowsTool reports whether the policy lets the agent call the named tool
+// at all.
+func (p *Policy) AllowsTool(name string) bool {
+	if p.invalid != nil {
+		return false
+	}
+	if len(p.tools) == 0 {
+		return true
+	}
+	for _, pattern := range p.tools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error wrapping ErrPolicyDenied when the call of the named
+// tool with args may not run. Arguments of the wrong type are left for
+// argument validation to report.
+func (p *Policy) Check(name string, args map[string]interface{}) error {
+	if p.invalid != nil {
+		return fmt.Errorf("%w: %v", ErrPolicyDenied, p.invalid)
+	}
+	if !p.AllowsTool(name) {
+		return fmt.Errorf("%w: tool %s is not allowed", ErrPolicyDenied, name)
+	}
+
+	if command, ok := args["command"].(string); ok && (name == "exec" || name == "ssh") {
+		if err := p.checkCommand(command); err != nil {
+			return err
+		}
+	}
+	if arg, ok := policyWriters[name]; ok && len(p.writablePaths) > 0 {
+		if target, ok := args[arg].(string); ok {
+			if err := p.checkWritable(target); err != nil {
+				return err
+			}
+		}
+	}
+	if arg, ok := policyFetchers[name]; ok && len(p.allowHosts) > 0 {
+		if rawURL, ok := args[arg].(string); ok {
+			if err := p.checkHost(rawURL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTool is Check for a call to tool, with a relative URL argument
+// resolved the way the tool will resolve it.
+func (p *Policy) checkTool(tool Tool, args map[string]interface{}) error {
+	if r, ok := tool.(urlResolver); ok && len(p.allowHosts) > 0 {
+		if arg, ok := policyFetchers[tool.Name()]; ok {
+			resolved := make(map[string]interface{}, len(args))
+			for k, v := range args {
+				resolved[k] = v
+			}
+			resolved[arg] = r.resolveURL(args)
+			args = resolved
+		}
+	}
+	return p.Check(tool.Name(), args)
+}
+
+func (p *Policy) checkCommand(command string) error {
+	for _, re := range p.denyCommands {
+		if re.MatchString(command) {
+			return fmt.Errorf("%w: command matches denied pattern %q", ErrPolicyDenied, re.String())
+		}
+	}
+	if len(p.allowCommands) == 0 {
+		return nil
+	}
+	for _, re := range p.allowCommands {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: command matches no allowed pattern", ErrPolicyDenied)
+}
+
+// checkWritable resolves target like the file tools do, following
+// symlinks, so a link cannot lead a write outside the writable paths.
+func (p *Policy) checkWritable(target string) error {
+	abs, err := validatePath(target, p.workspace, false)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPolicyDenied, err)
+	}
+	resolved := filepath.ToSlash(resolveExisting(abs))
+	for _, glob := range p.writablePaths {
+		if matchGlob(glob, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not a writable path", ErrPolicyDenied, target)
+}
+
+func (p *Policy) checkHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: cannot tell the host of %q", ErrPolicyDenied, rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range p.allowHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s is not allowed", ErrPolicyDenied, host)
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(workspace, "src"), 0755)
+	if err := os.Symlink(filepath.Join(outside, "new.go"), filepath.Join(workspace, "src", "dangling.go")); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPolicy(config.PolicyConfig{
+		Tools:         []string{"exec", "write_file", "web_fetch", "http_request", "mcp_github_*"},
+		AllowCommands: []string{`^(git|go) `},
+		DenyCommands:  []string{`git push`},
+		WritablePaths: []string{"src/**", filepath.Join(outside, "*.log")},
+		AllowHosts:    []string{"go.dev", "*.github.com"},
+	}, workspace)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	tests := []struct {
+		tool    string
+		args    map[string]interface{}
+		allowed bool
+	}{
+		{"read_file", nil, false},
+		{"mcp_github_create_issue", nil, true},
+		{"exec", map[string]interface{}{"command": "go test ./..."}, true},
+		{"exec", map[string]interface{}{"command": "git push origin main"}, false},
+		{"exec", map[string]interface{}{"command": "curl example.com"}, false},
+		{"write_file", map[string]interface{}{"path": "src/pkg/main.go"}, true},
+		{"write_file", map[string]interface{}{"path": "README.md"}, false},
+		{"write_file", map[string]interface{}{"path": "src/../README.md"}, false},
+		{"write_file", map[string]interface{}{"path": "escape/src/x.go"}, false},
+		{"write_file", map[string]interface{}{"path": "src/dangling.go"}, false},
+		{"write_file", map[string]interface{}{"path": filepath.Join(outside, "run.log")}, true},
+		{"web_fetch", map[string]interface{}{"url": "https://go.dev/doc"}, true},
+		{"web_fetch", map[string]interface{}{"url": "https://api.github.com/repos"}, true},
+		{"web_fetch", map[string]interface{}{"url": "https://github.com.evil.example/"}, false},
+		{"web_fetch", map[string]interface{}{"url": "not a url"}, false},
+		{"http_request", map[string]interface{}{"profile": "gh", "url": "https://api.github.com/user"}, true},
+		{"http_request", map[string]interface{}{"profile": "gh", "url": "https://example.com/"}, false},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.tool, tt.args)
+		if tt.allowed && err != nil {
+			t.Errorf("%s %v: denied: %v", tt.tool, tt.args, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrPolicyDenied) {
+			t.Errorf("%s %v: got %v, want ErrPolicyDenied", tt.tool, tt.args, err)
+		}
+	}
+}
+
+func TestPolicy_Empty(t *testing.T) {
+	if policy, err := NewPolicy(config.PolicyConfig{}, t.TempDir()); policy != nil || err != nil {
+		t.Errorf("empty policy = %v, %v; want nil", policy, err)
+	}
+}
+
+func TestPolicy_InvalidDeniesEverything(t *testing.T) {
+	policy := NewPolicyFromConfig(config.PolicyConfig{DenyCommands: []string{"("}}, t.TempDir())
+	if err := policy.Check("read_file", nil); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("invalid policy allowed a call: %v", err)
+	}
+}
+
+func TestRegistry_Policy(t *testing.T) {
+	registry := NewToolRegistry()
+	safe := &recordingTool{name: "safe"}
+	hidden := &recordingTool{name: "hidden"}
+	registry.Register(safe)
+	registry.Register(hidden)
+	policy, _ := NewPolicy(config.PolicyConfig{Tools: []string{"safe"}}, "")
+	registry.SetPolicy(policy)
+
+	defs := registry.ToProviderDefs()
+	if len(defs) != 1 || defs[0].Function.Name != "safe" {
+		t.Errorf("definitions = %+v, want only safe", defs)
+	}
+	result := registry.Execute(context.Background(), "hidden", nil)
+	if !errors.Is(result.Err, ErrPolicyDenied) || hidden.runs != 0 {
+		t.Errorf("hidden ran or was not denied: %v", result.Err)
+	}
+	if result := registry.Execute(context.Background(), "safe", nil); result.IsError || safe.runs != 1 {
+		t.Errorf("safe call failed: %s", result.ForLLM)
+	}
+}
+
+func TestRegistry_PolicyResolvesRelativeURLs(t *testing.T) {
+	requests := 0
+	tool := newTestHTTPTool(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}, config.HTTPProfileConfig{})
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	policy, _ := NewPolicy(config.PolicyConfig{AllowHosts: []string{"api.example.com"}}, "")
+	registry.SetPolicy(policy)
+
+	// The path is relative to the profile's base URL, on a host not allowed
+	result := registry.Execute(context.Background(), "http_request", map[string]interface{}{"profile": "internal", "url": "users"})
+	if !errors.Is(result.Err, ErrPolicyDenied) || requests != 0 {
+		t.Errorf("request to a host off the list was not denied: %v (%d requests)", result.Err, requests)
+	}
+
+	policy, _ = NewPolicy(config.PolicyConfig{AllowHosts: []string{"127.0.0.1"}}, "")
+	registry.SetPolicy(policy)
+	result = registry.Execute(context.Background(), "http_request", map[string]interface{}{"profile": "internal", "url": "users"})
+	if result.IsError || requests != 1 {
+		t.Errorf("request to an allowed host failed: %s (%d requests)", result.ForLLM, requests)
+	}
+}
@@ -3,16 +3,27 @@ package tools
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools     map[string]Tool
+	approvals *ApprovalGate
+	audit     *AuditLog
+	policy    *Policy
+	limits    map[string]int           // per-tool concurrency overrides
+	slots     map[string]chan struct{} // per-tool concurrency semaphores
+	timeouts  map[string]time.Duration // per-tool timeout overrides
+	output    outputLimit
+
+	defaultTimeout time.Duration
+	mu             sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -27,6 +38,74 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// ApplyConfig sets the timeouts, concurrency limits and output limit
+// configured in cfg. Relative artifact directories are resolved against
+// workspace.
+func (r *ToolRegistry) ApplyConfig(cfg config.ToolsConfig, workspace string) {
+	r.SetDefaultTimeout(time.Duration(cfg.DefaultTimeout) * time.Second)
+	for name, seconds := range cfg.Timeouts {
+		r.SetTimeout(name, time.Duration(seconds)*time.Second)
+	}
+	for name, limit := range cfg.Concurrency {
+		r.SetConcurrencyLimit(name, limit)
+	}
+
+	artifactDir := cfg.Output.ArtifactDir
+	if artifactDir == "" {
+		artifactDir = "artifacts"
+	}
+	if !filepath.IsAbs(artifactDir) {
+		artifactDir = filepath.Join(workspace, artifactDir)
+	}
+	r.SetOutputLimit(cfg.Output.MaxTokens, artifactDir)
+}
+
+// SetApprovalGate makes the registry confirm calls to tools that need
+// approval before executing them. A nil gate disables approvals.
+func (r *ToolRegistry) SetApprovalGate(gate *ApprovalGate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approvals = gate
+}
+
+// ApprovalGate returns the gate set with SetApprovalGate, if any.
+func (r *ToolRegistry) ApprovalGate() *ApprovalGate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.approvals
+}
+
+// SetAuditLog makes the registry record every tool call in audit. A nil
+// log disables auditing.
+func (r *ToolRegistry) SetAuditLog(audit *AuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = audit
+}
+
+// AuditLog returns the log set with SetAuditLog, if any.
+func (r *ToolRegistry) AuditLog() *AuditLog {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.audit
+}
+
+// SetPolicy makes the registry refuse the tool calls policy forbids and
+// leave the tools it does not allow out of the definitions sent to the
+// model. A nil policy allows everything.
+func (r *ToolRegistry) SetPolicy(policy *Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// Policy returns the policy set with SetPolicy, if any.
+func (r *ToolRegistry) Policy() *Policy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -41,7 +120,13 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 // ExecuteWithContext executes a tool with channel/chatID context and optional async callback.
 // If the tool implements AsyncTool and a non-nil callback is provided,
 // the callback will be set on the tool before execution.
-func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID string, asyncCallback AsyncCallback) *ToolResult {
+func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID string, asyncCallback AsyncCallback) (result *ToolResult) {
+	var approval ApprovalDecision
+	var denied bool
+	if audit := r.AuditLog(); audit != nil {
+		defer func() { audit.recordToolCall(ctx, name, args, result, approval, denied) }()
+	}
+
 	logger.InfoCF("tool", "Tool execution started",
 		map[string]interface{}{
 			"tool": name,
@@ -57,6 +142,42 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	if policy := r.Policy(); policy != nil {
+		if err := policy.checkTool(tool, args); err != nil {
+			denied = true
+			logger.WarnCF("tool", "Tool call denied by policy",
+				map[string]interface{}{
+					"tool":  name,
+					"error": err.Error(),
+				})
+			return ErrorResult(fmt.Sprintf("tool %s was not run: %v", name, err)).WithError(err)
+		}
+	}
+
+	// Reject malformed arguments before running anything, so the model can
+	// correct the call instead of the tool acting on partial input
+	if err := ValidateArgs(name, tool.Parameters(), args); err != nil {
+		logger.WarnCF("tool", "Tool arguments failed validation",
+			map[string]interface{}{
+				"tool":  name,
+				"error": err.Error(),
+			})
+		return ErrorResult(err.(*ValidationError).ForLLM()).WithError(err)
+	}
+
+	if gate := r.ApprovalGate(); gate != nil {
+		req := ApprovalRequest{Tool: name, Arguments: args, Channel: channel, ChatID: chatID}
+		var err error
+		if approval, err = gate.Decide(ctx, tool, req); err != nil {
+			logger.WarnCF("tool", "Tool call not approved",
+				map[string]interface{}{
+					"tool":  name,
+					"error": err.Error(),
+				})
+			return ErrorResult(fmt.Sprintf("tool %s was not run: %v", name, err)).WithError(err)
+		}
+	}
+
 	// If tool implements ContextualTool, set context
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
@@ -72,8 +193,10 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	}
 
 	start := time.Now()
-	result := tool.Execute(ctx, args)
+	ctx = withArtifactDir(ctx, r.outputLimit().artifactDir)
+	result = runTool(ctx, tool, args, r.timeoutFor(tool))
 	duration := time.Since(start)
+	r.limitResult(ctx, name, result)
 
 	// Log based on result type
 	if result.IsError {
@@ -120,6 +243,9 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 
 	definitions := make([]providers.ToolDefinition, 0, len(r.tools))
 	for _, tool := range r.tools {
+		if r.policy != nil && !r.policy.AllowsTool(tool.Name()) {
+			continue
+		}
 		schema := ToolToSchema(tool)
 
 		// Safely extract nested values with type checks
@@ -132,14 +258,19 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 		desc, _ := fn["description"].(string)
 		params, _ := fn["parameters"].(map[string]interface{})
 
-		definitions = append(definitions, providers.ToolDefinition{
+		def := providers.ToolDefinition{
 			Type: "function",
 			Function: providers.ToolFunctionDefinition{
 				Name:        name,
 				Description: desc,
 				Parameters:  params,
 			},
-		})
+		}
+		if ct, ok := tool.(*ComputerTool); ok {
+			display := ct.Display()
+			def.Computer = &display
+		}
+		definitions = append(definitions, def)
 	}
 	return definitions
 }
@@ -171,6 +302,9 @@ func (r *ToolRegistry) GetSummaries() []string {
 
 	summaries := make([]string, 0, len(r.tools))
 	for _, tool := range r.tools {
+		if r.policy != nil && !r.policy.AllowsTool(tool.Name()) {
+			continue
+		}
 		summaries = append(summaries, fmt.Sprintf("- `%s` - %s", tool.Name(), tool.Description()))
 	}
 	return summaries
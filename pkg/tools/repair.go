@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// DefaultMaxArgumentRepairs is how many responses in a row may contain tool
+// calls with malformed arguments before an agent loop gives up.
+const DefaultMaxArgumentRepairs = 3
+
+// InvalidArgumentsResult is the result of a call whose arguments were not a
+// JSON object. The tool is not run; the model is told what was wrong and
+// asked to send the call again.
+func InvalidArgumentsResult(call providers.ToolCall) *ToolResult {
+	data, _ := json.Marshal(map[string]string{
+		"error":  "invalid_arguments",
+		"tool":   call.Name,
+		"detail": call.ArgumentsError,
+		"instruction": fmt.Sprintf("%s was not run because its arguments were not valid JSON. "+
+			"Call it again with arguments that are a single JSON object matching its parameters.", call.Name),
+	})
+	return ErrorResult(string(data))
+}
+
+// ArgumentRepairs bounds how often the model is asked to resend malformed
+// tool calls. Max is the number of responses in a row that may contain
+// them; zero uses DefaultMaxArgumentRepairs.
+type ArgumentRepairs struct {
+	Max    int
+	inARow int
+}
+
+// Check records the calls of one response and returns an error once more
+// than Max responses in a row contained malformed arguments.
+func (r *ArgumentRepairs) Check(calls []providers.ToolCall) error {
+	var malformed *providers.ToolCall
+	for i := range calls {
+		if calls[i].ArgumentsError != "" {
+			malformed = &calls[i]
+			break
+		}
+	}
+	if malformed == nil {
+		r.inARow = 0
+		return nil
+	}
+	r.inARow++
+	limit := r.Max
+	if limit <= 0 {
+		limit = DefaultMaxArgumentRepairs
+	}
+	if r.inARow > limit {
+		return fmt.Errorf("model sent malformed arguments for %s in %d responses in a row: %s",
+			malformed.Name, r.inARow, malformed.ArgumentsError)
+	}
+	return nil
+}
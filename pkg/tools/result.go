@@ -1,6 +1,10 @@
 package tools
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
 
 // ToolResult represents the structured return value from tool execution.
 // It provides clear semantics for different types of results and supports
@@ -30,6 +34,10 @@ type ToolResult struct {
 	// Err is the underlying error (not JSON serialized).
 	// Used for internal error handling and logging.
 	Err error `json:"-"`
+
+	// Media holds images sent to the LLM along with ForLLM, such as
+	// screenshots.
+	Media []providers.ContentPart `json:"-"`
 }
 
 // NewToolResult creates a basic ToolResult with content for the LLM.
@@ -141,3 +149,17 @@ func (tr *ToolResult) WithError(err error) *ToolResult {
 	tr.Err = err
 	return tr
 }
+
+// ToolMessage returns the tool message that reports the result to the LLM
+// for the call toolCallID, falling back to Err when ForLLM is empty.
+func (tr *ToolResult) ToolMessage(toolCallID string) providers.Message {
+	content := tr.ForLLM
+	if content == "" && tr.Err != nil {
+		content = tr.Err.Error()
+	}
+	if len(tr.Media) == 0 {
+		return providers.Message{Role: "tool", Content: content, ToolCallID: toolCallID}
+	}
+	parts := append([]providers.ContentPart{providers.ToolResultPart(toolCallID, content, tr.IsError)}, tr.Media...)
+	return providers.NewMessage("tool", parts...)
+}
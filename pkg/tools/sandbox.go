@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const defaultSandboxImage = "alpine:3"
+
+// Sandbox runs exec commands in a Docker or Podman container, so commands
+// only see the workspace and the configured mounts, and reach the network
+// only when the config allows it. The workspace is mounted at its own
+// path, so paths the agent knows stay valid inside the container.
+type Sandbox struct {
+	runtime   string // docker or podman executable
+	image     string
+	network   string
+	mounts    []string
+	memory    string
+	cpus      string
+	user      string
+	workspace string
+}
+
+// NewSandbox prepares the container sandbox described by cfg for commands
+// in workspace. It fails when no container runtime is installed, rather than
+// letting commands run on the host.
+func NewSandbox(cfg config.ExecSandboxConfig, workspace string) (*Sandbox, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("the exec sandbox needs a Linux or macOS host")
+	}
+	if workspace == "" {
+		return nil, errors.New("the exec sandbox needs a workspace to mount")
+	}
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := findContainerRuntime(cfg.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sandbox{
+		runtime:   rt,
+		image:     cfg.Image,
+		network:   cfg.Network,
+		mounts:    cfg.Mounts,
+		memory:    cfg.Memory,
+		cpus:      cfg.CPUs,
+		user:      cfg.User,
+		workspace: resolveExisting(abs),
+	}
+	if s.image == "" {
+		s.image = defaultSandboxImage
+	}
+	if s.network == "" {
+		s.network = "none"
+	}
+	return s, nil
+}
+
+// findContainerRuntime resolves name, or docker and then podman when name
+// is empty, to an executable path.
+func findContainerRuntime(name string) (string, error) {
+	candidates := []string{"docker", "podman"}
+	if name != "" {
+		candidates = []string{name}
+	}
+	for _, candidate := range candidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	if name != "" {
+		return "", fmt.Errorf("container runtime %q not found", name)
+	}
+	return "", errors.New("the exec sandbox needs docker or podman, and neither was found in PATH")
+}
+
+// command returns the container run command for the shell command, started
+// in cwd. envNames are passed into the container; their values are read
+// from the runtime's own environment, so they do not appear in its
+// arguments.
+func (s *Sandbox) command(ctx context.Context, name, command, cwd string, envNames []string) (*exec.Cmd, error) {
+	if cwd == "" {
+		cwd = s.workspace
+	}
+	cwd, err := filepath.Abs(cwd)
+	if err != nil {
+		return nil, err
+	}
+	cwd = resolveExisting(cwd)
+	if !isWithin(s.workspace, cwd) {
+		return nil, fmt.Errorf("working directory %s is outside the sandbox workspace", cwd)
+	}
+
+	args := []string{"run", "--rm", "--name", name,
+		"--network", s.network,
+		"--volume", s.workspace + ":" + s.workspace,
+		"--workdir", cwd,
+	}
+	for _, mount := range s.mounts {
+		args = append(args, "--volume", mount)
+	}
+	if s.memory != "" {
+		args = append(args, "--memory", s.memory)
+	}
+	if s.cpus != "" {
+		args = append(args, "--cpus", s.cpus)
+	}
+	if s.user != "" {
+		args = append(args, "--user", s.user)
+	}
+	for _, env := range envNames {
+		args = append(args, "--env", env)
+	}
+	args = append(args, s.image, "sh", "-c", command)
+	return exec.CommandContext(ctx, s.runtime, args...), nil
+}
+
+// remove stops and deletes the named container. Killing the runtime's
+// client on timeout does not always stop the container itself.
+func (s *Sandbox) remove(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exec.CommandContext(ctx, s.runtime, "rm", "--force", name).Run()
+}
+
+// sandboxContainerName returns a unique name for a command's container.
+func sandboxContainerName() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "picoclaw-exec-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeContainerRuntime installs a script that prints its arguments, one per
+// line, in place of docker.
+func fakeContainerRuntime(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the exec sandbox is not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "docker")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\"\necho \"token=$PICOCLAW_TEST_TOKEN\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecTool_Sandbox(t *testing.T) {
+	t.Setenv("PICOCLAW_TEST_TOKEN", "abc")
+	workspace := t.TempDir()
+	tool, err := NewExecToolFromConfig(workspace, true, config.ExecToolsConfig{
+		EnvAllowlist: []string{"PICOCLAW_TEST_TOKEN", "PICOCLAW_TEST_UNSET"},
+		Sandbox: config.ExecSandboxConfig{
+			Enabled: true,
+			Runtime: fakeContainerRuntime(t),
+			Mounts:  []string{"/opt/cache:/cache:ro"},
+			Memory:  "512m",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewExecToolFromConfig: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(workspace, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command":     "make test",
+		"working_dir": "src",
+		"env":         map[string]interface{}{"CI": "1"},
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := strings.Join([]string{
+		"run", "--rm", "--name", "",
+		"--network", "none",
+		"--volume", workspace + ":" + workspace,
+		"--workdir", filepath.Join(workspace, "src"),
+		"--volume", "/opt/cache:/cache:ro",
+		"--memory", "512m",
+		"--env", "PICOCLAW_TEST_TOKEN",
+		"--env", "CI",
+		"alpine:3", "sh", "-c", "make test",
+		"token=abc",
+	}, "\n")
+	// Container names are random
+	lines := strings.Split(strings.TrimSpace(result.ForLLM), "\n")
+	if len(lines) > 3 && strings.HasPrefix(lines[3], "picoclaw-exec-") {
+		lines[3] = ""
+	}
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("runtime arguments:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExecTool_SandboxWorkingDirOutsideWorkspace(t *testing.T) {
+	tool, err := NewExecToolFromConfig(t.TempDir(), false, config.ExecToolsConfig{
+		Sandbox: config.ExecSandboxConfig{Enabled: true, Runtime: fakeContainerRuntime(t)},
+	})
+	if err != nil {
+		t.Fatalf("NewExecToolFromConfig: %v", err)
+	}
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command":     "ls",
+		"working_dir": t.TempDir(),
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the sandbox workspace") {
+		t.Errorf("Expected working_dir outside the workspace to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+func TestNewExecToolFromConfig_SandboxMissingRuntime(t *testing.T) {
+	_, err := NewExecToolFromConfig(t.TempDir(), false, config.ExecToolsConfig{
+		Sandbox: config.ExecSandboxConfig{Enabled: true, Runtime: "picoclaw-no-such-runtime"},
+	})
+	if err == nil {
+		t.Error("Expected an error when the container runtime is missing")
+	}
+}
@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSearchResults = 100
+	maxGrepFileSize      = 5 << 20
+	maxGrepLineLen       = 300
+)
+
+// skippedSearchDirs are never descended into by glob and grep.
+var skippedSearchDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	".venv":        true,
+	"__pycache__":  true,
+}
+
+// GlobTool finds files by name pattern under a directory.
+type GlobTool struct {
+	workspace *Workspace
+}
+
+func NewGlobTool(workspace string, restrict bool) *GlobTool {
+	return &GlobTool{workspace: NewWorkspace(workspace, restrict)}
+}
+
+func (t *GlobTool) Name() string {
+	return "glob"
+}
+
+func (t *GlobTool) Description() string {
+	return "Find files matching a glob pattern such as **/*.go or src/*.ts. Returns paths relative to the search directory."
+}
+
+func (t *GlobTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Glob pattern; ** matches any number of directories",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to search (default: workspace)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of paths to return (default 100)",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GlobTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+
+	root, err := searchRoot(args, t.workspace)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	limit := searchLimit(args)
+
+	var matches []string
+	truncated := false
+	err = walkSearchTree(ctx, root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !matchGlob(pattern, rel) {
+			return nil
+		}
+		if len(matches) >= limit {
+			truncated = true
+			return fs.SkipAll
+		}
+		matches = append(matches, rel)
+		return nil
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("glob failed: %v", err))
+	}
+
+	if len(matches) == 0 {
+		return NewToolResult("No files found")
+	}
+	sort.Strings(matches)
+	result := strings.Join(matches, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n... (stopped after %d results)", limit)
+	}
+	return NewToolResult(result)
+}
+
+// GrepTool searches file contents with a regular expression.
+type GrepTool struct {
+	workspace *Workspace
+}
+
+func NewGrepTool(workspace string, restrict bool) *GrepTool {
+	return &GrepTool{workspace: NewWorkspace(workspace, restrict)}
+}
+
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+func (t *GrepTool) Description() string {
+	return "Search file contents with a regular expression. Returns matching lines as path:line: text."
+}
+
+func (t *GrepTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression (RE2 syntax) to search for",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory to search (default: workspace)",
+			},
+			"include": map[string]interface{}{
+				"type":        "string",
+				"description": "Only search files matching this glob, e.g. *.go or **/*.md",
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match case-insensitively",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of matching lines to return (default 100)",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	include, _ := args["include"].(string)
+
+	root, err := searchRoot(args, t.workspace)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	limit := searchLimit(args)
+
+	var lines []string
+	truncated := false
+	search := func(file, display string) error {
+		if len(lines) >= limit {
+			truncated = true
+			return fs.SkipAll
+		}
+		if t.workspace.Restricted() && !t.workspace.Contains(file) {
+			return nil
+		}
+		for _, m := range grepFile(file, re, limit-len(lines)+1) {
+			if len(lines) >= limit {
+				truncated = true
+				return fs.SkipAll
+			}
+			lines = append(lines, display+":"+m)
+		}
+		return nil
+	}
+
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		search(root, filepath.Base(root))
+	} else {
+		err = walkSearchTree(ctx, root, func(rel string, d fs.DirEntry) error {
+			if d.IsDir() {
+				return nil
+			}
+			if include != "" && !matchGlob(include, rel) && !matchGlob(include, path.Base(rel)) {
+				return nil
+			}
+			return search(filepath.Join(root, filepath.FromSlash(rel)), rel)
+		})
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("grep failed: %v", err))
+		}
+	}
+
+	if len(lines) == 0 {
+		return NewToolResult("No matches found")
+	}
+	result := strings.Join(lines, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n... (stopped after %d matches)", limit)
+	}
+	return NewToolResult(result)
+}
+
+// grepFile returns up to limit "line: text" matches, skipping binary and
+// very large files.
+func grepFile(file string, re *regexp.Regexp, limit int) []string {
+	info, err := os.Stat(file)
+	if err != nil || info.Size() > maxGrepFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil
+	}
+
+	var matches []string
+	for i, line := range strings.Split(string(data), "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		line = strings.TrimRight(line, "\r")
+		if len(line) > maxGrepLineLen {
+			line = line[:maxGrepLineLen] + "..."
+		}
+		matches = append(matches, fmt.Sprintf("%d: %s", i+1, line))
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches
+}
+
+func searchRoot(args map[string]interface{}, workspace *Workspace) (string, error) {
+	dir, _ := args["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	return workspace.Resolve(dir)
+}
+
+func searchLimit(args map[string]interface{}) int {
+	if n, ok := args["max_results"].(float64); ok && n > 0 {
+		return int(n)
+	}
+	return defaultSearchResults
+}
+
+// walkSearchTree calls fn with the slash-separated path of every entry
+// below root, skipping VCS and dependency directories.
+func walkSearchTree(ctx context.Context, root string, fn func(rel string, d fs.DirEntry) error) error {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped rather than failing the search
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if p == root {
+			return nil
+		}
+		if d.IsDir() && skippedSearchDirs[d.Name()] {
+			return fs.SkipDir
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		return fn(filepath.ToSlash(rel), d)
+	})
+	if err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// matchGlob matches a slash-separated path against pattern, where a "**"
+// segment matches zero or more directories.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
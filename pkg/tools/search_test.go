@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSearchTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":              "package main\n\nfunc main() {\n\tTODO()\n}\n",
+		"pkg/util/util.go":     "package util\n\n// TODO: tidy\n",
+		"pkg/util/util_test.g": "not go\n",
+		"docs/readme.md":       "# todo list\n",
+		".git/config":          "TODO in git\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	return root
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/util/util.go", true},
+		{"pkg/**", "pkg/util/util.go", true},
+		{"pkg/*/util.go", "pkg/util/util.go", true},
+		{"docs/*.go", "docs/readme.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobTool(t *testing.T) {
+	root := writeSearchTree(t)
+	tool := NewGlobTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": "**/*.go"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if result.ForLLM != "main.go\npkg/util/util.go" {
+		t.Errorf("Expected main.go and pkg/util/util.go, got: %q", result.ForLLM)
+	}
+}
+
+func TestGlobTool_OutsideWorkspace(t *testing.T) {
+	root := writeSearchTree(t)
+	tool := NewGlobTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": "*", "path": t.TempDir()})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside") {
+		t.Errorf("Expected path outside workspace to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+func TestGrepTool(t *testing.T) {
+	root := writeSearchTree(t)
+	tool := NewGrepTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "TODO",
+		"include": "*.go",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "main.go:4: \tTODO()\npkg/util/util.go:3: // TODO: tidy"
+	if result.ForLLM != want {
+		t.Errorf("Expected %q, got %q", want, result.ForLLM)
+	}
+}
+
+func TestGrepTool_IgnoreCaseAndLimit(t *testing.T) {
+	root := writeSearchTree(t)
+	tool := NewGrepTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"pattern":     "todo",
+		"ignore_case": true,
+		"max_results": float64(2),
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, ".git") {
+		t.Errorf("Expected .git to be skipped, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "stopped after 2 matches") {
+		t.Errorf("Expected result limit note, got: %s", result.ForLLM)
+	}
+}
+
+func TestGrepTool_InvalidPattern(t *testing.T) {
+	tool := NewGrepTool(t.TempDir(), true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": "("})
+	if !result.IsError {
+		t.Error("Expected invalid regex to be rejected")
+	}
+}
@@ -9,16 +9,57 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
 )
 
 type ExecTool struct {
 	workingDir          string
 	timeout             time.Duration
+	maxTimeout          time.Duration
+	maxOutput           int
+	envAllowlist        []string
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+	sandbox             *Sandbox
+}
+
+// alwaysInheritedEnv is passed to commands even with an env allowlist;
+// without them most shells and toolchains cannot start.
+var alwaysInheritedEnv = []string{"PATH", "HOME", "USER", "LANG", "TERM", "TMPDIR", "SYSTEMROOT", "COMSPEC", "PATHEXT", "TEMP", "TMP"}
+
+// deniedEnv lists variables the model may not set for a command: they
+// load code into every program or shell it starts, or change which
+// programs run, getting around the command guard.
+var deniedEnv = []string{
+	"PATH", "PATHEXT", "COMSPEC", "BASH_ENV", "ENV", "ZDOTDIR", "SHELLOPTS", "BASHOPTS",
+	"PS4", "PROMPT_COMMAND", "IFS", "CDPATH", "GLOBIGNORE", "GCONV_PATH",
+	"NODE_OPTIONS", "PYTHONSTARTUP", "PERL5OPT", "RUBYOPT", "JAVA_TOOL_OPTIONS",
+	"GIT_CONFIG_GLOBAL", "GIT_CONFIG_SYSTEM", "GIT_SSH_COMMAND", "GIT_EXEC_PATH",
+}
+
+// deniedEnvPrefixes covers the dynamic loaders' variables, such as
+// LD_PRELOAD and DYLD_INSERT_LIBRARIES, and bash's exported functions.
+var deniedEnvPrefixes = []string{"LD_", "DYLD_", "BASH_FUNC_"}
+
+// envDenied reports whether the model may not set the variable name.
+func envDenied(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, denied := range deniedEnv {
+		if upper == denied {
+			return true
+		}
+	}
+	for _, prefix := range deniedEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
@@ -36,16 +77,59 @@ func NewExecTool(workingDir string, restrict bool) *ExecTool {
 	return &ExecTool{
 		workingDir:          workingDir,
 		timeout:             60 * time.Second,
+		maxTimeout:          10 * time.Minute,
+		maxOutput:           10000,
 		denyPatterns:        denyPatterns,
 		allowPatterns:       nil,
 		restrictToWorkspace: restrict,
 	}
 }
 
+// NewExecToolFromConfig creates an exec tool with the limits in cfg applied.
+func NewExecToolFromConfig(workingDir string, restrict bool, cfg config.ExecToolsConfig) (*ExecTool, error) {
+	tool := NewExecTool(workingDir, restrict)
+	if cfg.Timeout > 0 {
+		tool.SetTimeout(time.Duration(cfg.Timeout) * time.Second)
+	}
+	if cfg.MaxTimeout > 0 {
+		tool.SetMaxTimeout(time.Duration(cfg.MaxTimeout) * time.Second)
+	}
+	if cfg.MaxOutput > 0 {
+		tool.SetMaxOutput(cfg.MaxOutput)
+	}
+	tool.SetEnvAllowlist(cfg.EnvAllowlist)
+	if len(cfg.AllowPatterns) > 0 {
+		if err := tool.SetAllowPatterns(cfg.AllowPatterns); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Sandbox.Enabled {
+		sandbox, err := NewSandbox(cfg.Sandbox, workingDir)
+		if err != nil {
+			return nil, fmt.Errorf("exec sandbox: %w", err)
+		}
+		tool.SetSandbox(sandbox)
+	}
+	return tool, nil
+}
+
 func (t *ExecTool) Name() string {
 	return "exec"
 }
 
+func (t *ExecTool) RequiresApproval() bool {
+	return true
+}
+
+// Timeout leaves the registry's default aside: commands are bounded by their
+// own timeout, which may be raised per call up to maxTimeout.
+func (t *ExecTool) Timeout() time.Duration {
+	if t.maxTimeout <= 0 {
+		return 0
+	}
+	return t.maxTimeout + 10*time.Second
+}
+
 func (t *ExecTool) Description() string {
 	return "Execute a shell command and return its output. Use with caution."
 }
@@ -60,7 +144,16 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 			},
 			"working_dir": map[string]interface{}{
 				"type":        "string",
-				"description": "Optional working directory for the command",
+				"description": "Optional working directory for the command, relative to the workspace",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional timeout in seconds for long-running commands such as builds and tests",
+			},
+			"env": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Optional environment variables to set for the command",
+				"additionalProperties": map[string]interface{}{"type": "string"},
 			},
 		},
 		"required": []string{"command"},
@@ -75,7 +168,11 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 
 	cwd := t.workingDir
 	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		cwd = wd
+		resolved, err := validatePath(wd, t.workingDir, t.restrictToWorkspace)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid working_dir: %v", err))
+		}
+		cwd = resolved
 	}
 
 	if cwd == "" {
@@ -88,23 +185,53 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	if guardError := t.guardCommand(command, cwd); guardError != "" {
 		return ErrorResult(guardError)
 	}
+	if vars, ok := args["env"].(map[string]interface{}); ok {
+		for name := range vars {
+			if envDenied(name) {
+				return ErrorResult(fmt.Sprintf("env: %s cannot be set for commands", name))
+			}
+		}
+	}
+
+	timeout := t.timeout
+	if seconds, ok := args["timeout"].(float64); ok && seconds > 0 {
+		timeout = time.Duration(seconds * float64(time.Second))
+		if t.maxTimeout > 0 && timeout > t.maxTimeout {
+			timeout = t.maxTimeout
+		}
+	}
 
-	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
+	if t.sandbox != nil {
+		container := sandboxContainerName()
+		var err error
+		cmd, err = t.sandbox.command(cmdCtx, container, command, cwd, t.sandboxEnvNames(args["env"]))
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		defer func() {
+			if cmdCtx.Err() != nil {
+				t.sandbox.remove(container)
+			}
+		}()
+	} else if runtime.GOOS == "windows" {
 		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
 	} else {
 		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
 	}
-	if cwd != "" {
+	if cwd != "" && t.sandbox == nil {
 		cmd.Dir = cwd
 	}
+	cmd.Env = t.commandEnv(args["env"])
+	prepareCommand(cmd)
+	// Don't wait forever for children that keep the output pipes open
+	cmd.WaitDelay = 2 * time.Second
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd.Stdout, cmd.Stderr = teeStream(ctx, &stdout, &stderr)
 
 	err := cmd.Run()
 	output := stdout.String()
@@ -114,7 +241,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 
 	if err != nil {
 		if cmdCtx.Err() == context.DeadlineExceeded {
-			msg := fmt.Sprintf("Command timed out after %v", t.timeout)
+			msg := fmt.Sprintf("Command timed out after %v", timeout)
 			return &ToolResult{
 				ForLLM:  msg,
 				ForUser: msg,
@@ -128,10 +255,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		output = "(no output)"
 	}
 
-	maxLen := 10000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
-	}
+	output = LimitOutput(ctx, t.Name(), output, t.maxOutput)
 
 	if err != nil {
 		return &ToolResult{
@@ -148,6 +272,80 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
+// commandEnv builds the environment for a command: the inherited
+// environment, filtered by the allowlist when one is set, plus the
+// variables the model asked for.
+func (t *ExecTool) commandEnv(extra interface{}) []string {
+	env := os.Environ()
+	if len(t.envAllowlist) > 0 {
+		allowed := make(map[string]bool, len(t.envAllowlist)+len(alwaysInheritedEnv))
+		for _, name := range append(alwaysInheritedEnv, t.envAllowlist...) {
+			allowed[strings.ToUpper(name)] = true
+		}
+		filtered := env[:0:0]
+		for _, kv := range env {
+			name, _, _ := strings.Cut(kv, "=")
+			if allowed[strings.ToUpper(name)] {
+				filtered = append(filtered, kv)
+			}
+		}
+		env = filtered
+	}
+
+	return append(env, extraEnv(extra)...)
+}
+
+// extraEnv returns the variables the model asked for as sorted NAME=value
+// pairs, skipping invalid and denied names.
+func extraEnv(extra interface{}) []string {
+	vars, ok := extra.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, "=\x00") || envDenied(name) {
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%v", name, vars[name]))
+	}
+	return env
+}
+
+// sandboxEnvNames lists the variables passed into a sandbox container: the
+// allowlisted ones set on the host and those the model asked for. The host's
+// PATH, HOME and the like stay outside; the image brings its own.
+func (t *ExecTool) sandboxEnvNames(extra interface{}) []string {
+	var names []string
+	for _, name := range t.envAllowlist {
+		if _, ok := os.LookupEnv(name); ok {
+			names = append(names, name)
+		}
+	}
+	for _, kv := range extraEnv(extra) {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
+
+// truncateOutput keeps the start and the end of long output, where build
+// and test failures are usually reported.
+func truncateOutput(output string, maxLen int) string {
+	if maxLen <= 0 || len(output) <= maxLen {
+		return output
+	}
+	head := maxLen / 2
+	tail := maxLen - head
+	omitted := len(output) - head - tail
+	return output[:head] + fmt.Sprintf("\n... (truncated, %d chars omitted) ...\n", omitted) + output[len(output)-tail:]
+}
+
 func (t *ExecTool) guardCommand(command, cwd string) string {
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
@@ -208,6 +406,29 @@ func (t *ExecTool) SetTimeout(timeout time.Duration) {
 	t.timeout = timeout
 }
 
+// SetMaxTimeout caps the timeout a single call may request.
+func (t *ExecTool) SetMaxTimeout(timeout time.Duration) {
+	t.maxTimeout = timeout
+}
+
+// SetMaxOutput sets how many characters of output are returned.
+func (t *ExecTool) SetMaxOutput(chars int) {
+	t.maxOutput = chars
+}
+
+// SetEnvAllowlist limits the inherited environment to the named variables
+// (plus PATH, HOME and a few others every command needs). An empty list
+// inherits the full environment.
+func (t *ExecTool) SetEnvAllowlist(names []string) {
+	t.envAllowlist = names
+}
+
+// SetSandbox runs commands in the given container sandbox instead of on the
+// host. nil runs them on the host again.
+func (t *ExecTool) SetSandbox(sandbox *Sandbox) {
+	t.sandbox = sandbox
+}
+
 func (t *ExecTool) SetRestrictToWorkspace(restrict bool) {
 	t.restrictToWorkspace = restrict
 }
@@ -208,3 +208,97 @@ func TestShellTool_RestrictToWorkspace(t *testing.T) {
 		t.Errorf("Expected 'blocked' message for path traversal, got ForLLM: %s, ForUser: %s", result.ForLLM, result.ForUser)
 	}
 }
+
+// TestShellTool_TimeoutArgument verifies a per-call timeout is honoured and capped
+func TestShellTool_TimeoutArgument(t *testing.T) {
+	tool := NewExecTool("", false)
+	tool.SetMaxTimeout(200 * time.Millisecond)
+
+	args := map[string]interface{}{
+		"command": "sleep 5",
+		"timeout": float64(30),
+	}
+
+	start := time.Now()
+	result := tool.Execute(context.Background(), args)
+	if !result.IsError || !strings.Contains(result.ForLLM, "timed out") {
+		t.Errorf("Expected timeout error, got: %s", result.ForLLM)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected timeout to be capped at max_timeout, took %v", elapsed)
+	}
+}
+
+// TestShellTool_EnvAllowlist verifies only allowlisted variables are inherited
+func TestShellTool_EnvAllowlist(t *testing.T) {
+	t.Setenv("PICOCLAW_TEST_SECRET", "hunter2")
+	t.Setenv("PICOCLAW_TEST_ALLOWED", "visible")
+
+	tool := NewExecTool("", false)
+	tool.SetEnvAllowlist([]string{"PICOCLAW_TEST_ALLOWED"})
+
+	args := map[string]interface{}{
+		"command": "echo \"[$PICOCLAW_TEST_SECRET][$PICOCLAW_TEST_ALLOWED][$EXTRA]\"",
+		"env":     map[string]interface{}{"EXTRA": "set"},
+	}
+
+	result := tool.Execute(context.Background(), args)
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "[][visible][set]") {
+		t.Errorf("Expected filtered environment, got: %s", result.ForLLM)
+	}
+}
+
+// TestShellTool_DeniedEnv verifies the model cannot set loader and shell
+// startup variables
+func TestShellTool_DeniedEnv(t *testing.T) {
+	tool := NewExecTool("", false)
+
+	for _, name := range []string{"LD_PRELOAD", "ld_library_path", "DYLD_INSERT_LIBRARIES", "PATH", "BASH_ENV", "ENV", "BASH_FUNC_ls%%", "NODE_OPTIONS"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"command": "echo ran",
+			"env":     map[string]interface{}{name: "/tmp/evil"},
+		})
+		if !result.IsError || strings.Contains(result.ForLLM, "ran") {
+			t.Errorf("Expected %s to be refused, got: %s", name, result.ForLLM)
+		}
+	}
+
+	if env := extraEnv(map[string]interface{}{"LD_PRELOAD": "/tmp/evil.so", "CI": "1"}); len(env) != 1 || env[0] != "CI=1" {
+		t.Errorf("Expected denied variables to be dropped, got: %v", env)
+	}
+}
+
+// TestShellTool_WorkingDirOutsideWorkspace verifies working_dir cannot escape the workspace
+func TestShellTool_WorkingDirOutsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewExecTool(workspace, true)
+
+	args := map[string]interface{}{
+		"command":     "pwd",
+		"working_dir": t.TempDir(),
+	}
+
+	result := tool.Execute(context.Background(), args)
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("Expected working_dir outside workspace to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+// TestTruncateOutput verifies long output keeps its head and tail
+func TestTruncateOutput(t *testing.T) {
+	output := "HEAD" + strings.Repeat("x", 1000) + "TAIL"
+
+	got := truncateOutput(output, 100)
+	if !strings.HasPrefix(got, "HEAD") || !strings.HasSuffix(got, "TAIL") {
+		t.Errorf("Expected head and tail to be kept, got: %q", got)
+	}
+	if !strings.Contains(got, "truncated, 908 chars omitted") {
+		t.Errorf("Expected truncation note, got: %q", got)
+	}
+	if truncateOutput("short", 100) != "short" {
+		t.Error("Expected short output to be unchanged")
+	}
+}
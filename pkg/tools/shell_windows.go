@@ -0,0 +1,9 @@
+//go:build windows
+
+package tools
+
+import "os/exec"
+
+// prepareCommand is a no-op on Windows; WaitDelay bounds the wait for
+// processes that outlive the shell.
+func prepareCommand(cmd *exec.Cmd) {}
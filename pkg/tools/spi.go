@@ -20,6 +20,11 @@ func (t *SPITool) Name() string {
 	return "spi"
 }
 
+// The bus is shared, so transfers run one at a time.
+func (t *SPITool) MaxConcurrency() int {
+	return 1
+}
+
 func (t *SPITool) Description() string {
 	return "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), transfer (full-duplex send/receive), read (receive bytes). Linux only."
 }
@@ -0,0 +1,597 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const sqlMaxCell = 500
+
+// sqlReadKeywords are the statements the sql tool runs. Anything else is
+// refused before it reaches the database.
+var sqlReadKeywords = map[string]bool{
+	"SELECT": true, "WITH": true, "VALUES": true, "TABLE": true,
+	"EXPLAIN": true, "SHOW": true, "DESCRIBE": true, "DESC": true,
+}
+
+// sqlDatabase is a configured connection profile.
+type sqlDatabase struct {
+	driver string
+	dsn    string
+	write  bool
+}
+
+// sqlLimits are the limits shared by the sql tools.
+type sqlLimits struct {
+	maxRows   int
+	maxOutput int
+	timeout   time.Duration
+}
+
+// NewSQLTools returns the sql tool for the databases in cfg, plus
+// sql_write when any of them allows writes, or nil when the tools are
+// disabled or name no databases.
+func NewSQLTools(cfg config.SQLToolsConfig) ([]Tool, error) {
+	if !cfg.Enabled || len(cfg.Databases) == 0 {
+		return nil, nil
+	}
+	limits := sqlLimits{maxRows: 100, maxOutput: 20000, timeout: 30 * time.Second}
+	if cfg.MaxRows > 0 {
+		limits.maxRows = cfg.MaxRows
+	}
+	if cfg.MaxOutput > 0 {
+		limits.maxOutput = cfg.MaxOutput
+	}
+	if cfg.Timeout > 0 {
+		limits.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	all := make(map[string]*sqlDatabase, len(cfg.Databases))
+	writable := make(map[string]*sqlDatabase)
+	for name, dc := range cfg.Databases {
+		driver := strings.ToLower(dc.Driver)
+		switch driver {
+		case "postgres", "postgresql":
+			driver = "postgres"
+		case "mysql":
+		case "sqlite", "sqlite3":
+			if !sqliteAvailable {
+				return nil, fmt.Errorf("sql database %q: sqlite is not supported on this platform", name)
+			}
+			driver = "sqlite"
+		default:
+			return nil, fmt.Errorf("sql database %q: unknown driver %q (want postgres, mysql or sqlite)", name, dc.Driver)
+		}
+		dsn := os.ExpandEnv(dc.DSN)
+		if dsn == "" {
+			return nil, fmt.Errorf("sql database %q needs a dsn", name)
+		}
+		db := &sqlDatabase{driver: driver, dsn: dsn, write: dc.Write}
+		all[name] = db
+		if db.write {
+			writable[name] = db
+		}
+	}
+
+	tools := []Tool{&SQLTool{databases: all, limits: limits}}
+	if len(writable) > 0 {
+		tools = append(tools, &SQLWriteTool{databases: writable, limits: limits})
+	}
+	return tools, nil
+}
+
+var (
+	sqlPoolsMu sync.Mutex
+	sqlPools   = map[string]*sql.DB{}
+)
+
+// open returns the connection pool for db. Pools are shared by every tool
+// instance, so registries built per request do not each open their own.
+// Read-only SQLite connections set query_only, since SQLite ignores the
+// read-only flag on transactions.
+func (db *sqlDatabase) open(readOnly bool) (*sql.DB, error) {
+	dsn := db.dsn
+	if readOnly && db.driver == "sqlite" {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "_pragma=query_only(1)"
+	}
+	key := db.driver + "\x00" + dsn
+
+	sqlPoolsMu.Lock()
+	defer sqlPoolsMu.Unlock()
+	if pool, ok := sqlPools[key]; ok {
+		return pool, nil
+	}
+	pool, err := sql.Open(db.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	pool.SetMaxOpenConns(4)
+	pool.SetConnMaxIdleTime(5 * time.Minute)
+	sqlPools[key] = pool
+	return pool, nil
+}
+
+// query runs a read-only query in a transaction that is always rolled
+// back, and formats at most maxRows rows of its result.
+func (db *sqlDatabase) query(ctx context.Context, limits sqlLimits, query string, args ...interface{}) (string, error) {
+	pool, err := db.open(true)
+	if err != nil {
+		return "", err
+	}
+	tx, err := pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	return formatSQLRows(rows, limits.maxRows)
+}
+
+// placeholder returns the n-th bind parameter in the driver's syntax.
+func (db *sqlDatabase) placeholder(n int) string {
+	if db.driver == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// formatSQLRows renders rows as a " | "-separated table with a header line.
+func formatSQLRows(rows *sql.Rows, maxRows int) (string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Join(columns, " | "))
+	sb.WriteString("\n")
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	cells := make([]string, len(columns))
+	count, truncated := 0, false
+	for rows.Next() {
+		if count == maxRows {
+			truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			cells[i] = formatSQLValue(v)
+		}
+		sb.WriteString(strings.Join(cells, " | "))
+		sb.WriteString("\n")
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if truncated {
+		fmt.Fprintf(&sb, "(first %d rows shown; add a LIMIT or aggregate to see the rest)", maxRows)
+	} else if count == 1 {
+		sb.WriteString("(1 row)")
+	} else {
+		fmt.Fprintf(&sb, "(%d rows)", count)
+	}
+	return sb.String(), nil
+}
+
+func formatSQLValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if !utf8.Valid(v) {
+			return fmt.Sprintf("<%d bytes>", len(v))
+		}
+		s = string(v)
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(v)
+	}
+	s = strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s)
+	if utf8.RuneCountInString(s) > sqlMaxCell {
+		s = string([]rune(s)[:sqlMaxCell]) + "..."
+	}
+	return s
+}
+
+// sqlStatement checks that query is a single statement for driver and
+// returns its first keyword, upper-cased. Literals, quoted identifiers and
+// comments are skipped the way the driver's database reads them, so a
+// semicolon inside a string is not taken for a second statement. The
+// returned text is query with those removed. Executable comments, which
+// MySQL runs as SQL, are refused.
+func sqlStatement(driver, query string) (string, string, error) {
+	mysql := driver == "mysql"
+	var sb strings.Builder
+	ended := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		var skip int
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			if ended {
+				return "", "", errors.New("only one statement can be run at a time")
+			}
+			skip = sqlQuoted(query[i:], c, mysql)
+		case strings.HasPrefix(query[i:], "--") || (mysql && c == '#'):
+			skip = strings.IndexByte(query[i:], '\n')
+			if skip < 0 {
+				skip = len(query) - i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			// MySQL runs the text of /*! and /*M! comments, and hints in
+			// /*+ comments change how a statement runs, so neither can be
+			// skipped as a comment
+			if rest := query[i+2:]; strings.HasPrefix(rest, "!") || strings.HasPrefix(rest, "M!") || strings.HasPrefix(rest, "+") {
+				return "", "", errors.New("executable comments and optimizer hints are not allowed")
+			}
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", "", errors.New("unterminated comment")
+			}
+			skip = end + 4
+		case c == '$' && driver == "postgres":
+			if ended {
+				return "", "", errors.New("only one statement can be run at a time")
+			}
+			skip = sqlDollarQuoted(query[i:])
+		}
+		if skip < 0 {
+			return "", "", errors.New("unterminated quoted string")
+		}
+		if skip > 0 {
+			sb.WriteByte(' ')
+			i += skip
+			continue
+		}
+
+		switch {
+		case c == ';':
+			ended = true
+		case ended && !unicode.IsSpace(rune(c)):
+			return "", "", errors.New("only one statement can be run at a time")
+		default:
+			sb.WriteByte(c)
+		}
+		i++
+	}
+
+	text := sb.String()
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(fields) == 0 {
+		return "", "", errors.New("query is empty")
+	}
+	return strings.ToUpper(fields[0]), text, nil
+}
+
+// sqlQuoted returns the length of the quoted string at the start of s,
+// where a doubled quote stands for itself, or -1 if it is not closed.
+// MySQL also escapes characters with a backslash.
+func sqlQuoted(s string, quote byte, backslash bool) int {
+	for i := 1; i < len(s); i++ {
+		if backslash && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// sqlDollarQuoted returns the length of a PostgreSQL $tag$...$tag$ string
+// at the start of s, 0 if s does not start one (e.g. a $1 parameter), or
+// -1 if it is not closed.
+func sqlDollarQuoted(s string) int {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return 0
+	}
+	tag := s[:end+2]
+	for _, r := range tag[1 : len(tag)-1] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return 0
+		}
+	}
+	if len(tag) > 2 && unicode.IsDigit(rune(tag[1])) {
+		return 0
+	}
+	closing := strings.Index(s[len(tag):], tag)
+	if closing < 0 {
+		return -1
+	}
+	return len(tag) + closing + len(tag)
+}
+
+// checkReadOnlySQL refuses anything but a single query. The transaction
+// the query runs in is read-only as well; this check gives the model a
+// clear error and stops statements, such as SELECT ... INTO OUTFILE, that
+// a read-only transaction would let through.
+func checkReadOnlySQL(driver, query string) error {
+	keyword, text, err := sqlStatement(driver, query)
+	if err != nil {
+		return err
+	}
+	if !sqlReadKeywords[keyword] {
+		return fmt.Errorf("%s statements are not allowed; the sql tool only runs queries", keyword)
+	}
+	for _, word := range strings.FieldsFunc(strings.ToUpper(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_'
+	}) {
+		if word == "INTO" {
+			return errors.New("SELECT ... INTO is not allowed; the sql tool only runs queries")
+		}
+	}
+	return nil
+}
+
+func sqlDatabaseNames(databases map[string]*sqlDatabase) []string {
+	names := make([]string, 0, len(databases))
+	for name := range databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sqlLookup(databases map[string]*sqlDatabase, args map[string]interface{}) (*sqlDatabase, error) {
+	name, _ := args["database"].(string)
+	db, ok := databases[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %q; configured databases: %s", name, strings.Join(sqlDatabaseNames(databases), ", "))
+	}
+	return db, nil
+}
+
+// sqlError reports a failed statement, naming a timeout as such.
+func sqlError(ctx context.Context, err error, timeout time.Duration) *ToolResult {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrorResult(fmt.Sprintf("Query timed out after %v", timeout))
+	}
+	return ErrorResult(fmt.Sprintf("Query failed: %v", err))
+}
+
+// SQLTool queries the databases configured in tools.sql and describes their
+// schema. Queries run in a read-only transaction that is rolled back, and
+// only single SELECT-like statements are accepted.
+type SQLTool struct {
+	databases map[string]*sqlDatabase
+	limits    sqlLimits
+}
+
+func (t *SQLTool) Name() string {
+	return "sql"
+}
+
+// Timeout leaves the registry's default aside: queries are bounded by
+// their own timeout.
+func (t *SQLTool) Timeout() time.Duration {
+	return t.limits.timeout + 10*time.Second
+}
+
+func (t *SQLTool) Description() string {
+	return "Query a configured SQL database (read-only). Use action tables to list tables, describe to see a table's columns, and query to run a single SELECT. Databases: " +
+		strings.Join(sqlDatabaseNames(t.databases), ", ")
+}
+
+func (t *SQLTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"database": map[string]interface{}{
+				"type":        "string",
+				"enum":        sqlDatabaseNames(t.databases),
+				"description": "The configured database to use",
+			},
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"query", "tables", "describe"},
+				"description": "query runs a SELECT, tables lists tables and views, describe lists a table's columns",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "The SQL query, for action query",
+			},
+			"table": map[string]interface{}{
+				"type":        "string",
+				"description": "The table, for action describe; schema.table for a PostgreSQL table outside the search path",
+			},
+		},
+		"required": []string{"database", "action"},
+	}
+}
+
+func (t *SQLTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	db, err := sqlLookup(t.databases, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var query string
+	var params []interface{}
+	action, _ := args["action"].(string)
+	switch action {
+	case "query":
+		query, _ = args["query"].(string)
+		if err := checkReadOnlySQL(db.driver, query); err != nil {
+			return ErrorResult(err.Error())
+		}
+	case "tables":
+		query = db.tablesQuery()
+	case "describe":
+		table, _ := args["table"].(string)
+		if table == "" {
+			return ErrorResult("table is required")
+		}
+		query, params = db.describeQuery(table)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q", action))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.limits.timeout)
+	defer cancel()
+	output, err := db.query(ctx, t.limits, query, params...)
+	if err != nil {
+		return sqlError(ctx, err, t.limits.timeout)
+	}
+	if action == "describe" && strings.HasSuffix(output, "(0 rows)") {
+		return ErrorResult(fmt.Sprintf("table %q not found", args["table"]))
+	}
+	return NewToolResult(LimitOutput(ctx, t.Name(), output, t.limits.maxOutput))
+}
+
+// tablesQuery lists the tables and views of the database.
+func (db *sqlDatabase) tablesQuery() string {
+	switch db.driver {
+	case "postgres":
+		return `SELECT table_schema AS schema, table_name AS name, table_type AS type
+FROM information_schema.tables
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name`
+	case "mysql":
+		return `SELECT table_name AS name, table_type AS type
+FROM information_schema.tables
+WHERE table_schema = DATABASE()
+ORDER BY table_name`
+	default:
+		return `SELECT name, type FROM sqlite_master
+WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+ORDER BY name`
+	}
+}
+
+// describeQuery lists the columns of table.
+func (db *sqlDatabase) describeQuery(table string) (string, []interface{}) {
+	switch db.driver {
+	case "postgres":
+		schema := ""
+		if i := strings.LastIndexByte(table, '.'); i >= 0 {
+			schema, table = table[:i], table[i+1:]
+		}
+		return fmt.Sprintf(`SELECT column_name AS name, data_type AS type, is_nullable AS nullable, column_default AS "default"
+FROM information_schema.columns
+WHERE table_name = %s AND table_schema = COALESCE(NULLIF(%s, ''), current_schema())
+ORDER BY ordinal_position`, db.placeholder(1), db.placeholder(2)), []interface{}{table, schema}
+	case "mysql":
+		return "SELECT column_name AS name, column_type AS type, is_nullable AS nullable, column_default AS `default`, column_key AS `key`\n" +
+			"FROM information_schema.columns\n" +
+			"WHERE table_schema = DATABASE() AND table_name = ?\n" +
+			"ORDER BY ordinal_position", []interface{}{table}
+	default:
+		return `SELECT name, type, CASE "notnull" WHEN 1 THEN 'NO' ELSE 'YES' END AS nullable, dflt_value AS "default", pk
+FROM pragma_table_info(?)
+ORDER BY cid`, []interface{}{table}
+	}
+}
+
+// SQLWriteTool runs statements that change data on the databases
+// configured with write enabled. It asks for approval when approvals are
+// on.
+type SQLWriteTool struct {
+	databases map[string]*sqlDatabase
+	limits    sqlLimits
+}
+
+func (t *SQLWriteTool) Name() string {
+	return "sql_write"
+}
+
+func (t *SQLWriteTool) RequiresApproval() bool {
+	return true
+}
+
+func (t *SQLWriteTool) MaxConcurrency() int {
+	return 1
+}
+
+func (t *SQLWriteTool) Timeout() time.Duration {
+	return t.limits.timeout + 10*time.Second
+}
+
+func (t *SQLWriteTool) Description() string {
+	return "Run a single statement that changes a configured SQL database (INSERT, UPDATE, DELETE, DDL) and report the rows affected. Use sql for queries. Databases: " +
+		strings.Join(sqlDatabaseNames(t.databases), ", ")
+}
+
+func (t *SQLWriteTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"database": map[string]interface{}{
+				"type":        "string",
+				"enum":        sqlDatabaseNames(t.databases),
+				"description": "The configured database to change",
+			},
+			"statement": map[string]interface{}{
+				"type":        "string",
+				"description": "The SQL statement to run",
+			},
+		},
+		"required": []string{"database", "statement"},
+	}
+}
+
+func (t *SQLWriteTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	db, err := sqlLookup(t.databases, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	statement, _ := args["statement"].(string)
+	if _, _, err := sqlStatement(db.driver, statement); err != nil {
+		return ErrorResult(err.Error())
+	}
+	pool, err := db.open(false)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.limits.timeout)
+	defer cancel()
+	result, err := pool.ExecContext(ctx, statement)
+	if err != nil {
+		return sqlError(ctx, err, t.limits.timeout)
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		return NewToolResult(fmt.Sprintf("Statement executed: %d rows affected", n))
+	}
+	return NewToolResult("Statement executed")
+}
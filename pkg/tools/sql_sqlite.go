@@ -0,0 +1,9 @@
+//go:build (linux && (386 || amd64 || arm || arm64 || loong64 || ppc64le || riscv64 || s390x)) || (darwin && (amd64 || arm64)) || (windows && (386 || amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (netbsd && amd64) || (openbsd && (amd64 || arm64))
+
+package tools
+
+// The pure-Go SQLite driver is only available on the platforms listed in
+// the build constraint.
+import _ "modernc.org/sqlite"
+
+const sqliteAvailable = true
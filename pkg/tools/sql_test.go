@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestSQLTools(t *testing.T, write bool) (*SQLTool, *SQLWriteTool) {
+	t.Helper()
+	if !sqliteAvailable {
+		t.Skip("sqlite is not available on this platform")
+	}
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, note TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := db.Exec(`INSERT INTO users (name) VALUES (?)`, fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tools, err := NewSQLTools(config.SQLToolsConfig{
+		Enabled:   true,
+		Databases: map[string]config.SQLDatabaseConfig{"app": {Driver: "sqlite", DSN: path, Write: write}},
+		MaxRows:   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var writeTool *SQLWriteTool
+	if len(tools) > 1 {
+		writeTool = tools[1].(*SQLWriteTool)
+	}
+	return tools[0].(*SQLTool), writeTool
+}
+
+func TestSQLTool_Query(t *testing.T) {
+	tool, writeTool := newTestSQLTools(t, false)
+	if writeTool != nil {
+		t.Fatal("sql_write should only be offered for writable databases")
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"database": "app", "action": "query", "query": "SELECT id, name, note FROM users WHERE id <= 2 ORDER BY id;",
+	})
+	if result.IsError {
+		t.Fatalf("query failed: %s", result.ForLLM)
+	}
+	want := "id | name | note\n1 | user1 | NULL\n2 | user2 | NULL\n(2 rows)"
+	if result.ForLLM != want {
+		t.Errorf("got %q, want %q", result.ForLLM, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"database": "app", "action": "query", "query": "SELECT name FROM users",
+	})
+	if !strings.Contains(result.ForLLM, "user3") || strings.Contains(result.ForLLM, "user4") ||
+		!strings.Contains(result.ForLLM, "first 3 rows shown") {
+		t.Errorf("expected the result to stop after 3 rows, got %q", result.ForLLM)
+	}
+}
+
+func TestSQLTool_ReadOnly(t *testing.T) {
+	tool, _ := newTestSQLTools(t, false)
+
+	for _, query := range []string{
+		"DELETE FROM users",
+		"SELECT 1; DELETE FROM users",
+		"PRAGMA query_only = 0",
+		"ATTACH DATABASE 'other.db' AS other",
+		"WITH x AS (SELECT 1) INSERT INTO users (name) SELECT 'x' FROM x",
+	} {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"database": "app", "action": "query", "query": query,
+		})
+		if !result.IsError {
+			t.Errorf("%q should have been refused, got %q", query, result.ForLLM)
+		}
+	}
+
+	// The connection itself refuses writes too
+	if _, err := tool.databases["app"].query(context.Background(), tool.limits, "DELETE FROM users"); err == nil {
+		t.Error("expected the read-only connection to refuse DELETE")
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"database": "app", "action": "query", "query": "SELECT count(*) AS n FROM users -- ; DELETE",
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "\n5\n") {
+		t.Errorf("expected all 5 users to remain, got %q", result.ForLLM)
+	}
+}
+
+func TestSQLTool_Schema(t *testing.T) {
+	tool, _ := newTestSQLTools(t, false)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"database": "app", "action": "tables"})
+	if result.IsError || !strings.Contains(result.ForLLM, "users | table") {
+		t.Errorf("expected users in the table list, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"database": "app", "action": "describe", "table": "users"})
+	if result.IsError || !strings.Contains(result.ForLLM, "name | TEXT | NO") {
+		t.Errorf("expected the users columns, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"database": "app", "action": "describe", "table": "missing"})
+	if !result.IsError {
+		t.Errorf("expected an error for a missing table, got %q", result.ForLLM)
+	}
+}
+
+func TestSQLWriteTool(t *testing.T) {
+	tool, writeTool := newTestSQLTools(t, true)
+	if writeTool == nil {
+		t.Fatal("expected sql_write for a writable database")
+	}
+	if !writeTool.RequiresApproval() {
+		t.Error("sql_write should require approval")
+	}
+
+	result := writeTool.Execute(context.Background(), map[string]interface{}{
+		"database": "app", "statement": "UPDATE users SET note = 'seen' WHERE id > 3",
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "2 rows affected") {
+		t.Fatalf("expected 2 rows affected, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"database": "app", "action": "query", "query": "SELECT count(*) FROM users WHERE note = 'seen'",
+	})
+	if !strings.Contains(result.ForLLM, "\n2\n") {
+		t.Errorf("expected the update to be visible, got %q", result.ForLLM)
+	}
+}
+
+func TestSQLStatement(t *testing.T) {
+	tests := []struct {
+		driver, query, keyword string
+		wantErr                bool
+	}{
+		{"sqlite", "  select 1;  ", "SELECT", false},
+		{"sqlite", "/* note */ WITH a AS (SELECT 1) SELECT * FROM a", "WITH", false},
+		{"sqlite", "SELECT ';DROP TABLE x'", "SELECT", false},
+		{"sqlite", "SELECT 1; SELECT 2", "", true},
+		{"sqlite", "SELECT 1; 'x'", "", true},
+		{"mysql", `SELECT 'it\'s; fine' # trailing; comment`, "SELECT", false},
+		{"postgres", `SELECT 'a\'; DROP TABLE x; --'`, "", true},
+		{"postgres", "SELECT $$;$$, $1", "SELECT", false},
+		{"postgres", "SELECT 'unterminated", "", true},
+		{"postgres", "  -- nothing", "", true},
+		{"mysql", "SELECT 1 /*!50000 ; DROP TABLE x */", "", true},
+		{"mysql", "/*!SELECT*/ DELETE FROM users", "", true},
+		{"mysql", "/*M!100101 DELETE FROM users */ SELECT 1", "", true},
+		{"mysql", "SELECT /*+ MAX_EXECUTION_TIME(1) */ 1", "", true},
+		{"mysql", "SELECT 1 /* plain ! comment */", "SELECT", false},
+	}
+	for _, tt := range tests {
+		keyword, _, err := sqlStatement(tt.driver, tt.query)
+		if (err != nil) != tt.wantErr || keyword != tt.keyword {
+			t.Errorf("sqlStatement(%s, %q) = %q, %v", tt.driver, tt.query, keyword, err)
+		}
+	}
+}
+
+func TestNewSQLTools_Config(t *testing.T) {
+	if tools, err := NewSQLTools(config.SQLToolsConfig{}); tools != nil || err != nil {
+		t.Errorf("disabled config should give no tools, got %v, %v", tools, err)
+	}
+	_, err := NewSQLTools(config.SQLToolsConfig{
+		Enabled:   true,
+		Databases: map[string]config.SQLDatabaseConfig{"x": {Driver: "oracle", DSN: "x"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+	t.Setenv("TEST_SQL_PASSWORD", "hunter2")
+	tools, err := NewSQLTools(config.SQLToolsConfig{
+		Enabled:   true,
+		Databases: map[string]config.SQLDatabaseConfig{"pg": {Driver: "postgresql", DSN: "postgres://u:${TEST_SQL_PASSWORD}@db/x"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn := tools[0].(*SQLTool).databases["pg"].dsn; dsn != "postgres://u:hunter2@db/x" {
+		t.Errorf("expected the DSN to be expanded, got %q", dsn)
+	}
+}
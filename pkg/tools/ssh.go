@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// sshHost is a configured host with its command allowlist compiled.
+type sshHost struct {
+	address       string
+	user          string
+	keyFile       string
+	allowPatterns []*regexp.Regexp
+}
+
+// SSHTool runs commands on the hosts configured in tools.ssh. Host keys
+// must be in the known_hosts file, and only key-based authentication is
+// used.
+type SSHTool struct {
+	hosts      map[string]*sshHost
+	knownHosts string
+	timeout    time.Duration
+	maxTimeout time.Duration
+	maxOutput  int
+
+	// dial connects to a host; replaced in tests
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NewSSHToolFromConfig returns the ssh tool described by cfg, or nil when
+// it is disabled or names no hosts.
+func NewSSHToolFromConfig(cfg config.SSHToolsConfig) (*SSHTool, error) {
+	if !cfg.Enabled || len(cfg.Hosts) == 0 {
+		return nil, nil
+	}
+	t := &SSHTool{
+		hosts:      make(map[string]*sshHost, len(cfg.Hosts)),
+		knownHosts: expandHome(cfg.KnownHosts),
+		timeout:    60 * time.Second,
+		maxTimeout: 10 * time.Minute,
+		maxOutput:  10000,
+		dial:       (&net.Dialer{Timeout: 15 * time.Second}).DialContext,
+	}
+	if t.knownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("locating known_hosts: %w", err)
+		}
+		t.knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	if cfg.Timeout > 0 {
+		t.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.MaxTimeout > 0 {
+		t.maxTimeout = time.Duration(cfg.MaxTimeout) * time.Second
+	}
+	if cfg.MaxOutput > 0 {
+		t.maxOutput = cfg.MaxOutput
+	}
+
+	for name, hc := range cfg.Hosts {
+		if hc.Address == "" || hc.User == "" {
+			return nil, fmt.Errorf("ssh host %q needs an address and a user", name)
+		}
+		address := hc.Address
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, "22")
+		}
+		host := &sshHost{address: address, user: hc.User, keyFile: expandHome(hc.KeyFile)}
+		for _, p := range hc.AllowPatterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("ssh host %q: invalid allow pattern %q: %w", name, p, err)
+			}
+			host.allowPatterns = append(host.allowPatterns, re)
+		}
+		t.hosts[name] = host
+	}
+	return t, nil
+}
+
+func (t *SSHTool) Name() string {
+	return "ssh"
+}
+
+func (t *SSHTool) RequiresApproval() bool {
+	return true
+}
+
+// Timeout leaves the registry's default aside, like exec: commands are
+// bounded by their own timeout.
+func (t *SSHTool) Timeout() time.Duration {
+	return t.maxTimeout + 30*time.Second
+}
+
+func (t *SSHTool) Description() string {
+	return "Run a shell command on a configured remote host over SSH and return its output. Hosts: " + strings.Join(t.hostNames(), ", ")
+}
+
+func (t *SSHTool) hostNames() []string {
+	names := make([]string, 0, len(t.hosts))
+	for name := range t.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *SSHTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"host": map[string]interface{}{
+				"type":        "string",
+				"enum":        t.hostNames(),
+				"description": "The configured host to run the command on",
+			},
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "The shell command to run",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional timeout in seconds",
+			},
+		},
+		"required": []string{"host", "command"},
+	}
+}
+
+func (t *SSHTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	name, _ := args["host"].(string)
+	host, ok := t.hosts[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("unknown host %q; configured hosts: %s", name, strings.Join(t.hostNames(), ", ")))
+	}
+	command, _ := args["command"].(string)
+	if strings.TrimSpace(command) == "" {
+		return ErrorResult("command is required")
+	}
+	if !host.allows(command) {
+		return ErrorResult(fmt.Sprintf("Command blocked: not in the allowlist for host %s", name))
+	}
+
+	timeout := t.timeout
+	if seconds, ok := args["timeout"].(float64); ok && seconds > 0 {
+		timeout = min(time.Duration(seconds*float64(time.Second)), t.maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, stderr, err := t.run(ctx, host, command)
+	output := stdout
+	if stderr != "" {
+		output += "\nSTDERR:\n" + stderr
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrorResult(fmt.Sprintf("Command timed out after %v", timeout))
+		}
+		var exitErr *ssh.ExitError
+		if !errors.As(err, &exitErr) {
+			return ErrorResult(fmt.Sprintf("ssh %s: %v", name, err))
+		}
+		output += fmt.Sprintf("\nExit code: %d", exitErr.ExitStatus())
+	}
+	if output == "" {
+		output = "(no output)"
+	}
+
+	output = LimitOutput(ctx, t.Name(), output, t.maxOutput)
+	if err != nil {
+		return &ToolResult{ForLLM: output, ForUser: output, IsError: true}
+	}
+	return &ToolResult{ForLLM: output, ForUser: output}
+}
+
+func (h *sshHost) allows(command string) bool {
+	if len(h.allowPatterns) == 0 {
+		return true
+	}
+	for _, re := range h.allowPatterns {
+		if re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// run connects to host, runs command and returns its output. The
+// connection is closed when ctx ends, which stops a command that runs too
+// long.
+func (t *SSHTool) run(ctx context.Context, host *sshHost, command string) (string, string, error) {
+	clientConfig, closeAgent, err := t.clientConfig(host)
+	if err != nil {
+		return "", "", err
+	}
+	defer closeAgent()
+	conn, err := t.dial(ctx, "tcp", host.address)
+	if err != nil {
+		return "", "", err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host.address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return "", "", err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", "", err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout, session.Stderr = teeStream(ctx, &stdout, &stderr)
+	err = session.Run(command)
+	return stdout.String(), stderr.String(), err
+}
+
+// clientConfig authenticates with the host's key file, or the ssh-agent,
+// and checks the host key against known_hosts. The returned function
+// closes the agent connection.
+func (t *SSHTool) clientConfig(host *sshHost) (*ssh.ClientConfig, func(), error) {
+	hostKeyCallback, err := knownhosts.New(t.knownHosts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading known_hosts: %w", err)
+	}
+
+	closeAgent := func() {}
+	var auth ssh.AuthMethod
+	if host.keyFile != "" {
+		key, err := os.ReadFile(host.keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				return nil, nil, errors.New("key file is protected by a passphrase; load it into ssh-agent and leave key_file empty")
+			}
+			return nil, nil, fmt.Errorf("parsing key file: %w", err)
+		}
+		auth = ssh.PublicKeys(signer)
+	} else {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, nil, errors.New("no key_file configured and no ssh-agent running")
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to ssh-agent: %w", err)
+		}
+		closeAgent = func() { conn.Close() }
+		auth = ssh.PublicKeysCallback(agent.NewClient(conn).Signers)
+	}
+
+	return &ssh.ClientConfig{
+		User:            host.user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	}, closeAgent, nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// startSSHServer runs a server that accepts userKey and answers every
+// command by echoing it, exiting 3 for commands starting with "fail".
+// It returns the server address and a known_hosts file listing it.
+func startSSHServer(t *testing.T, userKey ssh.PublicKey) (string, string) {
+	t.Helper()
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, _ := ssh.NewSignerFromKey(hostPriv)
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(userKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSHConn(conn, serverConfig)
+		}
+	}()
+
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(ln.Addr().String())}, hostSigner.PublicKey())
+	os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600)
+	return ln.Addr().String(), knownHostsFile
+}
+
+func serveSSHConn(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				command := string(req.Payload[4:])
+				req.Reply(true, nil)
+				ch.Write([]byte("ran: " + command + "\n"))
+				status := uint32(0)
+				if strings.HasPrefix(command, "fail") {
+					ch.Stderr().Write([]byte("boom\n"))
+					status = 3
+				}
+				payload := make([]byte, 4)
+				binary.BigEndian.PutUint32(payload, status)
+				ch.SendRequest("exit-status", false, payload)
+				return
+			}
+		}()
+	}
+}
+
+// writeSSHKey writes a new private key file and returns its public key.
+func writeSSHKey(t *testing.T, path string) ssh.PublicKey {
+	t.Helper()
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, pem.EncodeToMemory(block), 0600)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	return sshPub
+}
+
+func TestSSHTool(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	address, knownHostsFile := startSSHServer(t, writeSSHKey(t, keyFile))
+
+	tool, err := NewSSHToolFromConfig(config.SSHToolsConfig{
+		Enabled:    true,
+		KnownHosts: knownHostsFile,
+		Hosts: map[string]config.SSHHostConfig{
+			"web": {Address: address, User: "ops", KeyFile: keyFile, AllowPatterns: []string{`^(uptime|df|fail)\b`}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSSHToolFromConfig: %v", err)
+	}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"host": "web", "command": "uptime"})
+	if result.IsError || result.ForLLM != "ran: uptime\n" {
+		t.Errorf("uptime = %q (error %v)", result.ForLLM, result.IsError)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"host": "web", "command": "fail now"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "STDERR:\nboom") || !strings.Contains(result.ForLLM, "Exit code: 3") {
+		t.Errorf("failing command = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"host": "web", "command": "rm -rf /"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not in the allowlist") {
+		t.Errorf("command outside the allowlist = %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"host": "db", "command": "uptime"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "unknown host") {
+		t.Errorf("unknown host = %q", result.ForLLM)
+	}
+}
+
+func TestSSHTool_UnknownHostKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	address, _ := startSSHServer(t, writeSSHKey(t, keyFile))
+	emptyKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(emptyKnownHosts, nil, 0600)
+
+	tool, err := NewSSHToolFromConfig(config.SSHToolsConfig{
+		Enabled:    true,
+		KnownHosts: emptyKnownHosts,
+		Hosts:      map[string]config.SSHHostConfig{"web": {Address: address, User: "ops", KeyFile: keyFile}},
+	})
+	if err != nil {
+		t.Fatalf("NewSSHToolFromConfig: %v", err)
+	}
+	result := tool.Execute(context.Background(), map[string]interface{}{"host": "web", "command": "uptime"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "key is unknown") {
+		t.Errorf("unknown host key = %q", result.ForLLM)
+	}
+}
+
+func TestNewSSHToolFromConfig(t *testing.T) {
+	if tool, err := NewSSHToolFromConfig(config.SSHToolsConfig{}); tool != nil || err != nil {
+		t.Errorf("disabled ssh tool = %v, %v", tool, err)
+	}
+	_, err := NewSSHToolFromConfig(config.SSHToolsConfig{
+		Enabled: true,
+		Hosts:   map[string]config.SSHHostConfig{"web": {Address: "example.com"}},
+	})
+	if err == nil {
+		t.Error("host without a user was accepted")
+	}
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// OutputStream receives a tool's output while it runs. It only feeds the
+// UI; the model still gets the tool's final, possibly truncated, result.
+type OutputStream func(chunk string)
+
+type outputStreamKey struct{}
+
+// WithOutputStream returns a context under which long-running tools, such
+// as exec and ssh, pass their output to fn as it is produced.
+func WithOutputStream(ctx context.Context, fn OutputStream) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, outputStreamKey{}, fn)
+}
+
+// StreamWriter returns a writer that forwards to the context's output
+// stream, or nil when there is none. It is safe to share between a
+// command's stdout and stderr.
+func StreamWriter(ctx context.Context) io.Writer {
+	fn, _ := ctx.Value(outputStreamKey{}).(OutputStream)
+	if fn == nil {
+		return nil
+	}
+	return &streamWriter{fn: fn}
+}
+
+// teeStream makes a command's stdout and stderr writers also write to the
+// context's output stream, if any.
+func teeStream(ctx context.Context, stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	s := StreamWriter(ctx)
+	if s == nil {
+		return stdout, stderr
+	}
+	return io.MultiWriter(stdout, s), io.MultiWriter(stderr, s)
+}
+
+type streamWriter struct {
+	mu      sync.Mutex
+	fn      OutputStream
+	partial []byte
+}
+
+// Write passes p on, holding back a trailing incomplete UTF-8 sequence
+// until the rest of it arrives.
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := append(w.partial, p...)
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	w.partial = append([]byte(nil), data[end:]...)
+	if end > 0 {
+		w.fn(string(data[:end]))
+	}
+	return len(p), nil
+}
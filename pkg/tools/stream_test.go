@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStreamWriter_KeepsRunesWhole(t *testing.T) {
+	var chunks []string
+	ctx := WithOutputStream(context.Background(), func(chunk string) { chunks = append(chunks, chunk) })
+	w := StreamWriter(ctx)
+
+	data := []byte("héllo ✓")
+	for i := range data {
+		w.Write(data[i : i+1])
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk %q splits a rune", c)
+		}
+	}
+	if got := strings.Join(chunks, ""); got != "héllo ✓" {
+		t.Errorf("streamed %q", got)
+	}
+
+	if StreamWriter(context.Background()) != nil {
+		t.Error("expected no writer without a stream")
+	}
+}
+
+func TestExecTool_StreamsOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	var mu sync.Mutex
+	var streamed strings.Builder
+	ctx := WithOutputStream(context.Background(), func(chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed.WriteString(chunk)
+	})
+
+	tool := NewExecTool("", false)
+	tool.maxOutput = 100
+	result := tool.Execute(ctx, map[string]interface{}{
+		"command": "for i in $(seq 1 50); do echo line $i; done; echo oops >&2",
+	})
+	if result.IsError {
+		t.Fatalf("command failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(streamed.String(), "line 25\n") || !strings.Contains(streamed.String(), "oops") {
+		t.Errorf("expected all output to be streamed, got %q", streamed.String())
+	}
+	if strings.Contains(result.ForLLM, "line 25\n") {
+		t.Errorf("expected the result for the model to stay truncated, got %q", result.ForLLM)
+	}
+}
@@ -226,6 +226,12 @@ func (t *SubagentTool) Name() string {
 	return "subagent"
 }
 
+// Timeout disables the registry's default: a subagent is bounded by its
+// iteration limit rather than wall-clock time.
+func (t *SubagentTool) Timeout() time.Duration {
+	return 0
+}
+
 func (t *SubagentTool) Description() string {
 	return "Execute a subagent task synchronously and return the result. Use this for delegating specific tasks to an independent agent instance. Returns execution summary to user and full details to LLM."
 }
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrToolTimeout is wrapped by the result error of a tool that did not
+// finish within its timeout.
+var ErrToolTimeout = errors.New("tool timed out")
+
+// TimedTool is an optional interface for tools that need a different
+// timeout than the registry default, e.g. because they enforce their own.
+// Zero or less means no timeout.
+type TimedTool interface {
+	Tool
+	Timeout() time.Duration
+}
+
+// SetDefaultTimeout bounds every tool call that has no timeout of its own.
+// Zero or less disables the default.
+func (r *ToolRegistry) SetDefaultTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultTimeout = d
+}
+
+// SetTimeout sets the timeout of the named tool, overriding both the
+// default and the tool's own Timeout. Zero or less means no timeout.
+func (r *ToolRegistry) SetTimeout(name string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timeouts == nil {
+		r.timeouts = make(map[string]time.Duration)
+	}
+	r.timeouts[name] = d
+}
+
+func (r *ToolRegistry) timeoutFor(tool Tool) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if d, ok := r.timeouts[tool.Name()]; ok {
+		return d
+	}
+	if tt, ok := tool.(TimedTool); ok {
+		return tt.Timeout()
+	}
+	return r.defaultTimeout
+}
+
+// runTool executes tool, returning early with an error result when timeout
+// elapses or ctx is cancelled. The tool sees the cancellation through its
+// context; one that ignores it keeps running in the background, but the
+// agent no longer waits for it.
+func runTool(ctx context.Context, tool Tool, args map[string]interface{}, timeout time.Duration) *ToolResult {
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan *ToolResult, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err := fmt.Errorf("tool %s panicked: %v", tool.Name(), p)
+				done <- ErrorResult(err.Error()).WithError(err)
+			}
+		}()
+		done <- tool.Execute(ctx, args)
+	}()
+
+	select {
+	case result := <-done:
+		if result == nil {
+			result = ErrorResult(fmt.Sprintf("tool %s returned no result", tool.Name()))
+		}
+		// A tool that failed because its context ended is reported as
+		// cancelled or timed out, however the two channels were ordered
+		if !result.IsError || ctx.Err() == nil {
+			return result
+		}
+	case <-ctx.Done():
+	}
+
+	if parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		err := fmt.Errorf("%w: %s did not finish within %s", ErrToolTimeout, tool.Name(), timeout)
+		return ErrorResult(fmt.Sprintf("tool %s timed out after %s", tool.Name(), timeout)).WithError(err)
+	}
+	err := ctx.Err()
+	return ErrorResult(fmt.Sprintf("tool %s was cancelled: %v", tool.Name(), err)).WithError(err)
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingTool waits until its context is done, optionally ignoring it.
+type blockingTool struct {
+	name         string
+	ignoreCancel bool
+	timeout      time.Duration
+}
+
+func (t *blockingTool) Name() string        { return t.name }
+func (t *blockingTool) Description() string { return "blocks" }
+func (t *blockingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (t *blockingTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if t.ignoreCancel {
+		time.Sleep(2 * time.Second)
+		return NewToolResult("finished late")
+	}
+	<-ctx.Done()
+	return ErrorResult("stopped")
+}
+
+// timedBlockingTool declares its own timeout.
+type timedBlockingTool struct{ blockingTool }
+
+func (t *timedBlockingTool) Timeout() time.Duration { return t.timeout }
+
+func TestRegistry_ToolTimeout(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&blockingTool{name: "stuck", ignoreCancel: true})
+	registry.SetDefaultTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	result := registry.Execute(context.Background(), "stuck", nil)
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Execute to return at the timeout, took %v", time.Since(start))
+	}
+	if !result.IsError || !errors.Is(result.Err, ErrToolTimeout) {
+		t.Fatalf("Expected a timeout error, got: %+v", result)
+	}
+	if !strings.Contains(result.ForLLM, "timed out after 50ms") {
+		t.Errorf("Expected timed out message, got: %s", result.ForLLM)
+	}
+}
+
+func TestRegistry_TimeoutPrecedence(t *testing.T) {
+	registry := NewToolRegistry()
+	tool := &timedBlockingTool{blockingTool{name: "timed", timeout: 30 * time.Millisecond}}
+	registry.Register(tool)
+	registry.SetDefaultTimeout(time.Hour)
+
+	if got := registry.timeoutFor(tool); got != 30*time.Millisecond {
+		t.Errorf("Expected the tool's own timeout, got %v", got)
+	}
+	registry.SetTimeout("timed", 10*time.Millisecond)
+	if got := registry.timeoutFor(tool); got != 10*time.Millisecond {
+		t.Errorf("Expected the configured override, got %v", got)
+	}
+
+	result := registry.Execute(context.Background(), "timed", nil)
+	if !errors.Is(result.Err, ErrToolTimeout) {
+		t.Errorf("Expected a timeout error, got: %+v", result)
+	}
+	if registry.timeoutFor(NewExecTool("", false)) <= 10*time.Minute {
+		t.Error("Expected exec to outlast its own max timeout")
+	}
+}
+
+func TestRegistry_CancellationStopsTool(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&blockingTool{name: "stuck", ignoreCancel: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := registry.Execute(ctx, "stuck", nil)
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Execute to return on cancellation, took %v", time.Since(start))
+	}
+	if !errors.Is(result.Err, context.Canceled) || !strings.Contains(result.ForLLM, "cancelled") {
+		t.Errorf("Expected a cancelled result, got: %+v", result)
+	}
+}
+
+func TestRegistry_RecoversToolPanic(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&FuncTool[struct{}]{
+		name:   "boom",
+		schema: map[string]interface{}{"type": "object"},
+		fn: func(ctx context.Context, args struct{}) *ToolResult {
+			panic("boom")
+		},
+	})
+
+	result := registry.Execute(context.Background(), "boom", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "panicked") {
+		t.Errorf("Expected panic to become an error result, got: %+v", result)
+	}
+}
@@ -23,6 +23,9 @@ type ToolLoopConfig struct {
 	Tools         *ToolRegistry
 	MaxIterations int
 	LLMOptions    map[string]any
+	// MaxParallelTools bounds how many tool calls run at once; zero uses
+	// DefaultMaxParallelTools and 1 runs them in order.
+	MaxParallelTools int
 }
 
 // ToolLoopResult contains the result of running the tool loop.
@@ -36,6 +39,7 @@ type ToolLoopResult struct {
 func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []providers.Message, channel, chatID string) (*ToolLoopResult, error) {
 	iteration := 0
 	var finalContent string
+	var repairs ArgumentRepairs
 
 	for iteration < config.MaxIterations {
 		iteration++
@@ -71,6 +75,11 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 				})
 			return nil, fmt.Errorf("LLM call failed: %w", err)
 		}
+		if config.Tools != nil {
+			if audit := config.Tools.AuditLog(); audit != nil {
+				audit.RecordResponse(ctx, response)
+			}
+		}
 
 		// 4. If no tool calls, we're done
 		if len(response.ToolCalls) == 0 {
@@ -111,10 +120,10 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 				},
 			})
 		}
-		messages = append(messages, assistantMsg)
+		messages = append(messages, providers.WithReasoning(assistantMsg, response))
 
-		// 7. Execute tool calls
-		for _, tc := range response.ToolCalls {
+		// 7. Execute tool calls (no async callback for subagents - they run independently)
+		run := func(ctx context.Context, tc providers.ToolCall) *ToolResult {
 			argsJSON, _ := json.Marshal(tc.Arguments)
 			argsPreview := utils.Truncate(string(argsJSON), 200)
 			logger.InfoCF("toolloop", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
@@ -122,28 +131,28 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 					"tool":      tc.Name,
 					"iteration": iteration,
 				})
-
-			// Execute tool (no async callback for subagents - they run independently)
-			var toolResult *ToolResult
-			if config.Tools != nil {
-				toolResult = config.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, channel, chatID, nil)
-			} else {
-				toolResult = ErrorResult("No tools available")
+			if tc.ArgumentsError != "" {
+				return InvalidArgumentsResult(tc)
 			}
+			return config.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, channel, chatID, nil)
+		}
+		var toolResults []*ToolResult
+		if config.Tools != nil {
+			toolResults = config.Tools.ExecuteParallel(ctx, response.ToolCalls, config.MaxParallelTools, run)
+		}
 
-			// Determine content for LLM
-			contentForLLM := toolResult.ForLLM
-			if contentForLLM == "" && toolResult.Err != nil {
-				contentForLLM = toolResult.Err.Error()
+		// 8. Add tool result messages in call order
+		for i, tc := range response.ToolCalls {
+			toolResult := ErrorResult("No tools available")
+			if toolResults != nil {
+				toolResult = toolResults[i]
 			}
 
 			// Add tool result message
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    contentForLLM,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
+			messages = append(messages, toolResult.ToolMessage(tc.ID))
+		}
+		if err := repairs.Check(response.ToolCalls); err != nil {
+			return nil, err
 		}
 	}
 
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ValidationError lists every way a tool call's arguments failed to match
+// the tool's parameters schema.
+type ValidationError struct {
+	Tool     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// ForLLM formats the error as a tool result the model can act on.
+func (e *ValidationError) ForLLM() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Error: invalid arguments for tool %q. The tool was not run.\n", e.Tool)
+	for _, p := range e.Problems {
+		sb.WriteString("- ")
+		sb.WriteString(p)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Fix the arguments to match the tool's parameters schema and call it again.")
+	return sb.String()
+}
+
+// ValidateArgs checks args against the JSON schema returned by a tool's
+// Parameters, with providers.ValidateSchema. A nil error means the
+// arguments are valid; otherwise it is a *ValidationError.
+func ValidateArgs(tool string, schema map[string]interface{}, args map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	var value interface{} = args
+	if args == nil {
+		value = map[string]interface{}{}
+	}
+	if problems := providers.ValidateSchema(value, schema); len(problems) > 0 {
+		return &ValidationError{Tool: tool, Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var validateSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"action": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"read", "write"},
+		},
+		"count": map[string]interface{}{
+			"type":    "integer",
+			"minimum": 1.0,
+			"maximum": 10.0,
+		},
+		"bytes": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "integer"},
+		},
+		"verbose": map[string]interface{}{
+			"type": "boolean",
+		},
+	},
+	"required":             []string{"action"},
+	"additionalProperties": false,
+}
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string // substrings of the reported problems; nil means valid
+	}{
+		{"valid", map[string]interface{}{"action": "read", "count": float64(3), "bytes": []interface{}{float64(1)}}, nil},
+		{"optional null", map[string]interface{}{"action": "read", "verbose": nil}, nil},
+		{"missing required", map[string]interface{}{}, []string{"action: required"}},
+		{"wrong type", map[string]interface{}{"action": "read", "verbose": "yes"}, []string{"verbose: expected boolean, got string"}},
+		{"not an integer", map[string]interface{}{"action": "read", "count": 1.5}, []string{"count: expected integer, got number"}},
+		{"enum", map[string]interface{}{"action": "delete"}, []string{`action: must be one of ["read", "write"]`}},
+		{"range", map[string]interface{}{"action": "read", "count": float64(11)}, []string{"count: must be <= 10"}},
+		{"item type", map[string]interface{}{"action": "read", "bytes": []interface{}{"ff"}}, []string{"bytes[0]: expected integer, got string"}},
+		{"unknown property", map[string]interface{}{"action": "read", "extra": 1}, []string{"extra: unknown property"}},
+		{"several problems", map[string]interface{}{"count": float64(0), "verbose": 1}, []string{"action: required", "count: must be >= 1", "verbose: expected boolean"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArgs("tool", validateSchema, tt.args)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Expected valid arguments, got: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected *ValidationError, got: %v", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Errorf("Expected %d problems, got %q", len(tt.want), verr.Problems)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_RejectsInvalidArgs(t *testing.T) {
+	tool := &recordingTool{name: "strict"}
+	registry := NewToolRegistry()
+	registry.Register(&schemaTool{recordingTool: tool, schema: validateSchema})
+
+	result := registry.Execute(context.Background(), "strict", map[string]interface{}{"action": 5})
+	if !result.IsError {
+		t.Fatal("Expected an error result for invalid arguments")
+	}
+	if tool.runs != 0 {
+		t.Errorf("Expected tool not to run, ran %d times", tool.runs)
+	}
+	if !strings.Contains(result.ForLLM, "action: expected string, got integer") || !strings.Contains(result.ForLLM, "call it again") {
+		t.Errorf("Expected a correctable error for the model, got: %s", result.ForLLM)
+	}
+
+	result = registry.Execute(context.Background(), "strict", map[string]interface{}{"action": "write"})
+	if result.IsError || tool.runs != 1 {
+		t.Errorf("Expected valid call to run, got: %s", result.ForLLM)
+	}
+}
+
+// schemaTool gives a recordingTool a custom parameters schema.
+type schemaTool struct {
+	*recordingTool
+	schema map[string]interface{}
+}
+
+func (t *schemaTool) Parameters() map[string]interface{} {
+	return t.schema
+}
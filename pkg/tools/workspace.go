@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideWorkspace is returned (wrapped) when a restricted workspace is
+// asked for a path outside its root.
+var ErrOutsideWorkspace = errors.New("access denied: path is outside the workspace")
+
+// Workspace is the directory file tools work in. It resolves the paths the
+// model passes, relative ones against the root, and when restricted refuses
+// any that lead outside it, whether through ".." or a symlink. Paths shown
+// to the model are relative to the root, so results do not reveal where
+// the workspace lives on the host.
+//
+// A nil or rootless Workspace takes paths as given.
+type Workspace struct {
+	root     string // absolute
+	resolved string // root with symlinks resolved
+	restrict bool
+}
+
+// NewWorkspace returns the workspace rooted at root. With restrict set, paths
+// outside it are refused.
+func NewWorkspace(root string, restrict bool) *Workspace {
+	if root == "" {
+		return &Workspace{restrict: restrict}
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = filepath.Clean(root)
+	}
+	return &Workspace{root: abs, resolved: resolveExisting(abs), restrict: restrict}
+}
+
+// Root returns the absolute workspace directory, or "" when there is none.
+func (w *Workspace) Root() string {
+	if w == nil {
+		return ""
+	}
+	return w.root
+}
+
+// Restricted reports whether paths outside the root are refused.
+func (w *Workspace) Restricted() bool {
+	return w != nil && w.restrict && w.root != ""
+}
+
+// Resolve returns the absolute path the tools should use for path. In a
+// restricted workspace symlinks are resolved first, and the result is the
+// resolved path, so the file checked is the file opened.
+func (w *Workspace) Resolve(path string) (string, error) {
+	if w == nil || w.root == "" {
+		return path, nil
+	}
+
+	abs := filepath.Clean(path)
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(w.root, path)
+	}
+	if !w.restrict {
+		return abs, nil
+	}
+
+	resolved := resolveExisting(abs)
+	if !isWithin(w.resolved, resolved) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkspace, path)
+	}
+	return resolved, nil
+}
+
+// Contains reports whether path, with symlinks resolved, is inside the
+// root. Tools that walk directories use it to skip links leading out of a
+// restricted workspace.
+func (w *Workspace) Contains(path string) bool {
+	if w == nil || w.root == "" {
+		return true
+	}
+	return isWithin(w.resolved, resolveExisting(path))
+}
+
+// Rel returns path relative to the workspace root, slash-separated, for
+// showing to the model. Paths outside the root are returned unchanged.
+func (w *Workspace) Rel(path string) string {
+	if w == nil || w.root == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	for _, root := range []string{w.root, w.resolved} {
+		if isWithin(root, path) {
+			rel, _ := filepath.Rel(root, path)
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// RelError rewrites the path in a file system error relative to the root,
+// like Rel.
+func (w *Workspace) RelError(err error) error {
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		return err
+	}
+	return &fs.PathError{Op: pathErr.Op, Path: w.Rel(pathErr.Path), Err: pathErr.Err}
+}
+
+// validatePath resolves path against workspace like Workspace.Resolve.
+func validatePath(path, workspace string, restrict bool) (string, error) {
+	return NewWorkspace(workspace, restrict).Resolve(path)
+}
+
+// isWithin reports whether path is root or inside it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// maxSymlinkHops bounds how many dangling symlinks resolveExisting follows,
+// so a loop of links cannot keep it going.
+const maxSymlinkHops = 40
+
+// resolveExisting resolves symlinks in the longest existing prefix of path,
+// so paths of files that do not exist yet can still be checked. A dangling
+// symlink is followed to where its target would be created. It returns ""
+// when the links loop.
+func resolveExisting(path string) string {
+	return resolveLinks(path, 0)
+}
+
+func resolveLinks(path string, hops int) string {
+	suffix := ""
+	for p := path; ; p = filepath.Dir(p) {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(resolved, suffix)
+		}
+		if target, err := os.Readlink(p); err == nil {
+			if hops >= maxSymlinkHops {
+				return ""
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			resolved := resolveLinks(target, hops+1)
+			if resolved == "" {
+				return ""
+			}
+			return filepath.Join(resolved, suffix)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return path
+		}
+		suffix = filepath.Join(filepath.Base(p), suffix)
+	}
+}
+
+// openNoFollow opens path like os.OpenFile, but refuses a symlink in its
+// place, so a file checked by Resolve cannot be swapped for a link to one
+// outside the workspace before it is written.
+func openNoFollow(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, flag|oNoFollow, perm)
+}
+
+// writeFileNoFollow is os.WriteFile through openNoFollow.
+func writeFileNoFollow(path string, data []byte, perm os.FileMode) error {
+	f, err := openNoFollow(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace_Resolve(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "ws")
+	outside := filepath.Join(base, "outside")
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.MkdirAll(outside, 0755)
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	ws := NewWorkspace(root, true)
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"src/main.go", true},
+		{"new/dir/file.txt", true},
+		{".", true},
+		{"src/../README.md", true},
+		{"../outside/secret.txt", false},
+		{"src/../../outside", false},
+		{"escape/secret.txt", false},
+		{filepath.Join(outside, "secret.txt"), false},
+		{filepath.Join(root, "src", "main.go"), true},
+	}
+	for _, tt := range tests {
+		resolved, err := ws.Resolve(tt.path)
+		if tt.ok && err != nil {
+			t.Errorf("Resolve(%q): %v", tt.path, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("Resolve(%q) = %q, %v; want ErrOutsideWorkspace", tt.path, resolved, err)
+		}
+	}
+
+	if _, err := NewWorkspace(root, false).Resolve("escape/secret.txt"); err != nil {
+		t.Errorf("unrestricted workspace refused a path: %v", err)
+	}
+	if got, err := (*Workspace)(nil).Resolve("a/b"); got != "a/b" || err != nil {
+		t.Errorf("nil workspace Resolve = %q, %v", got, err)
+	}
+}
+
+func TestWorkspace_DanglingSymlink(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "ws")
+	outside := filepath.Join(base, "outside")
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.MkdirAll(outside, 0755)
+	target := filepath.Join(outside, "created.txt")
+	if err := os.Symlink(target, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink("../link", filepath.Join(root, "src", "relative"))
+	os.Symlink("loop", filepath.Join(root, "loop"))
+	os.Symlink(filepath.Join(root, "src", "inside.txt"), filepath.Join(root, "ok"))
+
+	ws := NewWorkspace(root, true)
+	for _, path := range []string{"link", "src/relative", "loop"} {
+		if resolved, err := ws.Resolve(path); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("Resolve(%q) = %q, %v; want ErrOutsideWorkspace", path, resolved, err)
+		}
+	}
+	if _, err := ws.Resolve("ok"); err != nil {
+		t.Errorf("Resolve(ok) refused a link within the workspace: %v", err)
+	}
+
+	result := NewWriteFileTool(root, true).Execute(context.Background(), map[string]interface{}{"path": "link", "content": "x"})
+	if !result.IsError {
+		t.Errorf("write_file through a dangling link succeeded: %s", result.ForLLM)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("file was created outside the workspace: %v", err)
+	}
+}
+
+func TestWorkspace_Rel(t *testing.T) {
+	root := t.TempDir()
+	ws := NewWorkspace(root, true)
+
+	if got := ws.Rel(filepath.Join(root, "src", "main.go")); got != "src/main.go" {
+		t.Errorf("Rel = %q, want src/main.go", got)
+	}
+	if got := ws.Rel(root); got != "." {
+		t.Errorf("Rel(root) = %q, want .", got)
+	}
+	other := filepath.Join(filepath.Dir(root), "other")
+	if got := ws.Rel(other); got != other {
+		t.Errorf("Rel(outside) = %q, want it unchanged", got)
+	}
+
+	_, err := os.ReadFile(filepath.Join(root, "missing.txt"))
+	if msg := ws.RelError(err).Error(); strings.Contains(msg, root) || !strings.Contains(msg, "missing.txt") {
+		t.Errorf("RelError = %q, want the path relative to the workspace", msg)
+	}
+}
+
+func TestFileTools_RelativePaths(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+
+	result := NewWriteFileTool(root, true).Execute(ctx, map[string]interface{}{
+		"path":    filepath.Join(root, "notes", "todo.md"),
+		"content": "x",
+	})
+	if result.IsError || result.ForLLM != "File written: notes/todo.md" {
+		t.Errorf("write_file = %q", result.ForLLM)
+	}
+
+	result = NewReadFileTool(root, true).Execute(ctx, map[string]interface{}{"path": "missing.txt"})
+	if !result.IsError || strings.Contains(result.ForLLM, root) {
+		t.Errorf("read_file error reveals the workspace path: %q", result.ForLLM)
+	}
+}
+
+func TestGrepTool_SkipsSymlinksOutOfWorkspace(t *testing.T) {
+	root := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(secret, []byte("password=hunter2\n"), 0644)
+	os.WriteFile(filepath.Join(root, "config.txt"), []byte("password=changeme\n"), 0644)
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	result := NewGrepTool(root, true).Execute(context.Background(), map[string]interface{}{"pattern": "password"})
+	if strings.Contains(result.ForLLM, "hunter2") || !strings.Contains(result.ForLLM, "changeme") {
+		t.Errorf("grep = %q, want only the file inside the workspace", result.ForLLM)
+	}
+}